@@ -27,10 +27,13 @@ export default function LedgerScreen() {
       setError('');
       const [entriesData, choresData, groupData] = await Promise.all([
         ledgerApi.list(id),
-        choresApi.list(id),
+        choresApi.list(id, true),
         groupsApi.get(id),
       ]);
-      setEntries(entriesData || []);
+      // Newest first by when the entry happened, which may be backdated
+      setEntries((entriesData || []).sort(
+        (a, b) => new Date(b.occurred_at).getTime() - new Date(a.occurred_at).getTime()
+      ));
       setChores(choresData || []);
       setMembers(groupData.members || []);
       const currentMember = groupData.members.find((m: Member) => m.user_id === user?.id);
@@ -52,8 +55,11 @@ export default function LedgerScreen() {
     loadData();
   }, [id]);
 
+  // Archived chores still name their entries but cannot be logged
+  const activeChores = chores.filter(c => !c.deleted_at);
+
   const openModal = () => {
-    setSelectedChore(chores[0]?.id || '');
+    setSelectedChore(activeChores[0]?.id || '');
     setSelectedMember(isHead ? members[0]?.user_id || '' : user?.id || '');
     setModalVisible(true);
   };
@@ -61,7 +67,7 @@ export default function LedgerScreen() {
   const handleCreate = async () => {
     if (!id || !selectedChore) return;
 
-    const chore = chores.find(c => c.id === selectedChore);
+    const chore = activeChores.find(c => c.id === selectedChore);
     if (!chore) return;
 
     setSaving(true);
@@ -88,20 +94,30 @@ export default function LedgerScreen() {
     }
   };
 
-  const getChoreByID = (choreId: string) => chores.find(c => c.id === choreId);
+  const getChoreByID = (choreId: string | null) => chores.find(c => c.id === choreId);
   const getMemberByID = (userId: string) => members.find(m => m.user_id === userId);
 
+  // Entries that are not for a chore are labelled by their kind
+  const getEntryLabel = (item: LedgerEntry) => {
+    switch (item.kind) {
+      case 'allowance': return 'Allowance';
+      case 'interest': return 'Interest';
+      case 'loan': return 'Advance';
+      case 'loan_repayment': return item.withheld_from_id ? 'Loan repayment (withheld)' : 'Loan repayment';
+      default: return getChoreByID(item.chore_id)?.name || 'Unknown Chore';
+    }
+  };
+
   const renderEntry = ({ item }: { item: LedgerEntry }) => {
-    const chore = getChoreByID(item.chore_id);
     const member = getMemberByID(item.user_id);
     
     return (
       <View style={styles.entryCard}>
         <View style={styles.entryInfo}>
-          <Text style={styles.entryChore}>{chore?.name || 'Unknown Chore'}</Text>
+          <Text style={styles.entryChore}>{getEntryLabel(item)}</Text>
           <Text style={styles.entryMember}>{member?.name || 'Unknown'}</Text>
           <Text style={styles.entryDate}>
-            {new Date(item.created_at).toLocaleDateString()}
+            {new Date(item.occurred_at).toLocaleDateString()}
           </Text>
         </View>
         <View style={styles.entryRight}>
@@ -153,7 +169,7 @@ export default function LedgerScreen() {
           <View style={styles.modalContent}>
             <Text style={styles.modalTitle}>Add Entry</Text>
 
-            {chores.length === 0 ? (
+            {activeChores.length === 0 ? (
               <Text style={styles.noChores}>No chores available. Create chores first.</Text>
             ) : (
               <>
@@ -163,7 +179,7 @@ export default function LedgerScreen() {
                     selectedValue={selectedChore}
                     onValueChange={setSelectedChore}
                   >
-                    {chores.map(chore => (
+                    {activeChores.map(chore => (
                       <Picker.Item 
                         key={chore.id} 
                         label={`${chore.name} - $${chore.amount}`} 
@@ -211,7 +227,7 @@ export default function LedgerScreen() {
               <TouchableOpacity
                 style={[styles.modalButton, styles.saveButton]}
                 onPress={handleCreate}
-                disabled={saving || chores.length === 0}
+                disabled={saving || activeChores.length === 0}
               >
                 {saving ? (
                   <ActivityIndicator color="#fff" />
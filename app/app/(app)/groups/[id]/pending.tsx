@@ -20,7 +20,7 @@ export default function PendingScreen() {
       setError('');
       const [pendingData, choresData, groupData] = await Promise.all([
         ledgerApi.listPending(id),
-        choresApi.list(id),
+        choresApi.list(id, true),
         groupsApi.get(id),
       ]);
       setEntries(pendingData || []);
@@ -76,7 +76,7 @@ export default function PendingScreen() {
     ]);
   };
 
-  const getChoreByID = (choreId: string) => chores.find(c => c.id === choreId);
+  const getChoreByID = (choreId: string | null) => chores.find(c => c.id === choreId);
   const getMemberByID = (userId: string) => members.find(m => m.user_id === userId);
 
   const renderEntry = ({ item }: { item: LedgerEntry }) => {
@@ -23,6 +23,11 @@ export interface Group {
   id: string;
   name: string;
   head_user_id: string;
+  max_backdate_days: number | null;
+  expire_pending_after_days: number | null;
+  auto_approve_after_hours: number | null;
+  auto_approve_max_amount: number | null;
+  require_reject_reason: boolean;
   created_at: string;
 }
 
@@ -45,28 +50,118 @@ export interface Chore {
   name: string;
   description?: string;
   amount: number;
+  proof_required: boolean;
+  assignee_user_id?: string;
+  due_at?: string;
+  assigned_at?: string;
   created_at: string;
+  deleted_at?: string; // Archived chores are kept so their entries can still name them
 }
 
 export interface LedgerEntry {
   id: string;
   group_id: string;
   user_id: string;
-  chore_id: string;
-  amount: number;
+  kind: 'chore' | 'allowance' | 'interest' | 'loan' | 'loan_repayment';
+  chore_id: string | null;
+  allowance_id?: string;
+  allowance_period?: string;
+  interest_rate_id?: string;
+  loan_id?: string;
+  withheld_from_id?: string; // The earning a withheld loan repayment was taken from
+  amount: number; // Negative for loan repayments
   status: 'approved' | 'pending_approval' | 'rejected';
   created_by_user_id: string;
   approved_by_user_id?: string;
   rejected_by_user_id?: string;
+  status_reason?: string;
+  system_actor?: 'auto_approve' | 'auto_expire';
+  approval_rule_id?: string;
+  resubmitted_from_id?: string;
+  occurred_at: string;
+  created_at: string;
+  hash?: string;
+}
+
+export interface LedgerComment {
+  id: string;
+  entry_id: string;
+  user_id: string;
+  user_name: string;
+  body: string;
   created_at: string;
 }
 
+export interface LedgerAttachment {
+  id: string;
+  entry_id: string;
+  uploaded_by_user_id: string;
+  content_type: 'image/jpeg' | 'image/png';
+  size_bytes: number;
+  url: string;
+  created_at: string;
+}
+
+export interface BulkLedgerRequest {
+  ids?: string[];
+  filter?: { user_id?: string; chore_id?: string; from?: string; to?: string };
+  reason?: string;
+}
+
+export interface BulkLedgerResponse {
+  results: { id: string; ok: boolean; entry?: LedgerEntry; error?: string }[];
+  succeeded: number;
+  failed: number;
+}
+
 export interface Balance {
   user_id: string;
   name: string;
   balance: number;
+  confirmed_paid: number;
+  unconfirmed_paid: number;
+  jars?: JarBalance[];
+  unallocated?: number;
+  loans?: LoanBalance[];
+}
+
+export interface LoanBalance {
+  loan_id: string;
+  principal: number;
+  total_due: number;
+  outstanding: number;
+  repayment_plan: RepaymentPlan;
+}
+
+export interface JarBalance {
+  jar_id: string;
+  name: string;
+  split_percent: number;
+  balance: number;
+}
+
+export interface BalancePoint {
+  period_start: string;
+  period_end: string;
+  earned: number;
+  settled: number;
+  balance: number;
 }
 
+export interface BalanceHistory {
+  user_id: string;
+  interval: 'week' | 'month';
+  opening_balance: number;
+  points: BalancePoint[];
+}
+
+export interface SettlementEntry {
+  entry_id: string;
+  amount: number;
+}
+
+export type SettlementMethod = 'cash' | 'bank_transfer' | 'in_kind';
+
 export interface Settlement {
   id: string;
   group_id: string;
@@ -74,7 +169,213 @@ export interface Settlement {
   amount: number;
   date: string;
   note?: string;
+  method: SettlementMethod;
+  external_reference?: string;
+  category_id?: string;
+  jar_id?: string;
+  status: 'awaiting_ack' | 'acknowledged' | 'disputed';
+  acknowledged_at?: string;
+  disputed_at?: string;
+  dispute_reason?: string;
+  created_at: string;
+  updated_at?: string;
+  voided_at?: string;
+  voided_by_user_id?: string;
+  void_reason?: string;
+  entries: SettlementEntry[];
+  hash?: string;
+}
+
+export interface SettlementRevision {
+  id: string;
+  settlement_id: string;
+  action: 'edit' | 'void' | 'dispute' | 'resolve';
+  changed_by_user_id: string | null;
+  reason: string;
+  amount_before: number;
+  amount_after: number;
+  date_before: string;
+  date_after: string;
+  note_before?: string;
+  note_after?: string;
+  method_before?: SettlementMethod;
+  method_after?: SettlementMethod;
+  external_reference_before?: string;
+  external_reference_after?: string;
+  category_id_before?: string;
+  category_id_after?: string;
+  created_at: string;
+}
+
+export interface SettlementCategory {
+  id: string;
+  group_id: string;
+  name: string;
+  created_at: string;
+  deleted_at?: string;
+}
+
+export type AllowanceInterval = 'week' | 'month';
+
+export interface Allowance {
+  id: string;
+  group_id: string;
+  user_id: string;
+  amount: number;
+  amount_per_year?: number;
+  interval: AllowanceInterval;
+  start_date: string;
+  next_period: string;
+  paused_at?: string;
+  created_by_user_id: string;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string;
+}
+
+export type GoalSource = 'balance' | 'allocations';
+
+export interface GoalProgress {
+  saved: number;
+  remaining: number;
+  percent: number;
+  reached: boolean;
+  weekly_rate: number;
+  weeks: number;
+  projected_date?: string;
+  on_track?: boolean;
+}
+
+export interface Goal {
+  id: string;
+  group_id: string;
+  user_id: string;
+  name: string;
+  target_amount: number;
+  deadline?: string;
+  progress_source: GoalSource;
+  allocated: number;
+  image_url?: string;
+  reached_at?: string;
+  progress?: GoalProgress;
+  created_by_user_id: string;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string;
+}
+
+export interface GoalAllocation {
+  id: string;
+  goal_id: string;
+  amount: number;
+  note?: string;
+  created_by_user_id: string;
+  created_at: string;
+}
+
+export interface Jar {
+  id: string;
+  group_id: string;
+  user_id: string;
+  name: string;
+  split_percent: number;
+  position: number;
+  balance: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Jars {
+  jars: Jar[];
+  unallocated: number;
+}
+
+export interface JarTransfer {
+  id: string;
+  group_id: string;
+  user_id: string;
+  from_jar_id: string;
+  to_jar_id: string;
+  amount: number;
+  note?: string;
+  created_by_user_id: string;
+  created_at: string;
+}
+
+export interface InterestRate {
+  id: string;
+  group_id: string;
+  jar_id?: string;
+  apr: number;
+  compounding: AllowanceInterval;
+  min_balance: number;
+  start_date: string;
+  next_period: string;
+  created_by_user_id: string;
+  created_at: string;
+  updated_at: string;
+  deleted_at?: string;
+}
+
+export interface InterestBreakdown {
+  period_start: string;
+  period_end: string;
+  days: number;
+  daily_balances: number[];
+  average_balance: number;
+  apr: number;
+  min_balance: number;
+  amount: number;
+  explanation: string;
+}
+
+export interface InterestPosting {
+  id: string;
+  rate_id: string;
+  entry_id: string;
+  group_id: string;
+  user_id: string;
+  jar_id?: string;
+  period_start: string;
+  amount: number;
+  breakdown: InterestBreakdown;
+  created_at: string;
+}
+
+export type RepaymentPlan = 'weekly' | 'withhold';
+
+export interface LoanRepayment {
+  date: string;
+  amount: number;
+}
+
+export interface Loan {
+  id: string;
+  group_id: string;
+  user_id: string;
+  principal: number;
+  interest_percent: number;
+  total_due: number;
+  repayment_plan: RepaymentPlan;
+  weekly_amount?: number;
+  withhold_percent?: number;
+  start_date: string;
+  next_repayment?: string;
+  note?: string;
+  outstanding: number;
+  repaid_at?: string;
+  created_by_user_id: string;
   created_at: string;
+  updated_at: string;
+  schedule?: LoanRepayment[]; // Weekly repayments still to come
+  entries?: LedgerEntry[]; // The advance and repayments, for a single loan
+}
+
+export interface SettlementSummary {
+  count: number;
+  amount: number;
+  by_method: { method: SettlementMethod; count: number; amount: number }[];
+  by_category: { category_id: string | null; name: string | null; count: number; amount: number }[];
 }
 
 export interface InviteResponse {
@@ -100,8 +401,9 @@ async function request<T>(
 ): Promise<T> {
   const token = await getToken();
   
+  // Multipart uploads set their own content type with the boundary
   const headers: HeadersInit = {
-    'Content-Type': 'application/json',
+    ...(options.body instanceof FormData ? {} : { 'Content-Type': 'application/json' }),
     ...(options.headers || {}),
   };
 
@@ -154,7 +456,20 @@ export const groupsApi = {
     request<Group>('/groups', { method: 'POST', body: JSON.stringify(data) }),
   
   get: (id: string) => request<GroupDetail>(`/groups/${id}`),
-  
+
+  update: (
+    id: string,
+    data: {
+      name?: string;
+      max_backdate_days?: number | null;
+      expire_pending_after_days?: number | null;
+      auto_approve_after_hours?: number | null;
+      auto_approve_max_amount?: number | null;
+      require_reject_reason?: boolean;
+    }
+  ) =>
+    request<Group>(`/groups/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
+
   getMembers: (id: string) => request<Member[]>(`/groups/${id}/members`),
   
   createInvite: (id: string, expiresInDays?: number) =>
@@ -169,12 +484,13 @@ export const groupsApi = {
 
 // Chores API
 export const choresApi = {
-  list: (groupId: string) => request<Chore[]>(`/groups/${groupId}/chores`),
+  list: (groupId: string, includeDeleted = false) =>
+    request<Chore[]>(`/groups/${groupId}/chores${includeDeleted ? '?include_deleted=true' : ''}`),
   
-  create: (groupId: string, data: { name: string; description?: string; amount: number }) =>
+  create: (groupId: string, data: { name: string; description?: string; amount: number; proof_required?: boolean; assignee_user_id?: string; due_at?: string }) =>
     request<Chore>(`/groups/${groupId}/chores`, { method: 'POST', body: JSON.stringify(data) }),
   
-  update: (id: string, data: { name?: string; description?: string; amount?: number }) =>
+  update: (id: string, data: { name?: string; description?: string; amount?: number; proof_required?: boolean; assignee_user_id?: string | null; due_at?: string | null }) =>
     request<Chore>(`/chores/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
   
   delete: (id: string) => request<void>(`/chores/${id}`, { method: 'DELETE' }),
@@ -187,24 +503,204 @@ export const ledgerApi = {
     return request<LedgerEntry[]>(`/groups/${groupId}/ledger${params}`);
   },
   
-  create: (groupId: string, data: { user_id?: string; chore_id: string; amount: number }) =>
+  create: (groupId: string, data: { user_id?: string; chore_id: string; amount: number; occurred_at?: string }) =>
     request<LedgerEntry>(`/groups/${groupId}/ledger`, { method: 'POST', body: JSON.stringify(data) }),
   
   approve: (id: string) =>
     request<LedgerEntry>(`/ledger/${id}/approve`, { method: 'POST' }),
   
-  reject: (id: string) =>
-    request<LedgerEntry>(`/ledger/${id}/reject`, { method: 'POST' }),
+  reject: (id: string, reason?: string) =>
+    request<LedgerEntry>(`/ledger/${id}/reject`, { method: 'POST', body: JSON.stringify({ reason }) }),
+
+  resubmit: (id: string, data: { amount?: number; occurred_at?: string } = {}) =>
+    request<LedgerEntry>(`/ledger/${id}/resubmit`, { method: 'POST', body: JSON.stringify(data) }),
+
+  listComments: (id: string) => request<LedgerComment[]>(`/ledger/${id}/comments`),
+
+  addComment: (id: string, body: string) =>
+    request<LedgerComment>(`/ledger/${id}/comments`, { method: 'POST', body: JSON.stringify({ body }) }),
+
+  listAttachments: (id: string) => request<LedgerAttachment[]>(`/ledger/${id}/attachments`),
+
+  // photo is a local file, e.g. { uri, name: 'proof.jpg', type: 'image/jpeg' } from the image picker
+  uploadAttachment: (id: string, photo: { uri: string; name: string; type: string }) => {
+    const form = new FormData();
+    form.append('file', photo as unknown as Blob);
+    return request<LedgerAttachment>(`/ledger/${id}/attachments`, { method: 'POST', body: form });
+  },
   
   listPending: (groupId: string) => request<LedgerEntry[]>(`/groups/${groupId}/pending`),
+
+  approvePending: (groupId: string, data: BulkLedgerRequest) =>
+    request<BulkLedgerResponse>(`/groups/${groupId}/pending/approve`, { method: 'POST', body: JSON.stringify(data) }),
+
+  rejectPending: (groupId: string, data: BulkLedgerRequest) =>
+    request<BulkLedgerResponse>(`/groups/${groupId}/pending/reject`, { method: 'POST', body: JSON.stringify(data) }),
   
-  getBalance: (groupId: string) => request<Balance[]>(`/groups/${groupId}/balance`),
+  getBalance: (groupId: string, asOf?: string) =>
+    request<Balance[]>(`/groups/${groupId}/balance${asOf ? `?as_of=${encodeURIComponent(asOf)}` : ''}`),
+
+  getBalanceHistory: (groupId: string, userId: string, interval: 'week' | 'month' = 'week') =>
+    request<BalanceHistory>(`/groups/${groupId}/members/${userId}/balance/history?interval=${interval}`),
 };
 
 // Settlements API
 export const settlementsApi = {
   list: (groupId: string) => request<Settlement[]>(`/groups/${groupId}/settlements`),
   
-  create: (groupId: string, data: { user_id: string; amount: number; date: string; note?: string }) =>
+  create: (groupId: string, data: { user_id: string; amount: number; date: string; note?: string; method?: SettlementMethod; external_reference?: string; category_id?: string; jar_id?: string; entry_ids?: string[]; allow_negative?: boolean }) =>
     request<Settlement>(`/groups/${groupId}/settlements`, { method: 'POST', body: JSON.stringify(data) }),
+
+  summary: (groupId: string, filter: { method?: SettlementMethod; category_id?: string; from?: string; to?: string } = {}) => {
+    const params = new URLSearchParams(Object.entries(filter).filter(([, v]) => v) as [string, string][]).toString();
+    return request<SettlementSummary>(`/groups/${groupId}/settlements/summary${params ? `?${params}` : ''}`);
+  },
+
+  update: (id: string, data: { amount?: number; date?: string; note?: string; method?: SettlementMethod; external_reference?: string; category_id?: string; entry_ids?: string[]; allow_negative?: boolean; reason: string }) =>
+    request<Settlement>(`/settlements/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
+
+  void: (id: string, reason: string) =>
+    request<Settlement>(`/settlements/${id}/void`, { method: 'POST', body: JSON.stringify({ reason }) }),
+
+  listRevisions: (id: string) => request<SettlementRevision[]>(`/settlements/${id}/revisions`),
+
+  acknowledge: (id: string) =>
+    request<Settlement>(`/settlements/${id}/ack`, { method: 'POST' }),
+
+  dispute: (id: string, reason: string) =>
+    request<Settlement>(`/settlements/${id}/dispute`, { method: 'POST', body: JSON.stringify({ reason }) }),
+
+  resolve: (id: string, reason: string) =>
+    request<Settlement>(`/settlements/${id}/resolve`, { method: 'POST', body: JSON.stringify({ reason }) }),
+
+  listCategories: (groupId: string) => request<SettlementCategory[]>(`/groups/${groupId}/settlement-categories`),
+
+  createCategory: (groupId: string, name: string) =>
+    request<SettlementCategory>(`/groups/${groupId}/settlement-categories`, { method: 'POST', body: JSON.stringify({ name }) }),
+
+  renameCategory: (groupId: string, id: string, name: string) =>
+    request<SettlementCategory>(`/groups/${groupId}/settlement-categories/${id}`, { method: 'PATCH', body: JSON.stringify({ name }) }),
+
+  deleteCategory: (groupId: string, id: string) =>
+    request<void>(`/groups/${groupId}/settlement-categories/${id}`, { method: 'DELETE' }),
+};
+
+// Allowances API
+export const allowancesApi = {
+  list: (groupId: string, userId?: string) => {
+    const params = userId ? `?user_id=${userId}` : '';
+    return request<Allowance[]>(`/groups/${groupId}/allowances${params}`);
+  },
+
+  create: (groupId: string, data: { user_id: string; amount: number; amount_per_year?: number; interval: AllowanceInterval; start_date?: string }) =>
+    request<Allowance>(`/groups/${groupId}/allowances`, { method: 'POST', body: JSON.stringify(data) }),
+
+  update: (groupId: string, id: string, data: { amount?: number; amount_per_year?: number | null }) =>
+    request<Allowance>(`/groups/${groupId}/allowances/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
+
+  pause: (groupId: string, id: string) =>
+    request<Allowance>(`/groups/${groupId}/allowances/${id}/pause`, { method: 'POST' }),
+
+  resume: (groupId: string, id: string) =>
+    request<Allowance>(`/groups/${groupId}/allowances/${id}/resume`, { method: 'POST' }),
+
+  delete: (groupId: string, id: string) =>
+    request<void>(`/groups/${groupId}/allowances/${id}`, { method: 'DELETE' }),
+};
+
+// Savings goals API
+export const goalsApi = {
+  list: (groupId: string, userId: string, weeks?: number) => {
+    const params = weeks ? `?weeks=${weeks}` : '';
+    return request<Goal[]>(`/groups/${groupId}/members/${userId}/goals${params}`);
+  },
+
+  get: (groupId: string, userId: string, id: string) =>
+    request<Goal>(`/groups/${groupId}/members/${userId}/goals/${id}`),
+
+  create: (groupId: string, userId: string, data: { name: string; target_amount: number; deadline?: string; progress_source?: GoalSource }) =>
+    request<Goal>(`/groups/${groupId}/members/${userId}/goals`, { method: 'POST', body: JSON.stringify(data) }),
+
+  update: (groupId: string, userId: string, id: string, data: { name?: string; target_amount?: number; deadline?: string | null; progress_source?: GoalSource }) =>
+    request<Goal>(`/groups/${groupId}/members/${userId}/goals/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
+
+  delete: (groupId: string, userId: string, id: string) =>
+    request<void>(`/groups/${groupId}/members/${userId}/goals/${id}`, { method: 'DELETE' }),
+
+  listAllocations: (groupId: string, userId: string, id: string) =>
+    request<GoalAllocation[]>(`/groups/${groupId}/members/${userId}/goals/${id}/allocations`),
+
+  // A negative amount takes money back from the goal
+  allocate: (groupId: string, userId: string, id: string, amount: number, note?: string) =>
+    request<GoalAllocation>(`/groups/${groupId}/members/${userId}/goals/${id}/allocations`, { method: 'POST', body: JSON.stringify({ amount, note }) }),
+
+  // image is a local file, e.g. { uri, name: 'bike.jpg', type: 'image/jpeg' } from the image picker
+  setImage: (groupId: string, userId: string, id: string, image: { uri: string; name: string; type: string }) => {
+    const form = new FormData();
+    form.append('file', image as unknown as Blob);
+    return request<Goal>(`/groups/${groupId}/members/${userId}/goals/${id}/image`, { method: 'PUT', body: form });
+  },
+
+  deleteImage: (groupId: string, userId: string, id: string) =>
+    request<void>(`/groups/${groupId}/members/${userId}/goals/${id}/image`, { method: 'DELETE' }),
+};
+
+export const jarsApi = {
+  list: (groupId: string, userId: string) =>
+    request<Jars>(`/groups/${groupId}/members/${userId}/jars`),
+
+  // Jars with an id are kept, the rest are added; jars left out are removed
+  set: (groupId: string, userId: string, jars: { id?: string; name: string; split_percent: number }[]) =>
+    request<Jars>(`/groups/${groupId}/members/${userId}/jars`, { method: 'PUT', body: JSON.stringify({ jars }) }),
+
+  listTransfers: (groupId: string, userId: string) =>
+    request<JarTransfer[]>(`/groups/${groupId}/members/${userId}/jars/transfers`),
+
+  transfer: (groupId: string, userId: string, data: { from_jar_id: string; to_jar_id: string; amount: number; note?: string }) =>
+    request<JarTransfer>(`/groups/${groupId}/members/${userId}/jars/transfers`, { method: 'POST', body: JSON.stringify(data) }),
+};
+
+// Interest API
+export const interestApi = {
+  list: (groupId: string) =>
+    request<InterestRate[]>(`/groups/${groupId}/interest-rates`),
+
+  // Without a jar_id the rate covers the whole group
+  create: (groupId: string, data: { jar_id?: string; apr: number; compounding: AllowanceInterval; min_balance?: number; start_date?: string }) =>
+    request<InterestRate>(`/groups/${groupId}/interest-rates`, { method: 'POST', body: JSON.stringify(data) }),
+
+  update: (groupId: string, id: string, data: { apr?: number; min_balance?: number }) =>
+    request<InterestRate>(`/groups/${groupId}/interest-rates/${id}`, { method: 'PATCH', body: JSON.stringify(data) }),
+
+  delete: (groupId: string, id: string) =>
+    request<void>(`/groups/${groupId}/interest-rates/${id}`, { method: 'DELETE' }),
+
+  listPostings: (groupId: string, userId?: string) => {
+    const params = userId ? `?user_id=${userId}` : '';
+    return request<InterestPosting[]>(`/groups/${groupId}/interest/postings${params}`);
+  },
+};
+
+// Loans API
+export const loansApi = {
+  list: (groupId: string, userId?: string) => {
+    const params = userId ? `?user_id=${userId}` : '';
+    return request<Loan[]>(`/groups/${groupId}/loans${params}`);
+  },
+
+  get: (groupId: string, id: string) =>
+    request<Loan>(`/groups/${groupId}/loans/${id}`),
+
+  // Weekly plans take weekly_amount from first_repayment; withhold plans take withhold_percent of each approved earning
+  create: (groupId: string, data: {
+    user_id: string;
+    principal: number;
+    interest_percent?: number;
+    repayment_plan: RepaymentPlan;
+    weekly_amount?: number;
+    withhold_percent?: number;
+    first_repayment?: string;
+    note?: string;
+  }) =>
+    request<Loan>(`/groups/${groupId}/loans`, { method: 'POST', body: JSON.stringify(data) }),
 };
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// allowancesSchedule is how often due allowance periods are credited. Each
+// run catches up on every period missed since the last, so an hourly run is
+// enough for periods that start at midnight.
+const allowancesSchedule = "5 * * * *"
+
+// allowancesJob credits the allowance periods that have begun, and announces
+// each new entry as if a head had logged it, with no actor
+func allowancesJob(allowanceRepo *db.AllowanceRepo, bus *events.Bus) scheduler.Job {
+	return func(ctx context.Context) error {
+		entries, err := allowanceRepo.PostDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			bus.Publish(ctx, events.New(events.LedgerCreated, entry.GroupID, nil, entry))
+		}
+		if len(entries) > 0 {
+			log.Printf("Credited %d allowance periods", len(entries))
+		}
+		return nil
+	}
+}
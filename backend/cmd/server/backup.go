@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/srjn45/pocket-money/backend/internal/archive"
+	"github.com/srjn45/pocket-money/backend/internal/backup"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/config"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+)
+
+// backupJob snapshots the database, writes a verified backup and prunes
+// old backups according to the configured retention
+func backupJob(archiveRepo *db.ArchiveRepo, cfg config.BackupConfig) scheduler.Job {
+	return func(ctx context.Context) error {
+		b, err := archiveRepo.Snapshot(ctx)
+		if err != nil {
+			return err
+		}
+
+		path, err := backup.Write(cfg.Dir, b)
+		if err != nil {
+			return err
+		}
+		counts := b.Counts()
+		log.Printf("Wrote backup %s (%d users, %d groups, %d ledger entries, %d settlements)",
+			path, len(b.Users), len(b.Groups), counts.LedgerEntries, counts.Settlements)
+
+		removed, err := backup.Prune(cfg.Dir, backup.Policy{
+			KeepLast:   cfg.KeepLast,
+			KeepDaily:  cfg.KeepDaily,
+			KeepWeekly: cfg.KeepWeekly,
+		})
+		for _, p := range removed {
+			log.Printf("Removed old backup %s", p)
+		}
+		return err
+	}
+}
+
+// backupCommand writes one backup immediately
+func backupCommand(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dir := fs.String("dir", cfg.Backup.Dir, "directory to write the backup to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	pool, err := db.NewPool(cfg.DatabaseURL)
+	if err != nil {
+		log.Printf("Failed to create database pool: %v", err)
+		return 1
+	}
+	defer pool.Close()
+
+	store, err := storage.NewLocalStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Printf("Failed to open attachment storage: %v", err)
+		return 1
+	}
+
+	backupCfg := cfg.Backup
+	backupCfg.Dir = *dir
+	archiveRepo := db.NewArchiveRepo(pool, db.NewChainRepo(pool, chain.NewHasher(cfg.LedgerChainKey)), store)
+	if err := backupJob(archiveRepo, backupCfg)(context.Background()); err != nil {
+		log.Printf("Backup failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// restoreCommand restores a backup file into an empty database.
+// With -dry-run it only reads and validates the file.
+func restoreCommand(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate the backup without restoring it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: server restore [-dry-run] FILE")
+		return 2
+	}
+
+	b, err := backup.Read(fs.Arg(0))
+	if err != nil {
+		log.Printf("Failed to read backup: %v", err)
+		return 1
+	}
+	if errs := b.Validate(); len(errs) > 0 {
+		log.Printf("Backup is invalid:\n  %s", strings.Join(errs, "\n  "))
+		return 1
+	}
+	if errs := b.VerifyChain(chain.NewHasher(cfg.LedgerChainKey)); len(errs) > 0 {
+		log.Printf("Backup ledger chain does not verify with LEDGER_CHAIN_KEY:\n  %s", strings.Join(errs, "\n  "))
+		return 1
+	}
+	printBackupSummary(b)
+	if *dryRun {
+		return 0
+	}
+
+	if err := db.RunMigrations(cfg.DatabaseURL); err != nil {
+		log.Printf("Failed to run migrations: %v", err)
+		return 1
+	}
+
+	pool, err := db.NewPool(cfg.DatabaseURL)
+	if err != nil {
+		log.Printf("Failed to create database pool: %v", err)
+		return 1
+	}
+	defer pool.Close()
+
+	store, err := storage.NewLocalStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Printf("Failed to open attachment storage: %v", err)
+		return 1
+	}
+
+	archiveRepo := db.NewArchiveRepo(pool, db.NewChainRepo(pool, chain.NewHasher(cfg.LedgerChainKey)), store)
+	warnings, err := archiveRepo.Restore(context.Background(), b)
+	if err != nil {
+		if errors.Is(err, db.ErrNotEmpty) {
+			log.Printf("Refusing to restore: the database already has users")
+			return 1
+		}
+		log.Printf("Restore failed: %v", err)
+		return 1
+	}
+	for _, w := range warnings {
+		log.Printf("Warning: %s", w)
+	}
+
+	fmt.Println("Restore complete")
+	return 0
+}
+
+func printBackupSummary(b *archive.Backup) {
+	counts := b.Counts()
+	fmt.Printf("Backup from %s\n", b.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("  users:          %d\n", len(b.Users))
+	fmt.Printf("  groups:         %d\n", len(b.Groups))
+	fmt.Printf("  members:        %d\n", counts.Members)
+	fmt.Printf("  chores:         %d\n", counts.Chores)
+	fmt.Printf("  ledger entries: %d\n", counts.LedgerEntries)
+	fmt.Printf("  photos:         %d\n", counts.Attachments)
+	fmt.Printf("  settlements:    %d\n", counts.Settlements)
+	fmt.Printf("  webhooks:       %d\n", len(b.Webhooks))
+	fmt.Printf("  devices:        %d\n", len(b.Devices))
+	fmt.Printf("  events:         %d\n", len(b.GroupEvents))
+	fmt.Printf("  chain links:    %d\n", len(b.LedgerChain))
+	fmt.Printf("  invites:        %d (not restored)\n", counts.InvitesSkipped)
+}
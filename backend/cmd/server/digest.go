@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/mail"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// digestJob emails every member the digest of the past week
+func digestJob(digest *mail.Digest) scheduler.Job {
+	return func(ctx context.Context) error {
+		sent, err := digest.Send(ctx, time.Now())
+		log.Printf("Sent %d weekly digests", sent)
+		return err
+	}
+}
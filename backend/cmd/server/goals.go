@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+)
+
+// goalsSubscriber checks a group's savings goals whenever a member's balance
+// may have grown, and announces the goals that have been reached
+func goalsSubscriber(goalRepo *db.GoalRepo, bus *events.Bus) events.Subscriber {
+	return func(ctx context.Context, e events.Event) error {
+		switch e.Type {
+		case events.LedgerCreated, events.LedgerApproved, events.SettlementUpdated, events.SettlementVoided:
+			handlers.AnnounceReachedGoals(ctx, goalRepo, bus, e.GroupID)
+		}
+		return nil
+	}
+}
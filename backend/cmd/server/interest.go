@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// interestSchedule is how often interest for ended periods is posted. Each
+// run catches up on every period missed since the last, so an hourly run is
+// enough for periods that end at midnight.
+const interestSchedule = "15 * * * *"
+
+// interestJob posts the interest earned over periods that have ended, and
+// announces each new entry as if a head had logged it, with no actor
+func interestJob(interestRepo *db.InterestRepo, bus *events.Bus) scheduler.Job {
+	return func(ctx context.Context) error {
+		entries, err := interestRepo.PostDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			bus.Publish(ctx, events.New(events.LedgerCreated, entry.GroupID, nil, entry))
+		}
+		if len(entries) > 0 {
+			log.Printf("Posted %d interest entries", len(entries))
+		}
+		return nil
+	}
+}
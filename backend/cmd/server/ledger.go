@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/config"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+)
+
+// verifyLedger checks the hash chain of the given group, or of every group
+// when none is given. It returns a non-zero exit code if any chain is broken.
+func verifyLedger(cfg *config.Config, args []string) int {
+	ctx := context.Background()
+
+	pool, err := db.NewPool(cfg.DatabaseURL)
+	if err != nil {
+		log.Printf("Failed to create database pool: %v", err)
+		return 1
+	}
+	defer pool.Close()
+
+	chainRepo := db.NewChainRepo(pool, chain.NewHasher(cfg.LedgerChainKey))
+
+	var groupIDs []uuid.UUID
+	if len(args) > 0 {
+		for _, arg := range args {
+			id, err := uuid.Parse(arg)
+			if err != nil {
+				log.Printf("Invalid group ID %q: %v", arg, err)
+				return 2
+			}
+			groupIDs = append(groupIDs, id)
+		}
+	} else {
+		groups, err := db.NewGroupRepo(pool).ListAll(ctx)
+		if err != nil {
+			log.Printf("Failed to list groups: %v", err)
+			return 1
+		}
+		for _, g := range groups {
+			groupIDs = append(groupIDs, g.ID)
+		}
+	}
+
+	exitCode := 0
+	for _, groupID := range groupIDs {
+		report, err := chainRepo.Verify(ctx, groupID)
+		if err != nil {
+			log.Printf("Failed to verify group %s: %v", groupID, err)
+			return 1
+		}
+
+		if report.Valid {
+			fmt.Printf("%s: ok (%d links, %d records)\n", groupID, report.Links, report.Records)
+			continue
+		}
+
+		exitCode = 1
+		b := report.FirstBreak
+		fmt.Printf("%s: BROKEN (%d problems)\n", groupID, len(report.Breaks))
+		fmt.Printf("  first break: seq %d, %s %s: %s\n", b.Seq, b.RecordType, b.RecordID, b.Reason)
+	}
+
+	return exitCode
+}
+
+// sealLedger chains rows that were written before the hash chain existed
+func sealLedger(cfg *config.Config) int {
+	ctx := context.Background()
+
+	if err := db.RunMigrations(cfg.DatabaseURL); err != nil {
+		log.Printf("Failed to run migrations: %v", err)
+		return 1
+	}
+
+	pool, err := db.NewPool(cfg.DatabaseURL)
+	if err != nil {
+		log.Printf("Failed to create database pool: %v", err)
+		return 1
+	}
+	defer pool.Close()
+
+	sealed, err := db.NewChainRepo(pool, chain.NewHasher(cfg.LedgerChainKey)).Seal(ctx)
+	if err != nil {
+		log.Printf("Failed to seal ledger: %v", err)
+		return 1
+	}
+
+	fmt.Printf("Sealed %d records into the ledger chain\n", sealed)
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// loansSchedule is how often weekly loan repayments are taken. Each run
+// catches up on every week missed since the last, so an hourly run is
+// enough for repayments that fall due at midnight.
+const loansSchedule = "25 * * * *"
+
+// loansJob takes the weekly loan repayments that have fallen due, and
+// announces each new entry as if a head had logged it, with no actor
+func loansJob(loanRepo *db.LoanRepo, bus *events.Bus) scheduler.Job {
+	return func(ctx context.Context) error {
+		entries, err := loanRepo.PostDue(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			bus.Publish(ctx, events.New(events.LedgerCreated, entry.GroupID, nil, entry))
+		}
+		if len(entries) > 0 {
+			log.Printf("Took %d loan repayments", len(entries))
+		}
+		return nil
+	}
+}
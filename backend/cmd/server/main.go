@@ -1,17 +1,42 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
 	"github.com/srjn45/pocket-money/backend/internal/config"
 	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
 	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/mail"
 	"github.com/srjn45/pocket-money/backend/internal/middleware"
+	"github.com/srjn45/pocket-money/backend/internal/push"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+	"github.com/srjn45/pocket-money/backend/internal/webhook"
 )
 
+const usage = `Usage: server [command]
+
+Commands:
+  serve                     Run the API server (default)
+  verify-ledger [group-id]  Verify the ledger hash chain of one or all groups
+  seal-ledger               Add rows written before the hash chain existed to it
+  backup [-dir DIR]         Write a verified backup of the whole database
+  restore [-dry-run] FILE   Restore a backup into an empty database
+`
+
+// webhookPollInterval is how often the outbox is checked for due deliveries
+const webhookPollInterval = 5 * time.Second
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -19,6 +44,33 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(cfg)
+	case "verify-ledger":
+		os.Exit(verifyLedger(cfg, args))
+	case "seal-ledger":
+		os.Exit(sealLedger(cfg))
+	case "backup":
+		os.Exit(backupCommand(cfg, args))
+	case "restore":
+		os.Exit(restoreCommand(cfg, args))
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// serve runs migrations and starts the HTTP server
+func serve(cfg *config.Config) {
 	// Run database migrations
 	if err := db.RunMigrations(cfg.DatabaseURL); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
@@ -31,20 +83,131 @@ func main() {
 	}
 	defer pool.Close()
 
+	if cfg.LedgerChainKey == "" {
+		log.Printf("Warning: LEDGER_CHAIN_KEY is not set; the ledger hash chain is unkeyed and anyone with database access can rewrite it undetected")
+	}
+
 	// Create repositories
+	chainRepo := db.NewChainRepo(pool, chain.NewHasher(cfg.LedgerChainKey))
 	userRepo := db.NewUserRepo(pool)
 	groupRepo := db.NewGroupRepo(pool)
 	choreRepo := db.NewChoreRepo(pool)
-	ledgerRepo := db.NewLedgerRepo(pool)
-	settlementRepo := db.NewSettlementRepo(pool)
+	ledgerRepo := db.NewLedgerRepo(pool, chainRepo)
+	settlementRepo := db.NewSettlementRepo(pool, chainRepo)
+	settlementCategoryRepo := db.NewSettlementCategoryRepo(pool)
+	allowanceRepo := db.NewAllowanceRepo(pool, chainRepo)
+	goalRepo := db.NewGoalRepo(pool)
+	jarRepo := db.NewJarRepo(pool)
+	interestRepo := db.NewInterestRepo(pool, chainRepo)
+	loanRepo := db.NewLoanRepo(pool, chainRepo)
 	inviteRepo := db.NewInviteRepo(pool)
+	webhookRepo := db.NewWebhookRepo(pool)
+	eventRepo := db.NewEventRepo(pool)
+	notificationRepo := db.NewNotificationRepo(pool)
+	approvalRuleRepo := db.NewApprovalRuleRepo(pool)
+	commentRepo := db.NewCommentRepo(pool)
+	attachmentRepo := db.NewAttachmentRepo(pool)
+
+	attachmentStore, err := storage.NewLocalStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Fatalf("Failed to open attachment storage: %v", err)
+	}
+	archiveRepo := db.NewArchiveRepo(pool, chainRepo, attachmentStore)
+
+	var pushProvider push.Provider = push.NewLocalProvider()
+	if cfg.Push.Provider == "expo" {
+		pushProvider = push.NewExpoProvider(push.ExpoPushURL, cfg.Push.ExpoAccessToken)
+	}
+
+	mailTemplates, err := mail.LoadTemplates()
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+	mailLinks := mail.NewLinks(cfg.Mail.PublicURL, cfg.JWTSecret)
+	var mailer mail.Mailer = mail.NewLocalMailer()
+	if cfg.Mail.Enabled() {
+		mailer = mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	}
+
+	// Fan out events to the event log, connected clients, webhook subscriptions,
+	// push notifications and email, and check savings goals
+	broadcaster := events.NewBroadcaster()
+	bus := events.NewBus()
+	bus.Subscribe("stream", func(ctx context.Context, e events.Event) error {
+		rec, err := eventRepo.Append(ctx, e)
+		if err != nil {
+			return err
+		}
+		broadcaster.Publish(rec)
+		return nil
+	})
+	bus.Subscribe("webhooks", func(ctx context.Context, e events.Event) error {
+		_, err := webhookRepo.Enqueue(ctx, e)
+		return err
+	})
+	pushDispatcher := push.NewDispatcher(notificationRepo, pushProvider)
+	bus.Subscribe("push", pushDispatcher.Subscriber())
+	mailNotifier := mail.NewNotifier(notificationRepo, mailer, mailTemplates, mailLinks)
+	bus.Subscribe("email", mailNotifier.Subscriber())
+	bus.Subscribe("goals", goalsSubscriber(goalRepo, bus))
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(userRepo, cfg.JWTSecret)
-	groupHandler := handlers.NewGroupHandler(groupRepo, inviteRepo)
-	choreHandler := handlers.NewChoreHandler(choreRepo, groupRepo)
-	ledgerHandler := handlers.NewLedgerHandler(ledgerRepo, groupRepo, choreRepo)
-	settlementHandler := handlers.NewSettlementHandler(settlementRepo, groupRepo)
+	groupHandler := handlers.NewGroupHandler(groupRepo, inviteRepo, bus)
+	choreHandler := handlers.NewChoreHandler(choreRepo, groupRepo, bus)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerRepo, groupRepo, choreRepo, chainRepo, bus)
+	approvalRuleHandler := handlers.NewApprovalRuleHandler(approvalRuleRepo, groupRepo, choreRepo)
+	commentHandler := handlers.NewCommentHandler(commentRepo, ledgerRepo, groupRepo, bus)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentRepo, ledgerRepo, groupRepo, attachmentStore, int64(cfg.Attachments.MaxBytes))
+	settlementHandler := handlers.NewSettlementHandler(settlementRepo, groupRepo, bus)
+	settlementCategoryHandler := handlers.NewSettlementCategoryHandler(settlementCategoryRepo, groupRepo)
+	allowanceHandler := handlers.NewAllowanceHandler(allowanceRepo, groupRepo, bus)
+	goalHandler := handlers.NewGoalHandler(goalRepo, ledgerRepo, groupRepo, attachmentStore, int64(cfg.Attachments.MaxBytes), bus)
+	jarHandler := handlers.NewJarHandler(jarRepo, ledgerRepo, groupRepo)
+	interestHandler := handlers.NewInterestHandler(interestRepo, groupRepo)
+	loanHandler := handlers.NewLoanHandler(loanRepo, groupRepo, bus)
+	statementHandler := handlers.NewStatementHandler(ledgerRepo, settlementRepo, settlementCategoryRepo, groupRepo, choreRepo, userRepo)
+	archiveHandler := handlers.NewArchiveHandler(archiveRepo, groupRepo, userRepo, cfg.AdminEmails)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, groupRepo)
+	eventsHandler := handlers.NewEventsHandler(eventRepo, groupRepo, broadcaster)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo, groupRepo, mailLinks, mailTemplates.Locales())
+
+	// Start delivering queued webhooks
+	go webhook.NewDispatcher(webhookRepo).Run(context.Background(), webhookPollInterval)
+
+	// Start scheduled jobs; with several server instances each run happens on one of them
+	jobs := scheduler.NewRunner(db.NewJobRepo(pool))
+	if cfg.Backup.Schedule != "" {
+		log.Printf("Scheduled backups to %s at %q", cfg.Backup.Dir, cfg.Backup.Schedule)
+		jobs.Add("backup", mustParseSchedule(cfg.Backup.Schedule), backupJob(archiveRepo, cfg.Backup))
+	}
+	if cfg.Mail.Enabled() && cfg.Mail.DigestSchedule != "off" {
+		log.Printf("Scheduled weekly digest at %q", cfg.Mail.DigestSchedule)
+		digest := mail.NewDigest(notificationRepo, ledgerRepo, mailer, mailTemplates, mailLinks)
+		jobs.Add("digest", mustParseSchedule(cfg.Mail.DigestSchedule), digestJob(digest))
+	}
+	if cfg.Reminders.Schedule != "off" {
+		after := time.Duration(cfg.Reminders.PendingAfterHours) * time.Hour
+		jobs.Add("pending-reminders", mustParseSchedule(cfg.Reminders.Schedule),
+			reminders.PendingJob(notificationRepo, after, pushDispatcher, mailNotifier))
+		before := time.Duration(cfg.Reminders.ChoreDueHours) * time.Hour
+		jobs.Add("chore-reminders", mustParseSchedule(cfg.Reminders.Schedule),
+			reminders.ChoreDueJob(notificationRepo, before, pushDispatcher, mailNotifier))
+		every := time.Duration(cfg.Reminders.ChoreOverdueHours) * time.Hour
+		jobs.Add("overdue-chores", mustParseSchedule(cfg.Reminders.Schedule),
+			reminders.ChoreOverdueJob(notificationRepo, every, pushDispatcher, mailNotifier))
+	}
+	jobs.Add("pending-policies", mustParseSchedule(pendingPoliciesSchedule), pendingPoliciesJob(ledgerRepo, bus))
+	jobs.Add("allowances", mustParseSchedule(allowancesSchedule), allowancesJob(allowanceRepo, bus))
+	jobs.Add("interest", mustParseSchedule(interestSchedule), interestJob(interestRepo, bus))
+	jobs.Add("loans", mustParseSchedule(loansSchedule), loansJob(loanRepo, bus))
+	jobs.Start(context.Background())
 
 	// Setup router
 	router := gin.Default()
@@ -62,18 +225,29 @@ func main() {
 		v1.POST("/auth/register", authHandler.Register)
 		v1.POST("/auth/login", authHandler.Login)
 
+		// Email opt-out links (authenticated by their signed token)
+		v1.GET("/email/unsubscribe", notificationHandler.Unsubscribe)
+		v1.POST("/email/unsubscribe", notificationHandler.Unsubscribe)
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(auth.AuthMiddleware(cfg.JWTSecret))
 		{
 			// Auth routes
 			protected.GET("/auth/me", authHandler.Me)
+			protected.GET("/auth/me/devices", notificationHandler.ListDevices)
+			protected.POST("/auth/me/devices", notificationHandler.RegisterDevice)
+			protected.DELETE("/auth/me/devices/:id", notificationHandler.DeleteDevice)
+			protected.GET("/auth/me/notifications", notificationHandler.GetNotificationSettings)
+			protected.PATCH("/auth/me/notifications", notificationHandler.UpdateNotificationSettings)
 
 			// Group routes
 			protected.POST("/groups", groupHandler.CreateGroup)
 			protected.GET("/groups", groupHandler.ListGroups)
 			protected.GET("/groups/:id", groupHandler.GetGroup)
+			protected.PATCH("/groups/:id", groupHandler.UpdateGroup)
 			protected.GET("/groups/:id/members", groupHandler.ListMembers)
+			protected.GET("/groups/:id/events", eventsHandler.StreamEvents)
 			protected.POST("/groups/:id/invite", groupHandler.CreateInvite)
 			protected.POST("/groups/join", groupHandler.JoinGroup)
 
@@ -88,12 +262,92 @@ func main() {
 			protected.POST("/groups/:id/ledger", ledgerHandler.CreateLedger)
 			protected.POST("/ledger/:id/approve", ledgerHandler.ApproveLedger)
 			protected.POST("/ledger/:id/reject", ledgerHandler.RejectLedger)
+			protected.POST("/ledger/:id/resubmit", ledgerHandler.ResubmitLedger)
+			protected.GET("/ledger/:id/comments", commentHandler.ListComments)
+			protected.POST("/ledger/:id/comments", commentHandler.CreateComment)
+			protected.GET("/ledger/:id/attachments", attachmentHandler.ListAttachments)
+			protected.POST("/ledger/:id/attachments", attachmentHandler.UploadAttachment)
+			protected.GET("/ledger/:id/attachments/:attachment_id", attachmentHandler.GetAttachment)
 			protected.GET("/groups/:id/pending", ledgerHandler.ListPending)
+			protected.POST("/groups/:id/pending/approve", ledgerHandler.ApprovePending)
+			protected.POST("/groups/:id/pending/reject", ledgerHandler.RejectPending)
 			protected.GET("/groups/:id/balance", ledgerHandler.GetBalance)
+			protected.GET("/groups/:id/members/:user_id/balance/history", ledgerHandler.GetBalanceHistory)
+			protected.GET("/groups/:id/ledger/verify", ledgerHandler.VerifyLedger)
+
+			// Approval rule routes
+			protected.GET("/groups/:id/approval-rules", approvalRuleHandler.ListApprovalRules)
+			protected.POST("/groups/:id/approval-rules", approvalRuleHandler.CreateApprovalRule)
+			protected.DELETE("/groups/:id/approval-rules/:rule_id", approvalRuleHandler.DeleteApprovalRule)
 
 			// Settlement routes
 			protected.GET("/groups/:id/settlements", settlementHandler.ListSettlements)
 			protected.POST("/groups/:id/settlements", settlementHandler.CreateSettlement)
+			protected.GET("/groups/:id/settlements/summary", settlementHandler.SettlementSummary)
+			protected.PATCH("/settlements/:id", settlementHandler.UpdateSettlement)
+			protected.POST("/settlements/:id/void", settlementHandler.VoidSettlement)
+			protected.POST("/settlements/:id/ack", settlementHandler.AcknowledgeSettlement)
+			protected.POST("/settlements/:id/dispute", settlementHandler.DisputeSettlement)
+			protected.POST("/settlements/:id/resolve", settlementHandler.ResolveSettlement)
+			protected.GET("/settlements/:id/revisions", settlementHandler.ListSettlementRevisions)
+
+			// Settlement category routes
+			protected.GET("/groups/:id/settlement-categories", settlementCategoryHandler.ListSettlementCategories)
+			protected.POST("/groups/:id/settlement-categories", settlementCategoryHandler.CreateSettlementCategory)
+			protected.PATCH("/groups/:id/settlement-categories/:category_id", settlementCategoryHandler.RenameSettlementCategory)
+			protected.DELETE("/groups/:id/settlement-categories/:category_id", settlementCategoryHandler.DeleteSettlementCategory)
+
+			// Allowance routes
+			protected.GET("/groups/:id/allowances", allowanceHandler.ListAllowances)
+			protected.POST("/groups/:id/allowances", allowanceHandler.CreateAllowance)
+			protected.PATCH("/groups/:id/allowances/:allowance_id", allowanceHandler.UpdateAllowance)
+			protected.POST("/groups/:id/allowances/:allowance_id/pause", allowanceHandler.PauseAllowance)
+			protected.POST("/groups/:id/allowances/:allowance_id/resume", allowanceHandler.ResumeAllowance)
+			protected.DELETE("/groups/:id/allowances/:allowance_id", allowanceHandler.DeleteAllowance)
+
+			// Savings goal routes
+			protected.GET("/groups/:id/members/:user_id/goals", goalHandler.ListGoals)
+			protected.POST("/groups/:id/members/:user_id/goals", goalHandler.CreateGoal)
+			protected.GET("/groups/:id/members/:user_id/goals/:goal_id", goalHandler.GetGoal)
+			protected.PATCH("/groups/:id/members/:user_id/goals/:goal_id", goalHandler.UpdateGoal)
+			protected.DELETE("/groups/:id/members/:user_id/goals/:goal_id", goalHandler.DeleteGoal)
+			protected.GET("/groups/:id/members/:user_id/goals/:goal_id/allocations", goalHandler.ListGoalAllocations)
+			protected.POST("/groups/:id/members/:user_id/goals/:goal_id/allocations", goalHandler.AllocateGoal)
+			protected.GET("/groups/:id/members/:user_id/goals/:goal_id/image", goalHandler.GetGoalImage)
+			protected.PUT("/groups/:id/members/:user_id/goals/:goal_id/image", goalHandler.PutGoalImage)
+			protected.DELETE("/groups/:id/members/:user_id/goals/:goal_id/image", goalHandler.DeleteGoalImage)
+
+			// Jar routes
+			protected.GET("/groups/:id/members/:user_id/jars", jarHandler.ListJars)
+			protected.PUT("/groups/:id/members/:user_id/jars", jarHandler.SetJars)
+			protected.GET("/groups/:id/members/:user_id/jars/transfers", jarHandler.ListJarTransfers)
+			protected.POST("/groups/:id/members/:user_id/jars/transfers", jarHandler.TransferBetweenJars)
+
+			// Interest routes
+			protected.GET("/groups/:id/interest-rates", interestHandler.ListInterestRates)
+			protected.POST("/groups/:id/interest-rates", interestHandler.CreateInterestRate)
+			protected.PATCH("/groups/:id/interest-rates/:rate_id", interestHandler.UpdateInterestRate)
+			protected.DELETE("/groups/:id/interest-rates/:rate_id", interestHandler.DeleteInterestRate)
+			protected.GET("/groups/:id/interest/postings", interestHandler.ListInterestPostings)
+
+			// Loan routes
+			protected.GET("/groups/:id/loans", loanHandler.ListLoans)
+			protected.POST("/groups/:id/loans", loanHandler.CreateLoan)
+			protected.GET("/groups/:id/loans/:loan_id", loanHandler.GetLoan)
+
+			// Statement routes
+			protected.GET("/groups/:id/members/:user_id/statement", statementHandler.GetStatement)
+
+			// Export and import routes
+			protected.GET("/groups/:id/export", archiveHandler.ExportGroup)
+			protected.POST("/groups/import", archiveHandler.ImportGroup)
+
+			// Webhook routes
+			protected.GET("/groups/:id/webhooks", webhookHandler.ListWebhooks)
+			protected.POST("/groups/:id/webhooks", webhookHandler.CreateWebhook)
+			protected.PATCH("/webhooks/:id", webhookHandler.UpdateWebhook)
+			protected.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
 		}
 	}
 
@@ -104,3 +358,12 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// mustParseSchedule parses a cron expression already checked by config
+func mustParseSchedule(expr string) *scheduler.Schedule {
+	schedule, err := scheduler.Parse(expr)
+	if err != nil {
+		log.Fatalf("Invalid schedule %q: %v", expr, err)
+	}
+	return schedule
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// pendingPoliciesSchedule is how often group pending entry policies are applied
+const pendingPoliciesSchedule = "*/15 * * * *"
+
+// pendingPoliciesJob auto-approves and expires stale pending entries, and
+// announces each change as if a head had made it, with no actor
+func pendingPoliciesJob(ledgerRepo *db.LedgerRepo, bus *events.Bus) scheduler.Job {
+	return func(ctx context.Context) error {
+		entries, err := ledgerRepo.ApplyPendingPolicies(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			t := events.LedgerRejected
+			if entry.Status == models.StatusApproved {
+				t = events.LedgerApproved
+			}
+			bus.Publish(ctx, events.New(t, entry.GroupID, nil, entry))
+		}
+		if len(entries) > 0 {
+			log.Printf("Applied pending entry policies to %d entries", len(entries))
+		}
+		return nil
+	}
+}
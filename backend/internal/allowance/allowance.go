@@ -0,0 +1,90 @@
+// Package allowance works out when recurring allowances fall due and how
+// much each period pays.
+package allowance
+
+import (
+	"math"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// Day returns midnight UTC on t's UTC date
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// PeriodStart returns the start of the nth period, counting from 0, of an
+// allowance that started on start. Monthly periods fall on the start's day
+// of the month, or on the last day of months too short for it.
+func PeriodStart(start time.Time, interval models.AllowanceInterval, n int) time.Time {
+	start = Day(start)
+	if interval != models.IntervalMonth {
+		return start.AddDate(0, 0, 7*n)
+	}
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(start.Day(), last)-1)
+}
+
+// Next returns the start of the first period beginning on or after day
+func Next(start time.Time, interval models.AllowanceInterval, day time.Time) time.Time {
+	start, day = Day(start), Day(day)
+	if !day.After(start) {
+		return start
+	}
+
+	// Start from a period at or just before day and step forward
+	var n int
+	if interval == models.IntervalMonth {
+		n = (day.Year()-start.Year())*12 + int(day.Month()-start.Month()) - 1
+	} else {
+		n = int(day.Sub(start).Hours()/24) / 7
+	}
+	p := PeriodStart(start, interval, n)
+	for p.Before(day) {
+		n++
+		p = PeriodStart(start, interval, n)
+	}
+	return p
+}
+
+// Due returns the starts of the allowance's periods that have begun by now
+// and are not yet credited, oldest first. Nothing is due while it is paused
+// or once it is deleted.
+func Due(a *models.Allowance, now time.Time) []time.Time {
+	if a.PausedAt != nil || a.DeletedAt != nil {
+		return nil
+	}
+
+	today := Day(now)
+	var due []time.Time
+	for p := Next(a.StartDate, a.Interval, a.NextPeriod); !p.After(today); p = Next(a.StartDate, a.Interval, p.AddDate(0, 0, 1)) {
+		due = append(due, p)
+	}
+	return due
+}
+
+// Age returns how many whole years old someone born on dob is on day
+func Age(dob, day time.Time) int {
+	dob, day = Day(dob), Day(day)
+	age := day.Year() - dob.Year()
+	if day.Month() < dob.Month() || (day.Month() == dob.Month() && day.Day() < dob.Day()) {
+		age--
+	}
+	return max(age, 0)
+}
+
+// Amount returns what the allowance pays for the period starting on
+// period. An allowance with an amount per year of age pays that times the
+// member's age at the start of the period, once they are at least a year
+// old and their dob is known; otherwise it pays its fixed amount.
+func Amount(a *models.Allowance, dob *time.Time, period time.Time) float64 {
+	if a.AmountPerYear != nil && dob != nil {
+		if age := Age(*dob, period); age > 0 {
+			return math.Round(float64(age)**a.AmountPerYear*100) / 100
+		}
+	}
+	return a.Amount
+}
@@ -0,0 +1,83 @@
+package allowance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodStart(t *testing.T) {
+	start := date(2026, 1, 31)
+	assert.Equal(t, start, PeriodStart(start, models.IntervalWeek, 0))
+	assert.Equal(t, date(2026, 2, 14), PeriodStart(start, models.IntervalWeek, 2))
+
+	// Short months fall on their last day; later months go back to the 31st
+	assert.Equal(t, date(2026, 2, 28), PeriodStart(start, models.IntervalMonth, 1))
+	assert.Equal(t, date(2026, 3, 31), PeriodStart(start, models.IntervalMonth, 2))
+	assert.Equal(t, date(2026, 4, 30), PeriodStart(start, models.IntervalMonth, 3))
+	assert.Equal(t, date(2027, 1, 31), PeriodStart(start, models.IntervalMonth, 12))
+}
+
+func TestNext(t *testing.T) {
+	start := date(2026, 9, 7)
+	assert.Equal(t, start, Next(start, models.IntervalWeek, date(2026, 9, 1)))
+	assert.Equal(t, start, Next(start, models.IntervalWeek, start))
+	assert.Equal(t, date(2026, 9, 14), Next(start, models.IntervalWeek, date(2026, 9, 8)))
+	assert.Equal(t, date(2026, 9, 14), Next(start, models.IntervalWeek, time.Date(2026, 9, 14, 23, 0, 0, 0, time.UTC)))
+
+	start = date(2026, 1, 31)
+	assert.Equal(t, date(2026, 2, 28), Next(start, models.IntervalMonth, date(2026, 2, 1)))
+	assert.Equal(t, date(2026, 3, 31), Next(start, models.IntervalMonth, date(2026, 3, 1)))
+	assert.Equal(t, date(2026, 4, 30), Next(start, models.IntervalMonth, date(2026, 4, 1)))
+}
+
+func TestDue(t *testing.T) {
+	a := &models.Allowance{
+		Amount:     5,
+		Interval:   models.IntervalWeek,
+		StartDate:  date(2026, 9, 7),
+		NextPeriod: date(2026, 9, 14),
+	}
+
+	assert.Empty(t, Due(a, date(2026, 9, 13)))
+	assert.Equal(t, []time.Time{date(2026, 9, 14)}, Due(a, time.Date(2026, 9, 14, 0, 5, 0, 0, time.UTC)))
+
+	// After downtime every missed period is due
+	assert.Equal(t, []time.Time{date(2026, 9, 14), date(2026, 9, 21), date(2026, 9, 28)}, Due(a, date(2026, 10, 3)))
+
+	paused := date(2026, 9, 20)
+	a.PausedAt = &paused
+	assert.Empty(t, Due(a, date(2026, 10, 3)))
+}
+
+func TestAge(t *testing.T) {
+	dob := date(2016, 10, 18)
+	assert.Equal(t, 9, Age(dob, date(2026, 10, 17)))
+	assert.Equal(t, 10, Age(dob, date(2026, 10, 18)))
+	assert.Equal(t, 0, Age(dob, date(2015, 1, 1)))
+
+	leap := date(2016, 2, 29)
+	assert.Equal(t, 9, Age(leap, date(2026, 2, 28)))
+	assert.Equal(t, 10, Age(leap, date(2026, 3, 1)))
+}
+
+func TestAmount(t *testing.T) {
+	perYear := 0.75
+	a := &models.Allowance{Amount: 5, AmountPerYear: &perYear}
+	dob := date(2016, 10, 18)
+
+	assert.Equal(t, 6.75, Amount(a, &dob, date(2026, 10, 17)))
+	assert.Equal(t, 7.5, Amount(a, &dob, date(2026, 10, 18)))
+	assert.Equal(t, 5.0, Amount(a, nil, date(2026, 10, 18)), "no dob falls back to the fixed amount")
+	assert.Equal(t, 5.0, Amount(a, &dob, date(2017, 1, 1)), "under a year old falls back to the fixed amount")
+
+	a.AmountPerYear = nil
+	assert.Equal(t, 5.0, Amount(a, &dob, date(2026, 10, 18)))
+}
@@ -0,0 +1,867 @@
+// Package archive defines the versioned group export format and its validation.
+package archive
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+)
+
+// FormatVersion is the archive format written by this server
+const FormatVersion = 1
+
+// Archive is a self-contained snapshot of one group
+type Archive struct {
+	FormatVersion int            `json:"format_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Group         Group          `json:"group"`
+	Members       []Member       `json:"members"`
+	Chores        []Chore        `json:"chores"`
+	ApprovalRules []ApprovalRule `json:"approval_rules,omitempty"`
+	Allowances    []Allowance    `json:"allowances,omitempty"`
+	Loans         []Loan         `json:"loans,omitempty"`
+	LedgerEntries []LedgerEntry  `json:"ledger_entries"`
+	Comments      []Comment      `json:"comments,omitempty"`
+	Attachments   []Attachment   `json:"attachments,omitempty"`
+	// Jars are kept when deleted so the splits, transfers and settlements that name them still can
+	Jars         []Jar         `json:"jars,omitempty"`
+	JarTransfers []JarTransfer `json:"jar_transfers,omitempty"`
+	// Interest rates are kept when deleted so the entries they posted still name them
+	InterestRates []InterestRate `json:"interest_rates,omitempty"`
+	// Categories are kept when deleted so the settlements in them still name them
+	SettlementCategories []SettlementCategory `json:"settlement_categories,omitempty"`
+	Settlements          []Settlement         `json:"settlements"`
+	Goals                []Goal               `json:"goals,omitempty"`
+	Invites              []Invite             `json:"invites"`
+	// Files holds the content of the uploaded files the archive refers to,
+	// by storage key. Only ZIP archives carry them.
+	Files map[string][]byte `json:"-"`
+}
+
+// File describes an uploaded file kept outside the database. Its content is
+// in Archive.Files under StorageKey.
+type File struct {
+	StorageKey  string `json:"storage_key"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+// FileKeys returns the storage key of every file the archive refers to
+func (a *Archive) FileKeys() []string {
+	keys := make([]string, 0, len(a.Attachments))
+	for _, at := range a.Attachments {
+		keys = append(keys, at.StorageKey)
+	}
+	for _, g := range a.Goals {
+		if g.Image != nil {
+			keys = append(keys, g.Image.StorageKey)
+		}
+	}
+	return keys
+}
+
+// Group is the exported group row
+type Group struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	HeadUserID      uuid.UUID `json:"head_user_id"`
+	MaxBackdateDays *int      `json:"max_backdate_days"`
+	models.PendingPolicy
+	RequireRejectReason bool      `json:"require_reject_reason"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Member is an exported membership; users are matched by email on import
+type Member struct {
+	UserID   uuid.UUID         `json:"user_id"`
+	Email    string            `json:"email"`
+	Name     string            `json:"name"`
+	Role     models.MemberRole `json:"role"`
+	JoinedAt time.Time         `json:"joined_at"`
+}
+
+// Chore is an exported chore, kept when archived so its entries still name it
+type Chore struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Description    *string    `json:"description,omitempty"`
+	Amount         float64    `json:"amount"`
+	ProofRequired  bool       `json:"proof_required"`
+	AssigneeUserID *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueAt          *time.Time `json:"due_at,omitempty"`
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ApprovalRule is an exported approval rule, kept when deleted so the
+// entries it approved still name it
+type ApprovalRule struct {
+	ID              uuid.UUID  `json:"id"`
+	ChoreID         *uuid.UUID `json:"chore_id,omitempty"`
+	UserID          *uuid.UUID `json:"user_id,omitempty"`
+	MaxPerDay       *int       `json:"max_per_day,omitempty"`
+	CreatedByUserID uuid.UUID  `json:"created_by_user_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Allowance is an exported allowance, kept when deleted so the entries it
+// credited still name it; dates are YYYY-MM-DD
+type Allowance struct {
+	ID              uuid.UUID                `json:"id"`
+	UserID          uuid.UUID                `json:"user_id"`
+	Amount          float64                  `json:"amount"`
+	AmountPerYear   *float64                 `json:"amount_per_year,omitempty"`
+	Interval        models.AllowanceInterval `json:"interval"`
+	StartDate       string                   `json:"start_date"`
+	NextPeriod      string                   `json:"next_period"`
+	PausedAt        *time.Time               `json:"paused_at,omitempty"`
+	CreatedByUserID uuid.UUID                `json:"created_by_user_id"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+	DeletedAt       *time.Time               `json:"deleted_at,omitempty"`
+}
+
+// Loan is an exported loan; dates are YYYY-MM-DD
+type Loan struct {
+	ID              uuid.UUID            `json:"id"`
+	UserID          uuid.UUID            `json:"user_id"`
+	Principal       float64              `json:"principal"`
+	InterestPercent float64              `json:"interest_percent"`
+	TotalDue        float64              `json:"total_due"`
+	RepaymentPlan   models.RepaymentPlan `json:"repayment_plan"`
+	WeeklyAmount    *float64             `json:"weekly_amount,omitempty"`
+	WithholdPercent *float64             `json:"withhold_percent,omitempty"`
+	StartDate       string               `json:"start_date"`
+	NextRepayment   string               `json:"next_repayment,omitempty"`
+	Note            *string              `json:"note,omitempty"`
+	RepaidAt        *time.Time           `json:"repaid_at,omitempty"`
+	CreatedByUserID uuid.UUID            `json:"created_by_user_id"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// LedgerEntry is an exported ledger entry; AllowancePeriod is YYYY-MM-DD
+type LedgerEntry struct {
+	ID                uuid.UUID           `json:"id"`
+	UserID            uuid.UUID           `json:"user_id"`
+	Kind              models.EntryKind    `json:"kind,omitempty"` // Archives from before allowances omit it; imported as chore
+	ChoreID           *uuid.UUID          `json:"chore_id,omitempty"`
+	AllowanceID       *uuid.UUID          `json:"allowance_id,omitempty"`
+	AllowancePeriod   string              `json:"allowance_period,omitempty"`
+	InterestRateID    *uuid.UUID          `json:"interest_rate_id,omitempty"`
+	LoanID            *uuid.UUID          `json:"loan_id,omitempty"`
+	WithheldFromID    *uuid.UUID          `json:"withheld_from_id,omitempty"`
+	Amount            float64             `json:"amount"`
+	Status            models.LedgerStatus `json:"status"`
+	CreatedByUserID   uuid.UUID           `json:"created_by_user_id"`
+	ApprovedByUserID  *uuid.UUID          `json:"approved_by_user_id,omitempty"`
+	RejectedByUserID  *uuid.UUID          `json:"rejected_by_user_id,omitempty"`
+	StatusReason      *string             `json:"status_reason,omitempty"`
+	SystemActor       *models.SystemActor `json:"system_actor,omitempty"`
+	ApprovalRuleID    *uuid.UUID          `json:"approval_rule_id,omitempty"`
+	ResubmittedFromID *uuid.UUID          `json:"resubmitted_from_id,omitempty"`
+	OccurredAt        time.Time           `json:"occurred_at"`
+	CreatedAt         time.Time           `json:"created_at"`
+}
+
+// Comment is an exported comment on a ledger entry
+type Comment struct {
+	ID        uuid.UUID `json:"id"`
+	EntryID   uuid.UUID `json:"entry_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment is an exported photo on a ledger entry
+type Attachment struct {
+	ID               uuid.UUID `json:"id"`
+	EntryID          uuid.UUID `json:"entry_id"`
+	UploadedByUserID uuid.UUID `json:"uploaded_by_user_id"`
+	File
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Jar is an exported jar with the parts of approved entries that went into it
+type Jar struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Name         string     `json:"name"`
+	SplitPercent float64    `json:"split_percent"`
+	Position     int        `json:"position"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Splits       []JarSplit `json:"splits,omitempty"`
+}
+
+// JarSplit is the exported part of a ledger entry that went into a jar
+type JarSplit struct {
+	EntryID uuid.UUID `json:"entry_id"`
+	Amount  float64   `json:"amount"`
+}
+
+// JarTransfer is an exported transfer between two of a member's jars
+type JarTransfer struct {
+	ID              uuid.UUID `json:"id"`
+	UserID          uuid.UUID `json:"user_id"`
+	FromJarID       uuid.UUID `json:"from_jar_id"`
+	ToJarID         uuid.UUID `json:"to_jar_id"`
+	Amount          float64   `json:"amount"`
+	Note            *string   `json:"note,omitempty"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// InterestRate is an exported interest rate with the interest it posted;
+// dates are YYYY-MM-DD
+type InterestRate struct {
+	ID              uuid.UUID                `json:"id"`
+	JarID           *uuid.UUID               `json:"jar_id,omitempty"`
+	APR             float64                  `json:"apr"`
+	Compounding     models.AllowanceInterval `json:"compounding"`
+	MinBalance      float64                  `json:"min_balance"`
+	StartDate       string                   `json:"start_date"`
+	NextPeriod      string                   `json:"next_period"`
+	CreatedByUserID uuid.UUID                `json:"created_by_user_id"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+	DeletedAt       *time.Time               `json:"deleted_at,omitempty"`
+	Postings        []InterestPosting        `json:"postings,omitempty"`
+}
+
+// InterestPosting is the exported working of one interest entry;
+// PeriodStart is YYYY-MM-DD
+type InterestPosting struct {
+	ID          uuid.UUID                `json:"id"`
+	EntryID     uuid.UUID                `json:"entry_id"`
+	UserID      uuid.UUID                `json:"user_id"`
+	PeriodStart string                   `json:"period_start"`
+	Amount      float64                  `json:"amount"`
+	Breakdown   models.InterestBreakdown `json:"breakdown"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+// SettlementCategory is an exported settlement category
+type SettlementCategory struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Settlement is an exported settlement; Date is YYYY-MM-DD
+type Settlement struct {
+	ID                uuid.UUID                `json:"id"`
+	UserID            uuid.UUID                `json:"user_id"`
+	Amount            float64                  `json:"amount"`
+	Date              string                   `json:"date"`
+	Note              *string                  `json:"note,omitempty"`
+	Method            models.SettlementMethod  `json:"method,omitempty"` // Archives from before payout methods omit it; imported as cash
+	ExternalReference *string                  `json:"external_reference,omitempty"`
+	CategoryID        *uuid.UUID               `json:"category_id,omitempty"`
+	JarID             *uuid.UUID               `json:"jar_id,omitempty"`
+	Status            models.SettlementStatus  `json:"status,omitempty"` // Archives from before acknowledgement omit it; imported as acknowledged
+	AcknowledgedAt    *time.Time               `json:"acknowledged_at,omitempty"`
+	DisputedAt        *time.Time               `json:"disputed_at,omitempty"`
+	DisputeReason     *string                  `json:"dispute_reason,omitempty"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         *time.Time               `json:"updated_at,omitempty"`
+	VoidedAt          *time.Time               `json:"voided_at,omitempty"`
+	VoidedByUserID    *uuid.UUID               `json:"voided_by_user_id,omitempty"`
+	VoidReason        *string                  `json:"void_reason,omitempty"`
+	Entries           []models.SettlementEntry `json:"entries,omitempty"`
+	Revisions         []SettlementRevision     `json:"revisions,omitempty"`
+}
+
+// SettlementRevision is an exported edit or void of a settlement; dates
+// are YYYY-MM-DD
+type SettlementRevision struct {
+	ID                      uuid.UUID                `json:"id"`
+	Action                  models.SettlementAction  `json:"action"`
+	ChangedByUserID         *uuid.UUID               `json:"changed_by_user_id,omitempty"`
+	Reason                  string                   `json:"reason"`
+	AmountBefore            float64                  `json:"amount_before"`
+	AmountAfter             float64                  `json:"amount_after"`
+	DateBefore              string                   `json:"date_before"`
+	DateAfter               string                   `json:"date_after"`
+	NoteBefore              *string                  `json:"note_before,omitempty"`
+	NoteAfter               *string                  `json:"note_after,omitempty"`
+	MethodBefore            *models.SettlementMethod `json:"method_before,omitempty"`
+	MethodAfter             *models.SettlementMethod `json:"method_after,omitempty"`
+	ExternalReferenceBefore *string                  `json:"external_reference_before,omitempty"`
+	ExternalReferenceAfter  *string                  `json:"external_reference_after,omitempty"`
+	CategoryIDBefore        *uuid.UUID               `json:"category_id_before,omitempty"`
+	CategoryIDAfter         *uuid.UUID               `json:"category_id_after,omitempty"`
+	CreatedAt               time.Time                `json:"created_at"`
+}
+
+// Goal is an exported savings goal that has not been deleted; Deadline is
+// YYYY-MM-DD
+type Goal struct {
+	ID              uuid.UUID         `json:"id"`
+	UserID          uuid.UUID         `json:"user_id"`
+	Name            string            `json:"name"`
+	TargetAmount    float64           `json:"target_amount"`
+	Deadline        string            `json:"deadline,omitempty"`
+	ProgressSource  models.GoalSource `json:"progress_source"`
+	ReachedAt       *time.Time        `json:"reached_at,omitempty"`
+	CreatedByUserID uuid.UUID         `json:"created_by_user_id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Image           *File             `json:"image,omitempty"`
+	Allocations     []GoalAllocation  `json:"allocations,omitempty"`
+}
+
+// GoalAllocation is an exported allocation to a goal
+type GoalAllocation struct {
+	ID              uuid.UUID `json:"id"`
+	Amount          float64   `json:"amount"`
+	Note            *string   `json:"note,omitempty"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Invite is exported invite metadata; tokens are never exported
+type Invite struct {
+	ID        uuid.UUID `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the archive's version and internal references and returns
+// every problem found, so a dry run can report them all at once
+func (a *Archive) Validate() []string {
+	var errs []string
+	addf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if a.FormatVersion != FormatVersion {
+		addf("unsupported format_version %d, expected %d", a.FormatVersion, FormatVersion)
+		return errs
+	}
+	if strings.TrimSpace(a.Group.Name) == "" {
+		addf("group name is required")
+	}
+
+	members := make(map[uuid.UUID]bool, len(a.Members))
+	emails := make(map[string]bool, len(a.Members))
+	heads := 0
+	for i, m := range a.Members {
+		if members[m.UserID] {
+			addf("members[%d]: duplicate user_id %s", i, m.UserID)
+		}
+		members[m.UserID] = true
+
+		email := NormalizeEmail(m.Email)
+		if email == "" {
+			addf("members[%d]: email is required", i)
+		} else if emails[email] {
+			addf("members[%d]: duplicate email %s", i, m.Email)
+		}
+		emails[email] = true
+
+		switch m.Role {
+		case models.RoleHead:
+			heads++
+		case models.RoleMember:
+		default:
+			addf("members[%d]: invalid role %q", i, m.Role)
+		}
+	}
+	if !members[a.Group.HeadUserID] {
+		addf("group head %s is not a member", a.Group.HeadUserID)
+	}
+	if heads == 0 {
+		addf("group has no head member")
+	}
+	if (a.Group.AutoApproveAfterHours == nil) != (a.Group.AutoApproveMaxAmount == nil) {
+		addf("group auto_approve_after_hours and auto_approve_max_amount must be set together")
+	}
+
+	chores := make(map[uuid.UUID]bool, len(a.Chores))
+	for i, c := range a.Chores {
+		if chores[c.ID] {
+			addf("chores[%d]: duplicate id %s", i, c.ID)
+		}
+		chores[c.ID] = true
+		if strings.TrimSpace(c.Name) == "" {
+			addf("chores[%d]: name is required", i)
+		}
+		if c.Amount < 0 {
+			addf("chores[%d]: amount cannot be negative", i)
+		}
+		if c.AssigneeUserID != nil && !members[*c.AssigneeUserID] {
+			addf("chores[%d]: assignee %s is not a member", i, *c.AssigneeUserID)
+		}
+		if c.DueAt != nil && c.AssigneeUserID == nil {
+			addf("chores[%d]: due_at needs an assignee", i)
+		}
+	}
+
+	rules := make(map[uuid.UUID]bool, len(a.ApprovalRules))
+	for i, r := range a.ApprovalRules {
+		if rules[r.ID] {
+			addf("approval_rules[%d]: duplicate id %s", i, r.ID)
+		}
+		rules[r.ID] = true
+		if r.ChoreID != nil && !chores[*r.ChoreID] {
+			addf("approval_rules[%d]: unknown chore %s", i, *r.ChoreID)
+		}
+		if r.UserID != nil && !members[*r.UserID] {
+			addf("approval_rules[%d]: user %s is not a member", i, *r.UserID)
+		}
+		if !members[r.CreatedByUserID] {
+			addf("approval_rules[%d]: creator %s is not a member", i, r.CreatedByUserID)
+		}
+		if r.MaxPerDay != nil && *r.MaxPerDay < 1 {
+			addf("approval_rules[%d]: max_per_day must be at least 1", i)
+		}
+	}
+
+	allowances := make(map[uuid.UUID]bool, len(a.Allowances))
+	for i, al := range a.Allowances {
+		if allowances[al.ID] {
+			addf("allowances[%d]: duplicate id %s", i, al.ID)
+		}
+		allowances[al.ID] = true
+		if !members[al.UserID] {
+			addf("allowances[%d]: user %s is not a member", i, al.UserID)
+		}
+		if !members[al.CreatedByUserID] {
+			addf("allowances[%d]: creator %s is not a member", i, al.CreatedByUserID)
+		}
+		if al.Amount <= 0 {
+			addf("allowances[%d]: amount must be positive", i)
+		}
+		if al.AmountPerYear != nil && *al.AmountPerYear <= 0 {
+			addf("allowances[%d]: amount_per_year must be positive", i)
+		}
+		switch al.Interval {
+		case models.IntervalWeek, models.IntervalMonth:
+		default:
+			addf("allowances[%d]: invalid interval %q", i, al.Interval)
+		}
+		for _, d := range []string{al.StartDate, al.NextPeriod} {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				addf("allowances[%d]: invalid date %q", i, d)
+			}
+		}
+	}
+
+	loans := make(map[uuid.UUID]bool, len(a.Loans))
+	for i, l := range a.Loans {
+		if loans[l.ID] {
+			addf("loans[%d]: duplicate id %s", i, l.ID)
+		}
+		loans[l.ID] = true
+		if !members[l.UserID] {
+			addf("loans[%d]: user %s is not a member", i, l.UserID)
+		}
+		if !members[l.CreatedByUserID] {
+			addf("loans[%d]: creator %s is not a member", i, l.CreatedByUserID)
+		}
+		if l.Principal <= 0 {
+			addf("loans[%d]: principal must be positive", i)
+		}
+		if l.InterestPercent < 0 || l.InterestPercent > 100 {
+			addf("loans[%d]: interest_percent must be between 0 and 100", i)
+		}
+		if l.TotalDue < l.Principal {
+			addf("loans[%d]: total_due cannot be less than the principal", i)
+		}
+		switch l.RepaymentPlan {
+		case models.RepayWeekly:
+			if l.WeeklyAmount == nil || *l.WeeklyAmount <= 0 || l.WithholdPercent != nil {
+				addf("loans[%d]: a weekly plan needs a positive weekly_amount and no withhold_percent", i)
+			}
+			if _, err := time.Parse("2006-01-02", l.NextRepayment); err != nil {
+				addf("loans[%d]: invalid next_repayment %q", i, l.NextRepayment)
+			}
+		case models.RepayWithhold:
+			if l.WithholdPercent == nil || *l.WithholdPercent <= 0 || *l.WithholdPercent > 100 || l.WeeklyAmount != nil || l.NextRepayment != "" {
+				addf("loans[%d]: a withhold plan needs a withhold_percent above 0 and at most 100 and no weekly_amount or next_repayment", i)
+			}
+		default:
+			addf("loans[%d]: invalid repayment_plan %q", i, l.RepaymentPlan)
+		}
+		if _, err := time.Parse("2006-01-02", l.StartDate); err != nil {
+			addf("loans[%d]: invalid start_date %q", i, l.StartDate)
+		}
+	}
+
+	interestRates := make(map[uuid.UUID]bool, len(a.InterestRates))
+	for _, r := range a.InterestRates {
+		interestRates[r.ID] = true
+	}
+
+	entries := make(map[uuid.UUID]bool, len(a.LedgerEntries))
+	for i, e := range a.LedgerEntries {
+		if entries[e.ID] {
+			addf("ledger_entries[%d]: duplicate id %s", i, e.ID)
+		}
+		if !members[e.UserID] {
+			addf("ledger_entries[%d]: user %s is not a member", i, e.UserID)
+		}
+		if !members[e.CreatedByUserID] {
+			addf("ledger_entries[%d]: creator %s is not a member", i, e.CreatedByUserID)
+		}
+		switch e.Kind {
+		case "", models.EntryChore:
+			if e.ChoreID == nil {
+				addf("ledger_entries[%d]: chore_id is required", i)
+			} else if !chores[*e.ChoreID] {
+				addf("ledger_entries[%d]: unknown chore %s", i, *e.ChoreID)
+			}
+		case models.EntryAllowance:
+			if e.AllowanceID == nil {
+				addf("ledger_entries[%d]: allowance_id is required", i)
+			} else if !allowances[*e.AllowanceID] {
+				addf("ledger_entries[%d]: unknown allowance %s", i, *e.AllowanceID)
+			}
+			if _, err := time.Parse("2006-01-02", e.AllowancePeriod); err != nil {
+				addf("ledger_entries[%d]: invalid allowance_period %q", i, e.AllowancePeriod)
+			}
+		case models.EntryInterest:
+			if e.InterestRateID == nil {
+				addf("ledger_entries[%d]: interest_rate_id is required", i)
+			} else if !interestRates[*e.InterestRateID] {
+				addf("ledger_entries[%d]: unknown interest rate %s", i, *e.InterestRateID)
+			}
+		case models.EntryLoan, models.EntryRepayment:
+			if e.LoanID == nil {
+				addf("ledger_entries[%d]: loan_id is required", i)
+			} else if !loans[*e.LoanID] {
+				addf("ledger_entries[%d]: unknown loan %s", i, *e.LoanID)
+			}
+		default:
+			addf("ledger_entries[%d]: invalid kind %q", i, e.Kind)
+		}
+		// Repayments take money back out of the balance; everything else adds to it
+		if e.Kind == models.EntryRepayment {
+			if e.Amount >= 0 {
+				addf("ledger_entries[%d]: a loan repayment must be negative", i)
+			}
+		} else if e.Amount <= 0 {
+			addf("ledger_entries[%d]: amount must be positive", i)
+		}
+		if e.WithheldFromID != nil {
+			if e.Kind != models.EntryRepayment {
+				addf("ledger_entries[%d]: only a loan repayment can be withheld", i)
+			} else if !entries[*e.WithheldFromID] {
+				addf("ledger_entries[%d]: unknown withheld entry %s", i, *e.WithheldFromID)
+			}
+		}
+		switch e.Status {
+		case models.StatusApproved, models.StatusPendingApproval, models.StatusRejected:
+		default:
+			addf("ledger_entries[%d]: invalid status %q", i, e.Status)
+		}
+		if e.SystemActor != nil {
+			switch *e.SystemActor {
+			case models.SystemAutoApprove, models.SystemAutoExpire:
+			default:
+				addf("ledger_entries[%d]: invalid system_actor %q", i, *e.SystemActor)
+			}
+		}
+		if e.ApprovalRuleID != nil && !rules[*e.ApprovalRuleID] {
+			addf("ledger_entries[%d]: unknown approval rule %s", i, *e.ApprovalRuleID)
+		}
+		// Entries are in creation order, so a resubmission follows its original
+		if e.ResubmittedFromID != nil && !entries[*e.ResubmittedFromID] {
+			addf("ledger_entries[%d]: unknown resubmitted entry %s", i, *e.ResubmittedFromID)
+		}
+		entries[e.ID] = true
+	}
+
+	for i, c := range a.Comments {
+		if !entries[c.EntryID] {
+			addf("comments[%d]: unknown ledger entry %s", i, c.EntryID)
+		}
+		if !members[c.UserID] {
+			addf("comments[%d]: user %s is not a member", i, c.UserID)
+		}
+		if strings.TrimSpace(c.Body) == "" {
+			addf("comments[%d]: body is required", i)
+		}
+	}
+
+	attachments := make(map[uuid.UUID]bool, len(a.Attachments))
+	fileKeys := make(map[string]bool, len(a.Attachments))
+	for i, at := range a.Attachments {
+		if attachments[at.ID] {
+			addf("attachments[%d]: duplicate id %s", i, at.ID)
+		}
+		attachments[at.ID] = true
+		if !entries[at.EntryID] {
+			addf("attachments[%d]: unknown ledger entry %s", i, at.EntryID)
+		}
+		if !members[at.UploadedByUserID] {
+			addf("attachments[%d]: uploader %s is not a member", i, at.UploadedByUserID)
+		}
+		if !storage.ValidKey(at.StorageKey) {
+			addf("attachments[%d]: invalid storage_key %q", i, at.StorageKey)
+		} else if fileKeys[at.StorageKey] {
+			addf("attachments[%d]: duplicate storage_key %s", i, at.StorageKey)
+		}
+		fileKeys[at.StorageKey] = true
+	}
+
+	jarOwners := make(map[uuid.UUID]uuid.UUID, len(a.Jars))
+	jarNames := make(map[string]bool, len(a.Jars))
+	for i, j := range a.Jars {
+		if _, ok := jarOwners[j.ID]; ok {
+			addf("jars[%d]: duplicate id %s", i, j.ID)
+		}
+		jarOwners[j.ID] = j.UserID
+		if !members[j.UserID] {
+			addf("jars[%d]: user %s is not a member", i, j.UserID)
+		}
+		name := strings.ToLower(strings.TrimSpace(j.Name))
+		if name == "" {
+			addf("jars[%d]: name is required", i)
+		} else if j.DeletedAt == nil {
+			key := j.UserID.String() + ":" + name
+			if jarNames[key] {
+				addf("jars[%d]: duplicate name %q", i, j.Name)
+			}
+			jarNames[key] = true
+		}
+		if j.SplitPercent < 0 || j.SplitPercent > 100 {
+			addf("jars[%d]: split_percent must be between 0 and 100", i)
+		}
+		for k, s := range j.Splits {
+			if !entries[s.EntryID] {
+				addf("jars[%d].splits[%d]: unknown ledger entry %s", i, k, s.EntryID)
+			}
+			if s.Amount <= 0 {
+				addf("jars[%d].splits[%d]: amount must be positive", i, k)
+			}
+		}
+	}
+	memberJar := func(id, userID uuid.UUID) bool {
+		owner, ok := jarOwners[id]
+		return ok && owner == userID
+	}
+
+	for i, t := range a.JarTransfers {
+		if !members[t.UserID] {
+			addf("jar_transfers[%d]: user %s is not a member", i, t.UserID)
+		}
+		if !members[t.CreatedByUserID] {
+			addf("jar_transfers[%d]: creator %s is not a member", i, t.CreatedByUserID)
+		}
+		for _, id := range []uuid.UUID{t.FromJarID, t.ToJarID} {
+			if !memberJar(id, t.UserID) {
+				addf("jar_transfers[%d]: unknown jar %s", i, id)
+			}
+		}
+		if t.FromJarID == t.ToJarID {
+			addf("jar_transfers[%d]: cannot transfer a jar to itself", i)
+		}
+		if t.Amount <= 0 {
+			addf("jar_transfers[%d]: amount must be positive", i)
+		}
+	}
+
+	seenRates := make(map[uuid.UUID]bool, len(a.InterestRates))
+	for i, r := range a.InterestRates {
+		if seenRates[r.ID] {
+			addf("interest_rates[%d]: duplicate id %s", i, r.ID)
+		}
+		seenRates[r.ID] = true
+		if r.JarID != nil {
+			if _, ok := jarOwners[*r.JarID]; !ok {
+				addf("interest_rates[%d]: unknown jar %s", i, *r.JarID)
+			}
+		}
+		if !members[r.CreatedByUserID] {
+			addf("interest_rates[%d]: creator %s is not a member", i, r.CreatedByUserID)
+		}
+		if r.APR <= 0 || r.APR > 100 {
+			addf("interest_rates[%d]: apr must be above 0 and at most 100", i)
+		}
+		if r.MinBalance < 0 {
+			addf("interest_rates[%d]: min_balance cannot be negative", i)
+		}
+		switch r.Compounding {
+		case models.IntervalWeek, models.IntervalMonth:
+		default:
+			addf("interest_rates[%d]: invalid compounding %q", i, r.Compounding)
+		}
+		for _, d := range []string{r.StartDate, r.NextPeriod} {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				addf("interest_rates[%d]: invalid date %q", i, d)
+			}
+		}
+		for k, p := range r.Postings {
+			if !entries[p.EntryID] {
+				addf("interest_rates[%d].postings[%d]: unknown ledger entry %s", i, k, p.EntryID)
+			}
+			if !members[p.UserID] {
+				addf("interest_rates[%d].postings[%d]: user %s is not a member", i, k, p.UserID)
+			}
+			if _, err := time.Parse("2006-01-02", p.PeriodStart); err != nil {
+				addf("interest_rates[%d].postings[%d]: invalid period_start %q", i, k, p.PeriodStart)
+			}
+			if p.Amount <= 0 {
+				addf("interest_rates[%d].postings[%d]: amount must be positive", i, k)
+			}
+		}
+	}
+
+	categories := make(map[uuid.UUID]bool, len(a.SettlementCategories))
+	categoryNames := make(map[string]bool, len(a.SettlementCategories))
+	for i, c := range a.SettlementCategories {
+		if categories[c.ID] {
+			addf("settlement_categories[%d]: duplicate id %s", i, c.ID)
+		}
+		categories[c.ID] = true
+		name := strings.ToLower(strings.TrimSpace(c.Name))
+		if name == "" {
+			addf("settlement_categories[%d]: name is required", i)
+		} else if c.DeletedAt == nil {
+			if categoryNames[name] {
+				addf("settlement_categories[%d]: duplicate name %q", i, c.Name)
+			}
+			categoryNames[name] = true
+		}
+	}
+	validCategory := func(id *uuid.UUID) bool {
+		return id == nil || categories[*id]
+	}
+
+	for i, s := range a.Settlements {
+		if !members[s.UserID] {
+			addf("settlements[%d]: user %s is not a member", i, s.UserID)
+		}
+		if s.Method != "" && !slices.Contains(models.SettlementMethods, s.Method) {
+			addf("settlements[%d]: invalid method %q", i, s.Method)
+		}
+		if !validCategory(s.CategoryID) {
+			addf("settlements[%d]: unknown settlement category %s", i, *s.CategoryID)
+		}
+		if s.JarID != nil && !memberJar(*s.JarID, s.UserID) {
+			addf("settlements[%d]: unknown jar %s", i, *s.JarID)
+		}
+		if s.Amount <= 0 {
+			addf("settlements[%d]: amount must be positive", i)
+		}
+		if _, err := time.Parse("2006-01-02", s.Date); err != nil {
+			addf("settlements[%d]: invalid date %q", i, s.Date)
+		}
+		switch s.Status {
+		case "", models.SettlementAwaitingAck, models.SettlementAcknowledged:
+			if s.DisputeReason != nil {
+				addf("settlements[%d]: dispute_reason is only for disputed settlements", i)
+			}
+		case models.SettlementDisputed:
+			if s.DisputeReason == nil {
+				addf("settlements[%d]: a disputed settlement needs a dispute_reason", i)
+			}
+		default:
+			addf("settlements[%d]: invalid status %q", i, s.Status)
+		}
+		if (s.VoidedAt == nil) != (s.VoidReason == nil) {
+			addf("settlements[%d]: voided_at and void_reason must be set together", i)
+		}
+		if s.VoidedAt != nil && len(s.Entries) > 0 {
+			addf("settlements[%d]: a voided settlement cannot pay entries", i)
+		}
+		for j, e := range s.Entries {
+			if !entries[e.EntryID] {
+				addf("settlements[%d].entries[%d]: unknown ledger entry %s", i, j, e.EntryID)
+			}
+			if e.Amount <= 0 {
+				addf("settlements[%d].entries[%d]: amount must be positive", i, j)
+			}
+		}
+		for j, rev := range s.Revisions {
+			switch rev.Action {
+			case models.SettlementEdited, models.SettlementVoided, models.SettlementDisputeRaised, models.SettlementDisputeResolved:
+			default:
+				addf("settlements[%d].revisions[%d]: invalid action %q", i, j, rev.Action)
+			}
+			if strings.TrimSpace(rev.Reason) == "" {
+				addf("settlements[%d].revisions[%d]: reason is required", i, j)
+			}
+			for _, m := range []*models.SettlementMethod{rev.MethodBefore, rev.MethodAfter} {
+				if m != nil && !slices.Contains(models.SettlementMethods, *m) {
+					addf("settlements[%d].revisions[%d]: invalid method %q", i, j, *m)
+				}
+			}
+			for _, id := range []*uuid.UUID{rev.CategoryIDBefore, rev.CategoryIDAfter} {
+				if !validCategory(id) {
+					addf("settlements[%d].revisions[%d]: unknown settlement category %s", i, j, *id)
+				}
+			}
+			for _, d := range []string{rev.DateBefore, rev.DateAfter} {
+				if _, err := time.Parse("2006-01-02", d); err != nil {
+					addf("settlements[%d].revisions[%d]: invalid date %q", i, j, d)
+				}
+			}
+		}
+	}
+
+	goals := make(map[uuid.UUID]bool, len(a.Goals))
+	for i, g := range a.Goals {
+		if goals[g.ID] {
+			addf("goals[%d]: duplicate id %s", i, g.ID)
+		}
+		goals[g.ID] = true
+		if !members[g.UserID] {
+			addf("goals[%d]: user %s is not a member", i, g.UserID)
+		}
+		if !members[g.CreatedByUserID] {
+			addf("goals[%d]: creator %s is not a member", i, g.CreatedByUserID)
+		}
+		if strings.TrimSpace(g.Name) == "" {
+			addf("goals[%d]: name is required", i)
+		}
+		if g.TargetAmount <= 0 {
+			addf("goals[%d]: target_amount must be positive", i)
+		}
+		if g.Deadline != "" {
+			if _, err := time.Parse("2006-01-02", g.Deadline); err != nil {
+				addf("goals[%d]: invalid deadline %q", i, g.Deadline)
+			}
+		}
+		switch g.ProgressSource {
+		case models.GoalFromBalance, models.GoalFromAllocations:
+		default:
+			addf("goals[%d]: invalid progress_source %q", i, g.ProgressSource)
+		}
+		if g.Image != nil {
+			if !storage.ValidKey(g.Image.StorageKey) {
+				addf("goals[%d]: invalid image storage_key %q", i, g.Image.StorageKey)
+			} else if fileKeys[g.Image.StorageKey] {
+				addf("goals[%d]: duplicate storage_key %s", i, g.Image.StorageKey)
+			}
+			fileKeys[g.Image.StorageKey] = true
+		}
+		for j, al := range g.Allocations {
+			if !members[al.CreatedByUserID] {
+				addf("goals[%d].allocations[%d]: creator %s is not a member", i, j, al.CreatedByUserID)
+			}
+			if al.Amount == 0 {
+				addf("goals[%d].allocations[%d]: amount cannot be zero", i, j)
+			}
+		}
+	}
+
+	return errs
+}
+
+// NormalizeEmail lowercases and trims an email for matching
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
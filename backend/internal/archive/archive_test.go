@@ -0,0 +1,476 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func sample() *Archive {
+	head := uuid.New()
+	kid := uuid.New()
+	chore := uuid.New()
+	created := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	return &Archive{
+		FormatVersion: FormatVersion,
+		ExportedAt:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Group:         Group{ID: uuid.New(), Name: "Family", HeadUserID: head, CreatedAt: created},
+		Members: []Member{
+			{UserID: head, Email: "Parent@Example.com", Name: "Parent", Role: models.RoleHead, JoinedAt: created},
+			{UserID: kid, Email: "kid@example.com", Name: "Kid", Role: models.RoleMember, JoinedAt: created},
+		},
+		Chores: []Chore{{ID: chore, Name: "Dishes", Amount: 2, CreatedAt: created}},
+		LedgerEntries: []LedgerEntry{{
+			ID: uuid.New(), UserID: kid, ChoreID: &chore, Amount: 2, Status: models.StatusApproved,
+			CreatedByUserID: kid, ApprovedByUserID: &head, OccurredAt: created, CreatedAt: created,
+		}},
+		Settlements: []Settlement{{ID: uuid.New(), UserID: kid, Amount: 1, Date: "2026-09-02", CreatedAt: created}},
+		Invites:     []Invite{{ID: uuid.New(), ExpiresAt: created, CreatedAt: created}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, sample().Validate())
+
+	a := sample()
+	a.FormatVersion = 99
+	errs := a.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "unsupported format_version")
+
+	a = sample()
+	a.Members[1].Email = "PARENT@example.com"
+	missing := uuid.New()
+	a.LedgerEntries[0].ChoreID = &missing
+	a.LedgerEntries[0].Status = "done"
+	a.Settlements[0].Date = "02/09/2026"
+	a.Settlements[0].UserID = uuid.New()
+	errs = a.Validate()
+	assert.Len(t, errs, 5)
+	assert.Contains(t, errs[0], "duplicate email")
+
+	a = sample()
+	hours := 48
+	actor := models.SystemActor("robot")
+	a.Group.AutoApproveAfterHours = &hours
+	a.LedgerEntries[0].SystemActor = &actor
+	errs = a.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "must be set together")
+	assert.Contains(t, errs[1], `invalid system_actor "robot"`)
+
+	a = sample()
+	rule := ApprovalRule{ID: uuid.New(), ChoreID: &a.Chores[0].ID, UserID: &a.LedgerEntries[0].UserID, CreatedByUserID: a.Group.HeadUserID}
+	a.ApprovalRules = []ApprovalRule{rule}
+	a.LedgerEntries[0].ApprovalRuleID = &rule.ID
+	assert.Empty(t, a.Validate())
+
+	stranger := uuid.New()
+	a.ApprovalRules[0].UserID = &stranger
+	a.LedgerEntries[0].ApprovalRuleID = &stranger
+	errs = a.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "approval_rules[0]: user")
+	assert.Contains(t, errs[1], "unknown approval rule")
+
+	a = sample()
+	resubmitted := a.LedgerEntries[0]
+	resubmitted.ID = uuid.New()
+	resubmitted.ResubmittedFromID = &a.LedgerEntries[0].ID
+	a.LedgerEntries = append(a.LedgerEntries, resubmitted)
+	a.Comments = []Comment{{ID: uuid.New(), EntryID: resubmitted.ID, UserID: a.Group.HeadUserID, Body: "thanks"}}
+	assert.Empty(t, a.Validate())
+
+	a.LedgerEntries[0], a.LedgerEntries[1] = a.LedgerEntries[1], a.LedgerEntries[0]
+	a.Comments[0].Body = " "
+	errs = a.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "unknown resubmitted entry")
+	assert.Contains(t, errs[1], "comments[0]: body is required")
+
+	a = sample()
+	a.Settlements[0].Entries = []models.SettlementEntry{{EntryID: a.LedgerEntries[0].ID, Amount: 1}}
+	a.Settlements[0].Revisions = []SettlementRevision{{
+		ID: uuid.New(), Action: models.SettlementEdited, Reason: "typo",
+		AmountBefore: 10, AmountAfter: 1, DateBefore: "2026-09-02", DateAfter: "2026-09-02",
+	}}
+	assert.Empty(t, a.Validate())
+
+	voided := time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC)
+	a.Settlements[0].VoidedAt = &voided
+	a.Settlements[0].Revisions[0].Action = "delete"
+	errs = a.Validate()
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0], "must be set together")
+	assert.Contains(t, errs[1], "cannot pay entries")
+	assert.Contains(t, errs[2], `invalid action "delete"`)
+
+	a = sample()
+	a.Settlements[0].Status = models.SettlementDisputed
+	assert.Contains(t, a.Validate(), "settlements[0]: a disputed settlement needs a dispute_reason")
+	a.Settlements[0].Status = "lost"
+	assert.Contains(t, a.Validate(), `settlements[0]: invalid status "lost"`)
+
+	a = sample()
+	birthday := SettlementCategory{ID: uuid.New(), Name: "Birthday"}
+	a.SettlementCategories = []SettlementCategory{birthday, {ID: uuid.New(), Name: " birthday "}}
+	a.Settlements[0].Method = models.MethodBankTransfer
+	a.Settlements[0].CategoryID = &birthday.ID
+	errs = a.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], `settlement_categories[1]: duplicate name`)
+
+	// A deleted category can share its name with a current one
+	deleted := time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC)
+	a.SettlementCategories[1].DeletedAt = &deleted
+	assert.Empty(t, a.Validate())
+
+	unknown := uuid.New()
+	a.Settlements[0].Method = "cheque"
+	a.Settlements[0].CategoryID = &unknown
+	errs = a.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], `settlements[0]: invalid method "cheque"`)
+	assert.Contains(t, errs[1], "settlements[0]: unknown settlement category")
+
+	a = sample()
+	weekly := Allowance{
+		ID: uuid.New(), UserID: a.LedgerEntries[0].UserID, Amount: 5, Interval: models.IntervalWeek,
+		StartDate: "2026-09-07", NextPeriod: "2026-09-14", CreatedByUserID: a.Group.HeadUserID,
+	}
+	a.Allowances = []Allowance{weekly}
+	a.LedgerEntries = append(a.LedgerEntries, LedgerEntry{
+		ID: uuid.New(), UserID: weekly.UserID, Kind: models.EntryAllowance, AllowanceID: &weekly.ID,
+		AllowancePeriod: "2026-09-07", Amount: 5, Status: models.StatusApproved, CreatedByUserID: a.Group.HeadUserID,
+	})
+	assert.Empty(t, a.Validate())
+
+	a.Allowances[0].Interval = "fortnight"
+	a.LedgerEntries[1].AllowanceID = &missing
+	a.LedgerEntries[1].AllowancePeriod = ""
+	a.LedgerEntries[0].ChoreID = nil
+	errs = a.Validate()
+	require.Len(t, errs, 4)
+	assert.Contains(t, errs[0], `allowances[0]: invalid interval "fortnight"`)
+	assert.Contains(t, errs[1], "ledger_entries[0]: chore_id is required")
+	assert.Contains(t, errs[2], "ledger_entries[1]: unknown allowance")
+	assert.Contains(t, errs[3], "ledger_entries[1]: invalid allowance_period")
+
+	a = sample()
+	bike := Goal{
+		ID: uuid.New(), UserID: a.LedgerEntries[0].UserID, Name: "Bike", TargetAmount: 120, Deadline: "2026-12-01",
+		ProgressSource: models.GoalFromAllocations, CreatedByUserID: a.LedgerEntries[0].UserID,
+		Allocations: []GoalAllocation{{ID: uuid.New(), Amount: 1, CreatedByUserID: a.Group.HeadUserID}},
+	}
+	a.Goals = []Goal{bike}
+	assert.Empty(t, a.Validate())
+
+	a.Goals[0].ProgressSource = "savings"
+	a.Goals[0].Deadline = "December"
+	a.Goals[0].Allocations[0].Amount = 0
+	errs = a.Validate()
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0], `goals[0]: invalid deadline "December"`)
+	assert.Contains(t, errs[1], `goals[0]: invalid progress_source "savings"`)
+	assert.Contains(t, errs[2], "goals[0].allocations[0]: amount cannot be zero")
+
+	a = sample()
+	kid := a.LedgerEntries[0].UserID
+	spend := Jar{ID: uuid.New(), UserID: kid, Name: "Spend", SplitPercent: 50,
+		Splits: []JarSplit{{EntryID: a.LedgerEntries[0].ID, Amount: 1}}}
+	save := Jar{ID: uuid.New(), UserID: kid, Name: "Save", SplitPercent: 50,
+		Splits: []JarSplit{{EntryID: a.LedgerEntries[0].ID, Amount: 1}}}
+	a.Jars = []Jar{spend, save}
+	a.JarTransfers = []JarTransfer{{ID: uuid.New(), UserID: kid, FromJarID: save.ID, ToJarID: spend.ID, Amount: 0.5, CreatedByUserID: kid}}
+	a.Settlements[0].JarID = &spend.ID
+	assert.Empty(t, a.Validate())
+
+	a.Jars[1].Name = " spend"
+	a.Jars[1].Splits[0].EntryID = missing
+	a.JarTransfers[0].ToJarID = save.ID
+	a.Settlements[0].UserID = a.Group.HeadUserID
+	errs = a.Validate()
+	require.Len(t, errs, 4)
+	assert.Contains(t, errs[0], `jars[1]: duplicate name " spend"`)
+	assert.Contains(t, errs[1], "jars[1].splits[0]: unknown ledger entry")
+	assert.Contains(t, errs[2], "jar_transfers[0]: cannot transfer a jar to itself")
+	assert.Contains(t, errs[3], "settlements[0]: unknown jar")
+
+	a = sample()
+	kid = a.LedgerEntries[0].UserID
+	rate := InterestRate{ID: uuid.New(), APR: 5, Compounding: models.IntervalMonth, StartDate: "2026-09-01",
+		NextPeriod: "2026-10-01", CreatedByUserID: a.Group.HeadUserID}
+	interest := LedgerEntry{ID: uuid.New(), UserID: kid, Kind: models.EntryInterest, InterestRateID: &rate.ID, Amount: 0.41,
+		Status: models.StatusApproved, CreatedByUserID: a.Group.HeadUserID}
+	rate.Postings = []InterestPosting{{ID: uuid.New(), EntryID: interest.ID, UserID: kid, PeriodStart: "2026-09-01", Amount: 0.41}}
+	a.InterestRates = []InterestRate{rate}
+	a.LedgerEntries = append(a.LedgerEntries, interest)
+	assert.Empty(t, a.Validate())
+
+	a.InterestRates[0].JarID = &missing
+	a.InterestRates[0].Compounding = "day"
+	a.InterestRates[0].Postings[0].PeriodStart = "September"
+	a.LedgerEntries[1].InterestRateID = nil
+	errs = a.Validate()
+	require.Len(t, errs, 4)
+	assert.Contains(t, errs[0], "ledger_entries[1]: interest_rate_id is required")
+	assert.Contains(t, errs[1], "interest_rates[0]: unknown jar")
+	assert.Contains(t, errs[2], `interest_rates[0]: invalid compounding "day"`)
+	assert.Contains(t, errs[3], `interest_rates[0].postings[0]: invalid period_start "September"`)
+
+	a = sample()
+	kid = a.LedgerEntries[0].UserID
+	percent := 25.0
+	loan := Loan{ID: uuid.New(), UserID: kid, Principal: 20, TotalDue: 22, InterestPercent: 10, RepaymentPlan: models.RepayWithhold,
+		WithholdPercent: &percent, StartDate: "2026-09-01", CreatedByUserID: a.Group.HeadUserID}
+	advance := LedgerEntry{ID: uuid.New(), UserID: kid, Kind: models.EntryLoan, LoanID: &loan.ID, Amount: 20,
+		Status: models.StatusApproved, CreatedByUserID: a.Group.HeadUserID}
+	withheld := LedgerEntry{ID: uuid.New(), UserID: kid, Kind: models.EntryRepayment, LoanID: &loan.ID, WithheldFromID: &a.LedgerEntries[0].ID,
+		Amount: -1.25, Status: models.StatusApproved, CreatedByUserID: a.Group.HeadUserID}
+	a.Loans = []Loan{loan}
+	a.LedgerEntries = append(a.LedgerEntries, advance, withheld)
+	assert.Empty(t, a.Validate())
+
+	a.Loans[0].NextRepayment = "2026-09-08"
+	a.LedgerEntries[1].LoanID = &missing
+	a.LedgerEntries[2].Amount = 1.25
+	a.LedgerEntries[2].WithheldFromID = &missing
+	errs = a.Validate()
+	require.Len(t, errs, 4)
+	assert.Contains(t, errs[0], "loans[0]: a withhold plan needs")
+	assert.Contains(t, errs[1], "ledger_entries[1]: unknown loan")
+	assert.Contains(t, errs[2], "ledger_entries[2]: a loan repayment must be negative")
+	assert.Contains(t, errs[3], "ledger_entries[2]: unknown withheld entry")
+
+	a = sample()
+	a.Group.HeadUserID = uuid.New()
+	a.Members[0].Role = models.RoleMember
+	errs = a.Validate()
+	assert.Contains(t, errs, "group has no head member")
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	a := sample()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteZip(&buf, a))
+
+	decoded, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, a.Group.Name, decoded.Group.Name)
+	assert.Equal(t, a.LedgerEntries[0].ID, decoded.LedgerEntries[0].ID)
+	assert.Empty(t, decoded.Validate())
+}
+
+// withPhoto adds a photo on the sample's entry, with its file
+func withPhoto(a *Archive) *Archive {
+	key := "ledger/g/e/photo"
+	a.Attachments = []Attachment{{
+		ID: uuid.New(), EntryID: a.LedgerEntries[0].ID, UploadedByUserID: a.Members[1].UserID,
+		File:      File{StorageKey: key, ContentType: "image/png", SizeBytes: 5},
+		CreatedAt: a.LedgerEntries[0].CreatedAt,
+	}}
+	a.Files = map[string][]byte{key: []byte("photo")}
+	return a
+}
+
+func TestZipFiles(t *testing.T) {
+	a := withPhoto(sample())
+	assert.Empty(t, a.Validate())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteZip(&buf, a))
+	decoded, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, a.Attachments, decoded.Attachments)
+	assert.Equal(t, a.Files, decoded.Files)
+
+	// A JSON archive describes the photo but cannot carry it
+	buf.Reset()
+	require.NoError(t, WriteJSON(&buf, a))
+	decoded, err = Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, decoded.Attachments, 1)
+	assert.Empty(t, decoded.Files)
+
+	// A file that does not match the manifest is rejected
+	a.Files[a.Attachments[0].StorageKey] = []byte("other")
+	var tampered bytes.Buffer
+	require.NoError(t, WriteZip(&tampered, a))
+	zr, err := zip.NewReader(bytes.NewReader(tampered.Bytes()), int64(tampered.Len()))
+	require.NoError(t, err)
+	_, err = ReadFiles(zr, map[string]string{a.Attachments[0].StorageKey: Checksum([]byte("photo"))})
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	_, err = ReadFiles(zr, nil)
+	assert.ErrorContains(t, err, "not listed")
+	_, err = ReadFiles(zr, map[string]string{a.Attachments[0].StorageKey: Checksum([]byte("other")), "ledger/x": "0"})
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestZipGoalImage(t *testing.T) {
+	a := withPhoto(sample())
+	kid := a.Members[1].UserID
+	a.Goals = []Goal{{
+		ID: uuid.New(), UserID: kid, Name: "Bike", TargetAmount: 100, ProgressSource: models.GoalFromBalance,
+		CreatedByUserID: kid, Image: &File{StorageKey: "goals/g/bike/1", ContentType: "image/jpeg", SizeBytes: 4},
+	}}
+	a.Files["goals/g/bike/1"] = []byte("bike")
+	assert.Empty(t, a.Validate())
+	assert.ElementsMatch(t, []string{"ledger/g/e/photo", "goals/g/bike/1"}, a.FileKeys())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteZip(&buf, a))
+	decoded, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, a.Goals[0].Image, decoded.Goals[0].Image)
+	assert.Equal(t, []byte("bike"), decoded.Files["goals/g/bike/1"])
+
+	a.Goals[0].Image.StorageKey = a.Attachments[0].StorageKey
+	assert.Contains(t, a.Validate(), "goals[0]: duplicate storage_key ledger/g/e/photo")
+	a.Goals[0].Image.StorageKey = "/etc/passwd"
+	assert.Contains(t, a.Validate(), `goals[0]: invalid image storage_key "/etc/passwd"`)
+}
+
+func TestValidateChoreAssignee(t *testing.T) {
+	a := sample()
+	dueAt := a.Group.CreatedAt.Add(24 * time.Hour)
+	a.Chores[0].AssigneeUserID = &a.Members[1].UserID
+	a.Chores[0].DueAt = &dueAt
+	assert.Empty(t, a.Validate())
+
+	stranger := uuid.New()
+	a.Chores[0].AssigneeUserID = &stranger
+	assert.Equal(t, []string{"chores[0]: assignee " + stranger.String() + " is not a member"}, a.Validate())
+
+	a.Chores[0].AssigneeUserID = nil
+	assert.Equal(t, []string{"chores[0]: due_at needs an assignee"}, a.Validate())
+}
+
+func TestValidateAttachments(t *testing.T) {
+	a := withPhoto(sample())
+	a.Attachments = append(a.Attachments, Attachment{
+		ID: a.Attachments[0].ID, EntryID: uuid.New(), UploadedByUserID: uuid.New(),
+		File: File{StorageKey: "../escape"},
+	}, Attachment{
+		ID: uuid.New(), EntryID: a.LedgerEntries[0].ID, UploadedByUserID: a.Members[0].UserID,
+		File: a.Attachments[0].File,
+	})
+
+	errs := a.Validate()
+	assert.Contains(t, errs, "attachments[1]: duplicate id "+a.Attachments[0].ID.String())
+	assert.Contains(t, errs, "attachments[1]: unknown ledger entry "+a.Attachments[1].EntryID.String())
+	assert.Contains(t, errs, "attachments[1]: uploader "+a.Attachments[1].UploadedByUserID.String()+" is not a member")
+	assert.Contains(t, errs, `attachments[1]: invalid storage_key "../escape"`)
+	assert.Contains(t, errs, "attachments[2]: duplicate storage_key ledger/g/e/photo")
+}
+
+func TestZipChecksum(t *testing.T) {
+	var original bytes.Buffer
+	require.NoError(t, WriteZip(&original, sample()))
+
+	zr, err := zip.NewReader(bytes.NewReader(original.Bytes()), int64(original.Len()))
+	require.NoError(t, err)
+	var manifest []byte
+	for _, f := range zr.File {
+		if f.Name == ManifestFile {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			manifest, err = io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+	}
+	require.NotEmpty(t, manifest)
+
+	// Keep the original manifest but swap in an edited group.json
+	tampered := sample()
+	tampered.Group.Name = "Someone else"
+	var body bytes.Buffer
+	require.NoError(t, WriteJSON(&body, tampered))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{ManifestFile: manifest, ArchiveFile: body.Bytes()} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	_, err = Decode(buf.Bytes())
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	a := sample()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, a))
+
+	decoded, err := Decode(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, a.Settlements[0].Date, decoded.Settlements[0].Date)
+
+	_, err = Decode([]byte("not an archive"))
+	assert.Error(t, err)
+}
+
+func TestResolveUsers(t *testing.T) {
+	a := sample()
+	parent, kid := uuid.New(), uuid.New()
+
+	targets := a.TargetEmails(map[string]string{"kid@example.com": "kid@new.example.com"})
+	assert.Equal(t, "parent@example.com", targets[a.Members[0].UserID])
+	assert.Equal(t, "kid@new.example.com", targets[a.Members[1].UserID])
+
+	report := &Report{}
+	users := a.ResolveUsers(targets, map[string]uuid.UUID{
+		"parent@example.com":  parent,
+		"kid@new.example.com": kid,
+	}, report)
+	assert.True(t, report.Valid())
+	assert.Equal(t, parent, users[a.Members[0].UserID])
+	assert.Equal(t, kid, users[a.Members[1].UserID])
+	require.Len(t, report.Users, 2)
+	assert.Equal(t, &kid, report.Users[1].UserID)
+
+	report = &Report{}
+	a.ResolveUsers(targets, map[string]uuid.UUID{"parent@example.com": parent}, report)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "kid@new.example.com")
+	assert.Nil(t, report.Users[1].UserID)
+
+	report = &Report{}
+	a.ResolveUsers(map[uuid.UUID]string{
+		a.Members[0].UserID: "same@example.com",
+		a.Members[1].UserID: "same@example.com",
+	}, map[string]uuid.UUID{"same@example.com": parent}, report)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "same user")
+}
+
+func TestCheckConsent(t *testing.T) {
+	a := sample()
+	parent, kid := uuid.New(), uuid.New()
+	users := map[uuid.UUID]uuid.UUID{a.Members[0].UserID: parent, a.Members[1].UserID: kid}
+
+	report := &Report{}
+	a.CheckConsent(users, parent, report)
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], a.Members[1].Email)
+
+	// A group of only the importer adds nobody else
+	report = &Report{}
+	a.CheckConsent(map[uuid.UUID]uuid.UUID{a.Members[0].UserID: parent}, parent, report)
+	assert.True(t, report.Valid())
+}
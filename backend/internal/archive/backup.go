@@ -0,0 +1,344 @@
+package archive
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// Backup is a consistent snapshot of every user and group on a server.
+// Groups use the same format as a single group export.
+type Backup struct {
+	FormatVersion           int                      `json:"format_version"`
+	CreatedAt               time.Time                `json:"created_at"`
+	Users                   []User                   `json:"users"`
+	Groups                  []*Archive               `json:"groups"`
+	Webhooks                []Webhook                `json:"webhooks"`
+	NotificationSettings    []NotificationSettings   `json:"notification_settings"`
+	NotificationPreferences []NotificationPreference `json:"notification_preferences"`
+	Devices                 []Device                 `json:"devices"`
+	GroupEvents             []GroupEvent             `json:"group_events"`
+	// LedgerChain is every group's hash chain, verified against the backed-up
+	// rows before a restore rebuilds it. Backups from before it was added
+	// have none.
+	LedgerChain []ChainLink `json:"ledger_chain"`
+}
+
+// User is a backed-up user account, including its password hash so
+// accounts can log in again after a restore
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	Name         string    `json:"name"`
+	DOB          *string   `json:"dob,omitempty"` // YYYY-MM-DD
+	Sex          *string   `json:"sex,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Webhook is a backed-up webhook subscription. Its delivery log is not
+// backed up.
+type Webhook struct {
+	ID              uuid.UUID `json:"id"`
+	GroupID         uuid.UUID `json:"group_id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"secret"`
+	EventTypes      []string  `json:"event_types"`
+	Active          bool      `json:"active"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// NotificationSettings is a backed-up user's time zone, quiet hours and
+// email choices
+type NotificationSettings struct {
+	UserID             uuid.UUID `json:"user_id"`
+	Timezone           string    `json:"timezone"`
+	QuietHoursStart    *int      `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd      *int      `json:"quiet_hours_end,omitempty"`
+	Locale             string    `json:"locale"`
+	EmailNotifications bool      `json:"email_notifications"`
+	EmailDigest        bool      `json:"email_digest"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// NotificationPreference is a backed-up user's choice for one event type,
+// for all groups (GroupID nil) or for one group
+type NotificationPreference struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	GroupID   *uuid.UUID `json:"group_id,omitempty"`
+	EventType string     `json:"event_type"`
+	Enabled   bool       `json:"enabled"`
+}
+
+// Device is a backed-up push token, so notifications keep reaching the app
+// after a restore
+type Device struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Token      string    `json:"token"`
+	Platform   string    `json:"platform"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// GroupEvent is a backed-up entry in a group's event log, kept with its
+// sequence numbers so clients can resume live updates after a restore.
+// GroupSeq is zero in backups taken before events were numbered per group.
+type GroupEvent struct {
+	Seq         int64      `json:"seq"`
+	GroupSeq    int64      `json:"group_seq"`
+	ID          uuid.UUID  `json:"id"`
+	GroupID     uuid.UUID  `json:"group_id"`
+	Type        string     `json:"type"`
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty"`
+	Payload     string     `json:"payload"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ChainLink is a backed-up link of a group's ledger hash chain
+type ChainLink struct {
+	GroupID    uuid.UUID        `json:"group_id"`
+	Seq        int64            `json:"seq"`
+	RecordType chain.RecordType `json:"record_type"`
+	RecordID   uuid.UUID        `json:"record_id"`
+	Payload    string           `json:"payload"`
+	PrevHash   string           `json:"prev_hash"`
+	Hash       string           `json:"hash"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// Validate checks the backup's version, that every group is valid and that
+// every member refers to a backed-up user
+func (b *Backup) Validate() []string {
+	var errs []string
+	if b.FormatVersion != FormatVersion {
+		return []string{fmt.Sprintf("unsupported format_version %d, expected %d", b.FormatVersion, FormatVersion)}
+	}
+
+	users := make(map[uuid.UUID]bool, len(b.Users))
+	emails := make(map[string]bool, len(b.Users))
+	for i, u := range b.Users {
+		if users[u.ID] {
+			errs = append(errs, fmt.Sprintf("users[%d]: duplicate id %s", i, u.ID))
+		}
+		users[u.ID] = true
+		email := NormalizeEmail(u.Email)
+		if emails[email] {
+			errs = append(errs, fmt.Sprintf("users[%d]: duplicate email %s", i, u.Email))
+		}
+		emails[email] = true
+		if u.DOB != nil {
+			if _, err := time.Parse("2006-01-02", *u.DOB); err != nil {
+				errs = append(errs, fmt.Sprintf("users[%d]: invalid dob %q", i, *u.DOB))
+			}
+		}
+	}
+
+	groups := make(map[uuid.UUID]bool, len(b.Groups))
+	for i, g := range b.Groups {
+		groups[g.Group.ID] = true
+		for _, e := range g.Validate() {
+			errs = append(errs, fmt.Sprintf("groups[%d] (%s): %s", i, g.Group.Name, e))
+		}
+		for _, m := range g.Members {
+			if !users[m.UserID] {
+				errs = append(errs, fmt.Sprintf("groups[%d] (%s): member %s is not a backed-up user", i, g.Group.Name, m.UserID))
+			}
+		}
+	}
+
+	for i, w := range b.Webhooks {
+		if !groups[w.GroupID] {
+			errs = append(errs, fmt.Sprintf("webhooks[%d]: unknown group %s", i, w.GroupID))
+		}
+		if !users[w.CreatedByUserID] {
+			errs = append(errs, fmt.Sprintf("webhooks[%d]: creator %s is not a backed-up user", i, w.CreatedByUserID))
+		}
+	}
+
+	for i, s := range b.NotificationSettings {
+		if !users[s.UserID] {
+			errs = append(errs, fmt.Sprintf("notification_settings[%d]: %s is not a backed-up user", i, s.UserID))
+		}
+	}
+	for i, p := range b.NotificationPreferences {
+		if !users[p.UserID] {
+			errs = append(errs, fmt.Sprintf("notification_preferences[%d]: %s is not a backed-up user", i, p.UserID))
+		}
+		if p.GroupID != nil && !groups[*p.GroupID] {
+			errs = append(errs, fmt.Sprintf("notification_preferences[%d]: unknown group %s", i, *p.GroupID))
+		}
+	}
+	for i, d := range b.Devices {
+		if !users[d.UserID] {
+			errs = append(errs, fmt.Sprintf("devices[%d]: %s is not a backed-up user", i, d.UserID))
+		}
+	}
+	for i, e := range b.GroupEvents {
+		if !groups[e.GroupID] {
+			errs = append(errs, fmt.Sprintf("group_events[%d]: unknown group %s", i, e.GroupID))
+		}
+	}
+	for i, l := range b.LedgerChain {
+		if !groups[l.GroupID] {
+			errs = append(errs, fmt.Sprintf("ledger_chain[%d]: unknown group %s", i, l.GroupID))
+		}
+	}
+
+	return errs
+}
+
+// VerifyChain checks each group's backed-up hash chain against its
+// backed-up ledger entries and settlements with h, and returns the first
+// break of every group whose chain does not verify. Backups without a chain
+// verify trivially.
+func (b *Backup) VerifyChain(h *chain.Hasher) []string {
+	if b.LedgerChain == nil {
+		return nil
+	}
+
+	links := make(map[uuid.UUID][]chain.Link)
+	for _, l := range b.LedgerChain {
+		links[l.GroupID] = append(links[l.GroupID], chain.Link{
+			GroupID:    l.GroupID,
+			Seq:        l.Seq,
+			RecordType: l.RecordType,
+			RecordID:   l.RecordID,
+			Payload:    l.Payload,
+			PrevHash:   l.PrevHash,
+			Hash:       l.Hash,
+		})
+	}
+
+	var errs []string
+	for _, g := range b.Groups {
+		groupLinks := links[g.Group.ID]
+		slices.SortFunc(groupLinks, func(a, b chain.Link) int { return cmp.Compare(a.Seq, b.Seq) })
+
+		// Rows carry no hash in a backup, so each stands for its latest link
+		latest := make(map[chain.RecordKey]string, len(groupLinks))
+		for _, l := range groupLinks {
+			latest[chain.RecordKey{Type: l.RecordType, ID: l.RecordID}] = l.Hash
+		}
+		current := make(map[chain.RecordKey]chain.Record, len(g.LedgerEntries)+len(g.Settlements))
+		for i := range g.LedgerEntries {
+			e := &g.LedgerEntries[i]
+			key := chain.RecordKey{Type: chain.RecordLedgerEntry, ID: e.ID}
+			current[key] = chain.Record{Payload: chain.LedgerEntryPayload(e.model(g.Group.ID)), Hash: optionalHash(latest[key])}
+		}
+		for i := range g.Settlements {
+			s := &g.Settlements[i]
+			key := chain.RecordKey{Type: chain.RecordSettlement, ID: s.ID}
+			current[key] = chain.Record{Payload: chain.SettlementPayload(s.model(g.Group.ID)), Hash: optionalHash(latest[key])}
+		}
+
+		report := h.Verify(g.Group.ID, groupLinks, current)
+		if !report.Valid {
+			errs = append(errs, fmt.Sprintf("group %s (%s): seq %d, %s %s: %s", g.Group.ID, g.Group.Name,
+				report.FirstBreak.Seq, report.FirstBreak.RecordType, report.FirstBreak.RecordID, report.FirstBreak.Reason))
+		}
+	}
+	return errs
+}
+
+// model returns the entry as it was stored in group groupID, for its chain payload
+func (e *LedgerEntry) model(groupID uuid.UUID) *models.LedgerEntry {
+	entry := &models.LedgerEntry{
+		ID:                e.ID,
+		GroupID:           groupID,
+		UserID:            e.UserID,
+		Kind:              e.Kind,
+		ChoreID:           e.ChoreID,
+		AllowanceID:       e.AllowanceID,
+		InterestRateID:    e.InterestRateID,
+		LoanID:            e.LoanID,
+		WithheldFromID:    e.WithheldFromID,
+		Amount:            e.Amount,
+		Status:            e.Status,
+		CreatedByUserID:   e.CreatedByUserID,
+		ApprovedByUserID:  e.ApprovedByUserID,
+		RejectedByUserID:  e.RejectedByUserID,
+		StatusReason:      e.StatusReason,
+		SystemActor:       e.SystemActor,
+		ApprovalRuleID:    e.ApprovalRuleID,
+		ResubmittedFromID: e.ResubmittedFromID,
+		OccurredAt:        e.OccurredAt,
+		CreatedAt:         e.CreatedAt,
+	}
+	if entry.Kind == "" {
+		entry.Kind = models.EntryChore
+	}
+	if period, err := time.Parse("2006-01-02", e.AllowancePeriod); err == nil {
+		entry.AllowancePeriod = &period
+	}
+	return entry
+}
+
+// model returns the settlement as it was stored in group groupID, for its chain payload
+func (s *Settlement) model(groupID uuid.UUID) *models.Settlement {
+	settlement := &models.Settlement{
+		ID:                s.ID,
+		GroupID:           groupID,
+		UserID:            s.UserID,
+		Amount:            s.Amount,
+		Note:              s.Note,
+		Method:            s.Method,
+		ExternalReference: s.ExternalReference,
+		CategoryID:        s.CategoryID,
+		JarID:             s.JarID,
+		Status:            s.Status,
+		AcknowledgedAt:    s.AcknowledgedAt,
+		DisputedAt:        s.DisputedAt,
+		DisputeReason:     s.DisputeReason,
+		CreatedAt:         s.CreatedAt,
+		VoidedAt:          s.VoidedAt,
+		VoidedByUserID:    s.VoidedByUserID,
+		VoidReason:        s.VoidReason,
+	}
+	if settlement.Method == "" {
+		settlement.Method = models.MethodCash
+	}
+	if settlement.Status == "" {
+		settlement.Status = models.SettlementAcknowledged
+	}
+	settlement.Date, _ = time.Parse("2006-01-02", s.Date)
+	return settlement
+}
+
+func optionalHash(hash string) *string {
+	if hash == "" {
+		return nil
+	}
+	return &hash
+}
+
+// Counts totals the rows across every group in the backup
+func (b *Backup) Counts() Counts {
+	var c Counts
+	for _, g := range b.Groups {
+		c.Members += len(g.Members)
+		c.Chores += len(g.Chores)
+		c.ApprovalRules += len(g.ApprovalRules)
+		c.Allowances += len(g.Allowances)
+		c.Loans += len(g.Loans)
+		c.LedgerEntries += len(g.LedgerEntries)
+		c.Comments += len(g.Comments)
+		c.Attachments += len(g.Attachments)
+		c.Jars += len(g.Jars)
+		c.JarTransfers += len(g.JarTransfers)
+		c.InterestRates += len(g.InterestRates)
+		c.SettlementCategories += len(g.SettlementCategories)
+		c.Settlements += len(g.Settlements)
+		c.Goals += len(g.Goals)
+		c.InvitesSkipped += len(g.Invites)
+	}
+	return c
+}
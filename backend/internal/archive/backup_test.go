@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// chainedBackup returns a backup of the sample group with its entry and
+// settlement chained with h, as the server stored them
+func chainedBackup(t *testing.T, h *chain.Hasher) *Backup {
+	t.Helper()
+
+	a := sample()
+	e := a.LedgerEntries[0]
+	s := a.Settlements[0]
+	payloads := []struct {
+		recordType chain.RecordType
+		id         uuid.UUID
+		payload    chain.Payload
+	}{
+		{chain.RecordLedgerEntry, e.ID, chain.LedgerEntryPayload(&models.LedgerEntry{
+			ID: e.ID, GroupID: a.Group.ID, UserID: e.UserID, Kind: models.EntryChore, ChoreID: e.ChoreID,
+			Amount: e.Amount, Status: e.Status, CreatedByUserID: e.CreatedByUserID, ApprovedByUserID: e.ApprovedByUserID,
+			OccurredAt: e.OccurredAt, CreatedAt: e.CreatedAt,
+		})},
+		{chain.RecordSettlement, s.ID, chain.SettlementPayload(&models.Settlement{
+			ID: s.ID, GroupID: a.Group.ID, UserID: s.UserID, Amount: s.Amount,
+			Date: time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC), Method: models.MethodCash,
+			Status: models.SettlementAcknowledged, CreatedAt: s.CreatedAt,
+		})},
+	}
+
+	b := &Backup{FormatVersion: FormatVersion, Groups: []*Archive{a}, LedgerChain: []ChainLink{}}
+	prev := chain.GenesisHash
+	for i, p := range payloads {
+		payload, err := chain.Canonical(p.payload)
+		require.NoError(t, err)
+		seq := int64(i + 1)
+		hash := h.Hash(a.Group.ID, seq, p.recordType, prev, payload)
+		b.LedgerChain = append(b.LedgerChain, ChainLink{
+			GroupID: a.Group.ID, Seq: seq, RecordType: p.recordType, RecordID: p.id,
+			Payload: payload, PrevHash: prev, Hash: hash,
+		})
+		prev = hash
+	}
+	return b
+}
+
+func TestBackupVerifyChain(t *testing.T) {
+	h := chain.NewHasher("secret")
+
+	assert.Empty(t, chainedBackup(t, h).VerifyChain(h))
+
+	// A row edited in the backup no longer matches its link
+	b := chainedBackup(t, h)
+	b.Groups[0].LedgerEntries[0].Amount = 200
+	errs := b.VerifyChain(h)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "modified")
+
+	// A chain written with another key does not verify
+	assert.NotEmpty(t, chainedBackup(t, h).VerifyChain(chain.NewHasher("other")))
+
+	// Backups from before the chain was backed up have nothing to verify
+	b = chainedBackup(t, h)
+	b.LedgerChain = nil
+	assert.Empty(t, b.VerifyChain(h))
+}
+
+func TestBackupValidateReferences(t *testing.T) {
+	b := chainedBackup(t, chain.NewHasher(""))
+	for _, m := range b.Groups[0].Members {
+		b.Users = append(b.Users, User{ID: m.UserID, Email: m.Email, Name: m.Name})
+	}
+	require.Empty(t, b.Validate())
+
+	other := uuid.New()
+	b.Devices = []Device{{ID: uuid.New(), UserID: other, Token: "token", Platform: "ios"}}
+	b.GroupEvents = []GroupEvent{{Seq: 1, ID: uuid.New(), GroupID: other, Type: "ledger.created", Payload: "{}"}}
+
+	errs := b.Validate()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "devices[0]")
+	assert.Contains(t, errs[1], "group_events[0]")
+}
@@ -0,0 +1,226 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ArchiveFile is the name of the archive JSON inside a ZIP export
+	ArchiveFile = "group.json"
+	// ManifestFile describes and checksums ArchiveFile inside a ZIP export
+	ManifestFile = "manifest.json"
+	// FilesDir holds uploaded files inside a ZIP export, each at FilesDir
+	// followed by its storage key
+	FilesDir = "files/"
+)
+
+// ErrChecksumMismatch is returned when a ZIP's group.json does not match its manifest
+var ErrChecksumMismatch = errors.New("archive checksum does not match manifest")
+
+// Manifest is stored next to the archive in a ZIP export
+type Manifest struct {
+	FormatVersion int    `json:"format_version"`
+	GroupName     string `json:"group_name"`
+	SHA256        string `json:"sha256"`
+	// Files is the SHA-256 of each uploaded file, by storage key
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// Checksum returns the hex SHA-256 of data
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileChecksums returns the checksum of each file, by storage key
+func FileChecksums(files map[string][]byte) map[string]string {
+	if len(files) == 0 {
+		return nil
+	}
+	sums := make(map[string]string, len(files))
+	for key, data := range files {
+		sums[key] = Checksum(data)
+	}
+	return sums
+}
+
+// WriteFiles adds each file to a ZIP under FilesDir, in key order
+func WriteFiles(zw *zip.Writer, files map[string][]byte, modified time.Time) error {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Photos are already compressed
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: FilesDir + key, Method: zip.Store, Modified: modified})
+		if err != nil {
+			return fmt.Errorf("failed to add file %s: %w", key, err)
+		}
+		if _, err := fw.Write(files[key]); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ReadFiles reads the files under FilesDir in a ZIP and checks each against
+// its checksum. Every listed file must be present and every file listed.
+func ReadFiles(zr *zip.Reader, sums map[string]string) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(sums))
+	for _, f := range zr.File {
+		key, ok := strings.CutPrefix(f.Name, FilesDir)
+		if !ok || key == "" || strings.HasSuffix(key, "/") {
+			continue
+		}
+		want, listed := sums[key]
+		if !listed {
+			return nil, fmt.Errorf("file %s is not listed in the manifest", key)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %w", key, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", key, err)
+		}
+		if Checksum(data) != want {
+			return nil, fmt.Errorf("file %s: %w", key, ErrChecksumMismatch)
+		}
+		files[key] = data
+	}
+	for key := range sums {
+		if _, ok := files[key]; !ok {
+			return nil, fmt.Errorf("file %s is listed in the manifest but missing", key)
+		}
+	}
+	return files, nil
+}
+
+// WriteJSON writes the archive as indented JSON
+func WriteJSON(w io.Writer, a *Archive) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(a); err != nil {
+		return fmt.Errorf("failed to encode archive: %w", err)
+	}
+	return nil
+}
+
+// WriteZip writes the archive, its files and a checksummed manifest as a ZIP file
+func WriteZip(w io.Writer, a *Archive) error {
+	var body bytes.Buffer
+	if err := WriteJSON(&body, a); err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(Manifest{
+		FormatVersion: a.FormatVersion,
+		GroupName:     a.Group.Name,
+		SHA256:        Checksum(body.Bytes()),
+		Files:         FileChecksums(a.Files),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{ManifestFile, manifest},
+		{ArchiveFile, body.Bytes()},
+	} {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: a.ExportedAt})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := WriteFiles(zw, a.Files, a.ExportedAt); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+	return nil
+}
+
+// Decode reads an archive from JSON or from a ZIP produced by WriteZip
+func Decode(data []byte) (*Archive, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return decodeZip(data)
+	}
+	return decodeJSON(data)
+}
+
+func decodeJSON(data []byte) (*Archive, error) {
+	var a Archive
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("invalid archive json: %w", err)
+	}
+	return &a, nil
+}
+
+func decodeZip(data []byte) (*Archive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive zip: %w", err)
+	}
+
+	files := make(map[string][]byte, 2)
+	for _, f := range zr.File {
+		if f.Name != ArchiveFile && f.Name != ManifestFile {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		files[f.Name] = b
+	}
+
+	body, ok := files[ArchiveFile]
+	if !ok {
+		return nil, fmt.Errorf("archive zip has no %s", ArchiveFile)
+	}
+	// Uploaded files are only read with a manifest to check them against
+	var m Manifest
+	if raw, ok := files[ManifestFile]; ok {
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if m.SHA256 != Checksum(body) {
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	a, err := decodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	if a.Files, err = ReadFiles(zr, m.Files); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// UserMatch records how an archived member was mapped to a local user
+type UserMatch struct {
+	ArchivedUserID uuid.UUID  `json:"archived_user_id"`
+	Email          string     `json:"email"`
+	MatchedEmail   string     `json:"matched_email"`
+	UserID         *uuid.UUID `json:"user_id"` // nil when no local user has the email
+}
+
+// Counts summarises what an import creates
+type Counts struct {
+	Members              int `json:"members"`
+	Chores               int `json:"chores"`
+	ApprovalRules        int `json:"approval_rules"`
+	Allowances           int `json:"allowances"`
+	Loans                int `json:"loans"`
+	LedgerEntries        int `json:"ledger_entries"`
+	Comments             int `json:"comments"`
+	Attachments          int `json:"attachments"`
+	Jars                 int `json:"jars"`
+	JarTransfers         int `json:"jar_transfers"`
+	InterestRates        int `json:"interest_rates"`
+	SettlementCategories int `json:"settlement_categories"`
+	Settlements          int `json:"settlements"`
+	Goals                int `json:"goals"`
+	InvitesSkipped       int `json:"invites_skipped"`
+}
+
+// Report is the result of an import or dry run
+type Report struct {
+	DryRun    bool        `json:"dry_run"`
+	GroupID   *uuid.UUID  `json:"group_id,omitempty"` // Set once an import is committed
+	GroupName string      `json:"group_name"`
+	Users     []UserMatch `json:"users"`
+	Counts    Counts      `json:"counts"`
+	Errors    []string    `json:"errors"`
+	Warnings  []string    `json:"warnings"`
+}
+
+// Valid reports whether the import can proceed
+func (r *Report) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// TargetEmails returns the local emails each archived member maps to.
+// emailMap overrides the archived email; keys and values are normalized.
+func (a *Archive) TargetEmails(emailMap map[string]string) map[uuid.UUID]string {
+	targets := make(map[uuid.UUID]string, len(a.Members))
+	for _, m := range a.Members {
+		email := NormalizeEmail(m.Email)
+		if mapped, ok := emailMap[email]; ok {
+			email = mapped
+		}
+		targets[m.UserID] = email
+	}
+	return targets
+}
+
+// ResolveUsers maps archived user IDs to local user IDs using the users found
+// by email, recording every match and an error for each member left unmatched
+// or mapped onto the same local user as another member
+func (a *Archive) ResolveUsers(targets map[uuid.UUID]string, found map[string]uuid.UUID, report *Report) map[uuid.UUID]uuid.UUID {
+	users := make(map[uuid.UUID]uuid.UUID, len(a.Members))
+	claimed := make(map[uuid.UUID]string, len(a.Members))
+
+	for _, m := range a.Members {
+		match := UserMatch{ArchivedUserID: m.UserID, Email: m.Email, MatchedEmail: targets[m.UserID]}
+		if id, ok := found[match.MatchedEmail]; ok {
+			match.UserID = &id
+			if other, dup := claimed[id]; dup {
+				report.Errors = append(report.Errors, fmt.Sprintf("members %s and %s map to the same user", other, m.Email))
+			}
+			claimed[id] = m.Email
+			users[m.UserID] = id
+		} else {
+			report.Errors = append(report.Errors, fmt.Sprintf("no user with email %s; register it first or map it with map=%s=<email>", match.MatchedEmail, NormalizeEmail(m.Email)))
+		}
+		report.Users = append(report.Users, match)
+	}
+
+	return users
+}
+
+// CheckConsent records an error for each member the import would add to the
+// group other than the importing user. Those accounts never agreed to join
+// the group, so only an instance admin may import them.
+func (a *Archive) CheckConsent(users map[uuid.UUID]uuid.UUID, importer uuid.UUID, report *Report) {
+	for _, m := range a.Members {
+		if id, ok := users[m.UserID]; ok && id != importer {
+			report.Errors = append(report.Errors, fmt.Sprintf("member %s would be added without their consent; only an instance admin can import other members", m.Email))
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package backup writes whole-server backups to disk, verifies them and
+// prunes old ones according to a retention policy.
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/archive"
+)
+
+const (
+	// BackupFile is the name of the backup JSON inside a backup ZIP
+	BackupFile = "backup.json"
+	// ManifestFile describes and checksums BackupFile inside a backup ZIP
+	ManifestFile = "manifest.json"
+
+	filePrefix = "pocket-money-"
+	fileSuffix = ".zip"
+	timeLayout = "20060102T150405Z"
+)
+
+// ErrChecksumMismatch is returned when a backup's JSON does not match its manifest
+var ErrChecksumMismatch = errors.New("backup checksum does not match manifest")
+
+// Manifest is stored next to the backup JSON and summarises it
+type Manifest struct {
+	FormatVersion int            `json:"format_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Users         int            `json:"users"`
+	Groups        int            `json:"groups"`
+	Counts        archive.Counts `json:"counts"`
+	SHA256        string         `json:"sha256"`
+	// Files is the SHA-256 of each uploaded file, by storage key
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// Filename returns the file name for a backup taken at t
+func Filename(t time.Time) string {
+	return filePrefix + t.UTC().Format(timeLayout) + fileSuffix
+}
+
+// parseFilename returns the time encoded in a backup file name
+func parseFilename(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// groupFiles gathers the uploaded files of every group in the backup
+func groupFiles(b *archive.Backup) map[string][]byte {
+	all := make(map[string][]byte)
+	for _, g := range b.Groups {
+		for key, data := range g.Files {
+			all[key] = data
+		}
+	}
+	return all
+}
+
+// Encode writes the backup, its files and a checksummed manifest as a ZIP file
+func Encode(w io.Writer, b *archive.Backup) error {
+	body, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	all := groupFiles(b)
+
+	m := newManifest(b, archive.Checksum(body))
+	m.Files = archive.FileChecksums(all)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{ManifestFile, manifest},
+		{BackupFile, body},
+	} {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: b.CreatedAt})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := archive.WriteFiles(zw, all, b.CreatedAt); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+	return nil
+}
+
+func newManifest(b *archive.Backup, sum string) Manifest {
+	return Manifest{
+		FormatVersion: b.FormatVersion,
+		CreatedAt:     b.CreatedAt,
+		Users:         len(b.Users),
+		Groups:        len(b.Groups),
+		Counts:        b.Counts(),
+		SHA256:        sum,
+	}
+}
+
+// Decode reads a backup ZIP, checking its checksum and that the manifest
+// counts match the contents
+func Decode(data []byte) (*archive.Backup, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup zip: %w", err)
+	}
+
+	files := make(map[string][]byte, 2)
+	for _, f := range zr.File {
+		if f.Name != BackupFile && f.Name != ManifestFile {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+
+	raw, ok := files[ManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("backup zip has no %s", ManifestFile)
+	}
+	body, ok := files[BackupFile]
+	if !ok {
+		return nil, fmt.Errorf("backup zip has no %s", BackupFile)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.SHA256 != archive.Checksum(body) {
+		return nil, ErrChecksumMismatch
+	}
+
+	var b archive.Backup
+	if err := json.Unmarshal(body, &b); err != nil {
+		return nil, fmt.Errorf("invalid backup json: %w", err)
+	}
+	if got := newManifest(&b, m.SHA256); got.Users != m.Users || got.Groups != m.Groups || got.Counts != m.Counts {
+		return nil, fmt.Errorf("backup contents do not match manifest counts")
+	}
+
+	all, err := archive.ReadFiles(zr, m.Files)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range b.Groups {
+		g.Files = make(map[string][]byte)
+		for _, key := range g.FileKeys() {
+			if data, ok := all[key]; ok {
+				g.Files[key] = data
+			}
+		}
+	}
+
+	return &b, nil
+}
+
+// Read decodes the backup file at path
+func Read(path string) (*archive.Backup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return Decode(data)
+}
+
+// Write stores the backup in dir and returns its path. The file is written
+// under a temporary name, synced, read back and verified before it is
+// renamed into place, so a listed backup is always a complete, valid one.
+func Write(dir string, b *archive.Backup) (string, error) {
+	if errs := b.Validate(); len(errs) > 0 {
+		return "", fmt.Errorf("backup is invalid: %s", strings.Join(errs, "; "))
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".backup-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if err := Encode(tmp, b); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("failed to sync backup file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to close backup file: %w", err)
+	}
+
+	if err := verify(tmpPath, b); err != nil {
+		return "", fmt.Errorf("backup verification failed: %w", err)
+	}
+
+	path := filepath.Join(dir, Filename(b.CreatedAt))
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("failed to move backup into place: %w", err)
+	}
+	return path, nil
+}
+
+// verify reads back a written backup and checks it matches what was written
+func verify(path string, want *archive.Backup) error {
+	got, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if errs := got.Validate(); len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if len(got.Users) != len(want.Users) || len(got.Groups) != len(want.Groups) || got.Counts() != want.Counts() ||
+		len(got.Devices) != len(want.Devices) || len(got.GroupEvents) != len(want.GroupEvents) ||
+		len(got.LedgerChain) != len(want.LedgerChain) || len(groupFiles(got)) != len(groupFiles(want)) {
+		return fmt.Errorf("read back different contents than were written")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/archive"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func sample() *archive.Backup {
+	head := uuid.New()
+	created := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	return &archive.Backup{
+		FormatVersion: archive.FormatVersion,
+		CreatedAt:     time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC),
+		Users: []archive.User{
+			{ID: head, Email: "parent@example.com", PasswordHash: "hash", Name: "Parent", CreatedAt: created},
+		},
+		Groups: []*archive.Archive{{
+			FormatVersion: archive.FormatVersion,
+			Group:         archive.Group{ID: uuid.New(), Name: "Family", HeadUserID: head, CreatedAt: created},
+			Members: []archive.Member{
+				{UserID: head, Email: "parent@example.com", Name: "Parent", Role: models.RoleHead, JoinedAt: created},
+			},
+		}},
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	b := sample()
+
+	path, err := Write(dir, b)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "pocket-money-20261001T030000Z.zip"), path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	got, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, b.Users[0].PasswordHash, got.Users[0].PasswordHash)
+	assert.Equal(t, b.Groups[0].Group.ID, got.Groups[0].Group.ID)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteAndReadFiles(t *testing.T) {
+	b := sample()
+	g := b.Groups[0]
+	chore := archive.Chore{ID: uuid.New(), Name: "Dishes", Amount: 1, CreatedAt: g.Group.CreatedAt}
+	g.Chores = []archive.Chore{chore}
+	g.LedgerEntries = []archive.LedgerEntry{{
+		ID: uuid.New(), UserID: g.Group.HeadUserID, ChoreID: &chore.ID, Amount: 1, Status: models.StatusApproved,
+		CreatedByUserID: g.Group.HeadUserID, CreatedAt: g.Group.CreatedAt, OccurredAt: g.Group.CreatedAt,
+	}}
+	g.Attachments = []archive.Attachment{{
+		ID: uuid.New(), EntryID: g.LedgerEntries[0].ID, UploadedByUserID: g.Group.HeadUserID,
+		File: archive.File{StorageKey: "ledger/g/e/a", ContentType: "image/jpeg", SizeBytes: 5},
+	}}
+	g.Files = map[string][]byte{"ledger/g/e/a": []byte("photo")}
+
+	path, err := Write(t.TempDir(), b)
+	require.NoError(t, err)
+	got, err := Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, g.Files, got.Groups[0].Files)
+	assert.Equal(t, 1, got.Counts().Attachments)
+}
+
+func TestWriteRejectsInvalidBackup(t *testing.T) {
+	dir := t.TempDir()
+	b := sample()
+	b.Users = nil
+
+	_, err := Write(dir, b)
+	assert.ErrorContains(t, err, "not a backed-up user")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDecodeDetectsCorruption(t *testing.T) {
+	path, err := Write(t.TempDir(), sample())
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = Decode(data[:len(data)/2])
+	assert.Error(t, err)
+}
+
+func TestExpired(t *testing.T) {
+	// One backup every 12 hours for 30 days, newest first
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var files []File
+	for i := 0; i < 60; i++ {
+		files = append(files, File{Path: Filename(now.Add(-time.Duration(i) * 12 * time.Hour)), CreatedAt: now.Add(-time.Duration(i) * 12 * time.Hour)})
+	}
+
+	expired := Expired(files, Policy{KeepLast: 3, KeepDaily: 3, KeepWeekly: 2})
+	kept := len(files) - len(expired)
+
+	// Last 3: Oct 1 12:00, Oct 1 00:00, Sep 30 12:00.
+	// Daily adds Sep 29 12:00; weekly (Sep 28 - Oct 4 already kept) adds Sep 27 12:00.
+	assert.Equal(t, 5, kept)
+	expiredPaths := make(map[string]bool)
+	for _, f := range expired {
+		expiredPaths[f.Path] = true
+	}
+	assert.False(t, expiredPaths[Filename(time.Date(2026, 9, 29, 12, 0, 0, 0, time.UTC))])
+	assert.False(t, expiredPaths[Filename(time.Date(2026, 9, 27, 12, 0, 0, 0, time.UTC))])
+	assert.True(t, expiredPaths[Filename(time.Date(2026, 9, 29, 0, 0, 0, 0, time.UTC))])
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, Filename(base.AddDate(0, 0, -i))), nil, 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+
+	removed, err := Prune(dir, Policy{KeepLast: 2})
+	require.NoError(t, err)
+	assert.Len(t, removed, 3)
+
+	files, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, base, files[0].CreatedAt)
+	_, err = os.Stat(filepath.Join(dir, "notes.txt"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Policy says which backups to keep. A backup is kept if any rule keeps it.
+type Policy struct {
+	// KeepLast keeps the newest N backups
+	KeepLast int
+	// KeepDaily keeps the newest backup of each of the last N days that have one
+	KeepDaily int
+	// KeepWeekly keeps the newest backup of each of the last N ISO weeks that have one
+	KeepWeekly int
+}
+
+// File is a backup found on disk
+type File struct {
+	Path      string
+	CreatedAt time.Time
+}
+
+// List returns the backups in dir, newest first. Other files are ignored.
+func List(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var files []File
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t, ok := parseFilename(e.Name())
+		if !ok {
+			continue
+		}
+		files = append(files, File{Path: filepath.Join(dir, e.Name()), CreatedAt: t})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files, nil
+}
+
+// Expired returns the files the policy does not keep. files must be newest first.
+func Expired(files []File, p Policy) []File {
+	keep := make([]bool, len(files))
+	for i := 0; i < len(files) && i < p.KeepLast; i++ {
+		keep[i] = true
+	}
+	keepNewestPer(files, keep, p.KeepDaily, func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	})
+	keepNewestPer(files, keep, p.KeepWeekly, func(t time.Time) string {
+		year, week := t.UTC().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+
+	var expired []File
+	for i, f := range files {
+		if !keep[i] {
+			expired = append(expired, f)
+		}
+	}
+	return expired
+}
+
+// keepNewestPer marks the newest file in each of the first n buckets
+func keepNewestPer(files []File, keep []bool, n int, bucket func(time.Time) string) {
+	seen := make(map[string]bool, n)
+	for i, f := range files {
+		if len(seen) >= n {
+			return
+		}
+		b := bucket(f.CreatedAt)
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+		keep[i] = true
+	}
+}
+
+// Prune deletes the backups in dir that the policy does not keep and
+// returns their paths
+func Prune(dir string, p Policy) ([]string, error) {
+	files, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, f := range Expired(files, p) {
+		if err := os.Remove(f.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", f.Path, err)
+		}
+		removed = append(removed, f.Path)
+	}
+	return removed, nil
+}
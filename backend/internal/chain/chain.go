@@ -0,0 +1,171 @@
+package chain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// RecordType identifies which table a chain link covers
+type RecordType string
+
+const (
+	RecordLedgerEntry RecordType = "ledger_entry"
+	RecordSettlement  RecordType = "settlement"
+)
+
+// GenesisHash is the previous hash of the first link in every group's chain
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Hasher computes link hashes for the ledger chain.
+// With a key the hash is an HMAC-SHA256, so someone with direct database
+// access cannot recompute the chain after editing rows.
+type Hasher struct {
+	key []byte
+}
+
+// NewHasher creates a Hasher; an empty key falls back to plain SHA-256
+func NewHasher(key string) *Hasher {
+	return &Hasher{key: []byte(key)}
+}
+
+// Hash computes the hash of a link from its position, predecessor and payload
+func (h *Hasher) Hash(groupID uuid.UUID, seq int64, recordType RecordType, prevHash, payload string) string {
+	var mac hash.Hash
+	if len(h.key) > 0 {
+		mac = hmac.New(sha256.New, h.key)
+	} else {
+		mac = sha256.New()
+	}
+	fmt.Fprintf(mac, "%s|%d|%s|%s|%s", groupID, seq, recordType, prevHash, payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Payload is the canonical field set of a chained row
+type Payload map[string]interface{}
+
+// Canonical encodes a payload as JSON with sorted keys
+func Canonical(p Payload) (string, error) {
+	b, err := json.Marshal(map[string]interface{}(p))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode canonical payload: %w", err)
+	}
+	return string(b), nil
+}
+
+// Restrict returns the subset of p whose keys appear in stored.
+// Columns added after a link was written are not part of its payload
+// and must not invalidate it.
+func Restrict(p Payload, stored string) (Payload, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(stored), &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode stored payload: %w", err)
+	}
+
+	restricted := make(Payload, len(keys))
+	for k := range keys {
+		v, ok := p[k]
+		if !ok {
+			// Keep the key so the comparison fails instead of silently passing
+			restricted[k] = "<missing>"
+			continue
+		}
+		restricted[k] = v
+	}
+	return restricted, nil
+}
+
+// LedgerEntryPayload returns the canonical fields of a ledger entry
+func LedgerEntryPayload(e *models.LedgerEntry) Payload {
+	return Payload{
+		"id":                  e.ID.String(),
+		"group_id":            e.GroupID.String(),
+		"user_id":             e.UserID.String(),
+		"kind":                string(e.Kind),
+		"chore_id":            optionalUUID(e.ChoreID),
+		"allowance_id":        optionalUUID(e.AllowanceID),
+		"allowance_period":    optionalDate(e.AllowancePeriod),
+		"interest_rate_id":    optionalUUID(e.InterestRateID),
+		"loan_id":             optionalUUID(e.LoanID),
+		"withheld_from_id":    optionalUUID(e.WithheldFromID),
+		"amount":              formatAmount(e.Amount),
+		"status":              string(e.Status),
+		"created_by_user_id":  e.CreatedByUserID.String(),
+		"approved_by_user_id": optionalUUID(e.ApprovedByUserID),
+		"rejected_by_user_id": optionalUUID(e.RejectedByUserID),
+		"status_reason":       optionalString(e.StatusReason),
+		"system_actor":        optionalString((*string)(e.SystemActor)),
+		"approval_rule_id":    optionalUUID(e.ApprovalRuleID),
+		"resubmitted_from_id": optionalUUID(e.ResubmittedFromID),
+		"occurred_at":         formatTime(e.OccurredAt),
+		"created_at":          formatTime(e.CreatedAt),
+	}
+}
+
+// SettlementPayload returns the canonical fields of a settlement
+func SettlementPayload(s *models.Settlement) Payload {
+	return Payload{
+		"id":                 s.ID.String(),
+		"group_id":           s.GroupID.String(),
+		"user_id":            s.UserID.String(),
+		"amount":             formatAmount(s.Amount),
+		"date":               s.Date.Format("2006-01-02"),
+		"note":               optionalString(s.Note),
+		"method":             string(s.Method),
+		"external_reference": optionalString(s.ExternalReference),
+		"category_id":        optionalUUID(s.CategoryID),
+		"jar_id":             optionalUUID(s.JarID),
+		"status":             string(s.Status),
+		"acknowledged_at":    optionalTime(s.AcknowledgedAt),
+		"disputed_at":        optionalTime(s.DisputedAt),
+		"dispute_reason":     optionalString(s.DisputeReason),
+		"created_at":         formatTime(s.CreatedAt),
+		"voided_at":          optionalTime(s.VoidedAt),
+		"voided_by_user_id":  optionalUUID(s.VoidedByUserID),
+		"void_reason":        optionalString(s.VoidReason),
+	}
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+func optionalUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func optionalDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+func optionalString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
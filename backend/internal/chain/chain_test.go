@@ -0,0 +1,241 @@
+package chain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// buildChain links the given entries in order and returns the links and current rows
+func buildChain(t *testing.T, h *Hasher, groupID uuid.UUID, entries []*models.LedgerEntry) ([]Link, map[RecordKey]Record) {
+	t.Helper()
+
+	links := make([]Link, 0, len(entries))
+	current := make(map[RecordKey]Record)
+	prev := GenesisHash
+	for i, e := range entries {
+		payload, err := Canonical(LedgerEntryPayload(e))
+		require.NoError(t, err)
+
+		seq := int64(i + 1)
+		hash := h.Hash(groupID, seq, RecordLedgerEntry, prev, payload)
+		links = append(links, Link{
+			GroupID:    groupID,
+			Seq:        seq,
+			RecordType: RecordLedgerEntry,
+			RecordID:   e.ID,
+			Payload:    payload,
+			PrevHash:   prev,
+			Hash:       hash,
+		})
+		prev = hash
+
+		h := hash
+		current[RecordKey{Type: RecordLedgerEntry, ID: e.ID}] = Record{Payload: LedgerEntryPayload(e), Hash: &h}
+	}
+	return links, current
+}
+
+func newEntries(groupID uuid.UUID, n int) []*models.LedgerEntry {
+	entries := make([]*models.LedgerEntry, 0, n)
+	for i := 0; i < n; i++ {
+		choreID := uuid.New()
+		entries = append(entries, &models.LedgerEntry{
+			ID:              uuid.New(),
+			GroupID:         groupID,
+			UserID:          uuid.New(),
+			Kind:            models.EntryChore,
+			ChoreID:         &choreID,
+			Amount:          float64(i+1) * 1.5,
+			Status:          models.StatusApproved,
+			CreatedByUserID: uuid.New(),
+			CreatedAt:       time.Date(2026, 9, 1, 10, i, 0, 0, time.UTC),
+		})
+	}
+	return entries
+}
+
+func TestCanonical_SortedAndStable(t *testing.T) {
+	a, err := Canonical(Payload{"b": "2", "a": "1", "c": nil})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"1","b":"2","c":null}`, a)
+}
+
+func TestHash_KeyChangesResult(t *testing.T) {
+	groupID := uuid.New()
+	plain := NewHasher("").Hash(groupID, 1, RecordLedgerEntry, GenesisHash, "{}")
+	keyed := NewHasher("secret").Hash(groupID, 1, RecordLedgerEntry, GenesisHash, "{}")
+
+	assert.Len(t, plain, 64)
+	assert.NotEqual(t, plain, keyed)
+}
+
+func TestVerify_ValidChain(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	links, current := buildChain(t, h, groupID, newEntries(groupID, 3))
+
+	report := h.Verify(groupID, links, current)
+
+	assert.True(t, report.Valid)
+	assert.Nil(t, report.FirstBreak)
+	assert.Equal(t, 3, report.Links)
+	assert.Equal(t, 3, report.Records)
+}
+
+func TestVerify_EditedRow(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	entries := newEntries(groupID, 3)
+	links, current := buildChain(t, h, groupID, entries)
+
+	// Someone bumps the amount of the second entry directly in the database
+	edited := *entries[1]
+	edited.Amount = 500
+	key := RecordKey{Type: RecordLedgerEntry, ID: edited.ID}
+	current[key] = Record{Payload: LedgerEntryPayload(&edited), Hash: current[key].Hash}
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	require.NotNil(t, report.FirstBreak)
+	assert.Equal(t, int64(2), report.FirstBreak.Seq)
+	assert.Equal(t, edited.ID, report.FirstBreak.RecordID)
+	assert.Contains(t, report.FirstBreak.Reason, "modified")
+}
+
+func TestVerify_DeletedRow(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	entries := newEntries(groupID, 3)
+	links, current := buildChain(t, h, groupID, entries)
+
+	delete(current, RecordKey{Type: RecordLedgerEntry, ID: entries[0].ID})
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	assert.Equal(t, int64(1), report.FirstBreak.Seq)
+	assert.Contains(t, report.FirstBreak.Reason, "deleted")
+}
+
+func TestVerify_DeletedLink(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	entries := newEntries(groupID, 3)
+	links, current := buildChain(t, h, groupID, entries)
+
+	// Remove the second link and its row together
+	links = append(links[:1], links[2:]...)
+	delete(current, RecordKey{Type: RecordLedgerEntry, ID: entries[1].ID})
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	assert.Equal(t, int64(3), report.FirstBreak.Seq)
+	assert.Contains(t, report.FirstBreak.Reason, "missing")
+}
+
+func TestVerify_RecomputedLinkWithoutKey(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	entries := newEntries(groupID, 2)
+	links, current := buildChain(t, h, groupID, entries)
+
+	// An attacker edits the row and rewrites the last link with an unkeyed hash
+	edited := *entries[1]
+	edited.Amount = 500
+	payload, err := Canonical(LedgerEntryPayload(&edited))
+	require.NoError(t, err)
+	links[1].Payload = payload
+	links[1].Hash = NewHasher("").Hash(groupID, 2, RecordLedgerEntry, links[1].PrevHash, payload)
+	current[RecordKey{Type: RecordLedgerEntry, ID: edited.ID}] = Record{Payload: LedgerEntryPayload(&edited), Hash: &links[1].Hash}
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	assert.Equal(t, int64(2), report.FirstBreak.Seq)
+	assert.Contains(t, report.FirstBreak.Reason, "link hash")
+}
+
+func TestVerify_UnchainedRow(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	links, current := buildChain(t, h, groupID, newEntries(groupID, 1))
+
+	inserted := newEntries(groupID, 1)[0]
+	current[RecordKey{Type: RecordLedgerEntry, ID: inserted.ID}] = Record{Payload: LedgerEntryPayload(inserted)}
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	assert.Equal(t, int64(0), report.FirstBreak.Seq)
+	assert.Equal(t, inserted.ID, report.FirstBreak.RecordID)
+	assert.Contains(t, report.FirstBreak.Reason, "not in the chain")
+}
+
+func TestVerify_NewColumnsDoNotBreakOldLinks(t *testing.T) {
+	h := NewHasher("")
+	groupID := uuid.New()
+	entries := newEntries(groupID, 1)
+	links, current := buildChain(t, h, groupID, entries)
+
+	key := RecordKey{Type: RecordLedgerEntry, ID: entries[0].ID}
+	rec := current[key]
+	rec.Payload["added_later"] = "value"
+	current[key] = rec
+
+	report := h.Verify(groupID, links, current)
+
+	assert.True(t, report.Valid)
+}
+
+func TestVerify_EditedSettlementDispute(t *testing.T) {
+	h := NewHasher("secret")
+	groupID := uuid.New()
+	disputedAt := time.Date(2026, 9, 2, 9, 0, 0, 0, time.UTC)
+	reason := "I only got half"
+	settlement := &models.Settlement{
+		ID:            uuid.New(),
+		GroupID:       groupID,
+		UserID:        uuid.New(),
+		Amount:        10,
+		Date:          time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		Method:        models.MethodCash,
+		Status:        models.SettlementDisputed,
+		DisputedAt:    &disputedAt,
+		DisputeReason: &reason,
+		CreatedAt:     time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	payload, err := Canonical(SettlementPayload(settlement))
+	require.NoError(t, err)
+	hash := h.Hash(groupID, 1, RecordSettlement, GenesisHash, payload)
+	links := []Link{{
+		GroupID:    groupID,
+		Seq:        1,
+		RecordType: RecordSettlement,
+		RecordID:   settlement.ID,
+		Payload:    payload,
+		PrevHash:   GenesisHash,
+		Hash:       hash,
+	}}
+
+	// Someone rewrites the member's dispute reason directly in the database
+	edited := *settlement
+	changed := "All good"
+	edited.DisputeReason = &changed
+	current := map[RecordKey]Record{
+		{Type: RecordSettlement, ID: settlement.ID}: {Payload: SettlementPayload(&edited), Hash: &hash},
+	}
+
+	report := h.Verify(groupID, links, current)
+
+	require.False(t, report.Valid)
+	assert.Contains(t, report.FirstBreak.Reason, "modified")
+}
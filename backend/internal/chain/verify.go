@@ -0,0 +1,150 @@
+package chain
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Link is a single entry in a group's hash chain
+type Link struct {
+	GroupID    uuid.UUID
+	Seq        int64
+	RecordType RecordType
+	RecordID   uuid.UUID
+	Payload    string
+	PrevHash   string
+	Hash       string
+}
+
+// RecordKey identifies a chained row
+type RecordKey struct {
+	Type RecordType
+	ID   uuid.UUID
+}
+
+// Record is the current state of a chained row as read from its table
+type Record struct {
+	Payload Payload
+	Hash    *string
+}
+
+// Break describes a point where the chain no longer matches the data
+type Break struct {
+	Seq        int64      `json:"seq,omitempty"`
+	RecordType RecordType `json:"record_type"`
+	RecordID   uuid.UUID  `json:"record_id"`
+	Reason     string     `json:"reason"`
+}
+
+// Report is the result of verifying a group's chain
+type Report struct {
+	GroupID    uuid.UUID `json:"group_id"`
+	Valid      bool      `json:"valid"`
+	Links      int       `json:"links"`
+	Records    int       `json:"records"`
+	FirstBreak *Break    `json:"first_break,omitempty"`
+	Breaks     []Break   `json:"breaks"`
+}
+
+// Verify checks a group's links (ordered by seq) against the current rows.
+// It reports gaps, broken hash links, rows edited or deleted after their
+// last link, and rows that were inserted without going through the chain.
+func (h *Hasher) Verify(groupID uuid.UUID, links []Link, current map[RecordKey]Record) *Report {
+	report := &Report{
+		GroupID: groupID,
+		Links:   len(links),
+		Records: len(current),
+		Breaks:  []Break{},
+	}
+
+	latest := make(map[RecordKey]Link)
+	prev := GenesisHash
+	for i, l := range links {
+		if expected := int64(i + 1); l.Seq != expected {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: l.RecordType, RecordID: l.RecordID,
+				Reason: fmt.Sprintf("links missing before seq %d (expected seq %d)", l.Seq, expected),
+			})
+		} else if l.PrevHash != prev {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: l.RecordType, RecordID: l.RecordID,
+				Reason: "previous hash does not match preceding link",
+			})
+		}
+		if h.Hash(groupID, l.Seq, l.RecordType, l.PrevHash, l.Payload) != l.Hash {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: l.RecordType, RecordID: l.RecordID,
+				Reason: "link hash does not match its contents",
+			})
+		}
+		// Continue from the stored hash so one break does not cascade
+		prev = l.Hash
+		latest[RecordKey{Type: l.RecordType, ID: l.RecordID}] = l
+	}
+
+	for key, l := range latest {
+		rec, ok := current[key]
+		if !ok {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: key.Type, RecordID: key.ID,
+				Reason: "record was deleted",
+			})
+			continue
+		}
+		if !payloadMatches(rec.Payload, l.Payload) {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: key.Type, RecordID: key.ID,
+				Reason: "record was modified after it was chained",
+			})
+			continue
+		}
+		if rec.Hash == nil || *rec.Hash != l.Hash {
+			report.Breaks = append(report.Breaks, Break{
+				Seq: l.Seq, RecordType: key.Type, RecordID: key.ID,
+				Reason: "record hash does not match its latest link",
+			})
+		}
+	}
+
+	for key := range current {
+		if _, ok := latest[key]; !ok {
+			report.Breaks = append(report.Breaks, Break{
+				RecordType: key.Type, RecordID: key.ID,
+				Reason: "record is not in the chain",
+			})
+		}
+	}
+
+	// Order by seq; records outside the chain come last
+	sort.SliceStable(report.Breaks, func(i, j int) bool {
+		a, b := report.Breaks[i], report.Breaks[j]
+		if a.Seq == 0 || b.Seq == 0 {
+			if a.Seq == b.Seq {
+				return a.RecordID.String() < b.RecordID.String()
+			}
+			return b.Seq == 0
+		}
+		return a.Seq < b.Seq
+	})
+
+	report.Valid = len(report.Breaks) == 0
+	if !report.Valid {
+		report.FirstBreak = &report.Breaks[0]
+	}
+
+	return report
+}
+
+func payloadMatches(current Payload, stored string) bool {
+	restricted, err := Restrict(current, stored)
+	if err != nil {
+		return false
+	}
+	encoded, err := Canonical(restricted)
+	if err != nil {
+		return false
+	}
+	return encoded == stored
+}
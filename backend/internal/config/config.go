@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
 )
 
 // Config holds all configuration for the application
@@ -11,16 +16,134 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	CORSOrigins string
+	// AdminEmails are the instance admins, who may import group archives
+	// that add other accounts
+	AdminEmails []string
+	// LedgerChainKey keys the ledger hash chain; empty means unkeyed SHA-256
+	LedgerChainKey string
+	Backup         BackupConfig
+	Push           PushConfig
+	Mail           MailConfig
+	Reminders      ReminderConfig
+	Attachments    AttachmentConfig
+}
+
+// AttachmentConfig controls photo uploads on ledger entries
+type AttachmentConfig struct {
+	// Dir is where uploaded photos are stored
+	Dir string
+	// MaxBytes is the largest photo accepted
+	MaxBytes int
+}
+
+// ReminderConfig controls reminders about entries awaiting approval and
+// chores that are due
+type ReminderConfig struct {
+	// Schedule is a cron expression for checking for due reminders; "off" disables them
+	Schedule string
+	// PendingAfterHours is how long an entry waits before heads are reminded, and between reminders
+	PendingAfterHours int
+	// ChoreDueHours is how long before a chore is due that its assignee is reminded
+	ChoreDueHours int
+	// ChoreOverdueHours is how long between nudges about an overdue chore
+	ChoreOverdueHours int
+}
+
+// PushConfig selects the push notification provider
+type PushConfig struct {
+	// Provider is "expo" to deliver through Expo, or "local" to only log messages
+	Provider string
+	// ExpoAccessToken is sent when Expo enhanced push security is enabled
+	ExpoAccessToken string
+}
+
+// BackupConfig controls scheduled backups
+type BackupConfig struct {
+	// Schedule is a cron expression; empty disables scheduled backups
+	Schedule   string
+	Dir        string
+	KeepLast   int
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// MailConfig controls email notifications and the weekly digest
+type MailConfig struct {
+	// SMTPHost is the SMTP server; empty disables email and only logs it
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	// PublicURL is where the API is reached from email links
+	PublicURL string
+	// DigestSchedule is a cron expression for the weekly digest; "off" disables it
+	DigestSchedule string
+}
+
+// Enabled reports whether an SMTP server is configured
+func (m MailConfig) Enabled() bool {
+	return m.SMTPHost != ""
 }
 
 // Load loads configuration from environment variables
 // Returns an error if required variables are missing
 func Load() (*Config, error) {
 	cfg := &Config{
-		Port:        getEnvOrDefault("PORT", "8080"),
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
-		CORSOrigins: getEnvOrDefault("CORS_ORIGINS", "*"),
+		Port:           getEnvOrDefault("PORT", "8080"),
+		DatabaseURL:    os.Getenv("DATABASE_URL"),
+		JWTSecret:      os.Getenv("JWT_SECRET"),
+		CORSOrigins:    getEnvOrDefault("CORS_ORIGINS", "*"),
+		LedgerChainKey: os.Getenv("LEDGER_CHAIN_KEY"),
+		AdminEmails:    getEnvList("ADMIN_EMAILS"),
+		Backup: BackupConfig{
+			Schedule: os.Getenv("BACKUP_SCHEDULE"),
+			Dir:      getEnvOrDefault("BACKUP_DIR", "./backups"),
+		},
+		Push: PushConfig{
+			Provider:        getEnvOrDefault("PUSH_PROVIDER", "local"),
+			ExpoAccessToken: os.Getenv("EXPO_ACCESS_TOKEN"),
+		},
+		Mail: MailConfig{
+			SMTPHost:       os.Getenv("SMTP_HOST"),
+			SMTPUsername:   os.Getenv("SMTP_USERNAME"),
+			SMTPPassword:   os.Getenv("SMTP_PASSWORD"),
+			From:           os.Getenv("MAIL_FROM"),
+			PublicURL:      getEnvOrDefault("PUBLIC_URL", "http://localhost:8080"),
+			DigestSchedule: getEnvOrDefault("EMAIL_DIGEST_SCHEDULE", "0 8 * * 1"),
+		},
+		Reminders: ReminderConfig{
+			Schedule: getEnvOrDefault("REMINDER_SCHEDULE", "0 * * * *"),
+		},
+		Attachments: AttachmentConfig{
+			Dir: getEnvOrDefault("ATTACHMENT_DIR", "./attachments"),
+		},
+	}
+
+	var err error
+	if cfg.Backup.KeepLast, err = getEnvInt("BACKUP_KEEP_LAST", 7); err != nil {
+		return nil, err
+	}
+	if cfg.Backup.KeepDaily, err = getEnvInt("BACKUP_KEEP_DAILY", 7); err != nil {
+		return nil, err
+	}
+	if cfg.Backup.KeepWeekly, err = getEnvInt("BACKUP_KEEP_WEEKLY", 4); err != nil {
+		return nil, err
+	}
+	if cfg.Mail.SMTPPort, err = getEnvInt("SMTP_PORT", 587); err != nil {
+		return nil, err
+	}
+	if cfg.Reminders.PendingAfterHours, err = getEnvInt("PENDING_REMINDER_HOURS", 24); err != nil {
+		return nil, err
+	}
+	if cfg.Reminders.ChoreDueHours, err = getEnvInt("CHORE_REMINDER_HOURS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.Reminders.ChoreOverdueHours, err = getEnvInt("CHORE_OVERDUE_HOURS", 24); err != nil {
+		return nil, err
+	}
+	if cfg.Attachments.MaxBytes, err = getEnvInt("ATTACHMENT_MAX_BYTES", 5<<20); err != nil {
+		return nil, err
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -47,12 +170,77 @@ func (c *Config) validate() error {
 	if c.JWTSecret == "" {
 		return fmt.Errorf("JWT_SECRET is required")
 	}
+	if c.Backup.Schedule != "" {
+		if _, err := scheduler.Parse(c.Backup.Schedule); err != nil {
+			return fmt.Errorf("BACKUP_SCHEDULE: %w", err)
+		}
+	}
+	if c.Backup.KeepLast < 1 {
+		return fmt.Errorf("BACKUP_KEEP_LAST must be at least 1")
+	}
+	if c.Push.Provider != "local" && c.Push.Provider != "expo" {
+		return fmt.Errorf("PUSH_PROVIDER must be local or expo")
+	}
+	if c.Mail.Enabled() && c.Mail.From == "" {
+		return fmt.Errorf("MAIL_FROM is required when SMTP_HOST is set")
+	}
+	if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+		return fmt.Errorf("SMTP_PORT must be between 1 and 65535")
+	}
+	if u, err := url.Parse(c.Mail.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("PUBLIC_URL must be an http or https URL")
+	}
+	if c.Mail.DigestSchedule != "off" {
+		if _, err := scheduler.Parse(c.Mail.DigestSchedule); err != nil {
+			return fmt.Errorf("EMAIL_DIGEST_SCHEDULE: %w", err)
+		}
+	}
+	if c.Reminders.Schedule != "off" {
+		if _, err := scheduler.Parse(c.Reminders.Schedule); err != nil {
+			return fmt.Errorf("REMINDER_SCHEDULE: %w", err)
+		}
+	}
+	if c.Reminders.PendingAfterHours < 1 {
+		return fmt.Errorf("PENDING_REMINDER_HOURS must be at least 1")
+	}
+	if c.Reminders.ChoreDueHours < 1 {
+		return fmt.Errorf("CHORE_REMINDER_HOURS must be at least 1")
+	}
+	if c.Reminders.ChoreOverdueHours < 1 {
+		return fmt.Errorf("CHORE_OVERDUE_HOURS must be at least 1")
+	}
+	if c.Attachments.MaxBytes < 1 {
+		return fmt.Errorf("ATTACHMENT_MAX_BYTES must be at least 1")
+	}
 	return nil
 }
 
+// getEnvList reads a comma-separated list, skipping empty items
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
@@ -0,0 +1,365 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrAllowancePaused is returned when pausing an allowance that is already paused
+var ErrAllowancePaused = errors.New("allowance is already paused")
+
+// ErrAllowanceNotPaused is returned when resuming an allowance that is not paused
+var ErrAllowanceNotPaused = errors.New("allowance is not paused")
+
+// AllowanceRepo handles database operations for allowances and the ledger
+// entries that credit them
+type AllowanceRepo struct {
+	pool  *pgxpool.Pool
+	chain *ChainRepo
+}
+
+// NewAllowanceRepo creates a new AllowanceRepo
+func NewAllowanceRepo(pool *pgxpool.Pool, chainRepo *ChainRepo) *AllowanceRepo {
+	return &AllowanceRepo{pool: pool, chain: chainRepo}
+}
+
+const allowanceColumns = `id, group_id, user_id, amount, amount_per_year, interval_unit, start_date, next_period, paused_at, created_by_user_id, created_at, updated_at, deleted_at`
+
+// allowanceDue selects, after allowanceColumns, the member's dob and whether
+// they are still in the group, which posting due periods needs
+const allowanceDue = `,
+	(SELECT dob FROM users WHERE id = allowances.user_id),
+	EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = allowances.group_id AND gm.user_id = allowances.user_id)`
+
+// allowanceFields returns the scan destinations for allowanceColumns
+func allowanceFields(a *models.Allowance) []any {
+	return []any{&a.ID, &a.GroupID, &a.UserID, &a.Amount, &a.AmountPerYear, &a.Interval, &a.StartDate, &a.NextPeriod,
+		&a.PausedAt, &a.CreatedByUserID, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt}
+}
+
+func scanAllowance(row pgx.Row) (*models.Allowance, error) {
+	a := &models.Allowance{}
+	err := row.Scan(allowanceFields(a)...)
+	return a, err
+}
+
+// dueAllowance is an allowance locked for posting, with what posting needs
+type dueAllowance struct {
+	allowance *models.Allowance
+	dob       *time.Time
+	member    bool
+}
+
+func scanDueAllowance(row pgx.Row) (*dueAllowance, error) {
+	d := &dueAllowance{allowance: &models.Allowance{}}
+	err := row.Scan(append(allowanceFields(d.allowance), &d.dob, &d.member)...)
+	return d, err
+}
+
+// Create inserts a new allowance whose first period starts on its start date
+func (r *AllowanceRepo) Create(ctx context.Context, a *models.Allowance) (*models.Allowance, error) {
+	query := `
+		INSERT INTO allowances (group_id, user_id, amount, amount_per_year, interval_unit, start_date, next_period, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+		RETURNING ` + allowanceColumns
+
+	created, err := scanAllowance(r.pool.QueryRow(ctx, query,
+		a.GroupID, a.UserID, a.Amount, a.AmountPerYear, a.Interval, a.StartDate, a.CreatedByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allowance: %w", err)
+	}
+	return created, nil
+}
+
+// GetByID retrieves an allowance by ID, including deleted ones
+func (r *AllowanceRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Allowance, error) {
+	a, err := scanAllowance(r.pool.QueryRow(ctx, `SELECT `+allowanceColumns+` FROM allowances WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get allowance by id: %w", err)
+	}
+	return a, nil
+}
+
+// ListForGroup retrieves a group's allowances, optionally only one member's,
+// oldest first. Deleted allowances are only included if includeDeleted is set.
+func (r *AllowanceRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, userID *uuid.UUID, includeDeleted bool) ([]*models.Allowance, error) {
+	query := `
+		SELECT ` + allowanceColumns + `
+		FROM allowances
+		WHERE group_id = $1 AND ($2::uuid IS NULL OR user_id = $2) AND ($3 OR deleted_at IS NULL)
+		ORDER BY created_at, id
+	`
+	rows, err := r.pool.Query(ctx, query, groupID, userID, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list allowances: %w", err)
+	}
+	defer rows.Close()
+
+	var allowances []*models.Allowance
+	for rows.Next() {
+		a, err := scanAllowance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan allowance: %w", err)
+		}
+		allowances = append(allowances, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read allowances: %w", err)
+	}
+	return allowances, nil
+}
+
+// AllowanceUpdate holds the changes to an allowance; nil fields are kept.
+// Changes apply to periods not yet credited.
+type AllowanceUpdate struct {
+	Amount           *float64
+	SetAmountPerYear bool // AmountPerYear replaces the current value, nil paying the fixed amount
+	AmountPerYear    *float64
+}
+
+// Update changes the amounts of an allowance that has not been deleted
+func (r *AllowanceRepo) Update(ctx context.Context, id uuid.UUID, u AllowanceUpdate) (*models.Allowance, error) {
+	query := `
+		UPDATE allowances
+		SET amount = COALESCE($2, amount),
+		    amount_per_year = CASE WHEN $3 THEN $4 ELSE amount_per_year END,
+		    updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + allowanceColumns
+
+	a, err := scanAllowance(r.pool.QueryRow(ctx, query, id, u.Amount, u.SetAmountPerYear, u.AmountPerYear))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update allowance: %w", err)
+	}
+	return a, nil
+}
+
+// Pause stops an allowance from crediting new periods. Periods that began
+// before now and were not credited yet, e.g. while the server was down,
+// are credited first and their entries returned.
+func (r *AllowanceRepo) Pause(ctx context.Context, id uuid.UUID, now time.Time) (*models.Allowance, []*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	d, err := lockAllowance(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.allowance.PausedAt != nil {
+		return nil, nil, ErrAllowancePaused
+	}
+
+	var entries []*models.LedgerEntry
+	if d.member {
+		if entries, err = r.postDue(ctx, tx, d, now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	a, err := scanAllowance(tx.QueryRow(ctx, `
+		UPDATE allowances SET paused_at = $2, updated_at = now()
+		WHERE id = $1
+		RETURNING `+allowanceColumns, id, now))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pause allowance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return a, entries, nil
+}
+
+// Resume restarts a paused allowance. Periods that began while it was
+// paused are skipped; the next one credited is the first starting today or later.
+func (r *AllowanceRepo) Resume(ctx context.Context, id uuid.UUID, now time.Time) (*models.Allowance, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	d, err := lockAllowance(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.allowance.PausedAt == nil {
+		return nil, ErrAllowanceNotPaused
+	}
+
+	next := allowance.Next(d.allowance.StartDate, d.allowance.Interval, now)
+	if next.Before(d.allowance.NextPeriod) {
+		next = d.allowance.NextPeriod
+	}
+
+	a, err := scanAllowance(tx.QueryRow(ctx, `
+		UPDATE allowances SET paused_at = NULL, next_period = $2, updated_at = now()
+		WHERE id = $1
+		RETURNING `+allowanceColumns, id, next))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume allowance: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return a, nil
+}
+
+// Delete stops an allowance for good. The row is kept so the entries it
+// credited still name it.
+func (r *AllowanceRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `UPDATE allowances SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete allowance: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PostDue credits every period that has begun by now for each running
+// allowance of a current member, catching up on periods missed while the
+// server was down, and returns the new entries. Each period is credited at
+// most once, so running it again or on several instances is safe.
+func (r *AllowanceRepo) PostDue(ctx context.Context, now time.Time) ([]*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+allowanceColumns+allowanceDue+`
+		FROM allowances
+		WHERE deleted_at IS NULL AND paused_at IS NULL AND next_period <= $1
+		ORDER BY next_period, id
+		FOR UPDATE SKIP LOCKED
+	`, allowance.Day(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select due allowances: %w", err)
+	}
+	var due []*dueAllowance
+	for rows.Next() {
+		d, err := scanDueAllowance(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan allowance: %w", err)
+		}
+		if d.member {
+			due = append(due, d)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read due allowances: %w", err)
+	}
+
+	var entries []*models.LedgerEntry
+	for _, d := range due {
+		posted, err := r.postDue(ctx, tx, d, now)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, posted...)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entries, nil
+}
+
+// lockAllowance selects an allowance that has not been deleted for update
+func lockAllowance(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*dueAllowance, error) {
+	d, err := scanDueAllowance(tx.QueryRow(ctx, `
+		SELECT `+allowanceColumns+allowanceDue+`
+		FROM allowances
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock allowance: %w", err)
+	}
+	return d, nil
+}
+
+// postDue credits an allowance's due periods as approved entries, each on
+// its period's start, appends them to the chain and moves the allowance on
+// to its next period. A period that already has an entry is skipped.
+func (r *AllowanceRepo) postDue(ctx context.Context, tx pgx.Tx, d *dueAllowance, now time.Time) ([]*models.LedgerEntry, error) {
+	a := d.allowance
+	periods := allowance.Due(a, now)
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	var entries []*models.LedgerEntry
+	for _, period := range periods {
+		entry := &models.LedgerEntry{
+			ID:              uuid.New(),
+			GroupID:         a.GroupID,
+			UserID:          a.UserID,
+			Kind:            models.EntryAllowance,
+			AllowanceID:     &a.ID,
+			AllowancePeriod: &period,
+			Amount:          allowance.Amount(a, d.dob, period),
+			Status:          models.StatusApproved,
+			CreatedByUserID: a.CreatedByUserID,
+		}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO ledger_entries (id, group_id, user_id, kind, allowance_id, allowance_period, amount, status, created_by_user_id, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $6::date::timestamp AT TIME ZONE 'UTC')
+			ON CONFLICT (allowance_id, allowance_period) WHERE allowance_id IS NOT NULL DO NOTHING
+			RETURNING amount, occurred_at, created_at
+		`, entry.ID, entry.GroupID, entry.UserID, entry.Kind, entry.AllowanceID, period, entry.Amount, entry.Status, entry.CreatedByUserID,
+		).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to credit allowance: %w", err)
+		}
+
+		hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = &hash
+		if err := creditApproved(ctx, tx, r.chain, entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	next := allowance.Next(a.StartDate, a.Interval, periods[len(periods)-1].AddDate(0, 0, 1))
+	if _, err := tx.Exec(ctx, `UPDATE allowances SET next_period = $2 WHERE id = $1`, a.ID, next); err != nil {
+		return nil, fmt.Errorf("failed to advance allowance: %w", err)
+	}
+	a.NextPeriod = next
+
+	return entries, nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ApprovalRuleRepo handles database operations for approval rules
+type ApprovalRuleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewApprovalRuleRepo creates a new ApprovalRuleRepo
+func NewApprovalRuleRepo(pool *pgxpool.Pool) *ApprovalRuleRepo {
+	return &ApprovalRuleRepo{pool: pool}
+}
+
+const approvalRuleColumns = `id, group_id, chore_id, user_id, max_per_day, created_by_user_id, created_at, deleted_at`
+
+func scanApprovalRule(row pgx.Row) (*models.ApprovalRule, error) {
+	rule := &models.ApprovalRule{}
+	err := row.Scan(&rule.ID, &rule.GroupID, &rule.ChoreID, &rule.UserID, &rule.MaxPerDay, &rule.CreatedByUserID, &rule.CreatedAt, &rule.DeletedAt)
+	return rule, err
+}
+
+// Create inserts a new approval rule
+func (r *ApprovalRuleRepo) Create(ctx context.Context, groupID uuid.UUID, choreID, userID *uuid.UUID, maxPerDay *int, createdByUserID uuid.UUID) (*models.ApprovalRule, error) {
+	query := `
+		INSERT INTO approval_rules (group_id, chore_id, user_id, max_per_day, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + approvalRuleColumns
+
+	rule, err := scanApprovalRule(r.pool.QueryRow(ctx, query, groupID, choreID, userID, maxPerDay, createdByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create approval rule: %w", err)
+	}
+	return rule, nil
+}
+
+// GetByID retrieves an approval rule by ID, including deleted ones
+func (r *ApprovalRuleRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.ApprovalRule, error) {
+	rule, err := scanApprovalRule(r.pool.QueryRow(ctx, `SELECT `+approvalRuleColumns+` FROM approval_rules WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get approval rule by id: %w", err)
+	}
+	return rule, nil
+}
+
+// ListForGroup retrieves a group's approval rules that have not been deleted
+func (r *ApprovalRuleRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*models.ApprovalRule, error) {
+	query := `
+		SELECT ` + approvalRuleColumns + `
+		FROM approval_rules
+		WHERE group_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`
+	rows, err := r.pool.Query(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approval rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.ApprovalRule
+	for rows.Next() {
+		rule, err := scanApprovalRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Delete stops a rule from approving entries. The row is kept so entries it
+// approved still name it.
+func (r *ApprovalRuleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `UPDATE approval_rules SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete approval rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// matchApprovalRule returns the rule that approves a new entry, if any. Rules
+// only approve entries at or below the chore's list price, never for chores
+// that require proof, and the most specific rule under its daily limit wins.
+// Matching rules are locked first so concurrent entries cannot both take a
+// rule's last approval of the day.
+func matchApprovalRule(ctx context.Context, tx pgx.Tx, groupID, userID, choreID uuid.UUID, amount float64) (*uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT r.id, r.max_per_day
+		FROM approval_rules r
+		INNER JOIN chores c ON c.id = $3
+		WHERE r.group_id = $1
+		  AND r.deleted_at IS NULL
+		  AND (r.chore_id IS NULL OR r.chore_id = $3)
+		  AND (r.user_id IS NULL OR r.user_id = $2)
+		  AND round($4::numeric, 2) <= c.amount
+		  AND NOT c.proof_required
+		ORDER BY r.chore_id IS NULL, r.user_id IS NULL, r.created_at, r.id
+		FOR UPDATE OF r
+	`, groupID, userID, choreID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match approval rules: %w", err)
+	}
+
+	type candidate struct {
+		id        uuid.UUID
+		maxPerDay *int
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.maxPerDay); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan approval rule: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to match approval rules: %w", err)
+	}
+
+	for _, c := range candidates {
+		if c.maxPerDay == nil {
+			return &c.id, nil
+		}
+		// Counted after taking the lock, so entries committed meanwhile are seen
+		var today int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM ledger_entries
+			WHERE approval_rule_id = $1 AND user_id = $2 AND created_at >= date_trunc('day', now())
+		`, c.id, userID).Scan(&today)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count approvals by rule: %w", err)
+		}
+		if today < *c.maxPerDay {
+			return &c.id, nil
+		}
+	}
+	return nil, nil
+}
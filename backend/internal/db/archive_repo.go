@@ -0,0 +1,1473 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/archive"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+)
+
+// ErrNotEmpty is returned when restoring a backup into a database that already has users
+var ErrNotEmpty = errors.New("database is not empty")
+
+// ChainError is returned when restoring a backup whose ledger chain does not
+// verify with the current key, naming the first break of each group
+type ChainError struct {
+	Breaks []string
+}
+
+func (e *ChainError) Error() string {
+	return "backup ledger chain does not verify: " + strings.Join(e.Breaks, "; ")
+}
+
+// ArchiveRepo exports groups to archives and imports them back, with the
+// uploaded files they refer to
+type ArchiveRepo struct {
+	pool  *pgxpool.Pool
+	chain *ChainRepo
+	store storage.Store
+}
+
+// NewArchiveRepo creates a new ArchiveRepo
+func NewArchiveRepo(pool *pgxpool.Pool, chainRepo *ChainRepo, store storage.Store) *ArchiveRepo {
+	return &ArchiveRepo{pool: pool, chain: chainRepo, store: store}
+}
+
+// Export reads a consistent snapshot of a group into an archive
+func (r *ArchiveRepo) Export(ctx context.Context, groupID uuid.UUID) (*archive.Archive, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return r.exportGroup(ctx, tx, groupID, time.Now().UTC())
+}
+
+// Snapshot reads every user and group in one consistent transaction
+func (r *ArchiveRepo) Snapshot(ctx context.Context) (*archive.Backup, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	b := &archive.Backup{
+		FormatVersion: archive.FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Users:         []archive.User{},
+		Groups:        []*archive.Archive{},
+		Webhooks:      []archive.Webhook{},
+
+		NotificationSettings:    []archive.NotificationSettings{},
+		NotificationPreferences: []archive.NotificationPreference{},
+		Devices:                 []archive.Device{},
+		GroupEvents:             []archive.GroupEvent{},
+		LedgerChain:             []archive.ChainLink{},
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, email, password_hash, name, to_char(dob, 'YYYY-MM-DD'), sex, created_at
+		FROM users
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up users: %w", err)
+	}
+	for rows.Next() {
+		var u archive.User
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.DOB, &u.Sex, &u.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		b.Users = append(b.Users, u)
+	}
+	rows.Close()
+
+	var groupIDs []uuid.UUID
+	rows, err = tx.Query(ctx, `SELECT id FROM groups ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range groupIDs {
+		a, err := r.exportGroup(ctx, tx, id, b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		b.Groups = append(b.Groups, a)
+	}
+
+	rows, err = tx.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up webhooks: %w", err)
+	}
+	for rows.Next() {
+		var w archive.Webhook
+		if err := rows.Scan(&w.ID, &w.GroupID, &w.URL, &w.Secret, &w.EventTypes, &w.Active, &w.CreatedByUserID, &w.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		b.Webhooks = append(b.Webhooks, w)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT user_id, timezone, quiet_hours_start, quiet_hours_end, locale, email_notifications, email_digest, updated_at
+		FROM notification_settings
+		ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up notification settings: %w", err)
+	}
+	for rows.Next() {
+		var ns archive.NotificationSettings
+		if err := rows.Scan(&ns.UserID, &ns.Timezone, &ns.QuietHoursStart, &ns.QuietHoursEnd, &ns.Locale,
+			&ns.EmailNotifications, &ns.EmailDigest, &ns.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan notification settings: %w", err)
+		}
+		b.NotificationSettings = append(b.NotificationSettings, ns)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT id, user_id, group_id, event_type, enabled
+		FROM notification_preferences
+		ORDER BY user_id, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up notification preferences: %w", err)
+	}
+	for rows.Next() {
+		var p archive.NotificationPreference
+		if err := rows.Scan(&p.ID, &p.UserID, &p.GroupID, &p.EventType, &p.Enabled); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		b.NotificationPreferences = append(b.NotificationPreferences, p)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+deviceColumns+` FROM devices ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up devices: %w", err)
+	}
+	for rows.Next() {
+		var d archive.Device
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Token, &d.Platform, &d.CreatedAt, &d.LastSeenAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		b.Devices = append(b.Devices, d)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT seq, group_seq, id, group_id, type, actor_user_id, payload, created_at
+		FROM group_events
+		ORDER BY seq
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up group events: %w", err)
+	}
+	for rows.Next() {
+		var e archive.GroupEvent
+		if err := rows.Scan(&e.Seq, &e.GroupSeq, &e.ID, &e.GroupID, &e.Type, &e.ActorUserID, &e.Payload, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group event: %w", err)
+		}
+		b.GroupEvents = append(b.GroupEvents, e)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT group_id, seq, record_type, record_id, payload, prev_hash, hash, created_at
+		FROM ledger_chain
+		ORDER BY group_id, seq
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up ledger chain: %w", err)
+	}
+	for rows.Next() {
+		var l archive.ChainLink
+		if err := rows.Scan(&l.GroupID, &l.Seq, &l.RecordType, &l.RecordID, &l.Payload, &l.PrevHash, &l.Hash, &l.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan chain link: %w", err)
+		}
+		b.LedgerChain = append(b.LedgerChain, l)
+	}
+	rows.Close()
+
+	return b, nil
+}
+
+// exportGroup reads one group into an archive within tx
+func (r *ArchiveRepo) exportGroup(ctx context.Context, tx pgx.Tx, groupID uuid.UUID, exportedAt time.Time) (*archive.Archive, error) {
+	group, err := scanGroup(tx.QueryRow(ctx, `SELECT `+groupColumns+` FROM groups WHERE id = $1`, groupID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	a := &archive.Archive{
+		FormatVersion: archive.FormatVersion,
+		ExportedAt:    exportedAt,
+		Group: archive.Group{
+			ID:                  group.ID,
+			Name:                group.Name,
+			HeadUserID:          group.HeadUserID,
+			MaxBackdateDays:     group.MaxBackdateDays,
+			PendingPolicy:       group.PendingPolicy,
+			RequireRejectReason: group.RequireRejectReason,
+			CreatedAt:           group.CreatedAt,
+		},
+		Members:       []archive.Member{},
+		Chores:        []archive.Chore{},
+		LedgerEntries: []archive.LedgerEntry{},
+		Settlements:   []archive.Settlement{},
+		Invites:       []archive.Invite{},
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT gm.user_id, u.email, u.name, gm.role, gm.joined_at
+		FROM group_members gm
+		INNER JOIN users u ON gm.user_id = u.id
+		WHERE gm.group_id = $1
+		ORDER BY gm.joined_at, gm.user_id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export members: %w", err)
+	}
+	for rows.Next() {
+		var m archive.Member
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Name, &m.Role, &m.JoinedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		a.Members = append(a.Members, m)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT id, name, description, amount, proof_required, assignee_user_id, due_at, assigned_at, created_at, deleted_at
+		FROM chores
+		WHERE group_id = $1
+		ORDER BY created_at, id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export chores: %w", err)
+	}
+	for rows.Next() {
+		var c archive.Chore
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.Amount, &c.ProofRequired,
+			&c.AssigneeUserID, &c.DueAt, &c.AssignedAt, &c.CreatedAt, &c.DeletedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan chore: %w", err)
+		}
+		a.Chores = append(a.Chores, c)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+approvalRuleColumns+` FROM approval_rules WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export approval rules: %w", err)
+	}
+	for rows.Next() {
+		rule, err := scanApprovalRule(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan approval rule: %w", err)
+		}
+		a.ApprovalRules = append(a.ApprovalRules, archive.ApprovalRule{
+			ID:              rule.ID,
+			ChoreID:         rule.ChoreID,
+			UserID:          rule.UserID,
+			MaxPerDay:       rule.MaxPerDay,
+			CreatedByUserID: rule.CreatedByUserID,
+			CreatedAt:       rule.CreatedAt,
+			DeletedAt:       rule.DeletedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+allowanceColumns+` FROM allowances WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export allowances: %w", err)
+	}
+	for rows.Next() {
+		al, err := scanAllowance(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan allowance: %w", err)
+		}
+		a.Allowances = append(a.Allowances, archive.Allowance{
+			ID:              al.ID,
+			UserID:          al.UserID,
+			Amount:          al.Amount,
+			AmountPerYear:   al.AmountPerYear,
+			Interval:        al.Interval,
+			StartDate:       al.StartDate.Format("2006-01-02"),
+			NextPeriod:      al.NextPeriod.Format("2006-01-02"),
+			PausedAt:        al.PausedAt,
+			CreatedByUserID: al.CreatedByUserID,
+			CreatedAt:       al.CreatedAt,
+			UpdatedAt:       al.UpdatedAt,
+			DeletedAt:       al.DeletedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+loanColumns+` FROM loans WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export loans: %w", err)
+	}
+	loanList, err := collectLoans(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range loanList {
+		var next string
+		if l.NextRepayment != nil {
+			next = l.NextRepayment.Format("2006-01-02")
+		}
+		a.Loans = append(a.Loans, archive.Loan{
+			ID:              l.ID,
+			UserID:          l.UserID,
+			Principal:       l.Principal,
+			InterestPercent: l.InterestPercent,
+			TotalDue:        l.TotalDue,
+			RepaymentPlan:   l.RepaymentPlan,
+			WeeklyAmount:    l.WeeklyAmount,
+			WithholdPercent: l.WithholdPercent,
+			StartDate:       l.StartDate.Format("2006-01-02"),
+			NextRepayment:   next,
+			Note:            l.Note,
+			RepaidAt:        l.RepaidAt,
+			CreatedByUserID: l.CreatedByUserID,
+			CreatedAt:       l.CreatedAt,
+			UpdatedAt:       l.UpdatedAt,
+		})
+	}
+
+	rows, err = tx.Query(ctx, `SELECT `+ledgerEntryColumns+` FROM ledger_entries WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export ledger entries: %w", err)
+	}
+	entries, err := collectLedgerEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		var period string
+		if e.AllowancePeriod != nil {
+			period = e.AllowancePeriod.Format("2006-01-02")
+		}
+		a.LedgerEntries = append(a.LedgerEntries, archive.LedgerEntry{
+			ID:                e.ID,
+			UserID:            e.UserID,
+			Kind:              e.Kind,
+			ChoreID:           e.ChoreID,
+			AllowanceID:       e.AllowanceID,
+			AllowancePeriod:   period,
+			InterestRateID:    e.InterestRateID,
+			LoanID:            e.LoanID,
+			WithheldFromID:    e.WithheldFromID,
+			Amount:            e.Amount,
+			Status:            e.Status,
+			CreatedByUserID:   e.CreatedByUserID,
+			ApprovedByUserID:  e.ApprovedByUserID,
+			RejectedByUserID:  e.RejectedByUserID,
+			StatusReason:      e.StatusReason,
+			SystemActor:       e.SystemActor,
+			ApprovalRuleID:    e.ApprovalRuleID,
+			ResubmittedFromID: e.ResubmittedFromID,
+			OccurredAt:        e.OccurredAt,
+			CreatedAt:         e.CreatedAt,
+		})
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT c.id, c.entry_id, c.user_id, c.body, c.created_at
+		FROM ledger_comments c
+		INNER JOIN ledger_entries le ON c.entry_id = le.id
+		WHERE le.group_id = $1
+		ORDER BY c.created_at, c.id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export comments: %w", err)
+	}
+	for rows.Next() {
+		var c archive.Comment
+		if err := rows.Scan(&c.ID, &c.EntryID, &c.UserID, &c.Body, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		a.Comments = append(a.Comments, c)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM ledger_attachments
+		WHERE entry_id IN (SELECT id FROM ledger_entries WHERE group_id = $1)
+		ORDER BY created_at, id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export attachments: %w", err)
+	}
+	for rows.Next() {
+		at, err := scanAttachment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		a.Attachments = append(a.Attachments, archive.Attachment{
+			ID:               at.ID,
+			EntryID:          at.EntryID,
+			UploadedByUserID: at.UploadedByUserID,
+			File:             archive.File{StorageKey: at.StorageKey, ContentType: at.ContentType, SizeBytes: at.SizeBytes},
+			CreatedAt:        at.CreatedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+jarColumns+jarBalance+` FROM jars j WHERE j.group_id = $1 ORDER BY j.created_at, j.id`, groupID, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export jars: %w", err)
+	}
+	jarList, err := collectJars(rows)
+	if err != nil {
+		return nil, err
+	}
+	exportedJars := make(map[uuid.UUID]*archive.Jar, len(jarList))
+	for _, j := range jarList {
+		a.Jars = append(a.Jars, archive.Jar{
+			ID:           j.ID,
+			UserID:       j.UserID,
+			Name:         j.Name,
+			SplitPercent: j.SplitPercent,
+			Position:     j.Position,
+			CreatedAt:    j.CreatedAt,
+			UpdatedAt:    j.UpdatedAt,
+			DeletedAt:    j.DeletedAt,
+		})
+	}
+	for i := range a.Jars {
+		exportedJars[a.Jars[i].ID] = &a.Jars[i]
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT js.jar_id, js.entry_id, js.amount
+		FROM jar_splits js
+		INNER JOIN jars j ON j.id = js.jar_id
+		INNER JOIN ledger_entries le ON le.id = js.entry_id
+		WHERE j.group_id = $1
+		ORDER BY le.created_at, le.id, j.position, j.id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export jar splits: %w", err)
+	}
+	for rows.Next() {
+		var jarID uuid.UUID
+		var split archive.JarSplit
+		if err := rows.Scan(&jarID, &split.EntryID, &split.Amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan jar split: %w", err)
+		}
+		j := exportedJars[jarID]
+		j.Splits = append(j.Splits, split)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+jarTransferColumns+` FROM jar_transfers WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export jar transfers: %w", err)
+	}
+	for rows.Next() {
+		t, err := scanJarTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan jar transfer: %w", err)
+		}
+		a.JarTransfers = append(a.JarTransfers, archive.JarTransfer{
+			ID:              t.ID,
+			UserID:          t.UserID,
+			FromJarID:       t.FromJarID,
+			ToJarID:         t.ToJarID,
+			Amount:          t.Amount,
+			Note:            t.Note,
+			CreatedByUserID: t.CreatedByUserID,
+			CreatedAt:       t.CreatedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+interestRateColumns+` FROM interest_rates WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export interest rates: %w", err)
+	}
+	rates, err := collectInterestRates(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		a.InterestRates = append(a.InterestRates, archive.InterestRate{
+			ID:              rate.ID,
+			JarID:           rate.JarID,
+			APR:             rate.APR,
+			Compounding:     rate.Compounding,
+			MinBalance:      rate.MinBalance,
+			StartDate:       rate.StartDate.Format("2006-01-02"),
+			NextPeriod:      rate.NextPeriod.Format("2006-01-02"),
+			CreatedByUserID: rate.CreatedByUserID,
+			CreatedAt:       rate.CreatedAt,
+			UpdatedAt:       rate.UpdatedAt,
+			DeletedAt:       rate.DeletedAt,
+		})
+	}
+	exportedRates := make(map[uuid.UUID]*archive.InterestRate, len(a.InterestRates))
+	for i := range a.InterestRates {
+		exportedRates[a.InterestRates[i].ID] = &a.InterestRates[i]
+	}
+
+	rows, err = tx.Query(ctx, `SELECT `+interestPostingColumns+` FROM interest_postings WHERE group_id = $1 ORDER BY period_start, created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export interest postings: %w", err)
+	}
+	for rows.Next() {
+		p, err := scanInterestPosting(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan interest posting: %w", err)
+		}
+		rate := exportedRates[p.RateID]
+		rate.Postings = append(rate.Postings, archive.InterestPosting{
+			ID:          p.ID,
+			EntryID:     p.EntryID,
+			UserID:      p.UserID,
+			PeriodStart: p.PeriodStart.Format("2006-01-02"),
+			Amount:      p.Amount,
+			Breakdown:   p.Breakdown,
+			CreatedAt:   p.CreatedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+settlementCategoryColumns+` FROM settlement_categories WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settlement categories: %w", err)
+	}
+	for rows.Next() {
+		category, err := scanSettlementCategory(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan settlement category: %w", err)
+		}
+		a.SettlementCategories = append(a.SettlementCategories, archive.SettlementCategory{
+			ID:        category.ID,
+			Name:      category.Name,
+			CreatedAt: category.CreatedAt,
+			DeletedAt: category.DeletedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+settlementColumns+` FROM settlements WHERE group_id = $1 ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settlements: %w", err)
+	}
+	settlements, err := collectSettlements(rows)
+	if err != nil {
+		return nil, err
+	}
+	exported := make(map[uuid.UUID]*archive.Settlement, len(settlements))
+	for _, s := range settlements {
+		a.Settlements = append(a.Settlements, archive.Settlement{
+			ID:                s.ID,
+			UserID:            s.UserID,
+			Amount:            s.Amount,
+			Date:              s.Date.Format("2006-01-02"),
+			Note:              s.Note,
+			Method:            s.Method,
+			ExternalReference: s.ExternalReference,
+			CategoryID:        s.CategoryID,
+			JarID:             s.JarID,
+			Status:            s.Status,
+			AcknowledgedAt:    s.AcknowledgedAt,
+			DisputedAt:        s.DisputedAt,
+			DisputeReason:     s.DisputeReason,
+			CreatedAt:         s.CreatedAt,
+			UpdatedAt:         s.UpdatedAt,
+			VoidedAt:          s.VoidedAt,
+			VoidedByUserID:    s.VoidedByUserID,
+			VoidReason:        s.VoidReason,
+		})
+	}
+	for i := range a.Settlements {
+		exported[a.Settlements[i].ID] = &a.Settlements[i]
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT se.settlement_id, se.entry_id, se.amount
+		FROM settlement_entries se
+		INNER JOIN ledger_entries le ON le.id = se.entry_id
+		WHERE le.group_id = $1
+		ORDER BY le.occurred_at, le.created_at, le.id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settlement entries: %w", err)
+	}
+	for rows.Next() {
+		var settlementID uuid.UUID
+		var link models.SettlementEntry
+		if err := rows.Scan(&settlementID, &link.EntryID, &link.Amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan settlement entry: %w", err)
+		}
+		s := exported[settlementID]
+		s.Entries = append(s.Entries, link)
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+settlementRevisionColumns+`
+		FROM settlement_revisions
+		WHERE settlement_id IN (SELECT id FROM settlements WHERE group_id = $1)
+		ORDER BY created_at, id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export settlement revisions: %w", err)
+	}
+	for rows.Next() {
+		rev, err := scanSettlementRevision(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan settlement revision: %w", err)
+		}
+		s := exported[rev.SettlementID]
+		s.Revisions = append(s.Revisions, archive.SettlementRevision{
+			ID:                      rev.ID,
+			Action:                  rev.Action,
+			ChangedByUserID:         rev.ChangedByUserID,
+			Reason:                  rev.Reason,
+			AmountBefore:            rev.AmountBefore,
+			AmountAfter:             rev.AmountAfter,
+			DateBefore:              rev.DateBefore.Format("2006-01-02"),
+			DateAfter:               rev.DateAfter.Format("2006-01-02"),
+			NoteBefore:              rev.NoteBefore,
+			NoteAfter:               rev.NoteAfter,
+			MethodBefore:            rev.MethodBefore,
+			MethodAfter:             rev.MethodAfter,
+			ExternalReferenceBefore: rev.ExternalReferenceBefore,
+			ExternalReferenceAfter:  rev.ExternalReferenceAfter,
+			CategoryIDBefore:        rev.CategoryIDBefore,
+			CategoryIDAfter:         rev.CategoryIDAfter,
+			CreatedAt:               rev.CreatedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `SELECT `+goalColumns+` FROM savings_goals WHERE group_id = $1 AND deleted_at IS NULL ORDER BY created_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export goals: %w", err)
+	}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		var deadline string
+		if g.Deadline != nil {
+			deadline = g.Deadline.Format("2006-01-02")
+		}
+		var image *archive.File
+		if g.ImageStorageKey != nil {
+			image = &archive.File{StorageKey: *g.ImageStorageKey, ContentType: *g.ImageContentType, SizeBytes: *g.ImageSizeBytes}
+		}
+		a.Goals = append(a.Goals, archive.Goal{
+			ID:              g.ID,
+			UserID:          g.UserID,
+			Name:            g.Name,
+			TargetAmount:    g.TargetAmount,
+			Deadline:        deadline,
+			ProgressSource:  g.Source,
+			ReachedAt:       g.ReachedAt,
+			CreatedByUserID: g.CreatedByUserID,
+			CreatedAt:       g.CreatedAt,
+			UpdatedAt:       g.UpdatedAt,
+			Image:           image,
+		})
+	}
+	rows.Close()
+	exportedGoals := make(map[uuid.UUID]*archive.Goal, len(a.Goals))
+	for i := range a.Goals {
+		exportedGoals[a.Goals[i].ID] = &a.Goals[i]
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+goalAllocationColumns+`
+		FROM goal_allocations
+		WHERE goal_id IN (SELECT id FROM savings_goals WHERE group_id = $1 AND deleted_at IS NULL)
+		ORDER BY created_at, id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export goal allocations: %w", err)
+	}
+	for rows.Next() {
+		al, err := scanGoalAllocation(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal allocation: %w", err)
+		}
+		g := exportedGoals[al.GoalID]
+		g.Allocations = append(g.Allocations, archive.GoalAllocation{
+			ID:              al.ID,
+			Amount:          al.Amount,
+			Note:            al.Note,
+			CreatedByUserID: al.CreatedByUserID,
+			CreatedAt:       al.CreatedAt,
+		})
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx, `
+		SELECT id, expires_at, created_at
+		FROM invite_tokens
+		WHERE group_id = $1
+		ORDER BY created_at, id
+	`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export invites: %w", err)
+	}
+	for rows.Next() {
+		var inv archive.Invite
+		if err := rows.Scan(&inv.ID, &inv.ExpiresAt, &inv.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		a.Invites = append(a.Invites, inv)
+	}
+	rows.Close()
+
+	if err := r.loadFiles(ctx, a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// loadFiles reads the archive's files from the store. A file missing from
+// the store is left out, and an import of the archive reports it.
+func (r *ArchiveRepo) loadFiles(ctx context.Context, a *archive.Archive) error {
+	a.Files = make(map[string][]byte)
+	for _, key := range a.FileKeys() {
+		rc, err := r.store.Open(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", key, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", key, err)
+		}
+		a.Files[key] = data
+	}
+	return nil
+}
+
+// putFiles writes files to the store by key, removing those already written
+// if one fails
+func (r *ArchiveRepo) putFiles(ctx context.Context, files map[string][]byte) error {
+	written := make([]string, 0, len(files))
+	for key, data := range files {
+		if err := r.store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			r.deleteFiles(ctx, written)
+			return fmt.Errorf("failed to store file %s: %w", key, err)
+		}
+		written = append(written, key)
+	}
+	return nil
+}
+
+// deleteFiles removes files written for an import that did not commit
+func (r *ArchiveRepo) deleteFiles(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		_ = r.store.Delete(ctx, key)
+	}
+}
+
+// Import recreates an archived group under new IDs with members remapped to
+// local users, chaining every ledger entry and settlement in creation order.
+// Its files are stored under new keys before the import commits.
+// With dryRun set everything is written and then rolled back, so the report
+// reflects database constraints as well as archive validation.
+func (r *ArchiveRepo) Import(ctx context.Context, a *archive.Archive, users map[uuid.UUID]uuid.UUID, dryRun bool, report *archive.Report) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	files := make(map[string][]byte)
+	groupID, err := r.importGroup(ctx, tx, a, users, false, files, report)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := r.putFiles(ctx, files); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		r.deleteFiles(ctx, mapKeys(files))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	report.GroupID = &groupID
+
+	return nil
+}
+
+// Restore recreates every user and group from a backup, keeping their IDs
+// and file storage keys, and returns warnings about anything left out, such
+// as photos whose files are not in the backup. It refuses to run unless the
+// database has no users, so it cannot clobber live data. The backed-up
+// ledger chain must verify with the current key; the chain is then rebuilt
+// from the restored rows.
+func (r *ArchiveRepo) Restore(ctx context.Context, b *archive.Backup) ([]string, error) {
+	if errs := b.VerifyChain(r.chain.hasher); len(errs) > 0 {
+		return nil, &ChainError{Breaks: errs}
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var existing int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrNotEmpty
+	}
+
+	users := make(map[uuid.UUID]uuid.UUID, len(b.Users))
+	for _, u := range b.Users {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO users (id, email, password_hash, name, dob, sex, created_at)
+			VALUES ($1, $2, $3, $4, $5::date, $6, $7)
+		`, u.ID, u.Email, u.PasswordHash, u.Name, u.DOB, u.Sex, u.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore user %s: %w", u.Email, err)
+		}
+		users[u.ID] = u.ID
+	}
+
+	for _, ns := range b.NotificationSettings {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notification_settings (user_id, timezone, quiet_hours_start, quiet_hours_end, locale, email_notifications, email_digest, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, ns.UserID, ns.Timezone, ns.QuietHoursStart, ns.QuietHoursEnd, ns.Locale, ns.EmailNotifications, ns.EmailDigest, ns.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore notification settings for %s: %w", ns.UserID, err)
+		}
+	}
+
+	for _, d := range b.Devices {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO devices (id, user_id, token, platform, created_at, last_seen_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, d.ID, d.UserID, d.Token, d.Platform, d.CreatedAt, d.LastSeenAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore device %s: %w", d.ID, err)
+		}
+	}
+
+	report := &archive.Report{}
+	if b.LedgerChain == nil {
+		report.Warnings = append(report.Warnings, "the backup has no ledger chain, so its entries and settlements were restored without verifying them")
+	}
+	files := make(map[string][]byte)
+	for _, a := range b.Groups {
+		if _, err := r.importGroup(ctx, tx, a, users, true, files, report); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range b.Webhooks {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO webhooks (id, group_id, url, secret, event_types, active, created_by_user_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, w.ID, w.GroupID, w.URL, w.Secret, w.EventTypes, w.Active, w.CreatedByUserID, w.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore webhook %s: %w", w.ID, err)
+		}
+	}
+
+	for _, p := range b.NotificationPreferences {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notification_preferences (id, user_id, group_id, event_type, enabled)
+			VALUES ($1, $2, $3, $4, $5)
+		`, p.ID, p.UserID, p.GroupID, p.EventType, p.Enabled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore notification preference %s: %w", p.ID, err)
+		}
+	}
+
+	// Events keep their sequence numbers so clients resume where they left
+	// off; older backups only have the global one, which clients were given
+	for _, e := range b.GroupEvents {
+		groupSeq := e.GroupSeq
+		if groupSeq == 0 {
+			groupSeq = e.Seq
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO group_events (seq, group_seq, id, group_id, type, actor_user_id, payload, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, e.Seq, groupSeq, e.ID, e.GroupID, e.Type, e.ActorUserID, e.Payload, e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore group event %s: %w", e.ID, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		SELECT setval(pg_get_serial_sequence('group_events', 'seq'), COALESCE(MAX(seq), 0) + 1, false)
+		FROM group_events
+	`); err != nil {
+		return nil, fmt.Errorf("failed to reset group event sequence: %w", err)
+	}
+
+	if err := r.putFiles(ctx, files); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return report.Warnings, nil
+}
+
+// importGroup writes one archived group within tx and returns its ID, adding
+// the files to store to files by storage key. Unless keepIDs is set every row
+// and file gets a new ID and key, so an archive can be imported next to the
+// group it was exported from.
+func (r *ArchiveRepo) importGroup(ctx context.Context, tx pgx.Tx, a *archive.Archive, users map[uuid.UUID]uuid.UUID, keepIDs bool, files map[string][]byte, report *archive.Report) (uuid.UUID, error) {
+	newID := func(old uuid.UUID) uuid.UUID {
+		if keepIDs {
+			return old
+		}
+		return uuid.New()
+	}
+
+	groupID := newID(a.Group.ID)
+	_, err := tx.Exec(ctx, `
+		INSERT INTO groups (id, name, head_user_id, max_backdate_days,
+		                    expire_pending_after_days, auto_approve_after_hours, auto_approve_max_amount,
+		                    require_reject_reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, groupID, a.Group.Name, users[a.Group.HeadUserID], a.Group.MaxBackdateDays,
+		a.Group.ExpirePendingAfterDays, a.Group.AutoApproveAfterHours, a.Group.AutoApproveMaxAmount,
+		a.Group.RequireRejectReason, a.Group.CreatedAt)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to import group: %w", err)
+	}
+
+	for _, m := range a.Members {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO group_members (group_id, user_id, role, joined_at)
+			VALUES ($1, $2, $3, $4)
+		`, groupID, users[m.UserID], m.Role, m.JoinedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import member %s: %w", m.Email, err)
+		}
+		report.Counts.Members++
+	}
+
+	chores := make(map[uuid.UUID]uuid.UUID, len(a.Chores))
+	for _, c := range a.Chores {
+		id := newID(c.ID)
+		var assignee *uuid.UUID
+		assignedAt := c.AssignedAt
+		if c.AssigneeUserID != nil {
+			mapped := users[*c.AssigneeUserID]
+			assignee = &mapped
+			if assignedAt == nil {
+				assignedAt = &c.CreatedAt
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO chores (id, group_id, name, description, amount, proof_required, assignee_user_id, due_at, assigned_at, created_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, id, groupID, c.Name, c.Description, c.Amount, c.ProofRequired, assignee, c.DueAt, assignedAt, c.CreatedAt, c.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import chore %s: %w", c.Name, err)
+		}
+		chores[c.ID] = id
+		report.Counts.Chores++
+	}
+
+	rules := make(map[uuid.UUID]uuid.UUID, len(a.ApprovalRules))
+	for _, rule := range a.ApprovalRules {
+		id := newID(rule.ID)
+		var choreID, userID *uuid.UUID
+		if rule.ChoreID != nil {
+			mapped := chores[*rule.ChoreID]
+			choreID = &mapped
+		}
+		if rule.UserID != nil {
+			mapped := users[*rule.UserID]
+			userID = &mapped
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO approval_rules (id, group_id, chore_id, user_id, max_per_day, created_by_user_id, created_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, id, groupID, choreID, userID, rule.MaxPerDay, users[rule.CreatedByUserID], rule.CreatedAt, rule.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import approval rule %s: %w", rule.ID, err)
+		}
+		rules[rule.ID] = id
+		report.Counts.ApprovalRules++
+	}
+
+	allowances := make(map[uuid.UUID]uuid.UUID, len(a.Allowances))
+	for _, al := range a.Allowances {
+		id := newID(al.ID)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO allowances (id, group_id, user_id, amount, amount_per_year, interval_unit, start_date, next_period,
+			                        paused_at, created_by_user_id, created_at, updated_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		`, id, groupID, users[al.UserID], al.Amount, al.AmountPerYear, al.Interval, al.StartDate, al.NextPeriod,
+			al.PausedAt, users[al.CreatedByUserID], al.CreatedAt, al.UpdatedAt, al.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import allowance %s: %w", al.ID, err)
+		}
+		allowances[al.ID] = id
+		report.Counts.Allowances++
+	}
+
+	loans := make(map[uuid.UUID]uuid.UUID, len(a.Loans))
+	for _, l := range a.Loans {
+		id := newID(l.ID)
+		var next *string
+		if l.NextRepayment != "" {
+			next = &l.NextRepayment
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO loans (id, group_id, user_id, principal, interest_percent, total_due, repayment_plan, weekly_amount, withhold_percent,
+			                   start_date, next_repayment, note, repaid_at, created_by_user_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::date, $12, $13, $14, $15, $16)
+		`, id, groupID, users[l.UserID], l.Principal, l.InterestPercent, l.TotalDue, l.RepaymentPlan, l.WeeklyAmount, l.WithholdPercent,
+			l.StartDate, next, l.Note, l.RepaidAt, users[l.CreatedByUserID], l.CreatedAt, l.UpdatedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import loan %s: %w", l.ID, err)
+		}
+		loans[l.ID] = id
+		report.Counts.Loans++
+	}
+
+	categories := make(map[uuid.UUID]uuid.UUID, len(a.SettlementCategories))
+	for _, category := range a.SettlementCategories {
+		id := newID(category.ID)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO settlement_categories (id, group_id, name, created_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, id, groupID, category.Name, category.CreatedAt, category.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import settlement category %s: %w", category.Name, err)
+		}
+		categories[category.ID] = id
+		report.Counts.SettlementCategories++
+	}
+	category := func(id *uuid.UUID) *uuid.UUID {
+		if id == nil {
+			return nil
+		}
+		mapped := categories[*id]
+		return &mapped
+	}
+
+	jarIDs := make(map[uuid.UUID]uuid.UUID, len(a.Jars))
+	for _, j := range a.Jars {
+		id := newID(j.ID)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO jars (id, group_id, user_id, name, split_percent, position, created_at, updated_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, id, groupID, users[j.UserID], j.Name, j.SplitPercent, j.Position, j.CreatedAt, j.UpdatedAt, j.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import jar %s: %w", j.ID, err)
+		}
+		jarIDs[j.ID] = id
+		report.Counts.Jars++
+	}
+	jar := func(id *uuid.UUID) *uuid.UUID {
+		if id == nil {
+			return nil
+		}
+		mapped := jarIDs[*id]
+		return &mapped
+	}
+
+	interestRates := make(map[uuid.UUID]uuid.UUID, len(a.InterestRates))
+	for _, rate := range a.InterestRates {
+		id := newID(rate.ID)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO interest_rates (id, group_id, jar_id, apr, compounding, min_balance, start_date, next_period,
+			                            created_by_user_id, created_at, updated_at, deleted_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, id, groupID, jar(rate.JarID), rate.APR, rate.Compounding, rate.MinBalance, rate.StartDate, rate.NextPeriod,
+			users[rate.CreatedByUserID], rate.CreatedAt, rate.UpdatedAt, rate.DeletedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import interest rate %s: %w", rate.ID, err)
+		}
+		interestRates[rate.ID] = id
+		report.Counts.InterestRates++
+	}
+
+	// Interleave entries and settlements so the new chain follows the original order
+	type record struct {
+		createdAt  time.Time
+		entry      *archive.LedgerEntry
+		settlement *archive.Settlement
+	}
+	records := make([]record, 0, len(a.LedgerEntries)+len(a.Settlements))
+	for i := range a.LedgerEntries {
+		records = append(records, record{createdAt: a.LedgerEntries[i].CreatedAt, entry: &a.LedgerEntries[i]})
+	}
+	for i := range a.Settlements {
+		records = append(records, record{createdAt: a.Settlements[i].CreatedAt, settlement: &a.Settlements[i]})
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].createdAt.Before(records[j].createdAt) })
+
+	entries := make(map[uuid.UUID]uuid.UUID, len(a.LedgerEntries))
+	for _, rec := range records {
+		if rec.entry != nil {
+			entries[rec.entry.ID] = newID(rec.entry.ID)
+			err = r.importLedgerEntry(ctx, tx, groupID, entries[rec.entry.ID], rec.entry, users, chores, allowances, interestRates, loans, rules, entries, report)
+			report.Counts.LedgerEntries++
+		} else {
+			err = r.importSettlement(ctx, tx, groupID, newID(rec.settlement.ID), rec.settlement, users, category(rec.settlement.CategoryID), jar(rec.settlement.JarID), report)
+			report.Counts.Settlements++
+		}
+		if err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	// Links and revisions go in once every entry they may refer to exists
+	for _, s := range a.Settlements {
+		for _, link := range s.Entries {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO settlement_entries (settlement_id, entry_id, amount)
+				VALUES ($1, $2, $3)
+			`, newID(s.ID), entries[link.EntryID], link.Amount)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to import settlement %s entry %s: %w", s.ID, link.EntryID, err)
+			}
+		}
+		for _, rev := range s.Revisions {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO settlement_revisions (id, settlement_id, action, changed_by_user_id, reason,
+					amount_before, amount_after, date_before, date_after, note_before, note_after,
+					method_before, method_after, external_reference_before, external_reference_after,
+					category_id_before, category_id_after, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8::date, $9::date, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+			`, newID(rev.ID), newID(s.ID), rev.Action, r.optionalUser(rev.ChangedByUserID, users, "settlement "+s.ID.String(), "editor", report),
+				rev.Reason, rev.AmountBefore, rev.AmountAfter, rev.DateBefore, rev.DateAfter, rev.NoteBefore, rev.NoteAfter,
+				rev.MethodBefore, rev.MethodAfter, rev.ExternalReferenceBefore, rev.ExternalReferenceAfter,
+				category(rev.CategoryIDBefore), category(rev.CategoryIDAfter), rev.CreatedAt)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to import settlement %s revision %s: %w", s.ID, rev.ID, err)
+			}
+		}
+	}
+
+	for _, j := range a.Jars {
+		for _, split := range j.Splits {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO jar_splits (entry_id, jar_id, amount)
+				VALUES ($1, $2, $3)
+			`, entries[split.EntryID], jarIDs[j.ID], split.Amount)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to import jar %s split of entry %s: %w", j.ID, split.EntryID, err)
+			}
+		}
+	}
+
+	for _, rate := range a.InterestRates {
+		for _, p := range rate.Postings {
+			breakdown, err := json.Marshal(p.Breakdown)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to encode interest breakdown: %w", err)
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO interest_postings (id, rate_id, entry_id, group_id, user_id, jar_id, period_start, amount, breakdown, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`, newID(p.ID), interestRates[rate.ID], entries[p.EntryID], groupID, users[p.UserID], jar(rate.JarID),
+				p.PeriodStart, p.Amount, string(breakdown), p.CreatedAt)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to import interest rate %s posting %s: %w", rate.ID, p.ID, err)
+			}
+		}
+	}
+
+	for _, t := range a.JarTransfers {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO jar_transfers (id, group_id, user_id, from_jar_id, to_jar_id, amount, note, created_by_user_id, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, newID(t.ID), groupID, users[t.UserID], jarIDs[t.FromJarID], jarIDs[t.ToJarID], t.Amount, t.Note, users[t.CreatedByUserID], t.CreatedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import jar transfer %s: %w", t.ID, err)
+		}
+		report.Counts.JarTransfers++
+	}
+
+	for _, c := range a.Comments {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO ledger_comments (id, entry_id, user_id, body, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+		`, newID(c.ID), entries[c.EntryID], users[c.UserID], c.Body, c.CreatedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import comment %s: %w", c.ID, err)
+		}
+		report.Counts.Comments++
+	}
+
+	for _, at := range a.Attachments {
+		data, ok := a.Files[at.StorageKey]
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("ledger entry %s: photo %s is not in the archive and was not restored", at.EntryID, at.ID))
+			continue
+		}
+		id := newID(at.ID)
+		key := at.StorageKey
+		if !keepIDs {
+			key = storage.AttachmentKey(groupID, entries[at.EntryID], id)
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO ledger_attachments (id, entry_id, uploaded_by_user_id, content_type, size_bytes, storage_key, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, id, entries[at.EntryID], users[at.UploadedByUserID], at.ContentType, len(data), key, at.CreatedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import attachment %s: %w", at.ID, err)
+		}
+		files[key] = data
+		report.Counts.Attachments++
+	}
+
+	for _, g := range a.Goals {
+		id := newID(g.ID)
+		var deadline *string
+		if g.Deadline != "" {
+			deadline = &g.Deadline
+		}
+		var imageType, imageKey *string
+		var imageSize *int64
+		if g.Image != nil {
+			if data, ok := a.Files[g.Image.StorageKey]; ok {
+				key := g.Image.StorageKey
+				if !keepIDs {
+					key = storage.GoalImageKey(groupID, id)
+				}
+				size := int64(len(data))
+				imageType, imageKey, imageSize = &g.Image.ContentType, &key, &size
+				files[key] = data
+			} else {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("goal %s: image is not in the archive and was not restored", g.ID))
+			}
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO savings_goals (id, group_id, user_id, name, target_amount, deadline, progress_source,
+			                           reached_at, image_content_type, image_size_bytes, image_storage_key,
+			                           created_by_user_id, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6::date, $7, $8, $9, $10, $11, $12, $13, $14)
+		`, id, groupID, users[g.UserID], g.Name, g.TargetAmount, deadline, g.ProgressSource,
+			g.ReachedAt, imageType, imageSize, imageKey, users[g.CreatedByUserID], g.CreatedAt, g.UpdatedAt)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import goal %s: %w", g.ID, err)
+		}
+		for _, al := range g.Allocations {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO goal_allocations (id, goal_id, amount, note, created_by_user_id, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, newID(al.ID), id, al.Amount, al.Note, users[al.CreatedByUserID], al.CreatedAt)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("failed to import goal %s allocation %s: %w", g.ID, al.ID, err)
+			}
+		}
+		report.Counts.Goals++
+	}
+
+	report.Counts.InvitesSkipped = len(a.Invites)
+
+	return groupID, nil
+}
+
+// importLedgerEntry inserts one archived entry and appends it to the chain
+func (r *ArchiveRepo) importLedgerEntry(ctx context.Context, tx pgx.Tx, groupID, id uuid.UUID, e *archive.LedgerEntry, users, chores, allowances, interestRates, loans, rules, entries map[uuid.UUID]uuid.UUID, report *archive.Report) error {
+	entry := &models.LedgerEntry{
+		ID:               id,
+		GroupID:          groupID,
+		UserID:           users[e.UserID],
+		Kind:             e.Kind,
+		Status:           e.Status,
+		CreatedByUserID:  users[e.CreatedByUserID],
+		ApprovedByUserID: r.optionalUser(e.ApprovedByUserID, users, "ledger entry "+e.ID.String(), "approver", report),
+		RejectedByUserID: r.optionalUser(e.RejectedByUserID, users, "ledger entry "+e.ID.String(), "rejecter", report),
+		StatusReason:     e.StatusReason,
+		SystemActor:      e.SystemActor,
+	}
+	if entry.Kind == "" {
+		entry.Kind = models.EntryChore
+	}
+	if e.ChoreID != nil {
+		choreID := chores[*e.ChoreID]
+		entry.ChoreID = &choreID
+	}
+	if e.AllowanceID != nil {
+		allowanceID := allowances[*e.AllowanceID]
+		entry.AllowanceID = &allowanceID
+		period, err := time.Parse("2006-01-02", e.AllowancePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid allowance period %q: %w", e.AllowancePeriod, err)
+		}
+		entry.AllowancePeriod = &period
+	}
+	if e.InterestRateID != nil {
+		rateID := interestRates[*e.InterestRateID]
+		entry.InterestRateID = &rateID
+	}
+	if e.LoanID != nil {
+		loanID := loans[*e.LoanID]
+		entry.LoanID = &loanID
+	}
+	if e.WithheldFromID != nil {
+		earningID := entries[*e.WithheldFromID]
+		entry.WithheldFromID = &earningID
+	}
+	if e.ApprovalRuleID != nil {
+		ruleID := rules[*e.ApprovalRuleID]
+		entry.ApprovalRuleID = &ruleID
+	}
+	if e.ResubmittedFromID != nil {
+		originalID := entries[*e.ResubmittedFromID]
+		entry.ResubmittedFromID = &originalID
+	}
+
+	err := tx.QueryRow(ctx, `
+		INSERT INTO ledger_entries (id, group_id, user_id, kind, chore_id, allowance_id, allowance_period, interest_rate_id, loan_id, withheld_from_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, status_reason, system_actor, approval_rule_id, resubmitted_from_id, occurred_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		RETURNING amount, occurred_at, created_at
+	`, entry.ID, groupID, entry.UserID, entry.Kind, entry.ChoreID, entry.AllowanceID, entry.AllowancePeriod, entry.InterestRateID, entry.LoanID, entry.WithheldFromID, e.Amount, entry.Status, entry.CreatedByUserID,
+		entry.ApprovedByUserID, entry.RejectedByUserID, entry.StatusReason, entry.SystemActor, entry.ApprovalRuleID, entry.ResubmittedFromID, e.OccurredAt, e.CreatedAt,
+	).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to import ledger entry %s: %w", e.ID, err)
+	}
+
+	_, err = r.chain.appendLink(ctx, tx, groupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+	return err
+}
+
+// importSettlement inserts one archived settlement in its remapped category
+// and jar and appends it to the chain
+func (r *ArchiveRepo) importSettlement(ctx context.Context, tx pgx.Tx, groupID, id uuid.UUID, s *archive.Settlement, users map[uuid.UUID]uuid.UUID, categoryID, jarID *uuid.UUID, report *archive.Report) error {
+	date, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return fmt.Errorf("invalid settlement date %q: %w", s.Date, err)
+	}
+
+	status := s.Status
+	if status == "" {
+		status = models.SettlementAcknowledged
+	}
+	method := s.Method
+	if method == "" {
+		method = models.MethodCash
+	}
+
+	settlement := &models.Settlement{
+		ID:                id,
+		GroupID:           groupID,
+		UserID:            users[s.UserID],
+		Date:              date,
+		Note:              s.Note,
+		Method:            method,
+		ExternalReference: s.ExternalReference,
+		CategoryID:        categoryID,
+		JarID:             jarID,
+		Status:            status,
+		DisputeReason:     s.DisputeReason,
+		VoidReason:        s.VoidReason,
+		VoidedByUserID:    r.optionalUser(s.VoidedByUserID, users, "settlement "+s.ID.String(), "voider", report),
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO settlements (id, group_id, user_id, amount, date, note, method, external_reference, category_id, jar_id,
+			status, acknowledged_at, disputed_at, dispute_reason, created_at, updated_at, voided_at, voided_by_user_id, void_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING amount, acknowledged_at, disputed_at, created_at, updated_at, voided_at
+	`, settlement.ID, groupID, settlement.UserID, s.Amount, date, s.Note, method, s.ExternalReference, categoryID, jarID,
+		status, s.AcknowledgedAt, s.DisputedAt, s.DisputeReason, s.CreatedAt, s.UpdatedAt, s.VoidedAt, settlement.VoidedByUserID, s.VoidReason,
+	).Scan(&settlement.Amount, &settlement.AcknowledgedAt, &settlement.DisputedAt, &settlement.CreatedAt, &settlement.UpdatedAt, &settlement.VoidedAt)
+	if err != nil {
+		return fmt.Errorf("failed to import settlement %s: %w", s.ID, err)
+	}
+
+	_, err = r.chain.appendLink(ctx, tx, groupID, chain.RecordSettlement, settlement.ID, chain.SettlementPayload(settlement))
+	return err
+}
+
+// optionalUser remaps an optional user reference, dropping it with a warning
+// when the user is no longer a member
+func (r *ArchiveRepo) optionalUser(id *uuid.UUID, users map[uuid.UUID]uuid.UUID, record, role string, report *archive.Report) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	mapped, ok := users[*id]
+	if !ok {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %s %s is not a member and was dropped", record, role, *id))
+		return nil
+	}
+	return &mapped
+}
+
+// mapKeys returns the keys of files
+func mapKeys(files map[string][]byte) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrTooManyAttachments is returned when an entry already has the most photos allowed
+var ErrTooManyAttachments = errors.New("entry has too many attachments")
+
+// AttachmentRepo handles database operations for ledger entry attachments
+type AttachmentRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewAttachmentRepo creates a new AttachmentRepo
+func NewAttachmentRepo(pool *pgxpool.Pool) *AttachmentRepo {
+	return &AttachmentRepo{pool: pool}
+}
+
+const attachmentColumns = `id, entry_id, uploaded_by_user_id, content_type, size_bytes, storage_key, created_at`
+
+func scanAttachment(row pgx.Row) (*models.LedgerAttachment, error) {
+	a := &models.LedgerAttachment{}
+	err := row.Scan(&a.ID, &a.EntryID, &a.UploadedByUserID, &a.ContentType, &a.SizeBytes, &a.StorageKey, &a.CreatedAt)
+	return a, err
+}
+
+// Create records an attachment on a pending entry that has fewer than limit.
+// The entry is locked so concurrent uploads cannot exceed the limit.
+func (r *AttachmentRepo) Create(ctx context.Context, a *models.LedgerAttachment, limit int) (*models.LedgerAttachment, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var status models.LedgerStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM ledger_entries WHERE id = $1 FOR UPDATE`, a.EntryID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock ledger entry: %w", err)
+	}
+	if status != models.StatusPendingApproval {
+		return nil, ErrNotPending
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM ledger_attachments WHERE entry_id = $1`, a.EntryID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	if count >= limit {
+		return nil, ErrTooManyAttachments
+	}
+
+	query := `
+		INSERT INTO ledger_attachments (id, entry_id, uploaded_by_user_id, content_type, size_bytes, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, query, a.ID, a.EntryID, a.UploadedByUserID, a.ContentType, a.SizeBytes, a.StorageKey).Scan(&a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return a, nil
+}
+
+// GetByID retrieves an attachment by ID
+func (r *AttachmentRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.LedgerAttachment, error) {
+	a, err := scanAttachment(r.pool.QueryRow(ctx, `SELECT `+attachmentColumns+` FROM ledger_attachments WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return a, nil
+}
+
+// ListForEntry retrieves an entry's attachments, oldest first
+func (r *AttachmentRepo) ListForEntry(ctx context.Context, entryID uuid.UUID) ([]*models.LedgerAttachment, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM ledger_attachments
+		WHERE entry_id = $1
+		ORDER BY created_at, id
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*models.LedgerAttachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
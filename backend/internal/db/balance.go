@@ -0,0 +1,89 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrTooManyPeriods is returned when a balance history range spans too many periods
+var ErrTooManyPeriods = errors.New("too many periods")
+
+// MaxBalancePeriods bounds the length of a balance history series
+const MaxBalancePeriods = 366
+
+// BalanceInterval is the bucket size of a balance history series
+type BalanceInterval string
+
+const (
+	IntervalWeek  BalanceInterval = "week"
+	IntervalMonth BalanceInterval = "month"
+)
+
+// Valid reports whether the interval is supported
+func (i BalanceInterval) Valid() bool {
+	return i == IntervalWeek || i == IntervalMonth
+}
+
+// Truncate returns the start of the period containing t, in UTC.
+// Weeks start on Monday, matching Postgres date_trunc.
+func (i BalanceInterval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if i == IntervalMonth {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Next returns the start of the period after the one starting at start
+func (i BalanceInterval) Next(start time.Time) time.Time {
+	if i == IntervalMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// balancePeriods splits [from, to) into periods; the first is aligned to the
+// interval and the last is clipped to end at to
+func balancePeriods(interval BalanceInterval, from, to time.Time) ([]*models.BalancePoint, error) {
+	var points []*models.BalancePoint
+	for start := interval.Truncate(from); start.Before(to); start = interval.Next(start) {
+		if len(points) == MaxBalancePeriods {
+			return nil, ErrTooManyPeriods
+		}
+		end := interval.Next(start)
+		if end.After(to) {
+			end = to
+		}
+		points = append(points, &models.BalancePoint{PeriodStart: start, PeriodEnd: end})
+	}
+	return points, nil
+}
+
+// fillBalances sets each period's totals from the per-period sums and carries
+// the running balance forward from opening
+func fillBalances(points []*models.BalancePoint, opening float64, earned, settled map[int64]float64) {
+	balance := opening
+	for _, p := range points {
+		key := p.PeriodStart.Unix()
+		p.Earned = roundCents(earned[key])
+		p.Settled = roundCents(settled[key])
+		balance = roundCents(balance + p.Earned - p.Settled)
+		p.Balance = balance
+	}
+}
+
+// settlementCutoff returns the last settlement date that falls before an
+// exclusive time bound; a settlement counts from midnight UTC on its date
+func settlementCutoff(bound time.Time) time.Time {
+	last := bound.UTC().Add(-time.Microsecond)
+	return time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utcDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBalanceIntervalTruncate(t *testing.T) {
+	// 2026-09-16 is a Wednesday
+	at := time.Date(2026, 9, 16, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, utcDate(2026, 9, 14), IntervalWeek.Truncate(at))
+	assert.Equal(t, utcDate(2026, 9, 1), IntervalMonth.Truncate(at))
+
+	// Sunday belongs to the week that started the Monday before
+	assert.Equal(t, utcDate(2026, 9, 14), IntervalWeek.Truncate(utcDate(2026, 9, 20)))
+	assert.Equal(t, utcDate(2026, 9, 21), IntervalWeek.Truncate(utcDate(2026, 9, 21)))
+
+	// Non-UTC times are aligned in UTC
+	ist := time.FixedZone("IST", 5*3600+1800)
+	assert.Equal(t, utcDate(2026, 8, 1), IntervalMonth.Truncate(time.Date(2026, 9, 1, 2, 0, 0, 0, ist)))
+}
+
+func TestBalancePeriods(t *testing.T) {
+	points, err := balancePeriods(IntervalMonth, utcDate(2026, 7, 15), time.Date(2026, 9, 10, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, utcDate(2026, 7, 1), points[0].PeriodStart)
+	assert.Equal(t, utcDate(2026, 8, 1), points[0].PeriodEnd)
+	assert.Equal(t, utcDate(2026, 9, 1), points[2].PeriodStart)
+	assert.Equal(t, time.Date(2026, 9, 10, 12, 0, 0, 0, time.UTC), points[2].PeriodEnd)
+
+	points, err = balancePeriods(IntervalWeek, utcDate(2026, 9, 14), utcDate(2026, 9, 28))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, utcDate(2026, 9, 21), points[1].PeriodStart)
+	assert.Equal(t, utcDate(2026, 9, 28), points[1].PeriodEnd)
+
+	points, err = balancePeriods(IntervalWeek, utcDate(2026, 9, 14), utcDate(2026, 9, 14))
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	_, err = balancePeriods(IntervalWeek, utcDate(2000, 1, 1), utcDate(2026, 1, 1))
+	assert.ErrorIs(t, err, ErrTooManyPeriods)
+}
+
+func TestFillBalances(t *testing.T) {
+	points, err := balancePeriods(IntervalWeek, utcDate(2026, 9, 7), utcDate(2026, 9, 28))
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	earned := map[int64]float64{
+		utcDate(2026, 9, 7).Unix():  0.1,
+		utcDate(2026, 9, 21).Unix(): 0.2,
+	}
+	settled := map[int64]float64{
+		utcDate(2026, 9, 14).Unix(): 5,
+	}
+	fillBalances(points, 10, earned, settled)
+
+	assert.Equal(t, 0.1, points[0].Earned)
+	assert.Equal(t, 10.1, points[0].Balance)
+	assert.Equal(t, 5.0, points[1].Settled)
+	assert.Equal(t, 5.1, points[1].Balance)
+	assert.Equal(t, 5.3, points[2].Balance)
+}
+
+func TestSettlementCutoff(t *testing.T) {
+	// A bare date bound (start of the next day) includes settlements on that date
+	assert.Equal(t, utcDate(2026, 9, 1), settlementCutoff(utcDate(2026, 9, 2)))
+	// A mid-day bound includes settlements dated that day
+	assert.Equal(t, utcDate(2026, 9, 2), settlementCutoff(time.Date(2026, 9, 2, 9, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+)
+
+// ChainRepo maintains the per-group hash chain over ledger entries and settlements
+type ChainRepo struct {
+	pool   *pgxpool.Pool
+	hasher *chain.Hasher
+}
+
+// NewChainRepo creates a new ChainRepo
+func NewChainRepo(pool *pgxpool.Pool, hasher *chain.Hasher) *ChainRepo {
+	return &ChainRepo{pool: pool, hasher: hasher}
+}
+
+// appendLink adds a link for the given row to its group's chain and stores
+// the resulting hash on the row. It must run in the transaction that wrote the row.
+func (r *ChainRepo) appendLink(ctx context.Context, tx pgx.Tx, groupID uuid.UUID, recordType chain.RecordType, recordID uuid.UUID, payload chain.Payload) (string, error) {
+	encoded, err := chain.Canonical(payload)
+	if err != nil {
+		return "", err
+	}
+
+	// Serialize appends per group so seq and prev_hash stay consistent
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "ledger_chain:"+groupID.String()); err != nil {
+		return "", fmt.Errorf("failed to lock ledger chain: %w", err)
+	}
+
+	var lastSeq int64
+	prevHash := chain.GenesisHash
+	err = tx.QueryRow(ctx, `
+		SELECT seq, hash
+		FROM ledger_chain
+		WHERE group_id = $1
+		ORDER BY seq DESC
+		LIMIT 1
+	`, groupID).Scan(&lastSeq, &prevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get chain head: %w", err)
+	}
+
+	seq := lastSeq + 1
+	hash := r.hasher.Hash(groupID, seq, recordType, prevHash, encoded)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO ledger_chain (group_id, seq, record_type, record_id, payload, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, groupID, seq, recordType, recordID, encoded, prevHash, hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to append chain link: %w", err)
+	}
+
+	var table string
+	switch recordType {
+	case chain.RecordLedgerEntry:
+		table = "ledger_entries"
+	case chain.RecordSettlement:
+		table = "settlements"
+	default:
+		return "", fmt.Errorf("unknown chain record type %q", recordType)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET hash = $2 WHERE id = $1`, table), recordID, hash); err != nil {
+		return "", fmt.Errorf("failed to store record hash: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Verify recomputes a group's chain and compares it against the current rows
+func (r *ChainRepo) Verify(ctx context.Context, groupID uuid.UUID) (*chain.Report, error) {
+	links, err := r.listLinks(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.currentRecords(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.hasher.Verify(groupID, links, current), nil
+}
+
+// Seal chains every row that has no link yet, e.g. rows written before the
+// chain existed. It returns the number of rows added to the chain.
+func (r *ChainRepo) Seal(ctx context.Context) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+ledgerEntryColumns+`
+		FROM ledger_entries
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ledger_chain lc
+			WHERE lc.record_type = 'ledger_entry' AND lc.record_id = ledger_entries.id
+		)
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unchained ledger entries: %w", err)
+	}
+	entries, err := collectLedgerEntries(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT `+settlementColumns+`
+		FROM settlements
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ledger_chain lc
+			WHERE lc.record_type = 'settlement' AND lc.record_id = settlements.id
+		)
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unchained settlements: %w", err)
+	}
+	settlements, err := collectSettlements(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		if _, err := r.appendLink(ctx, tx, e.GroupID, chain.RecordLedgerEntry, e.ID, chain.LedgerEntryPayload(e)); err != nil {
+			return 0, err
+		}
+	}
+	for _, s := range settlements {
+		if _, err := r.appendLink(ctx, tx, s.GroupID, chain.RecordSettlement, s.ID, chain.SettlementPayload(s)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(entries) + len(settlements), nil
+}
+
+func (r *ChainRepo) listLinks(ctx context.Context, groupID uuid.UUID) ([]chain.Link, error) {
+	query := `
+		SELECT group_id, seq, record_type, record_id, payload, prev_hash, hash
+		FROM ledger_chain
+		WHERE group_id = $1
+		ORDER BY seq ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chain links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []chain.Link
+	for rows.Next() {
+		var l chain.Link
+		if err := rows.Scan(&l.GroupID, &l.Seq, &l.RecordType, &l.RecordID, &l.Payload, &l.PrevHash, &l.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan chain link: %w", err)
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chain links: %w", err)
+	}
+
+	return links, nil
+}
+
+func (r *ChainRepo) currentRecords(ctx context.Context, groupID uuid.UUID) (map[chain.RecordKey]chain.Record, error) {
+	current := make(map[chain.RecordKey]chain.Record)
+
+	rows, err := r.pool.Query(ctx, `SELECT `+ledgerEntryColumns+` FROM ledger_entries WHERE group_id = $1`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+	}
+	entries, err := collectLedgerEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		current[chain.RecordKey{Type: chain.RecordLedgerEntry, ID: e.ID}] = chain.Record{
+			Payload: chain.LedgerEntryPayload(e),
+			Hash:    e.Hash,
+		}
+	}
+
+	rows, err = r.pool.Query(ctx, `SELECT `+settlementColumns+` FROM settlements WHERE group_id = $1`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlements: %w", err)
+	}
+	settlements, err := collectSettlements(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range settlements {
+		current[chain.RecordKey{Type: chain.RecordSettlement, ID: s.ID}] = chain.Record{
+			Payload: chain.SettlementPayload(s),
+			Hash:    s.Hash,
+		}
+	}
+
+	return current, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -22,23 +23,35 @@ func NewChoreRepo(pool *pgxpool.Pool) *ChoreRepo {
 	return &ChoreRepo{pool: pool}
 }
 
-// Create inserts a new chore into the database
-func (r *ChoreRepo) Create(ctx context.Context, groupID uuid.UUID, name string, description *string, amount float64) (*models.Chore, error) {
-	chore := &models.Chore{
-		ID:          uuid.New(),
-		GroupID:     groupID,
-		Name:        name,
-		Description: description,
-		Amount:      amount,
-	}
+const choreColumns = `id, group_id, name, description, amount, proof_required, assignee_user_id, due_at, assigned_at, created_at, deleted_at`
 
+func scanChore(row pgx.Row) (*models.Chore, error) {
+	chore := &models.Chore{}
+	err := row.Scan(
+		&chore.ID,
+		&chore.GroupID,
+		&chore.Name,
+		&chore.Description,
+		&chore.Amount,
+		&chore.ProofRequired,
+		&chore.AssigneeUserID,
+		&chore.DueAt,
+		&chore.AssignedAt,
+		&chore.CreatedAt,
+		&chore.DeletedAt,
+	)
+	return chore, err
+}
+
+// Create inserts a new chore into the database, optionally assigned to a
+// member and due by dueAt
+func (r *ChoreRepo) Create(ctx context.Context, groupID uuid.UUID, name string, description *string, amount float64, proofRequired bool, assigneeUserID *uuid.UUID, dueAt *time.Time) (*models.Chore, error) {
 	query := `
-		INSERT INTO chores (id, group_id, name, description, amount)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING created_at
-	`
+		INSERT INTO chores (id, group_id, name, description, amount, proof_required, assignee_user_id, due_at, assigned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CASE WHEN $7::uuid IS NOT NULL THEN now() END)
+		RETURNING ` + choreColumns
 
-	err := r.pool.QueryRow(ctx, query, chore.ID, groupID, name, description, amount).Scan(&chore.CreatedAt)
+	chore, err := scanChore(r.pool.QueryRow(ctx, query, uuid.New(), groupID, name, description, amount, proofRequired, assigneeUserID, dueAt))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chore: %w", err)
 	}
@@ -46,24 +59,15 @@ func (r *ChoreRepo) Create(ctx context.Context, groupID uuid.UUID, name string,
 	return chore, nil
 }
 
-// GetByID retrieves a chore by ID
+// GetByID retrieves a chore by ID, including an archived one
 func (r *ChoreRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Chore, error) {
-	chore := &models.Chore{}
-
 	query := `
-		SELECT id, group_id, name, description, amount, created_at
+		SELECT ` + choreColumns + `
 		FROM chores
 		WHERE id = $1
 	`
 
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&chore.ID,
-		&chore.GroupID,
-		&chore.Name,
-		&chore.Description,
-		&chore.Amount,
-		&chore.CreatedAt,
-	)
+	chore, err := scanChore(r.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -74,16 +78,17 @@ func (r *ChoreRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Chore, e
 	return chore, nil
 }
 
-// ListForGroup retrieves all chores for a group
-func (r *ChoreRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*models.Chore, error) {
+// ListForGroup retrieves the chores for a group, archived ones only when
+// includeDeleted is set
+func (r *ChoreRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, includeDeleted bool) ([]*models.Chore, error) {
 	query := `
-		SELECT id, group_id, name, description, amount, created_at
+		SELECT ` + choreColumns + `
 		FROM chores
-		WHERE group_id = $1
+		WHERE group_id = $1 AND ($2 OR deleted_at IS NULL)
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.pool.Query(ctx, query, groupID)
+	rows, err := r.pool.Query(ctx, query, groupID, includeDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list chores: %w", err)
 	}
@@ -91,15 +96,8 @@ func (r *ChoreRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*mod
 
 	var chores []*models.Chore
 	for rows.Next() {
-		chore := &models.Chore{}
-		if err := rows.Scan(
-			&chore.ID,
-			&chore.GroupID,
-			&chore.Name,
-			&chore.Description,
-			&chore.Amount,
-			&chore.CreatedAt,
-		); err != nil {
+		chore, err := scanChore(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan chore: %w", err)
 		}
 		chores = append(chores, chore)
@@ -108,27 +106,40 @@ func (r *ChoreRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*mod
 	return chores, nil
 }
 
-// Update updates a chore
-func (r *ChoreRepo) Update(ctx context.Context, id uuid.UUID, name *string, description *string, amount *float64) (*models.Chore, error) {
-	// Build dynamic update query
+// ChoreUpdate holds the changes to a chore; nil fields are kept
+type ChoreUpdate struct {
+	Name           *string
+	Description    *string
+	Amount         *float64
+	ProofRequired  *bool
+	SetAssignee    bool // AssigneeUserID replaces the current value, nil removing it
+	AssigneeUserID *uuid.UUID
+	SetDueAt       bool // DueAt replaces the current value, nil removing it
+	DueAt          *time.Time
+}
+
+// Update updates a chore. Changing who it is assigned to or when it is due
+// resets assigned_at, so only entries logged from then on do it.
+func (r *ChoreRepo) Update(ctx context.Context, id uuid.UUID, u ChoreUpdate) (*models.Chore, error) {
 	query := `
 		UPDATE chores
 		SET name = COALESCE($2, name),
 		    description = COALESCE($3, description),
-		    amount = COALESCE($4, amount)
-		WHERE id = $1
-		RETURNING id, group_id, name, description, amount, created_at
-	`
-
-	chore := &models.Chore{}
-	err := r.pool.QueryRow(ctx, query, id, name, description, amount).Scan(
-		&chore.ID,
-		&chore.GroupID,
-		&chore.Name,
-		&chore.Description,
-		&chore.Amount,
-		&chore.CreatedAt,
-	)
+		    amount = COALESCE($4, amount),
+		    proof_required = COALESCE($5, proof_required),
+		    assignee_user_id = CASE WHEN $6 THEN $7 ELSE assignee_user_id END,
+		    due_at = CASE WHEN $8 THEN $9 ELSE due_at END,
+		    assigned_at = CASE
+		        WHEN (CASE WHEN $6 THEN $7 ELSE assignee_user_id END) IS NULL THEN NULL
+		        WHEN (CASE WHEN $6 THEN $7 ELSE assignee_user_id END) IS DISTINCT FROM assignee_user_id
+		          OR (CASE WHEN $8 THEN $9 ELSE due_at END) IS DISTINCT FROM due_at THEN now()
+		        ELSE assigned_at
+		    END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + choreColumns
+
+	chore, err := scanChore(r.pool.QueryRow(ctx, query, id, u.Name, u.Description, u.Amount, u.ProofRequired,
+		u.SetAssignee, u.AssigneeUserID, u.SetDueAt, u.DueAt))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -139,9 +150,10 @@ func (r *ChoreRepo) Update(ctx context.Context, id uuid.UUID, name *string, desc
 	return chore, nil
 }
 
-// Delete deletes a chore
+// Delete archives a chore. It is kept so its entries, which stay on the
+// ledger and in the hash chain, can still name it.
 func (r *ChoreRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM chores WHERE id = $1`
+	query := `UPDATE chores SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
 
 	result, err := r.pool.Exec(ctx, query, id)
 	if err != nil {
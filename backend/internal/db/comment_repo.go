@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// CommentRepo handles database operations for ledger entry comments
+type CommentRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewCommentRepo creates a new CommentRepo
+func NewCommentRepo(pool *pgxpool.Pool) *CommentRepo {
+	return &CommentRepo{pool: pool}
+}
+
+// Create adds a comment to an entry's thread
+func (r *CommentRepo) Create(ctx context.Context, entryID, userID uuid.UUID, body string) (*models.LedgerComment, error) {
+	comment := &models.LedgerComment{
+		ID:      uuid.New(),
+		EntryID: entryID,
+		UserID:  userID,
+		Body:    body,
+	}
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO ledger_comments (id, entry_id, user_id, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING created_at
+		)
+		SELECT inserted.created_at, u.name
+		FROM inserted, users u
+		WHERE u.id = $3
+	`
+
+	err := r.pool.QueryRow(ctx, query, comment.ID, entryID, userID, body).Scan(&comment.CreatedAt, &comment.UserName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// ListForEntry retrieves an entry's comments, oldest first
+func (r *CommentRepo) ListForEntry(ctx context.Context, entryID uuid.UUID) ([]*models.LedgerComment, error) {
+	query := `
+		SELECT c.id, c.entry_id, c.user_id, u.name, c.body, c.created_at
+		FROM ledger_comments c
+		INNER JOIN users u ON c.user_id = u.id
+		WHERE c.entry_id = $1
+		ORDER BY c.created_at, c.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*models.LedgerComment
+	for rows.Next() {
+		c := &models.LedgerComment{}
+		if err := rows.Scan(&c.ID, &c.EntryID, &c.UserID, &c.UserName, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, nil
+}
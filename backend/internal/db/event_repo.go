@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+)
+
+// EventRepo handles database operations for the group event log
+type EventRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewEventRepo creates a new EventRepo
+func NewEventRepo(pool *pgxpool.Pool) *EventRepo {
+	return &EventRepo{pool: pool}
+}
+
+// Append stores an event and returns its record, numbered after the
+// group's last event
+func (r *EventRepo) Append(ctx context.Context, e events.Event) (events.Record, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return events.Record{}, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return events.Record{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Serialize appends per group so events commit in group_seq order
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "group_events:"+e.GroupID.String()); err != nil {
+		return events.Record{}, fmt.Errorf("failed to lock group events: %w", err)
+	}
+
+	rec := events.Record{Type: e.Type, GroupID: e.GroupID, Payload: payload}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO group_events (id, group_id, group_seq, type, actor_user_id, payload, created_at)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(group_seq), 0) + 1 FROM group_events WHERE group_id = $2), $3, $4, $5, $6)
+		RETURNING group_seq
+	`, e.ID, e.GroupID, string(e.Type), e.ActorUserID, string(payload), e.OccurredAt).Scan(&rec.Seq)
+	if err != nil {
+		return events.Record{}, fmt.Errorf("failed to append event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return events.Record{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return rec, nil
+}
+
+// ListSince returns up to limit of a group's events after its group
+// sequence number seq, oldest first
+func (r *EventRepo) ListSince(ctx context.Context, groupID uuid.UUID, seq int64, limit int) ([]events.Record, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT group_seq, type, group_id, payload
+		FROM group_events
+		WHERE group_id = $1 AND group_seq > $2
+		ORDER BY group_seq
+		LIMIT $3
+	`, groupID, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	var records []events.Record
+	for rows.Next() {
+		var rec events.Record
+		var payload string
+		if err := rows.Scan(&rec.Seq, &rec.Type, &rec.GroupID, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		rec.Payload = []byte(payload)
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,76 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/testutil"
+)
+
+func TestEventRepo_NumbersEventsPerGroup(t *testing.T) {
+	pool, err := testutil.NewTestPool()
+	if err != nil {
+		t.Skipf("Skipping test: could not connect to test database: %v", err)
+	}
+	defer pool.Close()
+
+	_ = testutil.ResetTestDB(pool)
+	require.NoError(t, db.RunMigrations(testutil.GetTestDatabaseURL()))
+	defer testutil.CleanupTestDB(pool)
+
+	ctx := context.Background()
+	users := db.NewUserRepo(pool)
+	groups := db.NewGroupRepo(pool)
+	eventRepo := db.NewEventRepo(pool)
+
+	head, err := users.Create(ctx, "parent@example.com", "hash", "Parent", nil, nil)
+	require.NoError(t, err)
+	family, err := groups.Create(ctx, "Family", head.ID)
+	require.NoError(t, err)
+	other, err := groups.Create(ctx, "Other", head.ID)
+	require.NoError(t, err)
+
+	// Concurrent appends to two groups each get a gapless sequence
+	const perGroup = 20
+	var wg sync.WaitGroup
+	for i := 0; i < perGroup; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := eventRepo.Append(ctx, events.New(events.ChoreCreated, family.ID, &head.ID, nil))
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := eventRepo.Append(ctx, events.New(events.ChoreCreated, other.ID, &head.ID, nil))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	records, err := eventRepo.ListSince(ctx, family.ID, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, records, perGroup)
+	for i, rec := range records {
+		assert.Equal(t, int64(i+1), rec.Seq)
+		assert.Equal(t, family.ID, rec.GroupID)
+	}
+
+	// Resuming returns only what followed
+	records, err = eventRepo.ListSince(ctx, other.ID, perGroup-5, 100)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, int64(perGroup-4), records[0].Seq)
+
+	rec, err := eventRepo.Append(ctx, events.New(events.ChoreCreated, family.ID, &head.ID, nil))
+	require.NoError(t, err)
+	assert.Equal(t, int64(perGroup+1), rec.Seq)
+}
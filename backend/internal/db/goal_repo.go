@@ -0,0 +1,379 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/goals"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrOverAllocated is returned when an allocation would set aside more than
+// the member's balance across their goals
+var ErrOverAllocated = errors.New("allocations exceed balance")
+
+// ErrUnderAllocated is returned when taking back more than a goal has allocated
+var ErrUnderAllocated = errors.New("goal has less allocated")
+
+// GoalRepo handles database operations for savings goals and their allocations
+type GoalRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewGoalRepo creates a new GoalRepo
+func NewGoalRepo(pool *pgxpool.Pool) *GoalRepo {
+	return &GoalRepo{pool: pool}
+}
+
+const goalColumns = `id, group_id, user_id, name, target_amount, deadline, progress_source,
+	(SELECT COALESCE(SUM(amount), 0) FROM goal_allocations WHERE goal_id = savings_goals.id),
+	image_content_type, image_size_bytes, image_storage_key, reached_at, created_by_user_id, created_at, updated_at, deleted_at`
+
+// goalBalance selects, after goalColumns, the member's balance from entries
+// that occurred before $2 and settlements dated on or before $3
+const goalBalance = `,
+	(SELECT COALESCE(SUM(amount), 0) FROM ledger_entries le
+	 WHERE le.group_id = savings_goals.group_id AND le.user_id = savings_goals.user_id
+	   AND le.status = 'approved' AND le.occurred_at < $2)
+	-
+	(SELECT COALESCE(SUM(amount), 0) FROM settlements s
+	 WHERE s.group_id = savings_goals.group_id AND s.user_id = savings_goals.user_id
+	   AND s.voided_at IS NULL AND s.date <= $3)`
+
+const goalAllocationColumns = `id, goal_id, amount, note, created_by_user_id, created_at`
+
+// goalFields returns the scan destinations for goalColumns
+func goalFields(g *models.Goal) []any {
+	return []any{&g.ID, &g.GroupID, &g.UserID, &g.Name, &g.TargetAmount, &g.Deadline, &g.Source, &g.Allocated,
+		&g.ImageContentType, &g.ImageSizeBytes, &g.ImageStorageKey, &g.ReachedAt, &g.CreatedByUserID, &g.CreatedAt, &g.UpdatedAt, &g.DeletedAt}
+}
+
+func scanGoal(row pgx.Row) (*models.Goal, error) {
+	g := &models.Goal{}
+	err := row.Scan(goalFields(g)...)
+	return g, err
+}
+
+func scanGoalAllocation(row pgx.Row) (*models.GoalAllocation, error) {
+	a := &models.GoalAllocation{}
+	err := row.Scan(&a.ID, &a.GoalID, &a.Amount, &a.Note, &a.CreatedByUserID, &a.CreatedAt)
+	return a, err
+}
+
+// Create inserts a new goal
+func (r *GoalRepo) Create(ctx context.Context, g *models.Goal) (*models.Goal, error) {
+	query := `
+		INSERT INTO savings_goals (group_id, user_id, name, target_amount, deadline, progress_source, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + goalColumns
+
+	created, err := scanGoal(r.pool.QueryRow(ctx, query,
+		g.GroupID, g.UserID, g.Name, g.TargetAmount, g.Deadline, g.Source, g.CreatedByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+	return created, nil
+}
+
+// GetByID retrieves a goal by ID, including deleted ones
+func (r *GoalRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Goal, error) {
+	g, err := scanGoal(r.pool.QueryRow(ctx, `SELECT `+goalColumns+` FROM savings_goals WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get goal by id: %w", err)
+	}
+	return g, nil
+}
+
+// ListForMember retrieves a member's goals, oldest first. Deleted goals are
+// only included if includeDeleted is set.
+func (r *GoalRepo) ListForMember(ctx context.Context, groupID, userID uuid.UUID, includeDeleted bool) ([]*models.Goal, error) {
+	query := `
+		SELECT ` + goalColumns + `
+		FROM savings_goals
+		WHERE group_id = $1 AND user_id = $2 AND ($3 OR deleted_at IS NULL)
+		ORDER BY created_at, id
+	`
+	rows, err := r.pool.Query(ctx, query, groupID, userID, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Goal
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		list = append(list, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read goals: %w", err)
+	}
+	return list, nil
+}
+
+// GoalUpdate holds the changes to a goal; nil fields are kept
+type GoalUpdate struct {
+	Name         *string
+	TargetAmount *float64
+	SetDeadline  bool // Deadline replaces the current value, nil removing it
+	Deadline     *time.Time
+	Source       *models.GoalSource
+}
+
+// Update changes a goal that has not been deleted. Raising the target or
+// changing what counts towards it makes a reached goal unreached again, so
+// reaching it once more is announced.
+func (r *GoalRepo) Update(ctx context.Context, id uuid.UUID, u GoalUpdate) (*models.Goal, error) {
+	query := `
+		UPDATE savings_goals
+		SET name = COALESCE($2, name),
+		    target_amount = COALESCE($3, target_amount),
+		    deadline = CASE WHEN $4 THEN $5 ELSE deadline END,
+		    progress_source = COALESCE($6, progress_source),
+		    reached_at = CASE WHEN COALESCE($3, target_amount) > target_amount
+		                        OR COALESCE($6, progress_source) <> progress_source
+		                      THEN NULL ELSE reached_at END,
+		    updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + goalColumns
+
+	g, err := scanGoal(r.pool.QueryRow(ctx, query, id, u.Name, u.TargetAmount, u.SetDeadline, u.Deadline, u.Source))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+	return g, nil
+}
+
+// SetImage replaces a goal's image, or removes it when key is nil, and
+// returns the goal with the storage key of the image it had before, if any
+func (r *GoalRepo) SetImage(ctx context.Context, id uuid.UUID, contentType *string, sizeBytes *int64, key *string) (*models.Goal, *string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var previous *string
+	err = tx.QueryRow(ctx, `SELECT image_storage_key FROM savings_goals WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to lock goal: %w", err)
+	}
+
+	g, err := scanGoal(tx.QueryRow(ctx, `
+		UPDATE savings_goals
+		SET image_content_type = $2, image_size_bytes = $3, image_storage_key = $4, updated_at = now()
+		WHERE id = $1
+		RETURNING `+goalColumns, id, contentType, sizeBytes, key))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set goal image: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return g, previous, nil
+}
+
+// Delete soft-deletes a goal, releasing its allocations, and returns the
+// storage key of its image, if any, which it no longer refers to
+func (r *GoalRepo) Delete(ctx context.Context, id uuid.UUID) (*string, error) {
+	var previous *string
+	err := r.pool.QueryRow(ctx, `
+		UPDATE savings_goals g
+		SET deleted_at = now(), updated_at = now(),
+		    image_content_type = NULL, image_size_bytes = NULL, image_storage_key = NULL
+		FROM (SELECT id, image_storage_key FROM savings_goals WHERE id = $1 FOR UPDATE) old
+		WHERE g.id = old.id AND g.deleted_at IS NULL
+		RETURNING old.image_storage_key
+	`, id).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to delete goal: %w", err)
+	}
+	return previous, nil
+}
+
+// ListAllocations retrieves a goal's allocations, oldest first
+func (r *GoalRepo) ListAllocations(ctx context.Context, goalID uuid.UUID) ([]*models.GoalAllocation, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+goalAllocationColumns+`
+		FROM goal_allocations
+		WHERE goal_id = $1
+		ORDER BY created_at, id
+	`, goalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goal allocations: %w", err)
+	}
+	defer rows.Close()
+
+	var allocations []*models.GoalAllocation
+	for rows.Next() {
+		a, err := scanGoalAllocation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal allocation: %w", err)
+		}
+		allocations = append(allocations, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read goal allocations: %w", err)
+	}
+	return allocations, nil
+}
+
+// Allocate sets amount aside for a goal, or takes it back when negative.
+// The member's goals are locked so that together they never have more
+// allocated than the member's balance at now, and no goal has less than nothing.
+func (r *GoalRepo) Allocate(ctx context.Context, goalID uuid.UUID, amount float64, note *string, createdByUserID uuid.UUID, now time.Time) (*models.GoalAllocation, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var groupID, userID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT group_id, user_id FROM savings_goals WHERE id = $1 AND deleted_at IS NULL`, goalID).Scan(&groupID, &userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get goal: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+goalColumns+goalBalance+`
+		FROM savings_goals
+		WHERE group_id = $1 AND user_id = $4 AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, groupID, now, settlementCutoff(now), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock goals: %w", err)
+	}
+	var total, balance float64
+	var goal *models.Goal
+	for rows.Next() {
+		g := &models.Goal{}
+		if err := rows.Scan(append(goalFields(g), &balance)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		total += g.Allocated
+		if g.ID == goalID {
+			goal = g
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read goals: %w", err)
+	}
+	if goal == nil {
+		return nil, ErrNotFound
+	}
+
+	if roundCents(goal.Allocated+amount) < 0 {
+		return nil, ErrUnderAllocated
+	}
+	if amount > 0 && roundCents(total+amount) > roundCents(balance) {
+		return nil, ErrOverAllocated
+	}
+
+	allocation, err := scanGoalAllocation(tx.QueryRow(ctx, `
+		INSERT INTO goal_allocations (goal_id, amount, note, created_by_user_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+goalAllocationColumns, goalID, amount, note, createdByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal allocation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return allocation, nil
+}
+
+// MarkReached stamps the group's unreached goals of current members that
+// have reached their target by now, and returns them. Each goal is returned
+// once, however often this runs, until a change makes it unreached again.
+func (r *GoalRepo) MarkReached(ctx context.Context, groupID uuid.UUID, now time.Time) ([]*models.Goal, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+goalColumns+goalBalance+`
+		FROM savings_goals
+		WHERE group_id = $1 AND deleted_at IS NULL AND reached_at IS NULL
+		  AND EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = savings_goals.group_id AND gm.user_id = savings_goals.user_id)
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`, groupID, now, settlementCutoff(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select unreached goals: %w", err)
+	}
+	var reached []uuid.UUID
+	for rows.Next() {
+		g := &models.Goal{}
+		var balance float64
+		if err := rows.Scan(append(goalFields(g), &balance)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		if goals.Saved(g, balance) >= g.TargetAmount {
+			reached = append(reached, g.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read unreached goals: %w", err)
+	}
+	if len(reached) == 0 {
+		return nil, nil
+	}
+
+	rows, err = tx.Query(ctx, `
+		UPDATE savings_goals SET reached_at = $2
+		WHERE id = ANY($1)
+		RETURNING `+goalColumns, reached, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark goals reached: %w", err)
+	}
+	var list []*models.Goal
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		list = append(list, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reached goals: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return list, nil
+}
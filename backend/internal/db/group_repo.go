@@ -12,6 +12,9 @@ import (
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
+// groupColumns is the column list scanned by scanGroup
+const groupColumns = `id, name, head_user_id, max_backdate_days, expire_pending_after_days, auto_approve_after_hours, auto_approve_max_amount, require_reject_reason, created_at`
+
 // GroupRepo handles database operations for groups
 type GroupRepo struct {
 	pool *pgxpool.Pool
@@ -46,20 +49,13 @@ func (r *GroupRepo) Create(ctx context.Context, name string, headUserID uuid.UUI
 
 // GetByID retrieves a group by ID
 func (r *GroupRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Group, error) {
-	group := &models.Group{}
-
 	query := `
-		SELECT id, name, head_user_id, created_at
+		SELECT ` + groupColumns + `
 		FROM groups
 		WHERE id = $1
 	`
 
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&group.ID,
-		&group.Name,
-		&group.HeadUserID,
-		&group.CreatedAt,
-	)
+	group, err := scanGroup(r.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -73,7 +69,8 @@ func (r *GroupRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Group, e
 // ListForUser retrieves all groups a user is a member of
 func (r *GroupRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]*models.Group, error) {
 	query := `
-		SELECT g.id, g.name, g.head_user_id, g.created_at
+		SELECT g.id, g.name, g.head_user_id, g.max_backdate_days, g.expire_pending_after_days,
+		       g.auto_approve_after_hours, g.auto_approve_max_amount, g.require_reject_reason, g.created_at
 		FROM groups g
 		INNER JOIN group_members gm ON g.id = gm.group_id
 		WHERE gm.user_id = $1
@@ -88,8 +85,34 @@ func (r *GroupRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]*model
 
 	var groups []*models.Group
 	for rows.Next() {
-		group := &models.Group{}
-		if err := rows.Scan(&group.ID, &group.Name, &group.HeadUserID, &group.CreatedAt); err != nil {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// ListAll retrieves every group on this server
+func (r *GroupRepo) ListAll(ctx context.Context) ([]*models.Group, error) {
+	query := `
+		SELECT ` + groupColumns + `
+		FROM groups
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*models.Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan group: %w", err)
 		}
 		groups = append(groups, group)
@@ -98,6 +121,45 @@ func (r *GroupRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]*model
 	return groups, nil
 }
 
+// GroupSettings are changes to a group's settings. A nil Name or
+// RequireRejectReason is left unchanged; the other settings are only written
+// when their Set flag is true.
+type GroupSettings struct {
+	Name                *string
+	SetMaxBackdateDays  bool
+	MaxBackdateDays     *int
+	SetPendingPolicy    bool
+	PendingPolicy       models.PendingPolicy
+	RequireRejectReason *bool
+}
+
+// UpdateSettings updates a group's name and settings
+func (r *GroupRepo) UpdateSettings(ctx context.Context, id uuid.UUID, s GroupSettings) (*models.Group, error) {
+	query := `
+		UPDATE groups
+		SET name = COALESCE($2, name),
+		    max_backdate_days = CASE WHEN $3 THEN $4 ELSE max_backdate_days END,
+		    expire_pending_after_days = CASE WHEN $5 THEN $6 ELSE expire_pending_after_days END,
+		    auto_approve_after_hours = CASE WHEN $5 THEN $7 ELSE auto_approve_after_hours END,
+		    auto_approve_max_amount = CASE WHEN $5 THEN $8 ELSE auto_approve_max_amount END,
+		    require_reject_reason = COALESCE($9, require_reject_reason)
+		WHERE id = $1
+		RETURNING ` + groupColumns + `
+	`
+
+	p := s.PendingPolicy
+	group, err := scanGroup(r.pool.QueryRow(ctx, query, id, s.Name, s.SetMaxBackdateDays, s.MaxBackdateDays,
+		s.SetPendingPolicy, p.ExpirePendingAfterDays, p.AutoApproveAfterHours, p.AutoApproveMaxAmount, s.RequireRejectReason))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return group, nil
+}
+
 // AddMember adds a user to a group
 func (r *GroupRepo) AddMember(ctx context.Context, groupID, userID uuid.UUID, role models.MemberRole) (*models.GroupMember, error) {
 	member := &models.GroupMember{
@@ -184,13 +246,33 @@ func (r *GroupRepo) ListMembers(ctx context.Context, groupID uuid.UUID) ([]*mode
 	return members, nil
 }
 
-// CountChores returns the number of chores in a group
+// CountChores returns the number of chores in a group, not counting archived ones
 func (r *GroupRepo) CountChores(ctx context.Context, groupID uuid.UUID) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM chores WHERE group_id = $1`
+	query := `SELECT COUNT(*) FROM chores WHERE group_id = $1 AND deleted_at IS NULL`
 	err := r.pool.QueryRow(ctx, query, groupID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count chores: %w", err)
 	}
 	return count, nil
 }
+
+// scanGroup scans a row selected with groupColumns
+func scanGroup(row pgx.Row) (*models.Group, error) {
+	group := &models.Group{}
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.HeadUserID,
+		&group.MaxBackdateDays,
+		&group.ExpirePendingAfterDays,
+		&group.AutoApproveAfterHours,
+		&group.AutoApproveMaxAmount,
+		&group.RequireRejectReason,
+		&group.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
@@ -0,0 +1,504 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/interest"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrDuplicateRate is returned when the group, or the jar, already has an interest rate
+var ErrDuplicateRate = errors.New("an interest rate already exists for this")
+
+// InterestRepo handles database operations for interest rates and the
+// entries they post
+type InterestRepo struct {
+	pool  *pgxpool.Pool
+	chain *ChainRepo
+}
+
+// NewInterestRepo creates a new InterestRepo
+func NewInterestRepo(pool *pgxpool.Pool, chainRepo *ChainRepo) *InterestRepo {
+	return &InterestRepo{pool: pool, chain: chainRepo}
+}
+
+const interestRateColumns = `id, group_id, jar_id, apr, compounding, min_balance, start_date, next_period, created_by_user_id, created_at, updated_at, deleted_at`
+
+const interestPostingColumns = `id, rate_id, entry_id, group_id, user_id, jar_id, period_start, amount, breakdown, created_at`
+
+// memberChanges selects, by UTC day, what moved member $2's balance in
+// group $1 before $3: approved entries by when they occurred and
+// settlements that were not voided, dated before the day $4
+const memberChanges = `
+	SELECT (occurred_at AT TIME ZONE 'UTC')::date AS day, amount
+	FROM ledger_entries
+	WHERE group_id = $1 AND user_id = $2 AND status = 'approved' AND occurred_at < $3
+	UNION ALL
+	SELECT date, -amount
+	FROM settlements
+	WHERE group_id = $1 AND user_id = $2 AND voided_at IS NULL AND date < $4`
+
+// jarChanges selects, by UTC day, what moved the balances of the jars $1
+// before $2: their splits of approved entries, transfers in and out, and
+// settlements paid out of them that were not voided, dated before the day $3
+const jarChanges = `
+	SELECT (le.occurred_at AT TIME ZONE 'UTC')::date AS day, js.amount
+	FROM jar_splits js
+	INNER JOIN ledger_entries le ON le.id = js.entry_id
+	WHERE js.jar_id = ANY($1) AND le.status = 'approved' AND le.occurred_at < $2
+	UNION ALL
+	SELECT (created_at AT TIME ZONE 'UTC')::date, amount
+	FROM jar_transfers
+	WHERE to_jar_id = ANY($1) AND created_at < $2
+	UNION ALL
+	SELECT (created_at AT TIME ZONE 'UTC')::date, -amount
+	FROM jar_transfers
+	WHERE from_jar_id = ANY($1) AND created_at < $2
+	UNION ALL
+	SELECT date, -amount
+	FROM settlements
+	WHERE jar_id = ANY($1) AND voided_at IS NULL AND date < $3`
+
+func scanInterestRate(row pgx.Row) (*models.InterestRate, error) {
+	r := &models.InterestRate{}
+	err := row.Scan(&r.ID, &r.GroupID, &r.JarID, &r.APR, &r.Compounding, &r.MinBalance, &r.StartDate, &r.NextPeriod,
+		&r.CreatedByUserID, &r.CreatedAt, &r.UpdatedAt, &r.DeletedAt)
+	return r, err
+}
+
+func scanInterestPosting(row pgx.Row) (*models.InterestPosting, error) {
+	p := &models.InterestPosting{}
+	var breakdown string
+	err := row.Scan(&p.ID, &p.RateID, &p.EntryID, &p.GroupID, &p.UserID, &p.JarID, &p.PeriodStart, &p.Amount, &breakdown, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(breakdown), &p.Breakdown); err != nil {
+		return nil, fmt.Errorf("failed to decode interest breakdown: %w", err)
+	}
+	return p, nil
+}
+
+// collectInterestRates scans and closes rows selected with interestRateColumns
+func collectInterestRates(rows pgx.Rows) ([]*models.InterestRate, error) {
+	defer rows.Close()
+
+	var rates []*models.InterestRate
+	for rows.Next() {
+		rate, err := scanInterestRate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan interest rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interest rates: %w", err)
+	}
+	return rates, nil
+}
+
+// Create inserts a new interest rate whose first period starts on its
+// start date. A jar rate needs one of the group's current jars.
+func (r *InterestRepo) Create(ctx context.Context, rate *models.InterestRate) (*models.InterestRate, error) {
+	query := `
+		INSERT INTO interest_rates (group_id, jar_id, apr, compounding, min_balance, start_date, next_period, created_by_user_id)
+		SELECT $1, $2, $3, $4, $5, $6, $6, $7
+		WHERE $2::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM jars WHERE id = $2 AND group_id = $1 AND deleted_at IS NULL
+		)
+		RETURNING ` + interestRateColumns
+
+	created, err := scanInterestRate(r.pool.QueryRow(ctx, query,
+		rate.GroupID, rate.JarID, rate.APR, rate.Compounding, rate.MinBalance, rate.StartDate, rate.CreatedByUserID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUnknownJar
+		}
+		if isDuplicateKeyError(err) {
+			return nil, ErrDuplicateRate
+		}
+		return nil, fmt.Errorf("failed to create interest rate: %w", err)
+	}
+	return created, nil
+}
+
+// GetByID retrieves an interest rate by ID, including deleted ones
+func (r *InterestRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.InterestRate, error) {
+	rate, err := scanInterestRate(r.pool.QueryRow(ctx, `SELECT `+interestRateColumns+` FROM interest_rates WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get interest rate by id: %w", err)
+	}
+	return rate, nil
+}
+
+// ListForGroup retrieves a group's interest rates, oldest first. Deleted
+// rates are only included if includeDeleted is set.
+func (r *InterestRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, includeDeleted bool) ([]*models.InterestRate, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+interestRateColumns+`
+		FROM interest_rates
+		WHERE group_id = $1 AND ($2 OR deleted_at IS NULL)
+		ORDER BY created_at, id
+	`, groupID, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interest rates: %w", err)
+	}
+	return collectInterestRates(rows)
+}
+
+// InterestRateUpdate holds the changes to an interest rate; nil fields are
+// kept. Changes apply to periods not yet posted.
+type InterestRateUpdate struct {
+	APR        *float64
+	MinBalance *float64
+}
+
+// Update changes an interest rate that has not been deleted
+func (r *InterestRepo) Update(ctx context.Context, id uuid.UUID, u InterestRateUpdate) (*models.InterestRate, error) {
+	query := `
+		UPDATE interest_rates
+		SET apr = COALESCE($2, apr),
+		    min_balance = COALESCE($3, min_balance),
+		    updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + interestRateColumns
+
+	rate, err := scanInterestRate(r.pool.QueryRow(ctx, query, id, u.APR, u.MinBalance))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update interest rate: %w", err)
+	}
+	return rate, nil
+}
+
+// Delete stops an interest rate for good. The row is kept so the entries
+// it posted still name it; the period under way earns nothing.
+func (r *InterestRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `UPDATE interest_rates SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete interest rate: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListPostings retrieves the interest a group's rates have posted,
+// optionally only to one member, oldest period first
+func (r *InterestRepo) ListPostings(ctx context.Context, groupID uuid.UUID, userID *uuid.UUID) ([]*models.InterestPosting, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+interestPostingColumns+`
+		FROM interest_postings
+		WHERE group_id = $1 AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY period_start, created_at, id
+	`, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interest postings: %w", err)
+	}
+	defer rows.Close()
+
+	var postings []*models.InterestPosting
+	for rows.Next() {
+		p, err := scanInterestPosting(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan interest posting: %w", err)
+		}
+		postings = append(postings, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interest postings: %w", err)
+	}
+	return postings, nil
+}
+
+// PostDue posts the interest for every period of a running rate that has
+// ended by now, catching up on periods missed while the server was down,
+// and returns the new entries. Each member is paid for a period at most
+// once, so running it again or on several instances is safe.
+func (r *InterestRepo) PostDue(ctx context.Context, now time.Time) ([]*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+interestRateColumns+`
+		FROM interest_rates
+		WHERE deleted_at IS NULL AND next_period < $1
+		ORDER BY next_period, id
+		FOR UPDATE SKIP LOCKED
+	`, allowance.Day(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select due interest rates: %w", err)
+	}
+	rates, err := collectInterestRates(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*models.LedgerEntry
+	for _, rate := range rates {
+		posted, err := r.postDue(ctx, tx, rate, now)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, posted...)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entries, nil
+}
+
+// postDue posts a rate's ended periods, each as an approved entry on the
+// day after the period, and moves the rate on to its next period. Members
+// whose interest comes to nothing get no entry.
+func (r *InterestRepo) postDue(ctx context.Context, tx pgx.Tx, rate *models.InterestRate, now time.Time) ([]*models.LedgerEntry, error) {
+	periods := interest.Due(rate, now)
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	earners, err := interestEarners(ctx, tx, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*models.LedgerEntry
+	var end time.Time
+	for _, period := range periods {
+		end = interest.PeriodEnd(rate.StartDate, rate.Compounding, period)
+		for _, userID := range earners {
+			entry, err := r.post(ctx, tx, rate, userID, period, end)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE interest_rates SET next_period = $2 WHERE id = $1`, rate.ID, end); err != nil {
+		return nil, fmt.Errorf("failed to advance interest rate: %w", err)
+	}
+	rate.NextPeriod = end
+
+	return entries, nil
+}
+
+// interestEarners returns who a rate pays: a jar's owner while they are
+// still in the group, or every member of the group who is not a head
+func interestEarners(ctx context.Context, tx pgx.Tx, rate *models.InterestRate) ([]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT gm.user_id
+		FROM group_members gm
+		WHERE gm.group_id = $1
+		  AND CASE WHEN $2::uuid IS NULL THEN gm.role <> 'head'
+		      ELSE gm.user_id = (SELECT user_id FROM jars WHERE id = $2 AND deleted_at IS NULL) END
+		ORDER BY gm.user_id
+	`, rate.GroupID, rate.JarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interest earners: %w", err)
+	}
+	defer rows.Close()
+
+	var earners []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan interest earner: %w", err)
+		}
+		earners = append(earners, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read interest earners: %w", err)
+	}
+	return earners, nil
+}
+
+// post works out and posts one member's interest for the period [start,
+// end). It returns nil if the period was already posted or earned nothing.
+func (r *InterestRepo) post(ctx context.Context, tx pgx.Tx, rate *models.InterestRate, userID uuid.UUID, start, end time.Time) (*models.LedgerEntry, error) {
+	var posted bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM interest_postings WHERE rate_id = $1 AND user_id = $2 AND period_start = $3)
+	`, rate.ID, userID, start).Scan(&posted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check interest posting: %w", err)
+	}
+	if posted {
+		return nil, nil
+	}
+
+	changes, err := interestChanges(ctx, tx, rate, userID, end)
+	if err != nil {
+		return nil, err
+	}
+	breakdown := interest.Calculate(start, end, interest.DailyBalances(changes, start, end), rate.APR, rate.MinBalance)
+	if breakdown.Amount <= 0 {
+		return nil, nil
+	}
+
+	entry := &models.LedgerEntry{
+		ID:              uuid.New(),
+		GroupID:         rate.GroupID,
+		UserID:          userID,
+		Kind:            models.EntryInterest,
+		InterestRateID:  &rate.ID,
+		Amount:          breakdown.Amount,
+		Status:          models.StatusApproved,
+		CreatedByUserID: rate.CreatedByUserID,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ledger_entries (id, group_id, user_id, kind, interest_rate_id, amount, status, created_by_user_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING amount, occurred_at, created_at
+	`, entry.ID, entry.GroupID, entry.UserID, entry.Kind, entry.InterestRateID, entry.Amount, entry.Status, entry.CreatedByUserID, end,
+	).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post interest: %w", err)
+	}
+
+	// A jar's interest stays in the jar; the group's is split like any other entry
+	if rate.JarID != nil {
+		_, err := tx.Exec(ctx, `INSERT INTO jar_splits (entry_id, jar_id, amount) VALUES ($1, $2, $3)`, entry.ID, *rate.JarID, entry.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put interest into jar: %w", err)
+		}
+	} else if err := splitIntoJars(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(breakdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode interest breakdown: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO interest_postings (rate_id, entry_id, group_id, user_id, jar_id, period_start, amount, breakdown)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, rate.ID, entry.ID, entry.GroupID, userID, rate.JarID, start, entry.Amount, string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record interest posting: %w", err)
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+	if err != nil {
+		return nil, err
+	}
+	entry.Hash = &hash
+	return entry, nil
+}
+
+// interestChanges returns what moved the balance a rate pays a member on
+// before end: the jar's for a jar rate, otherwise the member's balance
+// less their jars that have a rate of their own
+func interestChanges(ctx context.Context, tx pgx.Tx, rate *models.InterestRate, userID uuid.UUID, end time.Time) ([]interest.Change, error) {
+	var changes []interest.Change
+	if rate.JarID == nil {
+		var err error
+		changes, err = collectChanges(tx.Query(ctx, `
+			SELECT day, SUM(amount) FROM (`+memberChanges+`) c
+			GROUP BY day ORDER BY day
+		`, rate.GroupID, userID, end, end))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jarIDs := []uuid.UUID{}
+	if rate.JarID != nil {
+		jarIDs = append(jarIDs, *rate.JarID)
+	} else {
+		rows, err := tx.Query(ctx, `
+			SELECT j.id
+			FROM jars j
+			INNER JOIN interest_rates ir ON ir.jar_id = j.id AND ir.deleted_at IS NULL
+			WHERE j.group_id = $1 AND j.user_id = $2
+		`, rate.GroupID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get jars with interest: %w", err)
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan jar: %w", err)
+			}
+			jarIDs = append(jarIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read jars with interest: %w", err)
+		}
+	}
+	if len(jarIDs) == 0 {
+		return changes, nil
+	}
+
+	jarMoves, err := collectChanges(tx.Query(ctx, `
+		SELECT day, SUM(amount) FROM (`+jarChanges+`) c
+		GROUP BY day ORDER BY day
+	`, jarIDs, end, end))
+	if err != nil {
+		return nil, err
+	}
+	if rate.JarID != nil {
+		return jarMoves, nil
+	}
+	for _, c := range jarMoves {
+		changes = append(changes, interest.Change{Day: c.Day, Amount: -c.Amount})
+	}
+	return changes, nil
+}
+
+// collectChanges scans and closes rows of days and amounts
+func collectChanges(rows pgx.Rows, err error) ([]interest.Change, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []interest.Change
+	for rows.Next() {
+		var c interest.Change
+		if err := rows.Scan(&c.Day, &c.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan balance change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balance changes: %w", err)
+	}
+	return changes, nil
+}
+
+// deleteJarRates stops the interest rates of jars that are being removed
+func deleteJarRates(ctx context.Context, tx pgx.Tx, jarID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE interest_rates SET deleted_at = now(), updated_at = now()
+		WHERE jar_id = $1 AND deleted_at IS NULL
+	`, jarID)
+	if err != nil {
+		return fmt.Errorf("failed to delete jar interest rates: %w", err)
+	}
+	return nil
+}
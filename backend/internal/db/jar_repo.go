@@ -0,0 +1,404 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/jars"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrUnknownJar is returned for a jar that is not one of the member's current jars
+var ErrUnknownJar = errors.New("jar is not a jar of this member")
+
+// ErrDuplicateJar is returned when a member would have two jars with the same name
+var ErrDuplicateJar = errors.New("jar already exists")
+
+// ErrJarNotEmpty is returned when removing a jar that still holds money
+var ErrJarNotEmpty = errors.New("jar still holds money; move it to another jar first")
+
+// JarBalanceError is returned when taking more out of a jar than it holds
+type JarBalanceError struct {
+	Jar     string
+	Balance float64
+}
+
+func (e *JarBalanceError) Error() string {
+	return fmt.Sprintf("amount exceeds the %s jar's balance of %.2f", e.Jar, e.Balance)
+}
+
+// JarRepo handles database operations for members' jars and the transfers between them
+type JarRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewJarRepo creates a new JarRepo
+func NewJarRepo(pool *pgxpool.Pool) *JarRepo {
+	return &JarRepo{pool: pool}
+}
+
+const jarColumns = `j.id, j.group_id, j.user_id, j.name, j.split_percent, j.position, j.created_at, j.updated_at, j.deleted_at`
+
+// jarBalance selects, after jarColumns, a jar's balance from its splits of
+// approved entries that occurred before $2, transfers made before $2 and
+// settlements dated on or before $3 that were not voided, leaving out the
+// settlement $4. A NULL $2 or $3 counts everything.
+const jarBalance = `,
+	(SELECT COALESCE(SUM(js.amount), 0) FROM jar_splits js
+	 INNER JOIN ledger_entries le ON le.id = js.entry_id
+	 WHERE js.jar_id = j.id AND le.status = 'approved'
+	   AND ($2::timestamptz IS NULL OR le.occurred_at < $2))
+	+
+	(SELECT COALESCE(SUM(amount), 0) FROM jar_transfers
+	 WHERE to_jar_id = j.id AND ($2::timestamptz IS NULL OR created_at < $2))
+	-
+	(SELECT COALESCE(SUM(amount), 0) FROM jar_transfers
+	 WHERE from_jar_id = j.id AND ($2::timestamptz IS NULL OR created_at < $2))
+	-
+	(SELECT COALESCE(SUM(amount), 0) FROM settlements s
+	 WHERE s.jar_id = j.id AND s.voided_at IS NULL
+	   AND ($3::date IS NULL OR s.date <= $3)
+	   AND ($4::uuid IS NULL OR s.id <> $4))`
+
+const jarTransferColumns = `id, group_id, user_id, from_jar_id, to_jar_id, amount, note, created_by_user_id, created_at`
+
+// jarFields returns the scan destinations for jarColumns and jarBalance
+func jarFields(j *models.Jar) []any {
+	return []any{&j.ID, &j.GroupID, &j.UserID, &j.Name, &j.SplitPercent, &j.Position, &j.CreatedAt, &j.UpdatedAt, &j.DeletedAt, &j.Balance}
+}
+
+func scanJarTransfer(row pgx.Row) (*models.JarTransfer, error) {
+	t := &models.JarTransfer{}
+	err := row.Scan(&t.ID, &t.GroupID, &t.UserID, &t.FromJarID, &t.ToJarID, &t.Amount, &t.Note, &t.CreatedByUserID, &t.CreatedAt)
+	return t, err
+}
+
+// collectJars scans and closes rows selected with jarColumns and jarBalance
+func collectJars(rows pgx.Rows) ([]*models.Jar, error) {
+	defer rows.Close()
+
+	var list []*models.Jar
+	for rows.Next() {
+		j := &models.Jar{}
+		if err := rows.Scan(jarFields(j)...); err != nil {
+			return nil, fmt.Errorf("failed to scan jar: %w", err)
+		}
+		j.Balance = roundCents(j.Balance)
+		list = append(list, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jars: %w", err)
+	}
+	return list, nil
+}
+
+// ListForMember retrieves a member's current jars in order, each with its
+// balance from entries that occurred before asOf and settlements dated
+// before it
+func (r *JarRepo) ListForMember(ctx context.Context, groupID, userID uuid.UUID, asOf time.Time) ([]*models.Jar, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+jarColumns+jarBalance+`
+		FROM jars j
+		WHERE j.group_id = $1 AND j.user_id = $5 AND j.deleted_at IS NULL
+		ORDER BY j.position, j.created_at, j.id
+	`, groupID, asOf, settlementCutoff(asOf), nil, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jars: %w", err)
+	}
+	return collectJars(rows)
+}
+
+// listJarBalances retrieves the balances of the jars the group's members
+// had at asOf, keyed by member. A nil asOf counts everything.
+func listJarBalances(ctx context.Context, pool *pgxpool.Pool, groupID uuid.UUID, asOf, cutoff *time.Time) (map[uuid.UUID][]*models.JarBalance, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT `+jarColumns+jarBalance+`
+		FROM jars j
+		WHERE j.group_id = $1
+		  AND ($2::timestamptz IS NULL OR j.created_at < $2)
+		  AND (j.deleted_at IS NULL OR ($2::timestamptz IS NOT NULL AND j.deleted_at >= $2))
+		ORDER BY j.position, j.created_at, j.id
+	`, groupID, asOf, cutoff, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jar balances: %w", err)
+	}
+	list, err := collectJars(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uuid.UUID][]*models.JarBalance)
+	for _, j := range list {
+		balances[j.UserID] = append(balances[j.UserID], &models.JarBalance{
+			JarID:        j.ID,
+			Name:         j.Name,
+			SplitPercent: j.SplitPercent,
+			Balance:      j.Balance,
+		})
+	}
+	return balances, nil
+}
+
+// JarSpec describes one of a member's jars; a nil ID adds a new jar
+type JarSpec struct {
+	ID           *uuid.UUID
+	Name         string
+	SplitPercent float64
+}
+
+// Replace sets a member's jars to specs, in order: named jars are renamed
+// and resplit, the rest are added, and current jars left out are removed.
+// A jar that still holds money cannot be removed, and a removed jar's
+// interest rate stops. The new split only applies to entries approved from
+// now on.
+func (r *JarRepo) Replace(ctx context.Context, groupID, userID uuid.UUID, specs []JarSpec) ([]*models.Jar, error) {
+	percents := make([]float64, len(specs))
+	for i, spec := range specs {
+		percents[i] = spec.SplitPercent
+	}
+	if err := jars.ValidateSplit(percents); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Jar balances change with settlements and transfers, so hold them still
+	if err := lockMemberSettlements(ctx, tx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+jarColumns+jarBalance+`
+		FROM jars j
+		WHERE j.group_id = $1 AND j.user_id = $5 AND j.deleted_at IS NULL
+		ORDER BY j.id
+		FOR UPDATE
+	`, groupID, nil, nil, nil, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock jars: %w", err)
+	}
+	current, err := collectJars(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[uuid.UUID]bool, len(specs))
+	for _, spec := range specs {
+		if spec.ID != nil {
+			kept[*spec.ID] = true
+		}
+	}
+	existing := make(map[uuid.UUID]bool, len(current))
+	for _, j := range current {
+		existing[j.ID] = true
+		if kept[j.ID] {
+			continue
+		}
+		if j.Balance != 0 {
+			return nil, ErrJarNotEmpty
+		}
+		if _, err := tx.Exec(ctx, `UPDATE jars SET deleted_at = now(), updated_at = now() WHERE id = $1`, j.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete jar: %w", err)
+		}
+		if err := deleteJarRates(ctx, tx, j.ID); err != nil {
+			return nil, err
+		}
+	}
+	for id := range kept {
+		if !existing[id] {
+			return nil, ErrUnknownJar
+		}
+	}
+
+	// Park the kept jars under their IDs first so names can swap between them
+	if _, err := tx.Exec(ctx, `
+		UPDATE jars SET name = id::text
+		WHERE group_id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`, groupID, userID); err != nil {
+		return nil, fmt.Errorf("failed to update jars: %w", err)
+	}
+
+	for i, spec := range specs {
+		if spec.ID != nil {
+			_, err = tx.Exec(ctx, `
+				UPDATE jars SET name = $2, split_percent = $3, position = $4, updated_at = now()
+				WHERE id = $1
+			`, *spec.ID, spec.Name, spec.SplitPercent, i)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO jars (group_id, user_id, name, split_percent, position)
+				VALUES ($1, $2, $3, $4, $5)
+			`, groupID, userID, spec.Name, spec.SplitPercent, i)
+		}
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				return nil, ErrDuplicateJar
+			}
+			return nil, fmt.Errorf("failed to save jar: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.ListForMember(ctx, groupID, userID, time.Now())
+}
+
+// ListTransfers retrieves the transfers between a member's jars, oldest first
+func (r *JarRepo) ListTransfers(ctx context.Context, groupID, userID uuid.UUID) ([]*models.JarTransfer, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+jarTransferColumns+`
+		FROM jar_transfers
+		WHERE group_id = $1 AND user_id = $2
+		ORDER BY created_at, id
+	`, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jar transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []*models.JarTransfer
+	for rows.Next() {
+		t, err := scanJarTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan jar transfer: %w", err)
+		}
+		transfers = append(transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jar transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// Transfer moves money between two of a member's current jars. The source
+// jar cannot be taken below zero.
+func (r *JarRepo) Transfer(ctx context.Context, t *models.JarTransfer) (*models.JarTransfer, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockMemberSettlements(ctx, tx, t.GroupID, t.UserID); err != nil {
+		return nil, err
+	}
+	if err := checkJar(ctx, tx, t.GroupID, t.UserID, t.ToJarID, nil, 0, true); err != nil {
+		return nil, err
+	}
+	if err := checkJar(ctx, tx, t.GroupID, t.UserID, t.FromJarID, nil, t.Amount, false); err != nil {
+		return nil, err
+	}
+
+	created, err := scanJarTransfer(tx.QueryRow(ctx, `
+		INSERT INTO jar_transfers (group_id, user_id, from_jar_id, to_jar_id, amount, note, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+jarTransferColumns,
+		t.GroupID, t.UserID, t.FromJarID, t.ToJarID, t.Amount, t.Note, t.CreatedByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jar transfer: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// checkJar returns ErrUnknownJar unless id is one of the member's current
+// jars, and a *JarBalanceError if taking amount out of it would leave it
+// below zero, unless allowNegative is set. The settlement being edited, if
+// any, is left out of the balance. The caller holds the member's settlement lock.
+func checkJar(ctx context.Context, tx pgx.Tx, groupID, userID, id uuid.UUID, excludeID *uuid.UUID, amount float64, allowNegative bool) error {
+	j := &models.Jar{}
+	err := tx.QueryRow(ctx, `
+		SELECT `+jarColumns+jarBalance+`
+		FROM jars j
+		WHERE j.id = $1 AND j.group_id = $5 AND j.user_id = $6 AND j.deleted_at IS NULL
+	`, id, nil, nil, excludeID, groupID, userID).Scan(jarFields(j)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUnknownJar
+		}
+		return fmt.Errorf("failed to check jar: %w", err)
+	}
+
+	if !allowNegative && roundCents(amount) > roundCents(j.Balance) {
+		return &JarBalanceError{Jar: j.Name, Balance: roundCents(j.Balance)}
+	}
+	return nil
+}
+
+// splitIntoJars divides an approved entry, less any loan repayments
+// withheld from it, between its member's current jars by their split
+// percentages. Members without jars keep the whole entry unallocated, as
+// do advances and entries that are not positive.
+func splitIntoJars(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error {
+	if entry.Status != models.StatusApproved || entry.Amount <= 0 || entry.Kind == models.EntryLoan {
+		return nil
+	}
+
+	var withheld float64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE withheld_from_id = $1
+	`, entry.ID).Scan(&withheld)
+	if err != nil {
+		return fmt.Errorf("failed to get withheld repayments: %w", err)
+	}
+	amount := roundCents(entry.Amount + withheld)
+	if amount <= 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, split_percent
+		FROM jars
+		WHERE group_id = $1 AND user_id = $2 AND deleted_at IS NULL
+		ORDER BY position, created_at, id
+	`, entry.GroupID, entry.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get jars: %w", err)
+	}
+	var ids []uuid.UUID
+	var percents []float64
+	for rows.Next() {
+		var id uuid.UUID
+		var percent float64
+		if err := rows.Scan(&id, &percent); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan jar: %w", err)
+		}
+		ids = append(ids, id)
+		percents = append(percents, percent)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read jars: %w", err)
+	}
+
+	for i, part := range jars.Split(amount, percents) {
+		if part <= 0 {
+			continue
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO jar_splits (entry_id, jar_id, amount)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (entry_id, jar_id) DO NOTHING
+		`, entry.ID, ids[i], part)
+		if err != nil {
+			return fmt.Errorf("failed to split entry into jars: %w", err)
+		}
+	}
+	return nil
+}
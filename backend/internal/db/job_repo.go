@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JobRepo coordinates scheduled jobs between server instances
+type JobRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewJobRepo creates a new JobRepo
+func NewJobRepo(pool *pgxpool.Pool) *JobRepo {
+	return &JobRepo{pool: pool}
+}
+
+// jobLockKey maps a job name to its advisory lock key
+func jobLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("pocket-money job:" + name))
+	return int64(h.Sum64())
+}
+
+// Claim takes the job's advisory lock and records tick as its latest run.
+// It returns ok=false if another instance holds the lock or has already run
+// this tick. The lock is held on a dedicated connection until release is
+// called with the job's result.
+func (r *JobRepo) Claim(ctx context.Context, name string, tick time.Time) (func(error), bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	key := jobLockKey(name)
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("failed to take job lock: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			// Closing the connection drops the lock with the session
+			log.Printf("jobs: failed to release lock for %s: %v", name, err)
+			_ = conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+
+	tag, err := conn.Exec(ctx, `
+		INSERT INTO job_runs (name, last_tick, started_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (name) DO UPDATE
+		SET last_tick = EXCLUDED.last_tick, started_at = now(), finished_at = NULL, last_error = NULL
+		WHERE job_runs.last_tick < EXCLUDED.last_tick
+	`, name, tick)
+	if err != nil {
+		unlock()
+		return nil, false, fmt.Errorf("failed to record job run: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		unlock()
+		return nil, false, nil
+	}
+
+	release := func(jobErr error) {
+		var lastError *string
+		if jobErr != nil {
+			msg := jobErr.Error()
+			lastError = &msg
+		}
+		_, err := conn.Exec(context.Background(), `
+			UPDATE job_runs SET finished_at = now(), last_error = $2 WHERE name = $1
+		`, name, lastError)
+		if err != nil {
+			log.Printf("jobs: failed to record result for %s: %v", name, err)
+		}
+		unlock()
+	}
+	return release, true, nil
+}
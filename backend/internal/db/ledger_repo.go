@@ -4,75 +4,130 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/srjn45/pocket-money/backend/internal/chain"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
+// ledgerEntryColumns is the column list scanned by scanLedgerEntry
+const ledgerEntryColumns = `id, group_id, user_id, kind, chore_id, allowance_id, allowance_period, interest_rate_id, loan_id, withheld_from_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, status_reason, system_actor, approval_rule_id, resubmitted_from_id, occurred_at, created_at, hash`
+
 // LedgerRepo handles database operations for ledger entries
 type LedgerRepo struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	chain *ChainRepo
 }
 
 // NewLedgerRepo creates a new LedgerRepo
-func NewLedgerRepo(pool *pgxpool.Pool) *LedgerRepo {
-	return &LedgerRepo{pool: pool}
+func NewLedgerRepo(pool *pgxpool.Pool, chainRepo *ChainRepo) *LedgerRepo {
+	return &LedgerRepo{pool: pool, chain: chainRepo}
 }
 
-// Create inserts a new ledger entry
-func (r *LedgerRepo) Create(ctx context.Context, groupID, userID, choreID, createdByUserID uuid.UUID, amount float64, status models.LedgerStatus, approvedByUserID *uuid.UUID) (*models.LedgerEntry, error) {
-	entry := &models.LedgerEntry{
+// Create inserts a new ledger entry and appends it to the group's chain.
+// A nil occurredAt records the entry as done at creation time. A pending
+// entry that matches one of the group's approval rules is approved by it.
+func (r *LedgerRepo) Create(ctx context.Context, groupID, userID, choreID, createdByUserID uuid.UUID, amount float64, status models.LedgerStatus, approvedByUserID *uuid.UUID, occurredAt *time.Time) (*models.LedgerEntry, error) {
+	return r.create(ctx, &models.LedgerEntry{
 		ID:               uuid.New(),
 		GroupID:          groupID,
 		UserID:           userID,
-		ChoreID:          choreID,
+		Kind:             models.EntryChore,
+		ChoreID:          &choreID,
 		Amount:           amount,
 		Status:           status,
 		CreatedByUserID:  createdByUserID,
 		ApprovedByUserID: approvedByUserID,
+	}, occurredAt)
+}
+
+// ErrAlreadyResubmitted is returned when a rejected entry was already resubmitted
+var ErrAlreadyResubmitted = errors.New("entry was already resubmitted")
+
+// Resubmit creates a new pending entry for the same member and chore as a
+// rejected one, linked to it. Each entry can be resubmitted once.
+func (r *LedgerRepo) Resubmit(ctx context.Context, original *models.LedgerEntry, createdByUserID uuid.UUID, amount float64, occurredAt *time.Time) (*models.LedgerEntry, error) {
+	entry, err := r.create(ctx, &models.LedgerEntry{
+		ID:                uuid.New(),
+		GroupID:           original.GroupID,
+		UserID:            original.UserID,
+		Kind:              original.Kind,
+		ChoreID:           original.ChoreID,
+		Amount:            amount,
+		Status:            models.StatusPendingApproval,
+		CreatedByUserID:   createdByUserID,
+		ResubmittedFromID: &original.ID,
+	}, occurredAt)
+	if isDuplicateKeyError(err) {
+		return nil, ErrAlreadyResubmitted
+	}
+	return entry, err
+}
+
+// create inserts entry, approving it by rule when one matches, and appends it to the chain
+func (r *LedgerRepo) create(ctx context.Context, entry *models.LedgerEntry, occurredAt *time.Time) (*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if entry.Status == models.StatusPendingApproval && entry.ChoreID != nil {
+		ruleID, err := matchApprovalRule(ctx, tx, entry.GroupID, entry.UserID, *entry.ChoreID, entry.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if ruleID != nil {
+			entry.Status = models.StatusApproved
+			entry.ApprovalRuleID = ruleID
+		}
 	}
 
 	query := `
-		INSERT INTO ledger_entries (id, group_id, user_id, chore_id, amount, status, created_by_user_id, approved_by_user_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING created_at
+		INSERT INTO ledger_entries (id, group_id, user_id, kind, chore_id, allowance_id, allowance_period, amount, status, created_by_user_id, approved_by_user_id, approval_rule_id, resubmitted_from_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14, now()))
+		RETURNING amount, occurred_at, created_at
 	`
 
-	err := r.pool.QueryRow(ctx, query,
-		entry.ID, groupID, userID, choreID, amount, status, createdByUserID, approvedByUserID,
-	).Scan(&entry.CreatedAt)
+	// Read the stored amount back so the chained payload matches the rounded column
+	err = tx.QueryRow(ctx, query,
+		entry.ID, entry.GroupID, entry.UserID, entry.Kind, entry.ChoreID, entry.AllowanceID, entry.AllowancePeriod, entry.Amount, entry.Status, entry.CreatedByUserID,
+		entry.ApprovedByUserID, entry.ApprovalRuleID, entry.ResubmittedFromID, occurredAt,
+	).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ledger entry: %w", err)
 	}
 
+	hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+	if err != nil {
+		return nil, err
+	}
+	entry.Hash = &hash
+	if err := creditApproved(ctx, tx, r.chain, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return entry, nil
 }
 
 // GetByID retrieves a ledger entry by ID
 func (r *LedgerRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.LedgerEntry, error) {
-	entry := &models.LedgerEntry{}
-
 	query := `
-		SELECT id, group_id, user_id, chore_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, created_at
+		SELECT ` + ledgerEntryColumns + `
 		FROM ledger_entries
 		WHERE id = $1
 	`
 
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&entry.ID,
-		&entry.GroupID,
-		&entry.UserID,
-		&entry.ChoreID,
-		&entry.Amount,
-		&entry.Status,
-		&entry.CreatedByUserID,
-		&entry.ApprovedByUserID,
-		&entry.RejectedByUserID,
-		&entry.CreatedAt,
-	)
+	entry, err := scanLedgerEntry(r.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -83,104 +138,424 @@ func (r *LedgerRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.LedgerE
 	return entry, nil
 }
 
-// ListForGroup retrieves all ledger entries for a group with optional status filter
-func (r *LedgerRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, status *models.LedgerStatus) ([]*models.LedgerEntry, error) {
-	var query string
-	var args []interface{}
+// LedgerFilter narrows a ledger listing; nil fields are ignored
+type LedgerFilter struct {
+	Status          *models.LedgerStatus
+	Kind            *models.EntryKind
+	UserID          *uuid.UUID
+	ChoreID         *uuid.UUID
+	CreatedByUserID *uuid.UUID
+	From            *time.Time // Inclusive lower bound on occurred_at
+	To              *time.Time // Exclusive upper bound on occurred_at
+	MinAmount       *float64
+	MaxAmount       *float64
+}
+
+// ledgerFilterWhere builds the conditions selecting a group's entries that match filter
+func ledgerFilterWhere(groupID uuid.UUID, filter LedgerFilter) whereBuilder {
+	var w whereBuilder
+	w.add("group_id = %s", groupID)
+	if filter.Status != nil {
+		w.add("status = %s", *filter.Status)
+	}
+	if filter.Kind != nil {
+		w.add("kind = %s", *filter.Kind)
+	}
+	if filter.UserID != nil {
+		w.add("user_id = %s", *filter.UserID)
+	}
+	if filter.ChoreID != nil {
+		w.add("chore_id = %s", *filter.ChoreID)
+	}
+	if filter.CreatedByUserID != nil {
+		w.add("created_by_user_id = %s", *filter.CreatedByUserID)
+	}
+	if filter.From != nil {
+		w.add("occurred_at >= %s", *filter.From)
+	}
+	if filter.To != nil {
+		w.add("occurred_at < %s", *filter.To)
+	}
+	if filter.MinAmount != nil {
+		w.add("amount >= %s", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		w.add("amount <= %s", *filter.MaxAmount)
+	}
+	return w
+}
+
+// ledgerSortColumns lists the fields ledger entries can be sorted by
+var ledgerSortColumns = map[string]sortColumn{
+	"created_at":  {column: "created_at", cast: "timestamptz"},
+	"occurred_at": {column: "occurred_at", cast: "timestamptz"},
+	"amount":      {column: "amount", cast: "numeric"},
+}
 
-	if status != nil {
-		query = `
-			SELECT id, group_id, user_id, chore_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, created_at
-			FROM ledger_entries
-			WHERE group_id = $1 AND status = $2
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{groupID, *status}
-	} else {
-		query = `
-			SELECT id, group_id, user_id, chore_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, created_at
-			FROM ledger_entries
-			WHERE group_id = $1
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{groupID}
+// ListForGroup retrieves a page of ledger entries for a group matching the filter
+func (r *LedgerRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, filter LedgerFilter, page Page) ([]*models.LedgerEntry, *PageInfo, error) {
+	w := ledgerFilterWhere(groupID, filter)
+
+	info := &PageInfo{}
+	countQuery := `SELECT COUNT(*) FROM ledger_entries WHERE ` + w.sql()
+	if err := r.pool.QueryRow(ctx, countQuery, w.args...).Scan(&info.Total); err != nil {
+		return nil, nil, fmt.Errorf("failed to count ledger entries: %w", err)
 	}
 
-	rows, err := r.pool.Query(ctx, query, args...)
+	keyed, suffix, err := keysetQuery(w, page, ledgerSortColumns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+		return nil, nil, err
 	}
-	defer rows.Close()
 
-	var entries []*models.LedgerEntry
-	for rows.Next() {
-		entry := &models.LedgerEntry{}
-		if err := rows.Scan(
-			&entry.ID,
-			&entry.GroupID,
-			&entry.UserID,
-			&entry.ChoreID,
-			&entry.Amount,
-			&entry.Status,
-			&entry.CreatedByUserID,
-			&entry.ApprovedByUserID,
-			&entry.RejectedByUserID,
-			&entry.CreatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
-		}
-		entries = append(entries, entry)
+	query := `
+		SELECT ` + ledgerEntryColumns + `
+		FROM ledger_entries
+		WHERE ` + keyed.sql() + `
+		` + suffix
+
+	rows, err := r.pool.Query(ctx, query, keyed.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list ledger entries: %w", err)
 	}
 
-	return entries, nil
+	entries, err := collectLedgerEntries(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit := pageLimit(page); limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		info.NextCursor = nextCursor(page.Sort, ledgerSortValue(last, page.Sort.Field), last.ID)
+	}
+
+	return entries, info, nil
 }
 
-// UpdateStatus updates the status of a ledger entry
-func (r *LedgerRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.LedgerStatus, approvedByUserID, rejectedByUserID *uuid.UUID) (*models.LedgerEntry, error) {
+// ListApprovedForMember retrieves a member's approved entries that occurred in [from, to), oldest first
+func (r *LedgerRepo) ListApprovedForMember(ctx context.Context, groupID, userID uuid.UUID, from, to time.Time) ([]*models.LedgerEntry, error) {
 	query := `
-		UPDATE ledger_entries
-		SET status = $2, approved_by_user_id = $3, rejected_by_user_id = $4
-		WHERE id = $1
-		RETURNING id, group_id, user_id, chore_id, amount, status, created_by_user_id, approved_by_user_id, rejected_by_user_id, created_at
+		SELECT ` + ledgerEntryColumns + `
+		FROM ledger_entries
+		WHERE group_id = $1 AND user_id = $2 AND status = 'approved'
+		  AND occurred_at >= $3 AND occurred_at < $4
+		ORDER BY occurred_at, id
 	`
 
-	entry := &models.LedgerEntry{}
-	err := r.pool.QueryRow(ctx, query, id, status, approvedByUserID, rejectedByUserID).Scan(
-		&entry.ID,
-		&entry.GroupID,
-		&entry.UserID,
-		&entry.ChoreID,
-		&entry.Amount,
-		&entry.Status,
-		&entry.CreatedByUserID,
-		&entry.ApprovedByUserID,
-		&entry.RejectedByUserID,
-		&entry.CreatedAt,
-	)
+	rows, err := r.pool.Query(ctx, query, groupID, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list member ledger entries: %w", err)
+	}
+
+	return collectLedgerEntries(rows)
+}
+
+// UpdateStatus updates the status of a ledger entry, with an optional reason,
+// and appends the new state to the chain
+func (r *LedgerRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status models.LedgerStatus, approvedByUserID, rejectedByUserID *uuid.UUID, reason *string) (*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	entry, err := r.updateStatus(ctx, tx, id, status, approvedByUserID, rejectedByUserID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return entry, nil
+}
+
+// ErrProofRequired is returned when approving an entry whose chore needs a photo and has none
+var ErrProofRequired = errors.New("a photo is required before this entry can be approved")
+
+// updateStatus sets a pending entry's status within tx and appends the new
+// state to the chain. The entry is locked first so two concurrent decisions
+// cannot both apply; the second gets ErrNotPending. Entries for chores that
+// require proof are only approved once they have an attachment.
+func (r *LedgerRepo) updateStatus(ctx context.Context, tx pgx.Tx, id uuid.UUID, status models.LedgerStatus, approvedByUserID, rejectedByUserID *uuid.UUID, reason *string) (*models.LedgerEntry, error) {
+	var current models.LedgerStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM ledger_entries WHERE id = $1 FOR UPDATE`, id).Scan(&current)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
 		}
+		return nil, fmt.Errorf("failed to lock ledger entry: %w", err)
+	}
+	if current != models.StatusPendingApproval {
+		return nil, ErrNotPending
+	}
+
+	if status == models.StatusApproved {
+		var missing bool
+		err := tx.QueryRow(ctx, `
+			SELECT c.proof_required AND NOT EXISTS (SELECT 1 FROM ledger_attachments a WHERE a.entry_id = le.id)
+			FROM ledger_entries le
+			INNER JOIN chores c ON c.id = le.chore_id
+			WHERE le.id = $1
+		`, id).Scan(&missing)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to check proof: %w", err)
+		}
+		if missing {
+			return nil, ErrProofRequired
+		}
+	}
+
+	query := `
+		UPDATE ledger_entries
+		SET status = $2, approved_by_user_id = $3, rejected_by_user_id = $4,
+		    status_reason = $5, system_actor = NULL
+		WHERE id = $1 AND status = 'pending_approval'
+		RETURNING ` + ledgerEntryColumns + `
+	`
+
+	entry, err := scanLedgerEntry(tx.QueryRow(ctx, query, id, status, approvedByUserID, rejectedByUserID, reason))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotPending
+		}
 		return nil, fmt.Errorf("failed to update ledger entry status: %w", err)
 	}
 
+	hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+	if err != nil {
+		return nil, err
+	}
+	entry.Hash = &hash
+	if err := creditApproved(ctx, tx, r.chain, entry); err != nil {
+		return nil, err
+	}
+
 	return entry, nil
 }
 
+// ErrNotPending is returned for an entry that is no longer awaiting approval
+var ErrNotPending = errors.New("entry is not pending approval")
+
+// ErrOtherGroup is returned for an entry that belongs to a different group
+var ErrOtherGroup = errors.New("entry belongs to another group")
+
+// BulkResult is the outcome for one entry of a bulk status change: the updated
+// entry, or why it was skipped
+type BulkResult struct {
+	ID    uuid.UUID
+	Entry *models.LedgerEntry
+	Err   error
+}
+
+// UpdateStatusBulk approves or rejects a group's pending entries in one
+// transaction. Entries named in ids that are missing, in another group, no
+// longer pending or missing required proof are reported in their result and
+// the rest still change.
+// Without ids, the group's pending entries matching filter change, oldest
+// first, up to limit. The reason, if any, is recorded on every changed entry.
+func (r *LedgerRepo) UpdateStatusBulk(ctx context.Context, groupID uuid.UUID, ids []uuid.UUID, filter LedgerFilter, limit int, status models.LedgerStatus, headID uuid.UUID, reason *string) ([]BulkResult, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var rows pgx.Rows
+	if ids != nil {
+		rows, err = tx.Query(ctx, `
+			SELECT `+ledgerEntryColumns+`
+			FROM ledger_entries
+			WHERE id = ANY($1)
+			FOR UPDATE
+		`, ids)
+	} else {
+		pending := models.StatusPendingApproval
+		filter.Status = &pending
+		w := ledgerFilterWhere(groupID, filter)
+		rows, err = tx.Query(ctx, `
+			SELECT `+ledgerEntryColumns+`
+			FROM ledger_entries
+			WHERE `+w.sql()+`
+			ORDER BY created_at, id
+			LIMIT `+strconv.Itoa(limit)+`
+			FOR UPDATE
+		`, w.args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to select ledger entries: %w", err)
+	}
+	found, err := collectLedgerEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if ids == nil {
+		for _, e := range found {
+			ids = append(ids, e.ID)
+		}
+	}
+	byID := make(map[uuid.UUID]*models.LedgerEntry, len(found))
+	for _, e := range found {
+		byID[e.ID] = e
+	}
+
+	var approvedBy, rejectedBy *uuid.UUID
+	if status == models.StatusApproved {
+		approvedBy = &headID
+	} else {
+		rejectedBy = &headID
+	}
+
+	results := make([]BulkResult, 0, len(ids))
+	for _, id := range ids {
+		result := BulkResult{ID: id}
+		switch e := byID[id]; {
+		case e == nil:
+			result.Err = ErrNotFound
+		case e.GroupID != groupID:
+			result.Err = ErrOtherGroup
+		case e.Status != models.StatusPendingApproval:
+			result.Err = ErrNotPending
+		default:
+			result.Entry, err = r.updateStatus(ctx, tx, id, status, approvedBy, rejectedBy, reason)
+			if errors.Is(err, ErrProofRequired) {
+				result.Err = err
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			// A repeated ID is reported as already processed
+			e.Status = status
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return results, nil
+}
+
+// ApplyPendingPolicies settles pending entries that have waited past their
+// group's policy. Entries within the auto-approval limit are approved first;
+// anything still pending past the expiry is rejected. The changed entries are
+// returned.
+func (r *LedgerRepo) ApplyPendingPolicies(ctx context.Context, now time.Time) ([]*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	approveQuery := `
+		WITH due AS (
+			SELECT le.id AS entry_id, g.auto_approve_after_hours AS hours
+			FROM ledger_entries le
+			JOIN groups g ON g.id = le.group_id
+			JOIN chores c ON c.id = le.chore_id
+			WHERE le.status = 'pending_approval'
+			  AND g.auto_approve_after_hours IS NOT NULL
+			  AND le.created_at <= $1::timestamptz - make_interval(hours => g.auto_approve_after_hours)
+			  AND le.amount <= g.auto_approve_max_amount
+			  AND (NOT c.proof_required OR EXISTS (SELECT 1 FROM ledger_attachments a WHERE a.entry_id = le.id))
+			FOR UPDATE OF le SKIP LOCKED
+		)
+		UPDATE ledger_entries
+		SET status = 'approved', approved_by_user_id = NULL, rejected_by_user_id = NULL,
+		    system_actor = 'auto_approve',
+		    status_reason = format('Approved automatically: no head acted within %s hours', due.hours)
+		FROM due
+		WHERE id = due.entry_id
+		RETURNING ` + ledgerEntryColumns + `
+	`
+	approved, err := r.applyPolicy(ctx, tx, approveQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to auto-approve ledger entries: %w", err)
+	}
+
+	expireQuery := `
+		WITH due AS (
+			SELECT le.id AS entry_id, g.expire_pending_after_days AS days
+			FROM ledger_entries le
+			JOIN groups g ON g.id = le.group_id
+			WHERE le.status = 'pending_approval'
+			  AND g.expire_pending_after_days IS NOT NULL
+			  AND le.created_at <= $1::timestamptz - make_interval(days => g.expire_pending_after_days)
+			FOR UPDATE OF le SKIP LOCKED
+		)
+		UPDATE ledger_entries
+		SET status = 'rejected', approved_by_user_id = NULL, rejected_by_user_id = NULL,
+		    system_actor = 'auto_expire',
+		    status_reason = format('Rejected automatically: pending for more than %s days', due.days)
+		FROM due
+		WHERE id = due.entry_id
+		RETURNING ` + ledgerEntryColumns + `
+	`
+	expired, err := r.applyPolicy(ctx, tx, expireQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire ledger entries: %w", err)
+	}
+
+	entries := append(approved, expired...)
+	for _, entry := range entries {
+		hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = &hash
+		if err := creditApproved(ctx, tx, r.chain, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return entries, nil
+}
+
+// applyPolicy runs one pending policy update and returns the changed entries
+func (r *LedgerRepo) applyPolicy(ctx context.Context, tx pgx.Tx, query string, now time.Time) ([]*models.LedgerEntry, error) {
+	rows, err := tx.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	return collectLedgerEntries(rows)
+}
+
 // GetBalanceForGroup calculates the balance for each member in a group
-// Balance = sum(approved ledger entries) - sum(settlements)
-func (r *LedgerRepo) GetBalanceForGroup(ctx context.Context, groupID uuid.UUID) ([]*models.Balance, error) {
+// Balance = sum(approved ledger entries) - sum(settlements not voided)
+// Settlements are also totalled by whether the member acknowledged them,
+// members with jars get the balance of each jar and of what is in none, and
+// members with loans get what is still outstanding on each.
+// A non-nil asOf counts only entries that occurred before it and settlements dated before it.
+func (r *LedgerRepo) GetBalanceForGroup(ctx context.Context, groupID uuid.UUID, asOf *time.Time) ([]*models.Balance, error) {
+	var cutoff *time.Time
+	if asOf != nil {
+		c := settlementCutoff(*asOf)
+		cutoff = &c
+	}
+
 	query := `
 		WITH ledger_totals AS (
 			SELECT user_id, COALESCE(SUM(amount), 0) as total
 			FROM ledger_entries
 			WHERE group_id = $1 AND status = 'approved'
+			  AND ($2::timestamptz IS NULL OR occurred_at < $2)
 			GROUP BY user_id
 		),
 		settlement_totals AS (
-			SELECT user_id, COALESCE(SUM(amount), 0) as total
+			SELECT user_id,
+			       COALESCE(SUM(amount) FILTER (WHERE status = 'acknowledged'), 0) as confirmed,
+			       COALESCE(SUM(amount) FILTER (WHERE status <> 'acknowledged'), 0) as unconfirmed
 			FROM settlements
-			WHERE group_id = $1
+			WHERE group_id = $1 AND voided_at IS NULL
+			  AND ($3::date IS NULL OR date <= $3)
 			GROUP BY user_id
 		),
 		all_members AS (
@@ -189,17 +564,19 @@ func (r *LedgerRepo) GetBalanceForGroup(ctx context.Context, groupID uuid.UUID)
 			INNER JOIN users u ON gm.user_id = u.id
 			WHERE gm.group_id = $1
 		)
-		SELECT 
-			am.user_id, 
+		SELECT
+			am.user_id,
 			am.name,
-			COALESCE(lt.total, 0) - COALESCE(st.total, 0) as balance
+			COALESCE(lt.total, 0) - COALESCE(st.confirmed, 0) - COALESCE(st.unconfirmed, 0) as balance,
+			COALESCE(st.confirmed, 0),
+			COALESCE(st.unconfirmed, 0)
 		FROM all_members am
 		LEFT JOIN ledger_totals lt ON am.user_id = lt.user_id
 		LEFT JOIN settlement_totals st ON am.user_id = st.user_id
 		ORDER BY am.name
 	`
 
-	rows, err := r.pool.Query(ctx, query, groupID)
+	rows, err := r.pool.Query(ctx, query, groupID, asOf, cutoff)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get balance: %w", err)
 	}
@@ -208,11 +585,189 @@ func (r *LedgerRepo) GetBalanceForGroup(ctx context.Context, groupID uuid.UUID)
 	var balances []*models.Balance
 	for rows.Next() {
 		balance := &models.Balance{}
-		if err := rows.Scan(&balance.UserID, &balance.Name, &balance.Balance); err != nil {
+		if err := rows.Scan(&balance.UserID, &balance.Name, &balance.Balance, &balance.ConfirmedPaid, &balance.UnconfirmedPaid); err != nil {
 			return nil, fmt.Errorf("failed to scan balance: %w", err)
 		}
 		balances = append(balances, balance)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balances: %w", err)
+	}
+
+	jarBalances, err := listJarBalances(ctx, r.pool, groupID, asOf, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		jars, ok := jarBalances[balance.UserID]
+		if !ok {
+			continue
+		}
+		unallocated := balance.Balance
+		for _, j := range jars {
+			unallocated -= j.Balance
+		}
+		unallocated = roundCents(unallocated)
+		balance.Jars = jars
+		balance.Unallocated = &unallocated
+	}
+
+	loanBalances, err := listLoanBalances(ctx, r.pool, groupID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		balance.Loans = loanBalances[balance.UserID]
+	}
 
 	return balances, nil
 }
+
+// GetMemberBalance calculates a member's balance from entries that occurred
+// before asOf and settlements dated before it that were not voided
+func (r *LedgerRepo) GetMemberBalance(ctx context.Context, groupID, userID uuid.UUID, asOf time.Time) (float64, error) {
+	query := `
+		SELECT
+			(SELECT COALESCE(SUM(amount), 0) FROM ledger_entries
+			 WHERE group_id = $1 AND user_id = $2 AND status = 'approved' AND occurred_at < $3)
+			-
+			(SELECT COALESCE(SUM(amount), 0) FROM settlements
+			 WHERE group_id = $1 AND user_id = $2 AND voided_at IS NULL AND date <= $4)
+	`
+
+	var balance float64
+	if err := r.pool.QueryRow(ctx, query, groupID, userID, asOf, settlementCutoff(asOf)).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to get member balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+// GetBalanceHistory returns a member's opening balance at the start of the
+// first interval and their running balance at the end of each interval in [from, to)
+func (r *LedgerRepo) GetBalanceHistory(ctx context.Context, groupID, userID uuid.UUID, interval BalanceInterval, from, to time.Time) (float64, []*models.BalancePoint, error) {
+	points, err := balancePeriods(interval, from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	start := interval.Truncate(from)
+
+	opening, err := r.GetMemberBalance(ctx, groupID, userID, start)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(points) == 0 {
+		return opening, points, nil
+	}
+
+	earnedQuery := `
+		SELECT date_trunc($3, occurred_at AT TIME ZONE 'UTC') AS period, SUM(amount)
+		FROM ledger_entries
+		WHERE group_id = $1 AND user_id = $2 AND status = 'approved'
+		  AND occurred_at >= $4 AND occurred_at < $5
+		GROUP BY period
+	`
+	earned, err := r.sumByPeriod(ctx, earnedQuery, groupID, userID, string(interval), start, to)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to sum ledger entries by period: %w", err)
+	}
+
+	settledQuery := `
+		SELECT date_trunc($3, date::timestamp) AS period, SUM(amount)
+		FROM settlements
+		WHERE group_id = $1 AND user_id = $2 AND voided_at IS NULL
+		  AND date >= $4 AND date <= $5
+		GROUP BY period
+	`
+	settled, err := r.sumByPeriod(ctx, settledQuery, groupID, userID, string(interval), start, settlementCutoff(to))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to sum settlements by period: %w", err)
+	}
+
+	fillBalances(points, opening, earned, settled)
+	return opening, points, nil
+}
+
+// sumByPeriod runs a (period, total) aggregation and keys the totals by period start
+func (r *LedgerRepo) sumByPeriod(ctx context.Context, query string, args ...interface{}) (map[int64]float64, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int64]float64)
+	for rows.Next() {
+		var period time.Time
+		var total float64
+		if err := rows.Scan(&period, &total); err != nil {
+			return nil, err
+		}
+		totals[period.Unix()] = total
+	}
+	return totals, rows.Err()
+}
+
+// scanLedgerEntry scans a row selected with ledgerEntryColumns
+func scanLedgerEntry(row pgx.Row) (*models.LedgerEntry, error) {
+	entry := &models.LedgerEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.GroupID,
+		&entry.UserID,
+		&entry.Kind,
+		&entry.ChoreID,
+		&entry.AllowanceID,
+		&entry.AllowancePeriod,
+		&entry.InterestRateID,
+		&entry.LoanID,
+		&entry.WithheldFromID,
+		&entry.Amount,
+		&entry.Status,
+		&entry.CreatedByUserID,
+		&entry.ApprovedByUserID,
+		&entry.RejectedByUserID,
+		&entry.StatusReason,
+		&entry.SystemActor,
+		&entry.ApprovalRuleID,
+		&entry.ResubmittedFromID,
+		&entry.OccurredAt,
+		&entry.CreatedAt,
+		&entry.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ledgerSortValue formats an entry's sort field the way keysetQuery compares it
+func ledgerSortValue(e *models.LedgerEntry, field string) string {
+	switch field {
+	case "amount":
+		return strconv.FormatFloat(e.Amount, 'f', 2, 64)
+	case "occurred_at":
+		return e.OccurredAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return e.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// collectLedgerEntries scans and closes rows selected with ledgerEntryColumns
+func collectLedgerEntries(rows pgx.Rows) ([]*models.LedgerEntry, error) {
+	defer rows.Close()
+
+	var entries []*models.LedgerEntry
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded,
+// holds a value its sort field cannot take, or does not belong to the
+// requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidSort is returned when a list is sorted by an unsupported field
+var ErrInvalidSort = errors.New("invalid sort field")
+
+const (
+	// DefaultPageLimit is used by listings that are always paged when the
+	// request does not specify a limit
+	DefaultPageLimit = 50
+	// MaxPageLimit caps the number of rows returned per page
+	MaxPageLimit = 200
+)
+
+// Sort describes the order of a list query
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Page holds keyset pagination parameters for list queries. A page without
+// a limit holds every remaining row, so clients that do not follow the next
+// cursor still see the whole list.
+type Page struct {
+	Limit  int
+	Cursor string
+	Sort   Sort
+}
+
+// PageInfo describes the page returned by a list query
+type PageInfo struct {
+	Total      int
+	NextCursor string
+}
+
+// sortColumn maps a public sort field to its column and the SQL type of its values
+type sortColumn struct {
+	column string
+	cast   string
+}
+
+// cursor is the decoded form of an opaque pagination cursor
+type cursor struct {
+	Field string    `json:"f"`
+	Desc  bool      `json:"d,omitempty"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// nextCursor builds the cursor that continues in the given order after the
+// row with the given sort value and ID
+func nextCursor(sort Sort, value string, id uuid.UUID) string {
+	return encodeCursor(cursor{Field: sort.Field, Desc: sort.Desc, Value: value, ID: id})
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// validCursorValue reports whether a cursor value can be cast to the SQL
+// type of its sort column, so a tampered cursor is rejected before the query
+func validCursorValue(cast, value string) bool {
+	var err error
+	switch cast {
+	case "timestamptz":
+		_, err = time.Parse(time.RFC3339Nano, value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "numeric":
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	return err == nil
+}
+
+// whereBuilder accumulates SQL conditions and their positional arguments
+type whereBuilder struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add appends a condition; each %s in the format is replaced by the next placeholder
+func (w *whereBuilder) add(format string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		w.args = append(w.args, arg)
+		placeholders[i] = fmt.Sprintf("$%d", len(w.args))
+	}
+	w.clauses = append(w.clauses, fmt.Sprintf(format, placeholders...))
+}
+
+func (w *whereBuilder) sql() string {
+	if len(w.clauses) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(w.clauses, " AND ")
+}
+
+// keysetQuery applies the page's sort order, cursor and limit to a filtered query.
+// It returns the ORDER BY/LIMIT suffix and the builder extended with the cursor condition.
+func keysetQuery(w whereBuilder, page Page, columns map[string]sortColumn) (whereBuilder, string, error) {
+	col, ok := columns[page.Sort.Field]
+	if !ok {
+		return w, "", ErrInvalidSort
+	}
+
+	dir, cmp := "ASC", ">"
+	if page.Sort.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	keyed := whereBuilder{
+		clauses: append([]string(nil), w.clauses...),
+		args:    append([]interface{}(nil), w.args...),
+	}
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return w, "", err
+		}
+		if c.Field != page.Sort.Field || c.Desc != page.Sort.Desc || !validCursorValue(col.cast, c.Value) {
+			return w, "", ErrInvalidCursor
+		}
+		keyed.add(fmt.Sprintf("(%s, id) %s (%%s::%s, %%s)", col.column, cmp, col.cast), c.Value, c.ID)
+	}
+
+	suffix := fmt.Sprintf("ORDER BY %s %s, id %s", col.column, dir, dir)
+	if limit := pageLimit(page); limit > 0 {
+		// Fetch one extra row to know whether there is a next page
+		suffix += fmt.Sprintf(" LIMIT %d", limit+1)
+	}
+	return keyed, suffix, nil
+}
+
+// pageLimit returns the effective limit used by keysetQuery, or 0 for no limit
+func pageLimit(page Page) int {
+	if page.Limit <= 0 {
+		return 0
+	}
+	if page.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return page.Limit
+}
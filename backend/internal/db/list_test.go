@@ -0,0 +1,110 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	id := uuid.New()
+	encoded := nextCursor(Sort{Field: "created_at", Desc: true}, "2026-09-01T10:00:00Z", id)
+
+	decoded, err := decodeCursor(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "created_at", decoded.Field)
+	assert.True(t, decoded.Desc)
+	assert.Equal(t, "2026-09-01T10:00:00Z", decoded.Value)
+	assert.Equal(t, id, decoded.ID)
+}
+
+func TestCursor_Garbage(t *testing.T) {
+	_, err := decodeCursor("not a cursor!")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestWhereBuilder_NumbersPlaceholders(t *testing.T) {
+	var w whereBuilder
+	assert.Equal(t, "TRUE", w.sql())
+
+	w.add("group_id = %s", "g")
+	w.add("amount BETWEEN %s AND %s", 1, 2)
+
+	assert.Equal(t, "group_id = $1 AND amount BETWEEN $2 AND $3", w.sql())
+	assert.Equal(t, []interface{}{"g", 1, 2}, w.args)
+}
+
+func TestKeysetQuery_Descending(t *testing.T) {
+	var w whereBuilder
+	w.add("group_id = %s", "g")
+
+	id := uuid.New()
+	page := Page{
+		Limit:  10,
+		Cursor: nextCursor(Sort{Field: "amount", Desc: true}, "5.00", id),
+		Sort:   Sort{Field: "amount", Desc: true},
+	}
+
+	keyed, suffix, err := keysetQuery(w, page, ledgerSortColumns)
+	require.NoError(t, err)
+
+	assert.Equal(t, "group_id = $1 AND (amount, id) < ($2::numeric, $3)", keyed.sql())
+	assert.Equal(t, []interface{}{"g", "5.00", id}, keyed.args)
+	assert.Equal(t, "ORDER BY amount DESC, id DESC LIMIT 11", suffix)
+
+	// The unpaged builder is left untouched for the count query
+	assert.Equal(t, "group_id = $1", w.sql())
+}
+
+func TestKeysetQuery_Errors(t *testing.T) {
+	var w whereBuilder
+
+	_, _, err := keysetQuery(w, Page{Sort: Sort{Field: "password"}}, ledgerSortColumns)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+
+	page := Page{
+		Cursor: nextCursor(Sort{Field: "amount"}, "5.00", uuid.New()),
+		Sort:   Sort{Field: "created_at"},
+	}
+	_, _, err = keysetQuery(w, page, ledgerSortColumns)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// A cursor only continues the direction it was issued for
+	page = Page{
+		Cursor: nextCursor(Sort{Field: "amount"}, "5.00", uuid.New()),
+		Sort:   Sort{Field: "amount", Desc: true},
+	}
+	_, _, err = keysetQuery(w, page, ledgerSortColumns)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Values that the column cast would reject never reach the query
+	for field, value := range map[string]string{"amount": "lots", "created_at": "yesterday"} {
+		page = Page{
+			Cursor: encodeCursor(cursor{Field: field, Value: value, ID: uuid.New()}),
+			Sort:   Sort{Field: field},
+		}
+		_, _, err = keysetQuery(w, page, ledgerSortColumns)
+		assert.ErrorIs(t, err, ErrInvalidCursor, field)
+	}
+	page = Page{
+		Cursor: encodeCursor(cursor{Field: "date", Value: "2026-13-01", ID: uuid.New()}),
+		Sort:   Sort{Field: "date"},
+	}
+	_, _, err = keysetQuery(w, page, settlementSortColumns)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestKeysetQuery_NoLimit(t *testing.T) {
+	var w whereBuilder
+	_, suffix, err := keysetQuery(w, Page{Sort: Sort{Field: "date"}}, settlementSortColumns)
+	require.NoError(t, err)
+	assert.Equal(t, "ORDER BY date ASC, id ASC", suffix)
+}
+
+func TestPageLimit_Bounds(t *testing.T) {
+	assert.Zero(t, pageLimit(Page{}))
+	assert.Equal(t, 20, pageLimit(Page{Limit: 20}))
+	assert.Equal(t, MaxPageLimit, pageLimit(Page{Limit: 10000}))
+}
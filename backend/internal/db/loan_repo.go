@@ -0,0 +1,375 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/loans"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// LoanRepo handles database operations for loans and the advance and
+// repayment entries they post
+type LoanRepo struct {
+	pool  *pgxpool.Pool
+	chain *ChainRepo
+}
+
+// NewLoanRepo creates a new LoanRepo
+func NewLoanRepo(pool *pgxpool.Pool, chainRepo *ChainRepo) *LoanRepo {
+	return &LoanRepo{pool: pool, chain: chainRepo}
+}
+
+// loanColumns selects a loan with what is still outstanding on it: the
+// total due less its approved repayments, which are negative entries
+const loanColumns = `id, group_id, user_id, principal, interest_percent, total_due, repayment_plan, weekly_amount, withhold_percent,
+	start_date, next_repayment, note,
+	total_due + (SELECT COALESCE(SUM(amount), 0) FROM ledger_entries
+	             WHERE loan_id = loans.id AND kind = 'loan_repayment' AND status = 'approved'),
+	repaid_at, created_by_user_id, created_at, updated_at`
+
+func scanLoan(row pgx.Row) (*models.Loan, error) {
+	l := &models.Loan{}
+	err := row.Scan(&l.ID, &l.GroupID, &l.UserID, &l.Principal, &l.InterestPercent, &l.TotalDue, &l.RepaymentPlan, &l.WeeklyAmount, &l.WithholdPercent,
+		&l.StartDate, &l.NextRepayment, &l.Note, &l.Outstanding, &l.RepaidAt, &l.CreatedByUserID, &l.CreatedAt, &l.UpdatedAt)
+	return l, err
+}
+
+// collectLoans scans and closes rows selected with loanColumns
+func collectLoans(rows pgx.Rows) ([]*models.Loan, error) {
+	defer rows.Close()
+
+	var list []*models.Loan
+	for rows.Next() {
+		l, err := scanLoan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan loan: %w", err)
+		}
+		list = append(list, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read loans: %w", err)
+	}
+	return list, nil
+}
+
+// Create inserts a new loan and pays its principal to the member as an
+// approved advance entry, appended to the chain. The advance is left out of
+// the member's jars. Both are returned.
+func (r *LoanRepo) Create(ctx context.Context, l *models.Loan) (*models.Loan, *models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	created, err := scanLoan(tx.QueryRow(ctx, `
+		INSERT INTO loans (group_id, user_id, principal, interest_percent, total_due, repayment_plan, weekly_amount, withhold_percent, start_date, next_repayment, note, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+loanColumns,
+		l.GroupID, l.UserID, l.Principal, l.InterestPercent, loans.TotalDue(l.Principal, l.InterestPercent), l.RepaymentPlan,
+		l.WeeklyAmount, l.WithholdPercent, l.StartDate, l.NextRepayment, l.Note, l.CreatedByUserID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create loan: %w", err)
+	}
+
+	entry := &models.LedgerEntry{
+		ID:               uuid.New(),
+		GroupID:          created.GroupID,
+		UserID:           created.UserID,
+		Kind:             models.EntryLoan,
+		LoanID:           &created.ID,
+		Amount:           created.Principal,
+		Status:           models.StatusApproved,
+		CreatedByUserID:  created.CreatedByUserID,
+		ApprovedByUserID: &created.CreatedByUserID,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ledger_entries (id, group_id, user_id, kind, loan_id, amount, status, created_by_user_id, approved_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING amount, occurred_at, created_at
+	`, entry.ID, entry.GroupID, entry.UserID, entry.Kind, entry.LoanID, entry.Amount, entry.Status, entry.CreatedByUserID, entry.ApprovedByUserID,
+	).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pay advance: %w", err)
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+	if err != nil {
+		return nil, nil, err
+	}
+	entry.Hash = &hash
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, entry, nil
+}
+
+// GetByID retrieves a loan by ID
+func (r *LoanRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	l, err := scanLoan(r.pool.QueryRow(ctx, `SELECT `+loanColumns+` FROM loans WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get loan: %w", err)
+	}
+	return l, nil
+}
+
+// ListForGroup returns a group's loans, optionally only one member's, newest first
+func (r *LoanRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, userID *uuid.UUID) ([]*models.Loan, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+loanColumns+`
+		FROM loans
+		WHERE group_id = $1 AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY created_at DESC, id
+	`, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loans: %w", err)
+	}
+	return collectLoans(rows)
+}
+
+// ListEntries returns a loan's advance and repayment entries in the order
+// they occurred
+func (r *LoanRepo) ListEntries(ctx context.Context, loanID uuid.UUID) ([]*models.LedgerEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+ledgerEntryColumns+`
+		FROM ledger_entries
+		WHERE loan_id = $1
+		ORDER BY occurred_at, created_at, id
+	`, loanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loan entries: %w", err)
+	}
+	return collectLedgerEntries(rows)
+}
+
+// PostDue takes every weekly repayment that has fallen due by now on the
+// loans of current members, catching up on weeks missed while the server
+// was down, and returns the new entries. Each week is taken at most once,
+// so running it again or on several instances is safe.
+func (r *LoanRepo) PostDue(ctx context.Context, now time.Time) ([]*models.LedgerEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+loanColumns+`
+		FROM loans
+		WHERE repayment_plan = 'weekly' AND repaid_at IS NULL AND next_repayment <= $1
+		  AND EXISTS (SELECT 1 FROM group_members gm WHERE gm.group_id = loans.group_id AND gm.user_id = loans.user_id)
+		ORDER BY next_repayment, id
+		FOR UPDATE SKIP LOCKED
+	`, allowance.Day(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select due loans: %w", err)
+	}
+	due, err := collectLoans(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*models.LedgerEntry
+	for _, l := range due {
+		posted, err := r.postDue(ctx, tx, l, now)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, posted...)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entries, nil
+}
+
+// postDue takes a loan's due weekly repayments as approved negative entries,
+// each on its due date, appends them to the chain and moves the loan on to
+// its next repayment, marking it repaid once nothing is outstanding. A week
+// that already has a repayment is skipped.
+func (r *LoanRepo) postDue(ctx context.Context, tx pgx.Tx, l *models.Loan, now time.Time) ([]*models.LedgerEntry, error) {
+	due := loans.Due(l, now)
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	var entries []*models.LedgerEntry
+	outstanding := l.Outstanding
+	for _, repayment := range due {
+		entry := &models.LedgerEntry{
+			ID:              uuid.New(),
+			GroupID:         l.GroupID,
+			UserID:          l.UserID,
+			Kind:            models.EntryRepayment,
+			LoanID:          &l.ID,
+			Amount:          -repayment.Amount,
+			Status:          models.StatusApproved,
+			CreatedByUserID: l.CreatedByUserID,
+		}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO ledger_entries (id, group_id, user_id, kind, loan_id, amount, status, created_by_user_id, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (loan_id, occurred_at) WHERE kind = 'loan_repayment' AND withheld_from_id IS NULL DO NOTHING
+			RETURNING amount, occurred_at, created_at
+		`, entry.ID, entry.GroupID, entry.UserID, entry.Kind, entry.LoanID, entry.Amount, entry.Status, entry.CreatedByUserID, repayment.Date,
+		).Scan(&entry.Amount, &entry.OccurredAt, &entry.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to take loan repayment: %w", err)
+		}
+		outstanding = roundCents(outstanding + entry.Amount)
+
+		hash, err := r.chain.appendLink(ctx, tx, entry.GroupID, chain.RecordLedgerEntry, entry.ID, chain.LedgerEntryPayload(entry))
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = &hash
+		entries = append(entries, entry)
+	}
+
+	next := due[len(due)-1].Date.AddDate(0, 0, 7)
+	_, err := tx.Exec(ctx, `
+		UPDATE loans
+		SET next_repayment = $2, repaid_at = CASE WHEN $3 THEN now() END, updated_at = now()
+		WHERE id = $1
+	`, l.ID, next, outstanding <= 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance loan: %w", err)
+	}
+	return entries, nil
+}
+
+// creditApproved finishes crediting a newly approved chore or allowance
+// entry: it withholds what the member's loans repaid by withholding are
+// owed out of it, oldest loan first, then splits what is left into the
+// member's jars. Call it after the entry itself is chained so each
+// withheld repayment follows the earning it was taken from.
+func creditApproved(ctx context.Context, tx pgx.Tx, chainRepo *ChainRepo, entry *models.LedgerEntry) error {
+	if entry.Status != models.StatusApproved || entry.Amount <= 0 {
+		return nil
+	}
+	if entry.Kind != models.EntryChore && entry.Kind != models.EntryAllowance {
+		return splitIntoJars(ctx, tx, entry)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+loanColumns+`
+		FROM loans
+		WHERE group_id = $1 AND user_id = $2 AND repayment_plan = 'withhold' AND repaid_at IS NULL
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, entry.GroupID, entry.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to select withholding loans: %w", err)
+	}
+	owed, err := collectLoans(rows)
+	if err != nil {
+		return err
+	}
+
+	left := entry.Amount
+	for _, l := range owed {
+		amount := loans.Withheld(entry.Amount, *l.WithholdPercent, left, l.Outstanding)
+		if amount <= 0 {
+			continue
+		}
+
+		repayment := &models.LedgerEntry{
+			ID:              uuid.New(),
+			GroupID:         entry.GroupID,
+			UserID:          entry.UserID,
+			Kind:            models.EntryRepayment,
+			LoanID:          &l.ID,
+			WithheldFromID:  &entry.ID,
+			Amount:          -amount,
+			Status:          models.StatusApproved,
+			CreatedByUserID: l.CreatedByUserID,
+		}
+		// clock_timestamp keeps the repayment after its earning when both
+		// are listed by created_at
+		err := tx.QueryRow(ctx, `
+			INSERT INTO ledger_entries (id, group_id, user_id, kind, loan_id, withheld_from_id, amount, status, created_by_user_id, occurred_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, clock_timestamp())
+			ON CONFLICT (loan_id, withheld_from_id) WHERE withheld_from_id IS NOT NULL DO NOTHING
+			RETURNING amount, occurred_at, created_at
+		`, repayment.ID, repayment.GroupID, repayment.UserID, repayment.Kind, repayment.LoanID, repayment.WithheldFromID,
+			repayment.Amount, repayment.Status, repayment.CreatedByUserID, entry.OccurredAt,
+		).Scan(&repayment.Amount, &repayment.OccurredAt, &repayment.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to withhold loan repayment: %w", err)
+		}
+		left = roundCents(left - amount)
+
+		if roundCents(l.Outstanding-amount) <= 0 {
+			_, err := tx.Exec(ctx, `UPDATE loans SET repaid_at = now(), updated_at = now() WHERE id = $1`, l.ID)
+			if err != nil {
+				return fmt.Errorf("failed to mark loan repaid: %w", err)
+			}
+		}
+
+		hash, err := chainRepo.appendLink(ctx, tx, repayment.GroupID, chain.RecordLedgerEntry, repayment.ID, chain.LedgerEntryPayload(repayment))
+		if err != nil {
+			return err
+		}
+		repayment.Hash = &hash
+	}
+
+	return splitIntoJars(ctx, tx, entry)
+}
+
+// listLoanBalances returns, by member, what is still outstanding on each
+// of a group's loans, oldest first. A non-nil asOf counts only loans made
+// and repayments that occurred before it.
+func listLoanBalances(ctx context.Context, pool *pgxpool.Pool, groupID uuid.UUID, asOf *time.Time) (map[uuid.UUID][]*models.LoanBalance, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT l.id, l.user_id, l.principal, l.total_due,
+		       l.total_due + COALESCE((
+		           SELECT SUM(le.amount) FROM ledger_entries le
+		           WHERE le.loan_id = l.id AND le.kind = 'loan_repayment' AND le.status = 'approved'
+		             AND ($2::timestamptz IS NULL OR le.occurred_at < $2)
+		       ), 0),
+		       l.repayment_plan
+		FROM loans l
+		WHERE l.group_id = $1 AND ($2::timestamptz IS NULL OR l.created_at < $2)
+		ORDER BY l.created_at, l.id
+	`, groupID, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loan balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[uuid.UUID][]*models.LoanBalance)
+	for rows.Next() {
+		var userID uuid.UUID
+		b := &models.LoanBalance{}
+		if err := rows.Scan(&b.LoanID, &userID, &b.Principal, &b.TotalDue, &b.Outstanding, &b.RepaymentPlan); err != nil {
+			return nil, fmt.Errorf("failed to scan loan balance: %w", err)
+		}
+		if b.Outstanding = roundCents(b.Outstanding); b.Outstanding > 0 {
+			balances[userID] = append(balances[userID], b)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read loan balances: %w", err)
+	}
+	return balances, nil
+}
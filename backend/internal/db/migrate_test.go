@@ -41,6 +41,32 @@ func TestMigrations_UpAndDown(t *testing.T) {
 		"ledger_entries",
 		"settlements",
 		"invite_tokens",
+		"ledger_chain",
+		"webhooks",
+		"webhook_deliveries",
+		"group_events",
+		"devices",
+		"notification_settings",
+		"notification_preferences",
+		"job_runs",
+		"ledger_reminders",
+		"chore_reminders",
+		"chore_overdue_reminders",
+		"approval_rules",
+		"ledger_comments",
+		"ledger_attachments",
+		"settlement_revisions",
+		"settlement_entries",
+		"settlement_categories",
+		"allowances",
+		"savings_goals",
+		"goal_allocations",
+		"jars",
+		"jar_splits",
+		"jar_transfers",
+		"interest_rates",
+		"interest_postings",
+		"loans",
 	}
 
 	for _, table := range tables {
@@ -0,0 +1,532 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/mail"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/push"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+)
+
+// NotificationRepo handles database operations for devices and notification settings
+type NotificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewNotificationRepo creates a new NotificationRepo
+func NewNotificationRepo(pool *pgxpool.Pool) *NotificationRepo {
+	return &NotificationRepo{pool: pool}
+}
+
+const deviceColumns = `id, user_id, token, platform, created_at, last_seen_at`
+
+// RegisterDevice stores a push token for a user. Registering a known token
+// moves it to this user, since a phone can change hands between accounts.
+func (r *NotificationRepo) RegisterDevice(ctx context.Context, userID uuid.UUID, token, platform string) (*models.Device, error) {
+	d := &models.Device{}
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO devices (user_id, token, platform)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE
+		SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, last_seen_at = now()
+		RETURNING `+deviceColumns,
+		userID, token, platform,
+	).Scan(&d.ID, &d.UserID, &d.Token, &d.Platform, &d.CreatedAt, &d.LastSeenAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+	return d, nil
+}
+
+// ListDevices returns a user's devices
+func (r *NotificationRepo) ListDevices(ctx context.Context, userID uuid.UUID) ([]*models.Device, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+deviceColumns+` FROM devices WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []*models.Device
+	for rows.Next() {
+		d := &models.Device{}
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Token, &d.Platform, &d.CreatedAt, &d.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// DeleteDevice deletes one of a user's devices
+func (r *NotificationRepo) DeleteDevice(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM devices WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveDevices deletes devices by token
+func (r *NotificationRepo) RemoveDevices(ctx context.Context, tokens []string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM devices WHERE token = ANY($1)`, tokens); err != nil {
+		return fmt.Errorf("failed to remove devices: %w", err)
+	}
+	return nil
+}
+
+// GetSettings returns a user's notification settings, with defaults if none are saved
+func (r *NotificationRepo) GetSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, []models.NotificationPreference, error) {
+	settings := &models.NotificationSettings{
+		UserID:             userID,
+		Timezone:           "UTC",
+		Locale:             "en",
+		EmailNotifications: true,
+		EmailDigest:        true,
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT timezone, quiet_hours_start, quiet_hours_end, locale, email_notifications, email_digest
+		FROM notification_settings
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+	for rows.Next() {
+		if err := rows.Scan(&settings.Timezone, &settings.QuietHoursStart, &settings.QuietHoursEnd,
+			&settings.Locale, &settings.EmailNotifications, &settings.EmailDigest); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan notification settings: %w", err)
+		}
+	}
+	rows.Close()
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT group_id, event_type, enabled
+		FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY group_id NULLS FIRST, event_type
+	`, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := []models.NotificationPreference{}
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.Scan(&p.GroupID, &p.EventType, &p.Enabled); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		prefs = append(prefs, p)
+	}
+	return settings, prefs, nil
+}
+
+// SaveSettings stores a user's time zone, quiet hours, locale and email
+// opt-outs, and replaces their preferences unless prefs is nil
+func (r *NotificationRepo) SaveSettings(ctx context.Context, s *models.NotificationSettings, prefs []models.NotificationPreference) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notification_settings
+			(user_id, timezone, quiet_hours_start, quiet_hours_end, locale, email_notifications, email_digest)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone,
+		    quiet_hours_start = EXCLUDED.quiet_hours_start,
+		    quiet_hours_end = EXCLUDED.quiet_hours_end,
+		    locale = EXCLUDED.locale,
+		    email_notifications = EXCLUDED.email_notifications,
+		    email_digest = EXCLUDED.email_digest,
+		    updated_at = now()
+	`, s.UserID, s.Timezone, s.QuietHoursStart, s.QuietHoursEnd, s.Locale, s.EmailNotifications, s.EmailDigest)
+	if err != nil {
+		return fmt.Errorf("failed to save notification settings: %w", err)
+	}
+
+	if prefs != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM notification_preferences WHERE user_id = $1`, s.UserID); err != nil {
+			return fmt.Errorf("failed to clear notification preferences: %w", err)
+		}
+		for _, p := range prefs {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO notification_preferences (user_id, group_id, event_type, enabled)
+				VALUES ($1, $2, $3, $4)
+			`, s.UserID, p.GroupID, p.EventType, p.Enabled)
+			if err != nil {
+				return fmt.Errorf("failed to save notification preference: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Heads returns the IDs of a group's heads
+func (r *NotificationRepo) Heads(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.pool.Query(ctx, `SELECT user_id FROM group_members WHERE group_id = $1 AND role = 'head'`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group heads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan group head: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Recipients returns the users' notification settings, preferences and device tokens
+func (r *NotificationRepo) Recipients(ctx context.Context, userIDs []uuid.UUID) ([]push.Recipient, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT u.id, COALESCE(s.timezone, 'UTC'), s.quiet_hours_start, s.quiet_hours_end,
+		       ARRAY(SELECT d.token FROM devices d WHERE d.user_id = u.id ORDER BY d.created_at)
+		FROM users u
+		LEFT JOIN notification_settings s ON s.user_id = u.id
+		WHERE u.id = ANY($1)
+	`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification recipients: %w", err)
+	}
+
+	byUser := make(map[uuid.UUID]*push.Recipient, len(userIDs))
+	var order []uuid.UUID
+	for rows.Next() {
+		var rec push.Recipient
+		var start, end *int
+		if err := rows.Scan(&rec.UserID, &rec.Timezone, &start, &end, &rec.Tokens); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan notification recipient: %w", err)
+		}
+		if start != nil && end != nil {
+			rec.QuietHours = &push.QuietHours{Start: *start, End: *end}
+		}
+		byUser[rec.UserID] = &rec
+		order = append(order, rec.UserID)
+	}
+	rows.Close()
+
+	prefs, err := r.preferences(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for id, rec := range byUser {
+		rec.Preferences = prefs[id]
+	}
+
+	recipients := make([]push.Recipient, 0, len(order))
+	for _, id := range order {
+		recipients = append(recipients, *byUser[id])
+	}
+	return recipients, nil
+}
+
+// preferences returns the users' notification preferences, keyed by user
+func (r *NotificationRepo) preferences(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]push.Preference, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT user_id, group_id, event_type, enabled
+		FROM notification_preferences
+		WHERE user_id = ANY($1)
+	`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := make(map[uuid.UUID][]push.Preference, len(userIDs))
+	for rows.Next() {
+		var userID uuid.UUID
+		var p push.Preference
+		var eventType string
+		if err := rows.Scan(&userID, &p.GroupID, &eventType, &p.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		p.EventType = events.Type(eventType)
+		prefs[userID] = append(prefs[userID], p)
+	}
+	return prefs, rows.Err()
+}
+
+// Labels returns the names of a group, a user and optionally a chore
+func (r *NotificationRepo) Labels(ctx context.Context, groupID, userID uuid.UUID, choreID *uuid.UUID) (push.Labels, error) {
+	var l push.Labels
+	err := r.pool.QueryRow(ctx, `
+		SELECT g.name,
+		       COALESCE((SELECT name FROM users WHERE id = $2), ''),
+		       COALESCE((SELECT name FROM chores WHERE id = $3), '')
+		FROM groups g
+		WHERE g.id = $1
+	`, groupID, userID, choreID).Scan(&l.GroupName, &l.UserName, &l.ChoreName)
+	if err != nil {
+		return l, fmt.Errorf("failed to get notification labels: %w", err)
+	}
+	return l, nil
+}
+
+// SetEmailOptOut turns off one kind of email for a user
+func (r *NotificationRepo) SetEmailOptOut(ctx context.Context, userID uuid.UUID, list mail.List) error {
+	var column string
+	switch list {
+	case mail.ListNotifications:
+		column = "email_notifications"
+	case mail.ListDigest:
+		column = "email_digest"
+	default:
+		return fmt.Errorf("unknown email list %q", list)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO notification_settings (user_id, %[1]s)
+		SELECT id, false FROM users WHERE id = $1
+		ON CONFLICT (user_id) DO UPDATE
+		SET %[1]s = false, updated_at = now()
+	`, column)
+	tag, err := r.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save email opt-out: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mailRecipientColumns are the columns scanned by scanMailRecipients
+const mailRecipientColumns = `u.id, u.email, u.name, COALESCE(s.locale, 'en'),
+	COALESCE(s.email_notifications, true), COALESCE(s.email_digest, true)`
+
+// scanMailRecipients reads recipients and attaches their preferences
+func (r *NotificationRepo) scanMailRecipients(ctx context.Context, rows pgx.Rows) ([]mail.Recipient, error) {
+	var recipients []mail.Recipient
+	var ids []uuid.UUID
+	for rows.Next() {
+		var rec mail.Recipient
+		if err := rows.Scan(&rec.UserID, &rec.Email, &rec.Name, &rec.Locale, &rec.Notifications, &rec.Digest); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan email recipient: %w", err)
+		}
+		recipients = append(recipients, rec)
+		ids = append(ids, rec.UserID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read email recipients: %w", err)
+	}
+
+	prefs, err := r.preferences(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recipients {
+		recipients[i].Preferences = prefs[recipients[i].UserID]
+	}
+	return recipients, nil
+}
+
+// MailRecipients returns the users' email addresses, settings and preferences
+func (r *NotificationRepo) MailRecipients(ctx context.Context, userIDs []uuid.UUID) ([]mail.Recipient, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+mailRecipientColumns+`
+		FROM users u
+		LEFT JOIN notification_settings s ON s.user_id = u.id
+		WHERE u.id = ANY($1)
+	`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email recipients: %w", err)
+	}
+	return r.scanMailRecipients(ctx, rows)
+}
+
+// DigestRecipients returns the group members who have not opted out of the digest
+func (r *NotificationRepo) DigestRecipients(ctx context.Context) ([]mail.Recipient, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+mailRecipientColumns+`
+		FROM users u
+		LEFT JOIN notification_settings s ON s.user_id = u.id
+		WHERE COALESCE(s.email_digest, true)
+		  AND EXISTS (SELECT 1 FROM group_members gm WHERE gm.user_id = u.id)
+		ORDER BY u.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest recipients: %w", err)
+	}
+	return r.scanMailRecipients(ctx, rows)
+}
+
+// DigestGroups returns a user's groups with what they earned between from and
+// to, their entries awaiting approval and, for heads, the group's
+func (r *NotificationRepo) DigestGroups(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]mail.DigestGroup, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT g.id, g.name, gm.role = 'head',
+		       (SELECT COALESCE(SUM(amount), 0) FROM ledger_entries
+		        WHERE group_id = g.id AND user_id = $1 AND status = 'approved'
+		          AND occurred_at >= $2 AND occurred_at < $3),
+		       (SELECT COUNT(*) FROM ledger_entries
+		        WHERE group_id = g.id AND user_id = $1 AND status = 'pending_approval'),
+		       (SELECT COUNT(*) FROM ledger_entries
+		        WHERE group_id = g.id AND status = 'pending_approval')
+		FROM group_members gm
+		INNER JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = $1
+		ORDER BY g.name
+	`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []mail.DigestGroup
+	for rows.Next() {
+		var g mail.DigestGroup
+		var awaiting int
+		if err := rows.Scan(&g.GroupID, &g.Name, &g.IsHead, &g.Earned, &g.Pending, &awaiting); err != nil {
+			return nil, fmt.Errorf("failed to scan digest group: %w", err)
+		}
+		if g.IsHead {
+			g.AwaitingApproval = awaiting
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// DuePendingReminders returns, per head and group, the entries logged before
+// cutoff that are still pending and that nobody was reminded about since
+// cutoff, and records that they were reminded at now
+func (r *NotificationRepo) DuePendingReminders(ctx context.Context, cutoff, now time.Time) ([]reminders.Pending, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT le.id, le.group_id, le.created_at
+			FROM ledger_entries le
+			LEFT JOIN ledger_reminders lr ON lr.entry_id = le.id
+			WHERE le.status = 'pending_approval'
+			  AND le.created_at < $1
+			  AND (lr.reminded_at IS NULL OR lr.reminded_at < $1)
+		), marked AS (
+			INSERT INTO ledger_reminders (entry_id, reminded_at)
+			SELECT id, $2 FROM due
+			ON CONFLICT (entry_id) DO UPDATE SET reminded_at = EXCLUDED.reminded_at
+		)
+		SELECT gm.user_id, g.id, g.name, COUNT(*), MIN(due.created_at)
+		FROM due
+		INNER JOIN groups g ON g.id = due.group_id
+		INNER JOIN group_members gm ON gm.group_id = g.id AND gm.role = 'head'
+		GROUP BY gm.user_id, g.id, g.name
+		ORDER BY g.name
+	`, cutoff, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var due []reminders.Pending
+	for rows.Next() {
+		var p reminders.Pending
+		if err := rows.Scan(&p.UserID, &p.GroupID, &p.GroupName, &p.Count, &p.Since); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		due = append(due, p)
+	}
+	return due, rows.Err()
+}
+
+// choreReminders selects, for chores in the CTE "due", the assignee, group,
+// chore, due time and the assignee's timezone
+const choreReminders = `
+		SELECT due.assignee_user_id, g.id, g.name, due.id, due.name, due.due_at, COALESCE(s.timezone, 'UTC')
+		FROM due
+		INNER JOIN groups g ON g.id = due.group_id
+		LEFT JOIN notification_settings s ON s.user_id = due.assignee_user_id
+		ORDER BY due.due_at, due.id
+`
+
+// assignedChores selects chores with a due time, not archived, whose
+// assignee is still a member and has not logged them since they were assigned
+const assignedChores = `
+			SELECT c.id, c.group_id, c.name, c.assignee_user_id, c.due_at
+			FROM chores c
+			INNER JOIN group_members gm ON gm.group_id = c.group_id AND gm.user_id = c.assignee_user_id
+			WHERE c.due_at IS NOT NULL AND c.deleted_at IS NULL
+			  AND NOT EXISTS (
+			      SELECT 1 FROM ledger_entries le
+			      WHERE le.chore_id = c.id AND le.user_id = c.assignee_user_id
+			        AND le.status IN ('pending_approval', 'approved')
+			        AND le.created_at >= c.assigned_at)
+`
+
+// DueChoreReminders returns the chores due after now and by until that
+// their assignee has not done and was not reminded about for that due time,
+// and records that they were reminded at now
+func (r *NotificationRepo) DueChoreReminders(ctx context.Context, until, now time.Time) ([]reminders.Chore, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (`+assignedChores+`
+			  AND c.due_at > $2 AND c.due_at <= $1
+			  AND NOT EXISTS (SELECT 1 FROM chore_reminders cr WHERE cr.chore_id = c.id AND cr.due_at = c.due_at)
+		), marked AS (
+			INSERT INTO chore_reminders (chore_id, due_at, reminded_at)
+			SELECT id, due_at, $2 FROM due
+			ON CONFLICT (chore_id) DO UPDATE SET due_at = EXCLUDED.due_at, reminded_at = EXCLUDED.reminded_at
+		)`+choreReminders, until, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due chore reminders: %w", err)
+	}
+	return scanChoreReminders(rows)
+}
+
+// OverdueChoreReminders returns the chores due by now that their assignee
+// has not done and was not nudged about since cutoff for that due time, and
+// records that they were nudged at now
+func (r *NotificationRepo) OverdueChoreReminders(ctx context.Context, cutoff, now time.Time) ([]reminders.Chore, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (`+assignedChores+`
+			  AND c.due_at <= $2
+			  AND NOT EXISTS (
+			      SELECT 1 FROM chore_overdue_reminders cr
+			      WHERE cr.chore_id = c.id AND cr.due_at = c.due_at AND cr.reminded_at >= $1)
+		), marked AS (
+			INSERT INTO chore_overdue_reminders (chore_id, due_at, reminded_at)
+			SELECT id, due_at, $2 FROM due
+			ON CONFLICT (chore_id) DO UPDATE SET due_at = EXCLUDED.due_at, reminded_at = EXCLUDED.reminded_at
+		)`+choreReminders, cutoff, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue chore reminders: %w", err)
+	}
+	return scanChoreReminders(rows)
+}
+
+func scanChoreReminders(rows pgx.Rows) ([]reminders.Chore, error) {
+	defer rows.Close()
+
+	var due []reminders.Chore
+	for rows.Next() {
+		var c reminders.Chore
+		if err := rows.Scan(&c.UserID, &c.GroupID, &c.GroupName, &c.ChoreID, &c.ChoreName, &c.DueAt, &c.Timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan chore reminder: %w", err)
+		}
+		due = append(due, c)
+	}
+	return due, rows.Err()
+}
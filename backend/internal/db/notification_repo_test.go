@@ -0,0 +1,99 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/testutil"
+)
+
+func TestChoreReminders(t *testing.T) {
+	pool, err := testutil.NewTestPool()
+	if err != nil {
+		t.Skipf("Skipping test: could not connect to test database: %v", err)
+	}
+	defer pool.Close()
+
+	_ = testutil.ResetTestDB(pool)
+	require.NoError(t, db.RunMigrations(testutil.GetTestDatabaseURL()))
+	defer testutil.CleanupTestDB(pool)
+
+	ctx := context.Background()
+	users := db.NewUserRepo(pool)
+	groups := db.NewGroupRepo(pool)
+	chores := db.NewChoreRepo(pool)
+	ledger := db.NewLedgerRepo(pool, db.NewChainRepo(pool, chain.NewHasher("test-key")))
+	notifications := db.NewNotificationRepo(pool)
+
+	head, err := users.Create(ctx, "parent@example.com", "hash", "Parent", nil, nil)
+	require.NoError(t, err)
+	kid, err := users.Create(ctx, "kid@example.com", "hash", "Kid", nil, nil)
+	require.NoError(t, err)
+	group, err := groups.Create(ctx, "Family", head.ID)
+	require.NoError(t, err)
+	_, err = groups.AddMember(ctx, group.ID, head.ID, models.RoleHead)
+	require.NoError(t, err)
+	_, err = groups.AddMember(ctx, group.ID, kid.ID, models.RoleMember)
+	require.NoError(t, err)
+
+	now := time.Now()
+	dueAt := now.Add(time.Hour)
+	dishes, err := chores.Create(ctx, group.ID, "Dishes", nil, 2, false, &kid.ID, &dueAt)
+	require.NoError(t, err)
+	require.NotNil(t, dishes.AssignedAt)
+
+	// Reminded once before the chore is due
+	due, err := notifications.DueChoreReminders(ctx, now.Add(2*time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, kid.ID, due[0].UserID)
+	assert.Equal(t, "Dishes", due[0].ChoreName)
+	assert.Equal(t, "Family", due[0].GroupName)
+	assert.Equal(t, "UTC", due[0].Timezone)
+	due, err = notifications.DueChoreReminders(ctx, now.Add(2*time.Hour), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	// Not overdue yet, then nudged once it is and again a day later
+	overdue, err := notifications.OverdueChoreReminders(ctx, now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Empty(t, overdue)
+	later := now.Add(2 * time.Hour)
+	overdue, err = notifications.OverdueChoreReminders(ctx, later.Add(-24*time.Hour), later)
+	require.NoError(t, err)
+	assert.Len(t, overdue, 1)
+	overdue, err = notifications.OverdueChoreReminders(ctx, later.Add(-24*time.Hour), later.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, overdue)
+	later = later.Add(25 * time.Hour)
+	overdue, err = notifications.OverdueChoreReminders(ctx, later.Add(-24*time.Hour), later)
+	require.NoError(t, err)
+	assert.Len(t, overdue, 1)
+
+	// Logging the chore does it
+	_, err = ledger.Create(ctx, group.ID, kid.ID, dishes.ID, kid.ID, 2, models.StatusPendingApproval, nil, nil)
+	require.NoError(t, err)
+	later = later.Add(25 * time.Hour)
+	overdue, err = notifications.OverdueChoreReminders(ctx, later.Add(-24*time.Hour), later)
+	require.NoError(t, err)
+	assert.Empty(t, overdue)
+
+	// Moving the due time assigns it again and starts the reminders over
+	time.Sleep(10 * time.Millisecond)
+	next := time.Now().Add(time.Hour)
+	moved, err := chores.Update(ctx, dishes.ID, db.ChoreUpdate{SetDueAt: true, DueAt: &next})
+	require.NoError(t, err)
+	assert.True(t, moved.AssignedAt.After(*dishes.AssignedAt))
+	due, err = notifications.DueChoreReminders(ctx, next.Add(time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrDuplicateCategory is returned when a group already has a category with
+// the same name
+var ErrDuplicateCategory = errors.New("category already exists")
+
+// ErrUnknownCategory is returned when a settlement names a category that is
+// not one of its group's current categories
+var ErrUnknownCategory = errors.New("category is not a category of this group")
+
+// SettlementCategoryRepo handles database operations for settlement categories
+type SettlementCategoryRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewSettlementCategoryRepo creates a new SettlementCategoryRepo
+func NewSettlementCategoryRepo(pool *pgxpool.Pool) *SettlementCategoryRepo {
+	return &SettlementCategoryRepo{pool: pool}
+}
+
+const settlementCategoryColumns = `id, group_id, name, created_at, deleted_at`
+
+func scanSettlementCategory(row pgx.Row) (*models.SettlementCategory, error) {
+	category := &models.SettlementCategory{}
+	err := row.Scan(&category.ID, &category.GroupID, &category.Name, &category.CreatedAt, &category.DeletedAt)
+	return category, err
+}
+
+// Create inserts a new settlement category
+func (r *SettlementCategoryRepo) Create(ctx context.Context, groupID uuid.UUID, name string) (*models.SettlementCategory, error) {
+	query := `
+		INSERT INTO settlement_categories (group_id, name)
+		VALUES ($1, $2)
+		RETURNING ` + settlementCategoryColumns
+
+	category, err := scanSettlementCategory(r.pool.QueryRow(ctx, query, groupID, name))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrDuplicateCategory
+		}
+		return nil, fmt.Errorf("failed to create settlement category: %w", err)
+	}
+	return category, nil
+}
+
+// GetByID retrieves a settlement category by ID, including deleted ones
+func (r *SettlementCategoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.SettlementCategory, error) {
+	category, err := scanSettlementCategory(r.pool.QueryRow(ctx, `SELECT `+settlementCategoryColumns+` FROM settlement_categories WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get settlement category by id: %w", err)
+	}
+	return category, nil
+}
+
+// ListForGroup retrieves a group's settlement categories by name. Deleted
+// categories are only included if includeDeleted is set, so old settlements
+// can still be labelled.
+func (r *SettlementCategoryRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, includeDeleted bool) ([]*models.SettlementCategory, error) {
+	query := `
+		SELECT ` + settlementCategoryColumns + `
+		FROM settlement_categories
+		WHERE group_id = $1 AND ($2 OR deleted_at IS NULL)
+		ORDER BY lower(name), created_at, id
+	`
+	rows, err := r.pool.Query(ctx, query, groupID, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlement categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.SettlementCategory
+	for rows.Next() {
+		category, err := scanSettlementCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan settlement category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read settlement categories: %w", err)
+	}
+	return categories, nil
+}
+
+// Rename changes the name of a category that has not been deleted
+func (r *SettlementCategoryRepo) Rename(ctx context.Context, id uuid.UUID, name string) (*models.SettlementCategory, error) {
+	query := `
+		UPDATE settlement_categories
+		SET name = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + settlementCategoryColumns
+
+	category, err := scanSettlementCategory(r.pool.QueryRow(ctx, query, id, name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if isDuplicateKeyError(err) {
+			return nil, ErrDuplicateCategory
+		}
+		return nil, fmt.Errorf("failed to rename settlement category: %w", err)
+	}
+	return category, nil
+}
+
+// Delete stops a category from being given to new settlements. The row is
+// kept so settlements already in it still name it.
+func (r *SettlementCategoryRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `UPDATE settlement_categories SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete settlement category: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkCategory returns ErrUnknownCategory unless id is a category of the
+// group that has not been deleted
+func checkCategory(ctx context.Context, tx pgx.Tx, groupID, id uuid.UUID) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM settlement_categories
+			WHERE id = $1 AND group_id = $2 AND deleted_at IS NULL
+		)
+	`, id, groupID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check settlement category: %w", err)
+	}
+	if !exists {
+		return ErrUnknownCategory
+	}
+	return nil
+}
@@ -2,83 +2,948 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/srjn45/pocket-money/backend/internal/chain"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
+// settlementColumns is the column list scanned by scanSettlement
+const settlementColumns = `id, group_id, user_id, amount, date, note, method, external_reference, category_id, jar_id, status, acknowledged_at, disputed_at, dispute_reason, created_at, updated_at, voided_at, voided_by_user_id, void_reason, hash`
+
+// settlementRevisionColumns is the column list scanned by scanSettlementRevision
+const settlementRevisionColumns = `id, settlement_id, action, changed_by_user_id, reason, amount_before, amount_after, date_before, date_after, note_before, note_after, method_before, method_after, external_reference_before, external_reference_after, category_id_before, category_id_after, created_at`
+
+// ErrVoided is returned when changing a settlement that was voided
+var ErrVoided = errors.New("settlement is voided")
+
+// ErrEntryNotPayable is returned when a settlement names an entry that is not
+// an approved entry of its member with an unpaid amount left
+var ErrEntryNotPayable = errors.New("entry is not an unpaid approved entry of this member")
+
+// OverpaymentError is returned when a settlement would pay a member more than
+// their balance
+type OverpaymentError struct {
+	Balance float64
+}
+
+func (e *OverpaymentError) Error() string {
+	return fmt.Sprintf("settlement exceeds the member's balance of %.2f", e.Balance)
+}
+
+// SettlementStatusError is returned when a settlement's acknowledgement
+// status does not allow a change
+type SettlementStatusError struct {
+	Status models.SettlementStatus
+}
+
+func (e *SettlementStatusError) Error() string {
+	switch e.Status {
+	case models.SettlementAcknowledged:
+		return "settlement is already acknowledged"
+	case models.SettlementDisputed:
+		return "settlement is disputed"
+	}
+	return "settlement is awaiting acknowledgement"
+}
+
 // SettlementRepo handles database operations for settlements
 type SettlementRepo struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	chain *ChainRepo
 }
 
 // NewSettlementRepo creates a new SettlementRepo
-func NewSettlementRepo(pool *pgxpool.Pool) *SettlementRepo {
-	return &SettlementRepo{pool: pool}
+func NewSettlementRepo(pool *pgxpool.Pool, chainRepo *ChainRepo) *SettlementRepo {
+	return &SettlementRepo{pool: pool, chain: chainRepo}
 }
 
-// Create inserts a new settlement
-func (r *SettlementRepo) Create(ctx context.Context, groupID, userID uuid.UUID, amount float64, date time.Time, note *string) (*models.Settlement, error) {
-	settlement := &models.Settlement{
-		ID:      uuid.New(),
-		GroupID: groupID,
-		UserID:  userID,
-		Amount:  amount,
-		Date:    date,
-		Note:    note,
+// Create inserts a new settlement, filling in its ID and timestamps, links it
+// to the entries it pays and appends it to the group's chain. Entries named
+// in entryIDs are paid first, then the member's oldest unpaid entries. Unless
+// allowNegative is set, a settlement larger than the member's balance is
+// refused with an *OverpaymentError, as is one larger than its jar holds when
+// it is paid out of a jar. The method defaults to cash, and a category must
+// be one of the group's current categories.
+func (r *SettlementRepo) Create(ctx context.Context, settlement *models.Settlement, entryIDs []uuid.UUID, allowNegative bool) (*models.Settlement, error) {
+	settlement.ID = uuid.New()
+	if settlement.Method == "" {
+		settlement.Method = models.MethodCash
+	}
+	groupID, userID := settlement.GroupID, settlement.UserID
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := lockMemberSettlements(ctx, tx, groupID, userID); err != nil {
+		return nil, err
+	}
+	if !allowNegative {
+		if err := checkOverpayment(ctx, tx, groupID, userID, nil, settlement.Amount); err != nil {
+			return nil, err
+		}
+	}
+	if settlement.JarID != nil {
+		if err := checkJar(ctx, tx, groupID, userID, *settlement.JarID, nil, settlement.Amount, allowNegative); err != nil {
+			return nil, err
+		}
+	}
+	if settlement.CategoryID != nil {
+		if err := checkCategory(ctx, tx, groupID, *settlement.CategoryID); err != nil {
+			return nil, err
+		}
 	}
 
 	query := `
-		INSERT INTO settlements (id, group_id, user_id, amount, date, note)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING created_at
+		INSERT INTO settlements (id, group_id, user_id, amount, date, note, method, external_reference, category_id, jar_id, status, acknowledged_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $11 = 'acknowledged' THEN now() END)
+		RETURNING amount, acknowledged_at, created_at
 	`
 
-	err := r.pool.QueryRow(ctx, query,
-		settlement.ID, groupID, userID, amount, date, note,
-	).Scan(&settlement.CreatedAt)
+	err = tx.QueryRow(ctx, query,
+		settlement.ID, groupID, userID, settlement.Amount, settlement.Date, settlement.Note,
+		settlement.Method, settlement.ExternalReference, settlement.CategoryID, settlement.JarID, settlement.Status,
+	).Scan(&settlement.Amount, &settlement.AcknowledgedAt, &settlement.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create settlement: %w", err)
 	}
 
+	if settlement.Entries, err = linkEntries(ctx, tx, settlement, entryIDs); err != nil {
+		return nil, err
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, groupID, chain.RecordSettlement, settlement.ID, chain.SettlementPayload(settlement))
+	if err != nil {
+		return nil, err
+	}
+	settlement.Hash = &hash
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return settlement, nil
 }
 
-// ListForGroup retrieves all settlements for a group
-func (r *SettlementRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*models.Settlement, error) {
+// GetByID retrieves a settlement with the entries it pays
+func (r *SettlementRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Settlement, error) {
+	settlement, err := scanSettlement(r.pool.QueryRow(ctx, `SELECT `+settlementColumns+` FROM settlements WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get settlement: %w", err)
+	}
+
+	if err := r.loadEntries(ctx, []*models.Settlement{settlement}); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// SettlementUpdate holds the changes to a settlement; nil fields are kept.
+// EntryIDs are paid first when the settlement is relinked, ahead of the
+// entries it already paid.
+type SettlementUpdate struct {
+	Amount            *float64
+	Date              *time.Time
+	Note              *string
+	Method            *models.SettlementMethod
+	ExternalReference *string
+	CategoryID        *uuid.UUID
+	EntryIDs          []uuid.UUID
+	AllowNegative     bool
+}
+
+// Update corrects a settlement, records the change in its revisions and
+// appends it to the group's chain. The settlement is relinked to the entries
+// it pays, and an increase beyond the member's balance is refused with an
+// *OverpaymentError unless AllowNegative is set, as is one beyond what its
+// jar holds. The jar itself cannot be changed. A new amount or date has to
+// be acknowledged again, which also settles any dispute.
+func (r *SettlementRepo) Update(ctx context.Context, id uuid.UUID, update SettlementUpdate, changedByUserID uuid.UUID, reason string) (*models.Settlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	before, err := lockSettlement(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	if update.Amount != nil {
+		after.Amount = *update.Amount
+	}
+	if update.Date != nil {
+		after.Date = *update.Date
+	}
+	if update.Note != nil {
+		after.Note = update.Note
+	}
+	if update.Method != nil {
+		after.Method = *update.Method
+	}
+	if update.ExternalReference != nil {
+		after.ExternalReference = update.ExternalReference
+	}
+	if update.CategoryID != nil {
+		after.CategoryID = update.CategoryID
+		if err := checkCategory(ctx, tx, before.GroupID, *update.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !update.AllowNegative && after.Amount > before.Amount {
+		if err := checkOverpayment(ctx, tx, before.GroupID, before.UserID, &before.ID, after.Amount); err != nil {
+			return nil, err
+		}
+		if before.JarID != nil {
+			if err := checkJar(ctx, tx, before.GroupID, before.UserID, *before.JarID, &before.ID, after.Amount, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Keep paying the same entries unless told otherwise
+	preferred, err := linkedEntryIDs(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	preferred = append(append([]uuid.UUID{}, update.EntryIDs...), preferred...)
+	if _, err := tx.Exec(ctx, `DELETE FROM settlement_entries WHERE settlement_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to unlink settlement entries: %w", err)
+	}
+
+	reconfirm := after.Amount != before.Amount || !after.Date.Equal(before.Date)
+	settlement, err := scanSettlement(tx.QueryRow(ctx, `
+		UPDATE settlements
+		SET amount = $2, date = $3, note = $4, method = $5, external_reference = $6, category_id = $7,
+		    updated_at = now(),
+		    status = CASE WHEN $8 THEN 'awaiting_ack' ELSE status END,
+		    acknowledged_at = CASE WHEN $8 THEN NULL ELSE acknowledged_at END,
+		    disputed_at = CASE WHEN $8 THEN NULL ELSE disputed_at END,
+		    dispute_reason = CASE WHEN $8 THEN NULL ELSE dispute_reason END
+		WHERE id = $1
+		RETURNING `+settlementColumns,
+		id, after.Amount, after.Date, after.Note, after.Method, after.ExternalReference, after.CategoryID, reconfirm,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settlement: %w", err)
+	}
+
+	if settlement.Entries, err = linkEntries(ctx, tx, settlement, preferred); err != nil {
+		return nil, err
+	}
+	if err := insertRevision(ctx, tx, models.SettlementEdited, before, settlement, changedByUserID, reason); err != nil {
+		return nil, err
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, settlement.GroupID, chain.RecordSettlement, settlement.ID, chain.SettlementPayload(settlement))
+	if err != nil {
+		return nil, err
+	}
+	settlement.Hash = &hash
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// Void marks a settlement voided so it no longer counts towards balances,
+// frees the entries it paid, records the change in its revisions and
+// appends it to the group's chain
+func (r *SettlementRepo) Void(ctx context.Context, id, voidedByUserID uuid.UUID, reason string) (*models.Settlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	before, err := lockSettlement(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM settlement_entries WHERE settlement_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to unlink settlement entries: %w", err)
+	}
+
+	settlement, err := scanSettlement(tx.QueryRow(ctx, `
+		UPDATE settlements
+		SET voided_at = now(), voided_by_user_id = $2, void_reason = $3
+		WHERE id = $1
+		RETURNING `+settlementColumns,
+		id, voidedByUserID, reason,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to void settlement: %w", err)
+	}
+
+	if err := insertRevision(ctx, tx, models.SettlementVoided, before, settlement, voidedByUserID, reason); err != nil {
+		return nil, err
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, settlement.GroupID, chain.RecordSettlement, settlement.ID, chain.SettlementPayload(settlement))
+	if err != nil {
+		return nil, err
+	}
+	settlement.Hash = &hash
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return settlement, nil
+}
+
+// Acknowledge records that the member received a settlement, ending any
+// dispute about it
+func (r *SettlementRepo) Acknowledge(ctx context.Context, id, userID uuid.UUID) (*models.Settlement, error) {
+	return r.changeStatus(ctx, id, []models.SettlementStatus{models.SettlementAwaitingAck, models.SettlementDisputed},
+		models.SettlementAcknowledged, userID, nil)
+}
+
+// Dispute records that the member says a settlement awaiting their
+// acknowledgement is wrong, and why
+func (r *SettlementRepo) Dispute(ctx context.Context, id, userID uuid.UUID, reason string) (*models.Settlement, error) {
+	return r.changeStatus(ctx, id, []models.SettlementStatus{models.SettlementAwaitingAck},
+		models.SettlementDisputed, userID, &reason)
+}
+
+// Resolve answers a dispute without changing the settlement, asking the
+// member to acknowledge it again
+func (r *SettlementRepo) Resolve(ctx context.Context, id, headID uuid.UUID, reason string) (*models.Settlement, error) {
+	return r.changeStatus(ctx, id, []models.SettlementStatus{models.SettlementDisputed},
+		models.SettlementAwaitingAck, headID, &reason)
+}
+
+// changeStatus moves a settlement that is in one of the from statuses to
+// status, appending it to the group's chain. Disputes and resolutions are
+// recorded in its revisions with their reason.
+func (r *SettlementRepo) changeStatus(ctx context.Context, id uuid.UUID, from []models.SettlementStatus, status models.SettlementStatus, actorID uuid.UUID, reason *string) (*models.Settlement, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	before, err := lockSettlement(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, before.Status) {
+		return nil, &SettlementStatusError{Status: before.Status}
+	}
+
+	settlement, err := scanSettlement(tx.QueryRow(ctx, `
+		UPDATE settlements
+		SET status = $2,
+		    acknowledged_at = CASE WHEN $2 = 'acknowledged' THEN now() END,
+		    disputed_at = CASE WHEN $2 = 'disputed' THEN now() END,
+		    dispute_reason = CASE WHEN $2 = 'disputed' THEN $3 END
+		WHERE id = $1
+		RETURNING `+settlementColumns,
+		id, status, reason,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settlement status: %w", err)
+	}
+
+	if reason != nil {
+		action := models.SettlementDisputeRaised
+		if before.Status == models.SettlementDisputed {
+			action = models.SettlementDisputeResolved
+		}
+		if err := insertRevision(ctx, tx, action, before, settlement, actorID, *reason); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := r.chain.appendLink(ctx, tx, settlement.GroupID, chain.RecordSettlement, settlement.ID, chain.SettlementPayload(settlement))
+	if err != nil {
+		return nil, err
+	}
+	settlement.Hash = &hash
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := r.loadEntries(ctx, []*models.Settlement{settlement}); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// ListRevisions retrieves a settlement's edits, disputes and void, oldest first
+func (r *SettlementRepo) ListRevisions(ctx context.Context, settlementID uuid.UUID) ([]*models.SettlementRevision, error) {
 	query := `
-		SELECT id, group_id, user_id, amount, date, note, created_at
+		SELECT ` + settlementRevisionColumns + `
+		FROM settlement_revisions
+		WHERE settlement_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, settlementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlement revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*models.SettlementRevision
+	for rows.Next() {
+		revision, err := scanSettlementRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan settlement revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read settlement revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// lockMemberSettlements serializes settlement changes for one member so
+// balance checks and entry links stay consistent
+func lockMemberSettlements(ctx context.Context, tx pgx.Tx, groupID, userID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "settlements:"+groupID.String()+":"+userID.String()); err != nil {
+		return fmt.Errorf("failed to lock member settlements: %w", err)
+	}
+	return nil
+}
+
+// lockSettlement locks a settlement that is not voided and its member's
+// settlements for the rest of the transaction
+func lockSettlement(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.Settlement, error) {
+	var groupID, userID uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT group_id, user_id FROM settlements WHERE id = $1`, id).Scan(&groupID, &userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get settlement: %w", err)
+	}
+
+	if err := lockMemberSettlements(ctx, tx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	settlement, err := scanSettlement(tx.QueryRow(ctx, `SELECT `+settlementColumns+` FROM settlements WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock settlement: %w", err)
+	}
+	if settlement.VoidedAt != nil {
+		return nil, ErrVoided
+	}
+
+	return settlement, nil
+}
+
+// checkOverpayment returns an *OverpaymentError if paying amount would take
+// the member's balance below zero. The settlement being edited, if any, is
+// left out of the balance.
+func checkOverpayment(ctx context.Context, tx pgx.Tx, groupID, userID uuid.UUID, excludeID *uuid.UUID, amount float64) error {
+	query := `
+		SELECT
+			(SELECT COALESCE(SUM(amount), 0) FROM ledger_entries
+			 WHERE group_id = $1 AND user_id = $2 AND status = 'approved')
+			-
+			(SELECT COALESCE(SUM(amount), 0) FROM settlements
+			 WHERE group_id = $1 AND user_id = $2 AND voided_at IS NULL
+			   AND ($3::uuid IS NULL OR id <> $3))
+	`
+
+	var balance float64
+	if err := tx.QueryRow(ctx, query, groupID, userID, excludeID).Scan(&balance); err != nil {
+		return fmt.Errorf("failed to get member balance: %w", err)
+	}
+
+	if roundCents(amount) > roundCents(balance) {
+		return &OverpaymentError{Balance: roundCents(balance)}
+	}
+	return nil
+}
+
+// unpaidEntry is an approved entry with the amount no settlement has paid yet
+type unpaidEntry struct {
+	ID     uuid.UUID
+	Unpaid float64
+}
+
+// linkEntries links a settlement to the entries it pays: entryIDs first, in
+// order, then the member's oldest unpaid entries. Any amount beyond what the
+// entries are owed is left unlinked.
+func linkEntries(ctx context.Context, tx pgx.Tx, s *models.Settlement, entryIDs []uuid.UUID) ([]models.SettlementEntry, error) {
+	query := `
+		SELECT le.id, le.amount - COALESCE(SUM(se.amount), 0) AS unpaid
+		FROM ledger_entries le
+		LEFT JOIN settlement_entries se ON se.entry_id = le.id
+		WHERE le.group_id = $1 AND le.user_id = $2 AND le.status = 'approved'
+		GROUP BY le.id
+		HAVING le.amount - COALESCE(SUM(se.amount), 0) > 0
+		ORDER BY le.occurred_at, le.created_at, le.id
+	`
+
+	rows, err := tx.Query(ctx, query, s.GroupID, s.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unpaid entries: %w", err)
+	}
+	defer rows.Close()
+
+	var unpaid []unpaidEntry
+	for rows.Next() {
+		var e unpaidEntry
+		if err := rows.Scan(&e.ID, &e.Unpaid); err != nil {
+			return nil, fmt.Errorf("failed to scan unpaid entry: %w", err)
+		}
+		unpaid = append(unpaid, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read unpaid entries: %w", err)
+	}
+
+	links, err := allocateSettlement(s.Amount, unpaid, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range links {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO settlement_entries (settlement_id, entry_id, amount)
+			VALUES ($1, $2, $3)
+		`, s.ID, l.EntryID, l.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link settlement entry: %w", err)
+		}
+	}
+
+	return links, nil
+}
+
+// allocateSettlement splits amount over unpaid entries, paying the entries
+// named in preferred first and then the rest in the given order. Every
+// preferred entry must be unpaid.
+func allocateSettlement(amount float64, unpaid []unpaidEntry, preferred []uuid.UUID) ([]models.SettlementEntry, error) {
+	byID := make(map[uuid.UUID]int, len(unpaid))
+	for i, e := range unpaid {
+		byID[e.ID] = i
+	}
+
+	order := make([]int, 0, len(unpaid))
+	used := make(map[int]bool, len(preferred))
+	for _, id := range preferred {
+		i, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrEntryNotPayable, id)
+		}
+		if !used[i] {
+			order = append(order, i)
+			used[i] = true
+		}
+	}
+	for i := range unpaid {
+		if !used[i] {
+			order = append(order, i)
+		}
+	}
+
+	cents := int64(math.Round(amount * 100))
+	var links []models.SettlementEntry
+	for _, i := range order {
+		if cents <= 0 {
+			break
+		}
+		owed := int64(math.Round(unpaid[i].Unpaid * 100))
+		paid := min(cents, owed)
+		if paid <= 0 {
+			continue
+		}
+		links = append(links, models.SettlementEntry{EntryID: unpaid[i].ID, Amount: float64(paid) / 100})
+		cents -= paid
+	}
+
+	return links, nil
+}
+
+// linkedEntryIDs returns the entries a settlement pays, oldest first
+func linkedEntryIDs(ctx context.Context, tx pgx.Tx, settlementID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT se.entry_id
+		FROM settlement_entries se
+		INNER JOIN ledger_entries le ON le.id = se.entry_id
+		WHERE se.settlement_id = $1
+		ORDER BY le.occurred_at, le.created_at, le.id
+	`, settlementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settlement entries: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settlement entries: %w", err)
+	}
+	return ids, nil
+}
+
+// insertRevision records a change to a settlement
+func insertRevision(ctx context.Context, tx pgx.Tx, action models.SettlementAction, before, after *models.Settlement, changedByUserID uuid.UUID, reason string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO settlement_revisions (settlement_id, action, changed_by_user_id, reason,
+			amount_before, amount_after, date_before, date_after, note_before, note_after,
+			method_before, method_after, external_reference_before, external_reference_after,
+			category_id_before, category_id_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, before.ID, action, changedByUserID, reason,
+		before.Amount, after.Amount, before.Date, after.Date, before.Note, after.Note,
+		before.Method, after.Method, before.ExternalReference, after.ExternalReference,
+		before.CategoryID, after.CategoryID)
+	if err != nil {
+		return fmt.Errorf("failed to record settlement revision: %w", err)
+	}
+	return nil
+}
+
+// loadEntries fills in the entries each settlement pays
+func (r *SettlementRepo) loadEntries(ctx context.Context, settlements []*models.Settlement) error {
+	if len(settlements) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(settlements))
+	byID := make(map[uuid.UUID]*models.Settlement, len(settlements))
+	for _, s := range settlements {
+		ids = append(ids, s.ID)
+		byID[s.ID] = s
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT se.settlement_id, se.entry_id, se.amount
+		FROM settlement_entries se
+		INNER JOIN ledger_entries le ON le.id = se.entry_id
+		WHERE se.settlement_id = ANY($1)
+		ORDER BY le.occurred_at, le.created_at, le.id
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to list settlement entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var settlementID uuid.UUID
+		var link models.SettlementEntry
+		if err := rows.Scan(&settlementID, &link.EntryID, &link.Amount); err != nil {
+			return fmt.Errorf("failed to scan settlement entry: %w", err)
+		}
+		s := byID[settlementID]
+		s.Entries = append(s.Entries, link)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read settlement entries: %w", err)
+	}
+
+	return nil
+}
+
+// SettlementFilter narrows a settlement listing; nil fields are ignored
+type SettlementFilter struct {
+	UserID     *uuid.UUID
+	From       *time.Time // Inclusive lower bound on date
+	To         *time.Time // Exclusive upper bound on date
+	MinAmount  *float64
+	MaxAmount  *float64
+	Voided     *bool // Only voided, or only standing, settlements
+	Status     *models.SettlementStatus
+	Method     *models.SettlementMethod
+	CategoryID *uuid.UUID
+}
+
+// settlementSortColumns lists the fields settlements can be sorted by
+var settlementSortColumns = map[string]sortColumn{
+	"date":       {column: "date", cast: "date"},
+	"created_at": {column: "created_at", cast: "timestamptz"},
+	"amount":     {column: "amount", cast: "numeric"},
+}
+
+// settlementWhere selects a group's settlements matching the filter
+func settlementWhere(groupID uuid.UUID, filter SettlementFilter) whereBuilder {
+	var w whereBuilder
+	w.add("group_id = %s", groupID)
+	if filter.UserID != nil {
+		w.add("user_id = %s", *filter.UserID)
+	}
+	if filter.From != nil {
+		w.add("date >= %s::date", filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		w.add("date < %s::date", filter.To.Format("2006-01-02"))
+	}
+	if filter.MinAmount != nil {
+		w.add("amount >= %s", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		w.add("amount <= %s", *filter.MaxAmount)
+	}
+	if filter.Voided != nil {
+		w.add("(voided_at IS NOT NULL) = %s", *filter.Voided)
+	}
+	if filter.Status != nil {
+		w.add("status = %s", *filter.Status)
+	}
+	if filter.Method != nil {
+		w.add("method = %s", *filter.Method)
+	}
+	if filter.CategoryID != nil {
+		w.add("category_id = %s", *filter.CategoryID)
+	}
+	return w
+}
+
+// ListForGroup retrieves a page of settlements for a group matching the filter
+func (r *SettlementRepo) ListForGroup(ctx context.Context, groupID uuid.UUID, filter SettlementFilter, page Page) ([]*models.Settlement, *PageInfo, error) {
+	w := settlementWhere(groupID, filter)
+
+	info := &PageInfo{}
+	countQuery := `SELECT COUNT(*) FROM settlements WHERE ` + w.sql()
+	if err := r.pool.QueryRow(ctx, countQuery, w.args...).Scan(&info.Total); err != nil {
+		return nil, nil, fmt.Errorf("failed to count settlements: %w", err)
+	}
+
+	keyed, suffix, err := keysetQuery(w, page, settlementSortColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `
+		SELECT ` + settlementColumns + `
+		FROM settlements
+		WHERE ` + keyed.sql() + `
+		` + suffix
+
+	rows, err := r.pool.Query(ctx, query, keyed.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list settlements: %w", err)
+	}
+
+	settlements, err := collectSettlements(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if limit := pageLimit(page); limit > 0 && len(settlements) > limit {
+		settlements = settlements[:limit]
+		last := settlements[limit-1]
+		info.NextCursor = nextCursor(page.Sort, settlementSortValue(last, page.Sort.Field), last.ID)
+	}
+
+	if err := r.loadEntries(ctx, settlements); err != nil {
+		return nil, nil, err
+	}
+
+	return settlements, info, nil
+}
+
+// Summarize totals a group's settlements matching the filter, overall, by
+// method and by category. Voided settlements are left out unless the filter
+// asks for them. Every method is listed, even without settlements; categories
+// are listed by name, with uncategorized settlements last.
+func (r *SettlementRepo) Summarize(ctx context.Context, groupID uuid.UUID, filter SettlementFilter) (*models.SettlementSummary, error) {
+	if filter.Voided == nil {
+		standing := false
+		filter.Voided = &standing
+	}
+	w := settlementWhere(groupID, filter)
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT method, COUNT(*), COALESCE(SUM(amount), 0)
 		FROM settlements
-		WHERE group_id = $1
-		ORDER BY date DESC, created_at DESC
+		WHERE `+w.sql()+`
+		GROUP BY method
+	`, w.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total settlements by method: %w", err)
+	}
+	byMethod := make(map[models.SettlementMethod]models.MethodTotal)
+	for rows.Next() {
+		var t models.MethodTotal
+		if err := rows.Scan(&t.Method, &t.Count, &t.Amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan method total: %w", err)
+		}
+		byMethod[t.Method] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read method totals: %w", err)
+	}
+
+	summary := &models.SettlementSummary{
+		ByMethod:   make([]models.MethodTotal, 0, len(models.SettlementMethods)),
+		ByCategory: []models.CategoryTotal{},
+	}
+	for _, method := range models.SettlementMethods {
+		t := byMethod[method]
+		t.Method = method
+		summary.ByMethod = append(summary.ByMethod, t)
+		summary.Count += t.Count
+		summary.Amount += t.Amount
+	}
+	summary.Amount = roundCents(summary.Amount)
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT s.category_id, c.name, COUNT(*), SUM(s.amount)
+		FROM (SELECT category_id, amount FROM settlements WHERE `+w.sql()+`) s
+		LEFT JOIN settlement_categories c ON c.id = s.category_id
+		GROUP BY s.category_id, c.name
+		ORDER BY s.category_id IS NULL, lower(c.name), s.category_id
+	`, w.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total settlements by category: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.CategoryTotal
+		if err := rows.Scan(&t.CategoryID, &t.Name, &t.Count, &t.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan category total: %w", err)
+		}
+		summary.ByCategory = append(summary.ByCategory, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read category totals: %w", err)
+	}
+
+	return summary, nil
+}
+
+// ListForMember retrieves a member's settlements dated in [from, to) that
+// were not voided, oldest first
+func (r *SettlementRepo) ListForMember(ctx context.Context, groupID, userID uuid.UUID, from, to time.Time) ([]*models.Settlement, error) {
+	query := `
+		SELECT ` + settlementColumns + `
+		FROM settlements
+		WHERE group_id = $1 AND user_id = $2 AND date >= $3::date AND date < $4::date
+		  AND voided_at IS NULL
+		ORDER BY date, created_at, id
 	`
 
-	rows, err := r.pool.Query(ctx, query, groupID)
+	rows, err := r.pool.Query(ctx, query, groupID, userID, from, to)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list settlements: %w", err)
+		return nil, fmt.Errorf("failed to list member settlements: %w", err)
+	}
+
+	return collectSettlements(rows)
+}
+
+// settlementSortValue formats a settlement's sort field the way keysetQuery compares it
+func settlementSortValue(s *models.Settlement, field string) string {
+	switch field {
+	case "created_at":
+		return s.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "amount":
+		return strconv.FormatFloat(s.Amount, 'f', 2, 64)
+	default:
+		return s.Date.Format("2006-01-02")
 	}
+}
+
+// scanSettlement scans a row selected with settlementColumns
+func scanSettlement(row pgx.Row) (*models.Settlement, error) {
+	settlement := &models.Settlement{}
+	err := row.Scan(
+		&settlement.ID,
+		&settlement.GroupID,
+		&settlement.UserID,
+		&settlement.Amount,
+		&settlement.Date,
+		&settlement.Note,
+		&settlement.Method,
+		&settlement.ExternalReference,
+		&settlement.CategoryID,
+		&settlement.JarID,
+		&settlement.Status,
+		&settlement.AcknowledgedAt,
+		&settlement.DisputedAt,
+		&settlement.DisputeReason,
+		&settlement.CreatedAt,
+		&settlement.UpdatedAt,
+		&settlement.VoidedAt,
+		&settlement.VoidedByUserID,
+		&settlement.VoidReason,
+		&settlement.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// scanSettlementRevision scans a row selected with settlementRevisionColumns
+func scanSettlementRevision(row pgx.Row) (*models.SettlementRevision, error) {
+	revision := &models.SettlementRevision{}
+	err := row.Scan(
+		&revision.ID,
+		&revision.SettlementID,
+		&revision.Action,
+		&revision.ChangedByUserID,
+		&revision.Reason,
+		&revision.AmountBefore,
+		&revision.AmountAfter,
+		&revision.DateBefore,
+		&revision.DateAfter,
+		&revision.NoteBefore,
+		&revision.NoteAfter,
+		&revision.MethodBefore,
+		&revision.MethodAfter,
+		&revision.ExternalReferenceBefore,
+		&revision.ExternalReferenceAfter,
+		&revision.CategoryIDBefore,
+		&revision.CategoryIDAfter,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// collectSettlements scans and closes rows selected with settlementColumns
+func collectSettlements(rows pgx.Rows) ([]*models.Settlement, error) {
 	defer rows.Close()
 
 	var settlements []*models.Settlement
 	for rows.Next() {
-		settlement := &models.Settlement{}
-		if err := rows.Scan(
-			&settlement.ID,
-			&settlement.GroupID,
-			&settlement.UserID,
-			&settlement.Amount,
-			&settlement.Date,
-			&settlement.Note,
-			&settlement.CreatedAt,
-		); err != nil {
+		settlement, err := scanSettlement(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan settlement: %w", err)
 		}
 		settlements = append(settlements, settlement)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read settlements: %w", err)
+	}
 
 	return settlements, nil
 }
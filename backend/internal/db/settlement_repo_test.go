@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestAllocateSettlement(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	unpaid := []unpaidEntry{{ID: a, Unpaid: 2.5}, {ID: b, Unpaid: 1.1}, {ID: c, Unpaid: 4}}
+
+	// Oldest entries are paid first, the last one only in part
+	links, err := allocateSettlement(4.6, unpaid, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.SettlementEntry{{EntryID: a, Amount: 2.5}, {EntryID: b, Amount: 1.1}, {EntryID: c, Amount: 1}}, links)
+
+	// Named entries jump the queue, once each
+	links, err = allocateSettlement(5, unpaid, []uuid.UUID{c, c})
+	require.NoError(t, err)
+	assert.Equal(t, []models.SettlementEntry{{EntryID: c, Amount: 4}, {EntryID: a, Amount: 1}}, links)
+
+	// Anything beyond what is owed stays unlinked
+	links, err = allocateSettlement(10, unpaid, nil)
+	require.NoError(t, err)
+	assert.Len(t, links, 3)
+
+	links, err = allocateSettlement(1, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	stranger := uuid.New()
+	_, err = allocateSettlement(1, unpaid, []uuid.UUID{stranger})
+	assert.ErrorIs(t, err, ErrEntryNotPayable)
+	assert.Contains(t, err.Error(), stranger.String())
+}
@@ -112,6 +112,37 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User,
 	return user, nil
 }
 
+// FindIDsByEmail returns the IDs of users whose email matches, case-insensitively,
+// keyed by lowercased email
+func (r *UserRepo) FindIDsByEmail(ctx context.Context, emails []string) (map[string]uuid.UUID, error) {
+	query := `
+		SELECT lower(email), id
+		FROM users
+		WHERE lower(email) = ANY($1)
+	`
+
+	rows, err := r.pool.Query(ctx, query, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users by email: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[string]uuid.UUID, len(emails))
+	for rows.Next() {
+		var email string
+		var id uuid.UUID
+		if err := rows.Scan(&email, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		ids[email] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	return ids, nil
+}
+
 // isDuplicateKeyError checks if the error is a duplicate key violation
 func isDuplicateKeyError(err error) bool {
 	return err != nil && (contains(err.Error(), "duplicate key") || contains(err.Error(), "23505"))
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/webhook"
+)
+
+// WebhookRepo handles database operations for webhooks and their delivery outbox
+type WebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookRepo creates a new WebhookRepo
+func NewWebhookRepo(pool *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{pool: pool}
+}
+
+const webhookColumns = `id, group_id, url, secret, event_types, active, created_by_user_id, created_at`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	w := &models.Webhook{}
+	err := row.Scan(&w.ID, &w.GroupID, &w.URL, &w.Secret, &w.EventTypes, &w.Active, &w.CreatedByUserID, &w.CreatedAt)
+	return w, err
+}
+
+// Create inserts a new webhook
+func (r *WebhookRepo) Create(ctx context.Context, groupID uuid.UUID, url, secret string, eventTypes []string, createdByUserID uuid.UUID) (*models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (group_id, url, secret, event_types, created_by_user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + webhookColumns
+
+	w, err := scanWebhook(r.pool.QueryRow(ctx, query, groupID, url, secret, eventTypes, createdByUserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return w, nil
+}
+
+// GetByID retrieves a webhook by ID
+func (r *WebhookRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	w, err := scanWebhook(r.pool.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook by id: %w", err)
+	}
+	return w, nil
+}
+
+// ListForGroup retrieves all webhooks for a group
+func (r *WebhookRepo) ListForGroup(ctx context.Context, groupID uuid.UUID) ([]*models.Webhook, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE group_id = $1 ORDER BY created_at`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// Update changes the given fields of a webhook; nil fields are left unchanged
+func (r *WebhookRepo) Update(ctx context.Context, id uuid.UUID, url, secret *string, eventTypes []string, active *bool) (*models.Webhook, error) {
+	query := `
+		UPDATE webhooks
+		SET url = COALESCE($2, url),
+		    secret = COALESCE($3, secret),
+		    event_types = COALESCE($4, event_types),
+		    active = COALESCE($5, active)
+		WHERE id = $1
+		RETURNING ` + webhookColumns
+
+	w, err := scanWebhook(r.pool.QueryRow(ctx, query, id, url, secret, eventTypes, active))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return w, nil
+}
+
+// Delete deletes a webhook and its delivery log
+func (r *WebhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Enqueue queues a delivery of the event to every active webhook in its
+// group that subscribes to its type, and returns how many were queued
+func (r *WebhookRepo) Enqueue(ctx context.Context, e events.Event) (int, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	result, err := r.pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4
+		FROM webhooks
+		WHERE group_id = $1 AND active AND $3 = ANY(event_types)
+	`, e.GroupID, e.ID, string(e.Type), string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// ListDeliveries returns a webhook's most recent deliveries, newest first,
+// optionally limited to one status
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID uuid.UUID, status *models.DeliveryStatus, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+		       last_attempt_at, response_status, last_error, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text IS NULL OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3
+	`, webhookID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// ClaimDue returns up to limit due pending deliveries and pushes their next
+// attempt back by lease, so concurrent dispatchers skip them while they are
+// being sent. Deliveries to webhooks that have since been deactivated stay
+// queued until the webhook is active again.
+func (r *WebhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			INNER JOIN webhooks w ON d.webhook_id = w.id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + $2 * interval '1 second'
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_type, d.payload, d.attempts
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*webhook.Delivery
+	for rows.Next() {
+		d := &webhook.Delivery{}
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.EventType, &payload, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt saves the outcome of a delivery attempt
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, id uuid.UUID, result webhook.Result) error {
+	status := models.DeliveryPending
+	switch {
+	case result.Delivered:
+		status = models.DeliveryDelivered
+	case result.NextAttemptAt == nil:
+		status = models.DeliveryFailed
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2,
+		    attempts = $3,
+		    last_attempt_at = now(),
+		    next_attempt_at = COALESCE($4, next_attempt_at),
+		    response_status = $5,
+		    last_error = $6,
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN now() END
+		WHERE id = $1
+	`, id, status, result.Attempts, result.NextAttemptAt, result.ResponseStatus, result.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
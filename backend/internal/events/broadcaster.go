@@ -0,0 +1,90 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Record is a stored event with its position in its group's event log. Payload is
+// the event's JSON encoding.
+type Record struct {
+	Seq     int64
+	Type    Type
+	GroupID uuid.UUID
+	Payload []byte
+}
+
+// subscriberBuffer is how many records a subscriber may fall behind before
+// it is dropped
+const subscriberBuffer = 64
+
+// Broadcaster fans stored events out to the clients connected to this
+// process, per group
+type Broadcaster struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[uuid.UUID]map[int]chan Record
+}
+
+// NewBroadcaster creates an empty Broadcaster
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: make(map[uuid.UUID]map[int]chan Record)}
+}
+
+// Subscribe returns a channel of the group's records published from now on
+// and a function that unsubscribes. The channel is closed when the
+// subscriber unsubscribes or falls too far behind; a dropped client should
+// reconnect and resume from the last record it saw.
+func (b *Broadcaster) Subscribe(groupID uuid.UUID) (<-chan Record, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Record, subscriberBuffer)
+	if b.subs[groupID] == nil {
+		b.subs[groupID] = make(map[int]chan Record)
+	}
+	b.subs[groupID][id] = ch
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(groupID, id)
+	}
+}
+
+// Publish sends rec to every subscriber of its group without blocking
+func (b *Broadcaster) Publish(rec Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, ch := range b.subs[rec.GroupID] {
+		select {
+		case ch <- rec:
+		default:
+			b.remove(rec.GroupID, id)
+		}
+	}
+}
+
+// Subscribers returns how many clients are subscribed to a group
+func (b *Broadcaster) Subscribers(groupID uuid.UUID) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[groupID])
+}
+
+// remove closes and forgets a subscription; b.mu must be held
+func (b *Broadcaster) remove(groupID uuid.UUID, id int) {
+	ch, ok := b.subs[groupID][id]
+	if !ok {
+		return
+	}
+	close(ch)
+	delete(b.subs[groupID], id)
+	if len(b.subs[groupID]) == 0 {
+		delete(b.subs, groupID)
+	}
+}
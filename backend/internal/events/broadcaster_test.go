@@ -0,0 +1,51 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcasterFansOutPerGroup(t *testing.T) {
+	b := NewBroadcaster()
+	group, other := uuid.New(), uuid.New()
+
+	first, unsubFirst := b.Subscribe(group)
+	second, unsubSecond := b.Subscribe(group)
+	elsewhere, unsubElsewhere := b.Subscribe(other)
+	defer unsubSecond()
+	defer unsubElsewhere()
+
+	b.Publish(Record{Seq: 1, Type: LedgerCreated, GroupID: group})
+
+	assert.Equal(t, int64(1), (<-first).Seq)
+	assert.Equal(t, int64(1), (<-second).Seq)
+	assert.Empty(t, elsewhere)
+
+	unsubFirst()
+	_, open := <-first
+	assert.False(t, open)
+	assert.Equal(t, 1, b.Subscribers(group))
+
+	// Unsubscribing twice is harmless
+	assert.NotPanics(t, unsubFirst)
+}
+
+func TestBroadcasterDropsSlowSubscribers(t *testing.T) {
+	b := NewBroadcaster()
+	group := uuid.New()
+	ch, unsub := b.Subscribe(group)
+	defer unsub()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Record{Seq: int64(i + 1), GroupID: group})
+	}
+
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+	assert.Zero(t, b.Subscribers(group))
+}
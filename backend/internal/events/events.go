@@ -0,0 +1,120 @@
+// Package events defines the events published when group data changes and
+// a bus that fans them out to subscribers such as webhooks.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	LedgerCreated          Type = "ledger.created"
+	LedgerApproved         Type = "ledger.approved"
+	LedgerRejected         Type = "ledger.rejected"
+	LedgerCommented        Type = "ledger.commented"
+	SettlementCreated      Type = "settlement.created"
+	SettlementUpdated      Type = "settlement.updated"
+	SettlementVoided       Type = "settlement.voided"
+	SettlementAcknowledged Type = "settlement.acknowledged"
+	SettlementDisputed     Type = "settlement.disputed"
+	ChoreCreated           Type = "chore.created"
+	ChoreUpdated           Type = "chore.updated"
+	ChoreDeleted           Type = "chore.deleted"
+	MemberJoined           Type = "member.joined"
+	GoalReached            Type = "goal.reached"
+)
+
+// Types lists every event type
+var Types = []Type{
+	LedgerCreated, LedgerApproved, LedgerRejected, LedgerCommented,
+	SettlementCreated, SettlementUpdated, SettlementVoided, SettlementAcknowledged, SettlementDisputed,
+	ChoreCreated, ChoreUpdated, ChoreDeleted,
+	MemberJoined,
+	GoalReached,
+}
+
+// Valid reports whether t is a known event type
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is something that happened in a group. Data is the affected
+// resource as returned by the API.
+type Event struct {
+	ID          uuid.UUID  `json:"id"`
+	Type        Type       `json:"type"`
+	GroupID     uuid.UUID  `json:"group_id"`
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty"`
+	OccurredAt  time.Time  `json:"occurred_at"`
+	Data        any        `json:"data"`
+}
+
+// New creates an event with a fresh ID stamped with the current time
+func New(t Type, groupID uuid.UUID, actorUserID *uuid.UUID, data any) Event {
+	return Event{
+		ID:          uuid.New(),
+		Type:        t,
+		GroupID:     groupID,
+		ActorUserID: actorUserID,
+		OccurredAt:  time.Now().UTC(),
+		Data:        data,
+	}
+}
+
+// Subscriber handles a published event
+type Subscriber func(ctx context.Context, e Event) error
+
+type subscription struct {
+	name string
+	fn   Subscriber
+}
+
+// Bus delivers published events to every subscriber in turn
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []subscription
+}
+
+// NewBus creates an empty Bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers fn to receive every event published after this call
+func (b *Bus) Subscribe(name string, fn Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscription{name: name, fn: fn})
+}
+
+// Publish hands e to each subscriber. Subscribers run even if the request
+// that caused the event is cancelled, and a failing subscriber is logged
+// without affecting the others or the caller. A nil Bus discards events.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		if err := s.fn(ctx, e); err != nil {
+			log.Printf("events: %s failed to handle %s %s: %v", s.name, e.Type, e.ID, err)
+		}
+	}
+}
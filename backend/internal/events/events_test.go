@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeValid(t *testing.T) {
+	assert.True(t, LedgerApproved.Valid())
+	assert.False(t, Type("ledger.deleted").Valid())
+}
+
+func TestBusPublish(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe("failing", func(ctx context.Context, e Event) error {
+		got = append(got, "failing")
+		return errors.New("boom")
+	})
+	bus.Subscribe("recorder", func(ctx context.Context, e Event) error {
+		assert.NoError(t, ctx.Err())
+		got = append(got, string(e.Type))
+		return nil
+	})
+
+	// A cancelled request context does not reach subscribers
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bus.Publish(ctx, New(SettlementCreated, uuid.New(), nil, nil))
+
+	assert.Equal(t, []string{"failing", "settlement.created"}, got)
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() {
+		bus.Publish(context.Background(), New(LedgerCreated, uuid.New(), nil, nil))
+	})
+}
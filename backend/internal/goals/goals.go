@@ -0,0 +1,99 @@
+// Package goals works out how far a member is towards a savings goal and
+// when they will reach it at their recent earning rate.
+package goals
+
+import (
+	"math"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+const (
+	// DefaultWeeks is how many weeks of earnings a projection uses by default
+	DefaultWeeks = 8
+	// MaxWeeks is the longest earning history a projection can use
+	MaxWeeks = 52
+)
+
+// Progress is how far a member is towards a goal
+type Progress struct {
+	Saved     float64 `json:"saved"`
+	Remaining float64 `json:"remaining"`
+	Percent   float64 `json:"percent"` // Capped at 100
+	Reached   bool    `json:"reached"`
+	// WeeklyRate is the member's average approved earnings per week over
+	// the last Weeks weeks
+	WeeklyRate float64 `json:"weekly_rate"`
+	Weeks      int     `json:"weeks"`
+	// ProjectedDate is when the goal is reached at WeeklyRate; nil once
+	// reached or if the member is not earning
+	ProjectedDate *time.Time `json:"projected_date,omitempty"`
+	// OnTrack reports whether the goal is reached or projected to be by its
+	// deadline; nil without a deadline
+	OnTrack *bool `json:"on_track,omitempty"`
+}
+
+// Saved returns what counts towards a goal: its allocations, or the
+// member's balance when it tracks the balance. Debt never counts.
+func Saved(g *models.Goal, balance float64) float64 {
+	saved := balance
+	if g.Source == models.GoalFromAllocations {
+		saved = g.Allocated
+	}
+	return max(roundCents(saved), 0)
+}
+
+// WeeklyRate returns the average per week of the approved entries that
+// occurred in the weeks before now. Advances are left out: they are lent,
+// not earned, though the repayments on them count against the rate.
+func WeeklyRate(entries []*models.LedgerEntry, weeks int) float64 {
+	if weeks <= 0 {
+		return 0
+	}
+	var total float64
+	for _, e := range entries {
+		if e.Status == models.StatusApproved && e.Kind != models.EntryLoan {
+			total += e.Amount
+		}
+	}
+	return roundCents(total / float64(weeks))
+}
+
+// Compute returns a goal's progress with saved counting towards it, projecting
+// its completion from a weekly rate over weeks. Projected dates are whole
+// days from today, rounded up.
+func Compute(g *models.Goal, saved, weeklyRate float64, weeks int, now time.Time) Progress {
+	p := Progress{
+		Saved:      saved,
+		Remaining:  max(roundCents(g.TargetAmount-saved), 0),
+		WeeklyRate: weeklyRate,
+		Weeks:      weeks,
+	}
+	p.Reached = p.Remaining == 0
+	if g.TargetAmount > 0 {
+		p.Percent = min(math.Floor(saved/g.TargetAmount*1000)/10, 100)
+	}
+
+	if !p.Reached && weeklyRate > 0 {
+		days := int(math.Ceil(p.Remaining / weeklyRate * 7))
+		projected := day(now).AddDate(0, 0, days)
+		p.ProjectedDate = &projected
+	}
+
+	if g.Deadline != nil {
+		onTrack := p.Reached || (p.ProjectedDate != nil && !p.ProjectedDate.After(day(*g.Deadline)))
+		p.OnTrack = &onTrack
+	}
+	return p
+}
+
+// day returns midnight UTC on t's UTC date
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,80 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestSaved(t *testing.T) {
+	g := &models.Goal{TargetAmount: 100, Source: models.GoalFromBalance, Allocated: 15}
+	assert.Equal(t, 42.5, Saved(g, 42.5))
+	assert.Equal(t, 0.0, Saved(g, -3), "debt does not count")
+
+	g.Source = models.GoalFromAllocations
+	assert.Equal(t, 15.0, Saved(g, 42.5))
+}
+
+func TestWeeklyRate(t *testing.T) {
+	entries := []*models.LedgerEntry{
+		{Amount: 10, Status: models.StatusApproved},
+		{Amount: 6, Status: models.StatusApproved},
+		{Amount: 50, Status: models.StatusPendingApproval},
+		{Amount: 100, Status: models.StatusApproved, Kind: models.EntryLoan},
+		{Amount: -4, Status: models.StatusApproved, Kind: models.EntryRepayment},
+		{Amount: 4, Status: models.StatusApproved},
+	}
+	assert.Equal(t, 4.0, WeeklyRate(entries, 4))
+	assert.Equal(t, 5.33, WeeklyRate(entries, 3))
+	assert.Equal(t, 0.0, WeeklyRate(nil, 8))
+	assert.Equal(t, 0.0, WeeklyRate(entries, 0))
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	deadline := date(2026, 12, 1)
+	g := &models.Goal{TargetAmount: 120, Deadline: &deadline}
+
+	// 80 left at 10 a week is 8 weeks, 56 days from today
+	p := Compute(g, 40, 10, 8, now)
+	assert.Equal(t, 40.0, p.Saved)
+	assert.Equal(t, 80.0, p.Remaining)
+	assert.Equal(t, 33.3, p.Percent)
+	assert.False(t, p.Reached)
+	assert.Equal(t, 8, p.Weeks)
+	require.NotNil(t, p.ProjectedDate)
+	assert.Equal(t, date(2026, 12, 13), *p.ProjectedDate)
+	require.NotNil(t, p.OnTrack)
+	assert.False(t, *p.OnTrack)
+
+	// Partial days round up
+	p = Compute(g, 40, 15, 8, now)
+	assert.Equal(t, date(2026, 11, 25), *p.ProjectedDate)
+	assert.True(t, *p.OnTrack)
+
+	// No earnings means no projection
+	p = Compute(g, 40, 0, 8, now)
+	assert.Nil(t, p.ProjectedDate)
+	assert.False(t, *p.OnTrack)
+
+	// Saving more than the target caps progress
+	p = Compute(g, 150, 10, 8, now)
+	assert.True(t, p.Reached)
+	assert.Equal(t, 0.0, p.Remaining)
+	assert.Equal(t, 100.0, p.Percent)
+	assert.Nil(t, p.ProjectedDate)
+	assert.True(t, *p.OnTrack)
+
+	// Without a deadline there is nothing to be on track for
+	g.Deadline = nil
+	p = Compute(g, 40, 10, 8, now)
+	assert.Nil(t, p.OnTrack)
+}
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// AllowanceHandler handles allowance requests
+type AllowanceHandler struct {
+	allowanceRepo *db.AllowanceRepo
+	groupRepo     *db.GroupRepo
+	events        *events.Bus
+}
+
+// NewAllowanceHandler creates a new AllowanceHandler
+func NewAllowanceHandler(allowanceRepo *db.AllowanceRepo, groupRepo *db.GroupRepo, bus *events.Bus) *AllowanceHandler {
+	return &AllowanceHandler{
+		allowanceRepo: allowanceRepo,
+		groupRepo:     groupRepo,
+		events:        bus,
+	}
+}
+
+// CreateAllowanceRequest represents the request body for creating an
+// allowance. StartDate defaults to today.
+type CreateAllowanceRequest struct {
+	UserID        uuid.UUID                `json:"user_id" binding:"required"`
+	Amount        float64                  `json:"amount" binding:"required,gt=0"`
+	AmountPerYear *float64                 `json:"amount_per_year"` // Paid per year of the member's age instead, when their dob is known
+	Interval      models.AllowanceInterval `json:"interval" binding:"required"`
+	StartDate     *string                  `json:"start_date"` // YYYY-MM-DD format
+}
+
+// UpdateAllowanceRequest represents the request body for changing an
+// allowance's amounts
+type UpdateAllowanceRequest struct {
+	Amount        *float64      `json:"amount" binding:"omitempty,gt=0"`
+	AmountPerYear NullableFloat `json:"amount_per_year"` // null pays the fixed amount
+}
+
+// AllowanceResponse represents an allowance in API responses
+type AllowanceResponse struct {
+	ID              uuid.UUID                `json:"id"`
+	GroupID         uuid.UUID                `json:"group_id"`
+	UserID          uuid.UUID                `json:"user_id"`
+	Amount          float64                  `json:"amount"`
+	AmountPerYear   *float64                 `json:"amount_per_year,omitempty"`
+	Interval        models.AllowanceInterval `json:"interval"`
+	StartDate       time.Time                `json:"start_date"`
+	NextPeriod      time.Time                `json:"next_period"`
+	PausedAt        *time.Time               `json:"paused_at,omitempty"`
+	CreatedByUserID uuid.UUID                `json:"created_by_user_id"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+	DeletedAt       *time.Time               `json:"deleted_at,omitempty"`
+}
+
+func newAllowanceResponse(a *models.Allowance) AllowanceResponse {
+	return AllowanceResponse{
+		ID:              a.ID,
+		GroupID:         a.GroupID,
+		UserID:          a.UserID,
+		Amount:          a.Amount,
+		AmountPerYear:   a.AmountPerYear,
+		Interval:        a.Interval,
+		StartDate:       a.StartDate,
+		NextPeriod:      a.NextPeriod,
+		PausedAt:        a.PausedAt,
+		CreatedByUserID: a.CreatedByUserID,
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
+		DeletedAt:       a.DeletedAt,
+	}
+}
+
+// allowanceStart validates an allowance's interval and start date. A missing
+// start date is today; past dates are refused so creating an allowance never
+// credits periods retroactively.
+func allowanceStart(interval models.AllowanceInterval, startDate *string, now time.Time) (time.Time, error) {
+	switch interval {
+	case models.IntervalWeek, models.IntervalMonth:
+	default:
+		return time.Time{}, errors.New("interval must be week or month")
+	}
+
+	today := allowance.Day(now)
+	if startDate == nil {
+		return today, nil
+	}
+	start, err := time.Parse("2006-01-02", *startDate)
+	if err != nil {
+		return time.Time{}, errors.New("invalid start_date format, use YYYY-MM-DD")
+	}
+	if start.Before(today) {
+		return time.Time{}, errors.New("start_date cannot be in the past")
+	}
+	return start, nil
+}
+
+// ListAllowances returns a group's allowances, optionally for one member
+// with user_id; deleted ones are included with include_deleted=true
+// GET /api/v1/groups/:id/allowances
+func (h *AllowanceHandler) ListAllowances(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	memberID, err := queryUUID(c, "user_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includeDeleted, err := queryBool(c, "include_deleted")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowances, err := h.allowanceRepo.ListForGroup(c.Request.Context(), groupID, memberID, includeDeleted != nil && *includeDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list allowances"})
+		return
+	}
+
+	response := make([]AllowanceResponse, 0, len(allowances))
+	for _, a := range allowances {
+		response = append(response, newAllowanceResponse(a))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateAllowance sets up a recurring allowance for a member (head only)
+// POST /api/v1/groups/:id/allowances
+func (h *AllowanceHandler) CreateAllowance(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can create allowances"})
+		return
+	}
+
+	var req CreateAllowanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, err := allowanceStart(req.Interval, req.StartDate, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.AmountPerYear != nil && *req.AmountPerYear <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount_per_year must be positive"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, req.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target user is not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check target membership"})
+		return
+	}
+
+	created, err := h.allowanceRepo.Create(c.Request.Context(), &models.Allowance{
+		GroupID:         groupID,
+		UserID:          req.UserID,
+		Amount:          req.Amount,
+		AmountPerYear:   req.AmountPerYear,
+		Interval:        req.Interval,
+		StartDate:       start,
+		CreatedByUserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create allowance"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newAllowanceResponse(created))
+}
+
+// UpdateAllowance changes an allowance's amounts for periods not yet
+// credited (head only)
+// PATCH /api/v1/groups/:id/allowances/:allowance_id
+func (h *AllowanceHandler) UpdateAllowance(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	allowanceID, err := uuid.Parse(c.Param("allowance_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allowance ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can update allowances"})
+		return
+	}
+
+	var req UpdateAllowanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.AmountPerYear.Value != nil && *req.AmountPerYear.Value <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount_per_year must be positive"})
+		return
+	}
+
+	current, err := h.allowanceRepo.GetByID(c.Request.Context(), allowanceID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get allowance"})
+		return
+	}
+	if err != nil || current.GroupID != groupID || current.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+		return
+	}
+
+	updated, err := h.allowanceRepo.Update(c.Request.Context(), allowanceID, db.AllowanceUpdate{
+		Amount:           req.Amount,
+		SetAmountPerYear: req.AmountPerYear.Set,
+		AmountPerYear:    req.AmountPerYear.Value,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update allowance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newAllowanceResponse(updated))
+}
+
+// PauseAllowance stops an allowance crediting new periods until it is
+// resumed; periods that already began are credited first (head only)
+// POST /api/v1/groups/:id/allowances/:allowance_id/pause
+func (h *AllowanceHandler) PauseAllowance(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// ResumeAllowance restarts a paused allowance from the next period
+// starting today or later (head only)
+// POST /api/v1/groups/:id/allowances/:allowance_id/resume
+func (h *AllowanceHandler) ResumeAllowance(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+// setPaused pauses or resumes an allowance for PauseAllowance and ResumeAllowance
+func (h *AllowanceHandler) setPaused(c *gin.Context, pause bool) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	allowanceID, err := uuid.Parse(c.Param("allowance_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allowance ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can pause or resume allowances"})
+		return
+	}
+
+	current, err := h.allowanceRepo.GetByID(c.Request.Context(), allowanceID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get allowance"})
+		return
+	}
+	if err != nil || current.GroupID != groupID || current.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+		return
+	}
+
+	var updated *models.Allowance
+	var credited []*models.LedgerEntry
+	if pause {
+		updated, credited, err = h.allowanceRepo.Pause(c.Request.Context(), allowanceID, time.Now())
+	} else {
+		updated, err = h.allowanceRepo.Resume(c.Request.Context(), allowanceID, time.Now())
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+		case errors.Is(err, db.ErrAllowancePaused), errors.Is(err, db.ErrAllowanceNotPaused):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update allowance"})
+		}
+		return
+	}
+
+	// Periods credited on the way to pausing are announced like the scheduler's
+	for _, entry := range credited {
+		h.events.Publish(c.Request.Context(), events.New(events.LedgerCreated, groupID, nil, newLedgerResponse(entry)))
+	}
+
+	c.JSON(http.StatusOK, newAllowanceResponse(updated))
+}
+
+// DeleteAllowance stops an allowance for good; entries it credited are
+// kept (head only)
+// DELETE /api/v1/groups/:id/allowances/:allowance_id
+func (h *AllowanceHandler) DeleteAllowance(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	allowanceID, err := uuid.Parse(c.Param("allowance_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allowance ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can delete allowances"})
+		return
+	}
+
+	current, err := h.allowanceRepo.GetByID(c.Request.Context(), allowanceID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get allowance"})
+		return
+	}
+	if err != nil || current.GroupID != groupID || current.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+		return
+	}
+
+	if err := h.allowanceRepo.Delete(c.Request.Context(), allowanceID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "allowance not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete allowance"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
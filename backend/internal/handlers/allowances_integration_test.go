@@ -0,0 +1,83 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestAllowance_PostDueAndPause(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	ctx := context.Background()
+
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/allowances", groupID), headToken, map[string]any{
+		"user_id":  kid,
+		"amount":   5,
+		"interval": models.IntervalWeek,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	a := decode[handlers.AllowanceResponse](t, w)
+	start := a.StartDate
+	day := func(n int) time.Time { return start.AddDate(0, 0, n) }
+
+	// The first period is credited once, however often the job runs
+	entries, err := app.allowances.PostDue(ctx, day(0).Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.EntryAllowance, entries[0].Kind)
+	assert.Equal(t, 5.0, entries[0].Amount)
+	entries, err = app.allowances.PostDue(ctx, day(0).Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Periods missed while the server was down are caught up, each on its own day
+	entries, err = app.allowances.PostDue(ctx, day(15))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.True(t, day(7).Equal(entries[0].OccurredAt))
+	assert.True(t, day(14).Equal(entries[1].OccurredAt))
+
+	path := fmt.Sprintf("/api/v1/groups/%s/allowances/%s", groupID, a.ID)
+	w = app.do(http.MethodPost, path+"/pause", kidToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = app.do(http.MethodPost, path+"/pause", headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotNil(t, decode[handlers.AllowanceResponse](t, w).PausedAt)
+	w = app.do(http.MethodPost, path+"/pause", headToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Nothing is credited while paused, and those periods are skipped on resuming
+	entries, err = app.allowances.PostDue(ctx, day(30))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	resumed, err := app.allowances.Resume(ctx, a.ID, day(30))
+	require.NoError(t, err)
+	assert.True(t, day(35).Equal(resumed.NextPeriod))
+	w = app.do(http.MethodPost, path+"/resume", headToken, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	entries, err = app.allowances.PostDue(ctx, day(36))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, day(35).Equal(entries[0].OccurredAt))
+
+	assert.Equal(t, 20.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 4, report.Links)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestAllowanceStart(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	start, err := allowanceStart(models.IntervalWeek, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, today, start)
+
+	date := "2026-10-18"
+	start, err = allowanceStart(models.IntervalMonth, &date, now)
+	require.NoError(t, err)
+	assert.Equal(t, today, start)
+
+	date = "2026-11-01"
+	start, err = allowanceStart(models.IntervalMonth, &date, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), start)
+
+	date = "2026-10-17"
+	_, err = allowanceStart(models.IntervalWeek, &date, now)
+	assert.EqualError(t, err, "start_date cannot be in the past")
+
+	date = "18/10/2026"
+	_, err = allowanceStart(models.IntervalWeek, &date, now)
+	assert.EqualError(t, err, "invalid start_date format, use YYYY-MM-DD")
+
+	_, err = allowanceStart("fortnight", nil, now)
+	assert.EqualError(t, err, "interval must be week or month")
+}
+
+func TestInterestRateStart(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+
+	start, err := interestRateStart(models.IntervalMonth, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), start)
+
+	date := "2026-10-17"
+	_, err = interestRateStart(models.IntervalWeek, &date, now)
+	assert.EqualError(t, err, "start_date cannot be in the past")
+
+	_, err = interestRateStart("day", nil, now)
+	assert.EqualError(t, err, "compounding must be week or month")
+}
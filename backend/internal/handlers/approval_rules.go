@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ApprovalRuleHandler handles approval rule requests
+type ApprovalRuleHandler struct {
+	ruleRepo  *db.ApprovalRuleRepo
+	groupRepo *db.GroupRepo
+	choreRepo *db.ChoreRepo
+}
+
+// NewApprovalRuleHandler creates a new ApprovalRuleHandler
+func NewApprovalRuleHandler(ruleRepo *db.ApprovalRuleRepo, groupRepo *db.GroupRepo, choreRepo *db.ChoreRepo) *ApprovalRuleHandler {
+	return &ApprovalRuleHandler{
+		ruleRepo:  ruleRepo,
+		groupRepo: groupRepo,
+		choreRepo: choreRepo,
+	}
+}
+
+// CreateApprovalRuleRequest represents the request body for creating an approval rule.
+// Omitted conditions match anything.
+type CreateApprovalRuleRequest struct {
+	ChoreID   *uuid.UUID `json:"chore_id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	MaxPerDay *int       `json:"max_per_day" binding:"omitempty,min=1"`
+}
+
+// ApprovalRuleResponse represents an approval rule in API responses
+type ApprovalRuleResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	GroupID         uuid.UUID  `json:"group_id"`
+	ChoreID         *uuid.UUID `json:"chore_id"`
+	UserID          *uuid.UUID `json:"user_id"`
+	MaxPerDay       *int       `json:"max_per_day"`
+	CreatedByUserID uuid.UUID  `json:"created_by_user_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func newApprovalRuleResponse(r *models.ApprovalRule) ApprovalRuleResponse {
+	return ApprovalRuleResponse{
+		ID:              r.ID,
+		GroupID:         r.GroupID,
+		ChoreID:         r.ChoreID,
+		UserID:          r.UserID,
+		MaxPerDay:       r.MaxPerDay,
+		CreatedByUserID: r.CreatedByUserID,
+		CreatedAt:       r.CreatedAt,
+	}
+}
+
+// ListApprovalRules returns a group's approval rules (head only)
+// GET /api/v1/groups/:id/approval-rules
+func (h *ApprovalRuleHandler) ListApprovalRules(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can view approval rules"})
+		return
+	}
+
+	rules, err := h.ruleRepo.ListForGroup(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list approval rules"})
+		return
+	}
+
+	response := make([]ApprovalRuleResponse, 0, len(rules))
+	for _, r := range rules {
+		response = append(response, newApprovalRuleResponse(r))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateApprovalRule adds a rule that auto-approves matching entries (head only)
+// POST /api/v1/groups/:id/approval-rules
+func (h *ApprovalRuleHandler) CreateApprovalRule(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can create approval rules"})
+		return
+	}
+
+	var req CreateApprovalRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ChoreID != nil {
+		chore, err := h.choreRepo.GetByID(c.Request.Context(), *req.ChoreID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "chore not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get chore"})
+			return
+		}
+		if chore.GroupID != groupID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chore does not belong to this group"})
+			return
+		}
+		if chore.DeletedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chore has been archived"})
+			return
+		}
+	}
+
+	if req.UserID != nil {
+		_, err := h.groupRepo.GetMember(c.Request.Context(), groupID, *req.UserID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "target user is not a member of this group"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check target membership"})
+			return
+		}
+	}
+
+	rule, err := h.ruleRepo.Create(c.Request.Context(), groupID, req.ChoreID, req.UserID, req.MaxPerDay, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create approval rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newApprovalRuleResponse(rule))
+}
+
+// DeleteApprovalRule stops a rule from approving entries (head only)
+// DELETE /api/v1/groups/:id/approval-rules/:rule_id
+func (h *ApprovalRuleHandler) DeleteApprovalRule(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can delete approval rules"})
+		return
+	}
+
+	rule, err := h.ruleRepo.GetByID(c.Request.Context(), ruleID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get approval rule"})
+		return
+	}
+	if err != nil || rule.GroupID != groupID || rule.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "approval rule not found"})
+		return
+	}
+
+	if err := h.ruleRepo.Delete(c.Request.Context(), ruleID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "approval rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete approval rule"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/archive"
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// maxImportSize bounds the size of an uploaded group archive
+const maxImportSize = 128 << 20
+
+// ArchiveHandler handles group export and import requests
+type ArchiveHandler struct {
+	archiveRepo *db.ArchiveRepo
+	groupRepo   *db.GroupRepo
+	userRepo    *db.UserRepo
+	admins      map[string]bool
+}
+
+// NewArchiveHandler creates a new ArchiveHandler. The users with adminEmails
+// are instance admins, who may import archives that add other accounts.
+func NewArchiveHandler(archiveRepo *db.ArchiveRepo, groupRepo *db.GroupRepo, userRepo *db.UserRepo, adminEmails []string) *ArchiveHandler {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		admins[archive.NormalizeEmail(email)] = true
+	}
+	return &ArchiveHandler{
+		archiveRepo: archiveRepo,
+		groupRepo:   groupRepo,
+		userRepo:    userRepo,
+		admins:      admins,
+	}
+}
+
+// isAdmin reports whether the user is an instance admin
+func (h *ArchiveHandler) isAdmin(c *gin.Context, userID uuid.UUID) (bool, error) {
+	if len(h.admins) == 0 {
+		return false, nil
+	}
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	return h.admins[archive.NormalizeEmail(user.Email)], nil
+}
+
+// ExportGroup downloads a versioned archive of a group as JSON or ZIP (head only)
+// GET /api/v1/groups/:id/export
+func (h *ArchiveHandler) ExportGroup(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	// Check if user is head of the group
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can export the group"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use json or zip"})
+		return
+	}
+
+	a, err := h.archiveRepo.Export(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export group"})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if format == "zip" {
+		contentType = "application/zip"
+		err = archive.WriteZip(&buf, a)
+	} else {
+		err = archive.WriteJSON(&buf, a)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode archive"})
+		return
+	}
+
+	filename := fmt.Sprintf("pocket-money-%s-%s.%s", groupID, a.ExportedAt.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// ImportGroup recreates a group from an exported archive, mapping members to
+// local users by email. The body is the JSON or ZIP archive; dry_run=true
+// reports what would happen without keeping any changes, and each
+// map=old@example.com=new@example.com remaps an archived email. Archives with
+// members besides the importing user need an instance admin.
+// POST /api/v1/groups/import
+func (h *ArchiveHandler) ImportGroup(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+			return
+		}
+	}
+
+	emailMap, err := parseEmailMap(c.QueryArray("map"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "archive is too large"})
+		return
+	}
+
+	a, err := archive.Decode(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := &archive.Report{
+		DryRun:    dryRun,
+		GroupName: a.Group.Name,
+		Users:     []archive.UserMatch{},
+		Errors:    append([]string{}, a.Validate()...),
+		Warnings:  []string{},
+	}
+	if !report.Valid() {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	// Match archived members to local users by email
+	targets := a.TargetEmails(emailMap)
+	emails := make([]string, 0, len(targets))
+	for _, email := range targets {
+		emails = append(emails, email)
+	}
+	found, err := h.userRepo.FindIDsByEmail(c.Request.Context(), emails)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to match users"})
+		return
+	}
+	users := a.ResolveUsers(targets, found, report)
+
+	// Only the archived group's head may restore it
+	if head, ok := users[a.Group.HeadUserID]; ok && head != userID {
+		report.Errors = append(report.Errors, "only the group head in the archive can import it")
+	}
+	admin, err := h.isAdmin(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		return
+	}
+	if !admin {
+		a.CheckConsent(users, userID, report)
+	}
+	if !report.Valid() {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	if err := h.archiveRepo.Import(c.Request.Context(), a, users, dryRun, report); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// parseEmailMap reads old=new email pairs into a normalized map
+func parseEmailMap(pairs []string) (map[string]string, error) {
+	emailMap := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = archive.NormalizeEmail(from), archive.NormalizeEmail(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid map %q, use old@example.com=new@example.com", pair)
+		}
+		emailMap[from] = to
+	}
+	return emailMap, nil
+}
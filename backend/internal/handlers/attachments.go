@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/photo"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+)
+
+const (
+	// maxAttachmentsPerEntry is the most photos one entry can have
+	maxAttachmentsPerEntry = 5
+	// multipartOverhead allows for the multipart framing around an upload
+	multipartOverhead = 64 << 10
+)
+
+// AttachmentHandler handles photo uploads and downloads on ledger entries
+type AttachmentHandler struct {
+	attachmentRepo *db.AttachmentRepo
+	ledgerRepo     *db.LedgerRepo
+	groupRepo      *db.GroupRepo
+	store          storage.Store
+	maxBytes       int64
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler that accepts photos of
+// up to maxBytes
+func NewAttachmentHandler(attachmentRepo *db.AttachmentRepo, ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo, store storage.Store, maxBytes int64) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentRepo: attachmentRepo,
+		ledgerRepo:     ledgerRepo,
+		groupRepo:      groupRepo,
+		store:          store,
+		maxBytes:       maxBytes,
+	}
+}
+
+// AttachmentResponse represents an attachment in API responses
+type AttachmentResponse struct {
+	ID               uuid.UUID `json:"id"`
+	EntryID          uuid.UUID `json:"entry_id"`
+	UploadedByUserID uuid.UUID `json:"uploaded_by_user_id"`
+	ContentType      string    `json:"content_type"`
+	SizeBytes        int64     `json:"size_bytes"`
+	URL              string    `json:"url"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func newAttachmentResponse(a *models.LedgerAttachment) AttachmentResponse {
+	return AttachmentResponse{
+		ID:               a.ID,
+		EntryID:          a.EntryID,
+		UploadedByUserID: a.UploadedByUserID,
+		ContentType:      a.ContentType,
+		SizeBytes:        a.SizeBytes,
+		URL:              fmt.Sprintf("/api/v1/ledger/%s/attachments/%s", a.EntryID, a.ID),
+		CreatedAt:        a.CreatedAt,
+	}
+}
+
+// ListAttachments returns the photos on a ledger entry
+// GET /api/v1/ledger/:id/attachments
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	if _, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	attachments, err := h.attachmentRepo.ListForEntry(c.Request.Context(), entry.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list attachments"})
+		return
+	}
+
+	response := make([]AttachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		response = append(response, newAttachmentResponse(a))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UploadAttachment adds a photo to a pending entry (the entry's member or a head).
+// The photo is sent as the multipart form field "file".
+// POST /api/v1/ledger/:id/attachments
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if entry.UserID != userID && member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the entry's member or a group head can add photos"})
+		return
+	}
+
+	if entry.Status != models.StatusPendingApproval {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entry is not pending approval"})
+		return
+	}
+
+	tooLarge := fmt.Sprintf("photo must be at most %d bytes", h.maxBytes)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, h.maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	if int64(len(data)) > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+
+	// The type comes from the content, never the file name or part header
+	contentType, clean, err := photo.Sanitize(data)
+	if err != nil {
+		if errors.Is(err, photo.ErrUnsupportedType) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachment := &models.LedgerAttachment{
+		ID:               uuid.New(),
+		EntryID:          entry.ID,
+		UploadedByUserID: userID,
+		ContentType:      contentType,
+		SizeBytes:        int64(len(clean)),
+	}
+	attachment.StorageKey = storage.AttachmentKey(entry.GroupID, entry.ID, attachment.ID)
+
+	if err := h.store.Put(c.Request.Context(), attachment.StorageKey, bytes.NewReader(clean)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store photo"})
+		return
+	}
+
+	saved, err := h.attachmentRepo.Create(c.Request.Context(), attachment, maxAttachmentsPerEntry)
+	if err != nil {
+		// The photo is not referenced, so remove it again
+		_ = h.store.Delete(c.Request.Context(), attachment.StorageKey)
+		switch {
+		case errors.Is(err, db.ErrNotPending):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entry is not pending approval"})
+		case errors.Is(err, db.ErrTooManyAttachments):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("an entry can have at most %d photos", maxAttachmentsPerEntry)})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save attachment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newAttachmentResponse(saved))
+}
+
+// GetAttachment returns a photo to members of the entry's group
+// GET /api/v1/ledger/:id/attachments/:attachment_id
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("attachment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	if _, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	attachment, err := h.attachmentRepo.GetByID(c.Request.Context(), attachmentID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get attachment"})
+		return
+	}
+	if err != nil || attachment.EntryID != entry.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return
+	}
+
+	rc, err := h.store.Open(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open attachment"})
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.SizeBytes, attachment.ContentType, rc, map[string]string{
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+		"Content-Disposition":    "inline",
+	})
+}
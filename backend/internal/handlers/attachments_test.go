@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestNewAttachmentResponse(t *testing.T) {
+	a := &models.LedgerAttachment{ID: uuid.New(), EntryID: uuid.New(), ContentType: "image/png", SizeBytes: 10, StorageKey: "ledger/x"}
+	response := newAttachmentResponse(a)
+
+	assert.Equal(t, "/api/v1/ledger/"+a.EntryID.String()+"/attachments/"+a.ID.String(), response.URL)
+}
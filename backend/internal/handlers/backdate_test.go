@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOccurredAt(t *testing.T) {
+	now := time.Date(2026, 9, 14, 12, 0, 0, 0, time.UTC)
+	week := 7
+
+	tests := []struct {
+		name       string
+		occurredAt time.Time
+		limit      *int
+		isHead     bool
+		wantErr    bool
+	}{
+		{name: "now", occurredAt: now},
+		{name: "within clock skew", occurredAt: now.Add(2 * time.Minute)},
+		{name: "future", occurredAt: now.Add(time.Hour), wantErr: true},
+		{name: "future for head", occurredAt: now.Add(time.Hour), isHead: true, wantErr: true},
+		{name: "no limit", occurredAt: now.AddDate(-1, 0, 0)},
+		{name: "inside limit", occurredAt: now.AddDate(0, 0, -6), limit: &week},
+		{name: "outside limit", occurredAt: now.AddDate(0, 0, -8), limit: &week, wantErr: true},
+		{name: "head exempt from limit", occurredAt: now.AddDate(0, 0, -30), limit: &week, isHead: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOccurredAt(tt.occurredAt, now, tt.limit, tt.isHead)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNullableInt(t *testing.T) {
+	var req UpdateGroupRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"x"}`), &req))
+	assert.False(t, req.MaxBackdateDays.Set)
+
+	req = UpdateGroupRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"max_backdate_days":null}`), &req))
+	assert.True(t, req.MaxBackdateDays.Set)
+	assert.Nil(t, req.MaxBackdateDays.Value)
+
+	req = UpdateGroupRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"max_backdate_days":3}`), &req))
+	assert.True(t, req.MaxBackdateDays.Set)
+	require.NotNil(t, req.MaxBackdateDays.Value)
+	assert.Equal(t, 3, *req.MaxBackdateDays.Value)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"max_backdate_days":"soon"}`), &req))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/srjn45/pocket-money/backend/internal/auth"
 	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
@@ -17,41 +18,105 @@ import (
 type ChoreHandler struct {
 	choreRepo *db.ChoreRepo
 	groupRepo *db.GroupRepo
+	events    *events.Bus
 }
 
 // NewChoreHandler creates a new ChoreHandler
-func NewChoreHandler(choreRepo *db.ChoreRepo, groupRepo *db.GroupRepo) *ChoreHandler {
+func NewChoreHandler(choreRepo *db.ChoreRepo, groupRepo *db.GroupRepo, bus *events.Bus) *ChoreHandler {
 	return &ChoreHandler{
 		choreRepo: choreRepo,
 		groupRepo: groupRepo,
+		events:    bus,
 	}
 }
 
 // CreateChoreRequest represents the request body for creating a chore
 type CreateChoreRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description *string `json:"description"`
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Name          string  `json:"name" binding:"required"`
+	Description   *string `json:"description"`
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	ProofRequired bool    `json:"proof_required"`
+	// AssigneeUserID is reminded before DueAt and nudged once it has passed
+	AssigneeUserID *uuid.UUID `json:"assignee_user_id"`
+	DueAt          *string    `json:"due_at"` // RFC 3339; needs an assignee
 }
 
 // UpdateChoreRequest represents the request body for updating a chore
 type UpdateChoreRequest struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Amount      *float64 `json:"amount"`
+	Name          *string  `json:"name"`
+	Description   *string  `json:"description"`
+	Amount        *float64 `json:"amount"`
+	ProofRequired *bool    `json:"proof_required"`
+	// AssigneeUserID and DueAt are replaced when present; null removes them
+	AssigneeUserID NullableString `json:"assignee_user_id"`
+	DueAt          NullableString `json:"due_at"`
 }
 
 // ChoreResponse represents a chore in API responses
 type ChoreResponse struct {
-	ID          uuid.UUID `json:"id"`
-	GroupID     uuid.UUID `json:"group_id"`
-	Name        string    `json:"name"`
-	Description *string   `json:"description,omitempty"`
-	Amount      float64   `json:"amount"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID             uuid.UUID  `json:"id"`
+	GroupID        uuid.UUID  `json:"group_id"`
+	Name           string     `json:"name"`
+	Description    *string    `json:"description,omitempty"`
+	Amount         float64    `json:"amount"`
+	ProofRequired  bool       `json:"proof_required"`
+	AssigneeUserID *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueAt          *time.Time `json:"due_at,omitempty"`
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"` // When the assignee or due time last changed
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 }
 
-// ListChores returns all chores for a group
+func newChoreResponse(ch *models.Chore) ChoreResponse {
+	return ChoreResponse{
+		ID:             ch.ID,
+		GroupID:        ch.GroupID,
+		Name:           ch.Name,
+		Description:    ch.Description,
+		Amount:         ch.Amount,
+		ProofRequired:  ch.ProofRequired,
+		AssigneeUserID: ch.AssigneeUserID,
+		DueAt:          ch.DueAt,
+		AssignedAt:     ch.AssignedAt,
+		CreatedAt:      ch.CreatedAt,
+		DeletedAt:      ch.DeletedAt,
+	}
+}
+
+// choreDueAt parses when a chore is due, which must be in the future
+func choreDueAt(value string, now time.Time) (time.Time, error) {
+	dueAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid due_at format, use RFC 3339")
+	}
+	if !dueAt.After(now) {
+		return time.Time{}, errors.New("due_at must be in the future")
+	}
+	return dueAt, nil
+}
+
+// checkAssignment checks who a chore is assigned to: a new assignee must be
+// a member, and a chore with a due time needs an assignee
+func (h *ChoreHandler) checkAssignment(c *gin.Context, groupID uuid.UUID, assignee *uuid.UUID, dueAt *time.Time, assigneeSet bool) bool {
+	if assigneeSet && assignee != nil {
+		if _, err := h.groupRepo.GetMember(c.Request.Context(), groupID, *assignee); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "assignee is not a member of this group"})
+				return false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+			return false
+		}
+	}
+	if dueAt != nil && assignee == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a chore with a due time needs an assignee"})
+		return false
+	}
+	return true
+}
+
+// ListChores returns the chores for a group; archived ones are included
+// with include_deleted=true
 // GET /api/v1/groups/:id/chores
 func (h *ChoreHandler) ListChores(c *gin.Context) {
 	userIDStr, exists := auth.GetUserID(c)
@@ -84,7 +149,13 @@ func (h *ChoreHandler) ListChores(c *gin.Context) {
 		return
 	}
 
-	chores, err := h.choreRepo.ListForGroup(c.Request.Context(), groupID)
+	includeDeleted, err := queryBool(c, "include_deleted")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chores, err := h.choreRepo.ListForGroup(c.Request.Context(), groupID, includeDeleted != nil && *includeDeleted)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list chores"})
 		return
@@ -92,14 +163,7 @@ func (h *ChoreHandler) ListChores(c *gin.Context) {
 
 	response := make([]ChoreResponse, 0, len(chores))
 	for _, ch := range chores {
-		response = append(response, ChoreResponse{
-			ID:          ch.ID,
-			GroupID:     ch.GroupID,
-			Name:        ch.Name,
-			Description: ch.Description,
-			Amount:      ch.Amount,
-			CreatedAt:   ch.CreatedAt,
-		})
+		response = append(response, newChoreResponse(ch))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -149,20 +213,29 @@ func (h *ChoreHandler) CreateChore(c *gin.Context) {
 		return
 	}
 
-	chore, err := h.choreRepo.Create(c.Request.Context(), groupID, req.Name, req.Description, req.Amount)
+	var dueAt *time.Time
+	if req.DueAt != nil {
+		d, err := choreDueAt(*req.DueAt, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dueAt = &d
+	}
+	if !h.checkAssignment(c, groupID, req.AssigneeUserID, dueAt, true) {
+		return
+	}
+
+	chore, err := h.choreRepo.Create(c.Request.Context(), groupID, req.Name, req.Description, req.Amount, req.ProofRequired, req.AssigneeUserID, dueAt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create chore"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, ChoreResponse{
-		ID:          chore.ID,
-		GroupID:     chore.GroupID,
-		Name:        chore.Name,
-		Description: chore.Description,
-		Amount:      chore.Amount,
-		CreatedAt:   chore.CreatedAt,
-	})
+	response := newChoreResponse(chore)
+	h.events.Publish(c.Request.Context(), events.New(events.ChoreCreated, groupID, &userID, response))
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // UpdateChore updates a chore
@@ -197,6 +270,10 @@ func (h *ChoreHandler) UpdateChore(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get chore"})
 		return
 	}
+	if chore.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chore not found"})
+		return
+	}
 
 	// Check if user is head of the group
 	member, err := h.groupRepo.GetMember(c.Request.Context(), chore.GroupID, userID)
@@ -220,20 +297,52 @@ func (h *ChoreHandler) UpdateChore(c *gin.Context) {
 		return
 	}
 
-	updatedChore, err := h.choreRepo.Update(c.Request.Context(), choreID, req.Name, req.Description, req.Amount)
+	update := db.ChoreUpdate{
+		Name:           req.Name,
+		Description:    req.Description,
+		Amount:         req.Amount,
+		ProofRequired:  req.ProofRequired,
+		SetAssignee:    req.AssigneeUserID.Set,
+		AssigneeUserID: chore.AssigneeUserID,
+		SetDueAt:       req.DueAt.Set,
+		DueAt:          chore.DueAt,
+	}
+	if req.AssigneeUserID.Set {
+		update.AssigneeUserID = nil
+		if req.AssigneeUserID.Value != nil {
+			id, err := uuid.Parse(*req.AssigneeUserID.Value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid assignee_user_id"})
+				return
+			}
+			update.AssigneeUserID = &id
+		}
+	}
+	if req.DueAt.Set {
+		update.DueAt = nil
+		if req.DueAt.Value != nil {
+			d, err := choreDueAt(*req.DueAt.Value, time.Now())
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			update.DueAt = &d
+		}
+	}
+	if !h.checkAssignment(c, chore.GroupID, update.AssigneeUserID, update.DueAt, req.AssigneeUserID.Set) {
+		return
+	}
+
+	updatedChore, err := h.choreRepo.Update(c.Request.Context(), choreID, update)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update chore"})
 		return
 	}
 
-	c.JSON(http.StatusOK, ChoreResponse{
-		ID:          updatedChore.ID,
-		GroupID:     updatedChore.GroupID,
-		Name:        updatedChore.Name,
-		Description: updatedChore.Description,
-		Amount:      updatedChore.Amount,
-		CreatedAt:   updatedChore.CreatedAt,
-	})
+	response := newChoreResponse(updatedChore)
+	h.events.Publish(c.Request.Context(), events.New(events.ChoreUpdated, updatedChore.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
 }
 
 // DeleteChore deletes a chore
@@ -268,6 +377,10 @@ func (h *ChoreHandler) DeleteChore(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get chore"})
 		return
 	}
+	if chore.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chore not found"})
+		return
+	}
 
 	// Check if user is head of the group
 	member, err := h.groupRepo.GetMember(c.Request.Context(), chore.GroupID, userID)
@@ -286,9 +399,15 @@ func (h *ChoreHandler) DeleteChore(c *gin.Context) {
 	}
 
 	if err := h.choreRepo.Delete(c.Request.Context(), choreID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chore not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete chore"})
 		return
 	}
 
+	h.events.Publish(c.Request.Context(), events.New(events.ChoreDeleted, chore.GroupID, &userID, newChoreResponse(chore)))
+
 	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChoreDueAt(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	dueAt, err := choreDueAt("2026-10-18T18:00:00+01:00", now)
+	require.NoError(t, err)
+	assert.True(t, dueAt.Equal(time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)))
+
+	_, err = choreDueAt("2026-10-18T09:00:00Z", now)
+	assert.EqualError(t, err, "due_at must be in the future")
+
+	_, err = choreDueAt("2026-10-18", now)
+	assert.EqualError(t, err, "invalid due_at format, use RFC 3339")
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// maxCommentLength is the longest comment body accepted, in characters
+const maxCommentLength = 2000
+
+// CommentHandler handles ledger entry comment requests
+type CommentHandler struct {
+	commentRepo *db.CommentRepo
+	ledgerRepo  *db.LedgerRepo
+	groupRepo   *db.GroupRepo
+	events      *events.Bus
+}
+
+// NewCommentHandler creates a new CommentHandler
+func NewCommentHandler(commentRepo *db.CommentRepo, ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo, bus *events.Bus) *CommentHandler {
+	return &CommentHandler{
+		commentRepo: commentRepo,
+		ledgerRepo:  ledgerRepo,
+		groupRepo:   groupRepo,
+		events:      bus,
+	}
+}
+
+// CreateCommentRequest represents the request body for commenting on an entry
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// CommentResponse represents a comment in API responses
+type CommentResponse struct {
+	ID        uuid.UUID `json:"id"`
+	EntryID   uuid.UUID `json:"entry_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newCommentResponse(c *models.LedgerComment) CommentResponse {
+	return CommentResponse{
+		ID:        c.ID,
+		EntryID:   c.EntryID,
+		UserID:    c.UserID,
+		UserName:  c.UserName,
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// commentBody trims a comment and checks it is neither empty nor too long
+func commentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment body must not be empty")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("comment body must be at most %d characters", maxCommentLength)
+	}
+	return body, nil
+}
+
+// ListComments returns the comment thread of a ledger entry
+// GET /api/v1/ledger/:id/comments
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	// Anyone in the entry's group can read and join the thread
+	if _, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	comments, err := h.commentRepo.ListForEntry(c.Request.Context(), entry.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list comments"})
+		return
+	}
+
+	response := make([]CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		response = append(response, newCommentResponse(comment))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateComment adds a comment to a ledger entry's thread
+// POST /api/v1/ledger/:id/comments
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	// Anyone in the entry's group can read and join the thread
+	if _, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, err := commentBody(req.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.commentRepo.Create(c.Request.Context(), entry.ID, userID, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create comment"})
+		return
+	}
+
+	response := newCommentResponse(comment)
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerCommented, entry.GroupID, &userID, response))
+
+	c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRejectReason(t *testing.T) {
+	reason, err := rejectReason("", false)
+	require.NoError(t, err)
+	assert.Nil(t, reason)
+
+	_, err = rejectReason("   ", true)
+	assert.EqualError(t, err, "reason is required to reject entries in this group")
+
+	reason, err = rejectReason("  only half the lawn \n", true)
+	require.NoError(t, err)
+	require.NotNil(t, reason)
+	assert.Equal(t, "only half the lawn", *reason)
+
+	_, err = rejectReason(strings.Repeat("é", maxReasonLength+1), false)
+	assert.Error(t, err)
+	_, err = rejectReason(strings.Repeat("é", maxReasonLength), false)
+	assert.NoError(t, err)
+}
+
+func TestCommentBody(t *testing.T) {
+	body, err := commentBody("  did the front, back is next  ")
+	require.NoError(t, err)
+	assert.Equal(t, "did the front, back is next", body)
+
+	_, err = commentBody(" \t\n")
+	assert.EqualError(t, err, "comment body must not be empty")
+
+	_, err = commentBody(strings.Repeat("a", maxCommentLength+1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+)
+
+const (
+	// sseHeartbeatInterval keeps idle streams open through proxies
+	sseHeartbeatInterval = 25 * time.Second
+	// sseReplayBatch is how many stored events are read at a time when resuming
+	sseReplayBatch = 500
+	// sseRetry tells clients how long to wait before reconnecting, in milliseconds
+	sseRetry = 3000
+	// sseMembershipInterval is how often an open stream checks that its user
+	// is still a member of the group
+	sseMembershipInterval = time.Minute
+)
+
+// EventsHandler streams group activity to connected clients
+type EventsHandler struct {
+	eventRepo   *db.EventRepo
+	groupRepo   *db.GroupRepo
+	broadcaster *events.Broadcaster
+}
+
+// NewEventsHandler creates a new EventsHandler
+func NewEventsHandler(eventRepo *db.EventRepo, groupRepo *db.GroupRepo, broadcaster *events.Broadcaster) *EventsHandler {
+	return &EventsHandler{
+		eventRepo:   eventRepo,
+		groupRepo:   groupRepo,
+		broadcaster: broadcaster,
+	}
+}
+
+// writeSSE writes a stored event as a server-sent event
+func writeSSE(w io.Writer, rec events.Record) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", rec.Seq, rec.Type, rec.Payload)
+	return err
+}
+
+// parseLastEventID reads the Last-Event-ID header, or the last_event_id
+// query parameter for clients that cannot set headers on reconnect
+func parseLastEventID(c *gin.Context) (int64, bool, error) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false, fmt.Errorf("invalid Last-Event-ID")
+	}
+	return seq, true, nil
+}
+
+// StreamEvents streams a group's events as server-sent events. With a
+// Last-Event-ID the events stored since that ID are replayed first. The
+// stream is closed once the user is no longer a member of the group.
+// GET /api/v1/groups/:id/events
+func (h *EventsHandler) StreamEvents(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	lastSeq, resume, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Subscribe before replaying so nothing published meanwhile is missed
+	live, unsubscribe := h.broadcaster.Subscribe(groupID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetry); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	// Replay stored events, remembering them so live copies are not repeated
+	replayed := make(map[int64]bool)
+	for resume {
+		records, err := h.eventRepo.ListSince(c.Request.Context(), groupID, lastSeq, sseReplayBatch)
+		if err != nil {
+			return
+		}
+		for _, rec := range records {
+			if err := writeSSE(c.Writer, rec); err != nil {
+				return
+			}
+			replayed[rec.Seq] = true
+			lastSeq = rec.Seq
+		}
+		c.Writer.Flush()
+		resume = len(records) == sseReplayBatch
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	membership := time.NewTicker(sseMembershipInterval)
+	defer membership.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case rec, ok := <-live:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes
+				return
+			}
+			if replayed[rec.Seq] {
+				continue
+			}
+			if err := writeSSE(c.Writer, rec); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-membership.C:
+			_, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+			if errors.Is(err, db.ErrNotFound) {
+				// Removed from the group; reconnecting is refused
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+)
+
+func TestWriteSSE(t *testing.T) {
+	var buf bytes.Buffer
+	rec := events.Record{Seq: 42, Type: events.LedgerApproved, GroupID: uuid.New(), Payload: []byte(`{"type":"ledger.approved"}`)}
+
+	require.NoError(t, writeSSE(&buf, rec))
+	assert.Equal(t, "id: 42\nevent: ledger.approved\ndata: {\"type\":\"ledger.approved\"}\n\n", buf.String())
+}
+
+func TestParseLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		header  string
+		query   string
+		want    int64
+		resume  bool
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "header", header: "17", want: 17, resume: true},
+		{name: "query", query: "?last_event_id=9", want: 9, resume: true},
+		{name: "header wins", header: "3", query: "?last_event_id=9", want: 3, resume: true},
+		{name: "invalid", header: "abc", wantErr: true},
+		{name: "negative", header: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/events"+tt.query, nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Last-Event-ID", tt.header)
+			}
+
+			seq, resume, err := parseLastEventID(c)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, seq)
+			assert.Equal(t, tt.resume, resume)
+		})
+	}
+}
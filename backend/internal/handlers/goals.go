@@ -0,0 +1,755 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/goals"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/photo"
+	"github.com/srjn45/pocket-money/backend/internal/storage"
+)
+
+// maxGoalsPerMember is the most goals one member can save for at once
+const maxGoalsPerMember = 20
+
+// GoalHandler handles savings goal requests
+type GoalHandler struct {
+	goalRepo   *db.GoalRepo
+	ledgerRepo *db.LedgerRepo
+	groupRepo  *db.GroupRepo
+	store      storage.Store
+	maxBytes   int64
+	events     *events.Bus
+}
+
+// NewGoalHandler creates a new GoalHandler that accepts goal images of up
+// to maxBytes
+func NewGoalHandler(goalRepo *db.GoalRepo, ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo, store storage.Store, maxBytes int64, bus *events.Bus) *GoalHandler {
+	return &GoalHandler{
+		goalRepo:   goalRepo,
+		ledgerRepo: ledgerRepo,
+		groupRepo:  groupRepo,
+		store:      store,
+		maxBytes:   maxBytes,
+		events:     bus,
+	}
+}
+
+// CreateGoalRequest represents the request body for creating a goal.
+// ProgressSource defaults to balance.
+type CreateGoalRequest struct {
+	Name           string             `json:"name" binding:"required"`
+	TargetAmount   float64            `json:"target_amount" binding:"required,gt=0"`
+	Deadline       *string            `json:"deadline"` // YYYY-MM-DD format
+	ProgressSource *models.GoalSource `json:"progress_source"`
+}
+
+// UpdateGoalRequest represents the request body for changing a goal
+type UpdateGoalRequest struct {
+	Name           *string            `json:"name"`
+	TargetAmount   *float64           `json:"target_amount" binding:"omitempty,gt=0"`
+	Deadline       NullableString     `json:"deadline"` // YYYY-MM-DD format; null removes it
+	ProgressSource *models.GoalSource `json:"progress_source"`
+}
+
+// AllocateGoalRequest represents the request body for setting money aside
+// for a goal; a negative amount takes it back
+type AllocateGoalRequest struct {
+	Amount float64 `json:"amount" binding:"required,ne=0"`
+	Note   *string `json:"note"`
+}
+
+// GoalResponse represents a goal in API responses
+type GoalResponse struct {
+	ID              uuid.UUID         `json:"id"`
+	GroupID         uuid.UUID         `json:"group_id"`
+	UserID          uuid.UUID         `json:"user_id"`
+	Name            string            `json:"name"`
+	TargetAmount    float64           `json:"target_amount"`
+	Deadline        *string           `json:"deadline,omitempty"`
+	ProgressSource  models.GoalSource `json:"progress_source"`
+	Allocated       float64           `json:"allocated"`
+	ImageURL        *string           `json:"image_url,omitempty"`
+	ReachedAt       *time.Time        `json:"reached_at,omitempty"`
+	Progress        *goals.Progress   `json:"progress,omitempty"`
+	CreatedByUserID uuid.UUID         `json:"created_by_user_id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+}
+
+func newGoalResponse(g *models.Goal, progress *goals.Progress) GoalResponse {
+	r := GoalResponse{
+		ID:              g.ID,
+		GroupID:         g.GroupID,
+		UserID:          g.UserID,
+		Name:            g.Name,
+		TargetAmount:    g.TargetAmount,
+		ProgressSource:  g.Source,
+		Allocated:       g.Allocated,
+		ReachedAt:       g.ReachedAt,
+		Progress:        progress,
+		CreatedByUserID: g.CreatedByUserID,
+		CreatedAt:       g.CreatedAt,
+		UpdatedAt:       g.UpdatedAt,
+		DeletedAt:       g.DeletedAt,
+	}
+	if g.Deadline != nil {
+		deadline := g.Deadline.Format("2006-01-02")
+		r.Deadline = &deadline
+	}
+	if g.ImageStorageKey != nil {
+		url := fmt.Sprintf("/api/v1/groups/%s/members/%s/goals/%s/image", g.GroupID, g.UserID, g.ID)
+		r.ImageURL = &url
+	}
+	return r
+}
+
+// goalName validates and trims a goal name
+func goalName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if len(name) > 100 {
+		return "", errors.New("name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+// goalDeadline parses a goal deadline, which cannot be before today
+func goalDeadline(value string, now time.Time) (time.Time, error) {
+	deadline, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid deadline format, use YYYY-MM-DD")
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	if deadline.Before(today) {
+		return time.Time{}, errors.New("deadline cannot be in the past")
+	}
+	return deadline, nil
+}
+
+// validGoalSource reports whether s says what counts towards a goal
+func validGoalSource(s models.GoalSource) bool {
+	return s == models.GoalFromBalance || s == models.GoalFromAllocations
+}
+
+// queryWeeks parses the weeks of earnings a projection uses
+func queryWeeks(c *gin.Context) (int, error) {
+	value := c.Query("weeks")
+	if value == "" {
+		return goals.DefaultWeeks, nil
+	}
+	weeks, err := strconv.Atoi(value)
+	if err != nil || weeks < 1 || weeks > goals.MaxWeeks {
+		return 0, fmt.Errorf("weeks must be between 1 and %d", goals.MaxWeeks)
+	}
+	return weeks, nil
+}
+
+// AnnounceReachedGoals marks the group's goals that have reached their
+// target and publishes goal.reached for each, with no actor
+func AnnounceReachedGoals(ctx context.Context, goalRepo *db.GoalRepo, bus *events.Bus, groupID uuid.UUID) {
+	reached, err := goalRepo.MarkReached(ctx, groupID, time.Now())
+	if err != nil {
+		log.Printf("goals: failed to check reached goals in group %s: %v", groupID, err)
+		return
+	}
+	for _, g := range reached {
+		bus.Publish(ctx, events.New(events.GoalReached, groupID, nil, newGoalResponse(g, nil)))
+	}
+}
+
+// memberScope checks the caller and the member a /members/:user_id route is
+// for, both members of the group, writing the error response if either check
+// fails. It returns the caller's ID and whether they may manage the member's
+// goals or jars: the member themselves or a head.
+func memberScope(c *gin.Context, groupRepo *db.GroupRepo) (userID, groupID, memberID uuid.UUID, canManage, ok bool) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err = uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	memberID, err = uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	member, err := groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if memberID != userID {
+		_, err = groupRepo.GetMember(c.Request.Context(), groupID, memberID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+			return
+		}
+	}
+
+	return userID, groupID, memberID, memberID == userID || member.Role == models.RoleHead, true
+}
+
+// memberGoal loads a goal of the member that has not been deleted, writing
+// the error response if there is none
+func (h *GoalHandler) memberGoal(c *gin.Context, groupID, memberID uuid.UUID) (*models.Goal, bool) {
+	goalID, err := uuid.Parse(c.Param("goal_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid goal ID"})
+		return nil, false
+	}
+
+	g, err := h.goalRepo.GetByID(c.Request.Context(), goalID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get goal"})
+		return nil, false
+	}
+	if err != nil || g.GroupID != groupID || g.UserID != memberID || g.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+		return nil, false
+	}
+	return g, true
+}
+
+// progressInputs returns the member's balance now and their average approved
+// earnings per week over the last weeks
+func (h *GoalHandler) progressInputs(ctx context.Context, groupID, memberID uuid.UUID, weeks int, now time.Time) (float64, float64, error) {
+	balance, err := h.ledgerRepo.GetMemberBalance(ctx, groupID, memberID, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := h.ledgerRepo.ListApprovedForMember(ctx, groupID, memberID, now.AddDate(0, 0, -7*weeks), now)
+	if err != nil {
+		return 0, 0, err
+	}
+	return balance, goals.WeeklyRate(entries, weeks), nil
+}
+
+// respondWithProgress writes a goal with its progress over the last weeks
+func (h *GoalHandler) respondWithProgress(c *gin.Context, status int, g *models.Goal, weeks int) {
+	now := time.Now()
+	balance, rate, err := h.progressInputs(c.Request.Context(), g.GroupID, g.UserID, weeks, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute goal progress"})
+		return
+	}
+	progress := goals.Compute(g, goals.Saved(g, balance), rate, weeks, now)
+	c.JSON(status, newGoalResponse(g, &progress))
+}
+
+// ListGoals returns a member's goals with their progress, projected from
+// the last weeks (default 8) of approved earnings; deleted goals are included
+// with include_deleted=true
+// GET /api/v1/groups/:id/members/:user_id/goals
+func (h *GoalHandler) ListGoals(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	weeks, err := queryWeeks(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	includeDeleted, err := queryBool(c, "include_deleted")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.goalRepo.ListForMember(c.Request.Context(), groupID, memberID, includeDeleted != nil && *includeDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list goals"})
+		return
+	}
+
+	now := time.Now()
+	balance, rate, err := h.progressInputs(c.Request.Context(), groupID, memberID, weeks, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute goal progress"})
+		return
+	}
+
+	response := make([]GoalResponse, 0, len(list))
+	for _, g := range list {
+		var progress *goals.Progress
+		if g.DeletedAt == nil {
+			p := goals.Compute(g, goals.Saved(g, balance), rate, weeks, now)
+			progress = &p
+		}
+		response = append(response, newGoalResponse(g, progress))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetGoal returns one of a member's goals with its progress
+// GET /api/v1/groups/:id/members/:user_id/goals/:goal_id
+func (h *GoalHandler) GetGoal(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	weeks, err := queryWeeks(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	h.respondWithProgress(c, http.StatusOK, g, weeks)
+}
+
+// CreateGoal starts a savings goal for a member (the member or a head)
+// POST /api/v1/groups/:id/members/:user_id/goals
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	userID, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	var req CreateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, err := goalName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	source := models.GoalFromBalance
+	if req.ProgressSource != nil {
+		if !validGoalSource(*req.ProgressSource) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "progress_source must be balance or allocations"})
+			return
+		}
+		source = *req.ProgressSource
+	}
+	var deadline *time.Time
+	if req.Deadline != nil {
+		d, err := goalDeadline(*req.Deadline, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		deadline = &d
+	}
+
+	existing, err := h.goalRepo.ListForMember(c.Request.Context(), groupID, memberID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list goals"})
+		return
+	}
+	if len(existing) >= maxGoalsPerMember {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a member can have at most %d goals", maxGoalsPerMember)})
+		return
+	}
+
+	created, err := h.goalRepo.Create(c.Request.Context(), &models.Goal{
+		GroupID:         groupID,
+		UserID:          memberID,
+		Name:            name,
+		TargetAmount:    req.TargetAmount,
+		Deadline:        deadline,
+		Source:          source,
+		CreatedByUserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create goal"})
+		return
+	}
+
+	// A member who already has enough reaches the goal straight away
+	AnnounceReachedGoals(c.Request.Context(), h.goalRepo, h.events, groupID)
+	if reloaded, err := h.goalRepo.GetByID(c.Request.Context(), created.ID); err == nil {
+		created = reloaded
+	}
+
+	h.respondWithProgress(c, http.StatusCreated, created, goals.DefaultWeeks)
+}
+
+// UpdateGoal changes a goal's name, target, deadline or what counts towards
+// it (the member or a head)
+// PATCH /api/v1/groups/:id/members/:user_id/goals/:goal_id
+func (h *GoalHandler) UpdateGoal(c *gin.Context) {
+	_, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	var req UpdateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := db.GoalUpdate{
+		TargetAmount: req.TargetAmount,
+		SetDeadline:  req.Deadline.Set,
+		Source:       req.ProgressSource,
+	}
+	if req.Name != nil {
+		name, err := goalName(*req.Name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update.Name = &name
+	}
+	if req.ProgressSource != nil && !validGoalSource(*req.ProgressSource) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "progress_source must be balance or allocations"})
+		return
+	}
+	if req.Deadline.Value != nil {
+		d, err := goalDeadline(*req.Deadline.Value, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update.Deadline = &d
+	}
+
+	current, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	updated, err := h.goalRepo.Update(c.Request.Context(), current.ID, update)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update goal"})
+		return
+	}
+
+	// Lowering the target may mean the goal is reached now
+	AnnounceReachedGoals(c.Request.Context(), h.goalRepo, h.events, groupID)
+	if reloaded, err := h.goalRepo.GetByID(c.Request.Context(), updated.ID); err == nil {
+		updated = reloaded
+	}
+
+	h.respondWithProgress(c, http.StatusOK, updated, goals.DefaultWeeks)
+}
+
+// DeleteGoal removes a goal, releasing the money allocated to it (the member or a head)
+// DELETE /api/v1/groups/:id/members/:user_id/goals/:goal_id
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	_, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	current, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	previous, err := h.goalRepo.Delete(c.Request.Context(), current.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete goal"})
+		return
+	}
+	if previous != nil {
+		_ = h.store.Delete(c.Request.Context(), *previous)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListGoalAllocations returns the money set aside for and taken back from a goal
+// GET /api/v1/groups/:id/members/:user_id/goals/:goal_id/allocations
+func (h *GoalHandler) ListGoalAllocations(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	allocations, err := h.goalRepo.ListAllocations(c.Request.Context(), g.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list goal allocations"})
+		return
+	}
+	if allocations == nil {
+		allocations = []*models.GoalAllocation{}
+	}
+
+	c.JSON(http.StatusOK, allocations)
+}
+
+// AllocateGoal sets money aside for a goal from the member's balance, or
+// takes it back with a negative amount (the member or a head)
+// POST /api/v1/groups/:id/members/:user_id/goals/:goal_id/allocations
+func (h *GoalHandler) AllocateGoal(c *gin.Context) {
+	userID, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	var req AllocateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		req.Note = &note
+		if note == "" {
+			req.Note = nil
+		}
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	allocation, err := h.goalRepo.Allocate(c.Request.Context(), g.ID, req.Amount, req.Note, userID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+		case errors.Is(err, db.ErrOverAllocated):
+			c.JSON(http.StatusConflict, gin.H{"error": "allocations across goals cannot exceed the member's balance"})
+		case errors.Is(err, db.ErrUnderAllocated):
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot take back more than the goal has allocated"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to allocate to goal"})
+		}
+		return
+	}
+
+	AnnounceReachedGoals(c.Request.Context(), h.goalRepo, h.events, groupID)
+
+	c.JSON(http.StatusCreated, allocation)
+}
+
+// PutGoalImage sets or replaces a goal's image (the member or a head). The
+// image is sent as the multipart form field "file".
+// PUT /api/v1/groups/:id/members/:user_id/goals/:goal_id/image
+func (h *GoalHandler) PutGoalImage(c *gin.Context) {
+	_, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	tooLarge := fmt.Sprintf("image must be at most %d bytes", h.maxBytes)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, h.maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	if int64(len(data)) > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+
+	// The type comes from the content, never the file name or part header
+	contentType, clean, err := photo.Sanitize(data)
+	if err != nil {
+		if errors.Is(err, photo.ErrUnsupportedType) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := storage.GoalImageKey(groupID, g.ID)
+	if err := h.store.Put(c.Request.Context(), key, bytes.NewReader(clean)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store image"})
+		return
+	}
+
+	size := int64(len(clean))
+	updated, previous, err := h.goalRepo.SetImage(c.Request.Context(), g.ID, &contentType, &size, &key)
+	if err != nil {
+		// The image is not referenced, so remove it again
+		_ = h.store.Delete(c.Request.Context(), key)
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save goal image"})
+		return
+	}
+	if previous != nil {
+		_ = h.store.Delete(c.Request.Context(), *previous)
+	}
+
+	c.JSON(http.StatusOK, newGoalResponse(updated, nil))
+}
+
+// GetGoalImage returns a goal's image to members of the group
+// GET /api/v1/groups/:id/members/:user_id/goals/:goal_id/image
+func (h *GoalHandler) GetGoalImage(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+	if g.ImageStorageKey == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "goal has no image"})
+		return
+	}
+
+	rc, err := h.store.Open(c.Request.Context(), *g.ImageStorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal has no image"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open goal image"})
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, *g.ImageSizeBytes, *g.ImageContentType, rc, map[string]string{
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+		"Content-Disposition":    "inline",
+	})
+}
+
+// DeleteGoalImage removes a goal's image (the member or a head)
+// DELETE /api/v1/groups/:id/members/:user_id/goals/:goal_id/image
+func (h *GoalHandler) DeleteGoalImage(c *gin.Context) {
+	_, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can manage goals"})
+		return
+	}
+
+	g, ok := h.memberGoal(c, groupID, memberID)
+	if !ok {
+		return
+	}
+
+	_, previous, err := h.goalRepo.SetImage(c.Request.Context(), g.ID, nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove goal image"})
+		return
+	}
+	if previous != nil {
+		_ = h.store.Delete(c.Request.Context(), *previous)
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoalName(t *testing.T) {
+	name, err := goalName("  New bike ")
+	require.NoError(t, err)
+	assert.Equal(t, "New bike", name)
+
+	_, err = goalName("   ")
+	assert.EqualError(t, err, "name is required")
+
+	_, err = goalName(strings.Repeat("x", 101))
+	assert.EqualError(t, err, "name must be at most 100 characters")
+}
+
+func TestGoalDeadline(t *testing.T) {
+	now := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+
+	deadline, err := goalDeadline("2026-10-18", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), deadline)
+
+	_, err = goalDeadline("2026-10-17", now)
+	assert.EqualError(t, err, "deadline cannot be in the past")
+
+	_, err = goalDeadline("next week", now)
+	assert.EqualError(t, err, "invalid deadline format, use YYYY-MM-DD")
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/srjn45/pocket-money/backend/internal/auth"
 	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
@@ -20,13 +22,15 @@ import (
 type GroupHandler struct {
 	groupRepo  *db.GroupRepo
 	inviteRepo *db.InviteRepo
+	events     *events.Bus
 }
 
 // NewGroupHandler creates a new GroupHandler
-func NewGroupHandler(groupRepo *db.GroupRepo, inviteRepo *db.InviteRepo) *GroupHandler {
+func NewGroupHandler(groupRepo *db.GroupRepo, inviteRepo *db.InviteRepo, bus *events.Bus) *GroupHandler {
 	return &GroupHandler{
 		groupRepo:  groupRepo,
 		inviteRepo: inviteRepo,
+		events:     bus,
 	}
 }
 
@@ -37,10 +41,137 @@ type CreateGroupRequest struct {
 
 // GroupResponse represents a group in API responses
 type GroupResponse struct {
-	ID         uuid.UUID `json:"id"`
-	Name       string    `json:"name"`
-	HeadUserID uuid.UUID `json:"head_user_id"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	HeadUserID      uuid.UUID `json:"head_user_id"`
+	MaxBackdateDays *int      `json:"max_backdate_days"`
+	models.PendingPolicy
+	RequireRejectReason bool      `json:"require_reject_reason"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// newGroupResponse converts a group to its API representation
+func newGroupResponse(g *models.Group) GroupResponse {
+	return GroupResponse{
+		ID:                  g.ID,
+		Name:                g.Name,
+		HeadUserID:          g.HeadUserID,
+		MaxBackdateDays:     g.MaxBackdateDays,
+		PendingPolicy:       g.PendingPolicy,
+		RequireRejectReason: g.RequireRejectReason,
+		CreatedAt:           g.CreatedAt,
+	}
+}
+
+// UpdateGroupRequest represents the request body for updating group settings
+type UpdateGroupRequest struct {
+	Name            *string     `json:"name"`
+	MaxBackdateDays NullableInt `json:"max_backdate_days"` // null removes the limit
+
+	RequireRejectReason *bool `json:"require_reject_reason"`
+
+	// Pending entry policies; null turns a policy off
+	ExpirePendingAfterDays NullableInt   `json:"expire_pending_after_days"`
+	AutoApproveAfterHours  NullableInt   `json:"auto_approve_after_hours"`
+	AutoApproveMaxAmount   NullableFloat `json:"auto_approve_max_amount"`
+}
+
+// setsPendingPolicy reports whether the request changes any pending entry policy
+func (r UpdateGroupRequest) setsPendingPolicy() bool {
+	return r.ExpirePendingAfterDays.Set || r.AutoApproveAfterHours.Set || r.AutoApproveMaxAmount.Set
+}
+
+// mergePendingPolicy applies the request's policy changes to the current
+// policy and checks the result. Auto-approval needs both a delay and a
+// maximum amount, so a head cannot approve everything by accident.
+func mergePendingPolicy(current models.PendingPolicy, req UpdateGroupRequest) (models.PendingPolicy, error) {
+	p := current
+	if req.ExpirePendingAfterDays.Set {
+		p.ExpirePendingAfterDays = req.ExpirePendingAfterDays.Value
+	}
+	if req.AutoApproveAfterHours.Set {
+		p.AutoApproveAfterHours = req.AutoApproveAfterHours.Value
+	}
+	if req.AutoApproveMaxAmount.Set {
+		p.AutoApproveMaxAmount = req.AutoApproveMaxAmount.Value
+	}
+
+	if p.ExpirePendingAfterDays != nil && *p.ExpirePendingAfterDays < 1 {
+		return p, errors.New("expire_pending_after_days must be at least 1")
+	}
+	if p.AutoApproveAfterHours != nil && *p.AutoApproveAfterHours < 1 {
+		return p, errors.New("auto_approve_after_hours must be at least 1")
+	}
+	if p.AutoApproveMaxAmount != nil && *p.AutoApproveMaxAmount < 0 {
+		return p, errors.New("auto_approve_max_amount cannot be negative")
+	}
+	if (p.AutoApproveAfterHours == nil) != (p.AutoApproveMaxAmount == nil) {
+		return p, errors.New("auto_approve_after_hours and auto_approve_max_amount must be set together")
+	}
+	return p, nil
+}
+
+// NullableInt distinguishes an omitted JSON field from an explicit null
+type NullableInt struct {
+	Set   bool
+	Value *int
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (n *NullableInt) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+// NullableFloat distinguishes an omitted JSON field from an explicit null
+type NullableFloat struct {
+	Set   bool
+	Value *float64
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (n *NullableFloat) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+// NullableString distinguishes an omitted JSON field from an explicit null
+type NullableString struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (n *NullableString) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
 }
 
 // MemberResponse represents a member in API responses
@@ -54,12 +185,15 @@ type MemberResponse struct {
 
 // GroupDetailResponse represents detailed group information
 type GroupDetailResponse struct {
-	ID          uuid.UUID        `json:"id"`
-	Name        string           `json:"name"`
-	HeadUserID  uuid.UUID        `json:"head_user_id"`
-	CreatedAt   time.Time        `json:"created_at"`
-	Members     []MemberResponse `json:"members"`
-	ChoresCount int              `json:"chores_count"`
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	HeadUserID      uuid.UUID `json:"head_user_id"`
+	MaxBackdateDays *int      `json:"max_backdate_days"`
+	models.PendingPolicy
+	RequireRejectReason bool             `json:"require_reject_reason"`
+	CreatedAt           time.Time        `json:"created_at"`
+	Members             []MemberResponse `json:"members"`
+	ChoresCount         int              `json:"chores_count"`
 }
 
 // CreateGroup handles group creation
@@ -97,12 +231,7 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, GroupResponse{
-		ID:         group.ID,
-		Name:       group.Name,
-		HeadUserID: group.HeadUserID,
-		CreatedAt:  group.CreatedAt,
-	})
+	c.JSON(http.StatusCreated, newGroupResponse(group))
 }
 
 // ListGroups returns all groups for the authenticated user
@@ -128,12 +257,7 @@ func (h *GroupHandler) ListGroups(c *gin.Context) {
 
 	response := make([]GroupResponse, 0, len(groups))
 	for _, g := range groups {
-		response = append(response, GroupResponse{
-			ID:         g.ID,
-			Name:       g.Name,
-			HeadUserID: g.HeadUserID,
-			CreatedAt:  g.CreatedAt,
-		})
+		response = append(response, newGroupResponse(g))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -209,15 +333,109 @@ func (h *GroupHandler) GetGroup(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, GroupDetailResponse{
-		ID:          group.ID,
-		Name:        group.Name,
-		HeadUserID:  group.HeadUserID,
-		CreatedAt:   group.CreatedAt,
-		Members:     memberResponses,
-		ChoresCount: choresCount,
+		ID:                  group.ID,
+		Name:                group.Name,
+		HeadUserID:          group.HeadUserID,
+		MaxBackdateDays:     group.MaxBackdateDays,
+		PendingPolicy:       group.PendingPolicy,
+		RequireRejectReason: group.RequireRejectReason,
+		CreatedAt:           group.CreatedAt,
+		Members:             memberResponses,
+		ChoresCount:         choresCount,
 	})
 }
 
+// UpdateGroup updates group settings (head only)
+// PATCH /api/v1/groups/:id
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	// Check if user is head of the group
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can update the group"})
+		return
+	}
+
+	var req UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil && *req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+	if req.MaxBackdateDays.Value != nil && *req.MaxBackdateDays.Value < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_backdate_days cannot be negative"})
+		return
+	}
+
+	settings := db.GroupSettings{
+		Name:                req.Name,
+		SetMaxBackdateDays:  req.MaxBackdateDays.Set,
+		MaxBackdateDays:     req.MaxBackdateDays.Value,
+		RequireRejectReason: req.RequireRejectReason,
+	}
+	if req.setsPendingPolicy() {
+		current, err := h.groupRepo.GetByID(c.Request.Context(), groupID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+			return
+		}
+		policy, err := mergePendingPolicy(current.PendingPolicy, req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.SetPendingPolicy = true
+		settings.PendingPolicy = policy
+	}
+
+	group, err := h.groupRepo.UpdateSettings(c.Request.Context(), groupID, settings)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newGroupResponse(group))
+}
+
 // ListMembers returns all members of a group
 // GET /api/v1/groups/:id/members
 func (h *GroupHandler) ListMembers(c *gin.Context) {
@@ -420,11 +638,12 @@ func (h *GroupHandler) JoinGroup(c *gin.Context) {
 	}
 
 	// Add user as member
-	_, err = h.groupRepo.AddMember(c.Request.Context(), invite.GroupID, userID, models.RoleMember)
+	member, err := h.groupRepo.AddMember(c.Request.Context(), invite.GroupID, userID, models.RoleMember)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join group"})
 		return
 	}
+	h.events.Publish(c.Request.Context(), events.New(events.MemberJoined, invite.GroupID, &userID, member))
 
 	// Get group details
 	group, err := h.groupRepo.GetByID(c.Request.Context(), invite.GroupID)
@@ -433,10 +652,5 @@ func (h *GroupHandler) JoinGroup(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, GroupResponse{
-		ID:         group.ID,
-		Name:       group.Name,
-		HeadUserID: group.HeadUserID,
-		CreatedAt:  group.CreatedAt,
-	})
+	c.JSON(http.StatusOK, newGroupResponse(group))
 }
@@ -0,0 +1,242 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/chain"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/testutil"
+)
+
+const integrationJWTSecret = "test-jwt-secret-for-integration-tests"
+
+// testApp is the API wired to a freshly migrated test database
+type testApp struct {
+	router *gin.Engine
+
+	users      *db.UserRepo
+	groups     *db.GroupRepo
+	chores     *db.ChoreRepo
+	ledger     *db.LedgerRepo
+	allowances *db.AllowanceRepo
+	loans      *db.LoanRepo
+
+	mu        sync.Mutex
+	published []events.Event
+}
+
+func setupIntegrationRouter(t *testing.T) (*testApp, func()) {
+	gin.SetMode(gin.TestMode)
+
+	pool, err := testutil.NewTestPool()
+	if err != nil {
+		t.Skipf("Skipping test: could not connect to test database: %v", err)
+	}
+
+	// Full reset to ensure clean state (drops schema + data)
+	_ = testutil.ResetTestDB(pool)
+
+	// Run migrations
+	dbURL := testutil.GetTestDatabaseURL()
+	err = db.RunMigrations(dbURL)
+	require.NoError(t, err)
+
+	chainRepo := db.NewChainRepo(pool, chain.NewHasher("test-ledger-chain-key"))
+	app := &testApp{
+		users:      db.NewUserRepo(pool),
+		groups:     db.NewGroupRepo(pool),
+		chores:     db.NewChoreRepo(pool),
+		ledger:     db.NewLedgerRepo(pool, chainRepo),
+		allowances: db.NewAllowanceRepo(pool, chainRepo),
+		loans:      db.NewLoanRepo(pool, chainRepo),
+	}
+
+	bus := events.NewBus()
+	bus.Subscribe("test", func(_ context.Context, e events.Event) error {
+		app.mu.Lock()
+		defer app.mu.Unlock()
+		app.published = append(app.published, e)
+		return nil
+	})
+	choreHandler := handlers.NewChoreHandler(app.chores, app.groups, bus)
+	ledgerHandler := handlers.NewLedgerHandler(app.ledger, app.groups, app.chores, chainRepo, bus)
+	approvalRuleHandler := handlers.NewApprovalRuleHandler(db.NewApprovalRuleRepo(pool), app.groups, app.chores)
+	settlementHandler := handlers.NewSettlementHandler(db.NewSettlementRepo(pool, chainRepo), app.groups, bus)
+	allowanceHandler := handlers.NewAllowanceHandler(app.allowances, app.groups, bus)
+	jarHandler := handlers.NewJarHandler(db.NewJarRepo(pool), app.ledger, app.groups)
+	loanHandler := handlers.NewLoanHandler(app.loans, app.groups, bus)
+
+	router := gin.New()
+	protected := router.Group("/api/v1")
+	protected.Use(auth.AuthMiddleware(integrationJWTSecret))
+	{
+		protected.GET("/groups/:id/chores", choreHandler.ListChores)
+		protected.DELETE("/chores/:id", choreHandler.DeleteChore)
+
+		protected.GET("/groups/:id/ledger", ledgerHandler.ListLedger)
+		protected.POST("/groups/:id/ledger", ledgerHandler.CreateLedger)
+		protected.POST("/ledger/:id/approve", ledgerHandler.ApproveLedger)
+		protected.POST("/ledger/:id/reject", ledgerHandler.RejectLedger)
+		protected.POST("/groups/:id/pending/approve", ledgerHandler.ApprovePending)
+		protected.POST("/groups/:id/pending/reject", ledgerHandler.RejectPending)
+		protected.GET("/groups/:id/balance", ledgerHandler.GetBalance)
+		protected.GET("/groups/:id/ledger/verify", ledgerHandler.VerifyLedger)
+
+		protected.POST("/groups/:id/approval-rules", approvalRuleHandler.CreateApprovalRule)
+
+		protected.POST("/groups/:id/settlements", settlementHandler.CreateSettlement)
+		protected.PATCH("/settlements/:id", settlementHandler.UpdateSettlement)
+		protected.POST("/settlements/:id/void", settlementHandler.VoidSettlement)
+		protected.GET("/settlements/:id/revisions", settlementHandler.ListSettlementRevisions)
+
+		protected.POST("/groups/:id/allowances", allowanceHandler.CreateAllowance)
+		protected.POST("/groups/:id/allowances/:allowance_id/pause", allowanceHandler.PauseAllowance)
+		protected.POST("/groups/:id/allowances/:allowance_id/resume", allowanceHandler.ResumeAllowance)
+
+		protected.GET("/groups/:id/members/:user_id/jars", jarHandler.ListJars)
+		protected.PUT("/groups/:id/members/:user_id/jars", jarHandler.SetJars)
+		protected.POST("/groups/:id/members/:user_id/jars/transfers", jarHandler.TransferBetweenJars)
+
+		protected.POST("/groups/:id/loans", loanHandler.CreateLoan)
+		protected.GET("/groups/:id/loans/:loan_id", loanHandler.GetLoan)
+	}
+	app.router = router
+
+	cleanup := func() {
+		testutil.CleanupTestDB(pool)
+		pool.Close()
+	}
+
+	return app, cleanup
+}
+
+// newUser creates a user and returns its ID and a bearer token
+func (a *testApp) newUser(t *testing.T, name string) (uuid.UUID, string) {
+	email := fmt.Sprintf("%s-%s@example.com", name, uuid.NewString()[:8])
+	user, err := a.users.Create(context.Background(), email, "hash", name, nil, nil)
+	require.NoError(t, err)
+
+	token, err := auth.IssueToken(user.ID.String(), integrationJWTSecret)
+	require.NoError(t, err)
+	return user.ID, token
+}
+
+// newGroup creates a group headed by head with the given members
+func (a *testApp) newGroup(t *testing.T, head uuid.UUID, members ...uuid.UUID) uuid.UUID {
+	ctx := context.Background()
+	group, err := a.groups.Create(ctx, "Family", head)
+	require.NoError(t, err)
+
+	_, err = a.groups.AddMember(ctx, group.ID, head, models.RoleHead)
+	require.NoError(t, err)
+	for _, m := range members {
+		_, err = a.groups.AddMember(ctx, group.ID, m, models.RoleMember)
+		require.NoError(t, err)
+	}
+	return group.ID
+}
+
+// newChore creates a chore worth amount in a group
+func (a *testApp) newChore(t *testing.T, groupID uuid.UUID, name string, amount float64) uuid.UUID {
+	chore, err := a.chores.Create(context.Background(), groupID, name, nil, amount, false, nil, nil)
+	require.NoError(t, err)
+	return chore.ID
+}
+
+// do sends a request as the token's user, encoding body as JSON when it is not nil
+func (a *testApp) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonBody)
+	}
+
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals a JSON response body
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	var v T
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v), w.Body.String())
+	return v
+}
+
+// logEntry records a chore entry as the token's user and returns the new entry
+func (a *testApp) logEntry(t *testing.T, groupID, choreID uuid.UUID, token string, amount float64) handlers.LedgerResponse {
+	w := a.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/ledger", groupID), token, map[string]any{
+		"chore_id": choreID,
+		"amount":   amount,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return decode[handlers.LedgerResponse](t, w)
+}
+
+// verifyChain checks the group's hash chain through the API
+func (a *testApp) verifyChain(t *testing.T, groupID uuid.UUID, token string) *chain.Report {
+	w := a.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/ledger/verify", groupID), token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return decode[*chain.Report](t, w)
+}
+
+// recordEntry records an approved entry for a member as the head, occurring at occurredAt
+func (a *testApp) recordEntry(t *testing.T, groupID, choreID uuid.UUID, headToken string, userID uuid.UUID, amount float64, occurredAt time.Time) handlers.LedgerResponse {
+	w := a.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/ledger", groupID), headToken, map[string]any{
+		"user_id":     userID,
+		"chore_id":    choreID,
+		"amount":      amount,
+		"occurred_at": occurredAt,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return decode[handlers.LedgerResponse](t, w)
+}
+
+// balances returns the group's balances by member, with query appended to the request
+func (a *testApp) balances(t *testing.T, groupID uuid.UUID, token, query string) map[uuid.UUID]handlers.BalanceResponse {
+	w := a.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/balance%s", groupID, query), token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	byUser := make(map[uuid.UUID]handlers.BalanceResponse)
+	for _, b := range decode[[]handlers.BalanceResponse](t, w) {
+		byUser[b.UserID] = b
+	}
+	return byUser
+}
+
+// ledgerEvents returns the types of the events published about a ledger entry, in order
+func (a *testApp) ledgerEvents(entryID uuid.UUID) []events.Type {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var types []events.Type
+	for _, e := range a.published {
+		if res, ok := e.Data.(handlers.LedgerResponse); ok && res.ID == entryID {
+			types = append(types, e.Type)
+		}
+	}
+	return types
+}
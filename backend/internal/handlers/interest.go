@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// InterestHandler handles interest rate requests
+type InterestHandler struct {
+	interestRepo *db.InterestRepo
+	groupRepo    *db.GroupRepo
+}
+
+// NewInterestHandler creates a new InterestHandler
+func NewInterestHandler(interestRepo *db.InterestRepo, groupRepo *db.GroupRepo) *InterestHandler {
+	return &InterestHandler{
+		interestRepo: interestRepo,
+		groupRepo:    groupRepo,
+	}
+}
+
+// CreateInterestRateRequest represents the request body for creating an
+// interest rate. Without a jar it covers the whole group. StartDate
+// defaults to today.
+type CreateInterestRateRequest struct {
+	JarID       *uuid.UUID               `json:"jar_id"`
+	APR         float64                  `json:"apr" binding:"required,gt=0,lte=100"` // Percent a year
+	Compounding models.AllowanceInterval `json:"compounding" binding:"required"`
+	MinBalance  float64                  `json:"min_balance" binding:"gte=0"`
+	StartDate   *string                  `json:"start_date"` // YYYY-MM-DD format
+}
+
+// UpdateInterestRateRequest represents the request body for changing an interest rate
+type UpdateInterestRateRequest struct {
+	APR        *float64 `json:"apr" binding:"omitempty,gt=0,lte=100"`
+	MinBalance *float64 `json:"min_balance" binding:"omitempty,gte=0"`
+}
+
+// interestRateStart validates a rate's compounding period and start date.
+// A missing start date is today; past dates are refused so a new rate
+// never pays for days before it was set up.
+func interestRateStart(compounding models.AllowanceInterval, startDate *string, now time.Time) (time.Time, error) {
+	switch compounding {
+	case models.IntervalWeek, models.IntervalMonth:
+	default:
+		return time.Time{}, errors.New("compounding must be week or month")
+	}
+	return allowanceStart(compounding, startDate, now)
+}
+
+// headOfGroup checks the caller is a head of the group in the id param and
+// returns its ID, writing the error response otherwise
+func (h *InterestHandler) headOfGroup(c *gin.Context, action string) (uuid.UUID, uuid.UUID, bool) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return uuid.Nil, uuid.Nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can " + action})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, groupID, true
+}
+
+// groupRate looks up the current rate in the rate_id param of the group,
+// writing the error response if there is none
+func (h *InterestHandler) groupRate(c *gin.Context, groupID uuid.UUID) (*models.InterestRate, bool) {
+	rateID, err := uuid.Parse(c.Param("rate_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interest rate ID"})
+		return nil, false
+	}
+
+	rate, err := h.interestRepo.GetByID(c.Request.Context(), rateID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get interest rate"})
+		return nil, false
+	}
+	if err != nil || rate.GroupID != groupID || rate.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "interest rate not found"})
+		return nil, false
+	}
+	return rate, true
+}
+
+// ListInterestRates returns a group's interest rates; deleted ones are
+// included with include_deleted=true
+// GET /api/v1/groups/:id/interest-rates
+func (h *InterestHandler) ListInterestRates(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	includeDeleted, err := queryBool(c, "include_deleted")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rates, err := h.interestRepo.ListForGroup(c.Request.Context(), groupID, includeDeleted != nil && *includeDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list interest rates"})
+		return
+	}
+	if rates == nil {
+		rates = []*models.InterestRate{}
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// CreateInterestRate sets up interest on the group's balances or on one
+// jar (head only). Interest is paid at the end of each compounding period
+// on the average daily balance over it.
+// POST /api/v1/groups/:id/interest-rates
+func (h *InterestHandler) CreateInterestRate(c *gin.Context) {
+	userID, groupID, ok := h.headOfGroup(c, "set interest rates")
+	if !ok {
+		return
+	}
+
+	var req CreateInterestRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, err := interestRateStart(req.Compounding, req.StartDate, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.interestRepo.Create(c.Request.Context(), &models.InterestRate{
+		GroupID:         groupID,
+		JarID:           req.JarID,
+		APR:             req.APR,
+		Compounding:     req.Compounding,
+		MinBalance:      req.MinBalance,
+		StartDate:       start,
+		CreatedByUserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUnknownJar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "jar is not a jar of this group"})
+		case errors.Is(err, db.ErrDuplicateRate):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create interest rate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateInterestRate changes a rate's APR or minimum balance for periods
+// not yet posted (head only)
+// PATCH /api/v1/groups/:id/interest-rates/:rate_id
+func (h *InterestHandler) UpdateInterestRate(c *gin.Context) {
+	_, groupID, ok := h.headOfGroup(c, "update interest rates")
+	if !ok {
+		return
+	}
+
+	var req UpdateInterestRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, ok := h.groupRate(c, groupID)
+	if !ok {
+		return
+	}
+
+	updated, err := h.interestRepo.Update(c.Request.Context(), rate.ID, db.InterestRateUpdate{
+		APR:        req.APR,
+		MinBalance: req.MinBalance,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "interest rate not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update interest rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteInterestRate stops a rate for good; interest it posted is kept
+// (head only)
+// DELETE /api/v1/groups/:id/interest-rates/:rate_id
+func (h *InterestHandler) DeleteInterestRate(c *gin.Context) {
+	_, groupID, ok := h.headOfGroup(c, "delete interest rates")
+	if !ok {
+		return
+	}
+
+	rate, ok := h.groupRate(c, groupID)
+	if !ok {
+		return
+	}
+
+	if err := h.interestRepo.Delete(c.Request.Context(), rate.ID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "interest rate not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete interest rate"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListInterestPostings returns the interest paid in a group, optionally to
+// one member with user_id, each with how it was worked out
+// GET /api/v1/groups/:id/interest/postings
+func (h *InterestHandler) ListInterestPostings(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	memberID, err := queryUUID(c, "user_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	postings, err := h.interestRepo.ListPostings(c.Request.Context(), groupID, memberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list interest postings"})
+		return
+	}
+	if postings == nil {
+		postings = []*models.InterestPosting{}
+	}
+
+	c.JSON(http.StatusOK, postings)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/jars"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// JarHandler handles requests for members' jars
+type JarHandler struct {
+	jarRepo    *db.JarRepo
+	ledgerRepo *db.LedgerRepo
+	groupRepo  *db.GroupRepo
+}
+
+// NewJarHandler creates a new JarHandler
+func NewJarHandler(jarRepo *db.JarRepo, ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo) *JarHandler {
+	return &JarHandler{
+		jarRepo:    jarRepo,
+		ledgerRepo: ledgerRepo,
+		groupRepo:  groupRepo,
+	}
+}
+
+// JarRequest describes one jar; an ID keeps an existing jar, otherwise a
+// new one is added
+type JarRequest struct {
+	ID           *uuid.UUID `json:"id"`
+	Name         string     `json:"name"`
+	SplitPercent float64    `json:"split_percent"`
+}
+
+// SetJarsRequest represents the request body for replacing a member's jars
+type SetJarsRequest struct {
+	Jars []JarRequest `json:"jars"`
+}
+
+// JarTransferRequest represents the request body for moving money between jars
+type JarTransferRequest struct {
+	FromJarID uuid.UUID `json:"from_jar_id" binding:"required"`
+	ToJarID   uuid.UUID `json:"to_jar_id" binding:"required"`
+	Amount    float64   `json:"amount" binding:"required,gt=0"`
+	Note      *string   `json:"note"`
+}
+
+// JarsResponse represents a member's jars in API responses. Unallocated is
+// the part of their balance in no jar.
+type JarsResponse struct {
+	Jars        []*models.Jar `json:"jars"`
+	Unallocated float64       `json:"unallocated"`
+}
+
+// jarSpecs checks a member's new jars: each needs a name, unique among
+// them, and their split percentages must add up to 100
+func jarSpecs(req []JarRequest) ([]db.JarSpec, error) {
+	specs := make([]db.JarSpec, 0, len(req))
+	names := make(map[string]bool, len(req))
+	percents := make([]float64, 0, len(req))
+	for _, j := range req {
+		name := strings.TrimSpace(j.Name)
+		if name == "" {
+			return nil, errors.New("every jar needs a name")
+		}
+		if len(name) > 50 {
+			return nil, errors.New("jar names must be at most 50 characters")
+		}
+		if names[strings.ToLower(name)] {
+			return nil, fmt.Errorf("jar %q is listed twice", name)
+		}
+		names[strings.ToLower(name)] = true
+		specs = append(specs, db.JarSpec{ID: j.ID, Name: name, SplitPercent: j.SplitPercent})
+		percents = append(percents, j.SplitPercent)
+	}
+	if err := jars.ValidateSplit(percents); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// respondWithJars writes the member's jars and what of their balance is in none
+func (h *JarHandler) respondWithJars(c *gin.Context, groupID, memberID uuid.UUID, list []*models.Jar, now time.Time) {
+	balance, err := h.ledgerRepo.GetMemberBalance(c.Request.Context(), groupID, memberID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get balance"})
+		return
+	}
+
+	if list == nil {
+		list = []*models.Jar{}
+	}
+	unallocated := balance
+	for _, j := range list {
+		unallocated -= j.Balance
+	}
+	c.JSON(http.StatusOK, JarsResponse{Jars: list, Unallocated: math.Round(unallocated*100) / 100})
+}
+
+// ListJars returns a member's jars with their balances
+// GET /api/v1/groups/:id/members/:user_id/jars
+func (h *JarHandler) ListJars(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	list, err := h.jarRepo.ListForMember(c.Request.Context(), groupID, memberID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jars"})
+		return
+	}
+
+	h.respondWithJars(c, groupID, memberID, list, now)
+}
+
+// SetJars replaces a member's jars and their split (head only). Jars left
+// out are removed, which they can only be once empty. The new split applies
+// to entries approved from now on.
+// PUT /api/v1/groups/:id/members/:user_id/jars
+func (h *JarHandler) SetJars(c *gin.Context) {
+	userID, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can set jars"})
+		return
+	}
+
+	var req SetJarsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	specs, err := jarSpecs(req.Jars)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.jarRepo.Replace(c.Request.Context(), groupID, memberID, specs)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUnknownJar), errors.Is(err, db.ErrDuplicateJar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, db.ErrJarNotEmpty):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set jars"})
+		}
+		return
+	}
+
+	h.respondWithJars(c, groupID, memberID, list, time.Now())
+}
+
+// ListJarTransfers returns the transfers between a member's jars, oldest first
+// GET /api/v1/groups/:id/members/:user_id/jars/transfers
+func (h *JarHandler) ListJarTransfers(c *gin.Context) {
+	_, groupID, memberID, _, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	transfers, err := h.jarRepo.ListTransfers(c.Request.Context(), groupID, memberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jar transfers"})
+		return
+	}
+	if transfers == nil {
+		transfers = []*models.JarTransfer{}
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+// TransferBetweenJars moves money from one of a member's jars to another
+// (the member or a head)
+// POST /api/v1/groups/:id/members/:user_id/jars/transfers
+func (h *JarHandler) TransferBetweenJars(c *gin.Context) {
+	userID, groupID, memberID, canManage, ok := memberScope(c, h.groupRepo)
+	if !ok {
+		return
+	}
+
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the member or a group head can move money between jars"})
+		return
+	}
+
+	var req JarTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.FromJarID == req.ToJarID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot transfer a jar to itself"})
+		return
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		req.Note = &note
+		if note == "" {
+			req.Note = nil
+		}
+	}
+
+	transfer, err := h.jarRepo.Transfer(c.Request.Context(), &models.JarTransfer{
+		GroupID:         groupID,
+		UserID:          memberID,
+		FromJarID:       req.FromJarID,
+		ToJarID:         req.ToJarID,
+		Amount:          req.Amount,
+		Note:            req.Note,
+		CreatedByUserID: userID,
+	})
+	if err != nil {
+		var jarBalance *db.JarBalanceError
+		switch {
+		case errors.Is(err, db.ErrUnknownJar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.As(err, &jarBalance):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer between jars"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
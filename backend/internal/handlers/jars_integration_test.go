@@ -0,0 +1,91 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+)
+
+func TestJars_SplitAndTransfer(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 10)
+	path := fmt.Sprintf("/api/v1/groups/%s/members/%s/jars", groupID, kid)
+
+	// Earned before the jars existed, so it stays unallocated
+	app.recordEntry(t, groupID, choreID, headToken, kid, 4, time.Now().Add(-time.Hour))
+
+	w := app.do(http.MethodPut, path, kidToken, map[string]any{"jars": []map[string]any{{"name": "Spend", "split_percent": 100}}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = app.do(http.MethodPut, path, headToken, map[string]any{"jars": []map[string]any{
+		{"name": "Spend", "split_percent": 50},
+		{"name": "Save", "split_percent": 30},
+		{"name": "Give", "split_percent": 20},
+	}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	jarIDs := make(map[string]uuid.UUID)
+	for _, j := range decode[handlers.JarsResponse](t, w).Jars {
+		jarIDs[j.Name] = j.ID
+	}
+
+	jarBalances := func() (map[string]float64, float64) {
+		w := app.do(http.MethodGet, path, kidToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		resp := decode[handlers.JarsResponse](t, w)
+		byName := make(map[string]float64)
+		for _, j := range resp.Jars {
+			byName[j.Name] = j.Balance
+		}
+		return byName, resp.Unallocated
+	}
+
+	// Entries are split when they are approved, not when they are logged
+	approved := app.logEntry(t, groupID, choreID, kidToken, 10)
+	rejected := app.logEntry(t, groupID, choreID, kidToken, 10)
+	balances, unallocated := jarBalances()
+	assert.Equal(t, map[string]float64{"Spend": 0, "Save": 0, "Give": 0}, balances)
+	assert.Equal(t, 4.0, unallocated)
+
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", approved.ID), headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/reject", rejected.ID), headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	balances, unallocated = jarBalances()
+	assert.Equal(t, map[string]float64{"Spend": 5, "Save": 3, "Give": 2}, balances)
+	assert.Equal(t, 4.0, unallocated)
+
+	transfer := func(from, to string, amount float64) int {
+		return app.do(http.MethodPost, path+"/transfers", kidToken, map[string]any{
+			"from_jar_id": jarIDs[from],
+			"to_jar_id":   jarIDs[to],
+			"amount":      amount,
+		}).Code
+	}
+	assert.Equal(t, http.StatusCreated, transfer("Save", "Spend", 2))
+	assert.Equal(t, http.StatusConflict, transfer("Save", "Spend", 5), "more than the jar holds")
+	assert.Equal(t, http.StatusBadRequest, transfer("Give", "Give", 1))
+
+	balances, unallocated = jarBalances()
+	assert.Equal(t, map[string]float64{"Spend": 7, "Save": 1, "Give": 2}, balances)
+	assert.Equal(t, 4.0, unallocated)
+
+	// The group balance shows the same split
+	b := app.balances(t, groupID, kidToken, "")[kid]
+	assert.Equal(t, 14.0, b.Balance)
+	require.NotNil(t, b.Unallocated)
+	assert.Equal(t, 4.0, *b.Unallocated)
+	assert.Len(t, b.Jars, 3)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/jars"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestJarSpecs(t *testing.T) {
+	id := uuid.New()
+	specs, err := jarSpecs([]JarRequest{
+		{ID: &id, Name: " Spend ", SplitPercent: 50},
+		{Name: "Save", SplitPercent: 40},
+		{Name: "Give", SplitPercent: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, specs, 3)
+	assert.Equal(t, &id, specs[0].ID)
+	assert.Equal(t, "Spend", specs[0].Name)
+	assert.Nil(t, specs[1].ID)
+
+	specs, err = jarSpecs(nil)
+	require.NoError(t, err)
+	assert.Empty(t, specs)
+
+	_, err = jarSpecs([]JarRequest{{Name: "  ", SplitPercent: 100}})
+	assert.EqualError(t, err, "every jar needs a name")
+
+	_, err = jarSpecs([]JarRequest{{Name: strings.Repeat("x", 51), SplitPercent: 100}})
+	assert.EqualError(t, err, "jar names must be at most 50 characters")
+
+	_, err = jarSpecs([]JarRequest{{Name: "Save", SplitPercent: 50}, {Name: "save", SplitPercent: 50}})
+	assert.EqualError(t, err, `jar "save" is listed twice`)
+
+	_, err = jarSpecs([]JarRequest{{Name: "Spend", SplitPercent: 50}, {Name: "Save", SplitPercent: 40}})
+	assert.ErrorIs(t, err, jars.ErrSplitTotal)
+}
+
+func TestNewBalanceResponse(t *testing.T) {
+	jarID := uuid.New()
+	unallocated := 2.5
+	response := newBalanceResponse(&models.Balance{
+		UserID:      uuid.New(),
+		Name:        "Kid",
+		Balance:     12.5,
+		Jars:        []*models.JarBalance{{JarID: jarID, Name: "Save", SplitPercent: 100, Balance: 10}},
+		Unallocated: &unallocated,
+	})
+
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &fields))
+	assert.Equal(t, 2.5, fields["unallocated"])
+	require.Len(t, fields["jars"], 1)
+	jar := fields["jars"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, jarID.String(), jar["jar_id"])
+	assert.Equal(t, 10.0, jar["balance"])
+	assert.NotContains(t, fields, "loans")
+
+	// Members without jars have neither field
+	body, err = json.Marshal(newBalanceResponse(&models.Balance{UserID: uuid.New(), Balance: 3}))
+	require.NoError(t, err)
+	fields = nil
+	require.NoError(t, json.Unmarshal(body, &fields))
+	assert.NotContains(t, fields, "jars")
+	assert.NotContains(t, fields, "unallocated")
+}
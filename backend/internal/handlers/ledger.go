@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/srjn45/pocket-money/backend/internal/auth"
 	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
@@ -18,46 +23,217 @@ type LedgerHandler struct {
 	ledgerRepo *db.LedgerRepo
 	groupRepo  *db.GroupRepo
 	choreRepo  *db.ChoreRepo
+	chainRepo  *db.ChainRepo
+	events     *events.Bus
 }
 
 // NewLedgerHandler creates a new LedgerHandler
-func NewLedgerHandler(ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo, choreRepo *db.ChoreRepo) *LedgerHandler {
+func NewLedgerHandler(ledgerRepo *db.LedgerRepo, groupRepo *db.GroupRepo, choreRepo *db.ChoreRepo, chainRepo *db.ChainRepo, bus *events.Bus) *LedgerHandler {
 	return &LedgerHandler{
 		ledgerRepo: ledgerRepo,
 		groupRepo:  groupRepo,
 		choreRepo:  choreRepo,
+		chainRepo:  chainRepo,
+		events:     bus,
 	}
 }
 
 // CreateLedgerRequest represents the request body for creating a ledger entry
 type CreateLedgerRequest struct {
-	UserID  *uuid.UUID `json:"user_id"` // Optional, only head can specify
-	ChoreID uuid.UUID  `json:"chore_id" binding:"required"`
-	Amount  float64    `json:"amount" binding:"required,gt=0"`
+	UserID     *uuid.UUID `json:"user_id"` // Optional, only head can specify
+	ChoreID    uuid.UUID  `json:"chore_id" binding:"required"`
+	Amount     float64    `json:"amount" binding:"required,gt=0"`
+	OccurredAt *time.Time `json:"occurred_at"` // Optional, defaults to now
+}
+
+// maxClockSkew tolerates client clocks running slightly ahead of the server
+const maxClockSkew = 5 * time.Minute
+
+// validateOccurredAt checks a requested occurred_at against the group's backdating rules.
+// Nobody may record a chore in the future; heads may backdate without limit.
+func validateOccurredAt(occurredAt, now time.Time, maxBackdateDays *int, isHead bool) error {
+	if occurredAt.After(now.Add(maxClockSkew)) {
+		return errors.New("occurred_at cannot be in the future")
+	}
+	if isHead || maxBackdateDays == nil {
+		return nil
+	}
+	if occurredAt.Before(now.AddDate(0, 0, -*maxBackdateDays)) {
+		return fmt.Errorf("occurred_at cannot be more than %d days in the past", *maxBackdateDays)
+	}
+	return nil
+}
+
+// maxReasonLength is the longest rejection reason, in characters
+const maxReasonLength = 500
+
+// RejectLedgerRequest represents the optional request body for rejecting an entry
+type RejectLedgerRequest struct {
+	Reason string `json:"reason"`
+}
+
+// rejectReason checks a rejection reason and returns it trimmed, or nil when none was given
+func rejectReason(reason string, required bool) (*string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		if required {
+			return nil, errors.New("reason is required to reject entries in this group")
+		}
+		return nil, nil
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return nil, fmt.Errorf("reason must be at most %d characters", maxReasonLength)
+	}
+	return &reason, nil
+}
+
+// ResubmitLedgerRequest represents the optional request body for resubmitting
+// a rejected entry
+type ResubmitLedgerRequest struct {
+	Amount     *float64   `json:"amount" binding:"omitempty,gt=0"`
+	OccurredAt *time.Time `json:"occurred_at"`
 }
 
 // LedgerResponse represents a ledger entry in API responses
 type LedgerResponse struct {
-	ID               uuid.UUID           `json:"id"`
-	GroupID          uuid.UUID           `json:"group_id"`
-	UserID           uuid.UUID           `json:"user_id"`
-	ChoreID          uuid.UUID           `json:"chore_id"`
-	Amount           float64             `json:"amount"`
-	Status           models.LedgerStatus `json:"status"`
-	CreatedByUserID  uuid.UUID           `json:"created_by_user_id"`
-	ApprovedByUserID *uuid.UUID          `json:"approved_by_user_id,omitempty"`
-	RejectedByUserID *uuid.UUID          `json:"rejected_by_user_id,omitempty"`
-	CreatedAt        time.Time           `json:"created_at"`
+	ID                uuid.UUID           `json:"id"`
+	GroupID           uuid.UUID           `json:"group_id"`
+	UserID            uuid.UUID           `json:"user_id"`
+	Kind              models.EntryKind    `json:"kind"`
+	ChoreID           *uuid.UUID          `json:"chore_id"`
+	AllowanceID       *uuid.UUID          `json:"allowance_id,omitempty"`
+	AllowancePeriod   *time.Time          `json:"allowance_period,omitempty"`
+	InterestRateID    *uuid.UUID          `json:"interest_rate_id,omitempty"`
+	LoanID            *uuid.UUID          `json:"loan_id,omitempty"`
+	WithheldFromID    *uuid.UUID          `json:"withheld_from_id,omitempty"`
+	Amount            float64             `json:"amount"`
+	Status            models.LedgerStatus `json:"status"`
+	CreatedByUserID   uuid.UUID           `json:"created_by_user_id"`
+	ApprovedByUserID  *uuid.UUID          `json:"approved_by_user_id,omitempty"`
+	RejectedByUserID  *uuid.UUID          `json:"rejected_by_user_id,omitempty"`
+	StatusReason      *string             `json:"status_reason,omitempty"`
+	SystemActor       *models.SystemActor `json:"system_actor,omitempty"`
+	ApprovalRuleID    *uuid.UUID          `json:"approval_rule_id,omitempty"`
+	ResubmittedFromID *uuid.UUID          `json:"resubmitted_from_id,omitempty"`
+	OccurredAt        time.Time           `json:"occurred_at"`
+	CreatedAt         time.Time           `json:"created_at"`
+	Hash              *string             `json:"hash,omitempty"`
+}
+
+// newLedgerResponse converts a ledger entry to its API representation
+func newLedgerResponse(e *models.LedgerEntry) LedgerResponse {
+	return LedgerResponse{
+		ID:                e.ID,
+		GroupID:           e.GroupID,
+		UserID:            e.UserID,
+		Kind:              e.Kind,
+		ChoreID:           e.ChoreID,
+		AllowanceID:       e.AllowanceID,
+		AllowancePeriod:   e.AllowancePeriod,
+		InterestRateID:    e.InterestRateID,
+		LoanID:            e.LoanID,
+		WithheldFromID:    e.WithheldFromID,
+		Amount:            e.Amount,
+		Status:            e.Status,
+		CreatedByUserID:   e.CreatedByUserID,
+		ApprovedByUserID:  e.ApprovedByUserID,
+		RejectedByUserID:  e.RejectedByUserID,
+		StatusReason:      e.StatusReason,
+		SystemActor:       e.SystemActor,
+		ApprovalRuleID:    e.ApprovalRuleID,
+		ResubmittedFromID: e.ResubmittedFromID,
+		OccurredAt:        e.OccurredAt,
+		CreatedAt:         e.CreatedAt,
+		Hash:              e.Hash,
+	}
 }
 
 // BalanceResponse represents a user's balance
 type BalanceResponse struct {
-	UserID  uuid.UUID `json:"user_id"`
-	Name    string    `json:"name"`
-	Balance float64   `json:"balance"`
+	UserID          uuid.UUID `json:"user_id"`
+	Name            string    `json:"name"`
+	Balance         float64   `json:"balance"`
+	ConfirmedPaid   float64   `json:"confirmed_paid"`   // Payouts the member acknowledged
+	UnconfirmedPaid float64   `json:"unconfirmed_paid"` // Paid but not yet acknowledged, or disputed
+	// Jars split the balance for members who have jars; Unallocated is the
+	// part of it in no jar
+	Jars        []*models.JarBalance `json:"jars,omitempty"`
+	Unallocated *float64             `json:"unallocated,omitempty"`
+	// Loans are what the member still owes on each outstanding loan
+	Loans []*models.LoanBalance `json:"loans,omitempty"`
 }
 
-// ListLedger returns ledger entries for a group
+// newBalanceResponse converts a balance to its API representation
+func newBalanceResponse(b *models.Balance) BalanceResponse {
+	return BalanceResponse{
+		UserID:          b.UserID,
+		Name:            b.Name,
+		Balance:         b.Balance,
+		ConfirmedPaid:   b.ConfirmedPaid,
+		UnconfirmedPaid: b.UnconfirmedPaid,
+		Jars:            b.Jars,
+		Unallocated:     b.Unallocated,
+		Loans:           b.Loans,
+	}
+}
+
+// BalanceHistoryResponse represents a member's running balance series
+type BalanceHistoryResponse struct {
+	UserID         uuid.UUID             `json:"user_id"`
+	Interval       db.BalanceInterval    `json:"interval"`
+	OpeningBalance float64               `json:"opening_balance"`
+	Points         []models.BalancePoint `json:"points"`
+}
+
+// defaultHistoryPeriods is how many intervals a balance history covers without from
+const defaultHistoryPeriods = 12
+
+// queryEntryKind parses the optional kind query parameter
+func queryEntryKind(c *gin.Context) (*models.EntryKind, error) {
+	kind := models.EntryKind(c.Query("kind"))
+	switch kind {
+	case "":
+		return nil, nil
+	case models.EntryChore, models.EntryAllowance, models.EntryInterest, models.EntryLoan, models.EntryRepayment:
+		return &kind, nil
+	}
+	return nil, fmt.Errorf("invalid kind %q", kind)
+}
+
+// parseLedgerFilter reads the ledger listing filters shared by ListLedger and ListPending
+func parseLedgerFilter(c *gin.Context) (db.LedgerFilter, error) {
+	var filter db.LedgerFilter
+	var err error
+
+	if filter.Kind, err = queryEntryKind(c); err != nil {
+		return filter, err
+	}
+	if filter.UserID, err = queryUUID(c, "user_id"); err != nil {
+		return filter, err
+	}
+	if filter.ChoreID, err = queryUUID(c, "chore_id"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedByUserID, err = queryUUID(c, "created_by"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = queryTime(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to", true); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = queryFloat(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = queryFloat(c, "max_amount"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// ListLedger returns a page of ledger entries for a group
 // GET /api/v1/groups/:id/ledger
 func (h *LedgerHandler) ListLedger(c *gin.Context) {
 	userIDStr, exists := auth.GetUserID(c)
@@ -90,37 +266,42 @@ func (h *LedgerHandler) ListLedger(c *gin.Context) {
 		return
 	}
 
+	filter, err := parseLedgerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Parse optional status filter
-	var status *models.LedgerStatus
 	if statusStr := c.Query("status"); statusStr != "" {
 		s := models.LedgerStatus(statusStr)
 		if s != models.StatusApproved && s != models.StatusPendingApproval && s != models.StatusRejected {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
 			return
 		}
-		status = &s
+		filter.Status = &s
+	}
+
+	page, err := parsePage(c, "-occurred_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	entries, err := h.ledgerRepo.ListForGroup(c.Request.Context(), groupID, status)
+	entries, info, err := h.ledgerRepo.ListForGroup(c.Request.Context(), groupID, filter, page)
 	if err != nil {
+		if msg, ok := listErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list ledger entries"})
 		return
 	}
+	setPageHeaders(c, info)
 
 	response := make([]LedgerResponse, 0, len(entries))
 	for _, e := range entries {
-		response = append(response, LedgerResponse{
-			ID:               e.ID,
-			GroupID:          e.GroupID,
-			UserID:           e.UserID,
-			ChoreID:          e.ChoreID,
-			Amount:           e.Amount,
-			Status:           e.Status,
-			CreatedByUserID:  e.CreatedByUserID,
-			ApprovedByUserID: e.ApprovedByUserID,
-			RejectedByUserID: e.RejectedByUserID,
-			CreatedAt:        e.CreatedAt,
-		})
+		response = append(response, newLedgerResponse(e))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -179,6 +360,23 @@ func (h *LedgerHandler) CreateLedger(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "chore does not belong to this group"})
 		return
 	}
+	if chore.DeletedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chore has been archived"})
+		return
+	}
+
+	// Validate backdating against the group's limit
+	if req.OccurredAt != nil {
+		group, err := h.groupRepo.GetByID(c.Request.Context(), groupID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+			return
+		}
+		if err := validateOccurredAt(*req.OccurredAt, time.Now(), group.MaxBackdateDays, member.Role == models.RoleHead); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	var targetUserID uuid.UUID
 	var status models.LedgerStatus
@@ -204,30 +402,26 @@ func (h *LedgerHandler) CreateLedger(c *gin.Context) {
 		status = models.StatusApproved
 		approvedByUserID = &userID
 	} else {
-		// Member can only create for self, pending approval
+		// Member can only create for self, pending approval unless an approval rule matches
 		targetUserID = userID
 		status = models.StatusPendingApproval
 		approvedByUserID = nil
 	}
 
-	entry, err := h.ledgerRepo.Create(c.Request.Context(), groupID, targetUserID, req.ChoreID, userID, req.Amount, status, approvedByUserID)
+	entry, err := h.ledgerRepo.Create(c.Request.Context(), groupID, targetUserID, req.ChoreID, userID, req.Amount, status, approvedByUserID, req.OccurredAt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create ledger entry"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, LedgerResponse{
-		ID:               entry.ID,
-		GroupID:          entry.GroupID,
-		UserID:           entry.UserID,
-		ChoreID:          entry.ChoreID,
-		Amount:           entry.Amount,
-		Status:           entry.Status,
-		CreatedByUserID:  entry.CreatedByUserID,
-		ApprovedByUserID: entry.ApprovedByUserID,
-		RejectedByUserID: entry.RejectedByUserID,
-		CreatedAt:        entry.CreatedAt,
-	})
+	response := newLedgerResponse(entry)
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerCreated, groupID, &userID, response))
+	if entry.Status == models.StatusApproved {
+		// A head's entry, or one an approval rule matched, is approved as it is created
+		h.events.Publish(c.Request.Context(), events.New(events.LedgerApproved, groupID, &userID, response))
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // ApproveLedger approves a pending ledger entry
@@ -286,24 +480,25 @@ func (h *LedgerHandler) ApproveLedger(c *gin.Context) {
 	}
 
 	// Update status
-	updatedEntry, err := h.ledgerRepo.UpdateStatus(c.Request.Context(), entryID, models.StatusApproved, &userID, nil)
+	updatedEntry, err := h.ledgerRepo.UpdateStatus(c.Request.Context(), entryID, models.StatusApproved, &userID, nil, nil)
 	if err != nil {
+		if errors.Is(err, db.ErrProofRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entry needs a photo before it can be approved"})
+			return
+		}
+		// Decided by someone else since it was read above
+		if errors.Is(err, db.ErrNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": "entry is not pending approval"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve entry"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LedgerResponse{
-		ID:               updatedEntry.ID,
-		GroupID:          updatedEntry.GroupID,
-		UserID:           updatedEntry.UserID,
-		ChoreID:          updatedEntry.ChoreID,
-		Amount:           updatedEntry.Amount,
-		Status:           updatedEntry.Status,
-		CreatedByUserID:  updatedEntry.CreatedByUserID,
-		ApprovedByUserID: updatedEntry.ApprovedByUserID,
-		RejectedByUserID: updatedEntry.RejectedByUserID,
-		CreatedAt:        updatedEntry.CreatedAt,
-	})
+	response := newLedgerResponse(updatedEntry)
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerApproved, updatedEntry.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
 }
 
 // RejectLedger rejects a pending ledger entry
@@ -355,6 +550,24 @@ func (h *LedgerHandler) RejectLedger(c *gin.Context) {
 		return
 	}
 
+	// The body is optional unless the group requires a reason
+	var req RejectLedgerRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.groupRepo.GetByID(c.Request.Context(), entry.GroupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+		return
+	}
+	reason, err := rejectReason(req.Reason, group.RequireRejectReason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Check status is pending
 	if entry.Status != models.StatusPendingApproval {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "entry is not pending approval"})
@@ -362,24 +575,127 @@ func (h *LedgerHandler) RejectLedger(c *gin.Context) {
 	}
 
 	// Update status
-	updatedEntry, err := h.ledgerRepo.UpdateStatus(c.Request.Context(), entryID, models.StatusRejected, nil, &userID)
+	updatedEntry, err := h.ledgerRepo.UpdateStatus(c.Request.Context(), entryID, models.StatusRejected, nil, &userID, reason)
 	if err != nil {
+		// Decided by someone else since it was read above
+		if errors.Is(err, db.ErrNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": "entry is not pending approval"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reject entry"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LedgerResponse{
-		ID:               updatedEntry.ID,
-		GroupID:          updatedEntry.GroupID,
-		UserID:           updatedEntry.UserID,
-		ChoreID:          updatedEntry.ChoreID,
-		Amount:           updatedEntry.Amount,
-		Status:           updatedEntry.Status,
-		CreatedByUserID:  updatedEntry.CreatedByUserID,
-		ApprovedByUserID: updatedEntry.ApprovedByUserID,
-		RejectedByUserID: updatedEntry.RejectedByUserID,
-		CreatedAt:        updatedEntry.CreatedAt,
-	})
+	response := newLedgerResponse(updatedEntry)
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerRejected, updatedEntry.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResubmitLedger submits a rejected entry again as a new pending entry linked
+// to the original (the entry's member only)
+// POST /api/v1/ledger/:id/resubmit
+func (h *LedgerHandler) ResubmitLedger(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry ID"})
+		return
+	}
+
+	entry, err := h.ledgerRepo.GetByID(c.Request.Context(), entryID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get entry"})
+		return
+	}
+
+	if _, err := h.groupRepo.GetMember(c.Request.Context(), entry.GroupID, userID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if entry.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the entry's member can resubmit it"})
+		return
+	}
+
+	if entry.Status != models.StatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only rejected entries can be resubmitted"})
+		return
+	}
+
+	if entry.ChoreID != nil {
+		chore, err := h.choreRepo.GetByID(c.Request.Context(), *entry.ChoreID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get chore"})
+			return
+		}
+		if chore.DeletedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chore has been archived"})
+			return
+		}
+	}
+
+	// The body is optional; the original amount is used when none is given
+	var req ResubmitLedgerRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amount := entry.Amount
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+
+	if req.OccurredAt != nil {
+		group, err := h.groupRepo.GetByID(c.Request.Context(), entry.GroupID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+			return
+		}
+		if err := validateOccurredAt(*req.OccurredAt, time.Now(), group.MaxBackdateDays, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	resubmitted, err := h.ledgerRepo.Resubmit(c.Request.Context(), entry, userID, amount, req.OccurredAt)
+	if err != nil {
+		if errors.Is(err, db.ErrAlreadyResubmitted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "entry has already been resubmitted"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resubmit entry"})
+		return
+	}
+
+	response := newLedgerResponse(resubmitted)
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerCreated, resubmitted.GroupID, &userID, response))
+	if resubmitted.Status == models.StatusApproved {
+		h.events.Publish(c.Request.Context(), events.New(events.LedgerApproved, resubmitted.GroupID, &userID, response))
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // ListPending returns pending ledger entries for a group (head only)
@@ -420,33 +736,40 @@ func (h *LedgerHandler) ListPending(c *gin.Context) {
 		return
 	}
 
+	filter, err := parseLedgerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	status := models.StatusPendingApproval
-	entries, err := h.ledgerRepo.ListForGroup(c.Request.Context(), groupID, &status)
+	filter.Status = &status
+
+	page, err := parsePage(c, "-created_at")
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, info, err := h.ledgerRepo.ListForGroup(c.Request.Context(), groupID, filter, page)
+	if err != nil {
+		if msg, ok := listErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pending entries"})
 		return
 	}
+	setPageHeaders(c, info)
 
 	response := make([]LedgerResponse, 0, len(entries))
 	for _, e := range entries {
-		response = append(response, LedgerResponse{
-			ID:               e.ID,
-			GroupID:          e.GroupID,
-			UserID:           e.UserID,
-			ChoreID:          e.ChoreID,
-			Amount:           e.Amount,
-			Status:           e.Status,
-			CreatedByUserID:  e.CreatedByUserID,
-			ApprovedByUserID: e.ApprovedByUserID,
-			RejectedByUserID: e.RejectedByUserID,
-			CreatedAt:        e.CreatedAt,
-		})
+		response = append(response, newLedgerResponse(e))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetBalance returns per-member balances for a group
+// GetBalance returns per-member balances for a group, optionally as of a past date
 // GET /api/v1/groups/:id/balance
 func (h *LedgerHandler) GetBalance(c *gin.Context) {
 	userIDStr, exists := auth.GetUserID(c)
@@ -479,7 +802,13 @@ func (h *LedgerHandler) GetBalance(c *gin.Context) {
 		return
 	}
 
-	balances, err := h.ledgerRepo.GetBalanceForGroup(c.Request.Context(), groupID)
+	asOf, err := queryTime(c, "as_of", true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	balances, err := h.ledgerRepo.GetBalanceForGroup(c.Request.Context(), groupID, asOf)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get balances"})
 		return
@@ -487,12 +816,156 @@ func (h *LedgerHandler) GetBalance(c *gin.Context) {
 
 	response := make([]BalanceResponse, 0, len(balances))
 	for _, b := range balances {
-		response = append(response, BalanceResponse{
-			UserID:  b.UserID,
-			Name:    b.Name,
-			Balance: b.Balance,
-		})
+		response = append(response, newBalanceResponse(b))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBalanceHistory returns a member's running balance per week or month
+// GET /api/v1/groups/:id/members/:user_id/balance/history
+func (h *LedgerHandler) GetBalanceHistory(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	memberIDStr := c.Param("user_id")
+	memberID, err := uuid.Parse(memberIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	// Check the requested user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, memberID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	interval := db.BalanceInterval(c.DefaultQuery("interval", string(db.IntervalWeek)))
+	if !interval.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval, use week or month"})
+		return
+	}
+
+	to := time.Now()
+	if t, err := queryTime(c, "to", true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if t != nil {
+		to = *t
+	}
+
+	from, err := queryTime(c, "from", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if from == nil {
+		// Default to the current interval and the ones before it
+		start := interval.Truncate(to.Add(-time.Microsecond))
+		for i := 1; i < defaultHistoryPeriods; i++ {
+			start = interval.Truncate(start.Add(-time.Microsecond))
+		}
+		from = &start
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	opening, points, err := h.ledgerRepo.GetBalanceHistory(c.Request.Context(), groupID, memberID, interval, *from, to)
+	if err != nil {
+		if errors.Is(err, db.ErrTooManyPeriods) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("range covers more than %d intervals", db.MaxBalancePeriods)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get balance history"})
+		return
+	}
+
+	response := BalanceHistoryResponse{
+		UserID:         memberID,
+		Interval:       interval,
+		OpeningBalance: opening,
+		Points:         make([]models.BalancePoint, 0, len(points)),
+	}
+	for _, p := range points {
+		response.Points = append(response.Points, *p)
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// VerifyLedger recomputes the group's ledger hash chain and reports the first break
+// GET /api/v1/groups/:id/ledger/verify
+func (h *LedgerHandler) VerifyLedger(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	report, err := h.chainRepo.Verify(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
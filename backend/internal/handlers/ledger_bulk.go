@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// maxBulkEntries is the most entries one bulk request changes
+const maxBulkEntries = 500
+
+// BulkLedgerRequest represents the request body for approving or rejecting
+// pending entries in bulk. Exactly one of IDs and Filter is given; Reason
+// only applies to rejections.
+type BulkLedgerRequest struct {
+	IDs    []uuid.UUID       `json:"ids"`
+	Filter *BulkLedgerFilter `json:"filter"`
+	Reason string            `json:"reason"`
+}
+
+// BulkLedgerFilter selects pending entries; From and To are RFC3339 or
+// YYYY-MM-DD bounds on occurred_at, with To inclusive for a bare date
+type BulkLedgerFilter struct {
+	UserID  *uuid.UUID `json:"user_id"`
+	ChoreID *uuid.UUID `json:"chore_id"`
+	From    string     `json:"from"`
+	To      string     `json:"to"`
+}
+
+// BulkLedgerResult is the outcome for one entry of a bulk request
+type BulkLedgerResult struct {
+	ID    uuid.UUID       `json:"id"`
+	OK    bool            `json:"ok"`
+	Entry *LedgerResponse `json:"entry,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// BulkLedgerResponse represents the result of a bulk request
+type BulkLedgerResponse struct {
+	Results   []BulkLedgerResult `json:"results"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+}
+
+// ledgerFilter converts the request's filter to a repository filter
+func (f *BulkLedgerFilter) ledgerFilter() (db.LedgerFilter, error) {
+	filter := db.LedgerFilter{UserID: f.UserID, ChoreID: f.ChoreID}
+	var err error
+	if filter.From, err = parseTime("from", f.From, false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTime("to", f.To, true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// validate checks that the request names its entries one way, and not too many
+func (r *BulkLedgerRequest) validate() error {
+	switch {
+	case r.IDs != nil && r.Filter != nil:
+		return errors.New("give either ids or filter, not both")
+	case r.IDs == nil && r.Filter == nil:
+		return errors.New("ids or filter is required")
+	case r.Filter == nil && len(r.IDs) == 0:
+		return errors.New("ids must not be empty")
+	case len(r.IDs) > maxBulkEntries:
+		return fmt.Errorf("at most %d ids can be given", maxBulkEntries)
+	}
+	return nil
+}
+
+// bulkErrorMessage describes why an entry was skipped
+func bulkErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return "entry not found"
+	case errors.Is(err, db.ErrOtherGroup):
+		return "entry belongs to another group"
+	case errors.Is(err, db.ErrNotPending):
+		return "entry is not pending approval"
+	case errors.Is(err, db.ErrProofRequired):
+		return "entry needs a photo before it can be approved"
+	}
+	return err.Error()
+}
+
+// ApprovePending approves pending entries by ID or filter (head only)
+// POST /api/v1/groups/:id/pending/approve
+func (h *LedgerHandler) ApprovePending(c *gin.Context) {
+	h.updatePending(c, models.StatusApproved)
+}
+
+// RejectPending rejects pending entries by ID or filter (head only)
+// POST /api/v1/groups/:id/pending/reject
+func (h *LedgerHandler) RejectPending(c *gin.Context) {
+	h.updatePending(c, models.StatusRejected)
+}
+
+// updatePending changes the status of pending entries in one transaction and
+// reports the outcome for each
+func (h *LedgerHandler) updatePending(c *gin.Context, status models.LedgerStatus) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		verb := "approve"
+		if status == models.StatusRejected {
+			verb = "reject"
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can " + verb + " entries"})
+		return
+	}
+
+	var req BulkLedgerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reason *string
+	if status == models.StatusRejected {
+		group, err := h.groupRepo.GetByID(c.Request.Context(), groupID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+			return
+		}
+		if reason, err = rejectReason(req.Reason, group.RequireRejectReason); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var filter db.LedgerFilter
+	if req.Filter != nil {
+		if filter, err = req.Filter.ledgerFilter(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	results, err := h.ledgerRepo.UpdateStatusBulk(c.Request.Context(), groupID, req.IDs, filter, maxBulkEntries, status, userID, reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update entries"})
+		return
+	}
+
+	eventType := events.LedgerApproved
+	if status == models.StatusRejected {
+		eventType = events.LedgerRejected
+	}
+
+	response := BulkLedgerResponse{Results: make([]BulkLedgerResult, 0, len(results))}
+	for _, r := range results {
+		if r.Err != nil {
+			response.Results = append(response.Results, BulkLedgerResult{ID: r.ID, Error: bulkErrorMessage(r.Err)})
+			response.Failed++
+			continue
+		}
+		entry := newLedgerResponse(r.Entry)
+		h.events.Publish(c.Request.Context(), events.New(eventType, groupID, &userID, entry))
+		response.Results = append(response.Results, BulkLedgerResult{ID: r.ID, OK: true, Entry: &entry})
+		response.Succeeded++
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkLedgerRequestValidate(t *testing.T) {
+	parse := func(body string) BulkLedgerRequest {
+		var req BulkLedgerRequest
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+		return req
+	}
+
+	id := uuid.New()
+	req := parse(`{"ids":["` + id.String() + `"]}`)
+	assert.NoError(t, req.validate())
+
+	req = parse(`{"filter":{}}`)
+	assert.NoError(t, req.validate())
+
+	req = parse(`{}`)
+	assert.EqualError(t, req.validate(), "ids or filter is required")
+
+	req = parse(`{"ids":[]}`)
+	assert.EqualError(t, req.validate(), "ids must not be empty")
+
+	req = parse(`{"ids":["` + id.String() + `"],"filter":{}}`)
+	assert.Error(t, req.validate())
+
+	req = BulkLedgerRequest{IDs: make([]uuid.UUID, maxBulkEntries+1)}
+	assert.Error(t, req.validate())
+}
+
+func TestBulkLedgerFilter(t *testing.T) {
+	kid := uuid.New()
+	f := BulkLedgerFilter{UserID: &kid, From: "2026-09-01", To: "2026-09-07"}
+	filter, err := f.ledgerFilter()
+	require.NoError(t, err)
+	assert.Equal(t, &kid, filter.UserID)
+	assert.Equal(t, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+	assert.Equal(t, time.Date(2026, 9, 8, 0, 0, 0, 0, time.UTC), *filter.To)
+
+	f = BulkLedgerFilter{To: "last week"}
+	_, err = f.ledgerFilter()
+	assert.EqualError(t, err, "invalid to, use RFC3339 or YYYY-MM-DD")
+}
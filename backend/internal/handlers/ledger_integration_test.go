@@ -0,0 +1,374 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestLedgerDecisions_KeepChainValid(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	approved := app.logEntry(t, groupID, choreID, kidToken, 2)
+	rejected := app.logEntry(t, groupID, choreID, kidToken, 2)
+	bulk := app.logEntry(t, groupID, choreID, kidToken, 2)
+	assert.Equal(t, models.StatusPendingApproval, approved.Status)
+
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", approved.ID), headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	entry := decode[handlers.LedgerResponse](t, w)
+	assert.Equal(t, models.StatusApproved, entry.Status)
+	assert.Equal(t, &head, entry.ApprovedByUserID)
+	assert.NotEqual(t, approved.Hash, entry.Hash)
+
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/reject", rejected.ID), headToken, map[string]any{"reason": "not done"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, models.StatusRejected, decode[handlers.LedgerResponse](t, w).Status)
+
+	// Members cannot decide their own entries
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", bulk.ID), kidToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Entries already decided fail individually in a bulk request
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/pending/approve", groupID), headToken, map[string]any{
+		"ids": []uuid.UUID{bulk.ID, rejected.ID},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	result := decode[handlers.BulkLedgerResponse](t, w)
+	assert.Equal(t, 1, result.Succeeded)
+	assert.Equal(t, 1, result.Failed)
+
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", approved.ID), headToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 3 creates, then 3 decisions
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 6, report.Links)
+	assert.Equal(t, 3, report.Records)
+}
+
+func TestDeleteChore_KeepsEntries(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	entry := app.recordEntry(t, groupID, choreID, headToken, kid, 2, time.Now())
+
+	w := app.do(http.MethodDelete, fmt.Sprintf("/api/v1/chores/%s", choreID), headToken, nil)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	w = app.do(http.MethodDelete, fmt.Sprintf("/api/v1/chores/%s", choreID), headToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The chore is archived, not deleted, and its entry stays on the ledger
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/chores", groupID), headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, decode[[]handlers.ChoreResponse](t, w))
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/chores?include_deleted=true", groupID), headToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	chores := decode[[]handlers.ChoreResponse](t, w)
+	require.Len(t, chores, 1)
+	assert.NotNil(t, chores[0].DeletedAt)
+
+	stored, err := app.ledger.GetByID(context.Background(), entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &choreID, stored.ChoreID)
+	assert.Equal(t, 2.0, app.balances(t, groupID, headToken, "")[kid].Balance)
+
+	// Archived chores cannot be logged
+	w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/ledger", groupID), kidToken, map[string]any{
+		"chore_id": choreID,
+		"amount":   2,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	report := app.verifyChain(t, groupID, headToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 1, report.Records)
+}
+
+func TestLedgerDecisions_ConcurrentApproveAndReject(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+	entry := app.logEntry(t, groupID, choreID, kidToken, 2)
+
+	// Both requests read the entry as pending; exactly one may decide it
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i, action := range []string{"approve", "reject"} {
+		wg.Add(1)
+		go func(i int, action string) {
+			defer wg.Done()
+			codes[i] = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/%s", entry.ID, action), headToken, nil).Code
+		}(i, action)
+	}
+	wg.Wait()
+
+	decided := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			decided++
+			continue
+		}
+		// The loser saw the entry decided either before or after reading it
+		assert.Contains(t, []int{http.StatusBadRequest, http.StatusConflict}, code)
+	}
+	assert.Equal(t, 1, decided)
+
+	report := app.verifyChain(t, groupID, headToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 2, report.Links)
+}
+
+func TestListLedger_Pagination(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	// Five approved entries a day apart, oldest first, plus one pending
+	start := time.Now().AddDate(0, 0, -10).Truncate(time.Second)
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/ledger", groupID), headToken, map[string]any{
+			"user_id":     kid,
+			"chore_id":    choreID,
+			"amount":      2,
+			"occurred_at": start.AddDate(0, 0, i),
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		ids = append(ids, decode[handlers.LedgerResponse](t, w).ID)
+	}
+	app.logEntry(t, groupID, choreID, kidToken, 2)
+
+	list := func(query url.Values) ([]handlers.LedgerResponse, int, string) {
+		w := app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/ledger?%s", groupID, query.Encode()), kidToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		total, err := strconv.Atoi(w.Header().Get(handlers.TotalCountHeader))
+		require.NoError(t, err)
+		return decode[[]handlers.LedgerResponse](t, w), total, w.Header().Get(handlers.NextCursorHeader)
+	}
+
+	// Without a limit everything comes back in one page
+	entries, total, next := list(url.Values{})
+	assert.Len(t, entries, 6)
+	assert.Equal(t, 6, total)
+	assert.Empty(t, next)
+
+	// Following the cursor walks the filtered entries exactly once, oldest first
+	var seen []uuid.UUID
+	query := url.Values{"limit": {"2"}, "sort": {"occurred_at"}, "status": {"approved"}}
+	for pages := 0; pages < 5; pages++ {
+		entries, total, next = list(query)
+		assert.Equal(t, 5, total)
+		for _, e := range entries {
+			seen = append(seen, e.ID)
+		}
+		if next == "" {
+			break
+		}
+		query.Set("cursor", next)
+	}
+	assert.Equal(t, ids, seen)
+
+	// A cursor only makes sense for the sort it was issued for
+	w := app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/ledger?limit=2&cursor=%s", groupID, url.QueryEscape(query.Get("cursor"))), kidToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/ledger?limit=2&cursor=garbage", groupID), kidToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/ledger?sort=password", groupID), kidToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetBalance_AsOf(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	noon := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	day := func(offset int) time.Time { return noon.AddDate(0, 0, offset) }
+	date := func(offset int) string { return day(offset).Format("2006-01-02") }
+
+	app.recordEntry(t, groupID, choreID, headToken, kid, 2, day(-10))
+	app.recordEntry(t, groupID, choreID, headToken, kid, 3, day(-5))
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/settlements", groupID), headToken, map[string]any{
+		"user_id": kid,
+		"amount":  1,
+		"date":    date(-3),
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	// Pending entries never count
+	app.logEntry(t, groupID, choreID, kidToken, 4)
+
+	tests := []struct {
+		name    string
+		query   string
+		balance float64
+		unpaid  float64
+	}{
+		{"before anything", "?as_of=" + date(-11), 0, 0},
+		{"date includes the whole day", "?as_of=" + date(-10), 2, 0},
+		{"timestamp is exclusive", "?as_of=" + url.QueryEscape(day(-5).Format(time.RFC3339)), 2, 0},
+		{"before the settlement", "?as_of=" + date(-4), 5, 0},
+		{"on the settlement date", "?as_of=" + date(-3), 4, 1},
+		{"now", "", 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := app.balances(t, groupID, kidToken, tt.query)[kid]
+			assert.Equal(t, tt.balance, b.Balance)
+			assert.Equal(t, tt.unpaid, b.UnconfirmedPaid)
+		})
+	}
+
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/balance?as_of=yesterday", groupID), kidToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestApplyPendingPolicies(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	expireDays, approveHours, maxAmount := 3, 24, 5.0
+	_, err := app.groups.UpdateSettings(context.Background(), groupID, db.GroupSettings{
+		SetPendingPolicy: true,
+		PendingPolicy: models.PendingPolicy{
+			ExpirePendingAfterDays: &expireDays,
+			AutoApproveAfterHours:  &approveHours,
+			AutoApproveMaxAmount:   &maxAmount,
+		},
+	})
+	require.NoError(t, err)
+
+	small := app.logEntry(t, groupID, choreID, kidToken, 2)
+	large := app.logEntry(t, groupID, choreID, kidToken, 10)
+	now := time.Now()
+
+	// Nothing has waited long enough yet
+	changed, err := app.ledger.ApplyPendingPolicies(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// Only the entry within the limit is approved
+	changed, err = app.ledger.ApplyPendingPolicies(context.Background(), now.Add(25*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, small.ID, changed[0].ID)
+	assert.Equal(t, models.StatusApproved, changed[0].Status)
+	assert.Equal(t, models.SystemAutoApprove, *changed[0].SystemActor)
+	assert.Nil(t, changed[0].ApprovedByUserID)
+
+	// The other expires, and a later run changes nothing more
+	changed, err = app.ledger.ApplyPendingPolicies(context.Background(), now.AddDate(0, 0, 4))
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, large.ID, changed[0].ID)
+	assert.Equal(t, models.StatusRejected, changed[0].Status)
+	assert.Equal(t, models.SystemAutoExpire, *changed[0].SystemActor)
+
+	changed, err = app.ledger.ApplyPendingPolicies(context.Background(), now.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// A head can no longer decide the expired entry
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", large.ID), headToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, 2.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 4, report.Links)
+}
+
+func TestCreateLedger_ApprovalRules(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	sibling, siblingToken := app.newUser(t, "sibling")
+	groupID := app.newGroup(t, head, kid, sibling)
+	choreID := app.newChore(t, groupID, "Dishes", 2)
+
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/approval-rules", groupID), headToken, map[string]any{
+		"chore_id":    choreID,
+		"user_id":     kid,
+		"max_per_day": 2,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	rule := decode[handlers.ApprovalRuleResponse](t, w)
+
+	// Amounts above the chore's are left for a head
+	pending := app.logEntry(t, groupID, choreID, kidToken, 3)
+	assert.Equal(t, models.StatusPendingApproval, pending.Status)
+	assert.Equal(t, []events.Type{events.LedgerCreated}, app.ledgerEvents(pending.ID))
+
+	// Approved by the rule up to its daily limit
+	for i := 0; i < 2; i++ {
+		entry := app.logEntry(t, groupID, choreID, kidToken, 2)
+		assert.Equal(t, models.StatusApproved, entry.Status)
+		assert.Equal(t, &rule.ID, entry.ApprovalRuleID)
+		assert.Nil(t, entry.ApprovedByUserID)
+		assert.Equal(t, []events.Type{events.LedgerCreated, events.LedgerApproved}, app.ledgerEvents(entry.ID))
+	}
+	assert.Equal(t, models.StatusPendingApproval, app.logEntry(t, groupID, choreID, kidToken, 2).Status)
+
+	// So are members the rule does not name
+	assert.Equal(t, models.StatusPendingApproval, app.logEntry(t, groupID, choreID, siblingToken, 2).Status)
+
+	balances := app.balances(t, groupID, kidToken, "")
+	assert.Equal(t, 4.0, balances[kid].Balance)
+	assert.Equal(t, 0.0, balances[sibling].Balance)
+
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 5, report.Links)
+
+	// A head's own entry is approved as it is created, too
+	own := app.logEntry(t, groupID, choreID, headToken, 2)
+	assert.Equal(t, models.StatusApproved, own.Status)
+	assert.Equal(t, []events.Type{events.LedgerCreated, events.LedgerApproved}, app.ledgerEvents(own.ID))
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+)
+
+const (
+	// TotalCountHeader carries the number of rows matching a list request's filters
+	TotalCountHeader = "X-Total-Count"
+	// NextCursorHeader carries the cursor for the next page, if there is one
+	NextCursorHeader = "X-Next-Cursor"
+)
+
+// parsePage reads the limit, cursor and sort query parameters.
+// Sort is a field name, prefixed with "-" for descending order. Without a
+// limit the whole list is returned in one page.
+func parsePage(c *gin.Context, defaultSort string) (db.Page, error) {
+	page := db.Page{Cursor: c.Query("cursor")}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("invalid limit")
+		}
+		page.Limit = limit
+	}
+
+	sort := c.DefaultQuery("sort", defaultSort)
+	if strings.HasPrefix(sort, "-") {
+		page.Sort = db.Sort{Field: strings.TrimPrefix(sort, "-"), Desc: true}
+	} else {
+		page.Sort = db.Sort{Field: sort}
+	}
+
+	return page, nil
+}
+
+// setPageHeaders exposes the total count and next cursor of a listing
+func setPageHeaders(c *gin.Context, info *db.PageInfo) {
+	c.Header(TotalCountHeader, strconv.Itoa(info.Total))
+	if info.NextCursor != "" {
+		c.Header(NextCursorHeader, info.NextCursor)
+	}
+}
+
+// queryUUID parses an optional UUID query parameter
+func queryUUID(c *gin.Context, name string) (*uuid.UUID, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &id, nil
+}
+
+// queryFloat parses an optional numeric query parameter
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &f, nil
+}
+
+// queryBool parses an optional true/false query parameter
+func queryBool(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &b, nil
+}
+
+// queryTime parses an optional RFC3339 timestamp or YYYY-MM-DD date query parameter.
+// With endOfDay set, a bare date is moved to the start of the following day so it
+// can be used as an exclusive upper bound that still covers the whole date.
+func queryTime(c *gin.Context, name string, endOfDay bool) (*time.Time, error) {
+	return parseTime(name, c.Query(name), endOfDay)
+}
+
+// parseTime parses an optional RFC3339 timestamp or YYYY-MM-DD date the way queryTime does
+func parseTime(name, value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, use RFC3339 or YYYY-MM-DD", name)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// listErrorMessage maps pagination errors from the repositories to client errors
+func listErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, db.ErrInvalidCursor):
+		return "invalid cursor", true
+	case errors.Is(err, db.ErrInvalidSort):
+		return "invalid sort field", true
+	}
+	return "", false
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/loans"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// LoanHandler handles loan requests
+type LoanHandler struct {
+	loanRepo  *db.LoanRepo
+	groupRepo *db.GroupRepo
+	events    *events.Bus
+}
+
+// NewLoanHandler creates a new LoanHandler
+func NewLoanHandler(loanRepo *db.LoanRepo, groupRepo *db.GroupRepo, bus *events.Bus) *LoanHandler {
+	return &LoanHandler{
+		loanRepo:  loanRepo,
+		groupRepo: groupRepo,
+		events:    bus,
+	}
+}
+
+// CreateLoanRequest represents the request body for making a loan. Weekly
+// plans take WeeklyAmount each week from FirstRepayment, which defaults to
+// a week from today; withholding plans take WithholdPercent of each
+// approved earning.
+type CreateLoanRequest struct {
+	UserID          uuid.UUID            `json:"user_id" binding:"required"`
+	Principal       float64              `json:"principal" binding:"required,gt=0"`
+	InterestPercent float64              `json:"interest_percent" binding:"gte=0,lte=100"` // Flat, on the principal
+	RepaymentPlan   models.RepaymentPlan `json:"repayment_plan" binding:"required"`
+	WeeklyAmount    *float64             `json:"weekly_amount" binding:"omitempty,gt=0"`
+	WithholdPercent *float64             `json:"withhold_percent" binding:"omitempty,gt=0,lte=100"`
+	FirstRepayment  *string              `json:"first_repayment"` // YYYY-MM-DD format
+	Note            *string              `json:"note"`
+}
+
+// LoanResponse represents a loan in API responses, with the weekly
+// repayments still to come and, for a single loan, the advance and
+// repayment entries it posted
+type LoanResponse struct {
+	*models.Loan
+	Schedule []loans.Repayment `json:"schedule,omitempty"`
+	Entries  []LedgerResponse  `json:"entries,omitempty"`
+}
+
+// newLoanResponse converts a loan and its entries to their API representation
+func newLoanResponse(l *models.Loan, entries []*models.LedgerEntry) LoanResponse {
+	response := LoanResponse{Loan: l, Schedule: loans.Schedule(l)}
+	for _, e := range entries {
+		response.Entries = append(response.Entries, newLedgerResponse(e))
+	}
+	return response
+}
+
+// loanPlan validates a loan's repayment plan and returns its first weekly
+// repayment, which is nil for withholding plans. Weekly plans may not start
+// in the past.
+func loanPlan(req *CreateLoanRequest, now time.Time) (*time.Time, error) {
+	switch req.RepaymentPlan {
+	case models.RepayWeekly:
+		if req.WeeklyAmount == nil {
+			return nil, errors.New("weekly_amount is required for a weekly plan")
+		}
+		if req.WithholdPercent != nil {
+			return nil, errors.New("withhold_percent is only for a withhold plan")
+		}
+	case models.RepayWithhold:
+		if req.WithholdPercent == nil {
+			return nil, errors.New("withhold_percent is required for a withhold plan")
+		}
+		if req.WeeklyAmount != nil || req.FirstRepayment != nil {
+			return nil, errors.New("weekly_amount and first_repayment are only for a weekly plan")
+		}
+		return nil, nil
+	default:
+		return nil, errors.New("repayment_plan must be weekly or withhold")
+	}
+
+	today := allowance.Day(now)
+	first := today.AddDate(0, 0, 7)
+	if req.FirstRepayment != nil {
+		var err error
+		first, err = time.Parse("2006-01-02", *req.FirstRepayment)
+		if err != nil {
+			return nil, errors.New("invalid first_repayment format, use YYYY-MM-DD")
+		}
+		if first.Before(today) {
+			return nil, errors.New("first_repayment cannot be in the past")
+		}
+	}
+	return &first, nil
+}
+
+// ListLoans returns a group's loans, newest first, optionally for one
+// member with user_id
+// GET /api/v1/groups/:id/loans
+func (h *LoanHandler) ListLoans(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	memberID, err := queryUUID(c, "user_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.loanRepo.ListForGroup(c.Request.Context(), groupID, memberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list loans"})
+		return
+	}
+
+	response := make([]LoanResponse, 0, len(list))
+	for _, l := range list {
+		response = append(response, newLoanResponse(l, nil))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateLoan advances a member money, paid into their balance as an
+// approved entry, to be paid back with any interest by the repayment plan
+// (head only)
+// POST /api/v1/groups/:id/loans
+func (h *LoanHandler) CreateLoan(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can make loans"})
+		return
+	}
+
+	var req CreateLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	first, err := loanPlan(&req, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		req.Note = &note
+		if note == "" {
+			req.Note = nil
+		}
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, req.UserID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target user is not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check target membership"})
+		return
+	}
+
+	created, advance, err := h.loanRepo.Create(c.Request.Context(), &models.Loan{
+		GroupID:         groupID,
+		UserID:          req.UserID,
+		Principal:       req.Principal,
+		InterestPercent: req.InterestPercent,
+		RepaymentPlan:   req.RepaymentPlan,
+		WeeklyAmount:    req.WeeklyAmount,
+		WithholdPercent: req.WithholdPercent,
+		StartDate:       allowance.Day(now),
+		NextRepayment:   first,
+		Note:            req.Note,
+		CreatedByUserID: userID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create loan"})
+		return
+	}
+
+	h.events.Publish(c.Request.Context(), events.New(events.LedgerCreated, groupID, &userID, newLedgerResponse(advance)))
+
+	c.JSON(http.StatusCreated, newLoanResponse(created, []*models.LedgerEntry{advance}))
+}
+
+// GetLoan returns a loan with its repayment schedule and the entries it
+// posted, so each change to the member's balance can be traced to it
+// GET /api/v1/groups/:id/loans/:loan_id
+func (h *LoanHandler) GetLoan(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	loanID, err := uuid.Parse(c.Param("loan_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid loan ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	l, err := h.loanRepo.GetByID(c.Request.Context(), loanID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get loan"})
+		return
+	}
+	if err != nil || l.GroupID != groupID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "loan not found"})
+		return
+	}
+
+	entries, err := h.loanRepo.ListEntries(c.Request.Context(), l.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list loan entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoanResponse(l, entries))
+}
@@ -0,0 +1,145 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// repaymentsOf returns the repayment entries posted on a loan
+func repaymentsOf(t *testing.T, app *testApp, groupID, loanID uuid.UUID, token string) []handlers.LedgerResponse {
+	w := app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/loans/%s", groupID, loanID), token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var repayments []handlers.LedgerResponse
+	for _, e := range decode[handlers.LoanResponse](t, w).Entries {
+		if e.Kind == models.EntryRepayment {
+			repayments = append(repayments, e)
+		}
+	}
+	return repayments
+}
+
+func TestLoan_WithholdsFromApprovedEarnings(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 6)
+
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/loans", groupID), headToken, map[string]any{
+		"user_id":          kid,
+		"principal":        5,
+		"repayment_plan":   models.RepayWithhold,
+		"withhold_percent": 50,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	loan := decode[handlers.LoanResponse](t, w)
+
+	first := app.logEntry(t, groupID, choreID, kidToken, 6)
+	second := app.logEntry(t, groupID, choreID, kidToken, 6)
+	third := app.logEntry(t, groupID, choreID, kidToken, 6)
+	assert.Empty(t, repaymentsOf(t, app, groupID, loan.ID, kidToken), "nothing is withheld from pending entries")
+
+	// Approved twice at once, the earning is withheld from only once
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", first.ID), headToken, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/pending/approve", groupID), headToken, map[string]any{
+			"ids": []uuid.UUID{first.ID},
+		})
+	}()
+	wg.Wait()
+
+	repayments := repaymentsOf(t, app, groupID, loan.ID, kidToken)
+	require.Len(t, repayments, 1)
+	assert.Equal(t, -3.0, repayments[0].Amount)
+	assert.Equal(t, &first.ID, repayments[0].WithheldFromID)
+
+	// The last repayment is capped at what is outstanding, and a repaid loan takes nothing more
+	for _, e := range []handlers.LedgerResponse{second, third} {
+		w = app.do(http.MethodPost, fmt.Sprintf("/api/v1/ledger/%s/approve", e.ID), headToken, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	repayments = repaymentsOf(t, app, groupID, loan.ID, kidToken)
+	require.Len(t, repayments, 2)
+	assert.Equal(t, -2.0, repayments[1].Amount)
+
+	b := app.balances(t, groupID, kidToken, "")[kid]
+	assert.Equal(t, 18.0, b.Balance) // 5 advanced + 18 earned - 5 repaid
+	assert.Empty(t, b.Loans)
+
+	// The advance, 3 entries and their approvals, and 2 repayments
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 9, report.Links)
+}
+
+func TestLoan_PostDueWeekly(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	ctx := context.Background()
+
+	w := app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/loans", groupID), headToken, map[string]any{
+		"user_id":        kid,
+		"principal":      10,
+		"repayment_plan": models.RepayWeekly,
+		"weekly_amount":  4,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	loan := decode[handlers.LoanResponse](t, w)
+	require.NotNil(t, loan.NextRepayment)
+	firstDue := *loan.NextRepayment
+
+	entries, err := app.loans.PostDue(ctx, firstDue.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Weeks missed are caught up, the last one only taking what is left
+	entries, err = app.loans.PostDue(ctx, firstDue.AddDate(0, 0, 15))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	var amounts []float64
+	for _, e := range entries {
+		amounts = append(amounts, e.Amount)
+	}
+	assert.Equal(t, []float64{-4, -4, -2}, amounts)
+	assert.True(t, firstDue.AddDate(0, 0, 14).Equal(entries[2].OccurredAt))
+
+	entries, err = app.loans.PostDue(ctx, firstDue.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	w = app.do(http.MethodGet, fmt.Sprintf("/api/v1/groups/%s/loans/%s", groupID, loan.ID), kidToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	repaid := decode[handlers.LoanResponse](t, w)
+	assert.Equal(t, 0.0, repaid.Outstanding)
+	assert.NotNil(t, repaid.RepaidAt)
+
+	assert.Equal(t, 0.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 4, report.Links)
+}
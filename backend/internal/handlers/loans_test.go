@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestLoanPlan(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+	weekly := 5.0
+	percent := 25.0
+
+	first, err := loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly, WeeklyAmount: &weekly}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), *first)
+
+	date := "2026-10-18"
+	first, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly, WeeklyAmount: &weekly, FirstRepayment: &date}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), *first)
+
+	first, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWithhold, WithholdPercent: &percent}, now)
+	require.NoError(t, err)
+	assert.Nil(t, first)
+
+	date = "2026-10-17"
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly, WeeklyAmount: &weekly, FirstRepayment: &date}, now)
+	assert.EqualError(t, err, "first_repayment cannot be in the past")
+
+	date = "25/10/2026"
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly, WeeklyAmount: &weekly, FirstRepayment: &date}, now)
+	assert.EqualError(t, err, "invalid first_repayment format, use YYYY-MM-DD")
+
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly}, now)
+	assert.EqualError(t, err, "weekly_amount is required for a weekly plan")
+
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWeekly, WeeklyAmount: &weekly, WithholdPercent: &percent}, now)
+	assert.EqualError(t, err, "withhold_percent is only for a withhold plan")
+
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWithhold}, now)
+	assert.EqualError(t, err, "withhold_percent is required for a withhold plan")
+
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: models.RepayWithhold, WithholdPercent: &percent, WeeklyAmount: &weekly}, now)
+	assert.EqualError(t, err, "weekly_amount and first_repayment are only for a weekly plan")
+
+	_, err = loanPlan(&CreateLoanRequest{RepaymentPlan: "monthly"}, now)
+	assert.EqualError(t, err, "repayment_plan must be weekly or withhold")
+}
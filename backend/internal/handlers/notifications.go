@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/mail"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/push"
+)
+
+// maxDeviceTokenLength bounds the push tokens clients may register
+const maxDeviceTokenLength = 512
+
+// NotificationHandler handles device registration, notification settings
+// and email opt-out links
+type NotificationHandler struct {
+	notificationRepo *db.NotificationRepo
+	groupRepo        *db.GroupRepo
+	links            *mail.Links
+	locales          []string
+}
+
+// NewNotificationHandler creates a new NotificationHandler. locales are the
+// languages emails can be sent in.
+func NewNotificationHandler(notificationRepo *db.NotificationRepo, groupRepo *db.GroupRepo, links *mail.Links, locales []string) *NotificationHandler {
+	return &NotificationHandler{
+		notificationRepo: notificationRepo,
+		groupRepo:        groupRepo,
+		links:            links,
+		locales:          locales,
+	}
+}
+
+// RegisterDeviceRequest represents the request body for registering a device
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform" binding:"required,oneof=ios android"`
+}
+
+// QuietHoursJSON is a quiet hours window in local "HH:MM" times
+type QuietHoursJSON struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// NullableQuietHours distinguishes an omitted quiet_hours field from an explicit null
+type NullableQuietHours struct {
+	Set   bool
+	Value *QuietHoursJSON
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (n *NullableQuietHours) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var v QuietHoursJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n.Value = &v
+	return nil
+}
+
+// UpdateNotificationSettingsRequest represents the request body for updating
+// notification settings. Preferences, when given, replace all existing ones.
+type UpdateNotificationSettingsRequest struct {
+	Timezone           *string                          `json:"timezone"`
+	QuietHours         NullableQuietHours               `json:"quiet_hours"` // null turns quiet hours off
+	Preferences        *[]models.NotificationPreference `json:"preferences"`
+	Locale             *string                          `json:"locale"`
+	EmailNotifications *bool                            `json:"email_notifications"`
+	EmailDigest        *bool                            `json:"email_digest"`
+}
+
+// NotificationSettingsResponse represents notification settings in API responses
+type NotificationSettingsResponse struct {
+	Timezone           string                          `json:"timezone"`
+	QuietHours         *QuietHoursJSON                 `json:"quiet_hours"`
+	Preferences        []models.NotificationPreference `json:"preferences"`
+	EventTypes         []events.Type                   `json:"event_types"` // Types a preference can be set for
+	Locale             string                          `json:"locale"`
+	Locales            []string                        `json:"locales"` // Languages emails can be sent in
+	EmailNotifications bool                            `json:"email_notifications"`
+	EmailDigest        bool                            `json:"email_digest"`
+}
+
+func (h *NotificationHandler) newNotificationSettingsResponse(s *models.NotificationSettings, prefs []models.NotificationPreference) NotificationSettingsResponse {
+	response := NotificationSettingsResponse{
+		Timezone:           s.Timezone,
+		Preferences:        prefs,
+		EventTypes:         push.EventTypes,
+		Locale:             s.Locale,
+		Locales:            h.locales,
+		EmailNotifications: s.EmailNotifications,
+		EmailDigest:        s.EmailDigest,
+	}
+	if s.QuietHoursStart != nil && s.QuietHoursEnd != nil {
+		response.QuietHours = &QuietHoursJSON{
+			Start: push.FormatClock(*s.QuietHoursStart),
+			End:   push.FormatClock(*s.QuietHoursEnd),
+		}
+	}
+	return response
+}
+
+// applyNotificationSettings validates an update and applies it to s,
+// returning the preferences to save (nil to keep the current ones)
+func applyNotificationSettings(s *models.NotificationSettings, req UpdateNotificationSettingsRequest, locales []string) ([]models.NotificationPreference, error) {
+	if req.Timezone != nil {
+		if *req.Timezone == "" || *req.Timezone == "Local" {
+			return nil, fmt.Errorf("invalid timezone %q", *req.Timezone)
+		}
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q", *req.Timezone)
+		}
+		s.Timezone = *req.Timezone
+	}
+
+	if req.Locale != nil {
+		if !slices.Contains(locales, *req.Locale) {
+			return nil, fmt.Errorf("unsupported locale %q", *req.Locale)
+		}
+		s.Locale = *req.Locale
+	}
+	if req.EmailNotifications != nil {
+		s.EmailNotifications = *req.EmailNotifications
+	}
+	if req.EmailDigest != nil {
+		s.EmailDigest = *req.EmailDigest
+	}
+
+	if req.QuietHours.Set {
+		s.QuietHoursStart, s.QuietHoursEnd = nil, nil
+		if q := req.QuietHours.Value; q != nil {
+			start, err := push.ParseClock(q.Start)
+			if err != nil {
+				return nil, fmt.Errorf("quiet_hours.start: %w", err)
+			}
+			end, err := push.ParseClock(q.End)
+			if err != nil {
+				return nil, fmt.Errorf("quiet_hours.end: %w", err)
+			}
+			if start == end {
+				return nil, fmt.Errorf("quiet_hours start and end must differ")
+			}
+			s.QuietHoursStart, s.QuietHoursEnd = &start, &end
+		}
+	}
+
+	if req.Preferences == nil {
+		return nil, nil
+	}
+	prefs := make([]models.NotificationPreference, 0, len(*req.Preferences))
+	seen := make(map[string]bool, len(*req.Preferences))
+	for _, p := range *req.Preferences {
+		if !push.Notifies(events.Type(p.EventType)) {
+			return nil, fmt.Errorf("event type %q does not send notifications", p.EventType)
+		}
+		key := p.EventType
+		if p.GroupID != nil {
+			key += "/" + p.GroupID.String()
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate preference for %s", key)
+		}
+		seen[key] = true
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+// RegisterDevice registers the app's push token for the current user
+// POST /api/v1/auth/me/devices
+func (h *NotificationHandler) RegisterDevice(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Token) > maxDeviceTokenLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is too long"})
+		return
+	}
+
+	device, err := h.notificationRepo.RegisterDevice(c.Request.Context(), userID, req.Token, req.Platform)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+// ListDevices returns the current user's registered devices
+// GET /api/v1/auth/me/devices
+func (h *NotificationHandler) ListDevices(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	devices, err := h.notificationRepo.ListDevices(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list devices"})
+		return
+	}
+	if devices == nil {
+		devices = []*models.Device{}
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// DeleteDevice unregisters one of the current user's devices
+// DELETE /api/v1/auth/me/devices/:id
+func (h *NotificationHandler) DeleteDevice(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	if err := h.notificationRepo.DeleteDevice(c.Request.Context(), userID, deviceID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete device"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetNotificationSettings returns the current user's notification settings
+// GET /api/v1/auth/me/notifications
+func (h *NotificationHandler) GetNotificationSettings(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settings, prefs, err := h.notificationRepo.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.newNotificationSettingsResponse(settings, prefs))
+}
+
+// UpdateNotificationSettings changes the current user's time zone, quiet
+// hours, notification preferences, email locale and email opt-outs
+// PATCH /api/v1/auth/me/notifications
+func (h *NotificationHandler) UpdateNotificationSettings(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, current, err := h.notificationRepo.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notification settings"})
+		return
+	}
+
+	prefs, err := applyNotificationSettings(settings, req, h.locales)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Group preferences are only allowed for the user's own groups
+	for _, p := range prefs {
+		if p.GroupID == nil {
+			continue
+		}
+		if _, err := h.groupRepo.GetMember(c.Request.Context(), *p.GroupID, userID); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("not a member of group %s", p.GroupID)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+			return
+		}
+	}
+
+	if err := h.notificationRepo.SaveSettings(c.Request.Context(), settings, prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save notification settings"})
+		return
+	}
+	if prefs == nil {
+		prefs = current
+	}
+
+	c.JSON(http.StatusOK, h.newNotificationSettingsResponse(settings, prefs))
+}
+
+// unsubscribePage asks to confirm an opt-out, so that link scanners
+// following the URL do not unsubscribe anyone, and confirms it afterwards
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Pocket Money</title></head>
+<body style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;max-width:480px;margin:48px auto;padding:0 16px;">
+{{if .Done}}<p>You will no longer receive {{.What}} from Pocket Money. You can turn them back on in the app.</p>
+{{else}}<p>Stop receiving {{.What}} from Pocket Money?</p>
+<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">Unsubscribe</button></form>
+{{end}}</body>
+</html>`))
+
+// unsubscribeWhat describes an email list on the unsubscribe page
+var unsubscribeWhat = map[mail.List]string{
+	mail.ListNotifications: "notification emails",
+	mail.ListDigest:        "the weekly digest",
+}
+
+// Unsubscribe opts a user out of one kind of email using the signed token
+// from an email. GET shows a confirmation page; POST, also used for
+// one-click unsubscribing from the mail client, opts out.
+// GET /api/v1/email/unsubscribe
+// POST /api/v1/email/unsubscribe
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	userID, list, err := h.links.ParseToken(token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unsubscribe link"})
+		return
+	}
+
+	done := c.Request.Method == http.MethodPost
+	if done {
+		if err := h.notificationRepo.SetEmailOptOut(c.Request.Context(), userID, list); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsubscribe"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	_ = unsubscribePage.Execute(c.Writer, gin.H{"Done": done, "What": unsubscribeWhat[list], "Token": token})
+}
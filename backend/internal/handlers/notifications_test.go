@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/mail"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+var testLocales = []string{"en"}
+
+func decodeNotificationUpdate(t *testing.T, body string) UpdateNotificationSettingsRequest {
+	t.Helper()
+	var req UpdateNotificationSettingsRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req
+}
+
+func TestApplyNotificationSettings(t *testing.T) {
+	s := &models.NotificationSettings{Timezone: "UTC", Locale: "en", EmailNotifications: true, EmailDigest: true}
+
+	prefs, err := applyNotificationSettings(s, decodeNotificationUpdate(t, `{
+		"timezone": "Europe/London",
+		"quiet_hours": {"start": "21:30", "end": "07:00"},
+		"preferences": [{"event_type": "ledger.approved", "enabled": false}],
+		"locale": "en",
+		"email_digest": false
+	}`), testLocales)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/London", s.Timezone)
+	assert.Equal(t, 21*60+30, *s.QuietHoursStart)
+	assert.Equal(t, 7*60, *s.QuietHoursEnd)
+	require.Len(t, prefs, 1)
+	assert.False(t, prefs[0].Enabled)
+	assert.Equal(t, "en", s.Locale)
+	assert.True(t, s.EmailNotifications)
+	assert.False(t, s.EmailDigest)
+
+	// Omitted fields are left alone; preferences are kept
+	prefs, err = applyNotificationSettings(s, decodeNotificationUpdate(t, `{}`), testLocales)
+	require.NoError(t, err)
+	assert.Nil(t, prefs)
+	assert.NotNil(t, s.QuietHoursStart)
+
+	// null turns quiet hours off; an empty list clears preferences
+	prefs, err = applyNotificationSettings(s, decodeNotificationUpdate(t, `{"quiet_hours": null, "preferences": []}`), testLocales)
+	require.NoError(t, err)
+	assert.NotNil(t, prefs)
+	assert.Empty(t, prefs)
+	assert.Nil(t, s.QuietHoursStart)
+	assert.Nil(t, s.QuietHoursEnd)
+}
+
+func TestApplyNotificationSettingsErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"unknown timezone":  `{"timezone": "Mars/Olympus"}`,
+		"local timezone":    `{"timezone": "Local"}`,
+		"bad clock":         `{"quiet_hours": {"start": "9pm", "end": "07:00"}}`,
+		"empty window":      `{"quiet_hours": {"start": "07:00", "end": "07:00"}}`,
+		"silent event type": `{"preferences": [{"event_type": "chore.created", "enabled": false}]}`,
+		"unknown locale":    `{"locale": "xx"}`,
+		"duplicate":         `{"preferences": [{"event_type": "ledger.approved", "enabled": false}, {"event_type": "ledger.approved", "enabled": true}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := applyNotificationSettings(&models.NotificationSettings{Timezone: "UTC"}, decodeNotificationUpdate(t, body), testLocales)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUnsubscribePage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	links := mail.NewLinks("http://localhost:8080", "secret")
+	h := NewNotificationHandler(nil, nil, links, testLocales)
+	router := gin.New()
+	router.GET("/email/unsubscribe", h.Unsubscribe)
+
+	// Following the link only asks for confirmation
+	token := links.Token(uuid.New(), mail.ListDigest)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/email/unsubscribe?token="+token, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "the weekly digest")
+	assert.Contains(t, w.Body.String(), `<form method="post">`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/email/unsubscribe?token=forged", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
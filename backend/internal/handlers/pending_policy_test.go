@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestMergePendingPolicy(t *testing.T) {
+	parse := func(body string) UpdateGroupRequest {
+		var req UpdateGroupRequest
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+		return req
+	}
+
+	p, err := mergePendingPolicy(models.PendingPolicy{}, parse(`{"auto_approve_after_hours":48,"auto_approve_max_amount":5}`))
+	require.NoError(t, err)
+	require.NotNil(t, p.AutoApproveMaxAmount)
+	assert.Equal(t, 5.0, *p.AutoApproveMaxAmount)
+	assert.Nil(t, p.ExpirePendingAfterDays)
+
+	// Fields left out keep their current value; null turns a policy off
+	p, err = mergePendingPolicy(p, parse(`{"expire_pending_after_days":7}`))
+	require.NoError(t, err)
+	assert.Equal(t, 48, *p.AutoApproveAfterHours)
+	assert.Equal(t, 7, *p.ExpirePendingAfterDays)
+
+	p, err = mergePendingPolicy(p, parse(`{"auto_approve_after_hours":null,"auto_approve_max_amount":null}`))
+	require.NoError(t, err)
+	assert.Nil(t, p.AutoApproveAfterHours)
+	assert.Nil(t, p.AutoApproveMaxAmount)
+
+	_, err = mergePendingPolicy(p, parse(`{"auto_approve_after_hours":24}`))
+	assert.EqualError(t, err, "auto_approve_after_hours and auto_approve_max_amount must be set together")
+	_, err = mergePendingPolicy(p, parse(`{"expire_pending_after_days":0}`))
+	assert.Error(t, err)
+	_, err = mergePendingPolicy(p, parse(`{"auto_approve_after_hours":24,"auto_approve_max_amount":-1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// maxCategoryNameLength is the longest settlement category name, in characters
+const maxCategoryNameLength = 50
+
+// SettlementCategoryHandler handles settlement category requests
+type SettlementCategoryHandler struct {
+	categoryRepo *db.SettlementCategoryRepo
+	groupRepo    *db.GroupRepo
+}
+
+// NewSettlementCategoryHandler creates a new SettlementCategoryHandler
+func NewSettlementCategoryHandler(categoryRepo *db.SettlementCategoryRepo, groupRepo *db.GroupRepo) *SettlementCategoryHandler {
+	return &SettlementCategoryHandler{
+		categoryRepo: categoryRepo,
+		groupRepo:    groupRepo,
+	}
+}
+
+// SettlementCategoryRequest represents the request body for creating or
+// renaming a settlement category
+type SettlementCategoryRequest struct {
+	Name string `json:"name"`
+}
+
+// SettlementCategoryResponse represents a settlement category in API responses
+type SettlementCategoryResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	GroupID   uuid.UUID  `json:"group_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func newSettlementCategoryResponse(c *models.SettlementCategory) SettlementCategoryResponse {
+	return SettlementCategoryResponse{
+		ID:        c.ID,
+		GroupID:   c.GroupID,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt,
+		DeletedAt: c.DeletedAt,
+	}
+}
+
+// categoryName validates a settlement category name
+func categoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxCategoryNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxCategoryNameLength)
+	}
+	return name, nil
+}
+
+// ListSettlementCategories returns a group's settlement categories; deleted
+// ones are included with include_deleted=true
+// GET /api/v1/groups/:id/settlement-categories
+func (h *SettlementCategoryHandler) ListSettlementCategories(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	includeDeleted, err := queryBool(c, "include_deleted")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	categories, err := h.categoryRepo.ListForGroup(c.Request.Context(), groupID, includeDeleted != nil && *includeDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list settlement categories"})
+		return
+	}
+
+	response := make([]SettlementCategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		response = append(response, newSettlementCategoryResponse(category))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSettlementCategory adds a settlement category (head only)
+// POST /api/v1/groups/:id/settlement-categories
+func (h *SettlementCategoryHandler) CreateSettlementCategory(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can create settlement categories"})
+		return
+	}
+
+	var req SettlementCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, err := categoryName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.categoryRepo.Create(c.Request.Context(), groupID, name)
+	if err != nil {
+		if errors.Is(err, db.ErrDuplicateCategory) {
+			c.JSON(http.StatusConflict, gin.H{"error": "a category with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create settlement category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSettlementCategoryResponse(category))
+}
+
+// RenameSettlementCategory renames a settlement category (head only)
+// PATCH /api/v1/groups/:id/settlement-categories/:category_id
+func (h *SettlementCategoryHandler) RenameSettlementCategory(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("category_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can rename settlement categories"})
+		return
+	}
+
+	var req SettlementCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, err := categoryName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.categoryRepo.GetByID(c.Request.Context(), categoryID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement category"})
+		return
+	}
+	if err != nil || category.GroupID != groupID || category.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "settlement category not found"})
+		return
+	}
+
+	category, err = h.categoryRepo.Rename(c.Request.Context(), categoryID, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement category not found"})
+		case errors.Is(err, db.ErrDuplicateCategory):
+			c.JSON(http.StatusConflict, gin.H{"error": "a category with this name already exists"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rename settlement category"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newSettlementCategoryResponse(category))
+}
+
+// DeleteSettlementCategory stops a category from being given to new
+// settlements; settlements already in it keep it (head only)
+// DELETE /api/v1/groups/:id/settlement-categories/:category_id
+func (h *SettlementCategoryHandler) DeleteSettlementCategory(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("category_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can delete settlement categories"})
+		return
+	}
+
+	category, err := h.categoryRepo.GetByID(c.Request.Context(), categoryID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement category"})
+		return
+	}
+	if err != nil || category.GroupID != groupID || category.DeletedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "settlement category not found"})
+		return
+	}
+
+	if err := h.categoryRepo.Delete(c.Request.Context(), categoryID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete settlement category"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
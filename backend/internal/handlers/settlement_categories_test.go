@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryName(t *testing.T) {
+	name, err := categoryName("  Pocket money ")
+	require.NoError(t, err)
+	assert.Equal(t, "Pocket money", name)
+
+	_, err = categoryName(" ")
+	assert.EqualError(t, err, "name is required")
+
+	_, err = categoryName(strings.Repeat("é", maxCategoryNameLength+1))
+	assert.Error(t, err)
+	_, err = categoryName(strings.Repeat("é", maxCategoryNameLength))
+	assert.NoError(t, err)
+}
@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/srjn45/pocket-money/backend/internal/auth"
 	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
 	"github.com/srjn45/pocket-money/backend/internal/models"
 )
 
@@ -17,36 +22,223 @@ import (
 type SettlementHandler struct {
 	settlementRepo *db.SettlementRepo
 	groupRepo      *db.GroupRepo
+	events         *events.Bus
 }
 
 // NewSettlementHandler creates a new SettlementHandler
-func NewSettlementHandler(settlementRepo *db.SettlementRepo, groupRepo *db.GroupRepo) *SettlementHandler {
+func NewSettlementHandler(settlementRepo *db.SettlementRepo, groupRepo *db.GroupRepo, bus *events.Bus) *SettlementHandler {
 	return &SettlementHandler{
 		settlementRepo: settlementRepo,
 		groupRepo:      groupRepo,
+		events:         bus,
 	}
 }
 
-// CreateSettlementRequest represents the request body for creating a settlement
+// maxReferenceLength is the longest external reference, in characters
+const maxReferenceLength = 100
+
+// CreateSettlementRequest represents the request body for creating a settlement.
+// EntryIDs are paid first, then the member's oldest unpaid entries. Method
+// defaults to cash. A JarID pays the settlement out of one of the member's jars.
 type CreateSettlementRequest struct {
-	UserID uuid.UUID `json:"user_id" binding:"required"`
-	Amount float64   `json:"amount" binding:"required,gt=0"`
-	Date   string    `json:"date" binding:"required"` // YYYY-MM-DD format
-	Note   *string   `json:"note"`
+	UserID            uuid.UUID               `json:"user_id" binding:"required"`
+	Amount            float64                 `json:"amount" binding:"required,gt=0"`
+	Date              string                  `json:"date" binding:"required"` // YYYY-MM-DD format
+	Note              *string                 `json:"note"`
+	Method            models.SettlementMethod `json:"method"`
+	ExternalReference *string                 `json:"external_reference"` // E.g. a bank transfer ID
+	CategoryID        *uuid.UUID              `json:"category_id"`
+	JarID             *uuid.UUID              `json:"jar_id"`
+	EntryIDs          []uuid.UUID             `json:"entry_ids"`
+	AllowNegative     bool                    `json:"allow_negative"` // Pay more than the member's balance or jar
+}
+
+// UpdateSettlementRequest represents the request body for correcting a settlement
+type UpdateSettlementRequest struct {
+	Amount            *float64                 `json:"amount" binding:"omitempty,gt=0"`
+	Date              *string                  `json:"date"` // YYYY-MM-DD format
+	Note              *string                  `json:"note"`
+	Method            *models.SettlementMethod `json:"method"`
+	ExternalReference *string                  `json:"external_reference"`
+	CategoryID        *uuid.UUID               `json:"category_id"`
+	EntryIDs          []uuid.UUID              `json:"entry_ids"`
+	AllowNegative     bool                     `json:"allow_negative"`
+	Reason            string                   `json:"reason"`
+}
+
+// VoidSettlementRequest represents the request body for voiding a settlement
+type VoidSettlementRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DisputeSettlementRequest represents the request body for disputing or
+// resolving a dispute about a settlement
+type DisputeSettlementRequest struct {
+	Reason string `json:"reason"`
 }
 
 // SettlementResponse represents a settlement in API responses
 type SettlementResponse struct {
-	ID        uuid.UUID `json:"id"`
-	GroupID   uuid.UUID `json:"group_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Amount    float64   `json:"amount"`
-	Date      time.Time `json:"date"`
-	Note      *string   `json:"note,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                uuid.UUID                `json:"id"`
+	GroupID           uuid.UUID                `json:"group_id"`
+	UserID            uuid.UUID                `json:"user_id"`
+	Amount            float64                  `json:"amount"`
+	Date              time.Time                `json:"date"`
+	Note              *string                  `json:"note,omitempty"`
+	Method            models.SettlementMethod  `json:"method"`
+	ExternalReference *string                  `json:"external_reference,omitempty"`
+	CategoryID        *uuid.UUID               `json:"category_id,omitempty"`
+	JarID             *uuid.UUID               `json:"jar_id,omitempty"`
+	Status            models.SettlementStatus  `json:"status"`
+	AcknowledgedAt    *time.Time               `json:"acknowledged_at,omitempty"`
+	DisputedAt        *time.Time               `json:"disputed_at,omitempty"`
+	DisputeReason     *string                  `json:"dispute_reason,omitempty"`
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         *time.Time               `json:"updated_at,omitempty"`
+	VoidedAt          *time.Time               `json:"voided_at,omitempty"`
+	VoidedByUserID    *uuid.UUID               `json:"voided_by_user_id,omitempty"`
+	VoidReason        *string                  `json:"void_reason,omitempty"`
+	Entries           []models.SettlementEntry `json:"entries"`
+	Hash              *string                  `json:"hash,omitempty"`
 }
 
-// ListSettlements returns all settlements for a group
+func newSettlementResponse(s *models.Settlement) SettlementResponse {
+	entries := s.Entries
+	if entries == nil {
+		entries = []models.SettlementEntry{}
+	}
+	return SettlementResponse{
+		ID:                s.ID,
+		GroupID:           s.GroupID,
+		UserID:            s.UserID,
+		Amount:            s.Amount,
+		Date:              s.Date,
+		Note:              s.Note,
+		Method:            s.Method,
+		ExternalReference: s.ExternalReference,
+		CategoryID:        s.CategoryID,
+		JarID:             s.JarID,
+		Status:            s.Status,
+		AcknowledgedAt:    s.AcknowledgedAt,
+		DisputedAt:        s.DisputedAt,
+		DisputeReason:     s.DisputeReason,
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+		VoidedAt:          s.VoidedAt,
+		VoidedByUserID:    s.VoidedByUserID,
+		VoidReason:        s.VoidReason,
+		Entries:           entries,
+		Hash:              s.Hash,
+	}
+}
+
+// settlementReason validates the reason a head gives for changing a settlement
+func settlementReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", errors.New("reason is required")
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", fmt.Errorf("reason must be at most %d characters", maxReasonLength)
+	}
+	return reason, nil
+}
+
+// querySettlementStatus parses the optional status query parameter
+func querySettlementStatus(c *gin.Context) (*models.SettlementStatus, error) {
+	status := models.SettlementStatus(c.Query("status"))
+	switch status {
+	case "":
+		return nil, nil
+	case models.SettlementAwaitingAck, models.SettlementAcknowledged, models.SettlementDisputed:
+		return &status, nil
+	}
+	return nil, errors.New("invalid status")
+}
+
+// validSettlementMethod reports whether method is a known settlement method
+func validSettlementMethod(method models.SettlementMethod) bool {
+	return slices.Contains(models.SettlementMethods, method)
+}
+
+// querySettlementMethod parses the optional method query parameter
+func querySettlementMethod(c *gin.Context) (*models.SettlementMethod, error) {
+	method := models.SettlementMethod(c.Query("method"))
+	if method == "" {
+		return nil, nil
+	}
+	if !validSettlementMethod(method) {
+		return nil, errors.New("invalid method")
+	}
+	return &method, nil
+}
+
+// externalReference trims a settlement's external reference, treating a
+// blank one as none
+func externalReference(reference *string) (*string, error) {
+	if reference == nil {
+		return nil, nil
+	}
+	trimmed := strings.TrimSpace(*reference)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(trimmed) > maxReferenceLength {
+		return nil, fmt.Errorf("external_reference must be at most %d characters", maxReferenceLength)
+	}
+	return &trimmed, nil
+}
+
+// settlementFilter parses the query parameters that narrow a settlement
+// listing or summary
+func settlementFilter(c *gin.Context) (db.SettlementFilter, error) {
+	var filter db.SettlementFilter
+	var err error
+	if filter.UserID, err = queryUUID(c, "user_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = queryTime(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(c, "to", true); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = queryFloat(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = queryFloat(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	if filter.Voided, err = queryBool(c, "voided"); err != nil {
+		return filter, err
+	}
+	if filter.Status, err = querySettlementStatus(c); err != nil {
+		return filter, err
+	}
+	if filter.Method, err = querySettlementMethod(c); err != nil {
+		return filter, err
+	}
+	if filter.CategoryID, err = queryUUID(c, "category_id"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// settlementErrorMessage describes why a settlement could not be saved, for
+// errors caused by the request
+func settlementErrorMessage(err error) (string, bool) {
+	var overpayment *db.OverpaymentError
+	var jarBalance *db.JarBalanceError
+	switch {
+	case errors.As(err, &overpayment), errors.As(err, &jarBalance):
+		return err.Error() + "; set allow_negative to pay more", true
+	case errors.Is(err, db.ErrEntryNotPayable), errors.Is(err, db.ErrUnknownCategory), errors.Is(err, db.ErrUnknownJar):
+		return err.Error(), true
+	}
+	return "", false
+}
+
+// ListSettlements returns a page of settlements for a group
 // GET /api/v1/groups/:id/settlements
 func (h *SettlementHandler) ListSettlements(c *gin.Context) {
 	userIDStr, exists := auth.GetUserID(c)
@@ -79,28 +271,86 @@ func (h *SettlementHandler) ListSettlements(c *gin.Context) {
 		return
 	}
 
-	settlements, err := h.settlementRepo.ListForGroup(c.Request.Context(), groupID)
+	filter, err := settlementFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := parsePage(c, "-date")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settlements, info, err := h.settlementRepo.ListForGroup(c.Request.Context(), groupID, filter, page)
 	if err != nil {
+		if msg, ok := listErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list settlements"})
 		return
 	}
+	setPageHeaders(c, info)
 
 	response := make([]SettlementResponse, 0, len(settlements))
 	for _, s := range settlements {
-		response = append(response, SettlementResponse{
-			ID:        s.ID,
-			GroupID:   s.GroupID,
-			UserID:    s.UserID,
-			Amount:    s.Amount,
-			Date:      s.Date,
-			Note:      s.Note,
-			CreatedAt: s.CreatedAt,
-		})
+		response = append(response, newSettlementResponse(s))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// SettlementSummary totals a group's settlements by method and category,
+// narrowed by the same filters as ListSettlements. Voided settlements are
+// left out unless voided is given.
+// GET /api/v1/groups/:id/settlements/summary
+func (h *SettlementHandler) SettlementSummary(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	filter, err := settlementFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := h.settlementRepo.Summarize(c.Request.Context(), groupID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to summarize settlements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
 // CreateSettlement creates a new settlement
 // POST /api/v1/groups/:id/settlements
 func (h *SettlementHandler) CreateSettlement(c *gin.Context) {
@@ -152,6 +402,20 @@ func (h *SettlementHandler) CreateSettlement(c *gin.Context) {
 		return
 	}
 
+	if req.Method == "" {
+		req.Method = models.MethodCash
+	}
+	if !validSettlementMethod(req.Method) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid method"})
+		return
+	}
+
+	reference, err := externalReference(req.ExternalReference)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Verify target user is a member
 	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, req.UserID)
 	if err != nil {
@@ -163,19 +427,482 @@ func (h *SettlementHandler) CreateSettlement(c *gin.Context) {
 		return
 	}
 
-	settlement, err := h.settlementRepo.Create(c.Request.Context(), groupID, req.UserID, req.Amount, date, req.Note)
+	// A head paying themselves has nobody else to confirm it
+	status := models.SettlementAwaitingAck
+	if req.UserID == userID {
+		status = models.SettlementAcknowledged
+	}
+
+	settlement, err := h.settlementRepo.Create(c.Request.Context(), &models.Settlement{
+		GroupID:           groupID,
+		UserID:            req.UserID,
+		Amount:            req.Amount,
+		Date:              date,
+		Note:              req.Note,
+		Method:            req.Method,
+		ExternalReference: reference,
+		CategoryID:        req.CategoryID,
+		JarID:             req.JarID,
+		Status:            status,
+	}, req.EntryIDs, req.AllowNegative)
 	if err != nil {
+		if msg, ok := settlementErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create settlement"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, SettlementResponse{
-		ID:        settlement.ID,
-		GroupID:   settlement.GroupID,
-		UserID:    settlement.UserID,
-		Amount:    settlement.Amount,
-		Date:      settlement.Date,
-		Note:      settlement.Note,
-		CreatedAt: settlement.CreatedAt,
-	})
+	response := newSettlementResponse(settlement)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementCreated, groupID, &userID, response))
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateSettlement corrects a settlement's amount, date, note, payout details or entries,
+// recording the reason (head only)
+// PATCH /api/v1/settlements/:id
+func (h *SettlementHandler) UpdateSettlement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	// Check if user is head of the group
+	member, err := h.groupRepo.GetMember(c.Request.Context(), settlement.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can edit settlements"})
+		return
+	}
+
+	var req UpdateSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := settlementReason(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Amount == nil && req.Date == nil && req.Note == nil && req.Method == nil &&
+		req.ExternalReference == nil && req.CategoryID == nil && req.EntryIDs == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	if req.Method != nil && !validSettlementMethod(*req.Method) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid method"})
+		return
+	}
+
+	update := db.SettlementUpdate{
+		Amount:        req.Amount,
+		Note:          req.Note,
+		Method:        req.Method,
+		CategoryID:    req.CategoryID,
+		EntryIDs:      req.EntryIDs,
+		AllowNegative: req.AllowNegative,
+	}
+	if req.ExternalReference != nil {
+		reference, err := externalReference(req.ExternalReference)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if reference == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "external_reference cannot be blank"})
+			return
+		}
+		update.ExternalReference = reference
+	}
+	if req.Date != nil {
+		date, err := time.Parse("2006-01-02", *req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		update.Date = &date
+	}
+
+	updated, err := h.settlementRepo.Update(c.Request.Context(), settlementID, update, userID, reason)
+	if err != nil {
+		if errors.Is(err, db.ErrVoided) {
+			c.JSON(http.StatusConflict, gin.H{"error": "settlement is voided"})
+			return
+		}
+		if msg, ok := settlementErrorMessage(err); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update settlement"})
+		return
+	}
+
+	response := newSettlementResponse(updated)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementUpdated, updated.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VoidSettlement voids a settlement so it no longer counts towards the
+// member's balance, recording the reason (head only)
+// POST /api/v1/settlements/:id/void
+func (h *SettlementHandler) VoidSettlement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	// Check if user is head of the group
+	member, err := h.groupRepo.GetMember(c.Request.Context(), settlement.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can void settlements"})
+		return
+	}
+
+	var req VoidSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := settlementReason(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voided, err := h.settlementRepo.Void(c.Request.Context(), settlementID, userID, reason)
+	if err != nil {
+		if errors.Is(err, db.ErrVoided) {
+			c.JSON(http.StatusConflict, gin.H{"error": "settlement is already voided"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to void settlement"})
+		return
+	}
+
+	response := newSettlementResponse(voided)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementVoided, voided.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListSettlementRevisions returns the edits and void of a settlement, oldest first
+// GET /api/v1/settlements/:id/revisions
+func (h *SettlementHandler) ListSettlementRevisions(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), settlement.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	revisions, err := h.settlementRepo.ListRevisions(c.Request.Context(), settlementID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list settlement revisions"})
+		return
+	}
+	if revisions == nil {
+		revisions = []*models.SettlementRevision{}
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// AcknowledgeSettlement confirms that a settlement was received (the paid
+// member only)
+// POST /api/v1/settlements/:id/ack
+func (h *SettlementHandler) AcknowledgeSettlement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	if settlement.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the paid member can acknowledge a settlement"})
+		return
+	}
+
+	acknowledged, err := h.settlementRepo.Acknowledge(c.Request.Context(), settlementID, userID)
+	if err != nil {
+		var statusErr *db.SettlementStatusError
+		if errors.As(err, &statusErr) || errors.Is(err, db.ErrVoided) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to acknowledge settlement"})
+		return
+	}
+
+	response := newSettlementResponse(acknowledged)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementAcknowledged, acknowledged.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisputeSettlement reports that a settlement awaiting acknowledgement is
+// wrong or was never received (the paid member only)
+// POST /api/v1/settlements/:id/dispute
+func (h *SettlementHandler) DisputeSettlement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	if settlement.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the paid member can dispute a settlement"})
+		return
+	}
+
+	var req DisputeSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := settlementReason(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	disputed, err := h.settlementRepo.Dispute(c.Request.Context(), settlementID, userID, reason)
+	if err != nil {
+		var statusErr *db.SettlementStatusError
+		if errors.As(err, &statusErr) || errors.Is(err, db.ErrVoided) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to dispute settlement"})
+		return
+	}
+
+	response := newSettlementResponse(disputed)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementDisputed, disputed.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResolveSettlement answers a dispute by standing by the settlement, which
+// goes back to awaiting acknowledgement (head only)
+// POST /api/v1/settlements/:id/resolve
+func (h *SettlementHandler) ResolveSettlement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid settlement ID"})
+		return
+	}
+
+	settlement, err := h.settlementRepo.GetByID(c.Request.Context(), settlementID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settlement not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get settlement"})
+		return
+	}
+
+	// Check if user is head of the group
+	member, err := h.groupRepo.GetMember(c.Request.Context(), settlement.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can resolve disputes"})
+		return
+	}
+
+	var req DisputeSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := settlementReason(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resolved, err := h.settlementRepo.Resolve(c.Request.Context(), settlementID, userID, reason)
+	if err != nil {
+		var statusErr *db.SettlementStatusError
+		if errors.As(err, &statusErr) || errors.Is(err, db.ErrVoided) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve dispute"})
+		return
+	}
+
+	response := newSettlementResponse(resolved)
+	h.events.Publish(c.Request.Context(), events.New(events.SettlementUpdated, resolved.GroupID, &userID, response))
+
+	c.JSON(http.StatusOK, response)
 }
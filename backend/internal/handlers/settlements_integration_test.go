@@ -0,0 +1,85 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/handlers"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestSettlement_EditAndVoid(t *testing.T) {
+	app, cleanup := setupIntegrationRouter(t)
+	defer cleanup()
+
+	head, headToken := app.newUser(t, "parent")
+	kid, kidToken := app.newUser(t, "kid")
+	groupID := app.newGroup(t, head, kid)
+	choreID := app.newChore(t, groupID, "Dishes", 5)
+	app.recordEntry(t, groupID, choreID, headToken, kid, 5, time.Now().AddDate(0, 0, -2))
+
+	today := time.Now().UTC().Format("2006-01-02")
+	settle := func(amount float64) *httptest.ResponseRecorder {
+		return app.do(http.MethodPost, fmt.Sprintf("/api/v1/groups/%s/settlements", groupID), headToken, map[string]any{
+			"user_id": kid,
+			"amount":  amount,
+			"date":    today,
+		})
+	}
+
+	w := settle(3)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	settlement := decode[handlers.SettlementResponse](t, w)
+	assert.Equal(t, 2.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+
+	// Paying more than is owed needs allow_negative
+	w = settle(10)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	path := fmt.Sprintf("/api/v1/settlements/%s", settlement.ID)
+	w = app.do(http.MethodPatch, path, headToken, map[string]any{"amount": 4})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a correction needs a reason")
+	w = app.do(http.MethodPatch, path, kidToken, map[string]any{"amount": 4, "reason": "miscounted"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = app.do(http.MethodPatch, path, headToken, map[string]any{"amount": 4, "reason": "miscounted"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 1.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+
+	w = app.do(http.MethodPost, path+"/void", headToken, map[string]any{"reason": "paid twice"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	voided := decode[handlers.SettlementResponse](t, w)
+	require.NotNil(t, voided.VoidedAt)
+	assert.Equal(t, &head, voided.VoidedByUserID)
+	assert.Equal(t, 5.0, app.balances(t, groupID, kidToken, "")[kid].Balance)
+
+	// A voided settlement is final
+	w = app.do(http.MethodPost, path+"/void", headToken, map[string]any{"reason": "again"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = app.do(http.MethodPatch, path, headToken, map[string]any{"amount": 2, "reason": "too late"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = app.do(http.MethodGet, path+"/revisions", kidToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	revisions := decode[[]models.SettlementRevision](t, w)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, models.SettlementEdited, revisions[0].Action)
+	assert.Equal(t, "miscounted", revisions[0].Reason)
+	assert.Equal(t, 3.0, revisions[0].AmountBefore)
+	assert.Equal(t, 4.0, revisions[0].AmountAfter)
+	assert.Equal(t, models.SettlementVoided, revisions[1].Action)
+	assert.Equal(t, "paid twice", revisions[1].Reason)
+
+	// The entry, then the settlement's creation, edit and void
+	report := app.verifyChain(t, groupID, kidToken)
+	assert.True(t, report.Valid, "%+v", report.Breaks)
+	assert.Equal(t, 4, report.Links)
+	assert.Equal(t, 2, report.Records)
+}
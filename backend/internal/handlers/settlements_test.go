@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func TestSettlementReason(t *testing.T) {
+	reason, err := settlementReason("  typed 500 instead of 50 ")
+	require.NoError(t, err)
+	assert.Equal(t, "typed 500 instead of 50", reason)
+
+	_, err = settlementReason(" \n")
+	assert.EqualError(t, err, "reason is required")
+
+	_, err = settlementReason(strings.Repeat("a", maxReasonLength+1))
+	assert.Error(t, err)
+}
+
+func TestSettlementErrorMessage(t *testing.T) {
+	msg, ok := settlementErrorMessage(&db.OverpaymentError{Balance: 12.5})
+	assert.True(t, ok)
+	assert.Equal(t, "settlement exceeds the member's balance of 12.50; set allow_negative to pay more", msg)
+
+	id := uuid.New()
+	msg, ok = settlementErrorMessage(fmt.Errorf("%w: %s", db.ErrEntryNotPayable, id))
+	assert.True(t, ok)
+	assert.Contains(t, msg, id.String())
+
+	msg, ok = settlementErrorMessage(db.ErrUnknownCategory)
+	assert.True(t, ok)
+	assert.Equal(t, "category is not a category of this group", msg)
+
+	msg, ok = settlementErrorMessage(&db.JarBalanceError{Jar: "Spend", Balance: 3})
+	assert.True(t, ok)
+	assert.Equal(t, "amount exceeds the Spend jar's balance of 3.00; set allow_negative to pay more", msg)
+
+	msg, ok = settlementErrorMessage(db.ErrUnknownJar)
+	assert.True(t, ok)
+	assert.Equal(t, "jar is not a jar of this member", msg)
+
+	_, ok = settlementErrorMessage(errors.New("connection refused"))
+	assert.False(t, ok)
+}
+
+func TestNewSettlementResponse_EntriesNeverNull(t *testing.T) {
+	response := newSettlementResponse(&models.Settlement{ID: uuid.New()})
+	assert.NotNil(t, response.Entries)
+	assert.Empty(t, response.Entries)
+}
+
+func TestExternalReference(t *testing.T) {
+	reference, err := externalReference(nil)
+	require.NoError(t, err)
+	assert.Nil(t, reference)
+
+	blank := "   "
+	reference, err = externalReference(&blank)
+	require.NoError(t, err)
+	assert.Nil(t, reference)
+
+	transfer := " TRX-20261018-0042 "
+	reference, err = externalReference(&transfer)
+	require.NoError(t, err)
+	require.NotNil(t, reference)
+	assert.Equal(t, "TRX-20261018-0042", *reference)
+
+	long := strings.Repeat("x", maxReferenceLength+1)
+	_, err = externalReference(&long)
+	assert.Error(t, err)
+}
+
+func TestValidSettlementMethod(t *testing.T) {
+	for _, method := range models.SettlementMethods {
+		assert.True(t, validSettlementMethod(method), method)
+	}
+	assert.False(t, validSettlementMethod("cheque"))
+	assert.False(t, validSettlementMethod(""))
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/statement"
+)
+
+// StatementHandler handles member statement requests
+type StatementHandler struct {
+	ledgerRepo     *db.LedgerRepo
+	settlementRepo *db.SettlementRepo
+	categoryRepo   *db.SettlementCategoryRepo
+	groupRepo      *db.GroupRepo
+	choreRepo      *db.ChoreRepo
+	userRepo       *db.UserRepo
+}
+
+// NewStatementHandler creates a new StatementHandler
+func NewStatementHandler(ledgerRepo *db.LedgerRepo, settlementRepo *db.SettlementRepo, categoryRepo *db.SettlementCategoryRepo, groupRepo *db.GroupRepo, choreRepo *db.ChoreRepo, userRepo *db.UserRepo) *StatementHandler {
+	return &StatementHandler{
+		ledgerRepo:     ledgerRepo,
+		settlementRepo: settlementRepo,
+		categoryRepo:   categoryRepo,
+		groupRepo:      groupRepo,
+		choreRepo:      choreRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// GetStatement returns a member's monthly statement as JSON, CSV or printable HTML
+// GET /api/v1/groups/:id/members/:user_id/statement
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	memberIDStr := c.Param("user_id")
+	memberID, err := uuid.Parse(memberIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	// Check if user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	// Check the requested user is a member
+	_, err = h.groupRepo.GetMember(c.Request.Context(), groupID, memberID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	period := statement.PeriodOf(time.Now())
+	if value := c.Query("period"); value != "" {
+		period, err = statement.ParsePeriod(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use json, csv or html"})
+		return
+	}
+
+	st, err := h.buildStatement(c, groupID, memberID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build statement"})
+		return
+	}
+
+	switch format {
+	case "csv":
+		var buf bytes.Buffer
+		if err := st.WriteCSV(&buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render statement"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, st.Filename("csv")))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	case "html":
+		var buf bytes.Buffer
+		if err := st.WriteHTML(&buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render statement"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	default:
+		c.JSON(http.StatusOK, st)
+	}
+}
+
+// buildStatement loads everything a statement needs for one member and period
+func (h *StatementHandler) buildStatement(c *gin.Context, groupID, memberID uuid.UUID, period statement.Period) (*statement.Statement, error) {
+	ctx := c.Request.Context()
+
+	group, err := h.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := h.userRepo.GetByID(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := h.ledgerRepo.GetMemberBalance(ctx, groupID, memberID, period.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := h.ledgerRepo.ListApprovedForMember(ctx, groupID, memberID, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements, err := h.settlementRepo.ListForMember(ctx, groupID, memberID, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+
+	chores, err := h.choreRepo.ListForGroup(ctx, groupID, true)
+	if err != nil {
+		return nil, err
+	}
+	choresByID := make(map[uuid.UUID]*models.Chore, len(chores))
+	for _, chore := range chores {
+		choresByID[chore.ID] = chore
+	}
+
+	categories, err := h.categoryRepo.ListForGroup(ctx, groupID, true)
+	if err != nil {
+		return nil, err
+	}
+	categoriesByID := make(map[uuid.UUID]*models.SettlementCategory, len(categories))
+	for _, category := range categories {
+		categoriesByID[category.ID] = category
+	}
+
+	return statement.Build(group, member, period, opening, entries, choresByID, settlements, categoriesByID, time.Now()), nil
+}
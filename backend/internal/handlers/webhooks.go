@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/auth"
+	"github.com/srjn45/pocket-money/backend/internal/db"
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/webhook"
+)
+
+// minWebhookSecretLength is the shortest signing secret a head may choose
+const minWebhookSecretLength = 16
+
+// WebhookHandler handles webhook subscription requests
+type WebhookHandler struct {
+	webhookRepo *db.WebhookRepo
+	groupRepo   *db.GroupRepo
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(webhookRepo *db.WebhookRepo, groupRepo *db.GroupRepo) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo: webhookRepo,
+		groupRepo:   groupRepo,
+	}
+}
+
+// CreateWebhookRequest represents the request body for creating a webhook
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	Secret     *string  `json:"secret"` // Optional, generated when omitted
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+}
+
+// UpdateWebhookRequest represents the request body for updating a webhook
+type UpdateWebhookRequest struct {
+	URL        *string  `json:"url"`
+	Secret     *string  `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookResponse represents a webhook in API responses
+type WebhookResponse struct {
+	ID              uuid.UUID `json:"id"`
+	GroupID         uuid.UUID `json:"group_id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"secret,omitempty"` // Only set when the webhook is created
+	EventTypes      []string  `json:"event_types"`
+	Active          bool      `json:"active"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(w *models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:              w.ID,
+		GroupID:         w.GroupID,
+		URL:             w.URL,
+		EventTypes:      w.EventTypes,
+		Active:          w.Active,
+		CreatedByUserID: w.CreatedByUserID,
+		CreatedAt:       w.CreatedAt,
+	}
+}
+
+// parseEventTypes validates and de-duplicates requested event types
+func parseEventTypes(types []string) ([]string, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("event_types must not be empty")
+	}
+	seen := make(map[string]bool, len(types))
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		if !events.Type(t).Valid() {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// validateWebhookSecret checks a head-chosen signing secret
+func validateWebhookSecret(secret string) error {
+	if len(secret) < minWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minWebhookSecretLength)
+	}
+	return nil
+}
+
+// ListWebhooks returns a group's webhooks (head only)
+// GET /api/v1/groups/:id/webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can view webhooks"})
+		return
+	}
+
+	webhooks, err := h.webhookRepo.ListForGroup(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(webhooks))
+	for _, w := range webhooks {
+		response = append(response, newWebhookResponse(w))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWebhook subscribes a URL to a group's events (head only).
+// The signing secret is returned only in this response.
+// POST /api/v1/groups/:id/webhooks
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group ID"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can create webhooks"})
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventTypes, err := parseEventTypes(req.EventTypes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var secret string
+	if req.Secret != nil {
+		if err := validateWebhookSecret(*req.Secret); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		secret = *req.Secret
+	} else if secret, err = webhook.NewSecret(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+
+	w, err := h.webhookRepo.Create(c.Request.Context(), groupID, req.URL, secret, eventTypes, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create webhook"})
+		return
+	}
+
+	response := newWebhookResponse(w)
+	response.Secret = w.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateWebhook changes a webhook's URL, secret, event types or active flag (head only)
+// PATCH /api/v1/webhooks/:id
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	w, err := h.webhookRepo.GetByID(c.Request.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), w.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can update webhooks"})
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.URL != nil {
+		if err := webhook.ValidateURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Secret != nil {
+		if err := validateWebhookSecret(*req.Secret); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var eventTypes []string
+	if req.EventTypes != nil {
+		if eventTypes, err = parseEventTypes(req.EventTypes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	updated, err := h.webhookRepo.Update(c.Request.Context(), webhookID, req.URL, req.Secret, eventTypes, req.Active)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(updated))
+}
+
+// DeleteWebhook deletes a webhook and its delivery log (head only)
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	w, err := h.webhookRepo.GetByID(c.Request.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), w.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can delete webhooks"})
+		return
+	}
+
+	if err := h.webhookRepo.Delete(c.Request.Context(), webhookID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns a webhook's most recent deliveries (head only)
+// GET /api/v1/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userIDStr, exists := auth.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook ID"})
+		return
+	}
+
+	w, err := h.webhookRepo.GetByID(c.Request.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get webhook"})
+		return
+	}
+
+	member, err := h.groupRepo.GetMember(c.Request.Context(), w.GroupID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check membership"})
+		return
+	}
+
+	if member.Role != models.RoleHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only group head can view webhook deliveries"})
+		return
+	}
+
+	var status *models.DeliveryStatus
+	if s := c.Query("status"); s != "" {
+		st := models.DeliveryStatus(s)
+		if st != models.DeliveryPending && st != models.DeliveryDelivered && st != models.DeliveryFailed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or failed"})
+			return
+		}
+		status = &st
+	}
+
+	limit := db.DefaultPageLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		if limit > db.MaxPageLimit {
+			limit = db.MaxPageLimit
+		}
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveries(c.Request.Context(), webhookID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhook deliveries"})
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventTypes(t *testing.T) {
+	types, err := parseEventTypes([]string{"ledger.approved", "settlement.created", "ledger.approved"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ledger.approved", "settlement.created"}, types)
+
+	_, err = parseEventTypes(nil)
+	assert.Error(t, err)
+
+	_, err = parseEventTypes([]string{"ledger.approved", "ledger.deleted"})
+	assert.EqualError(t, err, `unknown event type "ledger.deleted"`)
+}
+
+func TestValidateWebhookSecret(t *testing.T) {
+	assert.NoError(t, validateWebhookSecret("0123456789abcdef"))
+	assert.Error(t, validateWebhookSecret("short"))
+}
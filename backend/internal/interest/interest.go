@@ -0,0 +1,118 @@
+// Package interest works out the interest a balance earns over a period from
+// its average daily balance, in whole cents so every run agrees.
+package interest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// DaysPerYear is the year an annual rate is spread over, leap or not
+const DaysPerYear = 365
+
+// Change is the net amount a balance moved by on a UTC day
+type Change struct {
+	Day    time.Time
+	Amount float64
+}
+
+// PeriodEnd returns the day after the last day of the compounding period
+// that starts on period, for a rate that started on start
+func PeriodEnd(start time.Time, compounding models.AllowanceInterval, period time.Time) time.Time {
+	return allowance.Next(start, compounding, allowance.Day(period).AddDate(0, 0, 1))
+}
+
+// Due returns the starts of the rate's periods that have ended by now and
+// are not yet posted, oldest first. Nothing is due once it is deleted.
+func Due(r *models.InterestRate, now time.Time) []time.Time {
+	if r.DeletedAt != nil {
+		return nil
+	}
+
+	today := allowance.Day(now)
+	var due []time.Time
+	p := allowance.Next(r.StartDate, r.Compounding, r.NextPeriod)
+	for end := PeriodEnd(r.StartDate, r.Compounding, p); !end.After(today); end = PeriodEnd(r.StartDate, r.Compounding, p) {
+		due = append(due, p)
+		p = end
+	}
+	return due
+}
+
+// DailyBalances returns the balance at the end of each day in [start, end).
+// Changes before start make up the opening balance; later ones are ignored.
+func DailyBalances(changes []Change, start, end time.Time) []float64 {
+	start, end = allowance.Day(start), allowance.Day(end)
+
+	sorted := append([]Change(nil), changes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Day.Before(sorted[j].Day) })
+
+	var balances []float64
+	var cents int64
+	i := 0
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		for ; i < len(sorted) && !allowance.Day(sorted[i].Day).After(day); i++ {
+			cents += toCents(sorted[i].Amount)
+		}
+		balances = append(balances, float64(cents)/100)
+	}
+	return balances
+}
+
+// Calculate works out the interest for the period [start, end) from the
+// balance at the end of each of its days. Days in debt count as nothing.
+// No interest is paid when the average daily balance is below minBalance;
+// otherwise it is the average × apr% × days / 365, rounded half up to the
+// cent. Breakdown.Explanation spells the sum out.
+func Calculate(start, end time.Time, daily []float64, apr, minBalance float64) models.InterestBreakdown {
+	b := models.InterestBreakdown{
+		PeriodStart:   allowance.Day(start).Format("2006-01-02"),
+		PeriodEnd:     allowance.Day(end).AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:          len(daily),
+		DailyBalances: daily,
+		APR:           apr,
+		MinBalance:    minBalance,
+	}
+	if b.Days == 0 {
+		b.Explanation = "No days in this period, so no interest."
+		return b
+	}
+
+	// The sum of the daily balances is the average times the days, so the
+	// interest is sum × apr / 100 / 365 and stays in whole numbers until
+	// the final rounding. The rate is in hundredths of a percent.
+	var sum int64
+	for _, balance := range daily {
+		if c := toCents(balance); c > 0 {
+			sum += c
+		}
+	}
+	days := int64(b.Days)
+	b.AverageBalance = float64(divRound(sum, days)) / 100
+
+	if sum < toCents(minBalance)*days {
+		b.Explanation = fmt.Sprintf("Average balance %.2f over %d days is below the minimum of %.2f, so no interest.",
+			b.AverageBalance, b.Days, minBalance)
+		return b
+	}
+
+	b.Amount = float64(divRound(sum*toCents(apr), 100*100*DaysPerYear)) / 100
+	b.Explanation = fmt.Sprintf("Average balance %.2f × %.2f%% a year × %d/%d days = %.2f",
+		b.AverageBalance, apr, b.Days, DaysPerYear, b.Amount)
+	return b
+}
+
+// toCents converts an amount to whole cents
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// divRound divides two non-negative numbers, rounding half up
+func divRound(n, d int64) int64 {
+	return (2*n + d) / (2 * d)
+}
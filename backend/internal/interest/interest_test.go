@@ -0,0 +1,112 @@
+package interest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestPeriodEnd(t *testing.T) {
+	assert.Equal(t, day("2026-09-14"), PeriodEnd(day("2026-09-07"), models.IntervalWeek, day("2026-09-07")))
+	assert.Equal(t, day("2026-10-31"), PeriodEnd(day("2026-01-31"), models.IntervalMonth, day("2026-09-30")))
+	assert.Equal(t, day("2026-03-31"), PeriodEnd(day("2026-01-31"), models.IntervalMonth, day("2026-02-28")))
+}
+
+func TestDue(t *testing.T) {
+	rate := &models.InterestRate{
+		Compounding: models.IntervalWeek,
+		StartDate:   day("2026-09-07"),
+		NextPeriod:  day("2026-09-07"),
+	}
+
+	// The first week has not ended until its last day is over
+	assert.Empty(t, Due(rate, day("2026-09-13").Add(23*time.Hour)))
+	assert.Equal(t, []time.Time{day("2026-09-07")}, Due(rate, day("2026-09-14")))
+
+	// Missed weeks are caught up, oldest first
+	assert.Equal(t, []time.Time{day("2026-09-07"), day("2026-09-14"), day("2026-09-21")}, Due(rate, day("2026-09-30")))
+
+	rate.NextPeriod = day("2026-09-21")
+	assert.Equal(t, []time.Time{day("2026-09-21")}, Due(rate, day("2026-09-30")))
+
+	deleted := day("2026-09-29")
+	rate.DeletedAt = &deleted
+	assert.Empty(t, Due(rate, day("2026-09-30")))
+}
+
+func TestDailyBalances(t *testing.T) {
+	changes := []Change{
+		{Day: day("2026-09-03"), Amount: 2.5},
+		{Day: day("2026-08-20"), Amount: 10},
+		{Day: day("2026-09-01").Add(15 * time.Hour), Amount: -4},
+		{Day: day("2026-09-05"), Amount: 100}, // After the period
+	}
+
+	balances := DailyBalances(changes, day("2026-09-01"), day("2026-09-05"))
+	assert.Equal(t, []float64{6, 6, 8.5, 8.5}, balances)
+
+	assert.Empty(t, DailyBalances(changes, day("2026-09-05"), day("2026-09-05")))
+}
+
+func TestCalculate(t *testing.T) {
+	daily := []float64{100, 100, 100, 100, 100, 100, 100}
+	b := Calculate(day("2026-09-07"), day("2026-09-14"), daily, 5, 0)
+	assert.Equal(t, "2026-09-07", b.PeriodStart)
+	assert.Equal(t, "2026-09-13", b.PeriodEnd)
+	assert.Equal(t, 7, b.Days)
+	assert.Equal(t, 100.0, b.AverageBalance)
+	// 100 × 5% × 7/365 = 0.0958…
+	assert.Equal(t, 0.1, b.Amount)
+	assert.Equal(t, "Average balance 100.00 × 5.00% a year × 7/365 days = 0.10", b.Explanation)
+
+	// The same inputs always give the same answer
+	assert.Equal(t, b, Calculate(day("2026-09-07"), day("2026-09-14"), daily, 5, 0))
+}
+
+func TestCalculate_AverageAndRounding(t *testing.T) {
+	// 10 for three days, then 40 for one: an average of 17.50
+	daily := []float64{10, 10, 10, 40}
+	b := Calculate(day("2026-09-01"), day("2026-09-05"), daily, 36.5, 0)
+	assert.Equal(t, 17.5, b.AverageBalance)
+	// 70.00 × 36.5% / 365 = 0.07 exactly
+	assert.Equal(t, 0.07, b.Amount)
+
+	// Exactly half a cent rounds up: 50.00 × 36.5% / 365 = 0.005
+	b = Calculate(day("2026-09-01"), day("2026-09-02"), []float64{5}, 36.5, 0)
+	assert.Equal(t, 0.01, b.Amount)
+}
+
+func TestCalculate_DebtEarnsNothing(t *testing.T) {
+	b := Calculate(day("2026-09-01"), day("2026-09-05"), []float64{-20, -20, 20, 20}, 36.5, 0)
+	assert.Equal(t, 10.0, b.AverageBalance)
+	assert.Equal(t, 0.04, b.Amount)
+}
+
+func TestCalculate_MinimumBalance(t *testing.T) {
+	b := Calculate(day("2026-09-01"), day("2026-09-03"), []float64{4, 5.99}, 10, 5)
+	assert.Equal(t, 5.0, b.AverageBalance) // 4.995 rounded for display
+	assert.Zero(t, b.Amount)
+	assert.Equal(t, "Average balance 5.00 over 2 days is below the minimum of 5.00, so no interest.", b.Explanation)
+
+	b = Calculate(day("2026-09-01"), day("2026-09-03"), []float64{4, 6}, 365, 5)
+	require.Equal(t, 5.0, b.AverageBalance)
+	assert.Equal(t, 0.1, b.Amount)
+}
+
+func TestCalculate_NoDays(t *testing.T) {
+	b := Calculate(day("2026-09-01"), day("2026-09-01"), nil, 5, 0)
+	assert.Zero(t, b.Days)
+	assert.Zero(t, b.Amount)
+}
@@ -0,0 +1,80 @@
+// Package jars splits a member's earnings between their jars, such as
+// spend, save and give.
+package jars
+
+import (
+	"errors"
+	"math"
+)
+
+// MaxJars is the most jars a member can have
+const MaxJars = 10
+
+// ErrSplitTotal is returned when a member's split percentages do not add up to 100
+var ErrSplitTotal = errors.New("split percentages must add up to 100")
+
+// ValidateSplit checks that each percentage is between 0 and 100 with at
+// most two decimals, and that together they add up to 100. No jars at all
+// is a valid split: nothing is split.
+func ValidateSplit(percents []float64) error {
+	if len(percents) == 0 {
+		return nil
+	}
+	if len(percents) > MaxJars {
+		return errors.New("a member can have at most 10 jars")
+	}
+	var total int64
+	for _, p := range percents {
+		if p < 0 || p > 100 {
+			return errors.New("split percentages must be between 0 and 100")
+		}
+		hundredths := math.Round(p * 100)
+		if math.Abs(p*100-hundredths) > 1e-6 {
+			return errors.New("split percentages can have at most two decimals")
+		}
+		total += int64(hundredths)
+	}
+	if total != 10000 {
+		return ErrSplitTotal
+	}
+	return nil
+}
+
+// Split divides amount between jars by their percentages, in whole cents.
+// Each jar gets its share rounded down, and the cents left over go one at a
+// time to the jars that lost the most to rounding, earliest jar first, so
+// the parts always add up to amount.
+func Split(amount float64, percents []float64) []float64 {
+	parts := make([]float64, len(percents))
+	if len(percents) == 0 {
+		return parts
+	}
+
+	cents := int64(math.Round(amount * 100))
+	shares := make([]int64, len(percents))
+	remainders := make([]int64, len(percents))
+	var assigned int64
+	for i, p := range percents {
+		// Work in hundredths of a percent so the arithmetic stays exact
+		exact := cents * int64(math.Round(p*100))
+		shares[i] = exact / 10000
+		remainders[i] = exact % 10000
+		assigned += shares[i]
+	}
+
+	for left := cents - assigned; left > 0; left-- {
+		best := 0
+		for i := range remainders {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		shares[best]++
+		remainders[best] = -1
+	}
+
+	for i, s := range shares {
+		parts[i] = float64(s) / 100
+	}
+	return parts
+}
@@ -0,0 +1,40 @@
+package jars
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSplit(t *testing.T) {
+	assert.NoError(t, ValidateSplit(nil))
+	assert.NoError(t, ValidateSplit([]float64{50, 40, 10}))
+	assert.NoError(t, ValidateSplit([]float64{33.33, 33.33, 33.34}))
+	assert.NoError(t, ValidateSplit([]float64{100, 0}))
+
+	assert.ErrorIs(t, ValidateSplit([]float64{50, 40}), ErrSplitTotal)
+	assert.ErrorIs(t, ValidateSplit([]float64{33.33, 33.33, 33.33}), ErrSplitTotal)
+	assert.EqualError(t, ValidateSplit([]float64{120, -20}), "split percentages must be between 0 and 100")
+	assert.EqualError(t, ValidateSplit([]float64{33.333, 66.667}), "split percentages can have at most two decimals")
+	assert.EqualError(t, ValidateSplit(make([]float64, 11)), "a member can have at most 10 jars")
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []float64{5, 4, 1}, Split(10, []float64{50, 40, 10}))
+	assert.Equal(t, []float64{}, Split(10, nil))
+
+	// Leftover cents go to the jars that lost the most to rounding
+	assert.Equal(t, []float64{0.33, 0.33, 0.34}, Split(1, []float64{33.33, 33.33, 33.34}))
+	assert.Equal(t, []float64{0.34, 0.33, 0.33}, Split(1, []float64{33.34, 33.33, 33.33}))
+	assert.Equal(t, []float64{0.04, 0.03, 0}, Split(0.07, []float64{50, 50, 0}))
+	assert.Equal(t, []float64{1.67, 3.33}, Split(5, []float64{33.33, 66.67}))
+
+	// The parts always add up to the amount
+	for _, amount := range []float64{0.01, 0.99, 7.77, 123.45} {
+		var total float64
+		for _, p := range Split(amount, []float64{33.33, 33.33, 33.34}) {
+			total += p
+		}
+		assert.InDelta(t, amount, total, 1e-9)
+	}
+}
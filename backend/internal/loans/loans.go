@@ -0,0 +1,84 @@
+// Package loans works out what a member owes on an advance and how much of
+// it each repayment takes, in whole cents so every run agrees.
+package loans
+
+import (
+	"math"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/allowance"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// Repayment is one weekly repayment on the schedule
+type Repayment struct {
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+}
+
+// TotalDue returns what a loan of principal costs to pay back with flat
+// interest of interestPercent, rounded half up to the cent
+func TotalDue(principal, interestPercent float64) float64 {
+	p := toCents(principal)
+	return float64(p+divRound(p*toCents(interestPercent), 100*100)) / 100
+}
+
+// Schedule returns the weekly repayments that pay off what is outstanding,
+// from the loan's next repayment on. The last one takes only what is left.
+// Loans repaid by withholding have no schedule.
+func Schedule(l *models.Loan) []Repayment {
+	if l.RepaymentPlan != models.RepayWeekly || l.WeeklyAmount == nil || l.NextRepayment == nil {
+		return nil
+	}
+	weekly := toCents(*l.WeeklyAmount)
+	if weekly <= 0 {
+		return nil
+	}
+
+	var schedule []Repayment
+	day := allowance.Day(*l.NextRepayment)
+	for left := toCents(l.Outstanding); left > 0; left -= weekly {
+		schedule = append(schedule, Repayment{Date: day, Amount: float64(min(weekly, left)) / 100})
+		day = day.AddDate(0, 0, 7)
+	}
+	return schedule
+}
+
+// Due returns the scheduled repayments that fall on or before now's UTC
+// date, oldest first
+func Due(l *models.Loan, now time.Time) []Repayment {
+	today := allowance.Day(now)
+	var due []Repayment
+	for _, r := range Schedule(l) {
+		if r.Date.After(today) {
+			break
+		}
+		due = append(due, r)
+	}
+	return due
+}
+
+// Withheld returns how much of an approved earning goes to a loan repaid by
+// withholding: percent of the earning, rounded half up to the cent, but no
+// more than the part of the earning still left or what is outstanding
+func Withheld(earning, percent, left, outstanding float64) float64 {
+	if earning <= 0 || percent <= 0 {
+		return 0
+	}
+	cents := divRound(toCents(earning)*toCents(percent), 100*100)
+	cents = min(cents, toCents(left), toCents(outstanding))
+	if cents <= 0 {
+		return 0
+	}
+	return float64(cents) / 100
+}
+
+// toCents converts an amount to whole cents
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// divRound divides two non-negative numbers, rounding half up
+func divRound(n, d int64) int64 {
+	return (2*n + d) / (2 * d)
+}
@@ -0,0 +1,77 @@
+package loans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func weeklyLoan(weekly, outstanding float64, next string) *models.Loan {
+	n := day(next)
+	return &models.Loan{
+		RepaymentPlan: models.RepayWeekly,
+		WeeklyAmount:  &weekly,
+		NextRepayment: &n,
+		Outstanding:   outstanding,
+	}
+}
+
+func TestTotalDue(t *testing.T) {
+	assert.Equal(t, 20.0, TotalDue(20, 0))
+	assert.Equal(t, 22.0, TotalDue(20, 10))
+	// 3.33 × 12.5% = 0.41625
+	assert.Equal(t, 3.75, TotalDue(3.33, 12.5))
+	// Exactly half a cent rounds up: 0.10 × 5% = 0.005
+	assert.Equal(t, 0.11, TotalDue(0.1, 5))
+}
+
+func TestSchedule(t *testing.T) {
+	l := weeklyLoan(5, 12.5, "2026-09-07")
+	assert.Equal(t, []Repayment{
+		{Date: day("2026-09-07"), Amount: 5},
+		{Date: day("2026-09-14"), Amount: 5},
+		{Date: day("2026-09-21"), Amount: 2.5},
+	}, Schedule(l))
+
+	l.Outstanding = 0
+	assert.Empty(t, Schedule(l))
+
+	percent := 25.0
+	assert.Empty(t, Schedule(&models.Loan{RepaymentPlan: models.RepayWithhold, WithholdPercent: &percent, Outstanding: 10}))
+}
+
+func TestDue(t *testing.T) {
+	l := weeklyLoan(5, 12.5, "2026-09-07")
+
+	assert.Empty(t, Due(l, day("2026-09-06").Add(23*time.Hour)))
+	assert.Equal(t, []Repayment{{Date: day("2026-09-07"), Amount: 5}}, Due(l, day("2026-09-07")))
+
+	// Missed weeks are caught up, but never past what is owed
+	assert.Len(t, Due(l, day("2026-09-15")), 2)
+	assert.Len(t, Due(l, day("2026-12-01")), 3)
+}
+
+func TestWithheld(t *testing.T) {
+	assert.Equal(t, 2.5, Withheld(10, 25, 10, 100))
+	// 3.33 × 25% = 0.8325
+	assert.Equal(t, 0.83, Withheld(3.33, 25, 3.33, 100))
+
+	// Capped by what is owed and by what is left of the earning
+	assert.Equal(t, 1.2, Withheld(10, 50, 10, 1.2))
+	assert.Equal(t, 3.0, Withheld(10, 50, 3, 100))
+
+	assert.Zero(t, Withheld(10, 50, 0, 100))
+	assert.Zero(t, Withheld(10, 50, 10, 0))
+	assert.Zero(t, Withheld(-5, 50, 10, 100))
+}
@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// DigestPeriod is how far back a digest looks
+const DigestPeriod = 7 * 24 * time.Hour
+
+// DigestGroup is one of a user's groups in their digest
+type DigestGroup struct {
+	GroupID uuid.UUID
+	Name    string
+	IsHead  bool
+	Earned  float64 // Approved for the user during the period
+	Pending int     // The user's entries awaiting approval
+	Balance float64
+	// AwaitingApproval and Members are only filled in for heads
+	AwaitingApproval int
+	Members          []*models.Balance
+}
+
+// DigestData is what the digest template is rendered with
+type DigestData struct {
+	Name           string
+	From           time.Time
+	To             time.Time
+	Groups         []DigestGroup
+	UnsubscribeURL string
+}
+
+// DigestStore looks up digest recipients and their activity
+type DigestStore interface {
+	// DigestRecipients returns the users who have not opted out of the digest
+	DigestRecipients(ctx context.Context) ([]Recipient, error)
+	// DigestGroups returns a user's groups with their earnings between from
+	// and to and pending counts; balances are filled in separately
+	DigestGroups(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]DigestGroup, error)
+}
+
+// BalanceStore calculates group balances
+type BalanceStore interface {
+	GetBalanceForGroup(ctx context.Context, groupID uuid.UUID, asOf *time.Time) ([]*models.Balance, error)
+}
+
+// Digest sends the weekly digest
+type Digest struct {
+	store     DigestStore
+	balances  BalanceStore
+	mailer    Mailer
+	templates *Templates
+	links     *Links
+}
+
+// NewDigest creates a Digest
+func NewDigest(store DigestStore, balances BalanceStore, mailer Mailer, templates *Templates, links *Links) *Digest {
+	return &Digest{store: store, balances: balances, mailer: mailer, templates: templates, links: links}
+}
+
+// Send emails every recipient a digest of the period ending at now and
+// returns how many were sent. A failure for one user does not stop the rest.
+func (d *Digest) Send(ctx context.Context, now time.Time) (int, error) {
+	recipients, err := d.store.DigestRecipients(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	from := now.Add(-DigestPeriod)
+	balances := map[uuid.UUID][]*models.Balance{}
+	var sent, failed int
+	for _, r := range recipients {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		if !r.Digest || r.Email == "" {
+			continue
+		}
+		ok, err := d.sendOne(ctx, r, from, now, balances)
+		if err != nil {
+			log.Printf("mail: failed to send digest to %s: %v", r.UserID, err)
+			failed++
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("%d digests failed", failed)
+	}
+	return sent, nil
+}
+
+// sendOne sends one user's digest, caching group balances across users.
+// Users without groups get no digest.
+func (d *Digest) sendOne(ctx context.Context, r Recipient, from, to time.Time, balances map[uuid.UUID][]*models.Balance) (bool, error) {
+	groups, err := d.store.DigestGroups(ctx, r.UserID, from, to)
+	if err != nil {
+		return false, err
+	}
+	if len(groups) == 0 {
+		return false, nil
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		members, ok := balances[g.GroupID]
+		if !ok {
+			if members, err = d.balances.GetBalanceForGroup(ctx, g.GroupID, nil); err != nil {
+				return false, err
+			}
+			balances[g.GroupID] = members
+		}
+		for _, m := range members {
+			if m.UserID == r.UserID {
+				g.Balance = m.Balance
+			}
+		}
+		if g.IsHead {
+			g.Members = members
+		}
+	}
+
+	unsubscribe := d.links.Unsubscribe(r.UserID, ListDigest)
+	msg, err := d.templates.Render(r.Locale, "digest", DigestData{
+		Name:           r.Name,
+		From:           from,
+		To:             to,
+		Groups:         groups,
+		UnsubscribeURL: unsubscribe,
+	})
+	if err != nil {
+		return false, err
+	}
+	msg.To = r.Email
+	msg.Unsubscribe = unsubscribe
+	if err := d.mailer.Send(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
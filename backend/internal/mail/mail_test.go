@@ -0,0 +1,436 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/push"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+)
+
+// captureServer is a minimal local SMTP server that keeps what it receives
+type captureServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	received []captured
+}
+
+type captured struct {
+	from, to string
+	data     []byte
+}
+
+func newCaptureServer(t *testing.T) *captureServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &captureServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *captureServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 capture ESMTP")
+	var msg captured
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 capture")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = strings.Trim(strings.TrimSpace(line)[10:], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = strings.Trim(strings.TrimSpace(line)[8:], "<>")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = []byte(data.String())
+			s.mu.Lock()
+			s.received = append(s.received, msg)
+			s.mu.Unlock()
+			msg = captured{}
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *captureServer) messages() []captured {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]captured(nil), s.received...)
+}
+
+func TestSMTPMailerAgainstCaptureServer(t *testing.T) {
+	server := newCaptureServer(t)
+	host, port, err := net.SplitHostPort(server.ln.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: portNum, From: "Pocket Money <noreply@example.com>"})
+	err = mailer.Send(context.Background(), Message{
+		To:          "kid@example.com",
+		Subject:     "Dishes was approved ✓",
+		Text:        "Dishes (2.00) was approved.\n",
+		HTML:        "<p>Dishes (2.00) was approved.</p>",
+		Unsubscribe: "https://api.example.com/api/v1/email/unsubscribe?token=abc",
+	})
+	require.NoError(t, err)
+
+	received := server.messages()
+	require.Len(t, received, 1)
+	assert.Equal(t, "noreply@example.com", received[0].from)
+	assert.Equal(t, "kid@example.com", received[0].to)
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(received[0].data)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Dishes was approved ✓", subject)
+	assert.Equal(t, "<https://api.example.com/api/v1/email/unsubscribe?token=abc>", parsed.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", parsed.Header.Get("List-Unsubscribe-Post"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(quotedprintable.NewReader(p))
+		require.NoError(t, err)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=utf-8: Dishes (2.00) was approved.\r\n",
+		"text/html; charset=utf-8: <p>Dishes (2.00) was approved.</p>",
+	}, parts)
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en"}, templates.Locales())
+
+	data := NotificationData{Name: "Parent", Group: "Family", Member: "Kid", Chore: "Dishes", Amount: 2, UnsubscribeURL: "https://x/unsub?a=1&b=2"}
+	for _, e := range push.EventTypes {
+		msg, err := templates.Render("en", strings.ReplaceAll(string(e), ".", "_"), data)
+		require.NoError(t, err, e)
+		assert.NotEmpty(t, msg.Subject, e)
+		assert.NotContains(t, msg.Subject, "\n", e)
+		assert.Contains(t, msg.Text, "https://x/unsub?a=1&b=2", e)
+		assert.Contains(t, msg.HTML, `href="https://x/unsub?a=1&amp;b=2"`, e)
+	}
+
+	// Unknown locales fall back to English, and HTML is escaped
+	data.Chore = "<b>Dishes</b>"
+	msg, err := templates.Render("xx", "ledger_created", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Kid logged <b>Dishes</b> in Family", msg.Subject)
+	assert.Contains(t, msg.HTML, "&lt;b&gt;Dishes&lt;/b&gt;")
+
+	// A deleted chore has no name
+	data.Chore = ""
+	msg, err = templates.Render("en", "ledger_approved", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Your chore was approved", msg.Subject)
+
+	// The reason for an automatic decision is shown
+	data.Reason = "Rejected automatically: pending for more than 7 days"
+	msg, err = templates.Render("en", "ledger_rejected", data)
+	require.NoError(t, err)
+	assert.Contains(t, msg.Text, data.Reason)
+	assert.Contains(t, msg.HTML, "<p>"+data.Reason+"</p>")
+
+	_, err = templates.Render("en", "missing", data)
+	assert.Error(t, err)
+}
+
+func TestTemplatesLocaleFallback(t *testing.T) {
+	files := fstest.MapFS{
+		"en/_footer.txt":  {Data: []byte(`{{define "footer"}}--{{end}}`)},
+		"en/_layout.html": {Data: []byte(`{{template "content" .}}`)},
+		"en/hello.txt":    {Data: []byte(`{{define "subject"}}Hello{{end}}Hello {{.}}`)},
+		"en/hello.html":   {Data: []byte(`{{define "content"}}<p>Hello {{.}}</p>{{end}}`)},
+		"en/bye.txt":      {Data: []byte(`{{define "subject"}}Bye{{end}}Bye {{.}}`)},
+		"en/bye.html":     {Data: []byte(`{{define "content"}}<p>Bye {{.}}</p>{{end}}`)},
+		"es/_footer.txt":  {Data: []byte(`{{define "footer"}}--{{end}}`)},
+		"es/_layout.html": {Data: []byte(`{{template "content" .}}`)},
+		"es/hello.txt":    {Data: []byte(`{{define "subject"}}Hola{{end}}Hola {{.}}`)},
+		"es/hello.html":   {Data: []byte(`{{define "content"}}<p>Hola {{.}}</p>{{end}}`)},
+		"fr/broken.txt":   {Data: []byte(`no subject`)},
+		"fr/_footer.txt":  {Data: []byte(``)},
+		"fr/_layout.html": {Data: []byte(``)},
+		"fr/broken.html":  {Data: []byte(``)},
+	}
+
+	_, err := ParseTemplates(files)
+	assert.ErrorContains(t, err, "subject")
+
+	delete(files, "fr/broken.txt")
+	templates, err := ParseTemplates(files)
+	require.NoError(t, err)
+	assert.True(t, templates.HasLocale("es"))
+
+	msg, err := templates.Render("es", "hello", "Ana")
+	require.NoError(t, err)
+	assert.Equal(t, Message{Subject: "Hola", Text: "Hola Ana\n", HTML: "<p>Hola Ana</p>"}, msg)
+
+	// Emails missing from a locale are sent in English
+	msg, err = templates.Render("es", "bye", "Ana")
+	require.NoError(t, err)
+	assert.Equal(t, "Bye", msg.Subject)
+}
+
+func TestUnsubscribeTokens(t *testing.T) {
+	links := NewLinks("https://api.example.com/", "secret")
+	userID := uuid.New()
+
+	u := links.Unsubscribe(userID, ListDigest)
+	assert.True(t, strings.HasPrefix(u, "https://api.example.com/api/v1/email/unsubscribe?token="))
+
+	token := links.Token(userID, ListDigest)
+	gotUser, gotList, err := links.ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, gotUser)
+	assert.Equal(t, ListDigest, gotList)
+
+	// Another key, a swapped list and garbage are all rejected
+	_, _, err = NewLinks("https://api.example.com", "other").ParseToken(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	payload, _, _ := strings.Cut(links.Token(userID, ListNotifications), ".")
+	_, sig, _ := strings.Cut(token, ".")
+	_, _, err = links.ParseToken(payload + "." + sig)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	for _, bad := range []string{"", "abc", "a.b", "!!.!!"} {
+		_, _, err = links.ParseToken(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+}
+
+// fakeStore serves fixed recipients and activity
+type fakeStore struct {
+	heads      []uuid.UUID
+	recipients map[uuid.UUID]Recipient
+	groups     map[uuid.UUID][]DigestGroup
+	balances   []*models.Balance
+}
+
+func (s *fakeStore) Heads(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	return s.heads, nil
+}
+
+func (s *fakeStore) Labels(ctx context.Context, groupID, userID uuid.UUID, choreID *uuid.UUID) (push.Labels, error) {
+	return push.Labels{GroupName: "Family", UserName: "Kid", ChoreName: "Dishes"}, nil
+}
+
+func (s *fakeStore) MailRecipients(ctx context.Context, userIDs []uuid.UUID) ([]Recipient, error) {
+	var result []Recipient
+	for _, id := range userIDs {
+		if r, ok := s.recipients[id]; ok {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) DigestRecipients(ctx context.Context) ([]Recipient, error) {
+	var result []Recipient
+	for _, r := range s.recipients {
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s *fakeStore) DigestGroups(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]DigestGroup, error) {
+	return append([]DigestGroup(nil), s.groups[userID]...), nil
+}
+
+func (s *fakeStore) GetBalanceForGroup(ctx context.Context, groupID uuid.UUID, asOf *time.Time) ([]*models.Balance, error) {
+	return s.balances, nil
+}
+
+func TestNotifier(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	head, kid, optedOut := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+
+	store := &fakeStore{
+		heads: []uuid.UUID{head, optedOut},
+		recipients: map[uuid.UUID]Recipient{
+			head:     {UserID: head, Email: "parent@example.com", Name: "Parent", Locale: "en", Notifications: true},
+			kid:      {UserID: kid, Email: "kid@example.com", Name: "Kid", Locale: "en", Notifications: true},
+			optedOut: {UserID: optedOut, Email: "other@example.com", Name: "Other", Locale: "en", Notifications: false},
+		},
+	}
+	mailer := NewLocalMailer()
+	n := NewNotifier(store, mailer, templates, NewLinks("https://api.example.com", "secret"))
+
+	entry := map[string]any{"id": uuid.New(), "user_id": kid, "amount": 2, "status": models.StatusPendingApproval}
+	require.NoError(t, n.Handle(context.Background(), events.New(events.LedgerCreated, groupID, &kid, entry)))
+	sent := mailer.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "parent@example.com", sent[0].To)
+	assert.Equal(t, "Kid logged Dishes in Family", sent[0].Subject)
+	assert.Contains(t, sent[0].Unsubscribe, "/api/v1/email/unsubscribe?token=")
+
+	// The member hears about approvals unless they turned them off
+	require.NoError(t, n.Handle(context.Background(), events.New(events.LedgerApproved, groupID, &head, entry)))
+	require.Len(t, mailer.Sent(), 2)
+	assert.Equal(t, "kid@example.com", mailer.Sent()[1].To)
+
+	r := store.recipients[kid]
+	r.Preferences = []push.Preference{{EventType: events.LedgerApproved, Enabled: false}}
+	store.recipients[kid] = r
+	require.NoError(t, n.Handle(context.Background(), events.New(events.LedgerApproved, groupID, &head, entry)))
+	assert.Len(t, mailer.Sent(), 2)
+}
+
+func TestNotifierRemindPending(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	head := uuid.New()
+	store := &fakeStore{recipients: map[uuid.UUID]Recipient{
+		head: {UserID: head, Email: "parent@example.com", Name: "Parent", Locale: "en", Notifications: true},
+	}}
+	mailer := NewLocalMailer()
+	n := NewNotifier(store, mailer, templates, NewLinks("https://api.example.com", "secret"))
+
+	since := time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	require.NoError(t, n.RemindPending(context.Background(), []reminders.Pending{
+		{UserID: head, GroupID: uuid.New(), GroupName: "Family", Count: 3, Since: since},
+	}))
+
+	sent := mailer.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "3 entries are waiting for your approval in Family", sent[0].Subject)
+	assert.Contains(t, sent[0].Text, "since Fri 16 Oct")
+}
+
+func TestNotifierRemindChores(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	kid, quiet := uuid.New(), uuid.New()
+	store := &fakeStore{recipients: map[uuid.UUID]Recipient{
+		kid:   {UserID: kid, Email: "kid@example.com", Name: "Kid", Locale: "en", Notifications: true},
+		quiet: {UserID: quiet, Email: "quiet@example.com", Name: "Quiet", Locale: "en", Notifications: false},
+	}}
+	mailer := NewLocalMailer()
+	n := NewNotifier(store, mailer, templates, NewLinks("https://api.example.com", "secret"))
+
+	dueAt := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+	due := []reminders.Chore{
+		{UserID: kid, GroupID: uuid.New(), GroupName: "Family", ChoreName: "Dishes", DueAt: dueAt, Timezone: "America/New_York"},
+		{UserID: quiet, GroupID: uuid.New(), GroupName: "Family", ChoreName: "Lawn", DueAt: dueAt},
+	}
+	require.NoError(t, n.RemindChoreDue(context.Background(), due))
+	require.NoError(t, n.RemindChoreOverdue(context.Background(), due))
+
+	sent := mailer.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "Dishes is due soon in Family", sent[0].Subject)
+	assert.Contains(t, sent[0].Text, "is due Sat 17 Oct 14:00")
+	assert.Equal(t, "Dishes is overdue in Family", sent[1].Subject)
+	assert.Contains(t, sent[1].HTML, "was due Sat 17 Oct 14:00")
+}
+
+func TestDigest(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+	head, kid, noGroups := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+
+	store := &fakeStore{
+		recipients: map[uuid.UUID]Recipient{
+			head:     {UserID: head, Email: "parent@example.com", Name: "Parent", Locale: "en", Digest: true},
+			kid:      {UserID: kid, Email: "kid@example.com", Name: "Kid", Locale: "en", Digest: true},
+			noGroups: {UserID: noGroups, Email: "new@example.com", Name: "New", Locale: "en", Digest: true},
+		},
+		groups: map[uuid.UUID][]DigestGroup{
+			head: {{GroupID: groupID, Name: "Family", IsHead: true, AwaitingApproval: 3}},
+			kid:  {{GroupID: groupID, Name: "Family", Earned: 6.5, Pending: 3}},
+		},
+		balances: []*models.Balance{
+			{UserID: kid, Name: "Kid", Balance: 12.25},
+			{UserID: head, Name: "Parent", Balance: 0},
+		},
+	}
+	mailer := NewLocalMailer()
+	d := NewDigest(store, store, mailer, templates, NewLinks("https://api.example.com", "secret"))
+
+	sent, err := d.Send(context.Background(), time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	byTo := map[string]Message{}
+	for _, m := range mailer.Sent() {
+		byTo[m.To] = m
+	}
+	kidMail := byTo["kid@example.com"]
+	assert.Contains(t, kidMail.Text, "from 12 Oct to 19 Oct 2026")
+	assert.Contains(t, kidMail.Text, "Earned this week: 6.50")
+	assert.Contains(t, kidMail.Text, "Waiting for approval: 3")
+	assert.Contains(t, kidMail.Text, "Balance: 12.25")
+	assert.NotContains(t, kidMail.Text, "Entries for you to approve")
+
+	headMail := byTo["parent@example.com"]
+	assert.Contains(t, headMail.Text, "Entries for you to approve: 3")
+	assert.Contains(t, headMail.Text, "Kid: 12.25")
+	assert.Contains(t, headMail.Unsubscribe, "token=")
+}
@@ -0,0 +1,267 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/push"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+)
+
+// Recipient is a user who may be emailed, with their settings
+type Recipient struct {
+	UserID uuid.UUID
+	Email  string
+	Name   string
+	Locale string
+	// Notifications and Digest are false once the user opts out of them
+	Notifications bool
+	Digest        bool
+	Preferences   []push.Preference
+}
+
+// Store looks up who to email
+type Store interface {
+	push.HeadLister
+	// Labels returns the names of a group, a user and optionally a chore
+	Labels(ctx context.Context, groupID, userID uuid.UUID, choreID *uuid.UUID) (push.Labels, error)
+	// MailRecipients returns the users' email settings
+	MailRecipients(ctx context.Context, userIDs []uuid.UUID) ([]Recipient, error)
+}
+
+// NotificationData is what notification templates are rendered with
+type NotificationData struct {
+	Name           string // The recipient
+	Group          string
+	Member         string // Who the entry or settlement is for
+	Chore          string
+	Amount         float64
+	Reason         string // Why the entry's status changed, or the settlement is disputed, if given
+	UnsubscribeURL string
+}
+
+// Notifier emails the same events as push notifications, with the same
+// audience and preferences. Email has no quiet hours.
+type Notifier struct {
+	store     Store
+	mailer    Mailer
+	templates *Templates
+	links     *Links
+}
+
+// NewNotifier creates a Notifier
+func NewNotifier(store Store, mailer Mailer, templates *Templates, links *Links) *Notifier {
+	return &Notifier{store: store, mailer: mailer, templates: templates, links: links}
+}
+
+// Subscriber returns an events subscriber that emails in the background,
+// so requests do not wait on the SMTP server
+func (n *Notifier) Subscriber() events.Subscriber {
+	return func(ctx context.Context, e events.Event) error {
+		if !push.Notifies(e.Type) {
+			return nil
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+			defer cancel()
+			if err := n.Handle(ctx, e); err != nil {
+				log.Printf("mail: failed to notify %s %s: %v", e.Type, e.ID, err)
+			}
+		}()
+		return nil
+	}
+}
+
+// Handle sends the emails for one event
+func (n *Notifier) Handle(ctx context.Context, e events.Event) error {
+	res, err := push.DecodeResource(e)
+	if err != nil {
+		return err
+	}
+	userIDs, err := push.Audience(ctx, n.store, e, res)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	labels, err := n.store.Labels(ctx, e.GroupID, res.UserID, res.ChoreID)
+	if err != nil {
+		return err
+	}
+	recipients, err := n.store.MailRecipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	name := strings.ReplaceAll(string(e.Type), ".", "_")
+	var failed int
+	for _, r := range recipients {
+		if !r.Notifications || r.Email == "" || !push.Enabled(r.Preferences, e.GroupID, e.Type) {
+			continue
+		}
+		unsubscribe := n.links.Unsubscribe(r.UserID, ListNotifications)
+		msg, err := n.templates.Render(r.Locale, name, NotificationData{
+			Name:           r.Name,
+			Group:          labels.GroupName,
+			Member:         labels.UserName,
+			Chore:          labels.ChoreName,
+			Amount:         res.Amount,
+			Reason:         reason(res),
+			UnsubscribeURL: unsubscribe,
+		})
+		if err != nil {
+			return err
+		}
+		msg.To = r.Email
+		msg.Unsubscribe = unsubscribe
+		if err := n.mailer.Send(ctx, msg); err != nil {
+			log.Printf("mail: failed to send %s to %s: %v", e.Type, r.UserID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d emails failed", failed, len(recipients))
+	}
+	return nil
+}
+
+// PendingReminderData is what the pending reminder template is rendered with
+type PendingReminderData struct {
+	Name           string
+	Group          string
+	Count          int
+	Since          time.Time
+	UnsubscribeURL string
+}
+
+// RemindPending emails heads about entries that have been waiting for their
+// approval, unless they turned off notifications for new entries
+func (n *Notifier) RemindPending(ctx context.Context, due []reminders.Pending) error {
+	userIDs := make([]uuid.UUID, 0, len(due))
+	for _, p := range due {
+		userIDs = append(userIDs, p.UserID)
+	}
+	recipients, err := n.store.MailRecipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[uuid.UUID]Recipient, len(recipients))
+	for _, r := range recipients {
+		byUser[r.UserID] = r
+	}
+
+	var failed int
+	for _, p := range due {
+		r, ok := byUser[p.UserID]
+		if !ok || !r.Notifications || r.Email == "" || !push.Enabled(r.Preferences, p.GroupID, events.LedgerCreated) {
+			continue
+		}
+		unsubscribe := n.links.Unsubscribe(r.UserID, ListNotifications)
+		msg, err := n.templates.Render(r.Locale, "pending_reminder", PendingReminderData{
+			Name:           r.Name,
+			Group:          p.GroupName,
+			Count:          p.Count,
+			Since:          p.Since,
+			UnsubscribeURL: unsubscribe,
+		})
+		if err != nil {
+			return err
+		}
+		msg.To = r.Email
+		msg.Unsubscribe = unsubscribe
+		if err := n.mailer.Send(ctx, msg); err != nil {
+			log.Printf("mail: failed to send reminder to %s: %v", r.UserID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d reminder emails failed", failed)
+	}
+	return nil
+}
+
+// ChoreReminderData is what the chore reminder template is rendered with
+type ChoreReminderData struct {
+	Name           string
+	Group          string
+	Chore          string
+	DueAt          time.Time // In the recipient's timezone
+	Overdue        bool
+	UnsubscribeURL string
+}
+
+// RemindChoreDue emails members about chores assigned to them that are due
+// soon, unless they turned off notifications for chore changes
+func (n *Notifier) RemindChoreDue(ctx context.Context, due []reminders.Chore) error {
+	return n.remindChores(ctx, due, false)
+}
+
+// RemindChoreOverdue emails members about chores assigned to them whose due
+// time has passed, unless they turned off notifications for chore changes
+func (n *Notifier) RemindChoreOverdue(ctx context.Context, due []reminders.Chore) error {
+	return n.remindChores(ctx, due, true)
+}
+
+func (n *Notifier) remindChores(ctx context.Context, due []reminders.Chore, overdue bool) error {
+	userIDs := make([]uuid.UUID, 0, len(due))
+	for _, c := range due {
+		userIDs = append(userIDs, c.UserID)
+	}
+	recipients, err := n.store.MailRecipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[uuid.UUID]Recipient, len(recipients))
+	for _, r := range recipients {
+		byUser[r.UserID] = r
+	}
+
+	var failed int
+	for _, c := range due {
+		r, ok := byUser[c.UserID]
+		if !ok || !r.Notifications || r.Email == "" || !push.Enabled(r.Preferences, c.GroupID, events.ChoreUpdated) {
+			continue
+		}
+		unsubscribe := n.links.Unsubscribe(r.UserID, ListNotifications)
+		msg, err := n.templates.Render(r.Locale, "chore_reminder", ChoreReminderData{
+			Name:           r.Name,
+			Group:          c.GroupName,
+			Chore:          c.ChoreName,
+			DueAt:          c.LocalDueAt(),
+			Overdue:        overdue,
+			UnsubscribeURL: unsubscribe,
+		})
+		if err != nil {
+			return err
+		}
+		msg.To = r.Email
+		msg.Unsubscribe = unsubscribe
+		if err := n.mailer.Send(ctx, msg); err != nil {
+			log.Printf("mail: failed to send chore reminder to %s: %v", r.UserID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d chore reminder emails failed", failed)
+	}
+	return nil
+}
+
+// reason returns the status or dispute reason of an event's resource, if any
+func reason(res push.Resource) string {
+	switch {
+	case res.Reason != nil:
+		return *res.Reason
+	case res.Dispute != nil:
+		return *res.Dispute
+	}
+	return ""
+}
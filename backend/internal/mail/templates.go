@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used for users whose locale has no templates
+const DefaultLocale = "en"
+
+// templateFS holds one directory of templates per locale. Each email has
+// NAME.txt, which also defines "subject", and NAME.html. Files starting with
+// an underscore are shared: _footer.txt and _layout.html, which wraps the
+// "content" of each HTML template.
+//
+//go:embed templates/*/*
+var templateFS embed.FS
+
+var templateFuncs = map[string]any{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+}
+
+// Templates renders emails in the user's locale
+type Templates struct {
+	text map[string]map[string]*texttemplate.Template
+	html map[string]map[string]*htmltemplate.Template
+}
+
+// LoadTemplates parses the embedded templates
+func LoadTemplates() (*Templates, error) {
+	root, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplates(root)
+}
+
+// ParseTemplates parses templates from a directory per locale
+func ParseTemplates(root fs.FS) (*Templates, error) {
+	t := &Templates{
+		text: map[string]map[string]*texttemplate.Template{},
+		html: map[string]map[string]*htmltemplate.Template{},
+	}
+
+	locales, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+	for _, l := range locales {
+		if !l.IsDir() {
+			continue
+		}
+		locale := l.Name()
+		names, err := fs.Glob(root, locale+"/*.txt")
+		if err != nil {
+			return nil, err
+		}
+		t.text[locale] = map[string]*texttemplate.Template{}
+		t.html[locale] = map[string]*htmltemplate.Template{}
+		for _, file := range names {
+			name := strings.TrimSuffix(path.Base(file), ".txt")
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			text, err := texttemplate.New(name+".txt").Funcs(templateFuncs).
+				ParseFS(root, file, locale+"/_footer.txt")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s does not define a subject", file)
+			}
+			html, err := htmltemplate.New("_layout.html").Funcs(templateFuncs).
+				ParseFS(root, locale+"/_layout.html", locale+"/"+name+".html")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.html: %w", locale, name, err)
+			}
+			t.text[locale][name] = text
+			t.html[locale][name] = html
+		}
+	}
+	if len(t.text[DefaultLocale]) == 0 {
+		return nil, fmt.Errorf("no templates for default locale %q", DefaultLocale)
+	}
+	return t, nil
+}
+
+// Locales returns the locales that have templates
+func (t *Templates) Locales() []string {
+	locales := make([]string, 0, len(t.text))
+	for l := range t.text {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// HasLocale reports whether there are templates for a locale
+func (t *Templates) HasLocale(locale string) bool {
+	_, ok := t.text[locale]
+	return ok
+}
+
+// Render renders the subject, text and HTML of an email, falling back to
+// DefaultLocale for unknown locales and for emails a locale lacks
+func (t *Templates) Render(locale, name string, data any) (Message, error) {
+	text, ok := t.text[locale][name]
+	if !ok {
+		locale = DefaultLocale
+		if text, ok = t.text[locale][name]; !ok {
+			return Message{}, fmt.Errorf("unknown email template %q", name)
+		}
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	if err := t.html[locale][name].Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s HTML: %w", name, err)
+	}
+
+	return Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
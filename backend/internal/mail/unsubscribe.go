@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// List is a kind of email a user can opt out of
+type List string
+
+const (
+	ListNotifications List = "notifications"
+	ListDigest        List = "digest"
+)
+
+// Valid reports whether l is a known list
+func (l List) Valid() bool {
+	return l == ListNotifications || l == ListDigest
+}
+
+// ErrInvalidToken is returned for unsubscribe tokens that were not issued by
+// this server
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// Links builds the opt-out links put in emails. Tokens are signed rather
+// than stored, so a link keeps working for as long as the key does not change.
+type Links struct {
+	baseURL string
+	key     []byte
+}
+
+// NewLinks creates Links for the public base URL of the API. The key is
+// derived from secret, so the JWT secret can be reused.
+func NewLinks(baseURL, secret string) *Links {
+	key := sha256.Sum256([]byte("pocket-money unsubscribe:" + secret))
+	return &Links{baseURL: strings.TrimSuffix(baseURL, "/"), key: key[:]}
+}
+
+// Unsubscribe returns the opt-out URL for one user and list
+func (l *Links) Unsubscribe(userID uuid.UUID, list List) string {
+	return l.baseURL + "/api/v1/email/unsubscribe?token=" + url.QueryEscape(l.Token(userID, list))
+}
+
+// Token returns a signed token naming a user and list
+func (l *Links) Token(userID uuid.UUID, list List) string {
+	payload := userID.String() + ":" + string(list)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(l.sign(payload))
+}
+
+// ParseToken verifies a token and returns its user and list
+func (l *Links) ParseToken(token string) (uuid.UUID, List, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, l.sign(string(payload))) {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+
+	id, list, ok := strings.Cut(string(payload), ":")
+	if !ok || !List(list).Valid() {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	return userID, List(list), nil
+}
+
+func (l *Links) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, l.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)[:16]
+}
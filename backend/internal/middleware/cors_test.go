@@ -72,6 +72,23 @@ func TestCORSMiddleware_OptionsPreflightReturns204(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 }
 
+func TestCORSMiddleware_PreflightAllowsPut(t *testing.T) {
+	router := setupCORSTestRouter("http://localhost:8081")
+
+	// Browsers preflight the PUT that uploads a goal's image
+	req, _ := http.NewRequest(http.MethodOptions, "/api/v1/groups/g/members/u/goals/1/image", nil)
+	req.Header.Set("Origin", "http://localhost:8081")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "http://localhost:8081", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodPut)
+}
+
 func TestCORSMiddleware_AllowedHeaders(t *testing.T) {
 	router := setupCORSTestRouter("*")
 
@@ -83,10 +100,12 @@ func TestCORSMiddleware_AllowedHeaders(t *testing.T) {
 
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "GET")
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "POST")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PUT")
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PATCH")
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "DELETE")
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
 	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Last-Event-ID")
 }
 
 func TestParseOrigins(t *testing.T) {
@@ -23,6 +23,14 @@ const (
 	StatusRejected        LedgerStatus = "rejected"
 )
 
+// SystemActor names the system process that changed an entry's status
+type SystemActor string
+
+const (
+	SystemAutoExpire  SystemActor = "auto_expire"
+	SystemAutoApprove SystemActor = "auto_approve"
+)
+
 // User represents a user in the system
 type User struct {
 	ID           uuid.UUID  `json:"id"`
@@ -36,10 +44,21 @@ type User struct {
 
 // Group represents a family or group
 type Group struct {
-	ID         uuid.UUID `json:"id"`
-	Name       string    `json:"name"`
-	HeadUserID uuid.UUID `json:"head_user_id"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	HeadUserID      uuid.UUID `json:"head_user_id"`
+	MaxBackdateDays *int      `json:"max_backdate_days"` // Backdating limit for members; nil means no limit
+	PendingPolicy
+	RequireRejectReason bool      `json:"require_reject_reason"` // Heads must say why they reject an entry
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// PendingPolicy settles entries that no head approved or rejected in time.
+// Nil fields turn the policy off.
+type PendingPolicy struct {
+	ExpirePendingAfterDays *int     `json:"expire_pending_after_days"` // Reject entries pending this long
+	AutoApproveAfterHours  *int     `json:"auto_approve_after_hours"`  // Approve entries pending this long...
+	AutoApproveMaxAmount   *float64 `json:"auto_approve_max_amount"`   // ...if their amount is at most this
 }
 
 // GroupMember represents a user's membership in a group
@@ -52,37 +71,315 @@ type GroupMember struct {
 
 // Chore represents a task/chore that can be completed for money
 type Chore struct {
-	ID          uuid.UUID `json:"id"`
-	GroupID     uuid.UUID `json:"group_id"`
-	Name        string    `json:"name"`
-	Description *string   `json:"description,omitempty"`
-	Amount      float64   `json:"amount"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            uuid.UUID `json:"id"`
+	GroupID       uuid.UUID `json:"group_id"`
+	Name          string    `json:"name"`
+	Description   *string   `json:"description,omitempty"`
+	Amount        float64   `json:"amount"`
+	ProofRequired bool      `json:"proof_required"` // Entries need a photo before a head approves them
+	// AssigneeUserID is reminded before DueAt and nudged once it has passed,
+	// until they log the chore; AssignedAt is when either last changed
+	AssigneeUserID *uuid.UUID `json:"assignee_user_id,omitempty"`
+	DueAt          *time.Time `json:"due_at,omitempty"`
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"` // Archived chores are kept so their entries can still name them
 }
 
-// LedgerEntry represents a record of a completed chore
+// EntryKind is what a ledger entry credits a member for
+type EntryKind string
+
+const (
+	EntryChore     EntryKind = "chore"
+	EntryAllowance EntryKind = "allowance"
+	EntryInterest  EntryKind = "interest"
+	EntryLoan      EntryKind = "loan"           // An advance paid out on a loan
+	EntryRepayment EntryKind = "loan_repayment" // A negative entry paying a loan back
+)
+
+// LedgerEntry represents a record of a completed chore or another credit,
+// such as an allowance payment, or a loan repayment taken from the balance
 type LedgerEntry struct {
-	ID               uuid.UUID    `json:"id"`
-	GroupID          uuid.UUID    `json:"group_id"`
-	UserID           uuid.UUID    `json:"user_id"`
-	ChoreID          uuid.UUID    `json:"chore_id"`
-	Amount           float64      `json:"amount"`
-	Status           LedgerStatus `json:"status"`
-	CreatedByUserID  uuid.UUID    `json:"created_by_user_id"`
-	ApprovedByUserID *uuid.UUID   `json:"approved_by_user_id,omitempty"`
-	RejectedByUserID *uuid.UUID   `json:"rejected_by_user_id,omitempty"`
-	CreatedAt        time.Time    `json:"created_at"`
-}
-
-// Settlement represents a cash payout to a member
+	ID                uuid.UUID    `json:"id"`
+	GroupID           uuid.UUID    `json:"group_id"`
+	UserID            uuid.UUID    `json:"user_id"`
+	Kind              EntryKind    `json:"kind"`
+	ChoreID           *uuid.UUID   `json:"chore_id"`                   // Set for chore entries
+	AllowanceID       *uuid.UUID   `json:"allowance_id,omitempty"`     // Set for allowance entries...
+	AllowancePeriod   *time.Time   `json:"allowance_period,omitempty"` // ...with the start of the period they credit
+	InterestRateID    *uuid.UUID   `json:"interest_rate_id,omitempty"` // Set for interest entries
+	LoanID            *uuid.UUID   `json:"loan_id,omitempty"`          // Set for loan and loan repayment entries
+	WithheldFromID    *uuid.UUID   `json:"withheld_from_id,omitempty"` // The earning a withheld repayment was taken from
+	Amount            float64      `json:"amount"`
+	Status            LedgerStatus `json:"status"`
+	CreatedByUserID   uuid.UUID    `json:"created_by_user_id"`
+	ApprovedByUserID  *uuid.UUID   `json:"approved_by_user_id,omitempty"`
+	RejectedByUserID  *uuid.UUID   `json:"rejected_by_user_id,omitempty"`
+	StatusReason      *string      `json:"status_reason,omitempty"`       // Why the status last changed
+	SystemActor       *SystemActor `json:"system_actor,omitempty"`        // Set when the system, not a head, changed the status
+	ApprovalRuleID    *uuid.UUID   `json:"approval_rule_id,omitempty"`    // Set when a rule, not a head, approved the entry
+	ResubmittedFromID *uuid.UUID   `json:"resubmitted_from_id,omitempty"` // The rejected entry this one resubmits
+	OccurredAt        time.Time    `json:"occurred_at"`                   // When the chore was done; defaults to created_at
+	CreatedAt         time.Time    `json:"created_at"`
+	Hash              *string      `json:"hash,omitempty"` // Latest ledger chain hash
+}
+
+// AllowanceInterval is how often an allowance is paid
+type AllowanceInterval string
+
+const (
+	IntervalWeek  AllowanceInterval = "week"
+	IntervalMonth AllowanceInterval = "month"
+)
+
+// Allowance is a member's recurring pocket money, credited to the ledger
+// at the start of each period from StartDate on
+type Allowance struct {
+	ID              uuid.UUID         `json:"id"`
+	GroupID         uuid.UUID         `json:"group_id"`
+	UserID          uuid.UUID         `json:"user_id"`
+	Amount          float64           `json:"amount"`
+	AmountPerYear   *float64          `json:"amount_per_year,omitempty"` // Paid per year of age instead of Amount when the member's dob is known
+	Interval        AllowanceInterval `json:"interval"`
+	StartDate       time.Time         `json:"start_date"`
+	NextPeriod      time.Time         `json:"next_period"` // Start of the first period not yet credited
+	PausedAt        *time.Time        `json:"paused_at,omitempty"`
+	CreatedByUserID uuid.UUID         `json:"created_by_user_id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// InterestRate pays interest on members' balances, or on one jar, at the
+// end of each compounding period from StartDate on. A rate without a jar
+// covers every member who is not a head, leaving out jars with a rate of their own.
+type InterestRate struct {
+	ID              uuid.UUID         `json:"id"`
+	GroupID         uuid.UUID         `json:"group_id"`
+	JarID           *uuid.UUID        `json:"jar_id,omitempty"`
+	APR             float64           `json:"apr"` // Percent a year
+	Compounding     AllowanceInterval `json:"compounding"`
+	MinBalance      float64           `json:"min_balance"` // Average daily balance below which nothing is paid
+	StartDate       time.Time         `json:"start_date"`
+	NextPeriod      time.Time         `json:"next_period"` // Start of the first period not yet posted
+	CreatedByUserID uuid.UUID         `json:"created_by_user_id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// RepaymentPlan is how a loan is paid back
+type RepaymentPlan string
+
+const (
+	RepayWeekly   RepaymentPlan = "weekly"   // A fixed amount each week
+	RepayWithhold RepaymentPlan = "withhold" // A percentage of each approved earning
+)
+
+// Loan is an advance a head paid a member, with flat interest, that is
+// paid back out of the member's balance by its repayment plan
+type Loan struct {
+	ID              uuid.UUID     `json:"id"`
+	GroupID         uuid.UUID     `json:"group_id"`
+	UserID          uuid.UUID     `json:"user_id"`
+	Principal       float64       `json:"principal"`
+	InterestPercent float64       `json:"interest_percent"` // Flat, on the principal
+	TotalDue        float64       `json:"total_due"`        // Principal plus interest
+	RepaymentPlan   RepaymentPlan `json:"repayment_plan"`
+	WeeklyAmount    *float64      `json:"weekly_amount,omitempty"`    // Set for weekly plans
+	WithholdPercent *float64      `json:"withhold_percent,omitempty"` // Set for withholding plans
+	StartDate       time.Time     `json:"start_date"`
+	NextRepayment   *time.Time    `json:"next_repayment,omitempty"` // Weekly plans: the first repayment not yet taken
+	Note            *string       `json:"note,omitempty"`
+	Outstanding     float64       `json:"outstanding"`
+	RepaidAt        *time.Time    `json:"repaid_at,omitempty"`
+	CreatedByUserID uuid.UUID     `json:"created_by_user_id"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// InterestBreakdown shows how the interest for one period was worked out
+type InterestBreakdown struct {
+	PeriodStart    string    `json:"period_start"` // First day, YYYY-MM-DD
+	PeriodEnd      string    `json:"period_end"`   // Last day, YYYY-MM-DD
+	Days           int       `json:"days"`
+	DailyBalances  []float64 `json:"daily_balances"` // At the end of each day
+	AverageBalance float64   `json:"average_balance"`
+	APR            float64   `json:"apr"`
+	MinBalance     float64   `json:"min_balance"`
+	Amount         float64   `json:"amount"`
+	Explanation    string    `json:"explanation"`
+}
+
+// InterestPosting is the interest entry a rate posted for one member and period
+type InterestPosting struct {
+	ID          uuid.UUID         `json:"id"`
+	RateID      uuid.UUID         `json:"rate_id"`
+	EntryID     uuid.UUID         `json:"entry_id"`
+	GroupID     uuid.UUID         `json:"group_id"`
+	UserID      uuid.UUID         `json:"user_id"`
+	JarID       *uuid.UUID        `json:"jar_id,omitempty"`
+	PeriodStart time.Time         `json:"period_start"`
+	Amount      float64           `json:"amount"`
+	Breakdown   InterestBreakdown `json:"breakdown"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// GoalSource is what counts towards a savings goal
+type GoalSource string
+
+const (
+	GoalFromBalance     GoalSource = "balance"     // The member's whole balance
+	GoalFromAllocations GoalSource = "allocations" // Only money allocated to the goal
+)
+
+// Goal is something a member is saving up for
+type Goal struct {
+	ID               uuid.UUID  `json:"id"`
+	GroupID          uuid.UUID  `json:"group_id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	Name             string     `json:"name"`
+	TargetAmount     float64    `json:"target_amount"`
+	Deadline         *time.Time `json:"deadline,omitempty"`
+	Source           GoalSource `json:"progress_source"`
+	Allocated        float64    `json:"allocated"` // Sum of the goal's allocations
+	ImageContentType *string    `json:"image_content_type,omitempty"`
+	ImageSizeBytes   *int64     `json:"image_size_bytes,omitempty"`
+	ImageStorageKey  *string    `json:"-"`
+	ReachedAt        *time.Time `json:"reached_at,omitempty"` // When the goal was first reached
+	CreatedByUserID  uuid.UUID  `json:"created_by_user_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+// GoalAllocation sets money aside for a goal, or takes it back when negative
+type GoalAllocation struct {
+	ID              uuid.UUID `json:"id"`
+	GoalID          uuid.UUID `json:"goal_id"`
+	Amount          float64   `json:"amount"`
+	Note            *string   `json:"note,omitempty"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SettlementStatus is whether the member confirmed receiving a settlement
+type SettlementStatus string
+
+const (
+	SettlementAwaitingAck  SettlementStatus = "awaiting_ack"
+	SettlementAcknowledged SettlementStatus = "acknowledged"
+	SettlementDisputed     SettlementStatus = "disputed"
+)
+
+// SettlementMethod is how a settlement was paid out
+type SettlementMethod string
+
+const (
+	MethodCash         SettlementMethod = "cash"
+	MethodBankTransfer SettlementMethod = "bank_transfer"
+	MethodInKind       SettlementMethod = "in_kind"
+)
+
+// SettlementMethods lists every settlement method, in display order
+var SettlementMethods = []SettlementMethod{MethodCash, MethodBankTransfer, MethodInKind}
+
+// Settlement represents a payout to a member
 type Settlement struct {
-	ID        uuid.UUID `json:"id"`
-	GroupID   uuid.UUID `json:"group_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Amount    float64   `json:"amount"`
-	Date      time.Time `json:"date"`
-	Note      *string   `json:"note,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                uuid.UUID         `json:"id"`
+	GroupID           uuid.UUID         `json:"group_id"`
+	UserID            uuid.UUID         `json:"user_id"`
+	Amount            float64           `json:"amount"`
+	Date              time.Time         `json:"date"`
+	Note              *string           `json:"note,omitempty"`
+	Method            SettlementMethod  `json:"method"`
+	ExternalReference *string           `json:"external_reference,omitempty"` // E.g. a bank transfer ID
+	CategoryID        *uuid.UUID        `json:"category_id,omitempty"`
+	JarID             *uuid.UUID        `json:"jar_id,omitempty"` // The jar it was paid out of
+	Status            SettlementStatus  `json:"status"`
+	AcknowledgedAt    *time.Time        `json:"acknowledged_at,omitempty"`
+	DisputedAt        *time.Time        `json:"disputed_at,omitempty"`
+	DisputeReason     *string           `json:"dispute_reason,omitempty"` // What the member says is wrong
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         *time.Time        `json:"updated_at,omitempty"` // When a head last edited it
+	VoidedAt          *time.Time        `json:"voided_at,omitempty"`  // Voided settlements no longer count towards balances
+	VoidedByUserID    *uuid.UUID        `json:"voided_by_user_id,omitempty"`
+	VoidReason        *string           `json:"void_reason,omitempty"`
+	Entries           []SettlementEntry `json:"entries,omitempty"` // The approved entries it pays off
+	Hash              *string           `json:"hash,omitempty"`    // Latest ledger chain hash
+}
+
+// SettlementCategory is a group-defined label for what settlements are for
+type SettlementCategory struct {
+	ID        uuid.UUID  `json:"id"`
+	GroupID   uuid.UUID  `json:"group_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// MethodTotal sums settlements paid out one way
+type MethodTotal struct {
+	Method SettlementMethod `json:"method"`
+	Count  int              `json:"count"`
+	Amount float64          `json:"amount"`
+}
+
+// CategoryTotal sums settlements in one category; a nil CategoryID is
+// settlements without a category
+type CategoryTotal struct {
+	CategoryID *uuid.UUID `json:"category_id"`
+	Name       *string    `json:"name"`
+	Count      int        `json:"count"`
+	Amount     float64    `json:"amount"`
+}
+
+// SettlementSummary totals settlements overall, by method and by category
+type SettlementSummary struct {
+	Count      int             `json:"count"`
+	Amount     float64         `json:"amount"`
+	ByMethod   []MethodTotal   `json:"by_method"`
+	ByCategory []CategoryTotal `json:"by_category"`
+}
+
+// SettlementEntry is the part of an approved ledger entry a settlement pays
+type SettlementEntry struct {
+	EntryID uuid.UUID `json:"entry_id"`
+	Amount  float64   `json:"amount"`
+}
+
+// SettlementAction is the kind of change recorded in a settlement revision
+type SettlementAction string
+
+const (
+	SettlementEdited          SettlementAction = "edit"
+	SettlementVoided          SettlementAction = "void"
+	SettlementDisputeRaised   SettlementAction = "dispute"
+	SettlementDisputeResolved SettlementAction = "resolve"
+)
+
+// SettlementRevision records who changed a settlement, why, and what it
+// looked like before and after
+type SettlementRevision struct {
+	ID              uuid.UUID        `json:"id"`
+	SettlementID    uuid.UUID        `json:"settlement_id"`
+	Action          SettlementAction `json:"action"`
+	ChangedByUserID *uuid.UUID       `json:"changed_by_user_id"`
+	Reason          string           `json:"reason"`
+	AmountBefore    float64          `json:"amount_before"`
+	AmountAfter     float64          `json:"amount_after"`
+	DateBefore      time.Time        `json:"date_before"`
+	DateAfter       time.Time        `json:"date_after"`
+	NoteBefore      *string          `json:"note_before,omitempty"`
+	NoteAfter       *string          `json:"note_after,omitempty"`
+	// Payout details; nil on revisions recorded before methods and categories existed
+	MethodBefore            *SettlementMethod `json:"method_before,omitempty"`
+	MethodAfter             *SettlementMethod `json:"method_after,omitempty"`
+	ExternalReferenceBefore *string           `json:"external_reference_before,omitempty"`
+	ExternalReferenceAfter  *string           `json:"external_reference_after,omitempty"`
+	CategoryIDBefore        *uuid.UUID        `json:"category_id_before,omitempty"`
+	CategoryIDAfter         *uuid.UUID        `json:"category_id_after,omitempty"`
+	CreatedAt               time.Time         `json:"created_at"`
 }
 
 // InviteToken represents an invitation to join a group
@@ -103,7 +400,176 @@ type MemberWithUser struct {
 
 // Balance represents a user's balance in a group
 type Balance struct {
-	UserID  uuid.UUID `json:"user_id"`
-	Name    string    `json:"name"`
-	Balance float64   `json:"balance"`
+	UserID          uuid.UUID `json:"user_id"`
+	Name            string    `json:"name"`
+	Balance         float64   `json:"balance"`
+	ConfirmedPaid   float64   `json:"confirmed_paid"`   // Settlements the member acknowledged
+	UnconfirmedPaid float64   `json:"unconfirmed_paid"` // Settlements awaiting acknowledgement or disputed
+	// Jars splits the balance for members who have jars; Unallocated is the
+	// part of it in no jar, such as what was earned before the jars existed
+	Jars        []*JarBalance `json:"jars,omitempty"`
+	Unallocated *float64      `json:"unallocated,omitempty"`
+	// Loans are the member's loans with something still to pay back
+	Loans []*LoanBalance `json:"loans,omitempty"`
+}
+
+// LoanBalance is what a member still owes on one loan
+type LoanBalance struct {
+	LoanID        uuid.UUID     `json:"loan_id"`
+	Principal     float64       `json:"principal"`
+	TotalDue      float64       `json:"total_due"`
+	Outstanding   float64       `json:"outstanding"`
+	RepaymentPlan RepaymentPlan `json:"repayment_plan"`
+}
+
+// Jar is a named part of a member's balance, such as spend, save or give
+type Jar struct {
+	ID           uuid.UUID  `json:"id"`
+	GroupID      uuid.UUID  `json:"group_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Name         string     `json:"name"`
+	SplitPercent float64    `json:"split_percent"` // Share of each approved entry it receives
+	Position     int        `json:"position"`
+	Balance      float64    `json:"balance"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// JarBalance is what a member has in one jar
+type JarBalance struct {
+	JarID        uuid.UUID `json:"jar_id"`
+	Name         string    `json:"name"`
+	SplitPercent float64   `json:"split_percent"`
+	Balance      float64   `json:"balance"`
+}
+
+// JarSplit is the part of an approved entry that went into a jar
+type JarSplit struct {
+	EntryID uuid.UUID `json:"entry_id"`
+	JarID   uuid.UUID `json:"jar_id"`
+	Amount  float64   `json:"amount"`
+}
+
+// JarTransfer moves money between two of a member's jars
+type JarTransfer struct {
+	ID              uuid.UUID `json:"id"`
+	GroupID         uuid.UUID `json:"group_id"`
+	UserID          uuid.UUID `json:"user_id"`
+	FromJarID       uuid.UUID `json:"from_jar_id"`
+	ToJarID         uuid.UUID `json:"to_jar_id"`
+	Amount          float64   `json:"amount"`
+	Note            *string   `json:"note,omitempty"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// BalancePoint is a member's running balance at the end of a period
+type BalancePoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Earned      float64   `json:"earned"`  // Approved entries that occurred in the period
+	Settled     float64   `json:"settled"` // Settlements dated in the period
+	Balance     float64   `json:"balance"` // Running balance at period end
+}
+
+// DeliveryStatus represents the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// LedgerComment is a message on a ledger entry's thread
+type LedgerComment struct {
+	ID        uuid.UUID `json:"id"`
+	EntryID   uuid.UUID `json:"entry_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerAttachment is a photo uploaded as proof for a ledger entry
+type LedgerAttachment struct {
+	ID               uuid.UUID `json:"id"`
+	EntryID          uuid.UUID `json:"entry_id"`
+	UploadedByUserID uuid.UUID `json:"uploaded_by_user_id"`
+	ContentType      string    `json:"content_type"`
+	SizeBytes        int64     `json:"size_bytes"`
+	StorageKey       string    `json:"-"` // Where the photo is kept in the attachment store
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ApprovalRule auto-approves matching entries that members log
+type ApprovalRule struct {
+	ID              uuid.UUID  `json:"id"`
+	GroupID         uuid.UUID  `json:"group_id"`
+	ChoreID         *uuid.UUID `json:"chore_id"`    // nil matches any chore
+	UserID          *uuid.UUID `json:"user_id"`     // nil matches any member
+	MaxPerDay       *int       `json:"max_per_day"` // Per member; nil is unlimited
+	CreatedByUserID uuid.UUID  `json:"created_by_user_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Webhook is a group's subscription to events at a URL
+type Webhook struct {
+	ID              uuid.UUID `json:"id"`
+	GroupID         uuid.UUID `json:"group_id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"-"` // Only returned when the webhook is created
+	EventTypes      []string  `json:"event_types"`
+	Active          bool      `json:"active"`
+	CreatedByUserID uuid.UUID `json:"created_by_user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for, or sent to, a webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID      `json:"id"`
+	WebhookID      uuid.UUID      `json:"webhook_id"`
+	EventID        uuid.UUID      `json:"event_id"`
+	EventType      string         `json:"event_type"`
+	Payload        string         `json:"payload"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int           `json:"response_status,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Device is a phone registered for push notifications
+type Device struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Token      string    `json:"token"`
+	Platform   string    `json:"platform"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// NotificationSettings are a user's time zone, quiet hours, email locale
+// and email opt-outs
+type NotificationSettings struct {
+	UserID             uuid.UUID `json:"user_id"`
+	Timezone           string    `json:"timezone"`
+	QuietHoursStart    *int      `json:"quiet_hours_start,omitempty"` // Minutes after local midnight
+	QuietHoursEnd      *int      `json:"quiet_hours_end,omitempty"`
+	Locale             string    `json:"locale"`
+	EmailNotifications bool      `json:"email_notifications"`
+	EmailDigest        bool      `json:"email_digest"`
+}
+
+// NotificationPreference turns notifications of one event type on or off,
+// for all groups (GroupID nil) or for one group
+type NotificationPreference struct {
+	GroupID   *uuid.UUID `json:"group_id,omitempty"`
+	EventType string     `json:"event_type"`
+	Enabled   bool       `json:"enabled"`
 }
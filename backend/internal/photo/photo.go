@@ -0,0 +1,238 @@
+// Package photo checks uploaded photos and strips the metadata, such as
+// EXIF location and camera details, that they carry.
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register decoders for image.DecodeConfig
+	_ "image/png"
+	"net/http"
+)
+
+const (
+	// JPEG and PNG are the accepted content types
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+
+	// maxPixels bounds the decoded size of an image
+	maxPixels = 50_000_000
+)
+
+// ErrUnsupportedType is returned for files that are not JPEG or PNG images
+var ErrUnsupportedType = errors.New("only JPEG and PNG images are accepted")
+
+// ErrInvalidImage is returned for files that claim to be an image but cannot be read as one
+var ErrInvalidImage = errors.New("file is not a valid image")
+
+// Sanitize sniffs the content type of data, which must be a JPEG or PNG
+// image, and returns it with metadata removed. The content type is decided
+// from the bytes, never from the file name or a client-supplied header.
+func Sanitize(data []byte) (string, []byte, error) {
+	contentType := http.DetectContentType(data)
+
+	var clean []byte
+	var err error
+	switch contentType {
+	case JPEG:
+		clean, err = stripJPEG(data)
+	case PNG:
+		clean, err = stripPNG(data)
+	default:
+		return "", nil, ErrUnsupportedType
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(clean))
+	if err != nil {
+		return "", nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return "", nil, fmt.Errorf("image must be at most %d pixels", maxPixels)
+	}
+
+	return contentType, clean, nil
+}
+
+// stripJPEG drops the application segments other than JFIF, Adobe colour
+// information and ICC profiles, and comments, up to the start of the image data.
+// An EXIF segment is replaced by one holding only its orientation, so photos
+// taken with the camera turned still display upright.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	oriented := false
+	i := 2
+	for {
+		if i+1 >= len(data) || data[i] != 0xFF {
+			return nil, ErrInvalidImage
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker
+			i++
+			continue
+		case marker == 0xD9:
+			// End of image with no image data
+			return nil, ErrInvalidImage
+		case marker >= 0xD0 && marker <= 0xD7, marker == 0x01:
+			// Markers without a length
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+
+		if marker == 0xDA {
+			// Start of scan: the rest is compressed image data
+			return append(out, data[i:]...), nil
+		}
+		if keepJPEGSegment(marker, data[i+4:end]) {
+			out = append(out, data[i:end]...)
+		} else if orientation, ok := exifOrientation(marker, data[i+4:end]); ok && !oriented {
+			out = append(out, orientationSegment(orientation)...)
+			oriented = true
+		}
+		i = end
+	}
+}
+
+// keepJPEGSegment reports whether a segment is needed to display the image
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == 0xE0, marker == 0xEE:
+		// APP0 (JFIF) and APP14 (Adobe colour transform)
+		return true
+	case marker == 0xE2:
+		// APP2 is kept for ICC profiles only, not for embedded previews
+		return bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker >= 0xE1 && marker <= 0xEF, marker == 0xFE:
+		// Other application segments (EXIF, XMP, maker notes) and comments
+		return false
+	}
+	return true
+}
+
+const (
+	// exifHeader starts the payload of an EXIF APP1 segment
+	exifHeader = "Exif\x00\x00"
+	// orientationTag is the EXIF tag for how the image is rotated or flipped
+	orientationTag = 0x0112
+)
+
+// exifOrientation returns the orientation in an EXIF APP1 segment's first
+// directory, when it is set to anything other than the default of upright
+func exifOrientation(marker byte, payload []byte) (uint16, bool) {
+	if marker != 0xE1 || !bytes.HasPrefix(payload, []byte(exifHeader)) {
+		return 0, false
+	}
+	tiff := payload[len(exifHeader):]
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		// A SHORT value is held in the first two bytes of the value field
+		if order.Uint16(tiff[entry:entry+2]) == orientationTag && order.Uint16(tiff[entry+2:entry+4]) == 3 {
+			orientation := order.Uint16(tiff[entry+8 : entry+10])
+			return orientation, orientation >= 2 && orientation <= 8
+		}
+	}
+	return 0, false
+}
+
+// orientationSegment builds an EXIF APP1 segment holding only orientation
+func orientationSegment(orientation uint16) []byte {
+	payload := []byte(exifHeader)
+	payload = append(payload, "MM\x00*"...)
+	payload = binary.BigEndian.AppendUint32(payload, 8) // First directory follows the header
+	payload = binary.BigEndian.AppendUint16(payload, 1) // One entry
+	payload = binary.BigEndian.AppendUint16(payload, orientationTag)
+	payload = binary.BigEndian.AppendUint16(payload, 3) // SHORT
+	payload = binary.BigEndian.AppendUint32(payload, 1) // One value
+	payload = binary.BigEndian.AppendUint16(payload, orientation)
+	payload = append(payload, 0, 0)                     // Value field padding
+	payload = binary.BigEndian.AppendUint32(payload, 0) // No next directory
+
+	seg := []byte{0xFF, 0xE1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// droppedPNGChunks carry metadata rather than image data
+var droppedPNGChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG drops the metadata chunks, and anything after the end chunk
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrInvalidImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	i := len(pngSignature)
+	for {
+		if i+8 > len(data) {
+			return nil, ErrInvalidImage
+		}
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end < i || end > len(data) {
+			return nil, ErrInvalidImage
+		}
+
+		if !droppedPNGChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		if chunkType == "IEND" {
+			return out, nil
+		}
+		i = end
+	}
+}
@@ -0,0 +1,174 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 200, A: 255})
+	return img
+}
+
+// jpegSegment builds a JPEG marker segment with its length
+func jpegSegment(marker byte, payload string) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// exifPayload builds an EXIF APP1 payload whose first directory holds the
+// camera make and orientation, followed by the make's text and a GPS note
+func exifPayload(order binary.AppendByteOrder, orientation uint16) string {
+	tiff := []byte("MM\x00*")
+	if order == binary.LittleEndian {
+		tiff = []byte("II*\x00")
+	}
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 2)
+	// Make: ASCII, stored after the directory
+	makeOffset := 8 + 2 + 2*12 + 4
+	tiff = order.AppendUint16(tiff, 0x010F)
+	tiff = order.AppendUint16(tiff, 2)
+	tiff = order.AppendUint32(tiff, 10)
+	tiff = order.AppendUint32(tiff, uint32(makeOffset))
+	// Orientation: SHORT, held in the value field
+	tiff = order.AppendUint16(tiff, 0x0112)
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, "SecretCam\x00GPS 51.5N 0.12W"...)
+	return "Exif\x00\x00" + string(tiff)
+}
+
+// pngChunk builds a PNG chunk with its length and CRC
+func pngChunk(chunkType, payload string) []byte {
+	chunk := make([]byte, 4, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE([]byte(chunkType+payload)))
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(), nil))
+	plain := buf.Bytes()
+
+	// Insert EXIF, XMP, an embedded preview, an ICC profile and a comment after SOI
+	var tagged []byte
+	tagged = append(tagged, plain[:2]...)
+	tagged = append(tagged, jpegSegment(0xE1, "Exif\x00\x00GPS 51.5N 0.12W")...)
+	tagged = append(tagged, jpegSegment(0xE1, "http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")...)
+	tagged = append(tagged, jpegSegment(0xE2, "MPF\x00preview")...)
+	tagged = append(tagged, jpegSegment(0xE2, "ICC_PROFILE\x00\x01\x01profile")...)
+	tagged = append(tagged, jpegSegment(0xFE, "taken at home")...)
+	tagged = append(tagged, plain[2:]...)
+
+	contentType, clean, err := Sanitize(tagged)
+	require.NoError(t, err)
+	assert.Equal(t, JPEG, contentType)
+	for _, secret := range []string{"Exif", "GPS", "xmpmeta", "MPF", "taken at home"} {
+		assert.NotContains(t, string(clean), secret)
+	}
+	assert.Contains(t, string(clean), "ICC_PROFILE")
+
+	img, err := jpeg.Decode(bytes.NewReader(clean))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 3), img.Bounds())
+
+	// An already clean image passes through unchanged
+	_, again, err := Sanitize(plain)
+	require.NoError(t, err)
+	assert.Equal(t, plain, again)
+
+	_, _, err = Sanitize(tagged[:40])
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestSanitizeJPEGKeepsOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(), nil))
+	plain := buf.Bytes()
+
+	for _, order := range []binary.AppendByteOrder{binary.BigEndian, binary.LittleEndian} {
+		// A photo taken with the camera turned, to be rotated 90° clockwise
+		var oriented []byte
+		oriented = append(oriented, plain[:2]...)
+		oriented = append(oriented, jpegSegment(0xE1, exifPayload(order, 6))...)
+		oriented = append(oriented, plain[2:]...)
+
+		_, clean, err := Sanitize(oriented)
+		require.NoError(t, err)
+		assert.NotContains(t, string(clean), "SecretCam")
+		assert.NotContains(t, string(clean), "GPS")
+
+		// Only the orientation survives, in a segment of its own after SOI
+		require.Greater(t, len(clean), 4)
+		assert.Equal(t, []byte{0xFF, 0xE1}, clean[2:4])
+		length := int(binary.BigEndian.Uint16(clean[4:6]))
+		orientation, ok := exifOrientation(0xE1, clean[6:4+length])
+		assert.True(t, ok)
+		assert.Equal(t, uint16(6), orientation)
+
+		img, err := jpeg.Decode(bytes.NewReader(clean))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 4, 3), img.Bounds())
+	}
+
+	// The default upright orientation needs no segment
+	var upright []byte
+	upright = append(upright, plain[:2]...)
+	upright = append(upright, jpegSegment(0xE1, exifPayload(binary.BigEndian, 1))...)
+	upright = append(upright, plain[2:]...)
+	_, clean, err := Sanitize(upright)
+	require.NoError(t, err)
+	assert.Equal(t, plain, clean)
+}
+
+func TestSanitizePNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage()))
+	plain := buf.Bytes()
+
+	// The IHDR chunk follows the 8-byte signature and is 25 bytes long
+	ihdrEnd := 8 + 25
+	var tagged []byte
+	tagged = append(tagged, plain[:ihdrEnd]...)
+	tagged = append(tagged, pngChunk("tEXt", "Location\x00home")...)
+	tagged = append(tagged, pngChunk("eXIf", "MM\x00*GPS")...)
+	tagged = append(tagged, plain[ihdrEnd:]...)
+	tagged = append(tagged, "trailing data"...)
+
+	contentType, clean, err := Sanitize(tagged)
+	require.NoError(t, err)
+	assert.Equal(t, PNG, contentType)
+	assert.Equal(t, plain, clean)
+
+	_, _, err = Sanitize(plain[:len(plain)-12])
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestSanitizeRejectsOtherTypes(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("GIF89a\x01\x00\x01\x00"),
+		[]byte("<html><script>alert(1)</script></html>"),
+		[]byte("%PDF-1.7"),
+		{},
+	} {
+		_, _, err := Sanitize(data)
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	}
+}
@@ -0,0 +1,387 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+)
+
+// EventTypes are the events that can send a notification, and so can have
+// a preference
+var EventTypes = []events.Type{events.LedgerCreated, events.LedgerApproved, events.LedgerRejected, events.SettlementCreated, events.SettlementDisputed}
+
+// Notifies reports whether t can send a notification
+func Notifies(t events.Type) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Preference turns one event type on or off, for every group or for one
+type Preference struct {
+	GroupID   *uuid.UUID
+	EventType events.Type
+	Enabled   bool
+}
+
+// Enabled reports whether a user with these preferences wants notifications
+// of type t from a group. A group preference beats an all-groups one, and
+// everything is on by default.
+func Enabled(prefs []Preference, groupID uuid.UUID, t events.Type) bool {
+	enabled := true
+	for _, p := range prefs {
+		if p.EventType != t {
+			continue
+		}
+		if p.GroupID == nil {
+			enabled = p.Enabled
+		}
+	}
+	for _, p := range prefs {
+		if p.EventType == t && p.GroupID != nil && *p.GroupID == groupID {
+			return p.Enabled
+		}
+	}
+	return enabled
+}
+
+// Recipient is a user who may be notified, with their devices and settings
+type Recipient struct {
+	UserID      uuid.UUID
+	Timezone    string
+	QuietHours  *QuietHours
+	Preferences []Preference
+	Tokens      []string
+}
+
+// Labels are the names used in notification text
+type Labels struct {
+	GroupName string
+	UserName  string
+	ChoreName string
+}
+
+// Store looks up who to notify and forgets dead devices
+type Store interface {
+	// Heads returns the IDs of a group's heads
+	Heads(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error)
+	// Recipients returns the users' settings and device tokens
+	Recipients(ctx context.Context, userIDs []uuid.UUID) ([]Recipient, error)
+	// Labels returns the names of a group, a user and optionally a chore
+	Labels(ctx context.Context, groupID, userID uuid.UUID, choreID *uuid.UUID) (Labels, error)
+	// RemoveDevices deletes devices by token
+	RemoveDevices(ctx context.Context, tokens []string) error
+}
+
+// Dispatcher turns events into notifications
+type Dispatcher struct {
+	store    Store
+	provider Provider
+	now      func() time.Time
+}
+
+// NewDispatcher creates a Dispatcher
+func NewDispatcher(store Store, provider Provider) *Dispatcher {
+	return &Dispatcher{store: store, provider: provider, now: time.Now}
+}
+
+// Subscriber returns an events subscriber that notifies in the background,
+// so requests do not wait on the push service
+func (d *Dispatcher) Subscriber() events.Subscriber {
+	return func(ctx context.Context, e events.Event) error {
+		if !Notifies(e.Type) {
+			return nil
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			if err := d.Handle(ctx, e); err != nil {
+				log.Printf("push: failed to notify %s %s: %v", e.Type, e.ID, err)
+			}
+		}()
+		return nil
+	}
+}
+
+// Resource is the part of an event's data that notifications use
+type Resource struct {
+	ID      uuid.UUID           `json:"id"`
+	UserID  uuid.UUID           `json:"user_id"`
+	ChoreID *uuid.UUID          `json:"chore_id"`
+	Amount  float64             `json:"amount"`
+	Status  models.LedgerStatus `json:"status"`
+	Reason  *string             `json:"status_reason"`
+	Dispute *string             `json:"dispute_reason"`
+}
+
+// DecodeResource extracts the notified resource from an event
+func DecodeResource(e events.Event) (Resource, error) {
+	var res Resource
+	raw, err := json.Marshal(e.Data)
+	if err != nil {
+		return res, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return res, fmt.Errorf("failed to decode event data: %w", err)
+	}
+	return res, nil
+}
+
+// HeadLister looks up a group's heads
+type HeadLister interface {
+	Heads(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// Audience returns who hears about an event: heads for entries awaiting
+// approval and disputed settlements, otherwise the member the resource is
+// for. The actor is left out.
+func Audience(ctx context.Context, heads HeadLister, e events.Event, res Resource) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	switch e.Type {
+	case events.LedgerCreated:
+		if res.Status != models.StatusPendingApproval {
+			return nil, nil
+		}
+		var err error
+		if userIDs, err = heads.Heads(ctx, e.GroupID); err != nil {
+			return nil, err
+		}
+	case events.SettlementDisputed:
+		var err error
+		if userIDs, err = heads.Heads(ctx, e.GroupID); err != nil {
+			return nil, err
+		}
+	case events.LedgerApproved, events.LedgerRejected, events.SettlementCreated:
+		userIDs = []uuid.UUID{res.UserID}
+	default:
+		return nil, nil
+	}
+	return without(userIDs, e.ActorUserID), nil
+}
+
+// Handle sends the notifications for one event
+func (d *Dispatcher) Handle(ctx context.Context, e events.Event) error {
+	res, err := DecodeResource(e)
+	if err != nil {
+		return err
+	}
+	userIDs, err := Audience(ctx, d.store, e, res)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	labels, err := d.store.Labels(ctx, e.GroupID, res.UserID, res.ChoreID)
+	if err != nil {
+		return err
+	}
+	title, body := compose(e.Type, labels, res.Amount)
+	if e.Type == events.LedgerRejected && res.Reason != nil {
+		body += ": " + *res.Reason
+	}
+	if e.Type == events.SettlementDisputed && res.Dispute != nil {
+		body += ": " + *res.Dispute
+	}
+
+	recipients, err := d.store.Recipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	var messages []Message
+	for _, r := range recipients {
+		if !d.wants(r, e.GroupID, e.Type) {
+			continue
+		}
+		for _, token := range r.Tokens {
+			messages = append(messages, Message{
+				To:    token,
+				Title: title,
+				Body:  body,
+				Data: map[string]string{
+					"type":        string(e.Type),
+					"group_id":    e.GroupID.String(),
+					"resource_id": res.ID.String(),
+				},
+			})
+		}
+	}
+	return d.send(ctx, messages)
+}
+
+// send delivers messages and forgets devices that are no longer registered
+func (d *Dispatcher) send(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	unregistered, err := d.provider.Send(ctx, messages)
+	if len(unregistered) > 0 {
+		if removeErr := d.store.RemoveDevices(ctx, unregistered); removeErr != nil {
+			log.Printf("push: failed to remove unregistered devices: %v", removeErr)
+		}
+	}
+	return err
+}
+
+// wants reports whether a recipient should be notified now
+func (d *Dispatcher) wants(r Recipient, groupID uuid.UUID, t events.Type) bool {
+	if len(r.Tokens) == 0 || !Enabled(r.Preferences, groupID, t) {
+		return false
+	}
+	if r.QuietHours == nil {
+		return true
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return !r.QuietHours.Contains(d.now().In(loc))
+}
+
+// compose returns the notification title and body for an event
+func compose(t events.Type, l Labels, amount float64) (string, string) {
+	chore := l.ChoreName
+	if chore == "" {
+		chore = "Your chore"
+	}
+	switch t {
+	case events.LedgerCreated:
+		logged := l.ChoreName
+		if logged == "" {
+			logged = "a chore"
+		}
+		return "Chore waiting for approval", fmt.Sprintf("%s logged %s (%.2f) in %s", l.UserName, logged, amount, l.GroupName)
+	case events.LedgerApproved:
+		return "Chore approved", fmt.Sprintf("%s (%.2f) was approved in %s", chore, amount, l.GroupName)
+	case events.LedgerRejected:
+		return "Chore not approved", fmt.Sprintf("%s (%.2f) was rejected in %s", chore, amount, l.GroupName)
+	case events.SettlementCreated:
+		return "Payout recorded", fmt.Sprintf("%.2f was paid out to you in %s", amount, l.GroupName)
+	case events.SettlementDisputed:
+		return "Payout disputed", fmt.Sprintf("%s disputed a payout of %.2f in %s", l.UserName, amount, l.GroupName)
+	}
+	return "", ""
+}
+
+// without returns ids minus the actor, who does not need to hear about their own action
+func without(ids []uuid.UUID, actor *uuid.UUID) []uuid.UUID {
+	if actor == nil {
+		return ids
+	}
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if id != *actor {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// RemindPending notifies heads about entries that have been waiting for their
+// approval, unless they turned off notifications for new entries
+func (d *Dispatcher) RemindPending(ctx context.Context, due []reminders.Pending) error {
+	userIDs := make([]uuid.UUID, 0, len(due))
+	for _, p := range due {
+		userIDs = append(userIDs, p.UserID)
+	}
+	recipients, err := d.store.Recipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[uuid.UUID]Recipient, len(recipients))
+	for _, r := range recipients {
+		byUser[r.UserID] = r
+	}
+
+	var messages []Message
+	for _, p := range due {
+		r, ok := byUser[p.UserID]
+		if !ok || !d.wants(r, p.GroupID, events.LedgerCreated) {
+			continue
+		}
+		body := fmt.Sprintf("%d entries are waiting for your approval in %s", p.Count, p.GroupName)
+		if p.Count == 1 {
+			body = fmt.Sprintf("1 entry is waiting for your approval in %s", p.GroupName)
+		}
+		for _, token := range r.Tokens {
+			messages = append(messages, Message{
+				To:    token,
+				Title: "Still waiting for approval",
+				Body:  body,
+				Data: map[string]string{
+					"type":     "reminder.pending",
+					"group_id": p.GroupID.String(),
+				},
+			})
+		}
+	}
+	return d.send(ctx, messages)
+}
+
+// RemindChoreDue notifies members about chores assigned to them that are due
+// soon, unless they turned off notifications for chore changes
+func (d *Dispatcher) RemindChoreDue(ctx context.Context, due []reminders.Chore) error {
+	return d.remindChores(ctx, due, false)
+}
+
+// RemindChoreOverdue notifies members about chores assigned to them whose
+// due time has passed, unless they turned off notifications for chore changes
+func (d *Dispatcher) RemindChoreOverdue(ctx context.Context, due []reminders.Chore) error {
+	return d.remindChores(ctx, due, true)
+}
+
+func (d *Dispatcher) remindChores(ctx context.Context, due []reminders.Chore, overdue bool) error {
+	userIDs := make([]uuid.UUID, 0, len(due))
+	for _, c := range due {
+		userIDs = append(userIDs, c.UserID)
+	}
+	recipients, err := d.store.Recipients(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	byUser := make(map[uuid.UUID]Recipient, len(recipients))
+	for _, r := range recipients {
+		byUser[r.UserID] = r
+	}
+
+	var messages []Message
+	for _, c := range due {
+		r, ok := byUser[c.UserID]
+		if !ok || !d.wants(r, c.GroupID, events.ChoreUpdated) {
+			continue
+		}
+		title, kind := "Chore due soon", "reminder.chore_due"
+		body := fmt.Sprintf("%s is due %s in %s", c.ChoreName, c.LocalDueAt().Format("Mon 2 Jan 15:04"), c.GroupName)
+		if overdue {
+			title, kind = "Chore overdue", "reminder.chore_overdue"
+			body = fmt.Sprintf("%s was due %s in %s", c.ChoreName, c.LocalDueAt().Format("Mon 2 Jan 15:04"), c.GroupName)
+		}
+		for _, token := range r.Tokens {
+			messages = append(messages, Message{
+				To:    token,
+				Title: title,
+				Body:  body,
+				Data: map[string]string{
+					"type":     kind,
+					"group_id": c.GroupID.String(),
+					"chore_id": c.ChoreID.String(),
+				},
+			})
+		}
+	}
+	return d.send(ctx, messages)
+}
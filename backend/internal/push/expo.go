@@ -0,0 +1,116 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// ExpoPushURL is Expo's push send endpoint
+	ExpoPushURL = "https://exp.host/--/api/v2/push/send"
+	// expoBatchSize is the most messages Expo accepts per request
+	expoBatchSize = 100
+)
+
+// ExpoProvider sends notifications through the Expo Push service
+type ExpoProvider struct {
+	url         string
+	accessToken string
+	client      *http.Client
+}
+
+// NewExpoProvider creates an ExpoProvider. accessToken is optional and only
+// needed when enhanced push security is enabled for the Expo project.
+func NewExpoProvider(url, accessToken string) *ExpoProvider {
+	return &ExpoProvider{
+		url:         url,
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type expoMessage struct {
+	Message
+	Sound string `json:"sound,omitempty"`
+}
+
+type expoResponse struct {
+	Data []struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details struct {
+			Error string `json:"error"`
+		} `json:"details"`
+	} `json:"data"`
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Send posts the messages in batches and collects DeviceNotRegistered tickets
+func (p *ExpoProvider) Send(ctx context.Context, messages []Message) ([]string, error) {
+	var unregistered []string
+	for start := 0; start < len(messages); start += expoBatchSize {
+		end := min(start+expoBatchSize, len(messages))
+		batch := messages[start:end]
+
+		tokens, err := p.send(ctx, batch)
+		unregistered = append(unregistered, tokens...)
+		if err != nil {
+			return unregistered, err
+		}
+	}
+	return unregistered, nil
+}
+
+func (p *ExpoProvider) send(ctx context.Context, batch []Message) ([]string, error) {
+	payload := make([]expoMessage, len(batch))
+	for i, m := range batch {
+		payload[i] = expoMessage{Message: m, Sound: "default"}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode push messages: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if p.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send push messages: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result expoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid push response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || len(result.Errors) > 0 {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("push service error %s: %s", result.Errors[0].Code, result.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("push service returned status %d", resp.StatusCode)
+	}
+
+	// Tickets are returned in the order the messages were sent
+	var unregistered []string
+	for i, ticket := range result.Data {
+		if i < len(batch) && ticket.Status == "error" && ticket.Details.Error == "DeviceNotRegistered" {
+			unregistered = append(unregistered, batch[i].To)
+		}
+	}
+	return unregistered, nil
+}
@@ -0,0 +1,68 @@
+// Package push sends mobile push notifications for group events through a
+// pluggable provider, honouring each user's preferences and quiet hours.
+package push
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Message is a notification to one device
+type Message struct {
+	To    string            `json:"to"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Provider delivers messages to a push service
+type Provider interface {
+	// Send delivers the messages and returns the tokens the service reports
+	// as no longer registered, so their devices can be removed
+	Send(ctx context.Context, messages []Message) (unregistered []string, err error)
+}
+
+// localHistory is how many sent messages a LocalProvider keeps
+const localHistory = 100
+
+// LocalProvider logs messages instead of sending them and keeps the most
+// recent ones for inspection. It is used in development and tests.
+type LocalProvider struct {
+	mu   sync.Mutex
+	sent []Message
+	// Unregistered tokens are reported back as such when sent to
+	Unregistered map[string]bool
+}
+
+// NewLocalProvider creates a LocalProvider
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{Unregistered: map[string]bool{}}
+}
+
+// Send records and logs the messages
+func (p *LocalProvider) Send(ctx context.Context, messages []Message) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var unregistered []string
+	for _, m := range messages {
+		if p.Unregistered[m.To] {
+			unregistered = append(unregistered, m.To)
+			continue
+		}
+		log.Printf("push: to %s: %s - %s", m.To, m.Title, m.Body)
+		p.sent = append(p.sent, m)
+	}
+	if len(p.sent) > localHistory {
+		p.sent = append([]Message(nil), p.sent[len(p.sent)-localHistory:]...)
+	}
+	return unregistered, nil
+}
+
+// Sent returns the most recently sent messages
+func (p *LocalProvider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.sent...)
+}
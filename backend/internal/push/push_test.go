@@ -0,0 +1,330 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/events"
+	"github.com/srjn45/pocket-money/backend/internal/models"
+	"github.com/srjn45/pocket-money/backend/internal/reminders"
+)
+
+func TestQuietHours(t *testing.T) {
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return t
+	}
+
+	overnight := QuietHours{Start: 21 * 60, End: 7 * 60}
+	assert.True(t, overnight.Contains(at("21:00")))
+	assert.True(t, overnight.Contains(at("02:30")))
+	assert.False(t, overnight.Contains(at("07:00")))
+	assert.False(t, overnight.Contains(at("12:00")))
+
+	afternoon := QuietHours{Start: 13 * 60, End: 15 * 60}
+	assert.True(t, afternoon.Contains(at("14:59")))
+	assert.False(t, afternoon.Contains(at("15:00")))
+	assert.False(t, afternoon.Contains(at("12:59")))
+}
+
+func TestClock(t *testing.T) {
+	m, err := ParseClock("07:30")
+	require.NoError(t, err)
+	assert.Equal(t, 450, m)
+	assert.Equal(t, "07:30", FormatClock(m))
+
+	_, err = ParseClock("25:00")
+	assert.Error(t, err)
+}
+
+func TestEnabled(t *testing.T) {
+	group, other := uuid.New(), uuid.New()
+
+	assert.True(t, Enabled(nil, group, events.LedgerApproved))
+
+	prefs := []Preference{
+		{EventType: events.LedgerApproved, Enabled: false},
+		{GroupID: &group, EventType: events.LedgerApproved, Enabled: true},
+		{GroupID: &other, EventType: events.SettlementCreated, Enabled: false},
+	}
+	assert.True(t, Enabled(prefs, group, events.LedgerApproved))
+	assert.False(t, Enabled(prefs, other, events.LedgerApproved))
+	assert.False(t, Enabled(prefs, other, events.SettlementCreated))
+	assert.True(t, Enabled(prefs, group, events.SettlementCreated))
+}
+
+// memoryStore is an in-memory Store
+type memoryStore struct {
+	heads      []uuid.UUID
+	recipients map[uuid.UUID]Recipient
+	removed    []string
+}
+
+func (s *memoryStore) Heads(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	return s.heads, nil
+}
+
+func (s *memoryStore) Recipients(ctx context.Context, userIDs []uuid.UUID) ([]Recipient, error) {
+	var result []Recipient
+	for _, id := range userIDs {
+		if r, ok := s.recipients[id]; ok {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryStore) Labels(ctx context.Context, groupID, userID uuid.UUID, choreID *uuid.UUID) (Labels, error) {
+	return Labels{GroupName: "Family", UserName: "Kid", ChoreName: "Dishes"}, nil
+}
+
+func (s *memoryStore) RemoveDevices(ctx context.Context, tokens []string) error {
+	s.removed = append(s.removed, tokens...)
+	return nil
+}
+
+type ledgerData struct {
+	ID      uuid.UUID           `json:"id"`
+	UserID  uuid.UUID           `json:"user_id"`
+	ChoreID uuid.UUID           `json:"chore_id"`
+	Amount  float64             `json:"amount"`
+	Status  models.LedgerStatus `json:"status"`
+	Reason  *string             `json:"status_reason,omitempty"`
+}
+
+func TestDispatcherPendingGoesToHeads(t *testing.T) {
+	group := uuid.New()
+	head, otherHead, kid := uuid.New(), uuid.New(), uuid.New()
+	store := &memoryStore{
+		heads: []uuid.UUID{head, otherHead},
+		recipients: map[uuid.UUID]Recipient{
+			head:      {UserID: head, Timezone: "UTC", Tokens: []string{"head-phone", "head-tablet"}},
+			otherHead: {UserID: otherHead, Timezone: "UTC", Tokens: []string{"dead-token"}},
+		},
+	}
+	provider := NewLocalProvider()
+	provider.Unregistered["dead-token"] = true
+	d := NewDispatcher(store, provider)
+
+	e := events.New(events.LedgerCreated, group, &kid, ledgerData{ID: uuid.New(), UserID: kid, ChoreID: uuid.New(), Amount: 2, Status: models.StatusPendingApproval})
+	require.NoError(t, d.Handle(context.Background(), e))
+
+	sent := provider.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "head-phone", sent[0].To)
+	assert.Equal(t, "Chore waiting for approval", sent[0].Title)
+	assert.Equal(t, "Kid logged Dishes (2.00) in Family", sent[0].Body)
+	assert.Equal(t, "ledger.created", sent[0].Data["type"])
+	assert.Equal(t, []string{"dead-token"}, store.removed)
+
+	// Entries a head logs are already approved and notify nobody
+	e = events.New(events.LedgerCreated, group, &head, ledgerData{UserID: kid, Amount: 2, Status: models.StatusApproved})
+	require.NoError(t, d.Handle(context.Background(), e))
+	assert.Len(t, provider.Sent(), 2)
+}
+
+func TestDispatcherApprovalGoesToEarner(t *testing.T) {
+	group := uuid.New()
+	head, kid := uuid.New(), uuid.New()
+	store := &memoryStore{recipients: map[uuid.UUID]Recipient{
+		kid: {UserID: kid, Timezone: "UTC", Tokens: []string{"kid-phone"}},
+	}}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+
+	e := events.New(events.LedgerRejected, group, &head, ledgerData{UserID: kid, Amount: 1.5, Status: models.StatusRejected})
+	require.NoError(t, d.Handle(context.Background(), e))
+
+	sent := provider.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "kid-phone", sent[0].To)
+	assert.Equal(t, "Dishes (1.50) was rejected in Family", sent[0].Body)
+
+	reason := "only half the lawn"
+	e = events.New(events.LedgerRejected, group, &head, ledgerData{UserID: kid, Amount: 1.5, Status: models.StatusRejected, Reason: &reason})
+	require.NoError(t, d.Handle(context.Background(), e))
+	sent = provider.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "Dishes (1.50) was rejected in Family: only half the lawn", sent[1].Body)
+}
+
+func TestDispatcherDisputeGoesToHeads(t *testing.T) {
+	group := uuid.New()
+	head, kid := uuid.New(), uuid.New()
+	store := &memoryStore{
+		heads: []uuid.UUID{head},
+		recipients: map[uuid.UUID]Recipient{
+			head: {UserID: head, Timezone: "UTC", Tokens: []string{"head-phone"}},
+			kid:  {UserID: kid, Timezone: "UTC", Tokens: []string{"kid-phone"}},
+		},
+	}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+
+	data := struct {
+		ID      uuid.UUID `json:"id"`
+		UserID  uuid.UUID `json:"user_id"`
+		Amount  float64   `json:"amount"`
+		Dispute string    `json:"dispute_reason"`
+	}{uuid.New(), kid, 50, "I only got 5"}
+	require.NoError(t, d.Handle(context.Background(), events.New(events.SettlementDisputed, group, &kid, data)))
+
+	sent := provider.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "head-phone", sent[0].To)
+	assert.Equal(t, "Payout disputed", sent[0].Title)
+	assert.Equal(t, "Kid disputed a payout of 50.00 in Family: I only got 5", sent[0].Body)
+}
+
+func TestDispatcherRespectsPreferencesAndQuietHours(t *testing.T) {
+	group := uuid.New()
+	head, kid := uuid.New(), uuid.New()
+	store := &memoryStore{recipients: map[uuid.UUID]Recipient{
+		kid: {
+			UserID:     kid,
+			Timezone:   "America/New_York",
+			QuietHours: &QuietHours{Start: 21 * 60, End: 7 * 60},
+			Tokens:     []string{"kid-phone"},
+		},
+	}}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+	approved := events.New(events.LedgerApproved, group, &head, ledgerData{UserID: kid, Amount: 1})
+
+	// 02:00 UTC is 22:00 in New York: quiet
+	d.now = func() time.Time { return time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC) }
+	require.NoError(t, d.Handle(context.Background(), approved))
+	assert.Empty(t, provider.Sent())
+
+	// 16:00 UTC is noon in New York
+	d.now = func() time.Time { return time.Date(2026, 10, 1, 16, 0, 0, 0, time.UTC) }
+	require.NoError(t, d.Handle(context.Background(), approved))
+	assert.Len(t, provider.Sent(), 1)
+
+	// Turned off for this group
+	r := store.recipients[kid]
+	r.Preferences = []Preference{{GroupID: &group, EventType: events.LedgerApproved, Enabled: false}}
+	store.recipients[kid] = r
+	require.NoError(t, d.Handle(context.Background(), approved))
+	assert.Len(t, provider.Sent(), 1)
+}
+
+func TestDispatcherSkipsActor(t *testing.T) {
+	head := uuid.New()
+	store := &memoryStore{recipients: map[uuid.UUID]Recipient{
+		head: {UserID: head, Timezone: "UTC", Tokens: []string{"head-phone"}},
+	}}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+
+	// A head settling with themselves does not notify themselves
+	e := events.New(events.SettlementCreated, uuid.New(), &head, ledgerData{UserID: head, Amount: 5})
+	require.NoError(t, d.Handle(context.Background(), e))
+	assert.Empty(t, provider.Sent())
+}
+
+func TestDispatcherRemindPending(t *testing.T) {
+	family, chess := uuid.New(), uuid.New()
+	head := uuid.New()
+	store := &memoryStore{recipients: map[uuid.UUID]Recipient{
+		head: {
+			UserID:      head,
+			Timezone:    "UTC",
+			Tokens:      []string{"head-phone"},
+			Preferences: []Preference{{GroupID: &chess, EventType: events.LedgerCreated, Enabled: false}},
+		},
+	}}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+
+	err := d.RemindPending(context.Background(), []reminders.Pending{
+		{UserID: head, GroupID: family, GroupName: "Family", Count: 1},
+		{UserID: head, GroupID: chess, GroupName: "Chess club", Count: 4},
+	})
+	require.NoError(t, err)
+
+	sent := provider.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "1 entry is waiting for your approval in Family", sent[0].Body)
+	assert.Equal(t, family.String(), sent[0].Data["group_id"])
+}
+
+func TestDispatcherRemindChores(t *testing.T) {
+	family, chess := uuid.New(), uuid.New()
+	kid := uuid.New()
+	store := &memoryStore{recipients: map[uuid.UUID]Recipient{
+		kid: {
+			UserID:      kid,
+			Timezone:    "UTC",
+			Tokens:      []string{"kid-phone"},
+			Preferences: []Preference{{GroupID: &chess, EventType: events.ChoreUpdated, Enabled: false}},
+		},
+	}}
+	provider := NewLocalProvider()
+	d := NewDispatcher(store, provider)
+
+	dueAt := time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)
+	due := []reminders.Chore{
+		{UserID: kid, GroupID: family, GroupName: "Family", ChoreID: uuid.New(), ChoreName: "Dishes", DueAt: dueAt, Timezone: "Europe/London"},
+		{UserID: kid, GroupID: chess, GroupName: "Chess club", ChoreID: uuid.New(), ChoreName: "Tidy boards", DueAt: dueAt},
+	}
+	require.NoError(t, d.RemindChoreDue(context.Background(), due))
+	require.NoError(t, d.RemindChoreOverdue(context.Background(), due))
+
+	sent := provider.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "Dishes is due Sat 17 Oct 19:00 in Family", sent[0].Body)
+	assert.Equal(t, "reminder.chore_due", sent[0].Data["type"])
+	assert.Equal(t, due[0].ChoreID.String(), sent[0].Data["chore_id"])
+	assert.Equal(t, "Chore overdue", sent[1].Title)
+	assert.Equal(t, "Dishes was due Sat 17 Oct 19:00 in Family", sent[1].Body)
+}
+
+func TestExpoProvider(t *testing.T) {
+	var received []map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_, _ = w.Write([]byte(`{"data":[
+			{"status":"ok","id":"ticket-1"},
+			{"status":"error","message":"not registered","details":{"error":"DeviceNotRegistered"}},
+			{"status":"error","message":"too big","details":{"error":"MessageTooBig"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	p := NewExpoProvider(server.URL, "access-token")
+	unregistered, err := p.Send(context.Background(), []Message{
+		{To: "ExponentPushToken[a]", Title: "t", Body: "b", Data: map[string]string{"type": "ledger.approved"}},
+		{To: "ExponentPushToken[b]", Title: "t", Body: "b"},
+		{To: "ExponentPushToken[c]", Title: "t", Body: "b"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ExponentPushToken[b]"}, unregistered)
+	assert.Equal(t, "Bearer access-token", auth)
+	require.Len(t, received, 3)
+	assert.Equal(t, "ExponentPushToken[a]", received[0]["to"])
+	assert.Equal(t, "default", received[0]["sound"])
+	assert.Equal(t, map[string]any{"type": "ledger.approved"}, received[0]["data"])
+}
+
+func TestExpoProviderRequestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"errors":[{"code":"TOO_MANY_REQUESTS","message":"slow down"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := NewExpoProvider(server.URL, "").Send(context.Background(), []Message{{To: "x"}})
+	assert.EqualError(t, err, "push service error TOO_MANY_REQUESTS: slow down")
+}
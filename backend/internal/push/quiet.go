@@ -0,0 +1,39 @@
+package push
+
+import (
+	"fmt"
+	"time"
+
+	// Embed the time zone database so user time zones resolve in minimal images
+	_ "time/tzdata"
+)
+
+// QuietHours is a daily window, in minutes after local midnight, during
+// which no notifications are sent. End may be before Start to span midnight.
+type QuietHours struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether the local time t falls inside the window
+func (q QuietHours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.Start <= q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClock formats minutes after midnight as "HH:MM"
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
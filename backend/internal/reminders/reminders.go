@@ -0,0 +1,148 @@
+// Package reminders sends scheduled reminders through the configured
+// notification channels.
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/scheduler"
+)
+
+// Pending reminds a head about entries that have been awaiting their
+// approval for a while
+type Pending struct {
+	UserID    uuid.UUID // The head
+	GroupID   uuid.UUID
+	GroupName string
+	Count     int
+	Since     time.Time // When the oldest of the entries was logged
+}
+
+// Chore reminds a member about a chore assigned to them that they have not
+// logged since it was assigned
+type Chore struct {
+	UserID    uuid.UUID // The assignee
+	GroupID   uuid.UUID
+	GroupName string
+	ChoreID   uuid.UUID
+	ChoreName string
+	DueAt     time.Time
+	Timezone  string // The assignee's
+}
+
+// LocalDueAt returns when the chore is due in the assignee's timezone
+func (c Chore) LocalDueAt() time.Time {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return c.DueAt.In(loc)
+}
+
+// Store finds reminders that are due
+type Store interface {
+	// DuePendingReminders returns reminders for entries logged before cutoff
+	// that heads have not been reminded about since cutoff, and records that
+	// they were reminded at now
+	DuePendingReminders(ctx context.Context, cutoff, now time.Time) ([]Pending, error)
+}
+
+// ChoreStore finds chore reminders that are due
+type ChoreStore interface {
+	// DueChoreReminders returns reminders for chores due after now and by
+	// until that their assignee was not reminded about for that due time, and
+	// records that they were reminded at now
+	DueChoreReminders(ctx context.Context, until, now time.Time) ([]Chore, error)
+	// OverdueChoreReminders returns reminders for chores due by now that
+	// their assignee was not nudged about since cutoff, and records that they
+	// were nudged at now
+	OverdueChoreReminders(ctx context.Context, cutoff, now time.Time) ([]Chore, error)
+}
+
+// Channel delivers reminders, e.g. as push notifications or email
+type Channel interface {
+	RemindPending(ctx context.Context, reminders []Pending) error
+}
+
+// ChoreChannel delivers chore reminders
+type ChoreChannel interface {
+	RemindChoreDue(ctx context.Context, reminders []Chore) error
+	RemindChoreOverdue(ctx context.Context, reminders []Chore) error
+}
+
+// PendingJob reminds heads about entries that have been pending longer than
+// after, and again every after for as long as they stay pending
+func PendingJob(store Store, after time.Duration, channels ...Channel) scheduler.Job {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		due, err := store.DuePendingReminders(ctx, now.Add(-after), now)
+		if err != nil {
+			return err
+		}
+		remind := make([]func(context.Context, []Pending) error, 0, len(channels))
+		for _, ch := range channels {
+			remind = append(remind, ch.RemindPending)
+		}
+		return send(ctx, due, "pending approval reminders", remind)
+	}
+}
+
+// ChoreDueJob reminds members about chores assigned to them that are due
+// within before, once per due time
+func ChoreDueJob(store ChoreStore, before time.Duration, channels ...ChoreChannel) scheduler.Job {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		due, err := store.DueChoreReminders(ctx, now.Add(before), now)
+		if err != nil {
+			return err
+		}
+		remind := make([]func(context.Context, []Chore) error, 0, len(channels))
+		for _, ch := range channels {
+			remind = append(remind, ch.RemindChoreDue)
+		}
+		return send(ctx, due, "chore reminders", remind)
+	}
+}
+
+// ChoreOverdueJob nudges members about chores assigned to them whose due
+// time has passed, and again every every for as long as they are not done
+func ChoreOverdueJob(store ChoreStore, every time.Duration, channels ...ChoreChannel) scheduler.Job {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		due, err := store.OverdueChoreReminders(ctx, now.Add(-every), now)
+		if err != nil {
+			return err
+		}
+		remind := make([]func(context.Context, []Chore) error, 0, len(channels))
+		for _, ch := range channels {
+			remind = append(remind, ch.RemindChoreOverdue)
+		}
+		return send(ctx, due, "overdue chore reminders", remind)
+	}
+}
+
+// send delivers reminders through every channel; a failing channel does not
+// stop the others
+func send[T any](ctx context.Context, due []T, what string, remind []func(context.Context, []T) error) error {
+	if len(due) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, r := range remind {
+		if err := r(ctx, due); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	log.Printf("Sent %d %s", len(due), what)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to send reminders: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	due            []Pending
+	cutoff, called time.Time
+}
+
+func (s *fakeStore) DuePendingReminders(ctx context.Context, cutoff, now time.Time) ([]Pending, error) {
+	s.cutoff, s.called = cutoff, now
+	due := s.due
+	s.due = nil // Reminded now
+	return due, nil
+}
+
+type fakeChannel struct {
+	got []Pending
+	err error
+}
+
+func (c *fakeChannel) RemindPending(ctx context.Context, reminders []Pending) error {
+	c.got = append(c.got, reminders...)
+	return c.err
+}
+
+func TestPendingJob(t *testing.T) {
+	store := &fakeStore{due: []Pending{{UserID: uuid.New(), GroupID: uuid.New(), GroupName: "Family", Count: 2}}}
+	push := &fakeChannel{}
+	email := &fakeChannel{err: errors.New("smtp down")}
+	job := PendingJob(store, 24*time.Hour, push, email)
+
+	// A failing channel does not stop the others
+	err := job(context.Background())
+	assert.ErrorContains(t, err, "smtp down")
+	assert.Len(t, push.got, 1)
+	assert.Len(t, email.got, 1)
+	assert.Equal(t, 24*time.Hour, store.called.Sub(store.cutoff))
+
+	require.NoError(t, job(context.Background()))
+	assert.Len(t, push.got, 1)
+}
+
+type fakeChoreStore struct {
+	due, overdue  []Chore
+	until, cutoff time.Time
+	called        time.Time
+}
+
+func (s *fakeChoreStore) DueChoreReminders(ctx context.Context, until, now time.Time) ([]Chore, error) {
+	s.until, s.called = until, now
+	due := s.due
+	s.due = nil // Reminded once per due time
+	return due, nil
+}
+
+func (s *fakeChoreStore) OverdueChoreReminders(ctx context.Context, cutoff, now time.Time) ([]Chore, error) {
+	s.cutoff, s.called = cutoff, now
+	overdue := s.overdue
+	s.overdue = nil // Nudged now
+	return overdue, nil
+}
+
+type fakeChoreChannel struct {
+	due, overdue []Chore
+	err          error
+}
+
+func (c *fakeChoreChannel) RemindChoreDue(ctx context.Context, reminders []Chore) error {
+	c.due = append(c.due, reminders...)
+	return c.err
+}
+
+func (c *fakeChoreChannel) RemindChoreOverdue(ctx context.Context, reminders []Chore) error {
+	c.overdue = append(c.overdue, reminders...)
+	return c.err
+}
+
+func TestChoreJobs(t *testing.T) {
+	dishes := Chore{UserID: uuid.New(), GroupID: uuid.New(), GroupName: "Family", ChoreID: uuid.New(), ChoreName: "Dishes"}
+	lawn := Chore{UserID: uuid.New(), GroupID: uuid.New(), GroupName: "Family", ChoreID: uuid.New(), ChoreName: "Lawn"}
+	store := &fakeChoreStore{due: []Chore{dishes}, overdue: []Chore{lawn}}
+	push := &fakeChoreChannel{}
+	email := &fakeChoreChannel{err: errors.New("smtp down")}
+	dueJob := ChoreDueJob(store, 2*time.Hour, push, email)
+	overdueJob := ChoreOverdueJob(store, 24*time.Hour, push, email)
+
+	// Each job sends its own reminders, and a failing channel does not stop the others
+	err := dueJob(context.Background())
+	assert.ErrorContains(t, err, "smtp down")
+	assert.Equal(t, 2*time.Hour, store.until.Sub(store.called))
+	assert.Equal(t, []Chore{dishes}, push.due)
+	assert.Equal(t, []Chore{dishes}, email.due)
+	assert.Empty(t, push.overdue)
+
+	err = overdueJob(context.Background())
+	assert.ErrorContains(t, err, "smtp down")
+	assert.Equal(t, 24*time.Hour, store.called.Sub(store.cutoff))
+	assert.Equal(t, []Chore{lawn}, push.overdue)
+	assert.Len(t, push.due, 1)
+
+	// Nothing is sent twice
+	require.NoError(t, dueJob(context.Background()))
+	require.NoError(t, overdueJob(context.Background()))
+	assert.Len(t, push.due, 1)
+	assert.Len(t, push.overdue, 1)
+}
+
+func TestLocalDueAt(t *testing.T) {
+	c := Chore{DueAt: time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC), Timezone: "Asia/Kolkata"}
+	assert.Equal(t, "23:30", c.LocalDueAt().Format("15:04"))
+
+	c.Timezone = "Not/AZone"
+	assert.Equal(t, time.UTC, c.LocalDueAt().Location())
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 3 * * *", "2026-09-14 02:59", "2026-09-14 03:00"},
+		{"0 3 * * *", "2026-09-14 03:00", "2026-09-15 03:00"},
+		{"*/15 * * * *", "2026-09-14 10:07", "2026-09-14 10:15"},
+		{"30 2 * * 1-5", "2026-09-18 03:00", "2026-09-21 02:30"}, // Friday -> Monday
+		{"0 0 1 * *", "2026-09-14 12:00", "2026-10-01 00:00"},
+		{"0 9 * * 7", "2026-09-14 12:00", "2026-09-20 09:00"}, // 7 is Sunday
+		{"@weekly", "2026-09-14 12:00", "2026-09-20 00:00"},
+		{"@hourly", "2026-12-31 23:30", "2027-01-01 00:00"},
+		{"0 12 29 2 *", "2026-03-01 00:00", "2028-02-29 12:00"},
+		{"0 0 13 * 5", "2026-09-01 00:00", "2026-09-04 00:00"}, // day 13 OR Friday
+		{"5,35 8-9 * * *", "2026-09-14 08:36", "2026-09-14 09:05"},
+		{"10/20 * * * *", "2026-09-14 08:31", "2026-09-14 08:50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr+" from "+tt.from, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, at(tt.want), s.Next(at(tt.from)))
+		})
+	}
+}
+
+func TestNextImpossible(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(at("2026-01-01 00:00")).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
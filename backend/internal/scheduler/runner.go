@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Claimer decides which server instance runs each tick of a job. Claim
+// returns ok=false when another instance is running the job or has already
+// run this tick; otherwise the caller runs the job and then calls release
+// with its result.
+type Claimer interface {
+	Claim(ctx context.Context, name string, tick time.Time) (release func(error), ok bool, err error)
+}
+
+// Runner runs named jobs on their schedules. With several server instances
+// sharing a Claimer, each tick of a job runs on exactly one of them.
+type Runner struct {
+	claimer Claimer
+	jobs    []runnerJob
+}
+
+type runnerJob struct {
+	name     string
+	schedule *Schedule
+	job      Job
+}
+
+// NewRunner creates a Runner. A nil claimer runs every tick locally.
+func NewRunner(claimer Claimer) *Runner {
+	return &Runner{claimer: claimer}
+}
+
+// Add registers a job; it must be called before Start
+func (r *Runner) Add(name string, s *Schedule, job Job) {
+	r.jobs = append(r.jobs, runnerJob{name: name, schedule: s, job: job})
+}
+
+// Start runs every registered job in the background until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	for _, j := range r.jobs {
+		go loop(ctx, j.name, j.schedule, func(ctx context.Context, tick time.Time) (bool, error) {
+			return r.runTick(ctx, j.name, tick, j.job)
+		})
+	}
+}
+
+// runTick runs one tick of a job if this instance claims it
+func (r *Runner) runTick(ctx context.Context, name string, tick time.Time, job Job) (bool, error) {
+	if r.claimer == nil {
+		return true, job(ctx)
+	}
+
+	release, ok, err := r.claimer.Claim(ctx, name, tick)
+	if err != nil || !ok {
+		return false, err
+	}
+	err = job(ctx)
+	release(err)
+	return true, err
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryClaimer stands in for the database: one holder per job at a time,
+// and each tick is claimed once
+type memoryClaimer struct {
+	mu       sync.Mutex
+	held     map[string]bool
+	lastTick map[string]time.Time
+	results  []error
+}
+
+func newMemoryClaimer() *memoryClaimer {
+	return &memoryClaimer{held: map[string]bool{}, lastTick: map[string]time.Time{}}
+}
+
+func (c *memoryClaimer) Claim(ctx context.Context, name string, tick time.Time) (func(error), bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.held[name] || !tick.After(c.lastTick[name]) {
+		return nil, false, nil
+	}
+	c.held[name] = true
+	c.lastTick[name] = tick
+	return func(err error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.held[name] = false
+		c.results = append(c.results, err)
+	}, true, nil
+}
+
+func TestRunnerRunsEachTickOnce(t *testing.T) {
+	claimer := newMemoryClaimer()
+	instances := []*Runner{NewRunner(claimer), NewRunner(claimer)}
+	tick := at("2026-10-19 08:00")
+
+	var runs int
+	job := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+
+	// Both instances wake for the same tick; only the first runs it
+	for _, r := range instances {
+		_, err := r.runTick(context.Background(), "digest", tick, job)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, runs)
+
+	ran, err := instances[1].runTick(context.Background(), "digest", tick.Add(time.Hour), job)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 2, runs)
+}
+
+func TestRunnerSkipsWhileHeldAndReportsResult(t *testing.T) {
+	claimer := newMemoryClaimer()
+	r := NewRunner(claimer)
+	failure := errors.New("smtp down")
+
+	ran, err := r.runTick(context.Background(), "reminders", at("2026-10-19 08:00"), func(ctx context.Context) error {
+		// A second instance cannot start the next tick while this one runs
+		ran, err := NewRunner(claimer).runTick(ctx, "reminders", at("2026-10-19 09:00"), func(ctx context.Context) error {
+			t.Fatal("ran while held")
+			return nil
+		})
+		assert.False(t, ran)
+		assert.NoError(t, err)
+		return failure
+	})
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []error{failure}, claimer.results)
+}
+
+func TestRunnerWithoutClaimer(t *testing.T) {
+	var runs int
+	r := NewRunner(nil)
+	for i := 0; i < 2; i++ {
+		ran, err := r.runTick(context.Background(), "backup", at("2026-10-19 08:00"), func(ctx context.Context) error {
+			runs++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.True(t, ran)
+	}
+	assert.Equal(t, 2, runs)
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is work run on a schedule
+type Job func(ctx context.Context) error
+
+// Run calls job each time the schedule matches until ctx is cancelled.
+// Runs never overlap: a tick that passes while the job is running is skipped.
+func Run(ctx context.Context, name string, s *Schedule, job Job) {
+	loop(ctx, name, s, func(ctx context.Context, tick time.Time) (bool, error) {
+		return true, job(ctx)
+	})
+}
+
+// loop calls run at each tick of the schedule until ctx is cancelled. run
+// reports whether it did anything, so skipped ticks are not logged as runs.
+func loop(ctx context.Context, name string, s *Schedule, run func(ctx context.Context, tick time.Time) (bool, error)) {
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("scheduler: %s has no upcoming run, stopping", name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		ran, err := run(ctx, next)
+		if err != nil {
+			log.Printf("scheduler: %s failed after %s: %v", name, time.Since(start).Round(time.Millisecond), err)
+			continue
+		}
+		if ran {
+			log.Printf("scheduler: %s finished in %s", name, time.Since(start).Round(time.Millisecond))
+		}
+	}
+}
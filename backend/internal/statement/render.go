@@ -0,0 +1,103 @@
+package statement
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+//go:embed templates/statement.html
+var templates embed.FS
+
+var htmlTemplate = template.Must(template.New("statement.html").Funcs(template.FuncMap{
+	"money":         formatMoney,
+	"optionalMoney": optionalMoney,
+	"date":          func(t time.Time) string { return t.Format("2 Jan 2006") },
+	"method":        methodLabel,
+}).ParseFS(templates, "templates/statement.html"))
+
+// ClosingDate returns the last day of the statement period
+func (s *Statement) ClosingDate() time.Time {
+	return s.PeriodEnd.AddDate(0, 0, -1)
+}
+
+// Filename returns a download name such as statement-alex-2026-09.csv
+func (s *Statement) Filename(ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, s.MemberName)
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '-' }), "-")
+	if name == "" {
+		name = "member"
+	}
+	return fmt.Sprintf("statement-%s-%s.%s", name, s.Period, ext)
+}
+
+// WriteCSV writes the statement as CSV with opening and closing balance rows
+func (s *Statement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"date", "type", "description", "list_price", "adjustment", "amount", "balance"},
+		{s.PeriodStart.Format("2006-01-02"), "opening_balance", "Opening balance", "", "", "", formatMoney(s.OpeningBalance)},
+	}
+	for _, l := range s.Lines {
+		rows = append(rows, []string{
+			l.Date.Format("2006-01-02"),
+			string(l.Kind),
+			l.Description,
+			optionalMoney(l.ListPrice),
+			optionalMoney(l.Adjustment),
+			formatMoney(l.Amount),
+			formatMoney(l.Balance),
+		})
+	}
+	rows = append(rows, []string{s.ClosingDate().Format("2006-01-02"), "closing_balance", "Closing balance", "", "", "", formatMoney(s.ClosingBalance)})
+
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write statement csv: %w", err)
+	}
+	return nil
+}
+
+// WriteHTML renders the statement as a printable HTML page
+func (s *Statement) WriteHTML(w io.Writer) error {
+	if err := htmlTemplate.Execute(w, s); err != nil {
+		return fmt.Errorf("failed to render statement html: %w", err)
+	}
+	return nil
+}
+
+// methodLabel names a settlement method for people
+func methodLabel(method models.SettlementMethod) string {
+	switch method {
+	case models.MethodBankTransfer:
+		return "bank transfer"
+	case models.MethodInKind:
+		return "in kind"
+	}
+	return "cash"
+}
+
+func formatMoney(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func optionalMoney(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatMoney(*v)
+}
@@ -0,0 +1,287 @@
+// Package statement builds and renders monthly member statements.
+package statement
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+// ErrInvalidPeriod is returned for a period that is not in YYYY-MM form
+var ErrInvalidPeriod = errors.New("invalid period, use YYYY-MM")
+
+// LineKind identifies what a statement line records
+type LineKind string
+
+const (
+	LineEntry      LineKind = "entry"
+	LineSettlement LineKind = "settlement"
+)
+
+// Period is a calendar month in UTC
+type Period struct {
+	Start time.Time
+	End   time.Time // Exclusive
+}
+
+// ParsePeriod parses a YYYY-MM month
+func ParsePeriod(value string) (Period, error) {
+	start, err := time.Parse("2006-01", value)
+	if err != nil {
+		return Period{}, ErrInvalidPeriod
+	}
+	return Period{Start: start, End: start.AddDate(0, 1, 0)}, nil
+}
+
+// PeriodOf returns the month containing t
+func PeriodOf(t time.Time) Period {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Period{Start: start, End: start.AddDate(0, 1, 0)}
+}
+
+// String formats the period as YYYY-MM
+func (p Period) String() string {
+	return p.Start.Format("2006-01")
+}
+
+// Line is a single credit or debit on a statement
+type Line struct {
+	Kind        LineKind  `json:"kind"`
+	ID          uuid.UUID `json:"id"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	ListPrice   *float64  `json:"list_price,omitempty"` // Chore price, entries only
+	Adjustment  *float64  `json:"adjustment,omitempty"` // Bonus (+) or penalty (-) against the list price, entries only
+	Amount      float64   `json:"amount"`               // Credit (+) or debit (-)
+	Balance     float64   `json:"balance"`              // Running balance after this line
+	// How a settlement was paid out and its category name, settlements only
+	Method            models.SettlementMethod `json:"method,omitempty"`
+	ExternalReference *string                 `json:"external_reference,omitempty"`
+	Category          *string                 `json:"category,omitempty"`
+}
+
+// Totals summarises a statement's lines
+type Totals struct {
+	Earned    float64 `json:"earned"`
+	Bonuses   float64 `json:"bonuses"`
+	Penalties float64 `json:"penalties"`
+	Settled   float64 `json:"settled"`
+	// Advanced is lent on loans and Repaid is paid back on them; neither
+	// counts as Earned
+	Advanced float64 `json:"advanced,omitempty"`
+	Repaid   float64 `json:"repaid,omitempty"`
+	// Unconfirmed is the part of Settled the member has not acknowledged
+	// receiving yet, or disputes
+	Unconfirmed float64 `json:"unconfirmed"`
+}
+
+// Statement is a member's account for one month
+type Statement struct {
+	GroupID        uuid.UUID `json:"group_id"`
+	GroupName      string    `json:"group_name"`
+	UserID         uuid.UUID `json:"user_id"`
+	MemberName     string    `json:"member_name"`
+	Period         string    `json:"period"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	OpeningBalance float64   `json:"opening_balance"`
+	Lines          []Line    `json:"lines"`
+	Totals         Totals    `json:"totals"`
+	// Settled broken down by payout method and by category; only methods
+	// and categories with settlements in the period are listed
+	SettledByMethod   []models.MethodTotal   `json:"settled_by_method"`
+	SettledByCategory []models.CategoryTotal `json:"settled_by_category"`
+	ClosingBalance    float64                `json:"closing_balance"`
+	GeneratedAt       time.Time              `json:"generated_at"`
+}
+
+// choreOf returns the chore an entry was logged for, if it still exists
+func choreOf(e *models.LedgerEntry, chores map[uuid.UUID]*models.Chore) (*models.Chore, bool) {
+	if e.ChoreID == nil {
+		return nil, false
+	}
+	chore, ok := chores[*e.ChoreID]
+	return chore, ok
+}
+
+// Build assembles a statement from the member's approved entries and
+// settlements in the period. Bonuses and penalties are the difference
+// between an entry's amount and its chore's current list price. Categories
+// should include deleted ones so older settlements keep their names.
+func Build(group *models.Group, member *models.User, period Period, opening float64, entries []*models.LedgerEntry, chores map[uuid.UUID]*models.Chore, settlements []*models.Settlement, categories map[uuid.UUID]*models.SettlementCategory, now time.Time) *Statement {
+	st := &Statement{
+		GroupID:           group.ID,
+		GroupName:         group.Name,
+		UserID:            member.ID,
+		MemberName:        member.Name,
+		Period:            period.String(),
+		PeriodStart:       period.Start,
+		PeriodEnd:         period.End,
+		OpeningBalance:    roundCents(opening),
+		Lines:             make([]Line, 0, len(entries)+len(settlements)),
+		SettledByMethod:   []models.MethodTotal{},
+		SettledByCategory: []models.CategoryTotal{},
+		GeneratedAt:       now.UTC(),
+	}
+
+	for _, e := range entries {
+		line := Line{
+			Kind:        LineEntry,
+			ID:          e.ID,
+			Date:        e.OccurredAt.UTC(),
+			Description: "Unknown chore",
+			Amount:      e.Amount,
+		}
+		switch e.Kind {
+		case models.EntryLoan:
+			st.Totals.Advanced += e.Amount
+		case models.EntryRepayment:
+			st.Totals.Repaid -= e.Amount
+		default:
+			st.Totals.Earned += e.Amount
+		}
+
+		if e.Kind == models.EntryAllowance {
+			line.Description = "Allowance"
+		} else if e.Kind == models.EntryInterest {
+			line.Description = "Interest"
+		} else if e.Kind == models.EntryLoan {
+			line.Description = "Advance"
+		} else if e.Kind == models.EntryRepayment {
+			line.Description = "Loan repayment"
+			if e.WithheldFromID != nil {
+				line.Description = "Loan repayment (withheld)"
+			}
+		} else if chore, ok := choreOf(e, chores); ok {
+			line.Description = chore.Name
+			price := chore.Amount
+			adjustment := roundCents(e.Amount - chore.Amount)
+			line.ListPrice = &price
+			line.Adjustment = &adjustment
+			if adjustment > 0 {
+				st.Totals.Bonuses += adjustment
+			} else {
+				st.Totals.Penalties -= adjustment
+			}
+		}
+		st.Lines = append(st.Lines, line)
+	}
+
+	byMethod := make(map[models.SettlementMethod]*models.MethodTotal)
+	byCategory := make(map[uuid.UUID]*models.CategoryTotal)
+	var uncategorized *models.CategoryTotal
+	for _, s := range settlements {
+		description := "Settlement"
+		if s.Note != nil && *s.Note != "" {
+			description = *s.Note
+		}
+		switch s.Status {
+		case models.SettlementAwaitingAck:
+			description += " (unconfirmed)"
+			st.Totals.Unconfirmed += s.Amount
+		case models.SettlementDisputed:
+			description += " (disputed)"
+			st.Totals.Unconfirmed += s.Amount
+		}
+		line := Line{
+			Kind:              LineSettlement,
+			ID:                s.ID,
+			Date:              s.Date.UTC(),
+			Description:       description,
+			Amount:            -s.Amount,
+			Method:            s.Method,
+			ExternalReference: s.ExternalReference,
+		}
+		st.Totals.Settled += s.Amount
+
+		// Settlements recorded before methods existed were cash
+		method := s.Method
+		if method == "" {
+			method = models.MethodCash
+		}
+		if byMethod[method] == nil {
+			byMethod[method] = &models.MethodTotal{Method: method}
+		}
+		byMethod[method].Count++
+		byMethod[method].Amount += s.Amount
+
+		total := uncategorized
+		if s.CategoryID != nil {
+			if total = byCategory[*s.CategoryID]; total == nil {
+				total = &models.CategoryTotal{CategoryID: s.CategoryID}
+				if category, ok := categories[*s.CategoryID]; ok {
+					total.Name = &category.Name
+				}
+				byCategory[*s.CategoryID] = total
+			}
+			line.Category = total.Name
+		} else if total == nil {
+			total = &models.CategoryTotal{}
+			uncategorized = total
+		}
+		total.Count++
+		total.Amount += s.Amount
+
+		st.Lines = append(st.Lines, line)
+	}
+
+	for _, method := range models.SettlementMethods {
+		if total, ok := byMethod[method]; ok {
+			total.Amount = roundCents(total.Amount)
+			st.SettledByMethod = append(st.SettledByMethod, *total)
+		}
+	}
+	for _, total := range byCategory {
+		total.Amount = roundCents(total.Amount)
+		st.SettledByCategory = append(st.SettledByCategory, *total)
+	}
+	sort.Slice(st.SettledByCategory, func(i, j int) bool {
+		return categorySortKey(st.SettledByCategory[i]) < categorySortKey(st.SettledByCategory[j])
+	})
+	if uncategorized != nil {
+		uncategorized.Amount = roundCents(uncategorized.Amount)
+		st.SettledByCategory = append(st.SettledByCategory, *uncategorized)
+	}
+
+	// Settlements count from midnight on their date, so they sort before that day's entries
+	sort.SliceStable(st.Lines, func(i, j int) bool {
+		return st.Lines[i].Date.Before(st.Lines[j].Date)
+	})
+
+	balance := st.OpeningBalance
+	for i := range st.Lines {
+		balance = roundCents(balance + st.Lines[i].Amount)
+		st.Lines[i].Balance = balance
+	}
+	st.ClosingBalance = balance
+
+	st.Totals.Earned = roundCents(st.Totals.Earned)
+	st.Totals.Bonuses = roundCents(st.Totals.Bonuses)
+	st.Totals.Penalties = roundCents(st.Totals.Penalties)
+	st.Totals.Settled = roundCents(st.Totals.Settled)
+	st.Totals.Advanced = roundCents(st.Totals.Advanced)
+	st.Totals.Repaid = roundCents(st.Totals.Repaid)
+	st.Totals.Unconfirmed = roundCents(st.Totals.Unconfirmed)
+
+	return st
+}
+
+// categorySortKey orders category totals by name, then ID
+func categorySortKey(t models.CategoryTotal) string {
+	name := ""
+	if t.Name != nil {
+		name = strings.ToLower(*t.Name)
+	}
+	return name + "\x00" + t.CategoryID.String()
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,236 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srjn45/pocket-money/backend/internal/models"
+)
+
+func fixture(t *testing.T) *Statement {
+	t.Helper()
+
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+
+	group := &models.Group{ID: uuid.New(), Name: "Family"}
+	member := &models.User{ID: uuid.New(), Name: "Alex <Jr>"}
+	dishes := &models.Chore{ID: uuid.New(), Name: "Dishes", Amount: 2}
+	lawn := &models.Chore{ID: uuid.New(), Name: "Mow lawn", Amount: 10}
+	note := "Cash"
+	removed := uuid.New()
+
+	entries := []*models.LedgerEntry{
+		{ID: uuid.New(), ChoreID: &dishes.ID, Amount: 2.5, OccurredAt: time.Date(2026, 9, 3, 18, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), ChoreID: &lawn.ID, Amount: 8, OccurredAt: time.Date(2026, 9, 10, 10, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), ChoreID: &removed, Amount: 1, OccurredAt: time.Date(2026, 9, 20, 10, 0, 0, 0, time.UTC)},
+	}
+	settlements := []*models.Settlement{
+		{ID: uuid.New(), Amount: 5, Date: time.Date(2026, 9, 10, 0, 0, 0, 0, time.UTC), Note: &note},
+	}
+	chores := map[uuid.UUID]*models.Chore{dishes.ID: dishes, lawn.ID: lawn}
+
+	return Build(group, member, period, 4, entries, chores, settlements, nil, time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC))
+}
+
+func TestParsePeriod(t *testing.T) {
+	p, err := ParsePeriod("2026-12")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), p.Start)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), p.End)
+	assert.Equal(t, "2026-12", p.String())
+
+	for _, bad := range []string{"2026-13", "2026/09", "09-2026", "2026-09-01"} {
+		_, err := ParsePeriod(bad)
+		assert.ErrorIs(t, err, ErrInvalidPeriod, bad)
+	}
+
+	assert.Equal(t, "2026-09", PeriodOf(time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC)).String())
+}
+
+func TestBuild(t *testing.T) {
+	st := fixture(t)
+
+	assert.Equal(t, "2026-09", st.Period)
+	assert.Equal(t, 4.0, st.OpeningBalance)
+	require.Len(t, st.Lines, 4)
+
+	// Chronological, settlement before the same day's entries
+	assert.Equal(t, "Dishes", st.Lines[0].Description)
+	assert.Equal(t, LineSettlement, st.Lines[1].Kind)
+	assert.Equal(t, "Cash", st.Lines[1].Description)
+	assert.Equal(t, -5.0, st.Lines[1].Amount)
+	assert.Equal(t, "Mow lawn", st.Lines[2].Description)
+	assert.Equal(t, "Unknown chore", st.Lines[3].Description)
+	assert.Nil(t, st.Lines[3].ListPrice)
+
+	require.NotNil(t, st.Lines[0].Adjustment)
+	assert.Equal(t, 0.5, *st.Lines[0].Adjustment)
+	assert.Equal(t, -2.0, *st.Lines[2].Adjustment)
+
+	assert.Equal(t, 6.5, st.Lines[0].Balance)
+	assert.Equal(t, 1.5, st.Lines[1].Balance)
+	assert.Equal(t, 9.5, st.Lines[2].Balance)
+	assert.Equal(t, 10.5, st.ClosingBalance)
+
+	assert.Equal(t, Totals{Earned: 11.5, Bonuses: 0.5, Penalties: 2, Settled: 5}, st.Totals)
+}
+
+func TestBuild_Allowance(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	allowanceID := uuid.New()
+	entries := []*models.LedgerEntry{
+		{ID: uuid.New(), Kind: models.EntryAllowance, AllowanceID: &allowanceID, Amount: 5, OccurredAt: time.Date(2026, 9, 7, 0, 0, 0, 0, time.UTC)},
+	}
+
+	st := Build(&models.Group{}, &models.User{}, period, 0, entries, nil, nil, nil, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	require.Len(t, st.Lines, 1)
+	assert.Equal(t, "Allowance", st.Lines[0].Description)
+	assert.Nil(t, st.Lines[0].ListPrice)
+	assert.Equal(t, Totals{Earned: 5}, st.Totals)
+}
+
+func TestBuild_Interest(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	rateID := uuid.New()
+	entries := []*models.LedgerEntry{
+		{ID: uuid.New(), Kind: models.EntryInterest, InterestRateID: &rateID, Amount: 0.41, OccurredAt: time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	st := Build(&models.Group{}, &models.User{}, period, 0, entries, nil, nil, nil, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	require.Len(t, st.Lines, 1)
+	assert.Equal(t, "Interest", st.Lines[0].Description)
+	assert.Nil(t, st.Lines[0].ListPrice)
+	assert.Equal(t, Totals{Earned: 0.41}, st.Totals)
+}
+
+func TestBuild_Loans(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	loanID := uuid.New()
+	earning := uuid.New()
+	day := time.Date(2026, 9, 5, 0, 0, 0, 0, time.UTC)
+	entries := []*models.LedgerEntry{
+		{ID: uuid.New(), Kind: models.EntryLoan, LoanID: &loanID, Amount: 20, OccurredAt: day},
+		{ID: earning, Kind: models.EntryAllowance, Amount: 10, OccurredAt: day.AddDate(0, 0, 1)},
+		{ID: uuid.New(), Kind: models.EntryRepayment, LoanID: &loanID, WithheldFromID: &earning, Amount: -2.5, OccurredAt: day.AddDate(0, 0, 1)},
+		{ID: uuid.New(), Kind: models.EntryRepayment, LoanID: &loanID, Amount: -5, OccurredAt: day.AddDate(0, 0, 7)},
+	}
+
+	st := Build(&models.Group{}, &models.User{}, period, 0, entries, nil, nil, nil, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+
+	require.Len(t, st.Lines, 4)
+	assert.Equal(t, "Advance", st.Lines[0].Description)
+	assert.Equal(t, "Loan repayment (withheld)", st.Lines[2].Description)
+	assert.Equal(t, "Loan repayment", st.Lines[3].Description)
+	assert.Equal(t, Totals{Earned: 10, Advanced: 20, Repaid: 7.5}, st.Totals)
+	assert.Equal(t, 22.5, st.ClosingBalance)
+}
+
+func TestBuild_UnconfirmedSettlements(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	day := time.Date(2026, 9, 5, 0, 0, 0, 0, time.UTC)
+	settlements := []*models.Settlement{
+		{ID: uuid.New(), Amount: 3, Date: day, Status: models.SettlementAcknowledged},
+		{ID: uuid.New(), Amount: 2, Date: day, Status: models.SettlementAwaitingAck},
+		{ID: uuid.New(), Amount: 1.5, Date: day, Status: models.SettlementDisputed},
+	}
+
+	st := Build(&models.Group{}, &models.User{}, period, 10, nil, nil, settlements, nil, day)
+
+	require.Len(t, st.Lines, 3)
+	assert.Equal(t, "Settlement", st.Lines[0].Description)
+	assert.Equal(t, "Settlement (unconfirmed)", st.Lines[1].Description)
+	assert.Equal(t, "Settlement (disputed)", st.Lines[2].Description)
+	assert.Equal(t, 6.5, st.Totals.Settled)
+	assert.Equal(t, 3.5, st.Totals.Unconfirmed)
+	assert.Equal(t, 3.5, st.ClosingBalance)
+}
+
+func TestBuild_SettlementBreakdown(t *testing.T) {
+	period, err := ParsePeriod("2026-09")
+	require.NoError(t, err)
+	day := time.Date(2026, 9, 5, 0, 0, 0, 0, time.UTC)
+	birthday := &models.SettlementCategory{ID: uuid.New(), Name: "Birthday"}
+	allowance := &models.SettlementCategory{ID: uuid.New(), Name: "allowance"}
+	reference := "TRX-1"
+	settlements := []*models.Settlement{
+		{ID: uuid.New(), Amount: 3, Date: day, Method: models.MethodCash, CategoryID: &birthday.ID},
+		{ID: uuid.New(), Amount: 2.25, Date: day, Method: models.MethodBankTransfer, ExternalReference: &reference, CategoryID: &allowance.ID},
+		{ID: uuid.New(), Amount: 1, Date: day, Method: models.MethodCash},
+		{ID: uuid.New(), Amount: 0.75, Date: day, Method: models.MethodBankTransfer, CategoryID: &allowance.ID},
+	}
+	categories := map[uuid.UUID]*models.SettlementCategory{birthday.ID: birthday, allowance.ID: allowance}
+
+	st := Build(&models.Group{}, &models.User{}, period, 10, nil, nil, settlements, categories, day)
+
+	require.Len(t, st.Lines, 4)
+	assert.Equal(t, models.MethodBankTransfer, st.Lines[1].Method)
+	assert.Equal(t, &reference, st.Lines[1].ExternalReference)
+	require.NotNil(t, st.Lines[1].Category)
+	assert.Equal(t, "allowance", *st.Lines[1].Category)
+	assert.Nil(t, st.Lines[2].Category)
+
+	assert.Equal(t, []models.MethodTotal{
+		{Method: models.MethodCash, Count: 2, Amount: 4},
+		{Method: models.MethodBankTransfer, Count: 2, Amount: 3},
+	}, st.SettledByMethod)
+
+	// Categories by name, case-insensitively, with uncategorized last
+	require.Len(t, st.SettledByCategory, 3)
+	assert.Equal(t, &allowance.ID, st.SettledByCategory[0].CategoryID)
+	assert.Equal(t, 3.0, st.SettledByCategory[0].Amount)
+	assert.Equal(t, 2, st.SettledByCategory[0].Count)
+	assert.Equal(t, &birthday.ID, st.SettledByCategory[1].CategoryID)
+	assert.Nil(t, st.SettledByCategory[2].CategoryID)
+	assert.Equal(t, 1.0, st.SettledByCategory[2].Amount)
+
+	var buf bytes.Buffer
+	require.NoError(t, st.WriteHTML(&buf))
+	html := buf.String()
+	assert.Contains(t, html, "bank transfer &middot; TRX-1 &middot; allowance")
+	assert.Contains(t, html, "of which uncategorized")
+}
+
+func TestWriteCSV(t *testing.T) {
+	st := fixture(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, st.WriteCSV(&buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 7)
+	assert.Equal(t, []string{"2026-09-01", "opening_balance", "Opening balance", "", "", "", "4.00"}, rows[1])
+	assert.Equal(t, []string{"2026-09-03", "entry", "Dishes", "2.00", "0.50", "2.50", "6.50"}, rows[2])
+	assert.Equal(t, []string{"2026-09-30", "closing_balance", "Closing balance", "", "", "", "10.50"}, rows[6])
+}
+
+func TestWriteHTML(t *testing.T) {
+	st := fixture(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, st.WriteHTML(&buf))
+
+	html := buf.String()
+	assert.Contains(t, html, "Alex &lt;Jr&gt;")
+	assert.Contains(t, html, "Statement for September 2026")
+	assert.Contains(t, html, "Mow lawn")
+	assert.Contains(t, html, "10.50")
+}
+
+func TestFilename(t *testing.T) {
+	st := fixture(t)
+	assert.Equal(t, "statement-alex-jr-2026-09.csv", st.Filename("csv"))
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidKey(t *testing.T) {
+	assert.True(t, ValidKey("ledger/abc/photo-1.jpg"))
+	assert.True(t, ValidKey("a"))
+
+	assert.False(t, ValidKey(""))
+	assert.False(t, ValidKey("/etc/passwd"))
+	assert.False(t, ValidKey("ledger/../../etc"))
+	assert.False(t, ValidKey("ledger/./a"))
+	assert.False(t, ValidKey("ledger//a"))
+	assert.False(t, ValidKey("ledger/a/"))
+	assert.False(t, ValidKey(`ledger\a`))
+}
+
+func TestAttachmentKey(t *testing.T) {
+	group, entry, attachment := uuid.New(), uuid.New(), uuid.New()
+	key := AttachmentKey(group, entry, attachment)
+
+	assert.Equal(t, "ledger/"+group.String()+"/"+entry.String()+"/"+attachment.String(), key)
+	assert.True(t, ValidKey(key))
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "blobs")
+	s, err := NewLocalStore(root)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put(ctx, "ledger/g/e/a", strings.NewReader("photo")))
+	require.NoError(t, s.Put(ctx, "ledger/g/e/a", strings.NewReader("replaced")))
+
+	rc, err := s.Open(ctx, "ledger/g/e/a")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, rc.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", string(data))
+
+	// Only the object is left behind, no temporary files
+	entries, err := os.ReadDir(filepath.Join(root, "ledger", "g", "e"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, s.Delete(ctx, "ledger/g/e/a"))
+	require.NoError(t, s.Delete(ctx, "ledger/g/e/a"))
+	_, err = s.Open(ctx, "ledger/g/e/a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Error(t, s.Put(ctx, "../escape", strings.NewReader("x")))
+	_, err = s.Open(ctx, "../escape")
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result records the outcome of one delivery attempt
+type Result struct {
+	Attempts       int
+	Delivered      bool
+	ResponseStatus *int
+	Error          *string
+	// NextAttemptAt is when to retry; nil once the delivery is delivered or has failed for good
+	NextAttemptAt *time.Time
+}
+
+// Store is the delivery outbox
+type Store interface {
+	// ClaimDue returns up to limit pending deliveries that are due and hides
+	// them from other claimers for lease
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	// RecordAttempt saves the outcome of an attempt
+	RecordAttempt(ctx context.Context, id uuid.UUID, result Result) error
+}
+
+// Dispatcher sends due deliveries from a Store
+type Dispatcher struct {
+	store     Store
+	client    *http.Client
+	batchSize int
+	lease     time.Duration
+	now       func() time.Time
+}
+
+// NewDispatcher creates a Dispatcher. Redirects are not followed, so a 3xx
+// response counts as a failure.
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		batchSize: 20,
+		lease:     time.Minute,
+		now:       time.Now,
+	}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.RunOnce(ctx)
+			if err != nil {
+				log.Printf("webhook: %v", err)
+				break
+			}
+			if n < d.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims one batch of due deliveries, sends them and records the
+// results. It returns how many deliveries were attempted.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	deliveries, err := d.store.ClaimDue(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		result := d.Deliver(ctx, delivery)
+		if err := d.store.RecordAttempt(ctx, delivery.ID, result); err != nil {
+			return 0, fmt.Errorf("failed to record delivery %s: %w", delivery.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Deliver makes one signed attempt at a delivery. A 2xx response delivers
+// it; anything else schedules a retry until MaxAttempts is reached.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *Delivery) Result {
+	result := Result{Attempts: delivery.Attempts + 1}
+
+	status, err := d.send(ctx, delivery)
+	if status != 0 {
+		result.ResponseStatus = &status
+	}
+	if err == nil {
+		result.Delivered = true
+		return result
+	}
+
+	msg := err.Error()
+	result.Error = &msg
+	if result.Attempts < MaxAttempts {
+		next := d.now().Add(Backoff(result.Attempts))
+		result.NextAttemptAt = &next
+	}
+	return result
+}
+
+// send posts the payload and returns the response status
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	now := d.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pocket-money-webhooks/1")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,86 @@
+// Package webhook signs and delivers outgoing webhook requests from the
+// delivery outbox, retrying failures with exponential backoff.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-PocketMoney-Event"
+	HeaderDelivery  = "X-PocketMoney-Delivery"
+	HeaderTimestamp = "X-PocketMoney-Timestamp"
+	HeaderSignature = "X-PocketMoney-Signature"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts = 8
+	// baseBackoff is the wait after the first failed attempt; it doubles after each one
+	baseBackoff = 30 * time.Second
+	// maxBackoff caps the wait between attempts
+	maxBackoff = 6 * time.Hour
+)
+
+// Delivery is a queued request to one webhook
+type Delivery struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+	URL       string
+	Secret    string
+	EventType string
+	Payload   []byte
+	Attempts  int // Attempts made before this one
+}
+
+// Sign returns the signature header value for a payload sent at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<payload>".
+// Receivers should recompute it and reject old timestamps to stop replays.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before retrying after the given number
+// of failed attempts
+func Backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory outbox
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries []*Delivery
+	results    map[uuid.UUID]Result
+}
+
+func (s *memoryStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*Delivery
+	for _, d := range s.deliveries {
+		if r, done := s.results[d.ID]; done && r.NextAttemptAt == nil {
+			continue
+		}
+		due = append(due, d)
+		if len(due) == limit {
+			break
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) RecordAttempt(ctx context.Context, id uuid.UUID, result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[id] = result
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			d.Attempts = result.Attempts
+		}
+	}
+	return nil
+}
+
+func newTestDispatcher(deliveries ...*Delivery) (*Dispatcher, *memoryStore, time.Time) {
+	store := &memoryStore{deliveries: deliveries, results: map[uuid.UUID]Result{}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(store)
+	d.now = func() time.Time { return now }
+	return d, store, now
+}
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1790000000, 0)
+	sig := Sign("secret", ts, []byte(`{"a":1}`))
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, sig)
+	assert.Equal(t, sig, Sign("secret", ts, []byte(`{"a":1}`)))
+	assert.NotEqual(t, sig, Sign("other", ts, []byte(`{"a":1}`)))
+	assert.NotEqual(t, sig, Sign("secret", ts.Add(time.Second), []byte(`{"a":1}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://hooks.example.com/pocket"))
+	assert.NoError(t, ValidateURL("http://homeassistant.local:8123/api/webhook/abc"))
+	assert.Error(t, ValidateURL("ftp://example.com"))
+	assert.Error(t, ValidateURL("/relative"))
+	assert.Error(t, ValidateURL("https://"))
+}
+
+func TestDeliverSignedRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	delivery := &Delivery{
+		ID:        uuid.New(),
+		URL:       server.URL,
+		Secret:    "s3cret",
+		EventType: "ledger.approved",
+		Payload:   []byte(`{"type":"ledger.approved"}`),
+	}
+	d, store, now := newTestDispatcher(delivery)
+
+	n, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "ledger.approved", got.Header.Get(HeaderEvent))
+	assert.Equal(t, delivery.ID.String(), got.Header.Get(HeaderDelivery))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), got.Header.Get(HeaderTimestamp))
+	assert.Equal(t, Sign("s3cret", now, delivery.Payload), got.Header.Get(HeaderSignature))
+	assert.Equal(t, delivery.Payload, body)
+
+	result := store.results[delivery.ID]
+	assert.True(t, result.Delivered)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, http.StatusNoContent, *result.ResponseStatus)
+	assert.Nil(t, result.NextAttemptAt)
+	assert.Nil(t, result.Error)
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	delivery := &Delivery{ID: uuid.New(), URL: server.URL, Secret: "s", EventType: "ledger.created", Payload: []byte(`{}`)}
+	d, store, now := newTestDispatcher(delivery)
+
+	_, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	result := store.results[delivery.ID]
+	assert.False(t, result.Delivered)
+	assert.Equal(t, http.StatusBadGateway, *result.ResponseStatus)
+	assert.Equal(t, "unexpected response status 502", *result.Error)
+	assert.Equal(t, now.Add(30*time.Second), *result.NextAttemptAt)
+
+	_, err = d.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), *store.results[delivery.ID].NextAttemptAt)
+
+	_, err = d.RunOnce(context.Background())
+	require.NoError(t, err)
+	result = store.results[delivery.ID]
+	assert.True(t, result.Delivered)
+	assert.Equal(t, 3, result.Attempts)
+
+	// Delivered deliveries are not sent again
+	n, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	delivery := &Delivery{ID: uuid.New(), URL: server.URL, Secret: "s", Payload: []byte(`{}`), Attempts: MaxAttempts - 1}
+	d, store, _ := newTestDispatcher(delivery)
+
+	_, err := d.RunOnce(context.Background())
+	require.NoError(t, err)
+	result := store.results[delivery.ID]
+	assert.False(t, result.Delivered)
+	assert.Equal(t, MaxAttempts, result.Attempts)
+	assert.Nil(t, result.NextAttemptAt)
+}
+
+func TestDeliverDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+
+	d, _, _ := newTestDispatcher()
+	result := d.Deliver(context.Background(), &Delivery{ID: uuid.New(), URL: server.URL + "/hook", Payload: []byte(`{}`)})
+	assert.False(t, result.Delivered)
+	assert.Equal(t, http.StatusFound, *result.ResponseStatus)
+}
+
+func TestDeliverConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	d, _, _ := newTestDispatcher()
+	result := d.Deliver(context.Background(), &Delivery{ID: uuid.New(), URL: url, Payload: []byte(`{}`)})
+	assert.False(t, result.Delivered)
+	assert.Nil(t, result.ResponseStatus)
+	require.NotNil(t, result.Error)
+	assert.NotNil(t, result.NextAttemptAt)
+}
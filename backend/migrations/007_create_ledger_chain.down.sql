@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_chain_record;
+
+-- Drop hash columns
+ALTER TABLE settlements DROP COLUMN IF EXISTS hash;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS hash;
+
+-- Drop ledger_chain table
+DROP TABLE IF EXISTS ledger_chain;
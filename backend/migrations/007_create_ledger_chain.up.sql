@@ -0,0 +1,19 @@
+-- Create ledger_chain table (append-only hash chain per group)
+CREATE TABLE ledger_chain (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    record_type TEXT NOT NULL CHECK (record_type IN ('ledger_entry', 'settlement')),
+    record_id UUID NOT NULL,
+    payload TEXT NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, seq)
+);
+
+-- Latest chain hash carried on each chained row
+ALTER TABLE ledger_entries ADD COLUMN hash TEXT;
+ALTER TABLE settlements ADD COLUMN hash TEXT;
+
+-- Indexes
+CREATE INDEX idx_ledger_chain_record ON ledger_chain(record_type, record_id);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlements_group_created;
+DROP INDEX IF EXISTS idx_settlements_group_date;
+DROP INDEX IF EXISTS idx_ledger_entries_group_amount;
+DROP INDEX IF EXISTS idx_ledger_entries_group_created;
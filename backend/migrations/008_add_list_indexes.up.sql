@@ -0,0 +1,5 @@
+-- Keyset pagination indexes for ledger and settlement listings
+CREATE INDEX idx_ledger_entries_group_created ON ledger_entries(group_id, created_at, id);
+CREATE INDEX idx_ledger_entries_group_amount ON ledger_entries(group_id, amount, id);
+CREATE INDEX idx_settlements_group_date ON settlements(group_id, date, id);
+CREATE INDEX idx_settlements_group_created ON settlements(group_id, created_at, id);
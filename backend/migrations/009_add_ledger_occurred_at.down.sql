@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_entries_group_occurred;
+
+-- Drop columns
+ALTER TABLE groups DROP COLUMN IF EXISTS max_backdate_days;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS occurred_at;
@@ -0,0 +1,11 @@
+-- When the chore was actually done; existing entries occurred when they were logged
+ALTER TABLE ledger_entries ADD COLUMN occurred_at TIMESTAMPTZ;
+UPDATE ledger_entries SET occurred_at = created_at;
+ALTER TABLE ledger_entries ALTER COLUMN occurred_at SET NOT NULL;
+ALTER TABLE ledger_entries ALTER COLUMN occurred_at SET DEFAULT now();
+
+-- How many days back members may date entries (NULL = no limit, heads are exempt)
+ALTER TABLE groups ADD COLUMN max_backdate_days INTEGER CHECK (max_backdate_days >= 0);
+
+-- Indexes
+CREATE INDEX idx_ledger_entries_group_occurred ON ledger_entries(group_id, occurred_at, id);
@@ -0,0 +1,3 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlements_member_date;
+DROP INDEX IF EXISTS idx_ledger_entries_approved_member_occurred;
@@ -0,0 +1,3 @@
+-- Balance as-of and history aggregation indexes; INCLUDE amount for index-only scans
+CREATE INDEX idx_ledger_entries_approved_member_occurred ON ledger_entries(group_id, user_id, occurred_at) INCLUDE (amount) WHERE status = 'approved';
+CREATE INDEX idx_settlements_member_date ON settlements(group_id, user_id, date) INCLUDE (amount);
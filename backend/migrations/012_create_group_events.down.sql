@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_group_events_group_seq;
+
+-- Drop group_events table
+DROP TABLE IF EXISTS group_events;
@@ -0,0 +1,13 @@
+-- Create group_events table (event log for live updates and resume)
+CREATE TABLE group_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    actor_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Indexes
+CREATE INDEX idx_group_events_group_seq ON group_events(group_id, seq);
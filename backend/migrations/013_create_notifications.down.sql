@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_notification_preferences_scope;
+DROP INDEX IF EXISTS idx_devices_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,33 @@
+-- Create devices table (push tokens registered by the app)
+CREATE TABLE devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    platform TEXT NOT NULL CHECK (platform IN ('ios', 'android')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create notification_settings table (timezone and quiet hours, minutes after midnight)
+CREATE TABLE notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    quiet_hours_start SMALLINT CHECK (quiet_hours_start BETWEEN 0 AND 1439),
+    quiet_hours_end SMALLINT CHECK (quiet_hours_end BETWEEN 0 AND 1439),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL))
+);
+
+-- Create notification_preferences table (per event type, for all groups or one group)
+CREATE TABLE notification_preferences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL
+);
+
+-- Indexes
+CREATE INDEX idx_devices_user_id ON devices(user_id);
+CREATE UNIQUE INDEX idx_notification_preferences_scope
+    ON notification_preferences(user_id, COALESCE(group_id, '00000000-0000-0000-0000-000000000000'::uuid), event_type);
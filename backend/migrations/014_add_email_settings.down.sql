@@ -0,0 +1,4 @@
+ALTER TABLE notification_settings
+    DROP COLUMN IF EXISTS email_digest,
+    DROP COLUMN IF EXISTS email_notifications,
+    DROP COLUMN IF EXISTS locale;
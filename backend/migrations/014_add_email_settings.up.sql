@@ -0,0 +1,5 @@
+-- Email language and opt-outs; both kinds of email are on until the user opts out
+ALTER TABLE notification_settings
+    ADD COLUMN locale TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN email_notifications BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN email_digest BOOLEAN NOT NULL DEFAULT true;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ledger_reminders;
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,14 @@
+-- Create job_runs table (the last tick each scheduled job ran, across server instances)
+CREATE TABLE job_runs (
+    name TEXT PRIMARY KEY,
+    last_tick TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ,
+    last_error TEXT
+);
+
+-- Create ledger_reminders table (when heads were last reminded about a pending entry)
+CREATE TABLE ledger_reminders (
+    entry_id UUID PRIMARY KEY REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    reminded_at TIMESTAMPTZ NOT NULL
+);
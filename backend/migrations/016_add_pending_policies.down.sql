@@ -0,0 +1,9 @@
+ALTER TABLE ledger_entries
+    DROP COLUMN IF EXISTS system_actor,
+    DROP COLUMN IF EXISTS status_reason;
+
+ALTER TABLE groups
+    DROP CONSTRAINT IF EXISTS groups_auto_approve_complete,
+    DROP COLUMN IF EXISTS auto_approve_max_amount,
+    DROP COLUMN IF EXISTS auto_approve_after_hours,
+    DROP COLUMN IF EXISTS expire_pending_after_days;
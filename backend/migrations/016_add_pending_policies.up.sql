@@ -0,0 +1,12 @@
+-- Group policies for entries nobody approved or rejected (NULL = off)
+ALTER TABLE groups
+    ADD COLUMN expire_pending_after_days INTEGER CHECK (expire_pending_after_days >= 1),
+    ADD COLUMN auto_approve_after_hours INTEGER CHECK (auto_approve_after_hours >= 1),
+    ADD COLUMN auto_approve_max_amount DECIMAL(12, 2) CHECK (auto_approve_max_amount >= 0),
+    ADD CONSTRAINT groups_auto_approve_complete
+        CHECK ((auto_approve_after_hours IS NULL) = (auto_approve_max_amount IS NULL));
+
+-- Why an entry's status last changed, and which system process changed it
+ALTER TABLE ledger_entries
+    ADD COLUMN status_reason TEXT,
+    ADD COLUMN system_actor TEXT CHECK (system_actor IN ('auto_expire', 'auto_approve'));
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_entries_approval_rule;
+DROP INDEX IF EXISTS idx_approval_rules_group_id;
+
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS approval_rule_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS approval_rules;
@@ -0,0 +1,20 @@
+-- Create approval_rules table (auto-approve matching entries members log)
+CREATE TABLE approval_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    chore_id UUID REFERENCES chores(id) ON DELETE CASCADE,  -- NULL matches any chore
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,    -- NULL matches any member
+    max_per_day INTEGER CHECK (max_per_day >= 1),           -- NULL is unlimited
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ  -- Rules are kept once deleted so entries can still name them
+);
+
+-- The rule that approved an entry, instead of a head
+ALTER TABLE ledger_entries
+    ADD COLUMN approval_rule_id UUID REFERENCES approval_rules(id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX idx_approval_rules_group_id ON approval_rules(group_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_ledger_entries_approval_rule ON ledger_entries(approval_rule_id, user_id, created_at)
+    WHERE approval_rule_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_comments_entry_id;
+DROP INDEX IF EXISTS idx_ledger_entries_resubmitted_from;
+
+-- Drop tables
+DROP TABLE IF EXISTS ledger_comments;
+
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS resubmitted_from_id;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS require_reject_reason;
@@ -0,0 +1,21 @@
+-- Heads must give a reason when rejecting an entry
+ALTER TABLE groups
+    ADD COLUMN require_reject_reason BOOLEAN NOT NULL DEFAULT false;
+
+-- A rejected entry is resubmitted as a new entry, at most once
+ALTER TABLE ledger_entries
+    ADD COLUMN resubmitted_from_id UUID REFERENCES ledger_entries(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_ledger_entries_resubmitted_from ON ledger_entries(resubmitted_from_id)
+    WHERE resubmitted_from_id IS NOT NULL;
+
+-- Create ledger_comments table (discussion thread on an entry)
+CREATE TABLE ledger_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL CHECK (length(body) > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ledger_comments_entry_id ON ledger_comments(entry_id, created_at);
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_attachments_entry_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS ledger_attachments;
+
+ALTER TABLE chores DROP COLUMN IF EXISTS proof_required;
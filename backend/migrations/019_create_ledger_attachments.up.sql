@@ -0,0 +1,16 @@
+-- Entries for these chores need a photo before they can be approved
+ALTER TABLE chores
+    ADD COLUMN proof_required BOOLEAN NOT NULL DEFAULT false;
+
+-- Create ledger_attachments table (photos stored outside the database)
+CREATE TABLE ledger_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    uploaded_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_ledger_attachments_entry_id ON ledger_attachments(entry_id, created_at);
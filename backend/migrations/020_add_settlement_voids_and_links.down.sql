@@ -0,0 +1,13 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlement_entries_entry_id;
+DROP INDEX IF EXISTS idx_settlement_revisions_settlement_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS settlement_entries;
+DROP TABLE IF EXISTS settlement_revisions;
+
+ALTER TABLE settlements
+    DROP COLUMN IF EXISTS void_reason,
+    DROP COLUMN IF EXISTS voided_by_user_id,
+    DROP COLUMN IF EXISTS voided_at,
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,57 @@
+-- Heads can correct or void a settlement; voided settlements stay on record
+-- but no longer count towards balances
+ALTER TABLE settlements
+    ADD COLUMN updated_at TIMESTAMPTZ,
+    ADD COLUMN voided_at TIMESTAMPTZ,
+    ADD COLUMN voided_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN void_reason TEXT;
+
+-- Create settlement_revisions table (audit trail of edits and voids)
+CREATE TABLE settlement_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    settlement_id UUID NOT NULL REFERENCES settlements(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('edit', 'void')),
+    changed_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL CHECK (length(reason) > 0),
+    amount_before DECIMAL(12, 2) NOT NULL,
+    amount_after DECIMAL(12, 2) NOT NULL,
+    date_before DATE NOT NULL,
+    date_after DATE NOT NULL,
+    note_before TEXT,
+    note_after TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_settlement_revisions_settlement_id ON settlement_revisions(settlement_id, created_at);
+
+-- Create settlement_entries table (the approved entries a settlement pays off)
+CREATE TABLE settlement_entries (
+    settlement_id UUID NOT NULL REFERENCES settlements(id) ON DELETE CASCADE,
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (settlement_id, entry_id)
+);
+
+CREATE INDEX idx_settlement_entries_entry_id ON settlement_entries(entry_id);
+
+-- Link existing settlements to the entries they paid, oldest first: each
+-- member's entries and settlements are laid end to end and every
+-- settlement pays the part of each entry its range overlaps
+INSERT INTO settlement_entries (settlement_id, entry_id, amount)
+SELECT s.id, e.id, LEAST(e.paid_to, s.paid_to) - GREATEST(e.paid_from, s.paid_from)
+FROM (
+    SELECT id, group_id, user_id,
+           SUM(amount) OVER w - amount AS paid_from,
+           SUM(amount) OVER w AS paid_to
+    FROM ledger_entries
+    WHERE status = 'approved'
+    WINDOW w AS (PARTITION BY group_id, user_id ORDER BY occurred_at, created_at, id)
+) e
+JOIN (
+    SELECT id, group_id, user_id,
+           SUM(amount) OVER w - amount AS paid_from,
+           SUM(amount) OVER w AS paid_to
+    FROM settlements
+    WINDOW w AS (PARTITION BY group_id, user_id ORDER BY date, created_at, id)
+) s ON s.group_id = e.group_id AND s.user_id = e.user_id
+WHERE LEAST(e.paid_to, s.paid_to) > GREATEST(e.paid_from, s.paid_from);
@@ -0,0 +1,13 @@
+DELETE FROM settlement_revisions WHERE action IN ('dispute', 'resolve');
+ALTER TABLE settlement_revisions DROP CONSTRAINT settlement_revisions_action_check;
+ALTER TABLE settlement_revisions ADD CONSTRAINT settlement_revisions_action_check
+    CHECK (action IN ('edit', 'void'));
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlements_group_disputed;
+
+ALTER TABLE settlements
+    DROP COLUMN IF EXISTS dispute_reason,
+    DROP COLUMN IF EXISTS disputed_at,
+    DROP COLUMN IF EXISTS acknowledged_at,
+    DROP COLUMN IF EXISTS status;
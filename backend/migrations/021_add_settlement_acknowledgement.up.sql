@@ -0,0 +1,18 @@
+-- Members confirm or dispute the settlements paid to them. Settlements
+-- recorded before this existed count as acknowledged.
+ALTER TABLE settlements
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'acknowledged'
+        CHECK (status IN ('awaiting_ack', 'acknowledged', 'disputed')),
+    ADD COLUMN acknowledged_at TIMESTAMPTZ,
+    ADD COLUMN disputed_at TIMESTAMPTZ,
+    ADD COLUMN dispute_reason TEXT;
+
+ALTER TABLE settlements ALTER COLUMN status SET DEFAULT 'awaiting_ack';
+
+CREATE INDEX idx_settlements_group_disputed ON settlements(group_id, disputed_at)
+    WHERE status = 'disputed' AND voided_at IS NULL;
+
+-- Disputes and their resolutions join edits and voids in the audit trail
+ALTER TABLE settlement_revisions DROP CONSTRAINT settlement_revisions_action_check;
+ALTER TABLE settlement_revisions ADD CONSTRAINT settlement_revisions_action_check
+    CHECK (action IN ('edit', 'void', 'dispute', 'resolve'));
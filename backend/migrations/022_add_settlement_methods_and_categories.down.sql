@@ -0,0 +1,19 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlements_category_id;
+DROP INDEX IF EXISTS idx_settlement_categories_group_name;
+
+ALTER TABLE settlement_revisions
+    DROP COLUMN IF EXISTS category_id_after,
+    DROP COLUMN IF EXISTS category_id_before,
+    DROP COLUMN IF EXISTS external_reference_after,
+    DROP COLUMN IF EXISTS external_reference_before,
+    DROP COLUMN IF EXISTS method_after,
+    DROP COLUMN IF EXISTS method_before;
+
+ALTER TABLE settlements
+    DROP COLUMN IF EXISTS category_id,
+    DROP COLUMN IF EXISTS external_reference,
+    DROP COLUMN IF EXISTS method;
+
+-- Drop tables
+DROP TABLE IF EXISTS settlement_categories;
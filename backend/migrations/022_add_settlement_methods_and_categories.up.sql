@@ -0,0 +1,30 @@
+-- Create settlement_categories table (what a group's payouts are for)
+CREATE TABLE settlement_categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL CHECK (length(name) > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ  -- Categories are kept once deleted so settlements can still name them
+);
+
+-- How a settlement was paid out, the bank's reference for transfers, and
+-- its category. Settlements recorded before this existed were cash.
+ALTER TABLE settlements
+    ADD COLUMN method TEXT NOT NULL DEFAULT 'cash'
+        CHECK (method IN ('cash', 'bank_transfer', 'in_kind')),
+    ADD COLUMN external_reference TEXT,
+    ADD COLUMN category_id UUID REFERENCES settlement_categories(id);
+
+-- Edits record the payout details before and after too
+ALTER TABLE settlement_revisions
+    ADD COLUMN method_before TEXT,
+    ADD COLUMN method_after TEXT,
+    ADD COLUMN external_reference_before TEXT,
+    ADD COLUMN external_reference_after TEXT,
+    ADD COLUMN category_id_before UUID REFERENCES settlement_categories(id),
+    ADD COLUMN category_id_after UUID REFERENCES settlement_categories(id);
+
+-- Indexes
+CREATE UNIQUE INDEX idx_settlement_categories_group_name ON settlement_categories(group_id, lower(name))
+    WHERE deleted_at IS NULL;
+CREATE INDEX idx_settlements_category_id ON settlements(category_id) WHERE category_id IS NOT NULL;
@@ -0,0 +1,25 @@
+-- Entries without a chore cannot be kept once chore_id is required again,
+-- and are never deleted to make the rollback work
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_entries WHERE chore_id IS NULL) THEN
+        RAISE EXCEPTION 'cannot roll back allowances: ledger entries without a chore exist';
+    END IF;
+END $$;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_entries_allowance_period;
+DROP INDEX IF EXISTS idx_allowances_next_period;
+DROP INDEX IF EXISTS idx_allowances_group_id;
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_allowance_check,
+    DROP CONSTRAINT IF EXISTS ledger_entries_chore_check,
+    DROP CONSTRAINT IF EXISTS ledger_entries_kind_check,
+    DROP COLUMN IF EXISTS allowance_period,
+    DROP COLUMN IF EXISTS allowance_id,
+    DROP COLUMN IF EXISTS kind,
+    ALTER COLUMN chore_id SET NOT NULL;
+
+-- Drop tables
+DROP TABLE IF EXISTS allowances;
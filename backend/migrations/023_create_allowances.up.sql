@@ -0,0 +1,35 @@
+-- Create allowances table (a member's recurring pocket money)
+CREATE TABLE allowances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    amount_per_year DECIMAL(12, 2) CHECK (amount_per_year > 0),  -- Paid per year of age instead of amount when the member's dob is known
+    interval_unit TEXT NOT NULL CHECK (interval_unit IN ('week', 'month')),
+    start_date DATE NOT NULL,
+    next_period DATE NOT NULL,  -- Start of the first period not yet credited
+    paused_at TIMESTAMPTZ,
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ  -- Allowances are kept once deleted so their entries can still name them
+);
+
+-- Entries are no longer only for chores. Allowance entries name the
+-- allowance and the period they credit, once per period.
+ALTER TABLE ledger_entries
+    ALTER COLUMN chore_id DROP NOT NULL,
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'chore',
+    ADD COLUMN allowance_id UUID REFERENCES allowances(id) ON DELETE CASCADE,
+    ADD COLUMN allowance_period DATE,
+    ADD CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('chore', 'allowance')),
+    ADD CONSTRAINT ledger_entries_chore_check CHECK (kind <> 'chore' OR chore_id IS NOT NULL),
+    ADD CONSTRAINT ledger_entries_allowance_check
+        CHECK (kind <> 'allowance' OR (allowance_id IS NOT NULL AND allowance_period IS NOT NULL));
+
+-- Indexes
+CREATE INDEX idx_allowances_group_id ON allowances(group_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_allowances_next_period ON allowances(next_period)
+    WHERE deleted_at IS NULL AND paused_at IS NULL;
+CREATE UNIQUE INDEX idx_ledger_entries_allowance_period ON ledger_entries(allowance_id, allowance_period)
+    WHERE allowance_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_goal_allocations_goal_id;
+DROP INDEX IF EXISTS idx_savings_goals_unreached;
+DROP INDEX IF EXISTS idx_savings_goals_member;
+
+-- Drop tables
+DROP TABLE IF EXISTS goal_allocations;
+DROP TABLE IF EXISTS savings_goals;
@@ -0,0 +1,36 @@
+-- Create savings_goals table (what a member is saving up for)
+CREATE TABLE savings_goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL CHECK (length(trim(name)) > 0),
+    target_amount DECIMAL(12, 2) NOT NULL CHECK (target_amount > 0),
+    deadline DATE,
+    progress_source TEXT NOT NULL DEFAULT 'balance' CHECK (progress_source IN ('balance', 'allocations')),
+    image_content_type TEXT,  -- Optional photo, stored outside the database
+    image_size_bytes BIGINT CHECK (image_size_bytes > 0),
+    image_storage_key TEXT UNIQUE,
+    reached_at TIMESTAMPTZ,  -- Set once when the goal is first reached
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ,
+    CONSTRAINT savings_goals_image_check
+        CHECK ((image_storage_key IS NULL) = (image_content_type IS NULL) AND (image_storage_key IS NULL) = (image_size_bytes IS NULL))
+);
+
+-- Create goal_allocations table (money set aside for a goal; negative amounts take it back)
+CREATE TABLE goal_allocations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    goal_id UUID NOT NULL REFERENCES savings_goals(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount <> 0),
+    note TEXT,
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Indexes
+CREATE INDEX idx_savings_goals_member ON savings_goals(group_id, user_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_savings_goals_unreached ON savings_goals(group_id)
+    WHERE deleted_at IS NULL AND reached_at IS NULL;
+CREATE INDEX idx_goal_allocations_goal_id ON goal_allocations(goal_id, created_at);
@@ -0,0 +1,14 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_settlements_jar_id;
+DROP INDEX IF EXISTS idx_jar_transfers_to_jar_id;
+DROP INDEX IF EXISTS idx_jar_transfers_from_jar_id;
+DROP INDEX IF EXISTS idx_jar_transfers_member;
+DROP INDEX IF EXISTS idx_jar_splits_jar_id;
+DROP INDEX IF EXISTS idx_jars_member_name;
+
+ALTER TABLE settlements DROP COLUMN IF EXISTS jar_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS jar_transfers;
+DROP TABLE IF EXISTS jar_splits;
+DROP TABLE IF EXISTS jars;
@@ -0,0 +1,45 @@
+-- Create jars table (named parts of a member's balance, e.g. spend, save and give)
+CREATE TABLE jars (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL CHECK (length(trim(name)) > 0),
+    split_percent DECIMAL(5, 2) NOT NULL CHECK (split_percent >= 0 AND split_percent <= 100),  -- Share of each approved entry
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ  -- Jars are kept once deleted so splits and settlements can still name them
+);
+
+-- Create jar_splits table (the part of an approved entry that went into each jar)
+CREATE TABLE jar_splits (
+    entry_id UUID NOT NULL REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    jar_id UUID NOT NULL REFERENCES jars(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (entry_id, jar_id)
+);
+
+-- Create jar_transfers table (money moved between a member's jars)
+CREATE TABLE jar_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_jar_id UUID NOT NULL REFERENCES jars(id) ON DELETE CASCADE,
+    to_jar_id UUID NOT NULL REFERENCES jars(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    note TEXT,
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (from_jar_id <> to_jar_id)
+);
+
+-- The jar a settlement was paid out of, if any
+ALTER TABLE settlements ADD COLUMN jar_id UUID REFERENCES jars(id);
+
+-- Indexes
+CREATE UNIQUE INDEX idx_jars_member_name ON jars(group_id, user_id, lower(name)) WHERE deleted_at IS NULL;
+CREATE INDEX idx_jar_splits_jar_id ON jar_splits(jar_id);
+CREATE INDEX idx_jar_transfers_member ON jar_transfers(group_id, user_id, created_at);
+CREATE INDEX idx_jar_transfers_from_jar_id ON jar_transfers(from_jar_id);
+CREATE INDEX idx_jar_transfers_to_jar_id ON jar_transfers(to_jar_id);
+CREATE INDEX idx_settlements_jar_id ON settlements(jar_id) WHERE jar_id IS NOT NULL;
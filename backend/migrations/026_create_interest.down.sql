@@ -0,0 +1,26 @@
+-- Interest entries cannot be kept once the kind is gone, and are never
+-- deleted to make the rollback work
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_entries WHERE kind = 'interest') THEN
+        RAISE EXCEPTION 'cannot roll back interest: interest entries exist';
+    END IF;
+END $$;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_interest_postings_member;
+DROP INDEX IF EXISTS idx_ledger_entries_interest_rate_id;
+DROP INDEX IF EXISTS idx_interest_rates_next_period;
+DROP INDEX IF EXISTS idx_interest_rates_jar;
+DROP INDEX IF EXISTS idx_interest_rates_group;
+
+DROP TABLE IF EXISTS interest_postings;
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_interest_check,
+    DROP CONSTRAINT IF EXISTS ledger_entries_kind_check,
+    ADD CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('chore', 'allowance')),
+    DROP COLUMN IF EXISTS interest_rate_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS interest_rates;
@@ -0,0 +1,47 @@
+-- Create interest_rates table (interest a head pays on a group's balances or one jar)
+CREATE TABLE interest_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    jar_id UUID REFERENCES jars(id) ON DELETE CASCADE,  -- Set for a rate on one jar; otherwise it is the group's rate
+    apr DECIMAL(5, 2) NOT NULL CHECK (apr > 0 AND apr <= 100),
+    compounding TEXT NOT NULL CHECK (compounding IN ('week', 'month')),
+    min_balance DECIMAL(12, 2) NOT NULL DEFAULT 0 CHECK (min_balance >= 0),  -- Average daily balance needed to earn anything
+    start_date DATE NOT NULL,
+    next_period DATE NOT NULL,  -- Start of the first period not yet posted
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMPTZ  -- Rates are kept once deleted so their entries can still name them
+);
+
+-- Interest entries name the rate that paid them
+ALTER TABLE ledger_entries
+    ADD COLUMN interest_rate_id UUID REFERENCES interest_rates(id) ON DELETE CASCADE,
+    DROP CONSTRAINT ledger_entries_kind_check,
+    ADD CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('chore', 'allowance', 'interest')),
+    ADD CONSTRAINT ledger_entries_interest_check CHECK (kind <> 'interest' OR interest_rate_id IS NOT NULL);
+
+-- Create interest_postings table (how each interest entry was worked out)
+CREATE TABLE interest_postings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rate_id UUID NOT NULL REFERENCES interest_rates(id) ON DELETE CASCADE,
+    entry_id UUID NOT NULL UNIQUE REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jar_id UUID REFERENCES jars(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    breakdown TEXT NOT NULL,  -- JSON of how the amount was worked out
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (rate_id, user_id, period_start)
+);
+
+-- Indexes
+CREATE UNIQUE INDEX idx_interest_rates_group ON interest_rates(group_id)
+    WHERE jar_id IS NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_interest_rates_jar ON interest_rates(jar_id)
+    WHERE jar_id IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX idx_interest_rates_next_period ON interest_rates(next_period) WHERE deleted_at IS NULL;
+CREATE INDEX idx_ledger_entries_interest_rate_id ON ledger_entries(interest_rate_id)
+    WHERE interest_rate_id IS NOT NULL;
+CREATE INDEX idx_interest_postings_member ON interest_postings(group_id, user_id, period_start);
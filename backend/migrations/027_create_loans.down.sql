@@ -0,0 +1,28 @@
+-- Loan entries cannot be kept once the kinds are gone, and are never
+-- deleted to make the rollback work
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM ledger_entries WHERE kind IN ('loan', 'loan_repayment')) THEN
+        RAISE EXCEPTION 'cannot roll back loans: loan entries exist';
+    END IF;
+END $$;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_ledger_entries_withheld_from_id;
+DROP INDEX IF EXISTS idx_ledger_entries_loan_weekly;
+DROP INDEX IF EXISTS idx_ledger_entries_loan_withheld;
+DROP INDEX IF EXISTS idx_ledger_entries_loan_advance;
+DROP INDEX IF EXISTS idx_ledger_entries_loan_id;
+DROP INDEX IF EXISTS idx_loans_next_repayment;
+DROP INDEX IF EXISTS idx_loans_member;
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT IF EXISTS ledger_entries_withheld_check,
+    DROP CONSTRAINT IF EXISTS ledger_entries_loan_check,
+    DROP CONSTRAINT IF EXISTS ledger_entries_kind_check,
+    ADD CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('chore', 'allowance', 'interest')),
+    DROP COLUMN IF EXISTS withheld_from_id,
+    DROP COLUMN IF EXISTS loan_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS loans;
@@ -0,0 +1,46 @@
+-- Create loans table (advances a head pays a member, paid back out of their balance)
+CREATE TABLE loans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    principal DECIMAL(12, 2) NOT NULL CHECK (principal > 0),
+    interest_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (interest_percent >= 0 AND interest_percent <= 100),  -- Flat, on the principal
+    total_due DECIMAL(12, 2) NOT NULL CHECK (total_due >= principal),
+    repayment_plan TEXT NOT NULL CHECK (repayment_plan IN ('weekly', 'withhold')),
+    weekly_amount DECIMAL(12, 2) CHECK (weekly_amount > 0),
+    withhold_percent DECIMAL(5, 2) CHECK (withhold_percent > 0 AND withhold_percent <= 100),
+    start_date DATE NOT NULL,
+    next_repayment DATE,  -- Weekly plans: the first repayment not yet taken
+    note TEXT,
+    repaid_at TIMESTAMPTZ,
+    created_by_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT loans_plan_check CHECK (
+        (repayment_plan = 'weekly' AND weekly_amount IS NOT NULL AND withhold_percent IS NULL AND next_repayment IS NOT NULL)
+        OR (repayment_plan = 'withhold' AND withhold_percent IS NOT NULL AND weekly_amount IS NULL AND next_repayment IS NULL)
+    )
+);
+
+-- Loan entries name their loan; repayments withheld from an earning name it too
+ALTER TABLE ledger_entries
+    ADD COLUMN loan_id UUID REFERENCES loans(id) ON DELETE CASCADE,
+    ADD COLUMN withheld_from_id UUID REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    DROP CONSTRAINT ledger_entries_kind_check,
+    ADD CONSTRAINT ledger_entries_kind_check CHECK (kind IN ('chore', 'allowance', 'interest', 'loan', 'loan_repayment')),
+    ADD CONSTRAINT ledger_entries_loan_check CHECK (kind NOT IN ('loan', 'loan_repayment') OR loan_id IS NOT NULL),
+    ADD CONSTRAINT ledger_entries_withheld_check CHECK (withheld_from_id IS NULL OR kind = 'loan_repayment');
+
+-- Indexes
+CREATE INDEX idx_loans_member ON loans(group_id, user_id, created_at);
+CREATE INDEX idx_loans_next_repayment ON loans(next_repayment)
+    WHERE repayment_plan = 'weekly' AND repaid_at IS NULL;
+CREATE INDEX idx_ledger_entries_loan_id ON ledger_entries(loan_id) WHERE loan_id IS NOT NULL;
+-- One advance per loan, one repayment per loan and earning or week
+CREATE UNIQUE INDEX idx_ledger_entries_loan_advance ON ledger_entries(loan_id) WHERE kind = 'loan';
+CREATE UNIQUE INDEX idx_ledger_entries_loan_withheld ON ledger_entries(loan_id, withheld_from_id)
+    WHERE withheld_from_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_ledger_entries_loan_weekly ON ledger_entries(loan_id, occurred_at)
+    WHERE kind = 'loan_repayment' AND withheld_from_id IS NULL;
+CREATE INDEX idx_ledger_entries_withheld_from_id ON ledger_entries(withheld_from_id)
+    WHERE withheld_from_id IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_allowance_id_fkey,
+    ADD CONSTRAINT ledger_entries_allowance_id_fkey
+        FOREIGN KEY (allowance_id) REFERENCES allowances(id) ON DELETE CASCADE;
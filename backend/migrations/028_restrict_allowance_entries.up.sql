@@ -0,0 +1,7 @@
+-- Allowance entries are the ledger's history, so they must not go with
+-- their allowance. Allowances are only ever soft deleted; removing a whole
+-- group or member still removes its entries through group_id and user_id.
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_allowance_id_fkey,
+    ADD CONSTRAINT ledger_entries_allowance_id_fkey
+        FOREIGN KEY (allowance_id) REFERENCES allowances(id);
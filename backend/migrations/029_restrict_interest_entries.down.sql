@@ -0,0 +1,4 @@
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_interest_rate_id_fkey,
+    ADD CONSTRAINT ledger_entries_interest_rate_id_fkey
+        FOREIGN KEY (interest_rate_id) REFERENCES interest_rates(id) ON DELETE CASCADE;
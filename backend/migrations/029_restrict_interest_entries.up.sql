@@ -0,0 +1,6 @@
+-- Interest entries must not go with the rate that paid them; rates are
+-- only ever soft deleted
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_interest_rate_id_fkey,
+    ADD CONSTRAINT ledger_entries_interest_rate_id_fkey
+        FOREIGN KEY (interest_rate_id) REFERENCES interest_rates(id);
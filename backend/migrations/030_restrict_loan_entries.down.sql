@@ -0,0 +1,7 @@
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_withheld_from_id_fkey,
+    ADD CONSTRAINT ledger_entries_withheld_from_id_fkey
+        FOREIGN KEY (withheld_from_id) REFERENCES ledger_entries(id) ON DELETE CASCADE,
+    DROP CONSTRAINT ledger_entries_loan_id_fkey,
+    ADD CONSTRAINT ledger_entries_loan_id_fkey
+        FOREIGN KEY (loan_id) REFERENCES loans(id) ON DELETE CASCADE;
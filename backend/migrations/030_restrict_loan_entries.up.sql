@@ -0,0 +1,9 @@
+-- Advances and repayments must not go with their loan, nor a withheld
+-- repayment with the earning it was taken from
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_loan_id_fkey,
+    ADD CONSTRAINT ledger_entries_loan_id_fkey
+        FOREIGN KEY (loan_id) REFERENCES loans(id),
+    DROP CONSTRAINT ledger_entries_withheld_from_id_fkey,
+    ADD CONSTRAINT ledger_entries_withheld_from_id_fkey
+        FOREIGN KEY (withheld_from_id) REFERENCES ledger_entries(id);
@@ -0,0 +1,11 @@
+-- Drop tables
+DROP TABLE IF EXISTS chore_overdue_reminders;
+DROP TABLE IF EXISTS chore_reminders;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_chores_due_at;
+
+ALTER TABLE chores
+    DROP COLUMN IF EXISTS assigned_at,
+    DROP COLUMN IF EXISTS due_at,
+    DROP COLUMN IF EXISTS assignee_user_id;
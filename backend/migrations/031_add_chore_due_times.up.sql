@@ -0,0 +1,22 @@
+-- A chore can be assigned to a member, optionally with a time it is due by.
+-- assigned_at is when either last changed; entries logged since then do it.
+ALTER TABLE chores
+    ADD COLUMN assignee_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN due_at TIMESTAMPTZ,
+    ADD COLUMN assigned_at TIMESTAMPTZ;
+
+CREATE INDEX idx_chores_due_at ON chores(due_at) WHERE due_at IS NOT NULL;
+
+-- Create chore_reminders table (when the assignee was reminded that a chore is coming due)
+CREATE TABLE chore_reminders (
+    chore_id UUID PRIMARY KEY REFERENCES chores(id) ON DELETE CASCADE,
+    due_at TIMESTAMPTZ NOT NULL,  -- The due time reminded about
+    reminded_at TIMESTAMPTZ NOT NULL
+);
+
+-- Create chore_overdue_reminders table (when the assignee was last nudged about an overdue chore)
+CREATE TABLE chore_overdue_reminders (
+    chore_id UUID PRIMARY KEY REFERENCES chores(id) ON DELETE CASCADE,
+    due_at TIMESTAMPTZ NOT NULL,  -- The due time nudged about
+    reminded_at TIMESTAMPTZ NOT NULL
+);
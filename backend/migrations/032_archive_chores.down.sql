@@ -0,0 +1,17 @@
+-- Archived chores would show up as live again, and are never deleted to
+-- make the rollback work
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM chores WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'cannot roll back chore archiving: archived chores exist';
+    END IF;
+END $$;
+
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_chore_id_fkey,
+    ADD CONSTRAINT ledger_entries_chore_id_fkey
+        FOREIGN KEY (chore_id) REFERENCES chores(id) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_chores_group_id_active;
+
+ALTER TABLE chores DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,10 @@
+-- Chores are archived instead of deleted, so their entries can still name them
+ALTER TABLE chores ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_chores_group_id_active ON chores(group_id) WHERE deleted_at IS NULL;
+
+-- A chore with entries can no longer be deleted from under them
+ALTER TABLE ledger_entries
+    DROP CONSTRAINT ledger_entries_chore_id_fkey,
+    ADD CONSTRAINT ledger_entries_chore_id_fkey
+        FOREIGN KEY (chore_id) REFERENCES chores(id) ON DELETE RESTRICT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_group_events_group_id_group_seq;
+
+ALTER TABLE group_events DROP COLUMN IF EXISTS group_seq;
@@ -0,0 +1,10 @@
+-- Number events per group in commit order, so a client resuming from the
+-- last ID it saw cannot skip an event that committed after a later one.
+-- Existing events keep their global seq, which already increases per group.
+ALTER TABLE group_events ADD COLUMN group_seq BIGINT;
+
+UPDATE group_events SET group_seq = seq;
+
+ALTER TABLE group_events ALTER COLUMN group_seq SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_group_events_group_id_group_seq ON group_events(group_id, group_seq);
@@ -60,9 +60,35 @@ func CleanupTestDB(pool *pgxpool.Pool) error {
 
 	// Truncate all tables in reverse order of dependencies (preserves schema)
 	tables := []string{
+		"job_runs",
+		"ledger_reminders",
+		"chore_overdue_reminders",
+		"chore_reminders",
+		"notification_preferences",
+		"notification_settings",
+		"devices",
+		"group_events",
+		"webhook_deliveries",
+		"webhooks",
+		"settlement_entries",
+		"settlement_revisions",
+		"goal_allocations",
+		"savings_goals",
+		"interest_postings",
+		"jar_splits",
+		"jar_transfers",
+		"ledger_attachments",
+		"ledger_comments",
+		"ledger_chain",
 		"invite_tokens",
 		"settlements",
+		"jars",
+		"settlement_categories",
 		"ledger_entries",
+		"loans",
+		"interest_rates",
+		"allowances",
+		"approval_rules",
 		"chores",
 		"group_members",
 		"groups",
@@ -87,9 +113,35 @@ func ResetTestDB(pool *pgxpool.Pool) error {
 	// Drop all tables in reverse order of dependencies
 	tables := []string{
 		"schema_migrations",
+		"job_runs",
+		"ledger_reminders",
+		"chore_overdue_reminders",
+		"chore_reminders",
+		"notification_preferences",
+		"notification_settings",
+		"devices",
+		"group_events",
+		"webhook_deliveries",
+		"webhooks",
+		"settlement_entries",
+		"settlement_revisions",
+		"goal_allocations",
+		"savings_goals",
+		"interest_postings",
+		"jar_splits",
+		"jar_transfers",
+		"ledger_attachments",
+		"ledger_comments",
+		"ledger_chain",
 		"invite_tokens",
 		"settlements",
+		"jars",
+		"settlement_categories",
 		"ledger_entries",
+		"loans",
+		"interest_rates",
+		"allowances",
+		"approval_rules",
 		"chores",
 		"group_members",
 		"groups",